@@ -105,3 +105,128 @@ func parseReplaceInfo(out string) ReplaceInfo {
 	}
 	return info
 }
+
+// DeviceStats holds one member's error counters from `btrfs device stats`.
+type DeviceStats struct {
+	Device         string `json:"device"`
+	WriteIOErrs    uint64 `json:"write_io_errs"`
+	ReadIOErrs     uint64 `json:"read_io_errs"`
+	FlushIOErrs    uint64 `json:"flush_io_errs"`
+	CorruptionErrs uint64 `json:"corruption_errs"`
+	GenerationErrs uint64 `json:"generation_errs"`
+}
+
+// parseDeviceStats parses `btrfs device stats <mount>` output, one line per
+// counter:
+//
+//	[/dev/sda].write_io_errs    0
+//
+// Devices are returned in the order they first appear.
+func parseDeviceStats(out string) []DeviceStats {
+	re := regexp.MustCompile(`^\[(.+)\]\.(\w+)\s+(\d+)\s*$`)
+	var list []DeviceStats
+	index := map[string]int{}
+	for _, ln := range strings.Split(out, "\n") {
+		m := re.FindStringSubmatch(strings.TrimSpace(ln))
+		if len(m) != 4 {
+			continue
+		}
+		i, ok := index[m[1]]
+		if !ok {
+			i = len(list)
+			index[m[1]] = i
+			list = append(list, DeviceStats{Device: m[1]})
+		}
+		var v uint64
+		_, _ = fmt.Sscanf(m[3], "%d", &v)
+		d := &list[i]
+		switch m[2] {
+		case "write_io_errs":
+			d.WriteIOErrs = v
+		case "read_io_errs":
+			d.ReadIOErrs = v
+		case "flush_io_errs":
+			d.FlushIOErrs = v
+		case "corruption_errs":
+			d.CorruptionErrs = v
+		case "generation_errs":
+			d.GenerationErrs = v
+		}
+	}
+	return list
+}
+
+// Qgroup is one row of `btrfs qgroup show --raw`.
+type Qgroup struct {
+	ID         string `json:"id"` // "<level>/<subvol id>"
+	Level      int    `json:"level"`
+	SubvolID   uint64 `json:"subvol_id"`
+	Referenced uint64 `json:"referenced"`
+	Exclusive  uint64 `json:"exclusive"`
+}
+
+// parseQgroupShow parses `btrfs qgroup show --raw <mount>`:
+//
+//	qgroupid         rfer         excl
+//	--------         ----         ----
+//	0/5             16384        16384
+//	0/256      1073741824        65536
+//
+// Newer btrfs-progs add a trailing path column, which is ignored. Header,
+// separator and warning lines are skipped.
+func parseQgroupShow(out string) []Qgroup {
+	re := regexp.MustCompile(`^(\d+)/(\d+)\s+(\d+)\s+(\d+)(?:\s|$)`)
+	var list []Qgroup
+	for _, ln := range strings.Split(out, "\n") {
+		m := re.FindStringSubmatch(strings.TrimSpace(ln))
+		if len(m) != 5 {
+			continue
+		}
+		q := Qgroup{ID: m[1] + "/" + m[2]}
+		_, _ = fmt.Sscanf(m[1], "%d", &q.Level)
+		_, _ = fmt.Sscanf(m[2], "%d", &q.SubvolID)
+		_, _ = fmt.Sscanf(m[3], "%d", &q.Referenced)
+		_, _ = fmt.Sscanf(m[4], "%d", &q.Exclusive)
+		list = append(list, q)
+	}
+	return list
+}
+
+// Subvolume is one row of `btrfs subvolume list -u -q`. ParentUUID is set
+// for snapshots and names the subvolume they were taken from.
+type Subvolume struct {
+	ID         uint64 `json:"id"`
+	Path       string `json:"path"`
+	UUID       string `json:"uuid"`
+	ParentUUID string `json:"parent_uuid,omitempty"`
+}
+
+// parseSubvolumeList parses `btrfs subvolume list -u -q <mount>`:
+//
+//	ID 256 gen 30 top level 5 parent_uuid - uuid 6f1c... path data
+//	ID 260 gen 31 top level 256 parent_uuid 6f1c... uuid 9a2b... path data/.snapshots/daily-1
+func parseSubvolumeList(out string) []Subvolume {
+	reID := regexp.MustCompile(`^ID (\d+) `)
+	reParent := regexp.MustCompile(` parent_uuid (\S+)`)
+	reUUID := regexp.MustCompile(` uuid (\S+)`)
+	var list []Subvolume
+	for _, ln := range strings.Split(out, "\n") {
+		ln = strings.TrimSpace(ln)
+		m := reID.FindStringSubmatch(ln)
+		idx := strings.Index(ln, " path ")
+		if len(m) != 2 || idx < 0 {
+			continue
+		}
+		s := Subvolume{Path: ln[idx+len(" path "):]}
+		_, _ = fmt.Sscanf(m[1], "%d", &s.ID)
+		head := ln[:idx]
+		if p := reParent.FindStringSubmatch(head); len(p) == 2 && p[1] != "-" {
+			s.ParentUUID = p[1]
+		}
+		if u := reUUID.FindStringSubmatch(head); len(u) == 2 {
+			s.UUID = u[1]
+		}
+		list = append(list, s)
+	}
+	return list
+}
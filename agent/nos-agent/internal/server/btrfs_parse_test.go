@@ -60,3 +60,87 @@ func TestParseReplaceInfo(t *testing.T) {
 		t.Fatalf("expected not running")
 	}
 }
+
+func TestParseDeviceStats(t *testing.T) {
+	out := `[/dev/sda].write_io_errs    0
+[/dev/sda].read_io_errs     0
+[/dev/sda].flush_io_errs    0
+[/dev/sda].corruption_errs  0
+[/dev/sda].generation_errs  0
+[/dev/mapper/luks-b1].write_io_errs    12
+[/dev/mapper/luks-b1].read_io_errs     3
+[/dev/mapper/luks-b1].flush_io_errs    1
+[/dev/mapper/luks-b1].corruption_errs  7
+[/dev/mapper/luks-b1].generation_errs  2
+`
+	stats := parseDeviceStats(out)
+	if len(stats) != 2 || stats[0].Device != "/dev/sda" || stats[1].Device != "/dev/mapper/luks-b1" {
+		t.Fatalf("unexpected devices: %+v", stats)
+	}
+	if stats[0] != (DeviceStats{Device: "/dev/sda"}) {
+		t.Fatalf("expected clean counters: %+v", stats[0])
+	}
+	want := DeviceStats{Device: "/dev/mapper/luks-b1", WriteIOErrs: 12, ReadIOErrs: 3, FlushIOErrs: 1, CorruptionErrs: 7, GenerationErrs: 2}
+	if stats[1] != want {
+		t.Fatalf("got %+v, want %+v", stats[1], want)
+	}
+	if len(parseDeviceStats("ERROR: not a btrfs filesystem: /mnt/x\n")) != 0 {
+		t.Fatal("expected no devices from an error message")
+	}
+}
+
+func TestParseQgroupShow(t *testing.T) {
+	// btrfs-progs 5.x layout, with the rescan warning some versions print
+	old := `WARNING: qgroup data inconsistent, rescan recommended
+qgroupid         rfer         excl 
+--------         ----         ---- 
+0/5             16384        16384 
+0/256      1073741824        65536 
+0/260      1073709056    209715200 
+1/100      2147450880    209731584 
+`
+	got := parseQgroupShow(old)
+	if len(got) != 4 {
+		t.Fatalf("want 4 qgroups, got %+v", got)
+	}
+	want := Qgroup{ID: "0/260", Level: 0, SubvolID: 260, Referenced: 1073709056, Exclusive: 209715200}
+	if got[2] != want {
+		t.Fatalf("got %+v, want %+v", got[2], want)
+	}
+	if got[3].Level != 1 || got[3].SubvolID != 100 {
+		t.Fatalf("higher-level qgroup: %+v", got[3])
+	}
+
+	// btrfs-progs 6.x adds a path column
+	cur := `Qgroupid    Referenced    Exclusive   Path 
+--------    ----------    ---------   ---- 
+0/5              16384        16384   <toplevel> 
+0/257         52428800     10485760   data/.snapshots/daily-2024-05-01 
+0/258          4096           4096   <stale> 
+`
+	got = parseQgroupShow(cur)
+	if len(got) != 3 || got[1] != (Qgroup{ID: "0/257", SubvolID: 257, Referenced: 52428800, Exclusive: 10485760}) {
+		t.Fatalf("unexpected 6.x parse: %+v", got)
+	}
+
+	if len(parseQgroupShow("ERROR: can't list qgroups: quotas not enabled\n")) != 0 {
+		t.Fatal("expected no qgroups when quotas are disabled")
+	}
+}
+
+func TestParseSubvolumeList(t *testing.T) {
+	out := `ID 256 gen 30 top level 5 parent_uuid -                                    uuid 6f1c2d3e-0000-4000-8000-000000000001 path data
+ID 260 gen 31 top level 256 parent_uuid 6f1c2d3e-0000-4000-8000-000000000001 uuid 9a2b0000-0000-4000-8000-000000000002 path data/.snapshots/daily 2024-05-01
+`
+	got := parseSubvolumeList(out)
+	if len(got) != 2 {
+		t.Fatalf("want 2 subvolumes, got %+v", got)
+	}
+	if got[0] != (Subvolume{ID: 256, Path: "data", UUID: "6f1c2d3e-0000-4000-8000-000000000001"}) {
+		t.Fatalf("source: %+v", got[0])
+	}
+	want := Subvolume{ID: 260, Path: "data/.snapshots/daily 2024-05-01", UUID: "9a2b0000-0000-4000-8000-000000000002", ParentUUID: "6f1c2d3e-0000-4000-8000-000000000001"}
+	if got[1] != want {
+		t.Fatalf("snapshot: %+v", got[1])
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -88,6 +89,40 @@ func handleBtrfsReplaceStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// handleBtrfsDeviceStats reports `btrfs device stats` error counters for
+// every member of the filesystem mounted at ?mount=.
+func handleBtrfsDeviceStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	mount := r.URL.Query().Get("mount")
+	if !filepath.IsAbs(mount) {
+		writeErr(w, http.StatusBadRequest, "mount required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/usr/bin/btrfs", "device", "stats", mount)
+	cmd.Env = []string{"PATH=/usr/sbin:/usr/bin:/bin", "LANG=C", "LC_ALL=C"}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		writeErr(w, http.StatusInternalServerError, msg)
+		return
+	}
+	stats := parseDeviceStats(stdout.String())
+	if stats == nil {
+		stats = []DeviceStats{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"devices": stats})
+}
+
 func boolToStr(b bool) string {
 	if b {
 		return "true"
@@ -100,3 +135,62 @@ func optPct(p float64) string {
 	}
 	return " percent=" + strconv.FormatFloat(p, 'f', 1, 64)
 }
+
+// handleBtrfsSnapshotSpace lists the subvolumes of the filesystem mounted at
+// ?mount= together with their qgroup usage, so nosd can report how much space
+// snapshots hold exclusively. With quotas disabled the qgroup list is empty
+// and quota_enabled is false; consistent is false when btrfs reports the
+// qgroup numbers need a rescan.
+func handleBtrfsSnapshotSpace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	mount := r.URL.Query().Get("mount")
+	if !filepath.IsAbs(mount) {
+		writeErr(w, http.StatusBadRequest, "mount required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	run := func(args ...string) (string, string, error) {
+		cmd := exec.CommandContext(ctx, "/usr/bin/btrfs", args...)
+		cmd.Env = []string{"PATH=/usr/sbin:/usr/bin:/bin", "LANG=C", "LC_ALL=C"}
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		return stdout.String(), strings.TrimSpace(stderr.String()), err
+	}
+	out, stderr, err := run("subvolume", "list", "-u", "-q", mount)
+	if err != nil {
+		if stderr == "" {
+			stderr = err.Error()
+		}
+		writeErr(w, http.StatusInternalServerError, stderr)
+		return
+	}
+	subvols := parseSubvolumeList(out)
+	if subvols == nil {
+		subvols = []Subvolume{}
+	}
+	resp := map[string]any{"subvolumes": subvols, "qgroups": []Qgroup{}, "quota_enabled": false, "consistent": true}
+	qout, qerr, err := run("qgroup", "show", "--raw", mount)
+	switch {
+	case err == nil:
+		if q := parseQgroupShow(qout); q != nil {
+			resp["qgroups"] = q
+		}
+		resp["quota_enabled"] = true
+		resp["consistent"] = !strings.Contains(strings.ToLower(qerr+qout), "inconsistent")
+	case strings.Contains(strings.ToLower(qerr), "not enabled"):
+		// quotas off: report subvolumes only
+	default:
+		if qerr == "" {
+			qerr = err.Error()
+		}
+		writeErr(w, http.StatusInternalServerError, qerr)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
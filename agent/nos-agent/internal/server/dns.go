@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// resolvedDropIn is the systemd-resolved drop-in owned by NithronOS.
+const resolvedDropIn = "systemd/resolved.conf.d/nithronos.conf"
+
+// dnsSettings is the validated payload of network.dns.set.
+type dnsSettings struct {
+	Servers []string
+	Search  []string
+	DHCP    bool
+}
+
+// parseDNSParams validates servers (IP literals) and search domains. An
+// empty server list is only accepted when dhcp is true.
+func parseDNSParams(params map[string]interface{}) (dnsSettings, error) {
+	var s dnsSettings
+	s.DHCP, _ = params["dhcp"].(bool)
+	servers, err := stringList(params["servers"])
+	if err != nil {
+		return s, fmt.Errorf("servers: %v", err)
+	}
+	search, err := stringList(params["search"])
+	if err != nil {
+		return s, fmt.Errorf("search: %v", err)
+	}
+	for _, v := range servers {
+		if net.ParseIP(v) == nil {
+			return s, fmt.Errorf("invalid DNS server %q", v)
+		}
+	}
+	for _, d := range search {
+		if !validDomain(strings.TrimSuffix(d, ".")) {
+			return s, fmt.Errorf("invalid search domain %q", d)
+		}
+	}
+	if len(servers) == 0 && !s.DHCP {
+		return s, fmt.Errorf("at least one DNS server required unless dhcp is set")
+	}
+	s.Servers, s.Search = servers, search
+	return s, nil
+}
+
+func stringList(v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of strings")
+	}
+	out := make([]string, 0, len(raw))
+	for _, e := range raw {
+		str, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a list of strings")
+		}
+		if str = strings.TrimSpace(str); str != "" {
+			out = append(out, str)
+		}
+	}
+	return out, nil
+}
+
+// renderResolvConf produces a static /etc/resolv.conf.
+func renderResolvConf(s dnsSettings) string {
+	var b strings.Builder
+	b.WriteString("# Generated by NithronOS; manual changes will be overwritten.\n")
+	for _, ns := range s.Servers {
+		b.WriteString("nameserver " + ns + "\n")
+	}
+	if len(s.Search) > 0 {
+		b.WriteString("search " + strings.Join(s.Search, " ") + "\n")
+	}
+	return b.String()
+}
+
+// renderResolvedDropIn produces the [Resolve] drop-in for systemd-resolved.
+// With dhcp and no static servers the drop-in only carries search domains so
+// link-provided servers stay in effect.
+func renderResolvedDropIn(s dnsSettings) string {
+	var b strings.Builder
+	b.WriteString("# Generated by NithronOS; manual changes will be overwritten.\n[Resolve]\n")
+	if len(s.Servers) > 0 {
+		b.WriteString("DNS=" + strings.Join(s.Servers, " ") + "\n")
+	}
+	if len(s.Search) > 0 {
+		b.WriteString("Domains=" + strings.Join(s.Search, " ") + "\n")
+	}
+	return b.String()
+}
+
+// usesResolved reports whether <etcDir>/resolv.conf is managed by
+// systemd-resolved (a symlink into /run/systemd/resolve).
+func usesResolved() bool {
+	target, err := os.Readlink(filepath.Join(etcDir, "resolv.conf"))
+	return err == nil && strings.Contains(target, "systemd/resolve")
+}
+
+// applyDNS writes the resolved drop-in when systemd-resolved owns
+// resolv.conf, otherwise replaces resolv.conf itself. It returns the
+// backend used.
+func applyDNS(s dnsSettings) (string, error) {
+	if usesResolved() {
+		path := filepath.Join(etcDir, resolvedDropIn)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		return "systemd-resolved", writeFileAtomic(path, renderResolvedDropIn(s), 0o644)
+	}
+	if s.DHCP && len(s.Servers) == 0 {
+		// leave resolv.conf to the DHCP client
+		return "dhcp", nil
+	}
+	return "resolv.conf", writeFileAtomic(filepath.Join(etcDir, "resolv.conf"), renderResolvConf(s), 0o644)
+}
+
+func handleSetDNS(w http.ResponseWriter, params map[string]interface{}) {
+	s, err := parseDNSParams(params)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	backend, err := applyDNS(s)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, fmt.Sprintf("failed to write DNS config: %v", err))
+		return
+	}
+	if backend == "systemd-resolved" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, "systemctl", "restart", "systemd-resolved").CombinedOutput(); err != nil {
+			writeErr(w, http.StatusInternalServerError, fmt.Sprintf("DNS config written but systemd-resolved restart failed: %v: %s", err, strings.TrimSpace(string(out))))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "result": "success", "backend": backend})
+}
+
+// writeFileAtomic replaces path via a synced temp file and rename.
+func writeFileAtomic(path, data string, mode os.FileMode) error {
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path + ".tmp")
+		return err
+	}
+	_ = f.Sync()
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return err
+	}
+	return fsyncDir(filepath.Dir(path))
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDNSParams(t *testing.T) {
+	ok := []map[string]interface{}{
+		{"servers": []interface{}{"1.1.1.1", "2606:4700:4700::1111"}, "search": []interface{}{"home.lan"}},
+		{"servers": []interface{}{}, "dhcp": true},
+		{"dhcp": true, "search": []interface{}{"corp.example.com."}},
+	}
+	for i, p := range ok {
+		if _, err := parseDNSParams(p); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+	}
+	bad := []map[string]interface{}{
+		{},
+		{"servers": []interface{}{}},
+		{"servers": []interface{}{"dns.google"}},
+		{"servers": []interface{}{"1.1.1.1"}, "search": []interface{}{"bad_domain"}},
+		{"servers": "1.1.1.1"},
+		{"servers": []interface{}{1}},
+	}
+	for i, p := range bad {
+		if _, err := parseDNSParams(p); err == nil {
+			t.Fatalf("case %d: expected error for %v", i, p)
+		}
+	}
+}
+
+func TestRenderDNSConfig(t *testing.T) {
+	s := dnsSettings{Servers: []string{"1.1.1.1", "9.9.9.9"}, Search: []string{"home.lan", "example.com"}}
+	want := "# Generated by NithronOS; manual changes will be overwritten.\nnameserver 1.1.1.1\nnameserver 9.9.9.9\nsearch home.lan example.com\n"
+	if got := renderResolvConf(s); got != want {
+		t.Fatalf("resolv.conf:\n%s", got)
+	}
+	want = "# Generated by NithronOS; manual changes will be overwritten.\n[Resolve]\nDNS=1.1.1.1 9.9.9.9\nDomains=home.lan example.com\n"
+	if got := renderResolvedDropIn(s); got != want {
+		t.Fatalf("drop-in:\n%s", got)
+	}
+	if got := renderResolvedDropIn(dnsSettings{DHCP: true}); got != "# Generated by NithronOS; manual changes will be overwritten.\n[Resolve]\n" {
+		t.Fatalf("dhcp drop-in: %q", got)
+	}
+}
+
+func TestApplyDNSBackends(t *testing.T) {
+	dir := t.TempDir()
+	old := etcDir
+	etcDir = dir
+	defer func() { etcDir = old }()
+	s := dnsSettings{Servers: []string{"192.0.2.53"}}
+
+	backend, err := applyDNS(s)
+	if err != nil || backend != "resolv.conf" {
+		t.Fatalf("static: %s %v", backend, err)
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "resolv.conf"))
+	if string(b) != renderResolvConf(s) {
+		t.Fatalf("resolv.conf content: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "resolv.conf.tmp")); !os.IsNotExist(err) {
+		t.Fatal("temp file left behind")
+	}
+
+	_ = os.Remove(filepath.Join(dir, "resolv.conf"))
+	if err := os.Symlink("../run/systemd/resolve/stub-resolv.conf", filepath.Join(dir, "resolv.conf")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	backend, err = applyDNS(s)
+	if err != nil || backend != "systemd-resolved" {
+		t.Fatalf("resolved: %s %v", backend, err)
+	}
+	b, _ = os.ReadFile(filepath.Join(dir, resolvedDropIn))
+	if string(b) != renderResolvedDropIn(s) {
+		t.Fatalf("drop-in content: %q", b)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+type FSRemoveRequest struct {
+	Path string `json:"path"`
+}
+
+// removableDirs are the drop-in directories nosd may delete files from.
+func removableDirs() []string {
+	return []string{filepath.Join(etcDir, "exports.d")}
+}
+
+// handleFSRemove deletes one regular file from a drop-in directory. A file
+// that is already gone is not an error.
+func handleFSRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req FSRemoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !filepath.IsAbs(req.Path) {
+		writeErr(w, http.StatusBadRequest, "absolute path required")
+		return
+	}
+	clean := filepath.Clean(req.Path)
+	allowed := false
+	for _, d := range removableDirs() {
+		if filepath.Dir(clean) == d {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		writeErr(w, http.StatusBadRequest, "path forbidden")
+		return
+	}
+	fi, err := os.Lstat(clean)
+	if os.IsNotExist(err) {
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true, "removed": false})
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, fmt.Sprintf("stat: %v", err))
+		return
+	}
+	if !fi.Mode().IsRegular() {
+		writeErr(w, http.StatusBadRequest, "not a regular file")
+		return
+	}
+	if err := os.Remove(clean); err != nil {
+		writeErr(w, http.StatusInternalServerError, fmt.Sprintf("remove: %v", err))
+		return
+	}
+	_ = fsyncDir(filepath.Dir(clean))
+
+	logAuthPriv("fs.remove " + clean)
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true, "removed": true})
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fsRemove(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(FSRemoveRequest{Path: path})
+	w := httptest.NewRecorder()
+	handleFSRemove(w, httptest.NewRequest(http.MethodPost, "/v1/fs/remove", bytes.NewReader(b)))
+	return w
+}
+
+func TestFSRemove(t *testing.T) {
+	old := etcDir
+	etcDir = t.TempDir()
+	t.Cleanup(func() { etcDir = old })
+	dir := filepath.Join(etcDir, "exports.d")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "abc.exports")
+	if err := os.WriteFile(target, []byte("/srv *(ro)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := fsRemove(t, target); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"removed":true`)) {
+		t.Fatalf("remove: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("file still present: %v", err)
+	}
+	if w := fsRemove(t, target); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"removed":false`)) {
+		t.Fatalf("remove missing: %d %s", w.Code, w.Body.String())
+	}
+
+	for _, bad := range []string{
+		filepath.Join(etcDir, "passwd"),
+		filepath.Join(dir, "sub"),
+		filepath.Join(dir, "sub", "x.exports"),
+		filepath.Join(dir, "..", "shadow"),
+		"exports.d/abc.exports",
+	} {
+		if w := fsRemove(t, bad); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", bad, w.Code)
+		}
+	}
+}
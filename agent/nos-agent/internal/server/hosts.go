@@ -0,0 +1,74 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validHostLabel reports whether s is a single RFC 1123 label.
+func validHostLabel(s string) bool {
+	if len(s) == 0 || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// validDomain reports whether s is a dot-separated list of RFC 1123 labels.
+func validDomain(s string) bool {
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	for _, l := range strings.Split(s, ".") {
+		if !validHostLabel(l) {
+			return false
+		}
+	}
+	return true
+}
+
+// rewriteHosts points the Debian-style 127.0.1.1 entry at the new name,
+// listing the FQDN first when there is one. Other lines are kept verbatim;
+// the entry is appended if missing.
+func rewriteHosts(data, short, fqdn string) string {
+	entry := "127.0.1.1\t" + short
+	if fqdn != "" && fqdn != short {
+		entry = "127.0.1.1\t" + fqdn + " " + short
+	}
+	lines := strings.Split(strings.TrimRight(data, "\n"), "\n")
+	out := make([]string, 0, len(lines)+1)
+	replaced := false
+	for _, ln := range lines {
+		f := strings.Fields(ln)
+		if len(f) > 0 && f[0] == "127.0.1.1" {
+			if !replaced {
+				out = append(out, entry)
+				replaced = true
+			}
+			continue
+		}
+		out = append(out, ln)
+	}
+	if len(out) == 1 && out[0] == "" {
+		out = out[:0]
+	}
+	if !replaced {
+		out = append(out, entry)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// writeHostsFile rewrites <etcDir>/hosts via a synced temp file and rename.
+func writeHostsFile(short, fqdn string) error {
+	path := filepath.Join(etcDir, "hosts")
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileAtomic(path, rewriteHosts(string(b), short, fqdn), 0o644)
+}
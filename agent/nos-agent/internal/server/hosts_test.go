@@ -0,0 +1,59 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteHosts(t *testing.T) {
+	in := "127.0.0.1\tlocalhost\n127.0.1.1\toldname\n\n# The following lines are desirable for IPv6\n::1\tlocalhost ip6-localhost\n"
+	want := "127.0.0.1\tlocalhost\n127.0.1.1\tnas.home.lan nas\n\n# The following lines are desirable for IPv6\n::1\tlocalhost ip6-localhost\n"
+	if got := rewriteHosts(in, "nas", "nas.home.lan"); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	// entry appended when missing; duplicates collapsed
+	if got := rewriteHosts("127.0.0.1 localhost\n", "nas", "nas"); got != "127.0.0.1 localhost\n127.0.1.1\tnas\n" {
+		t.Fatalf("append: %q", got)
+	}
+	if got := rewriteHosts("127.0.1.1 a\n127.0.1.1 b\n", "nas", ""); got != "127.0.1.1\tnas\n" {
+		t.Fatalf("dedupe: %q", got)
+	}
+	if got := rewriteHosts("", "nas", ""); got != "127.0.1.1\tnas\n" {
+		t.Fatalf("empty: %q", got)
+	}
+}
+
+func TestWriteHostsFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	old := etcDir
+	etcDir = dir
+	defer func() { etcDir = old }()
+
+	if err := os.WriteFile(filepath.Join(dir, "hosts"), []byte("127.0.0.1 localhost\n127.0.1.1 old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeHostsFile("nas", "nas.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "hosts"))
+	if string(b) != "127.0.0.1 localhost\n127.0.1.1\tnas.example.com nas\n" {
+		t.Fatalf("hosts: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hosts.tmp")); !os.IsNotExist(err) {
+		t.Fatal("temp file left behind")
+	}
+}
+
+func TestValidHostLabel(t *testing.T) {
+	for _, ok := range []string{"nas", "nas-01", "N4S"} {
+		if !validHostLabel(ok) {
+			t.Errorf("%q should be valid", ok)
+		}
+	}
+	for _, bad := range []string{"", "-nas", "nas-", "nas.lan", "nas_01", "näs", string(make([]byte, 64))} {
+		if validHostLabel(bad) {
+			t.Errorf("%q should be invalid", bad)
+		}
+	}
+}
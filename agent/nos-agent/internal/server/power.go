@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPowerDelay bounds how far ahead a reboot/poweroff may be scheduled.
+const maxPowerDelay = 24 * time.Hour
+
+// powerCommand builds the systemd-run invocation that schedules verb
+// ("reboot" or "poweroff") after delay. A transient timer is used even for
+// "now" so the HTTP reply reaches nosd before the host goes down.
+func powerCommand(verb string, delay time.Duration) []string {
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return []string{
+		"systemd-run",
+		"--unit=nos-power-" + verb,
+		"--on-active=" + strconv.Itoa(int(delay/time.Second)) + "s",
+		"--timer-property=AccuracySec=1s",
+		"systemctl", verb,
+	}
+}
+
+func handleSchedulePower(w http.ResponseWriter, verb string, params map[string]interface{}) {
+	delay := time.Duration(0)
+	if v, ok := params["delay_seconds"].(float64); ok {
+		delay = time.Duration(v) * time.Second
+	}
+	if delay < 0 || delay > maxPowerDelay {
+		writeErr(w, http.StatusBadRequest, "delay_seconds out of range")
+		return
+	}
+	args := powerCommand(verb, delay)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+		msg := strings.TrimSpace(string(out))
+		if strings.Contains(msg, "already") {
+			writeErr(w, http.StatusConflict, fmt.Sprintf("%s already scheduled", verb))
+			return
+		}
+		writeErr(w, http.StatusInternalServerError, fmt.Sprintf("schedule %s failed: %s", verb, msg))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "result": "scheduled", "command": strings.Join(args, " ")})
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPowerCommand(t *testing.T) {
+	got := strings.Join(powerCommand("reboot", 90*time.Second), " ")
+	want := "systemd-run --unit=nos-power-reboot --on-active=90s --timer-property=AccuracySec=1s systemctl reboot"
+	if got != want {
+		t.Fatalf("got %q", got)
+	}
+	// immediate requests still go through a short timer
+	if got := powerCommand("poweroff", 0); got[2] != "--on-active=1s" || got[len(got)-1] != "poweroff" {
+		t.Fatalf("immediate poweroff: %v", got)
+	}
+}
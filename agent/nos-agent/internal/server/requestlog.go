@@ -0,0 +1,41 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is set by nosd on every agent call so agent log lines can
+// be matched to the originating API request.
+const RequestIDHeader = "X-Request-ID"
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// withRequestLog logs one line per request, tagged with the caller's request ID.
+func withRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reqID := r.Header.Get(RequestIDHeader)
+		if reqID != "" {
+			w.Header().Set(RequestIDHeader, reqID)
+		} else {
+			reqID = "-"
+		}
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r)
+		log.Printf("request_id=%s method=%s path=%s status=%d duration=%s", reqID, r.Method, r.URL.Path, sr.status, time.Since(start).Round(time.Millisecond))
+	})
+}
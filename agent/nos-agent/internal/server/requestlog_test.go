@@ -0,0 +1,33 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestLogIncludesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(prev)
+
+	h := withRequestLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/v1/snapshot/create", nil)
+	req.Header.Set(RequestIDHeader, "host/abc-000042")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	line := buf.String()
+	if !strings.Contains(line, "request_id=host/abc-000042") || !strings.Contains(line, "status=418") {
+		t.Fatalf("unexpected log line: %q", line)
+	}
+	if rr.Header().Get(RequestIDHeader) != "host/abc-000042" {
+		t.Fatal("request id not echoed")
+	}
+}
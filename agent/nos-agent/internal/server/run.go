@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -159,6 +160,17 @@ func allowedCommand(name string, args []string) bool {
 		return filepath.IsAbs(dst)
 	case "umount":
 		return len(args) == 1 && filepath.IsAbs(args[0])
+	case "apt-mark":
+		// apt-mark hold|unhold <package>...
+		if len(args) < 2 || (args[0] != "hold" && args[0] != "unhold") {
+			return false
+		}
+		for _, p := range args[1:] {
+			if !debPackageRe.MatchString(p) {
+				return false
+			}
+		}
+		return true
 	case "blkid":
 		// blkid -s UUID -o value <device>
 		if len(args) != 5 {
@@ -269,6 +281,8 @@ func allowedCommand(name string, args []string) bool {
 	}
 }
 
+var debPackageRe = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+
 func validDevice(p string) bool {
 	return p != "" && strings.HasPrefix(p, "/dev/") && !strings.ContainsAny(p, " \t\n\r\x00")
 }
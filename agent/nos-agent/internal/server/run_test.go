@@ -37,3 +37,17 @@ func TestAllowedCommandBalanceStatus(t *testing.T) {
 		t.Fatalf("should reject relative path")
 	}
 }
+
+func TestAllowedCommandAptMark(t *testing.T) {
+	if !allowedCommand("apt-mark", []string{"hold", "linux-image-amd64", "nosd"}) {
+		t.Fatalf("expected hold allowed")
+	}
+	if !allowedCommand("apt-mark", []string{"unhold", "linux-image-amd64"}) {
+		t.Fatalf("expected unhold allowed")
+	}
+	for _, args := range [][]string{{"hold"}, {"showhold"}, {"purge", "nosd"}, {"hold", "-o", "Dir=/tmp"}, {"hold", "Bad Name"}} {
+		if allowedCommand("apt-mark", args) {
+			t.Fatalf("should reject %v", args)
+		}
+	}
+}
@@ -58,6 +58,8 @@ func buildMux() http.Handler {
 	mux.HandleFunc("/v1/btrfs/snapshot", handleBtrfsSnapshot)
 	mux.HandleFunc("/v1/btrfs/balance/status", handleBtrfsBalanceStatus)
 	mux.HandleFunc("/v1/btrfs/replace/status", handleBtrfsReplaceStatus)
+	mux.HandleFunc("/v1/btrfs/device-stats", handleBtrfsDeviceStats)
+	mux.HandleFunc("/v1/btrfs/snapshot-space", handleBtrfsSnapshotSpace)
 	mux.HandleFunc("/v1/service/reload", handleServiceReload)
 	mux.HandleFunc("/v1/app/compose-up", handleComposeUp)
 	mux.HandleFunc("/v1/app/compose-down", handleComposeDown)
@@ -65,6 +67,7 @@ func buildMux() http.Handler {
 	mux.HandleFunc("/v1/firewall/apply", handleFirewallApply)
 	mux.HandleFunc("/v1/fs/write", handleFSWrite)
 	mux.HandleFunc("/v1/fs/mkdir", handleFSMkdir)
+	mux.HandleFunc("/v1/fs/remove", handleFSRemove)
 	mux.HandleFunc("/v1/run", handleRun)
 	mux.HandleFunc("/v1/fstab/ensure", handleFstabEnsure)
 	mux.HandleFunc("/v1/fstab/remove", handleFstabRemove)
@@ -86,7 +89,7 @@ func buildMux() http.Handler {
 	mux.HandleFunc("/v1/smart", handleSmartSummary)
 	// Prometheus metrics on the same unix socket
 	mux.Handle("/metrics", metricsHandler())
-	return mux
+	return withRequestLog(mux)
 }
 
 // Registration with nosd using bootstrap token on disk
@@ -352,10 +355,14 @@ func handleBtrfsSnapshot(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, http.StatusBadRequest, "missing fields")
 		return
 	}
+	// drain the body so the server watches the connection and cancels
+	// r.Context() if nosd hangs up
+	_, _ = io.Copy(io.Discard, r.Body)
 	snapDir := filepath.Join(req.Path, ".snapshots")
 	_ = os.MkdirAll(snapDir, 0o755)
 	target := filepath.Join(snapDir, req.Name)
-	cmd := exec.Command("btrfs", "subvolume", "snapshot", "-r", req.Path, target)
+	// tied to the request so a cancelled nosd job stops the command
+	cmd := exec.CommandContext(r.Context(), "btrfs", "subvolume", "snapshot", "-r", req.Path, target)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		writeErr(w, http.StatusInternalServerError, fmt.Sprintf("snapshot failed: %s", string(out)))
@@ -8,11 +8,15 @@ import (
 )
 
 type smartSummary struct {
-	Passed       *bool `json:"passed,omitempty"`
-	TemperatureC *int  `json:"temperature_c,omitempty"`
-	PowerOnHours *int  `json:"power_on_hours,omitempty"`
-	Reallocated  *int  `json:"reallocated,omitempty"`
-	MediaErrors  *int  `json:"media_errors,omitempty"`
+	Passed         *bool `json:"passed,omitempty"`
+	TemperatureC   *int  `json:"temperature_c,omitempty"`
+	PowerOnHours   *int  `json:"power_on_hours,omitempty"`
+	Reallocated    *int  `json:"reallocated,omitempty"`
+	PendingSectors *int  `json:"pending_sectors,omitempty"`
+	Uncorrectable  *int  `json:"offline_uncorrectable,omitempty"`
+	CRCErrors      *int  `json:"crc_errors,omitempty"`
+	MediaErrors    *int  `json:"media_errors,omitempty"`
+	PercentUsed    *int  `json:"percentage_used,omitempty"`
 }
 
 func handleSmartSummary(w http.ResponseWriter, r *http.Request) {
@@ -77,19 +81,35 @@ func parseSmartctlJSON(b []byte) smartSummary {
 			res.PowerOnHours = &v
 		}
 	}
-	// ATA attributes: find Reallocated_Sector_Ct (id 5)
+	// ATA attributes used for at-risk prediction, by id; names vary by vendor
+	// so they are only a fallback for output without ids
 	if ata, ok := m["ata_smart_attributes"].(map[string]any); ok {
 		if tbl, ok := ata["table"].([]any); ok {
 			for _, it := range tbl {
-				if row, ok := it.(map[string]any); ok {
-					if name, _ := row["name"].(string); strings.EqualFold(name, "Reallocated_Sector_Ct") {
-						if raw, ok := row["raw"].(map[string]any); ok {
-							if val, ok := raw["value"].(float64); ok {
-								v := int(val)
-								res.Reallocated = &v
-							}
-						}
-					}
+				row, ok := it.(map[string]any)
+				if !ok {
+					continue
+				}
+				raw, ok := row["raw"].(map[string]any)
+				if !ok {
+					continue
+				}
+				val, ok := raw["value"].(float64)
+				if !ok {
+					continue
+				}
+				v := int(val)
+				id, _ := row["id"].(float64)
+				name, _ := row["name"].(string)
+				switch {
+				case id == 5 || strings.EqualFold(name, "Reallocated_Sector_Ct"):
+					res.Reallocated = &v
+				case id == 197 || strings.EqualFold(name, "Current_Pending_Sector"):
+					res.PendingSectors = &v
+				case id == 198 || strings.EqualFold(name, "Offline_Uncorrectable"):
+					res.Uncorrectable = &v
+				case id == 199 || strings.EqualFold(name, "UDMA_CRC_Error_Count"):
+					res.CRCErrors = &v
 				}
 			}
 		}
@@ -100,6 +120,10 @@ func parseSmartctlJSON(b []byte) smartSummary {
 			v := int(me)
 			res.MediaErrors = &v
 		}
+		if pu, ok := nvme["percentage_used"].(float64); ok {
+			v := int(pu)
+			res.PercentUsed = &v
+		}
 		// Temperature may also be under this struct depending on drive
 		if res.TemperatureC == nil {
 			if tc, ok := nvme["temperature"].(float64); ok {
@@ -41,3 +41,24 @@ func TestParseSmartctlJSON_NVMe(t *testing.T) {
 		t.Fatalf("expected media_errors 1, got %+v", sum)
 	}
 }
+
+func TestParseSmartctlJSON_RiskAttributes(t *testing.T) {
+	b, err := os.ReadFile("testdata/smart_ata_failing.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	sum := parseSmartctlJSON(b)
+	for name, c := range map[string]struct {
+		got  *int
+		want int
+	}{
+		"reallocated":           {sum.Reallocated, 0},
+		"pending_sectors":       {sum.PendingSectors, 8},
+		"offline_uncorrectable": {sum.Uncorrectable, 2},
+		"crc_errors":            {sum.CRCErrors, 14},
+	} {
+		if c.got == nil || *c.got != c.want {
+			t.Fatalf("expected %s %d, got %+v", name, c.want, sum)
+		}
+	}
+}
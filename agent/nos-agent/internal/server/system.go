@@ -40,6 +40,12 @@ func handleExecute(w http.ResponseWriter, r *http.Request) {
 		handleSetNTP(w, req.Params)
 	case "system.network.configure", "network.interface.configure":
 		handleConfigureNetwork(w, req.Params)
+	case "network.dns.set":
+		handleSetDNS(w, req.Params)
+	case "system.power.reboot":
+		handleSchedulePower(w, "reboot", req.Params)
+	case "system.power.poweroff":
+		handleSchedulePower(w, "poweroff", req.Params)
 	default:
 		writeErr(w, http.StatusBadRequest, fmt.Sprintf("unknown action: %s", req.Action))
 	}
@@ -51,6 +57,18 @@ func handleSetHostname(w http.ResponseWriter, params map[string]interface{}) {
 		writeErr(w, http.StatusBadRequest, "hostname required")
 		return
 	}
+	if !validHostLabel(hostname) {
+		writeErr(w, http.StatusBadRequest, "invalid hostname")
+		return
+	}
+	fqdn := hostname
+	if domain, _ := params["domain"].(string); domain != "" {
+		if !validDomain(domain) || len(hostname)+1+len(domain) > 253 {
+			writeErr(w, http.StatusBadRequest, "invalid domain")
+			return
+		}
+		fqdn = hostname + "." + domain
+	}
 
 	// Set hostname using hostnamectl
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -67,23 +85,13 @@ func handleSetHostname(w http.ResponseWriter, params map[string]interface{}) {
 		_ = exec.Command("hostname", hostname).Run()
 	}
 
-	// Update /etc/hosts
-	hostsPath := "/etc/hosts"
-	hostsData, _ := os.ReadFile(hostsPath)
-	lines := strings.Split(string(hostsData), "\n")
-	newLines := []string{}
-
-	for _, line := range lines {
-		if strings.Contains(line, "127.0.1.1") {
-			newLines = append(newLines, fmt.Sprintf("127.0.1.1\t%s", hostname))
-		} else {
-			newLines = append(newLines, line)
-		}
+	// Point the 127.0.1.1 entry in /etc/hosts at the new name
+	if err := writeHostsFile(hostname, fqdn); err != nil {
+		writeErr(w, http.StatusInternalServerError, fmt.Sprintf("hostname set but /etc/hosts update failed: %v", err))
+		return
 	}
 
-	_ = os.WriteFile(hostsPath, []byte(strings.Join(newLines, "\n")), 0644)
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "result": "success"})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "result": "success", "fqdn": fqdn})
 }
 
 func handleSetTimezone(w http.ResponseWriter, params map[string]interface{}) {
@@ -168,6 +176,7 @@ func handleConfigureNetwork(w http.ResponseWriter, params map[string]interface{}
 	ipv4Address, _ := params["ipv4_address"].(string)
 	ipv4Gateway, _ := params["ipv4_gateway"].(string)
 	dnsServers, _ := params["dns"].([]interface{})
+	addrs := staticAddresses(ipv4Address, params["addresses"])
 
 	if iface == "" {
 		writeErr(w, http.StatusBadRequest, "interface name required")
@@ -189,9 +198,16 @@ func handleConfigureNetwork(w http.ResponseWriter, params map[string]interface{}
 			_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv4.dns", "").Run()
 		} else {
 			// Configure static IP
-			if ipv4Address != "" {
-				_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv4.method", "manual").Run()
-				_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv4.addresses", ipv4Address).Run()
+			if len(addrs) > 0 {
+				v4, v6 := splitAddressFamilies(addrs)
+				if len(v4) > 0 {
+					_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv4.method", "manual").Run()
+					_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv4.addresses", strings.Join(v4, ",")).Run()
+				}
+				if len(v6) > 0 {
+					_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv6.method", "manual").Run()
+					_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv6.addresses", strings.Join(v6, ",")).Run()
+				}
 
 				if ipv4Gateway != "" {
 					_ = exec.CommandContext(ctx, "nmcli", "con", "mod", iface, "ipv4.gateway", ipv4Gateway).Run()
@@ -231,8 +247,8 @@ func handleConfigureNetwork(w http.ResponseWriter, params map[string]interface{}
 		if dhcp {
 			config.WriteString("DHCP=yes\n")
 		} else {
-			if ipv4Address != "" {
-				config.WriteString(fmt.Sprintf("Address=%s\n", ipv4Address))
+			for _, a := range addrs {
+				config.WriteString(fmt.Sprintf("Address=%s\n", a))
 			}
 			if ipv4Gateway != "" {
 				config.WriteString(fmt.Sprintf("Gateway=%s\n", ipv4Gateway))
@@ -257,15 +273,17 @@ func handleConfigureNetwork(w http.ResponseWriter, params map[string]interface{}
 			_ = exec.CommandContext(ctx, "dhclient", iface).Run()
 		} else {
 			// Configure static IP using ip command
-			if ipv4Address != "" {
+			if len(addrs) > 0 {
 				// Bring interface down
 				_ = exec.CommandContext(ctx, "ip", "link", "set", iface, "down").Run()
 
 				// Remove existing addresses
 				_ = exec.CommandContext(ctx, "ip", "addr", "flush", "dev", iface).Run()
 
-				// Add new address
-				_ = exec.CommandContext(ctx, "ip", "addr", "add", ipv4Address, "dev", iface).Run()
+				// Add new addresses
+				for _, a := range addrs {
+					_ = exec.CommandContext(ctx, "ip", "addr", "add", a, "dev", iface).Run()
+				}
 
 				// Bring interface up
 				_ = exec.CommandContext(ctx, "ip", "link", "set", iface, "up").Run()
@@ -291,3 +309,38 @@ func handleConfigureNetwork(w http.ResponseWriter, params map[string]interface{}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "result": "success"})
 }
+
+// staticAddresses merges the primary IPv4 address with the extra
+// "addresses" list (IPv6 or secondary IPv4), dropping blanks and repeats.
+func staticAddresses(ipv4Address string, extra interface{}) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(a string) {
+		a = strings.TrimSpace(a)
+		if a != "" && !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	add(ipv4Address)
+	if list, ok := extra.([]interface{}); ok {
+		for _, v := range list {
+			if str, ok := v.(string); ok {
+				add(str)
+			}
+		}
+	}
+	return out
+}
+
+// splitAddressFamilies separates CIDR addresses into IPv4 and IPv6.
+func splitAddressFamilies(addrs []string) (v4, v6 []string) {
+	for _, a := range addrs {
+		if strings.Contains(a, ":") {
+			v6 = append(v6, a)
+		} else {
+			v4 = append(v4, a)
+		}
+	}
+	return v4, v6
+}
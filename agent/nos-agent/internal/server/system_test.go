@@ -0,0 +1,18 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStaticAddressesIncludesIPv6(t *testing.T) {
+	got := staticAddresses("192.0.2.10/24", []interface{}{"2001:db8::10/64", "192.0.2.10/24", "", "198.51.100.7/32"})
+	want := []string{"192.0.2.10/24", "2001:db8::10/64", "198.51.100.7/32"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("staticAddresses = %v, want %v", got, want)
+	}
+	v4, v6 := splitAddressFamilies(got)
+	if len(v4) != 2 || len(v6) != 1 || v6[0] != "2001:db8::10/64" {
+		t.Fatalf("split = %v / %v", v4, v6)
+	}
+}
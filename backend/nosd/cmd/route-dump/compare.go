@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// route is one entry of a route dump.
+type route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+func (r route) String() string { return r.Method + " " + r.Path }
+
+// loadRoutes reads a dump written by route-dump.
+func loadRoutes(path string) ([]route, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var routes []route
+	if err := json.Unmarshal(b, &routes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return routes, nil
+}
+
+// diffRoutes returns the routes in cur but not in old, and those in old
+// but not in cur, sorted. Dumps are compared as sets of method and path.
+func diffRoutes(old, cur []route) (added, removed []route) {
+	seen := func(routes []route) map[route]bool {
+		m := make(map[route]bool, len(routes))
+		for _, r := range routes {
+			m[r] = true
+		}
+		return m
+	}
+	oldSet, curSet := seen(old), seen(cur)
+	for r := range curSet {
+		if !oldSet[r] {
+			added = append(added, r)
+		}
+	}
+	for r := range oldSet {
+		if !curSet[r] {
+			removed = append(removed, r)
+		}
+	}
+	sortRoutes(added)
+	sortRoutes(removed)
+	return added, removed
+}
+
+func sortRoutes(routes []route) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+}
+
+// writeDiff prints added routes with "+" and removed ones with "-", then a
+// summary line.
+func writeDiff(w io.Writer, added, removed []route) {
+	for _, r := range added {
+		fmt.Fprintf(w, "+ %s\n", r)
+	}
+	for _, r := range removed {
+		fmt.Fprintf(w, "- %s\n", r)
+	}
+	fmt.Fprintf(w, "%d added, %d removed\n", len(added), len(removed))
+}
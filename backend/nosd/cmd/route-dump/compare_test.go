@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareRouteSets(t *testing.T) {
+	dump := filepath.Join(t.TempDir(), "routes.json")
+	// a previous dump, with a duplicate as route-dump can emit
+	if err := os.WriteFile(dump, []byte(`[
+		{"method":"GET","path":"/api/v1/pools"},
+		{"method":"GET","path":"/api/v1/pools"},
+		{"method":"POST","path":"/api/v1/pools/{id}/scrub"},
+		{"method":"GET","path":"/api/v1/shares"}
+	]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old, err := loadRoutes(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur := []route{
+		{"GET", "/api/v1/shares"},
+		{"GET", "/api/v1/pools"},
+		{"DELETE", "/api/v1/shares/{id}"},
+		{"GET", "/api/v1/apps"},
+	}
+
+	added, removed := diffRoutes(old, cur)
+	var out strings.Builder
+	writeDiff(&out, added, removed)
+	want := "+ GET /api/v1/apps\n" +
+		"+ DELETE /api/v1/shares/{id}\n" +
+		"- POST /api/v1/pools/{id}/scrub\n" +
+		"2 added, 1 removed\n"
+	if out.String() != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if added, removed := diffRoutes(cur, cur); len(added)+len(removed) != 0 {
+		t.Fatalf("same set: +%v -%v", added, removed)
+	}
+	if _, err := loadRoutes(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("loading a missing dump succeeded")
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
 
 	"nithronos/backend/nosd/internal/config"
 	"nithronos/backend/nosd/internal/server"
@@ -12,13 +14,39 @@ import (
 )
 
 func main() {
+	asOpenAPI := flag.Bool("openapi", false, "print the generated OpenAPI document instead of the route list")
+	compare := flag.String("compare", "", "compare with a previous dump `file`, print added and removed routes, and exit 1 if any were removed")
+	flag.Parse()
+
 	cfg := config.Defaults()
 	r := server.NewRouter(cfg).(*chi.Mux)
-	var routes []map[string]string
-	_ = chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
-		routes = append(routes, map[string]string{"method": method, "path": route})
+	if *asOpenAPI {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(server.OpenAPIDocument(r)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	var routes []route
+	_ = chi.Walk(r, func(method string, path string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, route{Method: method, Path: path})
 		return nil
 	})
+	if *compare != "" {
+		old, err := loadRoutes(*compare)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		added, removed := diffRoutes(old, routes)
+		writeDiff(os.Stdout, added, removed)
+		if len(removed) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 	b, _ := json.Marshal(routes)
 	fmt.Println(string(b))
 }
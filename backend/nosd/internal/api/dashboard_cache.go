@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DashboardCacheTTL is how long a computed dashboard response is served to
+// every client before it is computed again. Mutations that change what the
+// dashboard shows call InvalidateDashboardCache instead of waiting.
+var DashboardCacheTTL = 3 * time.Second
+
+// dashboardCache holds the last encoded response of each dashboard
+// endpoint. gen counts invalidations, so a response computed across one is
+// not stored.
+type dashboardCache struct {
+	mu      sync.Mutex
+	gen     uint64
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	body []byte
+	etag string
+	at   time.Time
+}
+
+var dashCache = &dashboardCache{entries: map[string]cachedResponse{}}
+
+// InvalidateDashboardCache drops every cached dashboard response, so the
+// next request recomputes it.
+func InvalidateDashboardCache() {
+	dashCache.mu.Lock()
+	defer dashCache.mu.Unlock()
+	dashCache.gen++
+	dashCache.entries = map[string]cachedResponse{}
+}
+
+// get returns the response cached under key, computing and storing it when
+// missing or older than DashboardCacheTTL.
+func (c *dashboardCache) get(key string, compute func() any) (cachedResponse, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	gen := c.gen
+	c.mu.Unlock()
+	if ok && time.Since(e.at) < DashboardCacheTTL {
+		return e, nil
+	}
+
+	body, err := json.Marshal(compute())
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	e = cachedResponse{body: body, etag: `"` + hex.EncodeToString(sum[:16]) + `"`, at: time.Now()}
+
+	c.mu.Lock()
+	if c.gen == gen {
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+	return e, nil
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// serveDashboard answers a dashboard GET from the cache, computing the
+// response with a timeout when needed. The body's hash is its ETag, so a
+// client whose copy is current gets 304 Not Modified even after the
+// response was recomputed.
+func serveDashboard(w http.ResponseWriter, r *http.Request, key string, timeout time.Duration, compute func(ctx context.Context) any) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	e, err := dashCache.get(key, func() any {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		return compute(ctx)
+	})
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	// no-cache lets browsers keep the body but revalidate it every time
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", e.etag)
+	if etagMatches(r.Header.Get("If-None-Match"), e.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(e.body)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func resetDashboardCache(t *testing.T) {
+	t.Helper()
+	InvalidateDashboardCache()
+	t.Cleanup(InvalidateDashboardCache)
+}
+
+func TestDashboardNotModified(t *testing.T) {
+	resetDashboardCache(t)
+	computed := 0
+	h := func(w http.ResponseWriter, r *http.Request) {
+		serveDashboard(w, r, "test", time.Second, func(ctx context.Context) any {
+			computed++
+			return map[string]int{"pools": 2}
+		})
+	}
+
+	res := httptest.NewRecorder()
+	h(res, httptest.NewRequest(http.MethodGet, "/api/v1/test", nil))
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || etag == "" || res.Body.String() != "{\"pools\":2}\n" {
+		t.Fatalf("first GET: %d etag %q body %q", res.Code, etag, res.Body.String())
+	}
+	if cc := res.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Fatalf("Cache-Control %q would stop clients from revalidating", cc)
+	}
+
+	for _, inm := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+		req.Header.Set("If-None-Match", inm)
+		res = httptest.NewRecorder()
+		h(res, req)
+		if res.Code != http.StatusNotModified || res.Body.Len() != 0 || res.Header().Get("ETag") != etag {
+			t.Fatalf("If-None-Match %s: %d body %q", inm, res.Code, res.Body.String())
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	res = httptest.NewRecorder()
+	h(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("stale ETag: %d", res.Code)
+	}
+	if computed != 1 {
+		t.Fatalf("computed %d times within the TTL, want 1", computed)
+	}
+
+	// an identical recomputed body keeps its ETag, so clients still get 304
+	InvalidateDashboardCache()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+	req.Header.Set("If-None-Match", etag)
+	res = httptest.NewRecorder()
+	h(res, req)
+	if res.Code != http.StatusNotModified || computed != 2 {
+		t.Fatalf("after recompute: %d, computed %d", res.Code, computed)
+	}
+}
+
+func TestDashboardCacheInvalidation(t *testing.T) {
+	resetDashboardCache(t)
+	pools := 1
+	get := func() (string, string) {
+		res := httptest.NewRecorder()
+		serveDashboard(res, httptest.NewRequest(http.MethodGet, "/", nil), "test", time.Second, func(ctx context.Context) any {
+			return map[string]int{"pools": pools}
+		})
+		return res.Header().Get("ETag"), res.Body.String()
+	}
+
+	etag1, _ := get()
+	pools = 2
+	if etag, body := get(); etag != etag1 || body != "{\"pools\":1}\n" {
+		t.Fatalf("cached response not served: %s %q", etag, body)
+	}
+	InvalidateDashboardCache()
+	if etag, body := get(); etag == etag1 || body != "{\"pools\":2}\n" {
+		t.Fatalf("after invalidation: %s %q", etag, body)
+	}
+
+	// a response computed while an invalidation happened is not kept
+	pools = 3
+	InvalidateDashboardCache()
+	res := httptest.NewRecorder()
+	serveDashboard(res, httptest.NewRequest(http.MethodGet, "/", nil), "test", time.Second, func(ctx context.Context) any {
+		InvalidateDashboardCache()
+		return map[string]int{"pools": 3}
+	})
+	pools = 4
+	if _, body := get(); body != "{\"pools\":4}\n" {
+		t.Fatalf("response computed across an invalidation was cached: %q", body)
+	}
+
+	// entries expire after the TTL
+	old := DashboardCacheTTL
+	DashboardCacheTTL = 0
+	t.Cleanup(func() { DashboardCacheTTL = old })
+	pools = 5
+	if _, body := get(); body != "{\"pools\":5}\n" {
+		t.Fatalf("expired entry served: %q", body)
+	}
+}
@@ -100,30 +100,18 @@ type EventInfo struct {
 
 // HandleDashboard returns aggregated dashboard data
 func HandleDashboard(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Use 300ms timeout to allow for proper CPU measurement
-	ctx, cancel := context.WithTimeout(r.Context(), 300*time.Millisecond)
-	defer cancel()
-
-	response := DashboardResponse{
-		System:      getSystemSummary(ctx),
-		Storage:     getStorageSummary(ctx),
-		Disks:       getDisksSummary(ctx),
-		Shares:      getShares(ctx),
-		Apps:        getInstalledApps(ctx),
-		Maintenance: getMaintenanceStatus(ctx),
-		Events:      getRecentEvents(ctx),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-	}
+	serveDashboard(w, r, "dashboard", 300*time.Millisecond, func(ctx context.Context) any {
+		return DashboardResponse{
+			System:      getSystemSummary(ctx),
+			Storage:     getStorageSummary(ctx),
+			Disks:       getDisksSummary(ctx),
+			Shares:      getShares(ctx),
+			Apps:        getInstalledApps(ctx),
+			Maintenance: getMaintenanceStatus(ctx),
+			Events:      getRecentEvents(ctx),
+		}
+	})
 }
 
 func getSystemSummary(ctx context.Context) SystemSummary {
@@ -387,68 +375,21 @@ func getStringField(m map[string]any, field string) string {
 
 // HandleStorageSummary returns storage summary
 func HandleStorageSummary(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
-	defer cancel()
-
-	summary := getStorageSummary(ctx)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(summary); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-	}
+	serveDashboard(w, r, "storage", 100*time.Millisecond, func(ctx context.Context) any {
+		return getStorageSummary(ctx)
+	})
 }
 
 // HandleDisksSummary returns disks summary
 func HandleDisksSummary(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
-	defer cancel()
-
-	summary := getDisksSummary(ctx)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(summary); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-	}
+	serveDashboard(w, r, "disks", 100*time.Millisecond, func(ctx context.Context) any {
+		return getDisksSummary(ctx)
+	})
 }
 
 // HandleRecentEvents returns recent events
 func HandleRecentEvents(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
-	defer cancel()
-
-	events := getRecentEvents(ctx)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(events); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-	}
-}
-
-// HandleMaintenanceStatus returns maintenance status
-func HandleMaintenanceStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
-	defer cancel()
-
-	status := getMaintenanceStatus(ctx)
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(status); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-	}
+	serveDashboard(w, r, "events", 100*time.Millisecond, func(ctx context.Context) any {
+		return getRecentEvents(ctx)
+	})
 }
@@ -6,17 +6,30 @@ import (
 )
 
 type App struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Status string `json:"status"`
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Status string  `json:"status"`
+	Params []Param `json:"params,omitempty"`
 }
 
+var mediaPathParam = Param{Name: "media_path", Env: "MEDIA_PATH", Type: ParamPath, Default: "/srv/media"}
+
 var defaultApps = []App{
-	{ID: "plex", Name: "Plex"},
-	{ID: "jellyfin", Name: "Jellyfin"},
-	{ID: "qbittorrent", Name: "qBittorrent"},
-	{ID: "nextcloud", Name: "Nextcloud"},
-	{ID: "immich", Name: "Immich"},
+	{ID: "plex", Name: "Plex", Params: []Param{
+		mediaPathParam,
+		{Name: "claim_token", Env: "PLEX_CLAIM", Type: ParamString},
+	}},
+	{ID: "jellyfin", Name: "Jellyfin", Params: []Param{mediaPathParam}},
+	{ID: "qbittorrent", Name: "qBittorrent", Params: []Param{
+		{Name: "web_port", Env: "WEBUI_PORT", Type: ParamPort, Default: 8080},
+		{Name: "downloads_path", Env: "DOWNLOADS_PATH", Type: ParamPath, Default: "/srv/downloads"},
+	}},
+	{ID: "nextcloud", Name: "Nextcloud", Params: []Param{
+		{Name: "https_port", Env: "HTTPS_PORT", Type: ParamPort, Default: 8081},
+	}},
+	{ID: "immich", Name: "Immich", Params: []Param{
+		{Name: "port", Env: "IMMICH_PORT", Type: ParamPort, Default: 2283},
+	}},
 }
 
 func Catalog(installDir string) []App {
@@ -45,7 +58,7 @@ func ComposeTemplate(id string) string {
     volumes:
       - ./config:/config
       - ./cache:/cache
-      - /srv/media:/media:ro
+      - ${MEDIA_PATH:-/srv/media}:/media:ro
 `
 	case "plex":
 		return `services:
@@ -56,9 +69,10 @@ func ComposeTemplate(id string) string {
     environment:
       - PUID=1000
       - PGID=1000
+      - PLEX_CLAIM=${PLEX_CLAIM:-}
     volumes:
       - ./config:/config
-      - /srv/media:/media:ro
+      - ${MEDIA_PATH:-/srv/media}:/media:ro
 `
 	case "qbittorrent":
 		return `services:
@@ -66,13 +80,13 @@ func ComposeTemplate(id string) string {
     image: lscr.io/linuxserver/qbittorrent:latest
     restart: unless-stopped
     ports:
-      - "8080:8080"
+      - "${WEBUI_PORT:-8080}:8080"
     environment:
       - PUID=1000
       - PGID=1000
     volumes:
       - ./config:/config
-      - /srv/downloads:/downloads
+      - ${DOWNLOADS_PATH:-/srv/downloads}:/downloads
 `
 	case "nextcloud":
 		return `services:
@@ -80,7 +94,7 @@ func ComposeTemplate(id string) string {
     image: lscr.io/linuxserver/nextcloud:latest
     restart: unless-stopped
     ports:
-      - "8081:443"
+      - "${HTTPS_PORT:-8081}:443"
     environment:
       - PUID=1000
       - PGID=1000
@@ -94,7 +108,7 @@ func ComposeTemplate(id string) string {
     image: ghcr.io/immich-app/immich-server:release
     restart: unless-stopped
     ports:
-      - "2283:2283"
+      - "${IMMICH_PORT:-2283}:2283"
     volumes:
       - ./library:/usr/src/app/upload
 `
@@ -0,0 +1,112 @@
+package apps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeError explains why a compose file was rejected.
+type ComposeError struct {
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ComposeError) Error() string {
+	if e.Service != "" {
+		return "compose: service " + e.Service + ": " + e.Message
+	}
+	return "compose: " + e.Message
+}
+
+// ValidateCompose checks that compose, after ${VAR} expansion from env, is a
+// usable compose document: valid YAML with a non-empty services mapping,
+// every service naming an image or build, and list-valued keys being lists.
+func ValidateCompose(compose string, env map[string]string) error {
+	expanded := expandCompose(compose, env)
+	if i := strings.Index(expanded, "${"); i >= 0 {
+		line := strings.Count(expanded[:i], "\n") + 1
+		return &ComposeError{Message: fmt.Sprintf("line %d: unterminated variable reference", line)}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &doc); err != nil {
+		return &ComposeError{Message: "invalid YAML: " + strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return &ComposeError{Message: "document is not a mapping"}
+	}
+	services := mappingValue(doc.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode || len(services.Content) == 0 {
+		return &ComposeError{Message: "no services defined"}
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name, svc := services.Content[i].Value, services.Content[i+1]
+		if svc.Kind != yaml.MappingNode {
+			return &ComposeError{Service: name, Message: "definition is not a mapping"}
+		}
+		if mappingValue(svc, "image") == nil && mappingValue(svc, "build") == nil {
+			return &ComposeError{Service: name, Message: "neither image nor build is set"}
+		}
+		for _, key := range []string{"ports", "volumes"} {
+			if v := mappingValue(svc, key); v != nil && v.Kind != yaml.SequenceNode {
+				return &ComposeError{Service: name, Message: key + " must be a list"}
+			}
+		}
+		if v := mappingValue(svc, "environment"); v != nil && v.Kind != yaml.SequenceNode && v.Kind != yaml.MappingNode {
+			return &ComposeError{Service: name, Message: "environment must be a list or mapping"}
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// DockerComposeConfig runs `docker compose config -q` against compose and its
+// env in a scratch directory, catching schema errors ValidateCompose does not
+// model. It is a no-op when the docker CLI is not installed.
+func DockerComposeConfig(ctx context.Context, compose string, env map[string]string) error {
+	docker, err := exec.LookPath("docker")
+	if err != nil {
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "nos-compose-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(compose), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(EnvFile(env)), 0o600); err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, docker, "compose", "--project-directory", dir, "config", "-q")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "is not a docker command") {
+			// docker without the compose plugin; nothing to check with
+			return nil
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return &ComposeError{Message: msg}
+	}
+	return nil
+}
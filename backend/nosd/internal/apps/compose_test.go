@@ -0,0 +1,48 @@
+package apps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateComposeTemplates(t *testing.T) {
+	for _, a := range defaultApps {
+		if err := ValidateCompose(ComposeTemplate(a.ID), nil); err != nil {
+			t.Errorf("%s: %v", a.ID, err)
+		}
+	}
+}
+
+func TestValidateComposeRejectsBrokenTemplates(t *testing.T) {
+	cases := map[string]struct {
+		compose string
+		want    string
+	}{
+		"bad indentation": {"services:\n  web:\n    image: nginx\n   ports:\n      - 80:80\n", "invalid YAML"},
+		"tab indent":      {"services:\n\tweb:\n\t\timage: nginx\n", "invalid YAML"},
+		"no services":     {"version: '3'\n", "no services defined"},
+		"empty services":  {"services: {}\n", "no services defined"},
+		"no image":        {"services:\n  web:\n    restart: always\n", "neither image nor build"},
+		"scalar ports":    {"services:\n  web:\n    image: nginx\n    ports: 80:80\n", "ports must be a list"},
+		"open variable":   {"services:\n  web:\n    image: nginx\n    volumes:\n      - ${DATA:/data\n", "line 5: unterminated variable"},
+	}
+	for name, c := range cases {
+		err := ValidateCompose(c.compose, nil)
+		var ce *ComposeError
+		if !errors.As(err, &ce) || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected %q, got %v", name, c.want, err)
+		}
+	}
+}
+
+func TestValidateComposeExpandsParams(t *testing.T) {
+	// a param that breaks the YAML only after substitution
+	compose := "services:\n  web:\n    image: nginx\n    ports:\n      - ${PORT:-80}:80\n"
+	if err := ValidateCompose(compose, map[string]string{"PORT": "8080"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCompose(compose, map[string]string{"PORT": "[8080"}); err == nil {
+		t.Fatal("expected expanded compose to be rejected")
+	}
+}
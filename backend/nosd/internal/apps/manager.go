@@ -303,7 +303,17 @@ func (m *Manager) ForceHealthCheck(ctx context.Context, appID string) error {
 	return m.healthMonitor.ForceCheck(ctx, appID)
 }
 
+// GetHealthHistory returns an app's recorded health transitions
+func (m *Manager) GetHealthHistory(appID string) ([]apps.HealthTransition, error) {
+	return m.stateStore.GetHealthHistory(appID)
+}
+
 // SyncCatalogs manually triggers catalog sync
 func (m *Manager) SyncCatalogs() error {
 	return m.catalogMgr.SyncRemoteCatalogs()
 }
+
+// CatalogSources returns per-source verification status from the last sync
+func (m *Manager) CatalogSources() []apps.CatalogSourceStatus {
+	return m.catalogMgr.SourceStatuses()
+}
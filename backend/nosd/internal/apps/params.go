@@ -0,0 +1,160 @@
+package apps
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parameter types understood by ValidateParams.
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamBool   = "bool"
+	ParamPort   = "port"
+	ParamPath   = "path" // absolute host path
+	ParamEnum   = "enum"
+)
+
+// Param declares one install-time setting of a built-in app. Validated
+// values are written to the app's .env file under Env, which the compose
+// template references with a default.
+type Param struct {
+	Name     string   `json:"name"`
+	Env      string   `json:"env"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Min      int      `json:"min,omitempty"`
+	Max      int      `json:"max,omitempty"`
+	Default  any      `json:"default,omitempty"`
+}
+
+// ParamError is a field-level validation failure.
+type ParamError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Lookup returns the built-in catalog entry for id.
+func Lookup(id string) (App, bool) {
+	for _, a := range defaultApps {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return App{}, false
+}
+
+// ValidateParams checks config against the declared params and returns the
+// resulting environment (Env name -> value) or the list of field errors.
+// Unknown keys are rejected.
+func ValidateParams(params []Param, config map[string]any) (map[string]string, []ParamError) {
+	env := map[string]string{}
+	var errs []ParamError
+	known := map[string]bool{}
+	for _, p := range params {
+		known[p.Name] = true
+		v, ok := config[p.Name]
+		if !ok || v == nil {
+			if p.Required {
+				errs = append(errs, ParamError{p.Name, "is required"})
+			}
+			continue
+		}
+		s, msg := p.check(v)
+		if msg != "" {
+			errs = append(errs, ParamError{p.Name, msg})
+			continue
+		}
+		env[p.Env] = s
+	}
+	var unknown []string
+	for k := range config {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		errs = append(errs, ParamError{k, "unknown parameter"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return env, nil
+}
+
+// check validates v and renders it for the .env file.
+func (p Param) check(v any) (string, string) {
+	switch p.Type {
+	case ParamInt, ParamPort:
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return "", "must be an integer"
+		}
+		lo, hi := p.Min, p.Max
+		if p.Type == ParamPort {
+			if lo == 0 {
+				lo = 1
+			}
+			if hi == 0 {
+				hi = 65535
+			}
+		}
+		n := int(f)
+		if (lo != 0 || hi != 0) && (n < lo || n > hi) {
+			return "", fmt.Sprintf("must be between %d and %d", lo, hi)
+		}
+		return strconv.Itoa(n), ""
+	case ParamBool:
+		b, ok := v.(bool)
+		if !ok {
+			return "", "must be a boolean"
+		}
+		return strconv.FormatBool(b), ""
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", "must be a string"
+	}
+	if strings.ContainsAny(s, "'\r\n\x00") {
+		return "", "must not contain quotes or control characters"
+	}
+	switch p.Type {
+	case ParamEnum:
+		for _, e := range p.Enum {
+			if s == e {
+				return s, ""
+			}
+		}
+		return "", "must be one of " + strings.Join(p.Enum, ", ")
+	case ParamPath:
+		if !path.IsAbs(s) || path.Clean(s) != s {
+			return "", "must be a clean absolute path"
+		}
+	}
+	if p.Required && s == "" {
+		return "", "is required"
+	}
+	return s, ""
+}
+
+// EnvFile renders env as a compose .env file with stable ordering. Values
+// are single-quoted so compose does not interpolate them.
+func EnvFile(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "='" + env[k] + "'\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package apps
+
+import (
+	"testing"
+)
+
+func TestValidateParams(t *testing.T) {
+	params := []Param{
+		{Name: "admin_user", Env: "ADMIN_USER", Type: ParamString, Required: true},
+		{Name: "port", Env: "PORT", Type: ParamPort},
+		{Name: "mode", Env: "MODE", Type: ParamEnum, Enum: []string{"fast", "safe"}},
+		{Name: "data", Env: "DATA_PATH", Type: ParamPath},
+		{Name: "debug", Env: "DEBUG", Type: ParamBool},
+	}
+
+	env, errs := ValidateParams(params, map[string]any{"admin_user": "nos", "port": float64(8443), "mode": "safe", "data": "/srv/data", "debug": true})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	if got := EnvFile(env); got != "ADMIN_USER='nos'\nDATA_PATH='/srv/data'\nDEBUG='true'\nMODE='safe'\nPORT='8443'\n" {
+		t.Fatalf("env file:\n%s", got)
+	}
+
+	_, errs = ValidateParams(params, map[string]any{"port": float64(70000), "mode": "turbo", "data": "../etc", "extra": 1, "debug": "yes"})
+	want := map[string]string{
+		"admin_user": "is required",
+		"port":       "must be between 1 and 65535",
+		"mode":       "must be one of fast, safe",
+		"data":       "must be a clean absolute path",
+		"debug":      "must be a boolean",
+		"extra":      "unknown parameter",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("errors: %+v", errs)
+	}
+	for _, e := range errs {
+		if want[e.Field] != e.Message {
+			t.Errorf("%s: got %q want %q", e.Field, e.Message, want[e.Field])
+		}
+	}
+
+	if _, errs := ValidateParams(params, map[string]any{"admin_user": "x'\nEVIL=1"}); len(errs) != 1 {
+		t.Fatalf("env injection accepted: %+v", errs)
+	}
+	if _, errs := ValidateParams(params, map[string]any{"admin_user": "x", "port": 80.5}); len(errs) != 1 || errs[0].Message != "must be an integer" {
+		t.Fatalf("fractional port: %+v", errs)
+	}
+}
@@ -0,0 +1,179 @@
+package apps
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OwnerSystem marks a port held by a listening socket outside the app set.
+const OwnerSystem = "system"
+
+// PortConflict reports a requested host port that is already taken.
+type PortConflict struct {
+	Port      int    `json:"port"`
+	Owner     string `json:"owner"`
+	Suggested int    `json:"suggested_port,omitempty"`
+}
+
+var composeVar = regexp.MustCompile(`\$\{(\w+)(?::?-([^}]*))?\}`)
+
+// expandCompose substitutes ${VAR} and ${VAR:-default} from env.
+func expandCompose(compose string, env map[string]string) string {
+	return composeVar.ReplaceAllStringFunc(compose, func(m string) string {
+		sub := composeVar.FindStringSubmatch(m)
+		if v, ok := env[sub[1]]; ok && v != "" {
+			return v
+		}
+		return sub[2]
+	})
+}
+
+// ComposeHostPorts lists the host ports published by a compose file after
+// variable expansion. Both short ("[ip:]host:container[/proto]") and long
+// ({published: N}) syntax are understood.
+func ComposeHostPorts(compose string, env map[string]string) []int {
+	var doc struct {
+		Services map[string]struct {
+			Ports []yaml.Node `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if yaml.Unmarshal([]byte(expandCompose(compose, env)), &doc) != nil {
+		return nil
+	}
+	seen := map[int]bool{}
+	var out []int
+	for _, svc := range doc.Services {
+		for _, n := range svc.Ports {
+			p := 0
+			switch n.Kind {
+			case yaml.ScalarNode:
+				spec := strings.SplitN(n.Value, "/", 2)[0]
+				parts := strings.Split(spec, ":")
+				if len(parts) >= 2 {
+					p, _ = strconv.Atoi(parts[len(parts)-2])
+				}
+			case yaml.MappingNode:
+				var long struct {
+					Published string `yaml:"published"`
+				}
+				if n.Decode(&long) == nil {
+					p, _ = strconv.Atoi(long.Published)
+				}
+			}
+			if p > 0 && !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// ReadEnvFile parses an app .env written by EnvFile.
+func ReadEnvFile(path string) map[string]string {
+	env := map[string]string{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return env
+	}
+	for _, ln := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(ln, "=")
+		if !ok || strings.HasPrefix(strings.TrimSpace(k), "#") {
+			continue
+		}
+		env[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `'"`)
+	}
+	return env
+}
+
+// InstalledPorts maps host ports published by installed apps to their id.
+func InstalledPorts(installDir string) map[int]string {
+	out := map[int]string{}
+	entries, err := os.ReadDir(installDir)
+	if err != nil {
+		return out
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(installDir, e.Name())
+		b, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+		if err != nil {
+			continue
+		}
+		for _, p := range ComposeHostPorts(string(b), ReadEnvFile(filepath.Join(dir, ".env"))) {
+			out[p] = e.Name()
+		}
+	}
+	return out
+}
+
+// ListeningPorts returns local ports in LISTEN state from /proc/net/tcp-style
+// tables.
+func ListeningPorts(paths ...string) map[int]bool {
+	out := map[int]bool{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for i, ln := range strings.Split(string(b), "\n") {
+			f := strings.Fields(ln)
+			if i == 0 || len(f) < 4 || f[3] != "0A" {
+				continue
+			}
+			idx := strings.LastIndexByte(f[1], ':')
+			if idx < 0 {
+				continue
+			}
+			if p, err := strconv.ParseUint(f[1][idx+1:], 16, 16); err == nil {
+				out[int(p)] = true
+			}
+		}
+	}
+	return out
+}
+
+// CheckPorts reports requested ports already owned by another app or a
+// listening socket. Ports the app itself already publishes (reinstall) are
+// not checked against sockets. Each conflict carries a free suggestion.
+func CheckPorts(id string, requested []int, installed map[int]string, listening map[int]bool) []PortConflict {
+	taken := func(p int) string {
+		if owner, ok := installed[p]; ok && owner != id {
+			return owner
+		}
+		if listening[p] && installed[p] != id {
+			return OwnerSystem
+		}
+		return ""
+	}
+	var out []PortConflict
+	claimed := map[int]bool{}
+	for _, p := range requested {
+		claimed[p] = true
+	}
+	for _, p := range requested {
+		owner := taken(p)
+		if owner == "" {
+			continue
+		}
+		c := PortConflict{Port: p, Owner: owner}
+		for s := p + 1; s <= 65535; s++ {
+			if taken(s) == "" && !claimed[s] {
+				c.Suggested = s
+				claimed[s] = true
+				break
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package apps
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComposeHostPorts(t *testing.T) {
+	compose := `services:
+  web:
+    ports:
+      - "${WEB_PORT:-8080}:80"
+      - 127.0.0.1:9443:443/tcp
+      - target: 53
+        published: "5353"
+        protocol: udp
+  worker:
+    ports:
+      - "8080:8080"
+`
+	if got := ComposeHostPorts(compose, nil); !reflect.DeepEqual(got, []int{5353, 8080, 9443}) {
+		t.Fatalf("defaults: %v", got)
+	}
+	if got := ComposeHostPorts(compose, map[string]string{"WEB_PORT": "8090"}); !reflect.DeepEqual(got, []int{5353, 8080, 8090, 9443}) {
+		t.Fatalf("env: %v", got)
+	}
+}
+
+func TestCheckPortsSuggestsFreePort(t *testing.T) {
+	installed := map[int]string{8080: "qbittorrent", 8081: "nextcloud"}
+	listening := map[int]bool{8082: true, 22: true}
+	got := CheckPorts("myapp", []int{8080, 22, 9000}, installed, listening)
+	want := []PortConflict{
+		{Port: 8080, Owner: "qbittorrent", Suggested: 8083},
+		{Port: 22, Owner: OwnerSystem, Suggested: 23},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v", got)
+	}
+	// an app reinstalling onto its own port is not a conflict
+	if got := CheckPorts("qbittorrent", []int{8080}, installed, map[int]bool{8080: true}); len(got) != 0 {
+		t.Fatalf("reinstall: %+v", got)
+	}
+}
+
+func TestListeningPorts(t *testing.T) {
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0016 0100007F:9C40 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 20 4 30 10 -1
+`
+	path := filepath.Join(t.TempDir(), "tcp")
+	if err := os.WriteFile(path, []byte(table), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := ListeningPorts(path); !reflect.DeepEqual(got, map[int]bool{8080: true}) {
+		t.Fatalf("got %v", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -23,21 +24,81 @@ const (
 	phcVersion            = 19
 )
 
-// HashPassword derives an Argon2id hash and returns a PHC-formatted string:
+// Params are the Argon2id cost parameters used for new hashes.
+type Params struct {
+	Time    uint32 // iterations
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+// DefaultParams returns the built-in Argon2id parameters.
+func DefaultParams() Params {
+	return Params{Time: defaultTime, Memory: defaultMemory, Threads: defaultThreads}
+}
+
+var (
+	paramsMu sync.RWMutex
+	current  = DefaultParams()
+)
+
+// SetParams changes the parameters used by HashPassword. Zero fields keep
+// their default value.
+func SetParams(p Params) {
+	d := DefaultParams()
+	if p.Time == 0 {
+		p.Time = d.Time
+	}
+	if p.Memory == 0 {
+		p.Memory = d.Memory
+	}
+	if p.Threads == 0 {
+		p.Threads = d.Threads
+	}
+	paramsMu.Lock()
+	current = p
+	paramsMu.Unlock()
+}
+
+// CurrentParams returns the parameters used by HashPassword.
+func CurrentParams() Params {
+	paramsMu.RLock()
+	defer paramsMu.RUnlock()
+	return current
+}
+
+// HashPassword derives an Argon2id hash with the current parameters and
+// returns a PHC-formatted string:
 // $argon2id$v=19$m=65536,t=3,p=1$<saltB64>$<hashB64>
 func HashPassword(plain string) (string, error) {
+	return HashPasswordWithParams(plain, CurrentParams())
+}
+
+// HashPasswordWithParams is HashPassword with explicit parameters.
+func HashPasswordWithParams(plain string, p Params) (string, error) {
 	salt := make([]byte, defaultSaltLen)
 	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	sum := argon2.IDKey([]byte(plain), salt, defaultTime, defaultMemory, defaultThreads, defaultKeyLen)
+	sum := argon2.IDKey([]byte(plain), salt, p.Time, p.Memory, p.Threads, defaultKeyLen)
 	// PHC with unpadded base64 (RawStdEncoding)
-	p := fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		phcAlg, phcVersion, defaultMemory, defaultTime, defaultThreads,
+	phc := fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		phcAlg, phcVersion, p.Memory, p.Time, p.Threads,
 		base64.RawStdEncoding.EncodeToString(salt),
 		base64.RawStdEncoding.EncodeToString(sum),
 	)
-	return p, nil
+	return phc, nil
+}
+
+// NeedsRehash reports whether phc was produced with weaker parameters than
+// the current ones (or a shorter key), so it should be re-hashed after the
+// next successful verification. Unparseable hashes report false.
+func NeedsRehash(phc string) bool {
+	pp, _, sum, err := parsePHC(phc)
+	if err != nil {
+		return false
+	}
+	cur := CurrentParams()
+	return pp.time < cur.Time || pp.memory < cur.Memory || pp.threads < cur.Threads || uint32(len(sum)) < defaultKeyLen
 }
 
 // VerifyPassword parses the PHC string and verifies the supplied plain text.
@@ -55,3 +55,34 @@ func TestPHCParsing(t *testing.T) {
 		t.Fatalf("decoded lengths wrong: salt=%d hash=%d", len(s), len(h))
 	}
 }
+
+func TestNeedsRehash(t *testing.T) {
+	defer SetParams(DefaultParams())
+	weak, err := HashPasswordWithParams("pw", Params{Time: 1, Memory: 8 * 1024, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsRehash(weak) {
+		t.Fatal("weak hash should need rehash")
+	}
+	if !VerifyPassword(weak, "pw") {
+		t.Fatal("weak hash should still verify")
+	}
+	cur, _ := HashPassword("pw")
+	if NeedsRehash(cur) {
+		t.Fatal("current hash should not need rehash")
+	}
+
+	// Raising the cost makes existing hashes stale; lowering it does not
+	SetParams(Params{Time: defaultTime + 1})
+	if !NeedsRehash(cur) {
+		t.Fatal("hash should need rehash after cost increase")
+	}
+	SetParams(Params{Time: 1, Memory: 8 * 1024, Threads: 1})
+	if NeedsRehash(cur) {
+		t.Fatal("stronger hash should not be downgraded")
+	}
+	if NeedsRehash("not-a-phc") {
+		t.Fatal("invalid hash should not report rehash")
+	}
+}
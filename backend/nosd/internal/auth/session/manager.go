@@ -102,30 +102,46 @@ func (m *Manager) Create(uid, ua, ip string, ttl time.Duration) (Record, error)
 }
 
 func (m *Manager) Verify(sid, ua, ip string) (string, bool) {
+	uid, b, ok := m.Check(sid, ua, ip)
+	if !ok || b.Changed() {
+		return "", false
+	}
+	return uid, true
+}
+
+// Binding reports which bound attributes of a session differ from the
+// current request.
+type Binding struct {
+	IPChanged bool
+	UAChanged bool
+}
+
+func (b Binding) Changed() bool { return b.IPChanged || b.UAChanged }
+
+// Check looks up a live session and compares its IP prefix and UA
+// fingerprint with the request. ok is false for unknown or expired sessions.
+// Last-seen is only updated when the binding still matches.
+func (m *Manager) Check(sid, ua, ip string) (uid string, b Binding, ok bool) {
 	m.mu.Lock()
+	defer m.mu.Unlock()
 	rec, ok := m.sidToRec[sid]
 	if !ok {
-		m.mu.Unlock()
-		return "", false
+		return "", Binding{}, false
 	}
 	if t, err := time.Parse(time.RFC3339, rec.Exp); err != nil || time.Now().UTC().After(t) {
-		m.mu.Unlock()
-		return "", false
+		return "", Binding{}, false
 	}
-	if rec.UAHash != sha256Hex(ua) {
-		m.mu.Unlock()
-		return "", false
+	b = Binding{
+		IPChanged: rec.IPHash != sha256Hex(maskIP(ip)),
+		UAChanged: rec.UAHash != sha256Hex(ua),
 	}
-	if rec.IPHash != sha256Hex(maskIP(ip)) {
-		m.mu.Unlock()
-		return "", false
+	if !b.Changed() {
+		// update last seen and persist (best-effort)
+		rec.LastSeenAt = time.Now().UTC().Format(time.RFC3339)
+		m.sidToRec[sid] = rec
+		_ = m.persistLocked()
 	}
-	// update last seen and persist (best-effort)
-	rec.LastSeenAt = time.Now().UTC().Format(time.RFC3339)
-	m.sidToRec[sid] = rec
-	_ = m.persistLocked()
-	m.mu.Unlock()
-	return rec.UID, true
+	return rec.UID, b, true
 }
 
 func (m *Manager) RevokeSID(sid string) error {
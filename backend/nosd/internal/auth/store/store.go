@@ -7,6 +7,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,19 @@ type User struct {
 	LastLoginAt    string   `json:"last_login_at"`
 	FailedAttempts int      `json:"failed_attempts"`
 	LockedUntil    string   `json:"locked_until"`
+	// PasswordChangedAt is when the password was last set (RFC3339); empty
+	// for users created before it was tracked, in which case CreatedAt applies.
+	PasswordChangedAt   string `json:"password_changed_at,omitempty"`
+	ForcePasswordChange bool   `json:"force_password_change,omitempty"`
+	// TOTPEnabled is set once the secret in TOTPEnc has been confirmed with
+	// a valid code; login then requires a TOTP or recovery code.
+	TOTPEnabled bool `json:"totp_enabled,omitempty"`
+	// TOTPPendingEnc holds a re-enrolled secret until /auth/totp/verify
+	// confirms it; TOTPEnc and the recovery codes stay in force meanwhile.
+	TOTPPendingEnc string `json:"totp_pending_enc,omitempty"`
+	// Email is unique across users, compared case-insensitively.
+	Email       string `json:"email,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
 }
 
 type dbFile struct {
@@ -34,6 +48,7 @@ type dbFile struct {
 
 var (
 	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email already in use")
 )
 
 type Store struct {
@@ -49,9 +64,44 @@ func New(path string) (*Store, error) {
 		// Start empty on missing/invalid file to avoid panics in early flows/tests
 		s.users = map[string]User{}
 	}
+	if s.migrateEmails() {
+		// best-effort: the migration is redone on every load until it sticks
+		_ = s.writeUsers(s.snapshot())
+	}
 	return s, nil
 }
 
+// migrateEmails fills Email for records written before it existed. The old
+// users API stored the email address as the username, so usernames that
+// look like one are copied over unless another user already holds it.
+func (s *Store) migrateEmails() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for name, u := range s.users {
+		if u.Email != "" || !strings.Contains(name, "@") {
+			continue
+		}
+		if _, taken := s.findByEmailLocked(name); taken {
+			continue
+		}
+		u.Email = name
+		s.users[name] = u
+		changed = true
+	}
+	return changed
+}
+
+func (s *Store) snapshot() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	return list
+}
+
 func (s *Store) load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -114,6 +164,29 @@ func (s *Store) FindByUsername(username string) (User, error) {
 	return u, nil
 }
 
+// FindByEmail looks a user up by email, ignoring case.
+func (s *Store) FindByEmail(email string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if u, ok := s.findByEmailLocked(email); ok {
+		return u, nil
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (s *Store) findByEmailLocked(email string) (User, bool) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return User{}, false
+	}
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
 func (s *Store) FindByID(id string) (User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -135,9 +208,56 @@ func (s *Store) List() ([]User, error) {
 	return users, nil
 }
 
+// StartPasswordClock records at as the password change time of a user that
+// has none yet and returns the stored value. Only that field is written, so
+// a concurrent update of the user is never overwritten.
+func (s *Store) StartPasswordClock(id, at string) (string, error) {
+	s.mu.Lock()
+	var (
+		name string
+		u    User
+		ok   bool
+	)
+	for name, u = range s.users {
+		if ok = u.ID == id; ok {
+			break
+		}
+	}
+	if !ok {
+		s.mu.Unlock()
+		return "", ErrUserNotFound
+	}
+	if u.PasswordChangedAt != "" {
+		s.mu.Unlock()
+		return u.PasswordChangedAt, nil
+	}
+	u.PasswordChangedAt = at
+	s.users[name] = u
+	list := make([]User, 0, len(s.users))
+	for _, usr := range s.users {
+		list = append(list, usr)
+	}
+	s.mu.Unlock()
+	if err := s.writeUsers(list); err != nil {
+		s.mu.Lock()
+		if cur, ok := s.users[name]; ok && cur.PasswordChangedAt == at {
+			cur.PasswordChangedAt = ""
+			s.users[name] = cur
+		}
+		s.mu.Unlock()
+		return "", err
+	}
+	return at, nil
+}
+
 func (s *Store) UpsertUser(u User) error {
+	u.Email = strings.TrimSpace(u.Email)
 	// Update in-memory under write lock and take a snapshot
 	s.mu.Lock()
+	if other, ok := s.findByEmailLocked(u.Email); ok && other.Username != u.Username {
+		s.mu.Unlock()
+		return ErrEmailTaken
+	}
 	prev, hadPrev := s.users[u.Username]
 	now := time.Now().UTC().Format(time.RFC3339)
 	if u.CreatedAt == "" {
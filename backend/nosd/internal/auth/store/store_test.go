@@ -75,3 +75,83 @@ func TestCrashRecoveryFromTmp(t *testing.T) {
 		t.Fatalf("users.json missing: %v", err)
 	}
 }
+
+func TestEmailUniqueCaseInsensitive(t *testing.T) {
+	s, _ := New(filepath.Join(t.TempDir(), "users.json"))
+	if err := s.UpsertUser(User{ID: "u1", Username: "alice", Email: "Alice@Example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertUser(User{ID: "u2", Username: "bob", Email: "alice@example.COM"}); err != ErrEmailTaken {
+		t.Fatalf("duplicate email: got %v", err)
+	}
+	if _, err := s.FindByUsername("bob"); err != ErrUserNotFound {
+		t.Fatalf("rejected user was stored")
+	}
+	// the owner can rewrite its own record
+	if err := s.UpsertUser(User{ID: "u1", Username: "alice", Email: "alice@example.com", DisplayName: "Alice"}); err != nil {
+		t.Fatalf("self update: %v", err)
+	}
+	if u, err := s.FindByEmail("ALICE@example.com"); err != nil || u.ID != "u1" || u.DisplayName != "Alice" {
+		t.Fatalf("FindByEmail: %+v %v", u, err)
+	}
+}
+
+func TestMigrateLegacyEmailFromUsername(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	legacy := `{"version":1,"users":[` +
+		`{"id":"u1","username":"carol@example.com","password_hash":"plain:x","roles":["admin"]},` +
+		`{"id":"u2","username":"dave","password_hash":"plain:x","roles":["user"]}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := New(path)
+	if u, err := s.FindByEmail("carol@example.com"); err != nil || u.ID != "u1" {
+		t.Fatalf("legacy email not migrated: %+v %v", u, err)
+	}
+	if u, _ := s.FindByUsername("dave"); u.Email != "" {
+		t.Fatalf("non-email username migrated: %q", u.Email)
+	}
+	// the migration is written back
+	b, _ := os.ReadFile(path)
+	var f dbFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range f.Users {
+		if u.ID == "u1" && u.Email != "carol@example.com" {
+			t.Fatalf("migration not persisted: %+v", u)
+		}
+	}
+}
+
+func TestStartPasswordClockOnlySetsUnsetField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertUser(User{ID: "u1", Username: "old", PasswordHash: "plain:x"}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := s.FindByID("u1")
+
+	got, err := s.StartPasswordClock("u1", "2024-06-01T00:00:00Z")
+	if err != nil || got != "2024-06-01T00:00:00Z" {
+		t.Fatalf("first start: %q %v", got, err)
+	}
+	// a later evaluation keeps the first time
+	if got, _ := s.StartPasswordClock("u1", "2024-07-01T00:00:00Z"); got != "2024-06-01T00:00:00Z" {
+		t.Fatalf("clock restarted: %q", got)
+	}
+	after, _ := s.FindByID("u1")
+	if after.UpdatedAt != before.UpdatedAt || after.PasswordHash != before.PasswordHash {
+		t.Fatalf("other fields changed: %+v", after)
+	}
+	reloaded, _ := New(path)
+	if u, _ := reloaded.FindByID("u1"); u.PasswordChangedAt != "2024-06-01T00:00:00Z" {
+		t.Fatalf("not persisted: %+v", u)
+	}
+	if _, err := s.StartPasswordClock("missing", "2024-06-01T00:00:00Z"); err != ErrUserNotFound {
+		t.Fatalf("unknown user: %v", err)
+	}
+}
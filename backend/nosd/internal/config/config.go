@@ -1,12 +1,17 @@
 package config
 
 import (
+	"maps"
+	"net/textproto"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
+
+	"nithronos/backend/nosd/pkg/maintenance"
 )
 
 type Config struct {
@@ -23,14 +28,22 @@ type Config struct {
 	EtcDir             string
 	AppsDataDir        string
 	AppsInstallDir     string
-	TrustProxy         bool
+	TrustProxy         bool // deprecated shortcut for TrustedProxies, see TrustedProxyNets
 	RateOTPPerMin      int
 	RateLoginPer15m    int
 	RateOTPWindowSec   int
 	RateLoginWindowSec int
 	// new fields
-	Bind                     string
-	CORSOrigin               string
+	Bind       string
+	CORSOrigin string
+	// CORSOrigins are further allowed origins for multi-name deployments
+	// (LAN IP, hostname, mDNS name); "https://*.example.com" matches any
+	// subdomain
+	CORSOrigins []string
+	// TrustedProxies are the IPs and CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Forwarded-Proto headers are believed; by
+	// default the Caddy front end on loopback
+	TrustedProxies           []string
 	SessionAccessTTLSeconds  int
 	SessionRefreshTTLSeconds int
 	MetricsEnabled           bool
@@ -38,27 +51,107 @@ type Config struct {
 	MetricsAllowlist         []string
 	AllowAgentRegistration   bool
 	RecoveryMode             bool
+	// PublicURL is the externally reachable base URL used in links sent to users
+	PublicURL string
+	// SMTP settings for outgoing mail (password reset); empty host disables email
+	SMTPHost     string
+	SMTPPort     int
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+	// Argon2id cost for new password hashes; weaker stored hashes are upgraded at login
+	Argon2Time      uint32
+	Argon2MemoryKiB uint32
+	Argon2Threads   uint8
+	// PasswordMaxAgeDays is how long a password stays valid before the user
+	// must change it; 0 disables expiry
+	PasswordMaxAgeDays int
+	// AgentSocketPath is the unix socket nos-agent listens on
+	AgentSocketPath string
+	// UpdatesCheckSeconds is how long an /updates/check result is reused
+	UpdatesCheckSeconds int
+	// UpdatesSnapshotScope selects pre-update snapshot targets: "os" or "all"
+	UpdatesSnapshotScope string
+	// BootConfirmSeconds is how long after the reboot following an
+	// update an admin has to confirm the boot before the OS snapshots are
+	// rolled back; 0 (the default) disables the check
+	BootConfirmSeconds int
+	// MaintenanceWindow limits when scheduled scrubs and backups may start
+	MaintenanceWindow maintenance.Window
+	// SessionBindingMode controls what happens when a session's IP prefix or
+	// UA fingerprint changes: "off", "flag" (reported in /me) or "enforce"
+	// (sensitive routes require re-authentication)
+	SessionBindingMode string
+	// SupportLogMaxBytes caps each log included in a support bundle (tail kept)
+	SupportLogMaxBytes int64
+	// SupportLogWindowSeconds limits journal excerpts in a support bundle
+	SupportLogWindowSeconds int
+	// MaxBodyBytes caps request bodies on mutating (POST/PUT/PATCH/DELETE)
+	// routes; larger bodies are rejected with 413
+	MaxBodyBytes int64
+	// RateOTPMaxAttempts is how many wrong setup OTPs are allowed before the
+	// code is invalidated and a new one has to be issued
+	RateOTPMaxAttempts int
+	// TelemetryURL receives opt-in usage reports; empty disables sending
+	TelemetryURL string
+	// SmartScanSeconds is how often every disk's SMART data is sampled into
+	// the per-device history; 0 disables the scheduled scan
+	SmartScanSeconds int
+	// SecurityHeaders are set on every response (see
+	// DefaultSecurityHeaders); http.headers entries override them and an
+	// empty value removes one
+	SecurityHeaders map[string]string
+	// HSTSMaxAgeSeconds is the Strict-Transport-Security max-age sent on
+	// HTTPS requests; 0 disables HSTS
+	HSTSMaxAgeSeconds     int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// AccessLogLevel is the level successful requests are logged at; 5xx
+	// responses are always logged as errors. "disabled" turns the access
+	// log off for everything but those.
+	AccessLogLevel zerolog.Level
+	// AccessLogSample logs one in every AccessLogSample requests that
+	// succeeded; failed requests are always logged
+	AccessLogSample int
 }
 
 type fileYAML struct {
 	HTTP struct {
-		Bind string `yaml:"bind"`
+		Bind         string            `yaml:"bind"`
+		PublicURL    string            `yaml:"publicURL"`
+		MaxBodyBytes int64             `yaml:"maxBodyBytes"`
+		Headers      map[string]string `yaml:"headers"`
+		HSTS         struct {
+			MaxAge            string `yaml:"maxAge"`
+			IncludeSubdomains *bool  `yaml:"includeSubDomains"`
+			Preload           bool   `yaml:"preload"`
+		} `yaml:"hsts"`
 	} `yaml:"http"`
 	CORS struct {
-		Origin string `yaml:"origin"`
+		Origin  string   `yaml:"origin"`
+		Origins []string `yaml:"origins"`
 	} `yaml:"cors"`
 	Rate struct {
 		OTPPerMin      int `yaml:"otpPerMin"`
 		LoginPer15m    int `yaml:"loginPer15m"`
 		OTPWindowSec   int `yaml:"otpWindowSec"`
 		LoginWindowSec int `yaml:"loginWindowSec"`
+		OTPMaxAttempts int `yaml:"otpMaxAttempts"`
 	} `yaml:"rate"`
-	TrustProxy bool `yaml:"trustProxy"`
-	Sessions   struct {
+	TrustProxy     bool     `yaml:"trustProxy"`
+	TrustedProxies []string `yaml:"trustedProxies"`
+	Sessions       struct {
 		AccessTTL  string `yaml:"accessTTL"`
 		RefreshTTL string `yaml:"refreshTTL"`
+		Binding    string `yaml:"binding"`
 	} `yaml:"sessions"`
-	Logging struct{ Level string } `yaml:"logging"`
+	Logging struct {
+		Level  string
+		Access struct {
+			Level  string `yaml:"level"`
+			Sample int    `yaml:"sample"`
+		} `yaml:"access"`
+	} `yaml:"logging"`
 	Metrics struct {
 		Enabled   bool     `yaml:"enabled"`
 		Pprof     bool     `yaml:"pprof"`
@@ -67,6 +160,40 @@ type fileYAML struct {
 	Agents struct {
 		AllowRegistration bool `yaml:"allowRegistration"`
 	} `yaml:"agents"`
+	Agent struct {
+		Socket string `yaml:"socket"`
+	} `yaml:"agent"`
+	Updates struct {
+		CheckInterval string `yaml:"checkInterval"`
+		SnapshotScope string `yaml:"snapshotScope"`
+		BootConfirm   string `yaml:"bootConfirmWindow"`
+	} `yaml:"updates"`
+	SMTP struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		From     string `yaml:"from"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"smtp"`
+	Auth struct {
+		Argon2 struct {
+			Time      uint32 `yaml:"time"`
+			MemoryKiB uint32 `yaml:"memoryKiB"`
+			Threads   uint8  `yaml:"threads"`
+		} `yaml:"argon2"`
+		PasswordMaxAgeDays *int `yaml:"passwordMaxAgeDays"`
+	} `yaml:"auth"`
+	Maintenance maintenance.Window `yaml:"maintenance"`
+	Support     struct {
+		LogMaxBytes int64  `yaml:"logMaxBytes"`
+		LogWindow   string `yaml:"logWindow"`
+	} `yaml:"support"`
+	Telemetry struct {
+		URL string `yaml:"url"`
+	} `yaml:"telemetry"`
+	Smart struct {
+		ScanInterval string `yaml:"scanInterval"`
+	} `yaml:"smart"`
 }
 
 func Defaults() Config {
@@ -91,6 +218,7 @@ func Defaults() Config {
 		RateLoginWindowSec:       900,
 		Bind:                     "127.0.0.1:9000",
 		CORSOrigin:               "http://localhost:5173",
+		TrustedProxies:           []string{"127.0.0.1", "::1"},
 		SessionAccessTTLSeconds:  int((15 * time.Minute).Seconds()),
 		SessionRefreshTTLSeconds: int((7 * 24 * time.Hour).Seconds()),
 		MetricsEnabled:           false,
@@ -98,46 +226,106 @@ func Defaults() Config {
 		MetricsAllowlist:         nil,
 		AllowAgentRegistration:   true,
 		RecoveryMode:             false,
+		SMTPPort:                 587,
+		Argon2Time:               3,
+		Argon2MemoryKiB:          64 * 1024,
+		Argon2Threads:            1,
+		PasswordMaxAgeDays:       90,
+		AgentSocketPath:          "/run/nos-agent.sock",
+		UpdatesCheckSeconds:      int(time.Hour.Seconds()),
+		UpdatesSnapshotScope:     "os",
+		BootConfirmSeconds:       0,
+		SessionBindingMode:       "off",
+		SupportLogMaxBytes:       5 << 20,
+		SupportLogWindowSeconds:  int((24 * time.Hour).Seconds()),
+		MaxBodyBytes:             1 << 20,
+		RateOTPMaxAttempts:       5,
+		SmartScanSeconds:         int(time.Hour.Seconds()),
+		SecurityHeaders:          DefaultSecurityHeaders(),
+		HSTSMaxAgeSeconds:        int((365 * 24 * time.Hour).Seconds()),
+		HSTSIncludeSubdomains:    true,
+		AccessLogLevel:           zerolog.InfoLevel,
+		AccessLogSample:          1,
 	}
 }
 
+// Load reads path like LoadFile, discarding the problem list.
 func Load(path string) Config {
+	cfg, _ := LoadFile(path)
+	return cfg
+}
+
+// LoadFile builds the config from defaults, the YAML file at path (a missing
+// file is fine) and NOS_* environment overrides, then validates it. Values
+// that fail to parse or validate are reported; fatal problems (e.g. an
+// unparseable file) mean the returned config should not be used.
+func LoadFile(path string) (Config, []Problem) {
 	cfg := Defaults()
+	var problems []Problem
+	warn := func(field, msg string) {
+		problems = append(problems, Problem{Field: field, Message: msg + "; using default"})
+	}
 	if b, err := os.ReadFile(path); err == nil {
 		var fy fileYAML
-		if yaml.Unmarshal(b, &fy) == nil {
+		if err := yaml.Unmarshal(b, &fy); err != nil {
+			problems = append(problems, Problem{Field: path, Message: err.Error(), Fatal: true})
+		} else {
 			if fy.HTTP.Bind != "" {
 				cfg.Bind = fy.HTTP.Bind
 			}
 			if fy.CORS.Origin != "" {
 				cfg.CORSOrigin = fy.CORS.Origin
 			}
+			if len(fy.CORS.Origins) > 0 {
+				cfg.CORSOrigins = append([]string{}, fy.CORS.Origins...)
+			}
 			if fy.TrustProxy {
 				cfg.TrustProxy = true
 			}
-			if fy.Rate.OTPPerMin > 0 {
+			if fy.TrustedProxies != nil {
+				cfg.TrustedProxies = append([]string{}, fy.TrustedProxies...)
+			}
+			if fy.Rate.OTPPerMin != 0 {
 				cfg.RateOTPPerMin = fy.Rate.OTPPerMin
 			}
-			if fy.Rate.LoginPer15m > 0 {
+			if fy.Rate.LoginPer15m != 0 {
 				cfg.RateLoginPer15m = fy.Rate.LoginPer15m
 			}
-			if fy.Rate.OTPWindowSec > 0 {
+			if fy.Rate.OTPWindowSec != 0 {
 				cfg.RateOTPWindowSec = fy.Rate.OTPWindowSec
 			}
-			if fy.Rate.LoginWindowSec > 0 {
+			if fy.Rate.LoginWindowSec != 0 {
 				cfg.RateLoginWindowSec = fy.Rate.LoginWindowSec
 			}
+			if fy.Rate.OTPMaxAttempts != 0 {
+				cfg.RateOTPMaxAttempts = fy.Rate.OTPMaxAttempts
+			}
 			if fy.Logging.Level != "" {
 				if l, err := zerolog.ParseLevel(fy.Logging.Level); err == nil {
 					cfg.LogLevel = l
+				} else {
+					warn("logging.level", "unknown level "+strconv.Quote(fy.Logging.Level))
+				}
+			}
+			if fy.Logging.Access.Level != "" {
+				if l, err := zerolog.ParseLevel(fy.Logging.Access.Level); err == nil {
+					cfg.AccessLogLevel = l
+				} else {
+					warn("logging.access.level", "unknown level "+strconv.Quote(fy.Logging.Access.Level))
 				}
 			}
-			if d, err := time.ParseDuration(fy.Sessions.AccessTTL); err == nil && d > 0 {
+			if fy.Logging.Access.Sample != 0 {
+				cfg.AccessLogSample = fy.Logging.Access.Sample
+			}
+			if d, ok := yamlDuration(fy.Sessions.AccessTTL, "sessions.accessTTL", warn); ok {
 				cfg.SessionAccessTTLSeconds = int(d.Seconds())
 			}
-			if d, err := time.ParseDuration(fy.Sessions.RefreshTTL); err == nil && d > 0 {
+			if d, ok := yamlDuration(fy.Sessions.RefreshTTL, "sessions.refreshTTL", warn); ok {
 				cfg.SessionRefreshTTLSeconds = int(d.Seconds())
 			}
+			if fy.Sessions.Binding != "" {
+				cfg.SessionBindingMode = fy.Sessions.Binding
+			}
 			cfg.MetricsEnabled = fy.Metrics.Enabled
 			cfg.PprofEnabled = fy.Metrics.Pprof
 			if len(fy.Metrics.Allowlist) > 0 {
@@ -146,9 +334,94 @@ func Load(path string) Config {
 			if fy.Agents.AllowRegistration {
 				cfg.AllowAgentRegistration = true
 			}
+			if fy.HTTP.PublicURL != "" {
+				cfg.PublicURL = fy.HTTP.PublicURL
+			}
+			if fy.HTTP.MaxBodyBytes != 0 {
+				cfg.MaxBodyBytes = fy.HTTP.MaxBodyBytes
+			}
+			for k, v := range fy.HTTP.Headers {
+				cfg.SecurityHeaders[textproto.CanonicalMIMEHeaderKey(k)] = v
+			}
+			if d, ok := yamlDuration(fy.HTTP.HSTS.MaxAge, "http.hsts.maxAge", warn); ok {
+				cfg.HSTSMaxAgeSeconds = int(d.Seconds())
+			}
+			if fy.HTTP.HSTS.IncludeSubdomains != nil {
+				cfg.HSTSIncludeSubdomains = *fy.HTTP.HSTS.IncludeSubdomains
+			}
+			cfg.HSTSPreload = fy.HTTP.HSTS.Preload
+			if fy.SMTP.Host != "" {
+				cfg.SMTPHost = fy.SMTP.Host
+			}
+			if fy.SMTP.Port != 0 {
+				cfg.SMTPPort = fy.SMTP.Port
+			}
+			if fy.SMTP.From != "" {
+				cfg.SMTPFrom = fy.SMTP.From
+			}
+			if fy.SMTP.Username != "" {
+				cfg.SMTPUsername = fy.SMTP.Username
+			}
+			if fy.SMTP.Password != "" {
+				cfg.SMTPPassword = fy.SMTP.Password
+			}
+			if fy.Auth.Argon2.Time > 0 {
+				cfg.Argon2Time = fy.Auth.Argon2.Time
+			}
+			if fy.Auth.Argon2.MemoryKiB > 0 {
+				cfg.Argon2MemoryKiB = fy.Auth.Argon2.MemoryKiB
+			}
+			if fy.Auth.Argon2.Threads > 0 {
+				cfg.Argon2Threads = fy.Auth.Argon2.Threads
+			}
+			if fy.Auth.PasswordMaxAgeDays != nil {
+				cfg.PasswordMaxAgeDays = *fy.Auth.PasswordMaxAgeDays
+			}
+			if fy.Agent.Socket != "" {
+				cfg.AgentSocketPath = fy.Agent.Socket
+			}
+			if d, ok := yamlDuration(fy.Updates.CheckInterval, "updates.checkInterval", warn); ok {
+				cfg.UpdatesCheckSeconds = int(d.Seconds())
+			}
+			if fy.Updates.SnapshotScope != "" {
+				cfg.UpdatesSnapshotScope = fy.Updates.SnapshotScope
+			}
+			if d, ok := yamlDuration(fy.Updates.BootConfirm, "updates.bootConfirmWindow", warn); ok {
+				cfg.BootConfirmSeconds = int(d.Seconds())
+			}
+			if fy.Maintenance.Enabled {
+				cfg.MaintenanceWindow = fy.Maintenance
+			}
+			if fy.Support.LogMaxBytes != 0 {
+				cfg.SupportLogMaxBytes = fy.Support.LogMaxBytes
+			}
+			if d, ok := yamlDuration(fy.Support.LogWindow, "support.logWindow", warn); ok {
+				cfg.SupportLogWindowSeconds = int(d.Seconds())
+			}
+			if fy.Telemetry.URL != "" {
+				cfg.TelemetryURL = fy.Telemetry.URL
+			}
+			if d, ok := yamlDuration(fy.Smart.ScanInterval, "smart.scanInterval", warn); ok {
+				cfg.SmartScanSeconds = int(d.Seconds())
+			}
 		}
 	}
-	return applyEnv(cfg)
+	cfg = applyEnv(cfg)
+	return cfg, append(problems, cfg.Validate()...)
+}
+
+// yamlDuration parses an optional duration from the config file, reporting
+// values that are set but unparseable.
+func yamlDuration(v, field string, warn func(field, msg string)) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		warn(field, "invalid duration "+strconv.Quote(v))
+		return 0, false
+	}
+	return d, true
 }
 
 func FromEnv() Config { return applyEnv(Defaults()) }
@@ -219,6 +492,22 @@ func applyEnv(cfg Config) Config {
 	} else if v := os.Getenv("NOS_UI_ORIGIN"); v != "" {
 		cfg.CORSOrigin = v
 	}
+	if v := os.Getenv("NOS_CORS_ORIGINS"); v != "" {
+		cfg.CORSOrigins = nil
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				cfg.CORSOrigins = append(cfg.CORSOrigins, o)
+			}
+		}
+	}
+	if v := os.Getenv("NOS_TRUSTED_PROXIES"); v != "" {
+		cfg.TrustedProxies = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				cfg.TrustedProxies = append(cfg.TrustedProxies, p)
+			}
+		}
+	}
 	if v := os.Getenv("NOS_RATE_OTP_PER_MIN"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			cfg.RateOTPPerMin = n
@@ -239,6 +528,11 @@ func applyEnv(cfg Config) Config {
 			cfg.RateLoginWindowSec = n
 		}
 	}
+	if v := os.Getenv("NOS_RATE_OTP_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RateOTPMaxAttempts = n
+		}
+	}
 	if v := os.Getenv("NOS_SESSION_ACCESS_TTL"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			cfg.SessionAccessTTLSeconds = int(d.Seconds())
@@ -279,5 +573,111 @@ func applyEnv(cfg Config) Config {
 	if v := os.Getenv("NOS_RECOVERY"); v != "" {
 		cfg.RecoveryMode = v == "1" || v == "true" || v == "yes"
 	}
+	if v := os.Getenv("NOS_AGENT_SOCKET"); v != "" {
+		cfg.AgentSocketPath = v
+	}
+	if v := os.Getenv("NOS_UPDATES_CHECK_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.UpdatesCheckSeconds = int(d.Seconds())
+		}
+	}
+	if v := os.Getenv("NOS_UPDATES_SNAPSHOT_SCOPE"); v == "os" || v == "all" {
+		cfg.UpdatesSnapshotScope = v
+	}
+	if v := os.Getenv("NOS_UPDATES_BOOT_CONFIRM_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.BootConfirmSeconds = int(d.Seconds())
+		}
+	}
+	if v := os.Getenv("NOS_SMART_SCAN_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.SmartScanSeconds = int(d.Seconds())
+		}
+	}
+	if v := os.Getenv("NOS_MAINTENANCE_WINDOW"); v != "" {
+		if w, err := maintenance.ParseWindow(v); err == nil {
+			cfg.MaintenanceWindow = w
+		}
+	}
+	if v := os.Getenv("NOS_SESSION_BINDING"); isSessionBindingMode(v) {
+		cfg.SessionBindingMode = v
+	}
+	if v := os.Getenv("NOS_SUPPORT_LOG_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.SupportLogMaxBytes = n
+		}
+	}
+	if v := os.Getenv("NOS_SUPPORT_LOG_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.SupportLogWindowSeconds = int(d.Seconds())
+		}
+	}
+	if v := os.Getenv("NOS_HTTP_MAX_BODY_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.MaxBodyBytes = n
+		}
+	}
+	if v := os.Getenv("NOS_ACCESS_LOG"); v != "" {
+		if l, err := zerolog.ParseLevel(v); err == nil {
+			cfg.AccessLogLevel = l
+		}
+	}
+	if v := os.Getenv("NOS_ACCESS_LOG_SAMPLE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.AccessLogSample = n
+		}
+	}
+	if v := os.Getenv("NOS_HTTP_CSP"); v != "" {
+		cfg.SecurityHeaders = maps.Clone(cfg.SecurityHeaders)
+		cfg.SecurityHeaders["Content-Security-Policy"] = v
+	}
+	if v := os.Getenv("NOS_HTTP_HSTS_MAX_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.HSTSMaxAgeSeconds = int(d.Seconds())
+		}
+	}
+	if v := os.Getenv("NOS_TELEMETRY_URL"); v != "" {
+		cfg.TelemetryURL = v
+	}
+	if v := os.Getenv("NOS_PUBLIC_URL"); v != "" {
+		cfg.PublicURL = v
+	}
+	if v := os.Getenv("NOS_SMTP_HOST"); v != "" {
+		cfg.SMTPHost = v
+	}
+	if v := os.Getenv("NOS_SMTP_PORT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.SMTPPort = n
+		}
+	}
+	if v := os.Getenv("NOS_SMTP_FROM"); v != "" {
+		cfg.SMTPFrom = v
+	}
+	if v := os.Getenv("NOS_SMTP_USERNAME"); v != "" {
+		cfg.SMTPUsername = v
+	}
+	if v := os.Getenv("NOS_SMTP_PASSWORD"); v != "" {
+		cfg.SMTPPassword = v
+	}
+	if v := os.Getenv("NOS_ARGON2_TIME"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil && n > 0 {
+			cfg.Argon2Time = uint32(n)
+		}
+	}
+	if v := os.Getenv("NOS_ARGON2_MEMORY_KIB"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil && n > 0 {
+			cfg.Argon2MemoryKiB = uint32(n)
+		}
+	}
+	if v := os.Getenv("NOS_ARGON2_THREADS"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 8); err == nil && n > 0 {
+			cfg.Argon2Threads = uint8(n)
+		}
+	}
+	if v := os.Getenv("NOS_PASSWORD_MAX_AGE_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.PasswordMaxAgeDays = n
+		}
+	}
 	return cfg
 }
@@ -1,10 +1,111 @@
 package config
 
-// AgentSocket returns the agent socket path. Kept as method for future plumb.
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// AgentSocket returns the nos-agent socket path, falling back to the default
+// for configs built without Defaults().
 func (c Config) AgentSocket() string {
-	// Touch fields to avoid staticcheck complaints about empty branches while keeping behavior.
-	_ = c.EtcDir
-	_ = c.Bind
-	// default path; can be overridden via env later if needed
+	if c.AgentSocketPath != "" {
+		return c.AgentSocketPath
+	}
 	return "/run/nos-agent.sock"
 }
+
+// SessionTTL is the lifetime of the nos_session cookie and its server-side
+// record.
+func (c Config) SessionTTL() time.Duration {
+	if c.SessionAccessTTLSeconds > 0 {
+		return time.Duration(c.SessionAccessTTLSeconds) * time.Second
+	}
+	return 15 * time.Minute
+}
+
+// RefreshTTL is the lifetime of the nos_refresh cookie.
+func (c Config) RefreshTTL() time.Duration {
+	if c.SessionRefreshTTLSeconds > 0 {
+		return time.Duration(c.SessionRefreshTTLSeconds) * time.Second
+	}
+	return 7 * 24 * time.Hour
+}
+
+// AllowedOrigins is cors.origin followed by cors.origins, without
+// duplicates.
+func (c Config) AllowedOrigins() []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, o := range append([]string{c.CORSOrigin}, c.CORSOrigins...) {
+		if o != "" && !seen[o] {
+			seen[o] = true
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// Session binding modes (Config.SessionBindingMode).
+const (
+	SessionBindingOff     = "off"
+	SessionBindingFlag    = "flag"
+	SessionBindingEnforce = "enforce"
+)
+
+func isSessionBindingMode(s string) bool {
+	return s == SessionBindingOff || s == SessionBindingFlag || s == SessionBindingEnforce
+}
+
+// privateProxyNets are what the deprecated trustProxy flag trusts: loopback
+// and private networks.
+var privateProxyNets = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// TrustedProxyNets parses TrustedProxies, plus loopback and private networks
+// when the deprecated TrustProxy flag is set. Entries that don't parse are
+// skipped; Validate reports them.
+func (c Config) TrustedProxyNets() []*net.IPNet {
+	entries := c.TrustedProxies
+	if c.TrustProxy {
+		entries = append(append([]string{}, entries...), privateProxyNets...)
+	}
+	var out []*net.IPNet
+	for _, e := range entries {
+		if n, ok := parseProxyNet(e); ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// parseProxyNet parses a CIDR or a bare IP, which is a single-host network.
+func parseProxyNet(s string) (*net.IPNet, bool) {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 128
+		if v4 := ip.To4(); v4 != nil {
+			ip, bits = v4, 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, true
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err == nil
+}
+
+// DefaultSecurityHeaders are the headers nosd sets on every response. The
+// CSP suits the web UI as built by Vite: scripts only from the UI's own
+// origin, inline styles (set by the component libraries), images from data:
+// and blob: URLs, and fetch, event streams and WebSockets back to nosd.
+func DefaultSecurityHeaders() map[string]string {
+	return map[string]string{
+		"Content-Security-Policy": "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; " +
+			"img-src 'self' data: blob:; font-src 'self' data:; connect-src 'self' ws: wss:; " +
+			"frame-ancestors 'none'; base-uri 'self'; form-action 'self'; object-src 'none'",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "no-referrer",
+		"X-Content-Type-Options":       "nosniff",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+		"Permissions-Policy":           "camera=(), microphone=(), geolocation=(), payment=()",
+	}
+}
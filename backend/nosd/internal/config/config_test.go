@@ -1,9 +1,12 @@
 package config
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/rs/zerolog"
 )
 
 func TestYAMLAndEnvPrecedence(t *testing.T) {
@@ -97,3 +100,229 @@ func TestYAMLAndEnvPrecedence(t *testing.T) {
 		t.Fatalf("pprof should be enabled by env")
 	}
 }
+
+func TestSMTPConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	data := []byte("" +
+		"http:\n  publicURL: https://nas.example.com\n" +
+		"smtp:\n  host: mail.example.com\n  port: 465\n  from: nas@example.com\n  username: nas\n  password: secret\n")
+	if err := os.WriteFile(cfgPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Load(cfgPath)
+	if cfg.PublicURL != "https://nas.example.com" {
+		t.Fatalf("public url from yaml: %s", cfg.PublicURL)
+	}
+	if cfg.SMTPHost != "mail.example.com" || cfg.SMTPPort != 465 || cfg.SMTPFrom != "nas@example.com" {
+		t.Fatalf("smtp from yaml: %+v", cfg)
+	}
+	if cfg.SMTPUsername != "nas" || cfg.SMTPPassword != "secret" {
+		t.Fatalf("smtp auth from yaml")
+	}
+
+	t.Setenv("NOS_SMTP_HOST", "relay.local")
+	t.Setenv("NOS_SMTP_PORT", "2525")
+	cfg2 := Load(cfgPath)
+	if cfg2.SMTPHost != "relay.local" || cfg2.SMTPPort != 2525 {
+		t.Fatalf("smtp env override: %s:%d", cfg2.SMTPHost, cfg2.SMTPPort)
+	}
+
+	if Defaults().SMTPPort != 587 {
+		t.Fatalf("default smtp port: %d", Defaults().SMTPPort)
+	}
+}
+
+func TestArgon2Config(t *testing.T) {
+	d := Defaults()
+	if d.Argon2Time != 3 || d.Argon2MemoryKiB != 64*1024 || d.Argon2Threads != 1 {
+		t.Fatalf("argon2 defaults: %d %d %d", d.Argon2Time, d.Argon2MemoryKiB, d.Argon2Threads)
+	}
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("auth:\n  argon2:\n    time: 4\n    memoryKiB: 131072\n    threads: 2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Load(cfgPath)
+	if cfg.Argon2Time != 4 || cfg.Argon2MemoryKiB != 131072 || cfg.Argon2Threads != 2 {
+		t.Fatalf("argon2 from yaml: %d %d %d", cfg.Argon2Time, cfg.Argon2MemoryKiB, cfg.Argon2Threads)
+	}
+	t.Setenv("NOS_ARGON2_TIME", "5")
+	if cfg := Load(cfgPath); cfg.Argon2Time != 5 {
+		t.Fatalf("argon2 env override: %d", cfg.Argon2Time)
+	}
+}
+
+func TestPasswordMaxAgeConfig(t *testing.T) {
+	if got := Defaults().PasswordMaxAgeDays; got != 90 {
+		t.Fatalf("default password max age: %d", got)
+	}
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("auth:\n  passwordMaxAgeDays: 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Load(cfgPath).PasswordMaxAgeDays; got != 0 {
+		t.Fatalf("password max age from yaml: %d", got)
+	}
+	t.Setenv("NOS_PASSWORD_MAX_AGE_DAYS", "30")
+	if got := Load(cfgPath).PasswordMaxAgeDays; got != 30 {
+		t.Fatalf("password max age env override: %d", got)
+	}
+}
+
+func TestAgentSocketConfig(t *testing.T) {
+	if got := Defaults().AgentSocket(); got != "/run/nos-agent.sock" {
+		t.Fatalf("default agent socket: %q", got)
+	}
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("agent:\n  socket: /tmp/a.sock\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Load(cfgPath).AgentSocket(); got != "/tmp/a.sock" {
+		t.Fatalf("agent socket from yaml: %q", got)
+	}
+	t.Setenv("NOS_AGENT_SOCKET", "/tmp/b.sock")
+	if got := Load(cfgPath).AgentSocket(); got != "/tmp/b.sock" {
+		t.Fatalf("agent socket env override: %q", got)
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	data := []byte("" +
+		"cors:\n  origin: https://nas.local\n" +
+		"  origins: [\"http://192.168.1.10\", \"https://*.example.com\", \"https://nas.local\", \"ftp://x\", \"https://host/path\"]\n")
+	if err := os.WriteFile(cfgPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, problems := LoadFile(cfgPath)
+	if len(problems) != 2 || problems[0].Field != "cors.origins" {
+		t.Fatalf("expected two dropped origins: %v", problems)
+	}
+	got := cfg.AllowedOrigins()
+	if len(got) != 3 || got[0] != "https://nas.local" || got[1] != "http://192.168.1.10" || got[2] != "https://*.example.com" {
+		t.Fatalf("allowed origins: %v", got)
+	}
+
+	t.Setenv("NOS_CORS_ORIGINS", "https://a.test, https://b.test")
+	if got := Load(cfgPath).CORSOrigins; len(got) != 2 || got[1] != "https://b.test" {
+		t.Fatalf("env override: %v", got)
+	}
+}
+
+func TestAccessLogConfig(t *testing.T) {
+	d := Defaults()
+	if d.AccessLogLevel != zerolog.InfoLevel || d.AccessLogSample != 1 {
+		t.Fatalf("access log defaults: %s %d", d.AccessLogLevel, d.AccessLogSample)
+	}
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("logging:\n  level: info\n  access:\n    level: debug\n    sample: 10\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Load(cfgPath)
+	if cfg.AccessLogLevel != zerolog.DebugLevel || cfg.AccessLogSample != 10 {
+		t.Fatalf("access log from yaml: %s %d", cfg.AccessLogLevel, cfg.AccessLogSample)
+	}
+	t.Setenv("NOS_ACCESS_LOG", "disabled")
+	t.Setenv("NOS_ACCESS_LOG_SAMPLE", "100")
+	cfg = Load(cfgPath)
+	if cfg.AccessLogLevel != zerolog.Disabled || cfg.AccessLogSample != 100 {
+		t.Fatalf("access log env override: %s %d", cfg.AccessLogLevel, cfg.AccessLogSample)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	if got := Defaults().TrustedProxyNets(); len(got) != 2 || got[0].String() != "127.0.0.1/32" || got[1].String() != "::1/128" {
+		t.Fatalf("default proxies: %v", got)
+	}
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("trustedProxies: [127.0.0.1, \"10.1.0.0/16\", \"fd00::/8\", proxy.lan]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, problems := LoadFile(cfgPath)
+	if len(problems) != 1 || problems[0].Field != "trustedProxies" {
+		t.Fatalf("expected the hostname to be dropped: %v", problems)
+	}
+	nets := cfg.TrustedProxyNets()
+	if len(nets) != 3 || nets[0].String() != "127.0.0.1/32" || nets[1].String() != "10.1.0.0/16" || nets[2].String() != "fd00::/8" {
+		t.Fatalf("nets: %v", nets)
+	}
+
+	if err := os.WriteFile(cfgPath, []byte("trustedProxies: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Load(cfgPath).TrustedProxyNets(); len(got) != 0 {
+		t.Fatalf("empty list should trust no proxy: %v", got)
+	}
+
+	t.Setenv("NOS_TRUSTED_PROXIES", "192.168.1.1, ::1")
+	if got := Load(cfgPath).TrustedProxyNets(); len(got) != 2 || got[1].String() != "::1/128" {
+		t.Fatalf("env override: %v", got)
+	}
+
+	// the deprecated flag trusts loopback and private networks, with a warning
+	legacy := Defaults()
+	legacy.TrustProxy = true
+	problems = legacy.Validate()
+	if len(problems) != 1 || problems[0].Field != "trustProxy" || problems[0].Fatal {
+		t.Fatalf("deprecation warning: %v", problems)
+	}
+	var private, public bool
+	for _, n := range legacy.TrustedProxyNets() {
+		private = private || n.Contains(net.ParseIP("192.168.1.20"))
+		public = public || n.Contains(net.ParseIP("203.0.113.5"))
+	}
+	if !private || public {
+		t.Fatalf("trustProxy ranges: private=%v public=%v", private, public)
+	}
+}
+
+func TestSecurityHeadersConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	data := []byte("" +
+		"http:\n" +
+		"  headers:\n    x-frame-options: \"\"\n    content-security-policy: \"default-src 'none'\"\n    Bad Name: x\n    Strict-Transport-Security: max-age=1\n" +
+		"  hsts:\n    maxAge: 720h\n    includeSubDomains: false\n    preload: true\n")
+	if err := os.WriteFile(cfgPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, problems := LoadFile(cfgPath)
+	fields := map[string]int{}
+	for _, p := range problems {
+		fields[p.Field]++
+	}
+	if fields["http.headers"] != 2 || fields["http.hsts.preload"] != 1 || len(problems) != 3 {
+		t.Fatalf("problems: %v", problems)
+	}
+	h := cfg.SecurityHeaders
+	if v, ok := h["X-Frame-Options"]; !ok || v != "" {
+		t.Errorf("X-Frame-Options should be blanked: %q %v", v, ok)
+	}
+	if h["Content-Security-Policy"] != "default-src 'none'" || h["Referrer-Policy"] != "no-referrer" {
+		t.Errorf("headers: %v", h)
+	}
+	if _, ok := h["Strict-Transport-Security"]; ok {
+		t.Errorf("HSTS accepted as a plain header")
+	}
+	if cfg.HSTSMaxAgeSeconds != 30*24*3600 || cfg.HSTSIncludeSubdomains || cfg.HSTSPreload {
+		t.Errorf("hsts: %d %v %v", cfg.HSTSMaxAgeSeconds, cfg.HSTSIncludeSubdomains, cfg.HSTSPreload)
+	}
+	if len(Defaults().SecurityHeaders["X-Frame-Options"]) == 0 {
+		t.Fatal("loading a file changed the defaults")
+	}
+
+	t.Setenv("NOS_HTTP_CSP", "default-src 'self'")
+	t.Setenv("NOS_HTTP_HSTS_MAX_AGE", "0s")
+	cfg = Load(cfgPath)
+	if cfg.SecurityHeaders["Content-Security-Policy"] != "default-src 'self'" || cfg.HSTSMaxAgeSeconds != 0 {
+		t.Fatalf("env override: %q %d", cfg.SecurityHeaders["Content-Security-Policy"], cfg.HSTSMaxAgeSeconds)
+	}
+}
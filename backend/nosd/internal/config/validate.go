@@ -0,0 +1,257 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Problem is one invalid configuration value. Non-fatal problems have been
+// replaced by a safe default; fatal ones leave the config unusable.
+type Problem struct {
+	Field   string
+	Message string
+	Fatal   bool
+}
+
+func (p Problem) String() string {
+	return p.Field + ": " + p.Message
+}
+
+// HasFatal reports whether any problem prevents using the config.
+func HasFatal(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Session lifetime bounds enforced by Validate.
+const (
+	minSessionTTLSeconds = 60
+	maxSessionTTLSeconds = 24 * 60 * 60
+	maxRefreshTTLSeconds = 90 * 24 * 60 * 60
+)
+
+// minHSTSPreloadSeconds is the shortest max-age browser preload lists take.
+const minHSTSPreloadSeconds = 365 * 24 * 60 * 60
+
+// Validate checks ranges and required fields. Out-of-range tunables are reset
+// to their Defaults() value and reported; missing paths and an unusable bind
+// address are reported as fatal.
+func (c *Config) Validate() []Problem {
+	d := Defaults()
+	var out []Problem
+	fix := func(field, msg string, apply func()) {
+		apply()
+		out = append(out, Problem{Field: field, Message: msg + "; using default"})
+	}
+	fatal := func(field, msg string) {
+		out = append(out, Problem{Field: field, Message: msg, Fatal: true})
+	}
+
+	if _, port, err := net.SplitHostPort(c.Bind); err != nil {
+		fatal("http.bind", fmt.Sprintf("invalid address %q: %v", c.Bind, err))
+	} else if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		fatal("http.bind", fmt.Sprintf("invalid port %q", port))
+	}
+	for _, f := range []struct{ field, value string }{
+		{"usersPath", c.UsersPath},
+		{"secretPath", c.SecretPath},
+		{"sessionsPath", c.SessionsPath},
+		{"rateLimitPath", c.RateLimitPath},
+		{"firstBootPath", c.FirstBootPath},
+		{"agent.socket", c.AgentSocketPath},
+	} {
+		if f.value == "" {
+			fatal(f.field, "required")
+		}
+	}
+
+	if c.RateOTPPerMin <= 0 {
+		fix("rate.otpPerMin", fmt.Sprintf("must be positive, got %d", c.RateOTPPerMin), func() { c.RateOTPPerMin = d.RateOTPPerMin })
+	}
+	if c.RateLoginPer15m <= 0 {
+		fix("rate.loginPer15m", fmt.Sprintf("must be positive, got %d", c.RateLoginPer15m), func() { c.RateLoginPer15m = d.RateLoginPer15m })
+	}
+	if c.RateOTPWindowSec <= 0 {
+		fix("rate.otpWindowSec", fmt.Sprintf("must be positive, got %d", c.RateOTPWindowSec), func() { c.RateOTPWindowSec = d.RateOTPWindowSec })
+	}
+	if c.RateLoginWindowSec <= 0 {
+		fix("rate.loginWindowSec", fmt.Sprintf("must be positive, got %d", c.RateLoginWindowSec), func() { c.RateLoginWindowSec = d.RateLoginWindowSec })
+	}
+	if c.RateOTPMaxAttempts <= 0 {
+		fix("rate.otpMaxAttempts", fmt.Sprintf("must be positive, got %d", c.RateOTPMaxAttempts), func() { c.RateOTPMaxAttempts = d.RateOTPMaxAttempts })
+	}
+
+	if c.SessionAccessTTLSeconds < minSessionTTLSeconds || c.SessionAccessTTLSeconds > maxSessionTTLSeconds {
+		fix("sessions.accessTTL", fmt.Sprintf("must be between 1m and 24h, got %ds", c.SessionAccessTTLSeconds), func() { c.SessionAccessTTLSeconds = d.SessionAccessTTLSeconds })
+	}
+	if c.SessionRefreshTTLSeconds > maxRefreshTTLSeconds {
+		fix("sessions.refreshTTL", fmt.Sprintf("must be at most 90 days, got %ds", c.SessionRefreshTTLSeconds), func() { c.SessionRefreshTTLSeconds = d.SessionRefreshTTLSeconds })
+	}
+	if c.SessionRefreshTTLSeconds < c.SessionAccessTTLSeconds {
+		fix("sessions.refreshTTL", "must not be shorter than accessTTL", func() {
+			c.SessionRefreshTTLSeconds = max(d.SessionRefreshTTLSeconds, c.SessionAccessTTLSeconds)
+		})
+	}
+	if !isSessionBindingMode(c.SessionBindingMode) {
+		fix("sessions.binding", fmt.Sprintf("unknown mode %q", c.SessionBindingMode), func() { c.SessionBindingMode = d.SessionBindingMode })
+	}
+
+	if c.Argon2Time < 1 {
+		fix("auth.argon2.time", "must be at least 1", func() { c.Argon2Time = d.Argon2Time })
+	}
+	if c.Argon2Threads < 1 {
+		fix("auth.argon2.threads", "must be at least 1", func() { c.Argon2Threads = d.Argon2Threads })
+	}
+	if c.Argon2MemoryKiB < 8*1024 {
+		fix("auth.argon2.memoryKiB", fmt.Sprintf("must be at least 8192, got %d", c.Argon2MemoryKiB), func() { c.Argon2MemoryKiB = d.Argon2MemoryKiB })
+	}
+
+	if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+		fix("smtp.port", fmt.Sprintf("out of range: %d", c.SMTPPort), func() { c.SMTPPort = d.SMTPPort })
+	}
+	if c.PublicURL != "" {
+		if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			out = append(out, Problem{Field: "http.publicURL", Message: fmt.Sprintf("not an absolute http(s) URL: %q; ignoring", c.PublicURL)})
+			c.PublicURL = ""
+		}
+	}
+	if c.TelemetryURL != "" {
+		if u, err := url.Parse(c.TelemetryURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			out = append(out, Problem{Field: "telemetry.url", Message: fmt.Sprintf("not an absolute http(s) URL: %q; telemetry disabled", c.TelemetryURL)})
+			c.TelemetryURL = ""
+		}
+	}
+
+	origins := c.CORSOrigins[:0:0]
+	for _, o := range c.CORSOrigins {
+		if validOrigin(o) {
+			origins = append(origins, o)
+		} else {
+			out = append(out, Problem{Field: "cors.origins", Message: fmt.Sprintf("%q is not an origin (scheme://host[:port], optionally *.domain) or \"*\"; dropped", o)})
+		}
+	}
+	if len(origins) != len(c.CORSOrigins) {
+		c.CORSOrigins = origins
+	}
+
+	proxies := c.TrustedProxies[:0:0]
+	for _, p := range c.TrustedProxies {
+		if _, ok := parseProxyNet(p); ok {
+			proxies = append(proxies, p)
+		} else {
+			out = append(out, Problem{Field: "trustedProxies", Message: fmt.Sprintf("%q is not an IP or CIDR; dropped", p)})
+		}
+	}
+	if len(proxies) != len(c.TrustedProxies) {
+		c.TrustedProxies = proxies
+	}
+	if c.TrustProxy {
+		out = append(out, Problem{Field: "trustProxy", Message: "deprecated: trusts X-Forwarded-For from any loopback or private address; list the proxies in trustedProxies instead"})
+	}
+
+	kept := c.MetricsAllowlist[:0:0]
+	for _, a := range c.MetricsAllowlist {
+		if net.ParseIP(a) != nil || strings.HasSuffix(a, ".") {
+			kept = append(kept, a)
+		} else if _, _, err := net.ParseCIDR(a); err == nil {
+			kept = append(kept, a)
+		} else {
+			out = append(out, Problem{Field: "metrics.allowlist", Message: fmt.Sprintf("%q is not an IP, CIDR or dot-suffix prefix; dropped", a)})
+		}
+	}
+	if len(kept) != len(c.MetricsAllowlist) {
+		c.MetricsAllowlist = kept
+	}
+
+	if c.PasswordMaxAgeDays < 0 {
+		fix("auth.passwordMaxAgeDays", "must not be negative", func() { c.PasswordMaxAgeDays = d.PasswordMaxAgeDays })
+	}
+	if c.UpdatesCheckSeconds < 0 {
+		fix("updates.checkInterval", "must not be negative", func() { c.UpdatesCheckSeconds = d.UpdatesCheckSeconds })
+	}
+	if c.BootConfirmSeconds < 0 {
+		fix("updates.bootConfirmWindow", "must not be negative", func() { c.BootConfirmSeconds = d.BootConfirmSeconds })
+	}
+	if c.UpdatesSnapshotScope != "os" && c.UpdatesSnapshotScope != "all" {
+		fix("updates.snapshotScope", fmt.Sprintf("must be \"os\" or \"all\", got %q", c.UpdatesSnapshotScope), func() { c.UpdatesSnapshotScope = d.UpdatesSnapshotScope })
+	}
+	if err := c.MaintenanceWindow.Validate(); err != nil {
+		out = append(out, Problem{Field: "maintenance", Message: err.Error() + "; window disabled"})
+		c.MaintenanceWindow = d.MaintenanceWindow
+	}
+	if c.SmartScanSeconds < 0 {
+		fix("smart.scanInterval", "must not be negative", func() { c.SmartScanSeconds = d.SmartScanSeconds })
+	}
+
+	if c.SupportLogMaxBytes <= 0 {
+		fix("support.logMaxBytes", "must be positive", func() { c.SupportLogMaxBytes = d.SupportLogMaxBytes })
+	}
+	if c.SupportLogWindowSeconds <= 0 {
+		fix("support.logWindow", "must be positive", func() { c.SupportLogWindowSeconds = d.SupportLogWindowSeconds })
+	}
+	for name, value := range c.SecurityHeaders {
+		msg := ""
+		switch {
+		case !validHeaderName(name):
+			msg = fmt.Sprintf("%q is not a header name; dropped", name)
+		case name == "Strict-Transport-Security":
+			msg = "set through http.hsts, which only sends it over HTTPS; dropped"
+		case strings.ContainsAny(value, "\r\n"):
+			msg = fmt.Sprintf("%s value contains a line break; dropped", name)
+		}
+		if msg != "" {
+			out = append(out, Problem{Field: "http.headers", Message: msg})
+			c.SecurityHeaders = maps.Clone(c.SecurityHeaders)
+			delete(c.SecurityHeaders, name)
+		}
+	}
+	if c.HSTSMaxAgeSeconds < 0 {
+		fix("http.hsts.maxAge", "must not be negative", func() { c.HSTSMaxAgeSeconds = d.HSTSMaxAgeSeconds })
+	}
+	if c.HSTSPreload && (!c.HSTSIncludeSubdomains || c.HSTSMaxAgeSeconds < minHSTSPreloadSeconds) {
+		out = append(out, Problem{Field: "http.hsts.preload", Message: "needs includeSubDomains and a maxAge of at least 8760h (one year); preload not sent"})
+		c.HSTSPreload = false
+	}
+	if c.AccessLogSample < 1 {
+		fix("logging.access.sample", fmt.Sprintf("must be at least 1, got %d", c.AccessLogSample), func() { c.AccessLogSample = d.AccessLogSample })
+	}
+	if c.MaxBodyBytes <= 0 {
+		fix("http.maxBodyBytes", "must be positive", func() { c.MaxBodyBytes = d.MaxBodyBytes })
+	}
+	return out
+}
+
+// validHeaderName reports whether name is an HTTP header field name (an
+// RFC 9110 token).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// validOrigin accepts "*" and http(s) origins without a path; the host may
+// start with "*." to allow any subdomain.
+func validOrigin(o string) bool {
+	if o == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(strings.TrimRight(o, "/"), "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultsAreValid(t *testing.T) {
+	cfg := Defaults()
+	if problems := cfg.Validate(); len(problems) != 0 {
+		t.Fatalf("defaults should validate: %v", problems)
+	}
+}
+
+func TestValidateSubstitutesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	data := []byte("" +
+		"rate:\n  otpPerMin: -1\n  loginWindowSec: -30\n" +
+		"sessions:\n  accessTTL: 2h\n  refreshTTL: 10m\n  binding: strict\n" +
+		"logging:\n  level: loud\n  access:\n    sample: -2\n" +
+		"smtp:\n  port: 70000\n" +
+		"http:\n  publicURL: nas.local\n  maxBodyBytes: -1\n" +
+		"metrics:\n  allowlist: [10.0.0.0/8, not-an-ip, 192.168.1.5, \"172.16.\"]\n" +
+		"updates:\n  checkInterval: hourly\n  snapshotScope: everything\n" +
+		"maintenance:\n  enabled: true\n  days: [funday]\n  start: \"01:00\"\n  end: \"05:00\"\n" +
+		"support:\n  logMaxBytes: -5\n")
+	if err := os.WriteFile(cfgPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, problems := LoadFile(cfgPath)
+	if HasFatal(problems) {
+		t.Fatalf("expected only recoverable problems: %v", problems)
+	}
+	fields := map[string]bool{}
+	for _, p := range problems {
+		fields[p.Field] = true
+	}
+	for _, f := range []string{"rate.otpPerMin", "rate.loginWindowSec", "sessions.refreshTTL", "sessions.binding", "logging.level", "smtp.port", "http.publicURL", "metrics.allowlist", "updates.checkInterval", "updates.snapshotScope", "maintenance", "support.logMaxBytes", "http.maxBodyBytes", "logging.access.sample"} {
+		if !fields[f] {
+			t.Errorf("no problem reported for %s: %v", f, problems)
+		}
+	}
+
+	d := Defaults()
+	if cfg.RateOTPPerMin != d.RateOTPPerMin || cfg.RateLoginWindowSec != d.RateLoginWindowSec {
+		t.Errorf("rate defaults not restored: %d %d", cfg.RateOTPPerMin, cfg.RateLoginWindowSec)
+	}
+	if cfg.SessionAccessTTLSeconds != 7200 || cfg.SessionRefreshTTLSeconds < cfg.SessionAccessTTLSeconds {
+		t.Errorf("session ttls: %d %d", cfg.SessionAccessTTLSeconds, cfg.SessionRefreshTTLSeconds)
+	}
+	if cfg.SessionBindingMode != SessionBindingOff || cfg.SMTPPort != 587 || cfg.PublicURL != "" {
+		t.Errorf("binding/smtp/publicURL: %q %d %q", cfg.SessionBindingMode, cfg.SMTPPort, cfg.PublicURL)
+	}
+	if strings.Join(cfg.MetricsAllowlist, ",") != "10.0.0.0/8,192.168.1.5,172.16." {
+		t.Errorf("allowlist: %v", cfg.MetricsAllowlist)
+	}
+	if cfg.UpdatesSnapshotScope != "os" || cfg.MaintenanceWindow.Enabled || cfg.SupportLogMaxBytes != d.SupportLogMaxBytes || cfg.MaxBodyBytes != d.MaxBodyBytes || cfg.AccessLogSample != d.AccessLogSample {
+		t.Errorf("updates/maintenance/support: %+v", cfg)
+	}
+}
+
+func TestValidateFatalProblems(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("rate:\n  otpPerMin: [1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, problems := LoadFile(cfgPath); !HasFatal(problems) {
+		t.Fatalf("malformed YAML should be fatal: %v", problems)
+	}
+
+	cfg := Defaults()
+	cfg.Bind = "127.0.0.1:99999"
+	cfg.UsersPath = ""
+	problems := cfg.Validate()
+	if !HasFatal(problems) || len(problems) != 2 {
+		t.Fatalf("expected bind and usersPath to be fatal: %v", problems)
+	}
+
+	// a missing file is not a problem
+	if _, problems := LoadFile(filepath.Join(dir, "absent.yaml")); len(problems) != 0 {
+		t.Fatalf("missing file: %v", problems)
+	}
+}
+
+func TestValidateSessionTTLBounds(t *testing.T) {
+	d := Defaults()
+	cfg := Defaults()
+	cfg.SessionAccessTTLSeconds = 10
+	cfg.SessionRefreshTTLSeconds = 365 * 24 * 60 * 60
+	problems := cfg.Validate()
+	if len(problems) != 2 || HasFatal(problems) {
+		t.Fatalf("expected two recoverable problems: %v", problems)
+	}
+	if cfg.SessionTTL() != d.SessionTTL() || cfg.RefreshTTL() != d.RefreshTTL() {
+		t.Fatalf("ttls not reset: %v %v", cfg.SessionTTL(), cfg.RefreshTTL())
+	}
+}
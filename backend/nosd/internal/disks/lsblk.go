@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"os/exec"
 	"strconv"
-	"strings"
 	"time"
 
 	"nithronos/backend/nosd/pkg/shell"
@@ -90,64 +89,75 @@ func SmartSummaryFor(ctx context.Context, devicePath string) *SmartSummary {
 	if err != nil {
 		return nil
 	}
+	return ParseSmartctl(res.Stdout)
+}
+
+// ATA attribute IDs read from the vendor table.
+const (
+	attrReallocated   = 5
+	attrPending       = 197
+	attrUncorrectable = 198
+	attrCRCErrors     = 199
+)
+
+// ParseSmartctl builds a summary from `smartctl -H -A -j` output, or nil
+// when it carries neither health, temperature nor power-on time.
+func ParseSmartctl(b []byte) *SmartSummary {
 	var parsed map[string]any
-	if err := json.Unmarshal(res.Stdout, &parsed); err != nil {
+	if err := json.Unmarshal(b, &parsed); err != nil {
 		return nil
 	}
-	var healthy *bool
-	if s, ok := parsed["smart_status"].(map[string]any); ok {
-		if p, ok := s["passed"].(bool); ok {
-			healthy = &p
+	s := &SmartSummary{}
+	if st, ok := parsed["smart_status"].(map[string]any); ok {
+		if p, ok := st["passed"].(bool); ok {
+			s.Healthy = &p
 		}
 	}
-	var temp *int
 	if t, ok := parsed["temperature"].(map[string]any); ok {
-		if c, ok := t["current"].(float64); ok {
-			v := int(c)
-			temp = &v
-		}
+		s.TempCelsius = intField(t, "current")
 	}
-	var poh *int
-	var reallocated *int
-	var mediaErr *int
 	if a, ok := parsed["power_on_time"].(map[string]any); ok {
-		if h, ok := a["hours"].(float64); ok {
-			v := int(h)
-			poh = &v
-		}
+		s.PowerOnHours = intField(a, "hours")
 	}
-	// try vendor tables for extended info
+	// vendor table for extended info; match on ID since names vary by vendor
 	if table, ok := parsed["ata_smart_attributes"].(map[string]any); ok {
-		if jv, ok2 := table["table"].([]any); ok2 {
-			for _, row := range jv {
-				if m, ok3 := row.(map[string]any); ok3 {
-					if name, ok4 := m["name"].(string); ok4 {
-						ln := strings.ToLower(name)
-						if strings.Contains(ln, "reallocated") {
-							if raw, ok5 := m["raw"].(map[string]any); ok5 {
-								if val, ok6 := raw["value"].(float64); ok6 {
-									x := int(val)
-									reallocated = &x
-								}
-							}
-						}
-					}
-				}
+		rows, _ := table["table"].([]any)
+		for _, row := range rows {
+			m, ok := row.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := m["id"].(float64)
+			raw, _ := m["raw"].(map[string]any)
+			switch int(id) {
+			case attrReallocated:
+				s.Reallocated = intField(raw, "value")
+			case attrPending:
+				s.PendingSectors = intField(raw, "value")
+			case attrUncorrectable:
+				s.Uncorrectable = intField(raw, "value")
+			case attrCRCErrors:
+				s.CRCErrors = intField(raw, "value")
 			}
 		}
 	}
 	if nvme, ok := parsed["nvme_smart_health_information_log"].(map[string]any); ok {
-		if me, ok2 := nvme["media_errors"].(float64); ok2 {
-			v := int(me)
-			mediaErr = &v
-		}
-		if t, ok2 := nvme["temperature"].(float64); ok2 && temp == nil {
-			vv := int(t)
-			temp = &vv
+		s.MediaErrors = intField(nvme, "media_errors")
+		s.PercentUsed = intField(nvme, "percentage_used")
+		if s.TempCelsius == nil {
+			s.TempCelsius = intField(nvme, "temperature")
 		}
 	}
-	if healthy == nil && temp == nil && poh == nil {
+	if s.Healthy == nil && s.TempCelsius == nil && s.PowerOnHours == nil {
 		return nil
 	}
-	return &SmartSummary{Healthy: healthy, TempCelsius: temp, PowerOnHours: poh, Reallocated: reallocated, MediaErrors: mediaErr}
+	return s
+}
+
+func intField(m map[string]any, key string) *int {
+	if v, ok := m[key].(float64); ok {
+		n := int(v)
+		return &n
+	}
+	return nil
 }
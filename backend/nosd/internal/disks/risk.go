@@ -0,0 +1,48 @@
+package disks
+
+import "fmt"
+
+// RiskThresholds are the attribute counts at which a disk is flagged
+// at_risk. These counters predict failure well before the overall SMART
+// verdict flips; zero disables a check.
+type RiskThresholds struct {
+	Reallocated   int `json:"reallocated"`
+	Pending       int `json:"pending"`
+	Uncorrectable int `json:"uncorrectable"`
+	CRCErrors     int `json:"crcErrors"`
+	MediaErrors   int `json:"mediaErrors"`
+	PercentUsed   int `json:"percentUsed"`
+}
+
+// DefaultRiskThresholds flags any reallocated, pending or uncorrectable
+// sector and any NVMe media error. CRC errors usually mean a bad cable, so
+// a few are tolerated; NVMe wear is flagged at 90% of rated endurance.
+func DefaultRiskThresholds() RiskThresholds {
+	return RiskThresholds{Reallocated: 1, Pending: 1, Uncorrectable: 1, CRCErrors: 10, MediaErrors: 1, PercentUsed: 90}
+}
+
+// EvaluateRisk sets AtRisk and RiskReasons from the summary's counters. A
+// failed overall SMART status always counts.
+func (s *SmartSummary) EvaluateRisk(t RiskThresholds) {
+	s.AtRisk, s.RiskReasons = false, nil
+	if s.Healthy != nil && !*s.Healthy {
+		s.RiskReasons = append(s.RiskReasons, "SMART overall-health failed")
+	}
+	for _, c := range []struct {
+		value     *int
+		threshold int
+		what      string
+	}{
+		{s.Reallocated, t.Reallocated, "reallocated sectors"},
+		{s.PendingSectors, t.Pending, "pending sectors"},
+		{s.Uncorrectable, t.Uncorrectable, "offline uncorrectable sectors"},
+		{s.CRCErrors, t.CRCErrors, "interface CRC errors"},
+		{s.MediaErrors, t.MediaErrors, "media errors"},
+		{s.PercentUsed, t.PercentUsed, "percent of rated endurance used"},
+	} {
+		if c.value != nil && c.threshold > 0 && *c.value >= c.threshold {
+			s.RiskReasons = append(s.RiskReasons, fmt.Sprintf("%s: %d (threshold %d)", c.what, *c.value, c.threshold))
+		}
+	}
+	s.AtRisk = len(s.RiskReasons) > 0
+}
@@ -0,0 +1,61 @@
+package disks
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadSmart(t *testing.T, name string) *SmartSummary {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	s := ParseSmartctl(b)
+	if s == nil {
+		t.Fatalf("%s: no summary parsed", name)
+	}
+	return s
+}
+
+func TestEvaluateRiskFixtures(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		atRisk  bool
+		reasons []string
+	}{
+		{"smart_ata_healthy.json", false, nil},
+		// vendor-specific attribute name, matched by id
+		{"smart_ata_at_risk.json", true, []string{"pending sectors: 8", "offline uncorrectable sectors: 2", "interface CRC errors: 14"}},
+		{"smart_nvme_worn.json", true, []string{"percent of rated endurance used: 93"}},
+		{"smart_ata_failed.json", true, []string{"SMART overall-health failed"}},
+	} {
+		s := loadSmart(t, tc.fixture)
+		s.EvaluateRisk(DefaultRiskThresholds())
+		if s.AtRisk != tc.atRisk || len(s.RiskReasons) != len(tc.reasons) {
+			t.Fatalf("%s: at_risk=%v reasons=%q", tc.fixture, s.AtRisk, s.RiskReasons)
+		}
+		for i, want := range tc.reasons {
+			if !strings.HasPrefix(s.RiskReasons[i], want) {
+				t.Fatalf("%s: reason %d = %q, want prefix %q", tc.fixture, i, s.RiskReasons[i], want)
+			}
+		}
+	}
+}
+
+func TestEvaluateRiskCustomThresholds(t *testing.T) {
+	s := loadSmart(t, "smart_ata_at_risk.json")
+	th := DefaultRiskThresholds()
+	th.Pending, th.Uncorrectable = 0, 0 // disabled
+	th.CRCErrors = 20
+	s.EvaluateRisk(th)
+	if s.AtRisk || s.RiskReasons != nil {
+		t.Fatalf("expected no risk with relaxed thresholds: %q", s.RiskReasons)
+	}
+	th.CRCErrors = 14 // the threshold itself counts
+	s.EvaluateRisk(th)
+	if !s.AtRisk || len(s.RiskReasons) != 1 {
+		t.Fatalf("expected CRC errors at threshold to flag: %q", s.RiskReasons)
+	}
+}
@@ -1,11 +1,18 @@
 package disks
 
 type SmartSummary struct {
-	Healthy      *bool `json:"healthy,omitempty"`
-	TempCelsius  *int  `json:"temp_c,omitempty"`
-	PowerOnHours *int  `json:"power_on_hours,omitempty"`
-	Reallocated  *int  `json:"reallocated_sectors,omitempty"`
-	MediaErrors  *int  `json:"media_errors,omitempty"`
+	Healthy        *bool `json:"healthy,omitempty"`
+	TempCelsius    *int  `json:"temp_c,omitempty"`
+	PowerOnHours   *int  `json:"power_on_hours,omitempty"`
+	Reallocated    *int  `json:"reallocated_sectors,omitempty"`
+	PendingSectors *int  `json:"pending_sectors,omitempty"`
+	Uncorrectable  *int  `json:"offline_uncorrectable,omitempty"`
+	CRCErrors      *int  `json:"crc_errors,omitempty"`
+	MediaErrors    *int  `json:"media_errors,omitempty"`
+	PercentUsed    *int  `json:"percentage_used,omitempty"`
+	// Set by EvaluateRisk
+	AtRisk      bool     `json:"at_risk"`
+	RiskReasons []string `json:"risk_reasons,omitempty"`
 }
 
 type Disk struct {
@@ -0,0 +1,281 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"nithronos/backend/nosd/pkg/auth"
+)
+
+// Channel types (Channel.Type).
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelNtfy    = "ntfy"
+	ChannelGotify  = "gotify"
+	ChannelSyslog  = "syslog"
+)
+
+// Sender delivers a notification to one external channel.
+type Sender interface {
+	Send(ctx context.Context, n *Notification) error
+}
+
+// deliveryTimeout bounds a single delivery attempt.
+const deliveryTimeout = 15 * time.Second
+
+var (
+	httpClient = &http.Client{Timeout: deliveryTimeout}
+	// newMailer is swapped in tests; email goes through the same SMTP mailer
+	// as password resets.
+	newMailer = func(cfg auth.SMTPConfig) auth.Mailer { return auth.NewSMTPMailer(cfg) }
+)
+
+// NewSender builds the sender for c, validating its config.
+func NewSender(c *Channel) (Sender, error) {
+	switch c.Type {
+	case ChannelEmail:
+		sc := auth.SMTPConfig{
+			Host:     configString(c.Config, "host"),
+			From:     configString(c.Config, "from"),
+			Username: configString(c.Config, "username"),
+			Password: configString(c.Config, "password"),
+		}
+		var to []string
+		for _, addr := range strings.Split(configString(c.Config, "to"), ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+		if sc.Host == "" || sc.From == "" || len(to) == 0 {
+			return nil, fmt.Errorf("email channel requires host, from and to")
+		}
+		if p := configString(c.Config, "port"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 || n > 65535 {
+				return nil, fmt.Errorf("email channel port must be between 1 and 65535")
+			}
+			sc.Port = n
+		}
+		return &emailSender{mailer: newMailer(sc), to: to}, nil
+	case ChannelWebhook:
+		u, err := channelURL(c.Config, "url", "")
+		if err != nil {
+			return nil, err
+		}
+		s := &webhookSender{url: u, headers: map[string]string{}}
+		if h, ok := c.Config["headers"].(map[string]interface{}); ok {
+			for k, v := range h {
+				if sv, ok := v.(string); ok {
+					s.headers[k] = sv
+				}
+			}
+		}
+		return s, nil
+	case ChannelNtfy:
+		server, err := channelURL(c.Config, "server", "https://ntfy.sh")
+		if err != nil {
+			return nil, err
+		}
+		topic := configString(c.Config, "topic")
+		if topic == "" || strings.Contains(topic, "/") {
+			return nil, fmt.Errorf("ntfy channel requires a topic")
+		}
+		return &ntfySender{url: strings.TrimSuffix(server, "/") + "/" + url.PathEscape(topic), token: configString(c.Config, "token")}, nil
+	case ChannelGotify:
+		server, err := channelURL(c.Config, "url", "")
+		if err != nil {
+			return nil, err
+		}
+		token := configString(c.Config, "token")
+		if token == "" {
+			return nil, fmt.Errorf("gotify channel requires an application token")
+		}
+		return &gotifySender{url: strings.TrimSuffix(server, "/") + "/message", token: token}, nil
+	case ChannelSyslog:
+		return syslogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel type: %s", c.Type)
+	}
+}
+
+// ValidateChannel reports whether c has a name and a usable config for its
+// type.
+func ValidateChannel(c *Channel) error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("channel name is required")
+	}
+	_, err := NewSender(c)
+	return err
+}
+
+// configString reads a string config value; numbers (JSON-decoded as
+// float64) are formatted so a port can be given either way.
+func configString(cfg map[string]interface{}, key string) string {
+	switch v := cfg[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return ""
+}
+
+func channelURL(cfg map[string]interface{}, key, def string) (string, error) {
+	raw := configString(cfg, key)
+	if raw == "" {
+		raw = def
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%s must be an absolute http(s) URL", key)
+	}
+	return raw, nil
+}
+
+// priority maps a notification type onto the 1-5 scale ntfy uses; Gotify
+// takes the same numbers scaled to 0-10.
+func priority(notifType string) int {
+	switch notifType {
+	case "error":
+		return 5
+	case "warning":
+		return 4
+	case "success":
+		return 2
+	}
+	return 3
+}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return do(req)
+}
+
+func do(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// emailSender mails each recipient through the shared SMTP mailer.
+type emailSender struct {
+	mailer auth.Mailer
+	to     []string
+}
+
+func (s *emailSender) Send(ctx context.Context, n *Notification) error {
+	subject := fmt.Sprintf("[NithronOS %s] %s", strings.ToUpper(n.Type), n.Title)
+	body := fmt.Sprintf("Time: %s\n\n%s\n", n.Timestamp.Format(time.RFC3339), n.Message)
+	if len(n.Details) > 0 {
+		body += "\n\nDetails:\n"
+		for k, v := range n.Details {
+			body += fmt.Sprintf("  %s: %v\n", k, v)
+		}
+	}
+	// the mailer has no context support; give up waiting once ctx is done
+	done := make(chan error, 1)
+	go func() {
+		for _, to := range s.to {
+			if err := s.mailer.Send(to, subject, body); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// webhookSender POSTs the notification as JSON.
+type webhookSender struct {
+	url     string
+	headers map[string]string
+}
+
+func (s *webhookSender) Send(ctx context.Context, n *Notification) error {
+	return postJSON(ctx, s.url, s.headers, n)
+}
+
+// ntfySender publishes to an ntfy topic: the message is the body, the rest
+// travels in headers.
+type ntfySender struct {
+	url   string
+	token string
+}
+
+func (s *ntfySender) Send(ctx context.Context, n *Notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(n.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", n.Title)
+	req.Header.Set("Priority", strconv.Itoa(priority(n.Type)))
+	req.Header.Set("Tags", n.Type+","+n.Category)
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return do(req)
+}
+
+// gotifySender posts to a Gotify server's /message endpoint with an
+// application token.
+type gotifySender struct {
+	url   string
+	token string
+}
+
+func (s *gotifySender) Send(ctx context.Context, n *Notification) error {
+	return postJSON(ctx, s.url, map[string]string{"X-Gotify-Key": s.token}, map[string]any{
+		"title":    n.Title,
+		"message":  n.Message,
+		"priority": priority(n.Type) * 2,
+	})
+}
+
+// syslogSender writes to the daemon log, which journald collects.
+type syslogSender struct{}
+
+func (syslogSender) Send(ctx context.Context, n *Notification) error {
+	log.Info().
+		Str("type", n.Type).
+		Str("category", n.Category).
+		Str("title", n.Title).
+		Msg(n.Message)
+	return nil
+}
@@ -0,0 +1,242 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/pkg/auth"
+)
+
+type received struct {
+	path    string
+	headers http.Header
+	body    string
+}
+
+// mockServer records every request and answers with status.
+func mockServer(t *testing.T, status int) (*httptest.Server, chan received) {
+	t.Helper()
+	got := make(chan received, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got <- received{path: r.URL.Path, headers: r.Header.Clone(), body: string(b)}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func wait(t *testing.T, got chan received) received {
+	t.Helper()
+	select {
+	case r := <-got:
+		return r
+	case <-time.After(3 * time.Second):
+		t.Fatal("no request received")
+	}
+	return received{}
+}
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestWebhookChannelTestAndRouting(t *testing.T) {
+	srv, got := mockServer(t, http.StatusNoContent)
+	m := newTestManager(t)
+	ch := &Channel{Name: "hook", Type: ChannelWebhook, Enabled: true,
+		Config:  map[string]interface{}{"url": srv.URL + "/hook", "headers": map[string]interface{}{"X-Token": "abc"}},
+		Filters: []Filter{{Categories: []string{"storage"}, MinLevel: "warning"}}}
+	if err := m.CreateChannel(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.TestChannel(ch.ID); err != nil {
+		t.Fatalf("test channel: %v", err)
+	}
+	r := wait(t, got)
+	var n Notification
+	if err := json.Unmarshal([]byte(r.body), &n); err != nil {
+		t.Fatalf("payload: %v %q", err, r.body)
+	}
+	if r.path != "/hook" || r.headers.Get("X-Token") != "abc" || n.Title != "Test Notification" {
+		t.Fatalf("unexpected request: %+v %+v", r, n)
+	}
+
+	// filtered out: wrong category, then below the minimum level
+	m.SendSystemNotification("boot", "system started", "info")
+	m.SendStorageNotification("scrub", "scrub finished", "info", nil)
+	m.SendStorageNotification("Disk alert: /dev/sda", "temperature high", "warning", map[string]interface{}{"device": "/dev/sda"})
+	r = wait(t, got)
+	if err := json.Unmarshal([]byte(r.body), &n); err != nil || n.Title != "Disk alert: /dev/sda" {
+		t.Fatalf("routed notification: %v %q", err, r.body)
+	}
+	select {
+	case extra := <-got:
+		t.Fatalf("unexpected delivery: %q", extra.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestTestChannelReportsDeliveryFailure(t *testing.T) {
+	srv, _ := mockServer(t, http.StatusInternalServerError)
+	m := newTestManager(t)
+	ch := &Channel{Name: "hook", Type: ChannelWebhook, Enabled: true, Config: map[string]interface{}{"url": srv.URL}}
+	if err := m.CreateChannel(ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.TestChannel(ch.ID); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("expected delivery error, got %v", err)
+	}
+	if err := m.TestChannel("missing"); !errors.Is(err, ErrChannelNotFound) {
+		t.Fatalf("expected ErrChannelNotFound, got %v", err)
+	}
+}
+
+func TestNtfyAndGotifySenders(t *testing.T) {
+	srv, got := mockServer(t, http.StatusOK)
+	n := &Notification{Type: "error", Category: "storage", Title: "Pool degraded", Message: "tank lost a device"}
+
+	s, err := NewSender(&Channel{Type: ChannelNtfy, Config: map[string]interface{}{"server": srv.URL, "topic": "nas", "token": "tk"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	r := wait(t, got)
+	if r.path != "/nas" || r.body != n.Message || r.headers.Get("Title") != n.Title || r.headers.Get("Priority") != "5" || r.headers.Get("Authorization") != "Bearer tk" {
+		t.Fatalf("ntfy request: %+v", r)
+	}
+
+	s, err = NewSender(&Channel{Type: ChannelGotify, Config: map[string]interface{}{"url": srv.URL, "token": "app"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	r = wait(t, got)
+	var body map[string]any
+	_ = json.Unmarshal([]byte(r.body), &body)
+	if r.path != "/message" || r.headers.Get("X-Gotify-Key") != "app" || body["title"] != n.Title || body["priority"] != float64(10) {
+		t.Fatalf("gotify request: %+v %v", r, body)
+	}
+}
+
+// fakeMailer records what the email sender hands to the shared mailer.
+type fakeMailer struct {
+	cfg  auth.SMTPConfig
+	to   []string
+	subj string
+}
+
+func (f *fakeMailer) Send(to, subject, body string) error {
+	f.to = append(f.to, to)
+	f.subj = subject
+	return nil
+}
+
+func TestEmailSender(t *testing.T) {
+	var fm *fakeMailer
+	newMailer = func(cfg auth.SMTPConfig) auth.Mailer {
+		fm = &fakeMailer{cfg: cfg}
+		return fm
+	}
+	t.Cleanup(func() { newMailer = func(cfg auth.SMTPConfig) auth.Mailer { return auth.NewSMTPMailer(cfg) } })
+
+	s, err := NewSender(&Channel{Type: ChannelEmail, Config: map[string]interface{}{
+		"host": "mail.example", "port": float64(2525), "from": "nas@example", "to": "a@example, b@example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), &Notification{Type: "warning", Title: "Hot disk", Message: "sda at 62C"}); err != nil {
+		t.Fatal(err)
+	}
+	if fm.cfg.Host != "mail.example" || fm.cfg.Port != 2525 || fm.cfg.From != "nas@example" || len(fm.to) != 2 || fm.subj != "[NithronOS WARNING] Hot disk" {
+		t.Fatalf("unexpected mail: %+v", fm)
+	}
+	if _, err := NewSender(&Channel{Type: ChannelEmail, Config: map[string]interface{}{
+		"host": "mail.example", "port": "smtp", "from": "nas@example", "to": "a@example"}}); err == nil {
+		t.Fatal("invalid port accepted")
+	}
+}
+
+func TestChannelValidation(t *testing.T) {
+	m := newTestManager(t)
+	for _, c := range []*Channel{
+		{Name: "no-url", Type: ChannelWebhook, Config: map[string]interface{}{}},
+		{Name: "bad-url", Type: ChannelWebhook, Config: map[string]interface{}{"url": "ftp://x"}},
+		{Name: "no-topic", Type: ChannelNtfy, Config: map[string]interface{}{}},
+		{Name: "no-token", Type: ChannelGotify, Config: map[string]interface{}{"url": "https://push.example"}},
+		{Name: "no-to", Type: ChannelEmail, Config: map[string]interface{}{"host": "h", "from": "f"}},
+		{Name: "pager", Type: "pager"},
+		{Type: ChannelSyslog},
+	} {
+		if err := m.CreateChannel(c); err == nil {
+			t.Errorf("%s/%s: expected validation error", c.Name, c.Type)
+		}
+	}
+}
+
+func TestRedactConfigHidesWebhookHeaders(t *testing.T) {
+	m := newTestManager(t)
+	ch := &Channel{Name: "hook", Type: ChannelWebhook, Enabled: true, Config: map[string]interface{}{
+		"url": "https://hooks.example/x", "headers": map[string]interface{}{"Authorization": "Bearer s3cret", "X-Team": "ops"}}}
+	if err := m.CreateChannel(ch); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.GetChannel(ch.ID)
+	red := RedactConfig(got.Config)
+	h, _ := red["headers"].(map[string]interface{})
+	if h["Authorization"] != redacted || h["X-Team"] != redacted || red["url"] != "https://hooks.example/x" {
+		t.Fatalf("headers not redacted: %v", red)
+	}
+	if got.Config["headers"].(map[string]interface{})["Authorization"] != "Bearer s3cret" {
+		t.Fatalf("redaction changed the stored config: %v", got.Config)
+	}
+
+	// a redacted header sent back keeps its stored value; a changed one is replaced
+	h["X-Team"] = "storage"
+	if err := m.UpdateChannel(ch.ID, &Channel{Enabled: true, Config: red}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = m.GetChannel(ch.ID)
+	stored := got.Config["headers"].(map[string]interface{})
+	if stored["Authorization"] != "Bearer s3cret" || stored["X-Team"] != "storage" {
+		t.Fatalf("unexpected headers after update: %v", stored)
+	}
+}
+
+func TestUpdateChannelKeepsRedactedSecrets(t *testing.T) {
+	m := newTestManager(t)
+	ch := &Channel{Name: "push", Type: ChannelGotify, Enabled: true, Config: map[string]interface{}{"url": "https://push.example", "token": "s3cret"}}
+	if err := m.CreateChannel(ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateChannel(ch.ID, &Channel{Enabled: true, Config: map[string]interface{}{"url": "https://push2.example", "token": redacted}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.GetChannel(ch.ID)
+	if got.Config["token"] != "s3cret" || got.Config["url"] != "https://push2.example" {
+		t.Fatalf("unexpected config: %v", got.Config)
+	}
+	if err := m.UpdateChannel(ch.ID, &Channel{Config: map[string]interface{}{"url": "nope"}}); !errors.Is(err, ErrInvalidChannel) {
+		t.Fatalf("invalid update: %v", err)
+	}
+	if got, _ := m.GetChannel(ch.ID); got.Config["url"] != "https://push2.example" {
+		t.Fatalf("invalid update applied: %v", got.Config)
+	}
+}
@@ -2,11 +2,10 @@ package notifications
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/smtp"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -40,7 +39,7 @@ type Action struct {
 type Channel struct {
 	ID      string                 `json:"id"`
 	Name    string                 `json:"name"`
-	Type    string                 `json:"type"` // email, webhook, syslog
+	Type    string                 `json:"type"` // email, webhook, ntfy, gotify, syslog
 	Enabled bool                   `json:"enabled"`
 	Config  map[string]interface{} `json:"config"`
 	Filters []Filter               `json:"filters"`
@@ -53,11 +52,78 @@ type Filter struct {
 	MinLevel   string   `json:"minLevel,omitempty"` // info, warning, error
 }
 
+// ErrChannelNotFound is returned for an unknown channel ID.
+var ErrChannelNotFound = errors.New("channel not found")
+
+// ErrInvalidChannel wraps validation failures from CreateChannel and
+// UpdateChannel; the wrapped message is meant for the user.
+var ErrInvalidChannel = errors.New("invalid channel")
+
+type invalidChannelError struct{ err error }
+
+func (e invalidChannelError) Error() string        { return e.err.Error() }
+func (e invalidChannelError) Is(target error) bool { return target == ErrInvalidChannel }
+
+// redacted replaces secret config values in API responses.
+const redacted = "***"
+
+// secretKeys are the config values RedactConfig hides.
+var secretKeys = map[string]bool{"password": true, "apiKey": true, "token": true, "secret": true}
+
+// RedactConfig returns a copy of a channel config with secrets and webhook
+// header values replaced by a placeholder. UpdateChannel keeps the stored
+// value wherever the placeholder comes back.
+func RedactConfig(cfg map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(cfg))
+	for k, v := range cfg {
+		switch {
+		case secretKeys[k]:
+			out[k] = redacted
+		case k == "headers":
+			h, ok := v.(map[string]interface{})
+			if !ok {
+				out[k] = v
+				continue
+			}
+			masked := make(map[string]interface{}, len(h))
+			for name := range h {
+				masked[name] = redacted
+			}
+			out[k] = masked
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// restoreRedacted puts the stored values back wherever an update carries
+// the placeholder, including inside webhook headers.
+func restoreRedacted(update, stored map[string]interface{}) {
+	for k, v := range update {
+		if v == redacted {
+			update[k] = stored[k]
+			continue
+		}
+		h, ok := v.(map[string]interface{})
+		if k != "headers" || !ok {
+			continue
+		}
+		old, _ := stored[k].(map[string]interface{})
+		for name, hv := range h {
+			if hv == redacted {
+				h[name] = old[name]
+			}
+		}
+	}
+}
+
 // Manager handles notifications
 type Manager struct {
 	storePath     string
 	notifications map[string]*Notification
 	channels      map[string]*Channel
+	routes        []*Route
 	subscribers   map[string][]chan *Notification
 	mu            sync.RWMutex
 }
@@ -118,6 +184,19 @@ func (m *Manager) load() error {
 		m.addDefaultChannels()
 	}
 
+	// Load routes; the default route always exists
+	routesPath := filepath.Join(m.storePath, "routes.json")
+	if _, err := fsatomic.LoadJSON(routesPath, &m.routes); err != nil {
+		return err
+	}
+	hasDefault := false
+	for _, r := range m.routes {
+		hasDefault = hasDefault || r.ID == DefaultRouteID
+	}
+	if !hasDefault {
+		m.routes = append(m.routes, defaultRoute())
+	}
+
 	return nil
 }
 
@@ -145,7 +224,12 @@ func (m *Manager) save() error {
 	}
 
 	channelsPath := filepath.Join(m.storePath, "channels.json")
-	return fsatomic.SaveJSON(context.Background(), channelsPath, channels, 0600)
+	if err := fsatomic.SaveJSON(context.Background(), channelsPath, channels, 0600); err != nil {
+		return err
+	}
+
+	routesPath := filepath.Join(m.storePath, "routes.json")
+	return fsatomic.SaveJSON(context.Background(), routesPath, m.routes, 0600)
 }
 
 func (m *Manager) addDefaultChannels() {
@@ -197,26 +281,31 @@ func (m *Manager) Send(notif *Notification) error {
 	return nil
 }
 
-// sendToChannels sends notification to configured channels
+// sendToChannels sends notification to the channels its routes select
 func (m *Manager) sendToChannels(notif *Notification) {
 	m.mu.RLock()
+	targets, all := m.routeTargets(notif)
 	channels := make([]*Channel, 0, len(m.channels))
 	for _, c := range m.channels {
-		if c.Enabled && m.matchesFilters(notif, c.Filters) {
+		if (all || targets[c.ID]) && c.Enabled && m.matchesFilters(notif, c.Filters) {
 			channels = append(channels, c)
 		}
 	}
 	m.mu.RUnlock()
 
 	for _, channel := range channels {
-		switch channel.Type {
-		case "email":
-			go m.sendEmail(channel, notif)
-		case "webhook":
-			go m.sendWebhook(channel, notif)
-		case "syslog":
-			go m.sendSyslog(channel, notif)
+		sender, err := NewSender(channel)
+		if err != nil {
+			log.Error().Err(err).Str("channel", channel.ID).Msg("Invalid notification channel")
+			continue
 		}
+		go func(id string, sender Sender) {
+			ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+			defer cancel()
+			if err := sender.Send(ctx, notif); err != nil {
+				log.Error().Err(err).Str("channel", id).Msg("Failed to deliver notification")
+			}
+		}(channel.ID, sender)
 	}
 }
 
@@ -259,15 +348,16 @@ func (m *Manager) matchesFilters(notif *Notification, filters []Filter) bool {
 	return false
 }
 
+// levels orders notification types for MinLevel filters and routes.
+var levels = map[string]int{
+	"info":    1,
+	"success": 2,
+	"warning": 3,
+	"error":   4,
+}
+
 // meetsMinLevel checks if notification type meets minimum level
 func (m *Manager) meetsMinLevel(notifType, minLevel string) bool {
-	levels := map[string]int{
-		"info":    1,
-		"success": 2,
-		"warning": 3,
-		"error":   4,
-	}
-
 	notifLevel, ok1 := levels[notifType]
 	minLevelVal, ok2 := levels[minLevel]
 
@@ -278,71 +368,6 @@ func (m *Manager) meetsMinLevel(notifType, minLevel string) bool {
 	return notifLevel >= minLevelVal
 }
 
-// sendEmail sends notification via email
-func (m *Manager) sendEmail(channel *Channel, notif *Notification) {
-	host, _ := channel.Config["host"].(string)
-	port, _ := channel.Config["port"].(string)
-	from, _ := channel.Config["from"].(string)
-	to, _ := channel.Config["to"].(string)
-	username, _ := channel.Config["username"].(string)
-	password, _ := channel.Config["password"].(string)
-
-	if host == "" || from == "" || to == "" {
-		log.Error().Str("channel", channel.ID).Msg("Invalid email configuration")
-		return
-	}
-
-	if port == "" {
-		port = "587"
-	}
-
-	// Build message
-	subject := fmt.Sprintf("[NithronOS %s] %s", strings.ToUpper(notif.Type), notif.Title)
-	body := fmt.Sprintf("Time: %s\n\n%s\n", notif.Timestamp.Format(time.RFC3339), notif.Message)
-
-	if len(notif.Details) > 0 {
-		body += "\n\nDetails:\n"
-		for k, v := range notif.Details {
-			body += fmt.Sprintf("  %s: %v\n", k, v)
-		}
-	}
-
-	msg := []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", from, to, subject, body))
-
-	// Send email
-	var auth smtp.Auth
-	if username != "" && password != "" {
-		auth = smtp.PlainAuth("", username, password, host)
-	}
-
-	addr := fmt.Sprintf("%s:%s", host, port)
-	if err := smtp.SendMail(addr, auth, from, []string{to}, msg); err != nil {
-		log.Error().Err(err).Str("channel", channel.ID).Msg("Failed to send email")
-	}
-}
-
-// sendWebhook sends notification via webhook
-func (m *Manager) sendWebhook(channel *Channel, notif *Notification) {
-	url, _ := channel.Config["url"].(string)
-	if url == "" {
-		log.Error().Str("channel", channel.ID).Msg("Invalid webhook configuration")
-		return
-	}
-
-	// TODO: Implement webhook sending
-	log.Debug().Str("channel", channel.ID).Str("url", url).Msg("Webhook notification not yet implemented")
-}
-
-// sendSyslog sends notification to syslog
-func (m *Manager) sendSyslog(channel *Channel, notif *Notification) {
-	// TODO: Implement syslog sending
-	log.Info().
-		Str("type", notif.Type).
-		Str("category", notif.Category).
-		Str("title", notif.Title).
-		Msg(notif.Message)
-}
-
 // List returns all notifications
 func (m *Manager) List(unreadOnly bool) []*Notification {
 	m.mu.RLock()
@@ -439,20 +464,37 @@ func (m *Manager) ListChannels() []*Channel {
 
 	list := make([]*Channel, 0, len(m.channels))
 	for _, c := range m.channels {
-		list = append(list, c)
+		list = append(list, c.clone())
 	}
 	return list
 }
 
+// GetChannel returns a copy of the channel; callers may redact it freely.
 func (m *Manager) GetChannel(id string) (*Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	channel, ok := m.channels[id]
-	return channel, ok
+	if !ok {
+		return nil, false
+	}
+	return channel.clone(), true
+}
+
+func (c *Channel) clone() *Channel {
+	cp := *c
+	cp.Config = make(map[string]interface{}, len(c.Config))
+	for k, v := range c.Config {
+		cp.Config[k] = v
+	}
+	cp.Filters = append([]Filter(nil), c.Filters...)
+	return &cp
 }
 
 func (m *Manager) CreateChannel(channel *Channel) error {
+	if err := ValidateChannel(channel); err != nil {
+		return invalidChannelError{err}
+	}
 	if channel.ID == "" {
 		channel.ID = uuid.New().String()
 	}
@@ -460,7 +502,7 @@ func (m *Manager) CreateChannel(channel *Channel) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.channels[channel.ID] = channel
+	m.channels[channel.ID] = channel.clone()
 	return m.save()
 }
 
@@ -470,21 +512,28 @@ func (m *Manager) UpdateChannel(id string, updates *Channel) error {
 
 	channel, ok := m.channels[id]
 	if !ok {
-		return fmt.Errorf("channel not found")
+		return ErrChannelNotFound
 	}
 
-	// Update fields
+	// Update fields on a copy so an invalid update leaves the channel intact
+	next := channel.clone()
 	if updates.Name != "" {
-		channel.Name = updates.Name
+		next.Name = updates.Name
 	}
-	channel.Enabled = updates.Enabled
+	next.Enabled = updates.Enabled
 	if updates.Config != nil {
-		channel.Config = updates.Config
+		// Secrets come back redacted from GET; keep the stored value
+		restoreRedacted(updates.Config, channel.Config)
+		next.Config = updates.Config
 	}
 	if updates.Filters != nil {
-		channel.Filters = updates.Filters
+		next.Filters = updates.Filters
+	}
+	if err := ValidateChannel(next); err != nil {
+		return invalidChannelError{err}
 	}
 
+	m.channels[id] = next
 	return m.save()
 }
 
@@ -493,6 +542,7 @@ func (m *Manager) DeleteChannel(id string) error {
 	defer m.mu.Unlock()
 
 	delete(m.channels, id)
+	m.dropChannelFromRoutes(id)
 	return m.save()
 }
 
@@ -500,7 +550,7 @@ func (m *Manager) DeleteChannel(id string) error {
 func (m *Manager) TestChannel(id string) error {
 	channel, ok := m.GetChannel(id)
 	if !ok {
-		return fmt.Errorf("channel not found")
+		return ErrChannelNotFound
 	}
 
 	// Send test notification
@@ -514,20 +564,16 @@ func (m *Manager) TestChannel(id string) error {
 			"channel_type": channel.Type,
 			"test":         true,
 		},
+		Timestamp: time.Now(),
 	}
 
-	switch channel.Type {
-	case "email":
-		m.sendEmail(channel, testNotif)
-	case "webhook":
-		m.sendWebhook(channel, testNotif)
-	case "syslog":
-		m.sendSyslog(channel, testNotif)
-	default:
-		return fmt.Errorf("unknown channel type: %s", channel.Type)
+	sender, err := NewSender(channel)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	return sender.Send(ctx, testNotif)
 }
 
 // cleanupOldNotifications removes old notifications periodically
@@ -0,0 +1,211 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// DefaultRouteID names the catch-all route used when no other route matches.
+const DefaultRouteID = "default"
+
+var (
+	// ErrRouteNotFound is returned for an unknown route ID.
+	ErrRouteNotFound = errors.New("route not found")
+	// ErrDefaultRoute is returned when deleting the default route.
+	ErrDefaultRoute = errors.New("the default route cannot be deleted")
+)
+
+// Route sends notifications matching its criteria to a set of channels.
+// Every matching route contributes its channels; when none match, the
+// default route applies. A route with no channels (only valid for the
+// default) targets every enabled channel.
+type Route struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Categories []string `json:"categories,omitempty"`
+	MinLevel   string   `json:"minLevel,omitempty"` // info, success, warning, error
+	Channels   []string `json:"channels"`
+}
+
+func defaultRoute() *Route {
+	return &Route{ID: DefaultRouteID, Name: "Default", Channels: []string{}}
+}
+
+func (r *Route) clone() *Route {
+	cp := *r
+	cp.Categories = append([]string(nil), r.Categories...)
+	cp.Channels = append([]string{}, r.Channels...)
+	return &cp
+}
+
+func (r *Route) matches(m *Manager, n *Notification) bool {
+	if len(r.Categories) > 0 && !contains(r.Categories, n.Category) {
+		return false
+	}
+	return r.MinLevel == "" || m.meetsMinLevel(n.Type, r.MinLevel)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRoute checks r against the current channels; callers hold m.mu.
+func (m *Manager) validateRoute(r *Route) error {
+	if r.ID != DefaultRouteID {
+		if r.Name == "" {
+			return fmt.Errorf("route name is required")
+		}
+		if len(r.Channels) == 0 {
+			return fmt.Errorf("route must target at least one channel")
+		}
+	}
+	if _, ok := levels[r.MinLevel]; r.MinLevel != "" && !ok {
+		return fmt.Errorf("unknown level: %s", r.MinLevel)
+	}
+	for _, id := range r.Channels {
+		if _, ok := m.channels[id]; !ok {
+			return fmt.Errorf("unknown channel: %s", id)
+		}
+	}
+	return nil
+}
+
+// routeTargets returns the channel IDs a notification is routed to; all is
+// true when the applicable route targets every channel.
+func (m *Manager) routeTargets(n *Notification) (ids map[string]bool, all bool) {
+	ids = map[string]bool{}
+	var def *Route
+	for _, r := range m.routes {
+		if r.ID == DefaultRouteID {
+			def = r
+			continue
+		}
+		if r.matches(m, n) {
+			for _, id := range r.Channels {
+				ids[id] = true
+			}
+		}
+	}
+	if len(ids) > 0 {
+		return ids, false
+	}
+	if def == nil || len(def.Channels) == 0 {
+		return nil, true
+	}
+	for _, id := range def.Channels {
+		ids[id] = true
+	}
+	return ids, false
+}
+
+// ListRoutes returns the routes in evaluation order, default last.
+func (m *Manager) ListRoutes() []*Route {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*Route, 0, len(m.routes))
+	var def *Route
+	for _, r := range m.routes {
+		if r.ID == DefaultRouteID {
+			def = r
+			continue
+		}
+		list = append(list, r.clone())
+	}
+	if def != nil {
+		list = append(list, def.clone())
+	}
+	return list
+}
+
+func (m *Manager) GetRoute(id string) (*Route, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.routes {
+		if r.ID == id {
+			return r.clone(), true
+		}
+	}
+	return nil, false
+}
+
+func (m *Manager) CreateRoute(r *Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r.ID = uuid.New().String()
+	if r.Channels == nil {
+		r.Channels = []string{}
+	}
+	if err := m.validateRoute(r); err != nil {
+		return err
+	}
+	m.routes = append(m.routes, r.clone())
+	return m.save()
+}
+
+// UpdateRoute replaces the route's criteria and channels.
+func (m *Manager) UpdateRoute(id string, r *Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, cur := range m.routes {
+		if cur.ID != id {
+			continue
+		}
+		next := r.clone()
+		next.ID = id
+		if next.Name == "" {
+			next.Name = cur.Name
+		}
+		if err := m.validateRoute(next); err != nil {
+			return err
+		}
+		m.routes[i] = next
+		return m.save()
+	}
+	return ErrRouteNotFound
+}
+
+func (m *Manager) DeleteRoute(id string) error {
+	if id == DefaultRouteID {
+		return ErrDefaultRoute
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, r := range m.routes {
+		if r.ID == id {
+			m.routes = append(m.routes[:i], m.routes[i+1:]...)
+			return m.save()
+		}
+	}
+	return ErrRouteNotFound
+}
+
+// dropChannelFromRoutes removes a deleted channel from every route, and
+// routes left without channels; callers hold m.mu.
+func (m *Manager) dropChannelFromRoutes(channelID string) {
+	kept := m.routes[:0]
+	for _, r := range m.routes {
+		channels := r.Channels[:0]
+		for _, id := range r.Channels {
+			if id != channelID {
+				channels = append(channels, id)
+			}
+		}
+		r.Channels = channels
+		if len(channels) > 0 || r.ID == DefaultRouteID {
+			kept = append(kept, r)
+		}
+	}
+	m.routes = kept
+}
@@ -0,0 +1,121 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// expectTitles drains got until every title has arrived and checks nothing
+// else follows.
+func expectTitles(t *testing.T, got chan received, titles ...string) {
+	t.Helper()
+	want := map[string]bool{}
+	for _, title := range titles {
+		want[title] = true
+	}
+	for range titles {
+		var n Notification
+		r := wait(t, got)
+		_ = json.Unmarshal([]byte(r.body), &n)
+		if !want[n.Title] {
+			t.Fatalf("unexpected delivery %q, want %v", n.Title, titles)
+		}
+		delete(want, n.Title)
+	}
+	select {
+	case r := <-got:
+		t.Fatalf("unexpected extra delivery: %s", r.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSeverityRouting(t *testing.T) {
+	critSrv, crit := mockServer(t, http.StatusOK)
+	infoSrv, info := mockServer(t, http.StatusOK)
+	m := newTestManager(t)
+	m.channels = map[string]*Channel{} // drop the default syslog channel
+
+	pager := &Channel{Name: "pager", Type: ChannelWebhook, Enabled: true, Config: map[string]interface{}{"url": critSrv.URL}}
+	feed := &Channel{Name: "feed", Type: ChannelWebhook, Enabled: true, Config: map[string]interface{}{"url": infoSrv.URL}}
+	for _, c := range []*Channel{pager, feed} {
+		if err := m.CreateChannel(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.CreateRoute(&Route{Name: "critical", MinLevel: "error", Channels: []string{pager.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateRoute(&Route{Name: "backups", Categories: []string{"backup"}, Channels: []string{feed.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateRoute(DefaultRouteID, &Route{Channels: []string{feed.ID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// error → critical route only
+	m.SendStorageNotification("pool degraded", "", "error", nil)
+	expectTitles(t, crit, "pool degraded")
+	expectTitles(t, info)
+
+	// matches no rule → default route
+	m.SendStorageNotification("scrub done", "", "info", nil)
+	expectTitles(t, info, "scrub done")
+	expectTitles(t, crit)
+
+	// a failed backup matches both rules
+	m.SendBackupNotification("nightly", "failed", nil)
+	expectTitles(t, crit, "Backup Job: nightly")
+	expectTitles(t, info, "Backup Job: nightly")
+
+	// with the default route emptied, unmatched notifications reach every channel
+	if err := m.UpdateRoute(DefaultRouteID, &Route{}); err != nil {
+		t.Fatal(err)
+	}
+	m.SendSystemNotification("update available", "", "warning")
+	expectTitles(t, crit, "update available")
+	expectTitles(t, info, "update available")
+}
+
+func TestRouteValidationAndDefaults(t *testing.T) {
+	m := newTestManager(t)
+	routes := m.ListRoutes()
+	if len(routes) != 1 || routes[0].ID != DefaultRouteID {
+		t.Fatalf("expected only the default route, got %+v", routes)
+	}
+	if err := m.DeleteRoute(DefaultRouteID); !errors.Is(err, ErrDefaultRoute) {
+		t.Fatalf("expected ErrDefaultRoute, got %v", err)
+	}
+	for _, r := range []*Route{
+		{Name: "no channels"},
+		{Name: "ghost", Channels: []string{"missing"}},
+		{Name: "bad level", MinLevel: "critical", Channels: []string{"system-log"}},
+		{Channels: []string{"system-log"}},
+	} {
+		if err := m.CreateRoute(r); err == nil {
+			t.Errorf("%q: expected validation error", r.Name)
+		}
+	}
+
+	r := &Route{Name: "logs", Channels: []string{"system-log"}}
+	if err := m.CreateRoute(r); err != nil {
+		t.Fatal(err)
+	}
+	// deleting the only target channel removes the route
+	if err := m.DeleteChannel("system-log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.GetRoute(r.ID); ok {
+		t.Fatal("route without channels should be removed")
+	}
+
+	reloaded, err := NewManager(m.storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes := reloaded.ListRoutes(); len(routes) != 1 || routes[0].ID != DefaultRouteID {
+		t.Fatalf("routes not persisted: %+v", routes)
+	}
+}
@@ -0,0 +1,245 @@
+// Package openapi builds the OpenAPI 3 document nosd serves from its route
+// inventory, so the spec always lists exactly the registered endpoints.
+package openapi
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Route is one registered method and chi path pattern, with the
+// credentials it takes.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Auth   Auth   `json:"auth,omitempty"`
+}
+
+// Auth is what a route requires before its handler runs.
+type Auth string
+
+const (
+	// AuthNone routes are open; handlers may still check a body token.
+	AuthNone Auth = "none"
+	// AuthSession routes take the session cookie (and, for writes, the
+	// CSRF header).
+	AuthSession Auth = "session"
+	// AuthSetup routes take the first-boot setup token.
+	AuthSetup Auth = "setup"
+	// AuthSessionOrSetup routes take a session, or the setup token until
+	// setup is complete.
+	AuthSessionOrSetup Auth = "session_or_setup"
+	// AuthAgent routes take a registered agent's bearer token.
+	AuthAgent Auth = "agent"
+	// AuthLocal routes are open to clients on localhost only.
+	AuthLocal Auth = "localhost"
+)
+
+// Info is the document's title and API version.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Document is an OpenAPI 3.0 document. Only the parts generated from
+// routes are modelled.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Components holds the security schemes operations refer to.
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is an apiKey or http scheme.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityRequirement names one scheme; an operation lists alternatives.
+type SecurityRequirement map[string][]string
+
+// securitySchemes are the credentials nosd accepts.
+var securitySchemes = map[string]SecurityScheme{
+	"session": {
+		Type: "apiKey", In: "cookie", Name: "nos_session",
+		Description: "Session cookie from POST /api/v1/auth/login. Writes also send the nos_csrf cookie's value in X-CSRF-Token.",
+	},
+	"setupToken": {
+		Type: "http", Scheme: "bearer",
+		Description: "Setup token from POST /api/v1/setup/otp/verify; also accepted in X-Setup-Token or the setup cookie.",
+	},
+	"agentToken": {
+		Type: "http", Scheme: "bearer",
+		Description: "Per-agent token from POST /api/v1/agents/register.",
+	},
+}
+
+// security maps each Auth to the operation's security requirements; an
+// empty list marks the operation as open.
+func security(a Auth) []SecurityRequirement {
+	switch a {
+	case AuthSession:
+		return []SecurityRequirement{{"session": {}}}
+	case AuthSetup:
+		return []SecurityRequirement{{"setupToken": {}}}
+	case AuthSessionOrSetup:
+		return []SecurityRequirement{{"session": {}}, {"setupToken": {}}}
+	case AuthAgent:
+		return []SecurityRequirement{{"agentToken": {}}}
+	default:
+		return []SecurityRequirement{}
+	}
+}
+
+// PathItem maps lower-case HTTP methods to their operations.
+type PathItem map[string]*Operation
+
+// Operation describes one method on one path.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags"`
+	Description string                `json:"description,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	Security    []SecurityRequirement `json:"security"`
+	Responses   map[string]Response   `json:"responses"`
+}
+
+// Parameter is a path parameter taken from a {name} segment.
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+// Schema is the parameter schema; chi regexp patterns become Pattern.
+type Schema struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Response is a response description; schemas aren't generated yet.
+type Response struct {
+	Description string `json:"description"`
+}
+
+// methods are the HTTP methods OpenAPI path items can hold.
+var methods = map[string]bool{
+	"GET": true, "PUT": true, "POST": true, "DELETE": true,
+	"OPTIONS": true, "HEAD": true, "PATCH": true, "TRACE": true,
+}
+
+// chiParamRe matches {name} and {name:regexp} segments of a chi pattern.
+var chiParamRe = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+
+// PathTemplate converts a chi pattern to an OpenAPI path template and its
+// parameters: {id:[0-9]+} becomes {id} with a pattern, and a trailing
+// catch-all * becomes {path}.
+func PathTemplate(pattern string) (string, []Parameter) {
+	var params []Parameter
+	path := chiParamRe.ReplaceAllStringFunc(pattern, func(seg string) string {
+		m := chiParamRe.FindStringSubmatch(seg)
+		p := Parameter{Name: m[1], In: "path", Required: true, Schema: Schema{Type: "string"}}
+		if m[2] != "" {
+			p.Schema.Pattern = "^" + m[2] + "$"
+		}
+		params = append(params, p)
+		return "{" + m[1] + "}"
+	})
+	if strings.HasSuffix(path, "/*") {
+		path = strings.TrimSuffix(path, "*") + "{path}"
+		params = append(params, Parameter{Name: "path", In: "path", Required: true, Description: "remaining path", Schema: Schema{Type: "string"}})
+	}
+	return path, params
+}
+
+// operationID derives a stable id such as get_api_v1_pools_id.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteByte('_')
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// tag groups an operation by the first path segment after the API prefix,
+// e.g. "pools" for /api/v1/pools/{id}.
+func tag(path string) string {
+	rest := strings.TrimPrefix(path, "/api/v1/")
+	if rest == path {
+		rest = strings.TrimPrefix(path, "/api/")
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	if i := strings.IndexAny(rest, "/."); i >= 0 {
+		rest = rest[:i]
+	}
+	if rest == "" {
+		return "root"
+	}
+	return rest
+}
+
+// Generate builds the document for routes. Methods OpenAPI can't describe
+// are skipped; the same method and path listed twice is described once.
+// Routes without Auth are documented as requiring a session.
+func Generate(info Info, routes []Route) *Document {
+	doc := &Document{
+		OpenAPI:    "3.0.3",
+		Info:       info,
+		Paths:      map[string]PathItem{},
+		Components: Components{SecuritySchemes: securitySchemes},
+	}
+	for _, rt := range routes {
+		method := strings.ToUpper(rt.Method)
+		if !methods[method] {
+			continue
+		}
+		path, params := PathTemplate(rt.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		auth := rt.Auth
+		if auth == "" {
+			auth = AuthSession
+		}
+		op := &Operation{
+			OperationID: operationID(method, path),
+			Tags:        []string{tag(path)},
+			Parameters:  params,
+			Security:    security(auth),
+			Responses:   map[string]Response{"default": {Description: "See the NithronOS API documentation"}},
+		}
+		if auth == AuthLocal {
+			op.Description = "Only answered for clients on localhost."
+		}
+		item[strings.ToLower(method)] = op
+	}
+	return doc
+}
+
+// SortRoutes orders routes by path, then method.
+func SortRoutes(routes []Route) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+}
@@ -0,0 +1,74 @@
+package openapi
+
+import "testing"
+
+func TestPathTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		params        []string
+	}{
+		{"/api/v1/pools", "/api/v1/pools", nil},
+		{"/api/v1/pools/{id}/snapshots/{snap}", "/api/v1/pools/{id}/snapshots/{snap}", []string{"id", "snap"}},
+		{"/api/v1/jobs/{id:[0-9]+}", "/api/v1/jobs/{id}", []string{"id"}},
+		{"/api/v1/shares/*", "/api/v1/shares/{path}", []string{"path"}},
+	} {
+		path, params := PathTemplate(tc.pattern)
+		if path != tc.path || len(params) != len(tc.params) {
+			t.Fatalf("%s: %s %+v", tc.pattern, path, params)
+		}
+		for i, p := range params {
+			if p.Name != tc.params[i] || p.In != "path" || !p.Required {
+				t.Fatalf("%s: param %d = %+v", tc.pattern, i, p)
+			}
+		}
+	}
+	if _, params := PathTemplate("/api/v1/jobs/{id:[0-9]+}"); params[0].Schema.Pattern != "^[0-9]+$" {
+		t.Fatalf("pattern = %q", params[0].Schema.Pattern)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(Info{Title: "t", Version: "1"}, []Route{
+		{Method: "GET", Path: "/api/v1/pools/{id}"},
+		{Method: "DELETE", Path: "/api/v1/pools/{id}"},
+		{Method: "CONNECT", Path: "/api/v1/tunnel"},
+	})
+	item := doc.Paths["/api/v1/pools/{id}"]
+	if len(doc.Paths) != 1 || item["get"] == nil || item["delete"] == nil {
+		t.Fatalf("paths = %+v", doc.Paths)
+	}
+	if id := item["delete"].OperationID; id != "delete_api_v1_pools_id" {
+		t.Fatalf("operationId = %s", id)
+	}
+	if tags := item["get"].Tags; len(tags) != 1 || tags[0] != "pools" {
+		t.Fatalf("tags = %v", tags)
+	}
+}
+
+func TestGenerateSecurity(t *testing.T) {
+	doc := Generate(Info{}, []Route{
+		{Method: "POST", Path: "/api/v1/auth/login", Auth: AuthNone},
+		{Method: "GET", Path: "/api/v1/pools"},
+		{Method: "GET", Path: "/api/v1/system/info", Auth: AuthSessionOrSetup},
+		{Method: "POST", Path: "/api/v1/setup/recover", Auth: AuthLocal},
+	})
+	if sec := doc.Paths["/api/v1/auth/login"]["post"].Security; sec == nil || len(sec) != 0 {
+		t.Fatalf("open route security = %#v, want an empty list", sec)
+	}
+	if sec := doc.Paths["/api/v1/pools"]["get"].Security; len(sec) != 1 || sec[0]["session"] == nil {
+		t.Fatalf("default security = %v, want the session", sec)
+	}
+	if sec := doc.Paths["/api/v1/system/info"]["get"].Security; len(sec) != 2 {
+		t.Fatalf("session or setup security = %v", sec)
+	}
+	if op := doc.Paths["/api/v1/setup/recover"]["post"]; len(op.Security) != 0 || op.Description == "" {
+		t.Fatalf("localhost route = %+v", op)
+	}
+	for _, req := range doc.Paths["/api/v1/system/info"]["get"].Security {
+		for name := range req {
+			if _, ok := doc.Components.SecuritySchemes[name]; !ok {
+				t.Fatalf("security scheme %s not defined", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package pools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DevDiskDir holds the udev by-id/by-uuid links; swapped in tests.
+var DevDiskDir = "/dev/disk"
+
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrDeviceMismatch = errors.New("device changed since the plan was made")
+)
+
+// DeviceRef is one device named in a pool operation: what the caller sent,
+// the kernel node it resolved to and the by-id link that keeps naming the
+// same disk across reboots and hotplug.
+type DeviceRef struct {
+	Input string `json:"input"`
+	Path  string `json:"path"`
+	ByID  string `json:"byId,omitempty"`
+	// Stable is set when Input was a by-id/by-uuid link or UUID=
+	Stable bool `json:"stable"`
+}
+
+// Ref is the name to use in commands: the by-id link when there is one.
+func (d DeviceRef) Ref() string {
+	if d.ByID != "" {
+		return d.ByID
+	}
+	return d.Path
+}
+
+// ResolveDevice accepts /dev/disk/by-id/..., /dev/disk/by-uuid/..., UUID=...
+// or a kernel path. Stable references must resolve; a kernel path that
+// does not exist is passed through unresolved so the later free/signature
+// checks report it.
+func ResolveDevice(in string) (DeviceRef, error) {
+	in = strings.TrimSpace(in)
+	ref := DeviceRef{Input: in}
+	p := in
+	if u, ok := strings.CutPrefix(in, "UUID="); ok {
+		p = filepath.Join(DevDiskDir, "by-uuid", u)
+	}
+	if !filepath.IsAbs(p) {
+		return ref, fmt.Errorf("%w: %q is not a device path", ErrDeviceNotFound, in)
+	}
+	ref.Stable = strings.HasPrefix(p, DevDiskDir+string(filepath.Separator))
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		if ref.Stable {
+			return ref, fmt.Errorf("%w: %s", ErrDeviceNotFound, in)
+		}
+		ref.Path = filepath.Clean(p)
+		return ref, nil
+	}
+	ref.Path = real
+	if strings.HasPrefix(p, filepath.Join(DevDiskDir, "by-id")+string(filepath.Separator)) {
+		ref.ByID = p
+	} else {
+		ref.ByID = findByID(real)
+	}
+	return ref, nil
+}
+
+// ResolveDevices resolves every input and returns a warning for each one
+// given only by kernel name.
+func ResolveDevices(in []string) ([]DeviceRef, []string, error) {
+	refs := make([]DeviceRef, 0, len(in))
+	var warnings []string
+	for _, d := range in {
+		ref, err := ResolveDevice(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ref.Stable {
+			w := fmt.Sprintf("device %s is named by its kernel path, which can change across reboots", ref.Input)
+			if ref.ByID != "" {
+				w += "; using " + ref.ByID
+			}
+			warnings = append(warnings, w)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, warnings, nil
+}
+
+// CheckDeviceRefs re-resolves refs recorded at plan time and fails with
+// ErrDeviceMismatch when a by-id link now points at a different kernel node,
+// i.e. the disks were reordered or swapped since.
+func CheckDeviceRefs(refs []DeviceRef) error {
+	for _, d := range refs {
+		if d.ByID == "" {
+			continue
+		}
+		real, err := filepath.EvalSymlinks(d.ByID)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrDeviceNotFound, d.ByID)
+		}
+		if real != d.Path {
+			return fmt.Errorf("%w: %s is now %s, planned as %s", ErrDeviceMismatch, d.ByID, real, d.Path)
+		}
+	}
+	return nil
+}
+
+// findByID returns the by-id link for a kernel node, preferring
+// model/serial names over wwn- ones; "" if there is none.
+func findByID(real string) string {
+	dir := filepath.Join(DevDiskDir, "by-id")
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, e := range ents {
+		if t, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name())); err == nil && t == real {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Slice(names, func(i, j int) bool {
+		wi, wj := strings.HasPrefix(names[i], "wwn-"), strings.HasPrefix(names[j], "wwn-")
+		if wi != wj {
+			return !wi
+		}
+		return names[i] < names[j]
+	})
+	return filepath.Join(dir, names[0])
+}
@@ -0,0 +1,97 @@
+package pools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeDevDisk builds <tmp>/sdb, <tmp>/sdc and <tmp>/disk/by-{id,uuid} links.
+func fakeDevDisk(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"sdb", "sdc"} {
+		if err := os.WriteFile(filepath.Join(root, d), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"disk/by-id/wwn-0x5000c500a1b2c3d4":     "sdb",
+		"disk/by-id/ata-WDC_WD40EFRX_WD-ABC123": "sdb",
+		"disk/by-id/ata-WDC_WD40EFRX_WD-DEF456": "sdc",
+		"disk/by-uuid/1111-2222":                "sdb",
+	}
+	for link, target := range links {
+		p := filepath.Join(root, link)
+		_ = os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.Symlink(filepath.Join(root, target), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := DevDiskDir
+	DevDiskDir = filepath.Join(root, "disk")
+	t.Cleanup(func() { DevDiskDir = old })
+	return root
+}
+
+func TestResolveDevice(t *testing.T) {
+	root := fakeDevDisk(t)
+	sdb := filepath.Join(root, "sdb")
+	byID := filepath.Join(root, "disk/by-id/ata-WDC_WD40EFRX_WD-ABC123")
+
+	for _, in := range []string{sdb, byID, filepath.Join(root, "disk/by-uuid/1111-2222"), "UUID=1111-2222"} {
+		ref, err := ResolveDevice(in)
+		if err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if ref.Path != sdb || ref.ByID != byID || ref.Ref() != byID {
+			t.Fatalf("%s: got %+v", in, ref)
+		}
+		if ref.Stable == (in == sdb) {
+			t.Fatalf("%s: stable=%v", in, ref.Stable)
+		}
+	}
+
+	refs, warnings, err := ResolveDevices([]string{sdb, byID})
+	if err != nil || len(refs) != 2 || len(warnings) != 1 {
+		t.Fatalf("warnings for kernel path only: %v %v", warnings, err)
+	}
+
+	if _, err := ResolveDevice(filepath.Join(root, "disk/by-id/ata-gone")); !errors.Is(err, ErrDeviceNotFound) {
+		t.Fatalf("missing by-id link: %v", err)
+	}
+	// an unknown kernel path passes through for the later checks to report
+	if ref, err := ResolveDevice(filepath.Join(root, "sdz")); err != nil || ref.ByID != "" {
+		t.Fatalf("unknown kernel path: %+v %v", ref, err)
+	}
+}
+
+func TestCheckDeviceRefsDetectsMismatch(t *testing.T) {
+	root := fakeDevDisk(t)
+	link := filepath.Join(root, "disk/by-id/ata-WDC_WD40EFRX_WD-ABC123")
+	ref, err := ResolveDevice(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDeviceRefs([]DeviceRef{ref}); err != nil {
+		t.Fatalf("unchanged: %v", err)
+	}
+
+	// the disk now enumerates as sdc, e.g. after a reboot with a new disk
+	_ = os.Remove(link)
+	if err := os.Symlink(filepath.Join(root, "sdc"), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDeviceRefs([]DeviceRef{ref}); !errors.Is(err, ErrDeviceMismatch) {
+		t.Fatalf("want mismatch, got %v", err)
+	}
+
+	_ = os.Remove(link)
+	if err := CheckDeviceRefs([]DeviceRef{ref}); !errors.Is(err, ErrDeviceNotFound) {
+		t.Fatalf("want not found, got %v", err)
+	}
+}
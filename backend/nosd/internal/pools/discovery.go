@@ -33,6 +33,7 @@ func ListPools(ctx context.Context) ([]Pool, error) {
 			p.Label = label
 		}
 		p.UUID = uuid
+		p.Devices = btrfsDevicesForMount(ctx, m)
 		// RAID profile is best-effort via `btrfs fi usage`
 		p.RAID = btrfsRaidProfile(ctx, m)
 		pools = append(pools, p)
@@ -152,6 +153,35 @@ func btrfsShowForMount(ctx context.Context, mount string) (string, string) {
 	return currentLabel, currentUUID
 }
 
+// btrfsDevicesForMount lists the member devices of the filesystem mounted
+// at mount; lsblk only reports a mountpoint for one of them.
+func btrfsDevicesForMount(ctx context.Context, mount string) []string {
+	out, err := exec.CommandContext(ctx, "btrfs", "filesystem", "show", mount).Output()
+	if err != nil {
+		return nil
+	}
+	return parseShowDevices(string(out))
+}
+
+// parseShowDevices extracts the paths from the "devid N size ... path DEV"
+// lines of `btrfs filesystem show`.
+func parseShowDevices(out string) []string {
+	devs := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 || f[0] != "devid" {
+			continue
+		}
+		for i := 1; i+1 < len(f); i++ {
+			if f[i] == "path" {
+				devs = append(devs, f[i+1])
+				break
+			}
+		}
+	}
+	return uniqueStrings(devs)
+}
+
 func uniqueStrings(in []string) []string {
 	seen := map[string]struct{}{}
 	out := []string{}
@@ -0,0 +1,23 @@
+package pools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseShowDevices(t *testing.T) {
+	out := `Label: 'tank'  uuid: 0f1e2d3c-4b5a-6978-8695-a4b3c2d1e0f9
+	Total devices 3 FS bytes used 1.20TiB
+	devid    1 size 3.64TiB used 1.21TiB path /dev/sdb
+	devid    2 size 3.64TiB used 1.21TiB path /dev/sdc
+	devid    3 size 3.64TiB used 1.21TiB path /dev/mapper/crypt-sdd
+
+`
+	want := []string{"/dev/sdb", "/dev/sdc", "/dev/mapper/crypt-sdd"}
+	if got := parseShowDevices(out); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := parseShowDevices("ERROR: not a btrfs filesystem\n"); len(got) != 0 {
+		t.Fatalf("unexpected devices: %v", got)
+	}
+}
@@ -0,0 +1,106 @@
+// Package progress tracks the last observed progress of long-running
+// btrfs operations (balance, scrub, replace) per pool.
+package progress
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind is the operation being tracked.
+type Kind string
+
+const (
+	Balance Kind = "balance"
+	Scrub   Kind = "scrub"
+	Replace Kind = "replace"
+)
+
+// Entry is the last observed progress of one operation on one pool.
+type Entry struct {
+	Pool      string    `json:"pool"`
+	Kind      Kind      `json:"kind"`
+	Percent   float64   `json:"percent"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type key struct {
+	pool string
+	kind Kind
+}
+
+// Tracker holds the running operations. The pool executor and status
+// handlers write to it, the metrics and status endpoints read it; it is
+// safe for concurrent use.
+type Tracker struct {
+	mu      sync.RWMutex
+	entries map[key]Entry
+	now     func() time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{entries: map[key]Entry{}, now: time.Now}
+}
+
+// Set records pct (clamped to 0..100) for a running operation.
+func (t *Tracker) Set(pool string, kind Kind, pct float64) {
+	if pct < 0 {
+		pct = 0
+	} else if pct > 100 {
+		pct = 100
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[key{pool, kind}] = Entry{Pool: pool, Kind: kind, Percent: pct, UpdatedAt: t.now().UTC()}
+}
+
+// Clear drops an operation once it has finished or failed.
+func (t *Tracker) Clear(pool string, kind Kind) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key{pool, kind})
+}
+
+// Get returns the progress of one operation; ok is false when it is not running.
+func (t *Tracker) Get(pool string, kind Kind) (Entry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	e, ok := t.entries[key{pool, kind}]
+	return e, ok
+}
+
+// Pool returns the running operations on one pool, ordered by kind.
+func (t *Tracker) Pool(pool string) []Entry {
+	t.mu.RLock()
+	out := []Entry{}
+	for k, e := range t.entries {
+		if k.pool == pool {
+			out = append(out, e)
+		}
+	}
+	t.mu.RUnlock()
+	sortEntries(out)
+	return out
+}
+
+// All returns every running operation ordered by pool, then kind.
+func (t *Tracker) All() []Entry {
+	t.mu.RLock()
+	out := make([]Entry, 0, len(t.entries))
+	for _, e := range t.entries {
+		out = append(out, e)
+	}
+	t.mu.RUnlock()
+	sortEntries(out)
+	return out
+}
+
+func sortEntries(es []Entry) {
+	sort.Slice(es, func(i, j int) bool {
+		if es[i].Pool != es[j].Pool {
+			return es[i].Pool < es[j].Pool
+		}
+		return es[i].Kind < es[j].Kind
+	})
+}
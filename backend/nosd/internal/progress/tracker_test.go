@@ -0,0 +1,89 @@
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTrackerSetGetClear(t *testing.T) {
+	tr := NewTracker()
+	if _, ok := tr.Get("/mnt/p1", Balance); ok {
+		t.Fatal("empty tracker reported progress")
+	}
+	tr.Set("/mnt/p1", Balance, 42.5)
+	tr.Set("/mnt/p1", Scrub, 150)
+	tr.Set("/mnt/p2", Replace, -3)
+
+	if e, ok := tr.Get("/mnt/p1", Balance); !ok || e.Percent != 42.5 || e.UpdatedAt.IsZero() {
+		t.Fatalf("balance = %+v %v", e, ok)
+	}
+	if e, _ := tr.Get("/mnt/p1", Scrub); e.Percent != 100 {
+		t.Fatalf("percent not clamped to 100: %v", e.Percent)
+	}
+	if e, _ := tr.Get("/mnt/p2", Replace); e.Percent != 0 {
+		t.Fatalf("percent not clamped to 0: %v", e.Percent)
+	}
+
+	p1 := tr.Pool("/mnt/p1")
+	if len(p1) != 2 || p1[0].Kind != Balance || p1[1].Kind != Scrub {
+		t.Fatalf("pool entries = %+v", p1)
+	}
+	tr.Clear("/mnt/p1", Balance)
+	if _, ok := tr.Get("/mnt/p1", Balance); ok {
+		t.Fatal("cleared entry still reported")
+	}
+	all := tr.All()
+	if len(all) != 2 || all[0].Pool != "/mnt/p1" || all[1].Pool != "/mnt/p2" {
+		t.Fatalf("all = %+v", all)
+	}
+}
+
+// Run with -race: writers per pool/kind race readers of every view.
+func TestTrackerConcurrentUpdatesAndReads(t *testing.T) {
+	tr := NewTracker()
+	kinds := []Kind{Balance, Scrub, Replace}
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		for _, k := range kinds {
+			wg.Add(1)
+			go func(pool string, k Kind) {
+				defer wg.Done()
+				for i := 0; i <= 100; i++ {
+					tr.Set(pool, k, float64(i))
+					if i%25 == 0 {
+						tr.Clear(pool, k)
+					}
+				}
+				tr.Set(pool, k, 100)
+			}(fmt.Sprintf("/mnt/p%d", p), k)
+		}
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				for _, e := range tr.All() {
+					if e.Percent < 0 || e.Percent > 100 {
+						t.Errorf("out of range: %+v", e)
+						return
+					}
+				}
+				_ = tr.Pool("/mnt/p0")
+				_, _ = tr.Get("/mnt/p1", Scrub)
+			}
+		}()
+	}
+	wg.Wait()
+
+	all := tr.All()
+	if len(all) != 4*len(kinds) {
+		t.Fatalf("got %d entries, want %d", len(all), 4*len(kinds))
+	}
+	for _, e := range all {
+		if e.Percent != 100 {
+			t.Fatalf("last write lost: %+v", e)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"nithronos/backend/nosd/internal/config"
@@ -15,6 +17,7 @@ import (
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
 )
 
@@ -38,6 +41,7 @@ type SystemInfo struct {
 	BootTime     time.Time `json:"boot_time"`
 	Timezone     string    `json:"timezone"`
 	LocalTime    time.Time `json:"local_time"`
+	LoadAverage  []float64 `json:"load_average"`
 }
 
 // HardwareInfo represents hardware information
@@ -122,6 +126,33 @@ type SupportInfo struct {
 	GitHub        string `json:"github"`
 }
 
+// nosVersion is the release string reported by about and telemetry.
+const nosVersion = "0.9.5-pre-alpha"
+
+// Build info (set by build), e.g.
+// -ldflags "-X nithronos/backend/nosd/internal/server.GitCommit=$(git rev-parse --short HEAD)".
+// When unset, the VCS stamp Go embeds in the binary is used instead.
+var (
+	BuildTime = "unknown"
+	GitCommit = "unknown"
+)
+
+// aboutStaticTTL is how long hardware and version details are reused;
+// uptime, load, memory and disk usage are always read live.
+const aboutStaticTTL = 5 * time.Minute
+
+// aboutStatic holds the About fields that only change on reboot, hardware
+// swap or package upgrade, and are slow to gather (several exec calls).
+type aboutStatic struct {
+	Kernel      string
+	CPU         CPUInfo
+	MemoryTotal uint64
+	Motherboard string
+	BIOS        BIOSInfo
+	Software    SoftwareInfo
+	Build       BuildInfo
+}
+
 // BuildInfo represents build information
 type BuildInfo struct {
 	Version   string    `json:"version"`
@@ -135,13 +166,24 @@ type BuildInfo struct {
 // AboutHandler handles about/system information endpoints
 type AboutHandler struct {
 	config config.Config
+
+	// seam for tests: gathers the cached fields
+	collectStatic func() aboutStatic
+	staticTTL     time.Duration
+
+	mu       sync.Mutex
+	static   aboutStatic
+	staticAt time.Time
 }
 
 // NewAboutHandler creates a new about handler
 func NewAboutHandler(cfg config.Config) *AboutHandler {
-	return &AboutHandler{
-		config: cfg,
+	h := &AboutHandler{
+		config:    cfg,
+		staticTTL: aboutStaticTTL,
 	}
+	h.collectStatic = h.getStaticInfo
+	return h
 }
 
 // Routes returns the routes for the about handler
@@ -153,24 +195,58 @@ func (h *AboutHandler) Routes() chi.Router {
 
 // GetAboutInfo returns comprehensive system information
 func (h *AboutHandler) GetAboutInfo(w http.ResponseWriter, r *http.Request) {
+	static := h.staticInfo()
 	info := AboutInfo{
-		System:    h.getSystemInfo(),
-		Hardware:  h.getHardwareInfo(),
-		Software:  h.getSoftwareInfo(),
+		System:    h.getSystemInfo(static),
+		Hardware:  h.getHardwareInfo(static),
+		Software:  static.Software,
 		License:   h.getLicenseInfo(),
 		Support:   h.getSupportInfo(),
-		BuildInfo: h.getBuildInfo(),
+		BuildInfo: static.Build,
 	}
 
 	writeJSON(w, info)
 }
 
+// staticInfo returns the cached static fields, gathering them again once
+// they are older than staticTTL.
+func (h *AboutHandler) staticInfo() aboutStatic {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.staticAt.IsZero() || time.Since(h.staticAt) > h.staticTTL {
+		h.static = h.collectStatic()
+		h.staticAt = time.Now()
+	}
+	return h.static
+}
+
 // Helper methods
 
-func (h *AboutHandler) getSystemInfo() SystemInfo {
+func (h *AboutHandler) getStaticInfo() aboutStatic {
+	st := aboutStatic{
+		CPU:      h.getCPUInfo(),
+		Software: h.getSoftwareInfo(),
+		Build:    h.getBuildInfo(),
+	}
+	if hostInfo, err := host.Info(); err == nil {
+		st.Kernel = hostInfo.KernelVersion
+	}
+	if memInfo, err := mem.VirtualMemory(); err == nil {
+		st.MemoryTotal = memInfo.Total
+	}
+	// Get BIOS info (Linux-specific)
+	if runtime.GOOS == "linux" {
+		st.BIOS = h.getBIOSInfo()
+		st.Motherboard = h.getMotherboardInfo()
+	}
+	return st
+}
+
+func (h *AboutHandler) getSystemInfo(static aboutStatic) SystemInfo {
 	info := SystemInfo{
 		Platform:     runtime.GOOS,
 		Architecture: runtime.GOARCH,
+		Kernel:       static.Kernel,
 	}
 
 	// Get hostname
@@ -178,11 +254,13 @@ func (h *AboutHandler) getSystemInfo() SystemInfo {
 		info.Hostname = hostname
 	}
 
-	// Get host info
-	if hostInfo, err := host.Info(); err == nil {
-		info.Kernel = hostInfo.KernelVersion
-		info.Uptime = int64(hostInfo.Uptime)
-		info.BootTime = time.Now().Add(-time.Duration(hostInfo.Uptime) * time.Second)
+	if uptime, err := host.Uptime(); err == nil {
+		info.Uptime = int64(uptime)
+		info.BootTime = time.Now().Add(-time.Duration(uptime) * time.Second)
+	}
+
+	if avg, err := load.Avg(); err == nil {
+		info.LoadAverage = []float64{avg.Load1, avg.Load5, avg.Load15}
 	}
 
 	// Get timezone
@@ -192,31 +270,19 @@ func (h *AboutHandler) getSystemInfo() SystemInfo {
 	return info
 }
 
-func (h *AboutHandler) getHardwareInfo() HardwareInfo {
+func (h *AboutHandler) getHardwareInfo(static aboutStatic) HardwareInfo {
 	info := HardwareInfo{
-		Storage: []DiskInfo{},
-		Network: []NICInfo{},
-	}
-
-	// Get CPU info
-	if cpuInfo, err := cpu.Info(); err == nil && len(cpuInfo) > 0 {
-		info.CPU = CPUInfo{
-			Model:    cpuInfo[0].ModelName,
-			Cores:    int(cpuInfo[0].Cores),
-			Threads:  len(cpuInfo),
-			Speed:    cpuInfo[0].Mhz,
-			VendorID: cpuInfo[0].VendorID,
-			Family:   cpuInfo[0].Family,
-		}
-		if cpuInfo[0].CacheSize > 0 {
-			info.CPU.Cache = int(cpuInfo[0].CacheSize)
-		}
+		CPU:         static.CPU,
+		Storage:     []DiskInfo{},
+		Network:     []NICInfo{},
+		Motherboard: static.Motherboard,
+		BIOS:        static.BIOS,
 	}
 
 	// Get memory info
 	if memInfo, err := mem.VirtualMemory(); err == nil {
 		info.Memory = MemoryInfo{
-			Total:     memInfo.Total,
+			Total:     static.MemoryTotal,
 			Used:      memInfo.Used,
 			Free:      memInfo.Free,
 			Available: memInfo.Available,
@@ -261,12 +327,24 @@ func (h *AboutHandler) getHardwareInfo() HardwareInfo {
 		}
 	}
 
-	// Get BIOS info (Linux-specific)
-	if runtime.GOOS == "linux" {
-		info.BIOS = h.getBIOSInfo()
-		info.Motherboard = h.getMotherboardInfo()
-	}
+	return info
+}
 
+func (h *AboutHandler) getCPUInfo() CPUInfo {
+	var info CPUInfo
+	if cpuInfo, err := cpu.Info(); err == nil && len(cpuInfo) > 0 {
+		info = CPUInfo{
+			Model:    cpuInfo[0].ModelName,
+			Cores:    int(cpuInfo[0].Cores),
+			Threads:  len(cpuInfo),
+			Speed:    cpuInfo[0].Mhz,
+			VendorID: cpuInfo[0].VendorID,
+			Family:   cpuInfo[0].Family,
+		}
+		if cpuInfo[0].CacheSize > 0 {
+			info.Cache = int(cpuInfo[0].CacheSize)
+		}
+	}
 	return info
 }
 
@@ -316,7 +394,7 @@ func (h *AboutHandler) getMotherboardInfo() string {
 
 func (h *AboutHandler) getSoftwareInfo() SoftwareInfo {
 	info := SoftwareInfo{
-		NithronOS:    "0.9.5-pre-alpha",
+		NithronOS:    nosVersion,
 		APIVersion:   "1.0.0",
 		WebUIVersion: "1.0.0",
 		AgentVersion: "1.0.0",
@@ -339,6 +417,8 @@ func (h *AboutHandler) getSoftwareInfo() SoftwareInfo {
 	info.Components["samba"] = h.getComponentVersion("smbd")
 	info.Components["nfs"] = h.getComponentVersion("nfs-server")
 	info.Components["systemd"] = h.getComponentVersion("systemd")
+	info.Components["btrfs-progs"] = h.getComponentVersion("btrfs")
+	info.Components["smartctl"] = h.getComponentVersion("smartctl")
 
 	return info
 }
@@ -359,6 +439,22 @@ func (h *AboutHandler) getComponentVersion(component string) string {
 				return strings.TrimSpace(lines[0])
 			}
 		}
+	case "btrfs":
+		// "btrfs-progs v6.2"
+		if output, err := exec.Command("btrfs", "--version").Output(); err == nil {
+			parts := strings.Fields(string(output))
+			if len(parts) > 1 {
+				return parts[1]
+			}
+		}
+	case "smartctl":
+		// "smartctl 7.3 2022-02-28 r5338 [x86_64-linux-6.1.0] (local build)"
+		if output, err := exec.Command("smartctl", "--version").Output(); err == nil {
+			parts := strings.Fields(string(output))
+			if len(parts) > 1 {
+				return parts[1]
+			}
+		}
 	case "systemd":
 		if output, err := exec.Command("systemctl", "--version").Output(); err == nil {
 			lines := strings.Split(string(output), "\n")
@@ -401,20 +497,29 @@ func (h *AboutHandler) getSupportInfo() SupportInfo {
 
 func (h *AboutHandler) getBuildInfo() BuildInfo {
 	info := BuildInfo{
-		Version:   "0.9.5-pre-alpha",
-		Branch:    "main",
-		BuildDate: time.Now(), // Would be set at build time
-		Compiler:  runtime.Version(),
+		Version:  nosVersion,
+		Commit:   GitCommit,
+		Branch:   "main",
+		Compiler: runtime.Version(),
 	}
-
-	// Try to get git commit
-	if output, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
-		info.Commit = strings.TrimSpace(string(output))[:8] // First 8 chars
+	buildTime := BuildTime
+
+	// Fall back to the VCS stamp embedded by the go tool
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "unknown":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && buildTime == "unknown":
+				buildTime = s.Value
+			}
+		}
 	}
-
-	// Try to get git branch
-	if output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
-		info.Branch = strings.TrimSpace(string(output))
+	if len(info.Commit) > 8 {
+		info.Commit = info.Commit[:8] // First 8 chars
+	}
+	if t, err := time.Parse(time.RFC3339, buildTime); err == nil {
+		info.BuildDate = t
 	}
 
 	// Get build host
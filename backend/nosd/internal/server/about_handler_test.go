@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestAboutCachesStaticFields(t *testing.T) {
+	h := NewAboutHandler(config.Config{})
+	calls := 0
+	h.collectStatic = func() aboutStatic {
+		calls++
+		return aboutStatic{
+			CPU:         CPUInfo{Model: "cpu-" + time.Now().Format(time.RFC3339Nano), Cores: calls},
+			MemoryTotal: uint64(calls) << 30,
+			Motherboard: "board",
+		}
+	}
+	r := h.Routes()
+	get := func() AboutInfo {
+		t.Helper()
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+		var info AboutInfo
+		if err := json.Unmarshal(res.Body.Bytes(), &info); err != nil {
+			t.Fatalf("%v: %s", err, res.Body.String())
+		}
+		return info
+	}
+
+	first := get()
+	time.Sleep(time.Millisecond)
+	second := get()
+	if calls != 1 {
+		t.Fatalf("static fields gathered %d times, want 1", calls)
+	}
+	if first.Hardware.CPU != second.Hardware.CPU || first.Hardware.Memory.Total != second.Hardware.Memory.Total ||
+		first.Hardware.Motherboard != "board" || second.Hardware.Motherboard != "board" {
+		t.Fatalf("static fields changed between calls: %+v vs %+v", first.Hardware, second.Hardware)
+	}
+	if !second.System.LocalTime.After(first.System.LocalTime) {
+		t.Fatalf("live fields were cached too")
+	}
+
+	// once the TTL passes the static fields are gathered again
+	h.staticTTL = 0
+	time.Sleep(time.Millisecond)
+	if third := get(); calls != 2 || third.Hardware.CPU.Cores != 2 {
+		t.Fatalf("cache not refreshed after TTL: calls=%d cpu=%+v", calls, third.Hardware.CPU)
+	}
+}
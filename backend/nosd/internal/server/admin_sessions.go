@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"nithronos/backend/nosd/internal/auth/session"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+)
+
+type adminSession struct {
+	SID           string `json:"sid"`
+	UserID        string `json:"userId"`
+	Username      string `json:"username"`
+	CreatedAt     string `json:"createdAt"`
+	LastSeenAt    string `json:"lastSeenAt"`
+	ExpiresAt     string `json:"expiresAt"`
+	IPPrefix      string `json:"ipPrefix"`
+	UAFingerprint string `json:"uaFingerprint"`
+}
+
+// GET /api/v1/admin/sessions[?user=<id|username>&limit=N&offset=N]
+// Lists sessions across all known users, most recently seen first. IP and
+// UA are only ever exposed as the hashes the session store keeps.
+func handleAdminSessions(mgr *session.Manager, users *userstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit := 50
+		if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+		offset := 0
+		if n, err := strconv.Atoi(q.Get("offset")); err == nil && n > 0 {
+			offset = n
+		}
+		filter := q.Get("user")
+
+		list, _ := users.List()
+		out := []adminSession{}
+		for _, u := range list {
+			if filter != "" && filter != u.ID && filter != u.Username {
+				continue
+			}
+			for _, s := range mgr.ListByUser(u.ID) {
+				out = append(out, adminSession{
+					SID:           s.SID,
+					UserID:        u.ID,
+					Username:      u.Username,
+					CreatedAt:     s.CreatedAt,
+					LastSeenAt:    s.LastSeenAt,
+					ExpiresAt:     s.Exp,
+					IPPrefix:      s.IPHash,
+					UAFingerprint: s.UAHash,
+				})
+			}
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].LastSeenAt != out[j].LastSeenAt {
+				return out[i].LastSeenAt > out[j].LastSeenAt
+			}
+			return out[i].SID < out[j].SID
+		})
+
+		total := len(out)
+		if offset > total {
+			offset = total
+		}
+		end := offset + limit
+		if end > total {
+			end = total
+		}
+		writeJSON(w, map[string]any{
+			"sessions": out[offset:end],
+			"total":    total,
+			"limit":    limit,
+			"offset":   offset,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/auth/session"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+)
+
+func TestAdminSessionsAcrossUsers(t *testing.T) {
+	dir := t.TempDir()
+	users, _ := userstore.New(filepath.Join(dir, "users.json"))
+	for _, u := range []userstore.User{
+		{ID: "u-alice", Username: "alice", Roles: []string{"admin"}},
+		{ID: "u-bob", Username: "bob", Roles: []string{"user"}},
+	} {
+		if err := users.UpsertUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr := session.New(filepath.Join(dir, "sessions.json"))
+	for i, uid := range []string{"u-alice", "u-alice", "u-bob"} {
+		if _, err := mgr.Create(uid, "Mozilla/5.0", "192.168.1."+strconv.Itoa(10+i), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// sessions of unknown users are not listed
+	_, _ = mgr.Create("u-ghost", "curl", "10.0.0.1", time.Hour)
+
+	h := handleAdminSessions(mgr, users)
+	get := func(query string) (list []adminSession, total int, raw string) {
+		t.Helper()
+		res := httptest.NewRecorder()
+		h(res, httptest.NewRequest(http.MethodGet, "/api/v1/admin/sessions"+query, nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: %d", query, res.Code)
+		}
+		var out struct {
+			Sessions []adminSession `json:"sessions"`
+			Total    int            `json:"total"`
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Sessions, out.Total, res.Body.String()
+	}
+
+	list, total, raw := get("")
+	if total != 3 || len(list) != 3 {
+		t.Fatalf("expected 3 sessions, got %d/%d", len(list), total)
+	}
+	if strings.Contains(raw, "192.168.1.") || strings.Contains(raw, "Mozilla") {
+		t.Fatalf("raw IP/UA leaked: %s", raw)
+	}
+	for _, s := range list {
+		if s.Username == "" || s.UserID == "" || s.CreatedAt == "" || s.LastSeenAt == "" || s.IPPrefix == "" {
+			t.Fatalf("incomplete entry: %+v", s)
+		}
+	}
+
+	if list, total, _ := get("?user=bob"); total != 1 || list[0].UserID != "u-bob" {
+		t.Fatalf("filter by username: %+v", list)
+	}
+	if _, total, _ := get("?user=u-alice"); total != 2 {
+		t.Fatalf("filter by id: %d", total)
+	}
+
+	page1, _, _ := get("?limit=2")
+	page2, total, _ := get("?limit=2&offset=2")
+	if len(page1) != 2 || len(page2) != 1 || total != 3 {
+		t.Fatalf("pagination: %d + %d of %d", len(page1), len(page2), total)
+	}
+	if page1[0].SID == page2[0].SID || page1[1].SID == page2[0].SID {
+		t.Fatal("pages overlap")
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+const agentUnavailableMsg = "System agent is unavailable; check that nos-agent is running"
+
+// writeAgentError translates an agent call failure into a typed response
+// with a stable code:
+//   - 503 agent.unavailable when the agent can't be reached;
+//   - the agent's own 4xx status and the message from its {"error": "..."}
+//     body, under code, when it rejected the request (those messages are
+//     meant for the user);
+//   - 502 for agent 5xx replies and 500 for anything else, under code with
+//     msg.
+//
+// Raw agent and internal errors are logged, never echoed to the client.
+func writeAgentError(w http.ResponseWriter, err error, code, msg string) {
+	if agentclient.IsUnavailable(err) {
+		httpx.WriteTypedError(w, http.StatusServiceUnavailable, "agent.unavailable", agentUnavailableMsg, 5)
+		return
+	}
+	var he *agentclient.HTTPError
+	if errors.As(err, &he) && he.Status >= 400 && he.Status < 500 {
+		m, ok := agentMessage(err)
+		if !ok {
+			m = msg
+		}
+		httpx.WriteTypedError(w, he.Status, code, m, 0)
+		return
+	}
+	log.Error().Str("event", "agent.call.failed").Str("code", code).Err(err).Msg("")
+	status := http.StatusInternalServerError
+	if he != nil {
+		status = http.StatusBadGateway
+	}
+	httpx.WriteTypedError(w, status, code, msg, 0)
+}
+
+// agentMessage extracts the message of an agent HTTP error: the "error" field
+// of its JSON body, or the body itself when the agent replied in plain text.
+// ok is false for other errors and for bodies that carry no message.
+func agentMessage(err error) (string, bool) {
+	var he *agentclient.HTTPError
+	if !errors.As(err, &he) {
+		return "", false
+	}
+	body := strings.TrimSpace(he.Body)
+	if strings.HasPrefix(body, "{") {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(body), &e) != nil {
+			return "", false
+		}
+		body = strings.TrimSpace(e.Error)
+	}
+	return body, body != ""
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/pools"
+)
+
+// fakeAgentSocket serves HTTP on a unix socket and records request paths.
+// A nil handler answers every request with {"ok":true}.
+func fakeAgentSocket(t *testing.T, h http.HandlerFunc) (string, func() []string) {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	var mu sync.Mutex
+	var seen []string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.Path)
+		mu.Unlock()
+		if h != nil {
+			h(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return sock, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, seen...)
+	}
+}
+
+func TestHandlersDialConfiguredAgentSocket(t *testing.T) {
+	healthTestEnv(t)
+	sock, seen := fakeAgentSocket(t, nil)
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/health/smart?device=/dev/sda", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("smart proxy: %d %s", res.Code, res.Body.String())
+	}
+	paths := seen()
+	if len(paths) != 1 || paths[0] != "/v1/smart" {
+		t.Fatalf("agent did not receive smart request: %v", paths)
+	}
+
+	_, _, subs := getHealth(t)
+	if subs["agent"]["status"] != "ok" {
+		t.Fatalf("agent probe should reach custom socket: %v", subs["agent"])
+	}
+}
+
+func postSMBUser(t *testing.T) (int, map[string]any) {
+	t.Helper()
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	body := bytes.NewReader(mustJSON(map[string]any{"username": "bob", "password": "pw"}))
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/smb/users", body))
+	var out map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	return res.Code, out
+}
+
+func TestAgentUnavailableReturns503(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_AGENT_SOCKET", filepath.Join(dir, "missing.sock"))
+
+	code, out := postSMBUser(t)
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d %v", code, out)
+	}
+	e, _ := out["error"].(map[string]any)
+	if e["code"] != "agent.unavailable" {
+		t.Fatalf("expected agent.unavailable, got %v", out)
+	}
+}
+
+func TestAgentHTTPErrorPropagated(t *testing.T) {
+	healthTestEnv(t)
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid username", http.StatusBadRequest)
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+
+	code, out := postSMBUser(t)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %v", code, out)
+	}
+	e, _ := out["error"].(map[string]any)
+	if e["code"] == "agent.unavailable" {
+		t.Fatalf("agent 400 reported as unavailable: %v", out)
+	}
+}
+
+func TestAgentFailuresReturnTypedCodes(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "btrfs: exit status 1: /dev/sdz busy (secret detail)", http.StatusInternalServerError)
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	ensureDevicesFree = func(ctx context.Context, devices []string) error { return nil }
+	t.Cleanup(func() { ensureDevicesFree = pools.EnsureDevicesFree })
+
+	r := NewRouter(config.FromEnv())
+	for _, tc := range []struct {
+		path, code string
+		body       map[string]any
+		confirm    bool
+	}{
+		{"/api/v1/pools/create", "pools.create_failed", map[string]any{"devices": []string{"/dev/sdz"}, "raid": "single"}, true},
+		{"/api/v1/pools/p1/snapshots", "snapshots.create_failed", map[string]any{"subvol": "/mnt/p1/data", "name": "s1"}, false},
+		{"/api/v1/smb/users", "smb.user_create_failed", map[string]any{"username": "bob", "password": "pw"}, false},
+		{"/api/v1/updates/apply", "updates.apply_failed", map[string]any{"packages": []string{"nosd"}, "confirm": "yes"}, false},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(mustJSON(tc.body)))
+		if tc.confirm {
+			req.Header.Set("Confirm", "yes")
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != http.StatusBadGateway {
+			t.Fatalf("%s: expected 502, got %d %s", tc.path, res.Code, res.Body.String())
+		}
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		e, _ := out["error"].(map[string]any)
+		if e["code"] != tc.code {
+			t.Fatalf("%s: expected code %s, got %v", tc.path, tc.code, out)
+		}
+		if msg, _ := e["message"].(string); msg == "" || strings.Contains(msg, "secret") {
+			t.Fatalf("%s: message should be set and must not echo the agent error: %q", tc.path, msg)
+		}
+	}
+}
+
+func TestAgentRejectionUsesErrorField(t *testing.T) {
+	healthTestEnv(t)
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid username"}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	code, out := postSMBUser(t)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %v", code, out)
+	}
+	e, _ := out["error"].(map[string]any)
+	if e["message"] != "invalid username" {
+		t.Fatalf("expected the agent's error field as message, got %v", out)
+	}
+}
+
+func TestAgentProxyReadsReturnTypedCodes(t *testing.T) {
+	healthTestEnv(t)
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"exit status 1: secret detail"}`, http.StatusInternalServerError)
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	cfg := config.FromEnv()
+	for _, tc := range []struct {
+		name, code string
+		h          http.HandlerFunc
+		req        *http.Request
+	}{
+		{"scrub start", "pools.scrub_start_failed", handleScrubStart(cfg), httptest.NewRequest(http.MethodPost, "/api/v1/pools/scrub/start", strings.NewReader(`{"mount":"/mnt/p1"}`))},
+		{"scrub status", "pools.scrub_status_failed", handleScrubStatus(cfg), httptest.NewRequest(http.MethodGet, "/api/v1/pools/scrub/status?mount=/mnt/p1", nil)},
+		{"smart", "smart.read_failed", handleSmartProxy(cfg), httptest.NewRequest(http.MethodGet, "/api/v1/smart?device=/dev/sda", nil)},
+	} {
+		res := httptest.NewRecorder()
+		tc.h(res, tc.req)
+		if res.Code != http.StatusBadGateway {
+			t.Fatalf("%s: expected 502, got %d %s", tc.name, res.Code, res.Body.String())
+		}
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		e, _ := out["error"].(map[string]any)
+		if e["code"] != tc.code {
+			t.Fatalf("%s: expected code %s, got %v", tc.name, tc.code, out)
+		}
+		if strings.Contains(res.Body.String(), "secret") {
+			t.Fatalf("%s: agent error echoed: %s", tc.name, res.Body.String())
+		}
+	}
+}
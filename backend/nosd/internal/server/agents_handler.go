@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/internal/ratelimit"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// agentsStorePath holds registered agents (seam for tests).
+var agentsStorePath = filepath.Join("/var/lib/nos", "agents.json")
+
+// agentBootstrapTokenPath is the shared token agents present to register.
+func agentBootstrapTokenPath(cfg config.Config) string {
+	return filepath.Join(cfg.EtcDir, "nos", "agent-token")
+}
+
+// POST /api/v1/agents/register
+// Attempts are limited per client IP with the login budget
+// (RuntimeRateLimits().LoginPer15m per LoginWindow).
+func handleAgentRegister(cfg config.Config, rl *ratelimit.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.AllowAgentRegistration {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		ip := clientIP(r, cfg)
+		lim := RuntimeRateLimits()
+		ok, rem, reset := rl.Allow("agent-register:ip:"+ip, lim.LoginPer15m, lim.LoginWindow)
+		setRateLimitHeaders(w, lim.LoginPer15m, rem, reset)
+		if !ok {
+			retry := int(time.Until(reset).Seconds())
+			Logger(cfg).Warn().Str("event", "rate.limited").Str("route", "/api/v1/agents/register").Str("key", "agent-register:ip:"+ip).Int("remaining", rem).Int("retryAfterSec", retry).Msg("")
+			httpx.WriteTypedError(w, http.StatusTooManyRequests, "rate.limited", "Too many attempts. Try later.", retry)
+			return
+		}
+		var body struct {
+			Token string `json:"token"`
+			Node  string `json:"node"`
+			Arch  string `json:"arch"`
+			OS    string `json:"os"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		// compare against bootstrap token
+		bootTok, _ := os.ReadFile(agentBootstrapTokenPath(cfg))
+		want := []byte(strings.TrimSpace(string(bootTok)))
+		if len(want) == 0 || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(body.Token)), want) != 1 {
+			Logger(cfg).Warn().Str("event", "agent.register.denied").Str("ip", ip).Msg("")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// issue a per-agent token; only its hash is stored
+		tok, err := newAgentToken()
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "token generation failed")
+			return
+		}
+		rec := agentRecord{
+			ID:        generateUUID(),
+			TokenHash: hashAgentToken(tok),
+			Node:      body.Node,
+			Arch:      body.Arch,
+			OS:        body.OS,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := updateAgents(r.Context(), func(list []agentRecord) ([]agentRecord, error) {
+			return append(list, rec), nil
+		}); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to save agent")
+			return
+		}
+		Logger(cfg).Info().Str("event", "agent.registered").Str("agent", rec.ID).Str("node", rec.Node).Str("ip", ip).Msg("")
+		writeJSON(w, map[string]any{"id": rec.ID, "token": tok})
+	}
+}
+
+// GET /api/v1/agents (admin)
+func handleListAgents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := loadAgents()
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to read agents")
+			return
+		}
+		out := make([]agentInfo, 0, len(list))
+		for _, a := range list {
+			out = append(out, a.info())
+		}
+		writeJSON(w, map[string]any{"agents": out})
+	}
+}
+
+// DELETE /api/v1/agents/{id} (admin) revokes an agent's token.
+func handleDeleteAgent(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		err := updateAgents(r.Context(), func(list []agentRecord) ([]agentRecord, error) {
+			for i, a := range list {
+				if a.ID == id {
+					return append(list[:i], list[i+1:]...), nil
+				}
+			}
+			return nil, errAgentNotFound
+		})
+		if errors.Is(err, errAgentNotFound) {
+			httpx.WriteTypedError(w, http.StatusNotFound, "agents.not_found", "Agent not found", 0)
+			return
+		}
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to save agents")
+			return
+		}
+		Logger(cfg).Info().Str("event", "agent.revoked").Str("agent", id).Str("by", sessionUID(r)).Msg("")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /api/v1/agents/heartbeat authenticates with "Authorization: Bearer
+// <agent token>" and records when the agent was last seen.
+func handleAgentHeartbeat() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(tok) == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var id string
+		err := updateAgents(r.Context(), func(list []agentRecord) ([]agentRecord, error) {
+			i := authenticateAgent(list, strings.TrimSpace(tok))
+			if i < 0 {
+				return nil, errAgentNotFound
+			}
+			list[i].LastSeen = time.Now().UTC().Format(time.RFC3339)
+			id = list[i].ID
+			return list, nil
+		})
+		if errors.Is(err, errAgentNotFound) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to save agents")
+			return
+		}
+		writeJSON(w, map[string]any{"id": id})
+	}
+}
+
+// agentRecord is one entry of agents.json. Token is only read from files
+// written before tokens were hashed and is migrated on the next save.
+type agentRecord struct {
+	ID        string `json:"id"`
+	TokenHash string `json:"token_hash,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Node      string `json:"node"`
+	Arch      string `json:"arch"`
+	OS        string `json:"os"`
+	CreatedAt string `json:"created_at"`
+	LastSeen  string `json:"last_seen,omitempty"`
+}
+
+// agentInfo is the listing view of an agent (no token material).
+type agentInfo struct {
+	ID        string `json:"id"`
+	Node      string `json:"node"`
+	Arch      string `json:"arch"`
+	OS        string `json:"os"`
+	CreatedAt string `json:"created_at"`
+	LastSeen  string `json:"last_seen,omitempty"`
+}
+
+func (a agentRecord) info() agentInfo {
+	return agentInfo{ID: a.ID, Node: a.Node, Arch: a.Arch, OS: a.OS, CreatedAt: a.CreatedAt, LastSeen: a.LastSeen}
+}
+
+var (
+	errAgentNotFound = errors.New("agent not found")
+	// agentsMu serializes read-modify-write of agents.json
+	agentsMu sync.Mutex
+)
+
+// newAgentToken returns 32 random bytes, base64url encoded.
+func newAgentToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAgentToken hashes a high-entropy agent token for storage; a fast hash
+// suffices since tokens are random rather than user-chosen.
+func hashAgentToken(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateAgent returns the index of the agent owning tok, or -1.
+func authenticateAgent(list []agentRecord, tok string) int {
+	h := []byte(hashAgentToken(tok))
+	match := -1
+	for i, a := range list {
+		if subtle.ConstantTimeCompare(h, []byte(a.TokenHash)) == 1 {
+			match = i
+		}
+	}
+	return match
+}
+
+// loadAgents reads agents.json, hashing any legacy plaintext tokens.
+func loadAgents() ([]agentRecord, error) {
+	var list []agentRecord
+	b, err := os.ReadFile(agentsStorePath)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Token != "" {
+			list[i].TokenHash = hashAgentToken(list[i].Token)
+			list[i].Token = ""
+		}
+	}
+	return list, nil
+}
+
+// updateAgents applies fn to the stored agents and saves the result.
+func updateAgents(ctx context.Context, fn func([]agentRecord) ([]agentRecord, error)) error {
+	agentsMu.Lock()
+	defer agentsMu.Unlock()
+	list, err := loadAgents()
+	if err != nil {
+		return err
+	}
+	list, err = fn(list)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(agentsStorePath), 0o755); err != nil {
+		return err
+	}
+	return fsatomic.SaveJSON(ctx, agentsStorePath, list, 0o600)
+}
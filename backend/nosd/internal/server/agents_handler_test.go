@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestAgentRegisterThrottlesBadTokens(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_RATE_LOGIN_PER_15M", "3")
+	t.Setenv("NOS_RATE_LOGIN_WINDOW_SEC", "900")
+	if err := os.MkdirAll(filepath.Join(dir, "nos"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nos", "agent-token"), []byte("bootstrap-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := agentsStorePath
+	agentsStorePath = filepath.Join(dir, "agents.json")
+	t.Cleanup(func() { agentsStorePath = old })
+	r := NewRouter(config.FromEnv())
+
+	register := func(token, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/agents/register", strings.NewReader(`{"token":"`+token+`","node":"n1"}`))
+		req.RemoteAddr = addr
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	for i := 0; i < 3; i++ {
+		if res := register("guess", "10.0.0.5:4000"); res.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: %d", i, res.Code)
+		}
+	}
+	res := register("guess", "10.0.0.5:4000")
+	if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", res.Code, res.Header())
+	}
+	// throttled even with the right token until the window resets
+	if res := register("bootstrap-secret", "10.0.0.5:4000"); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("correct token while throttled: %d", res.Code)
+	}
+	// other clients are unaffected
+	if res := register("bootstrap-secret", "10.0.0.6:4000"); res.Code != http.StatusOK {
+		t.Fatalf("other client: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestAgentRegisterListRevoke(t *testing.T) {
+	dir := healthTestEnv(t)
+	if err := os.MkdirAll(filepath.Join(dir, "nos"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nos", "agent-token"), []byte("bootstrap-secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := agentsStorePath
+	agentsStorePath = filepath.Join(dir, "agents.json")
+	t.Cleanup(func() { agentsStorePath = old })
+	// a record written before tokens were hashed
+	legacy := `[{"ID":"legacy-1","Token":"plain-token","Node":"old","Arch":"arm64","OS":"linux","CreatedAt":"2025-01-01T00:00:00Z"}]`
+	if err := os.WriteFile(agentsStorePath, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(config.FromEnv())
+
+	do := func(method, path, body, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do(http.MethodPost, "/api/v1/agents/register", `{"token":"bootstrap-secret","node":"n1","arch":"amd64","os":"linux"}`, "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("register: %d %s", res.Code, res.Body.String())
+	}
+	var reg struct{ ID, Token string }
+	if err := json.Unmarshal(res.Body.Bytes(), &reg); err != nil || reg.ID == "" || len(reg.Token) < 40 {
+		t.Fatalf("register body: %s", res.Body.String())
+	}
+	stored, _ := os.ReadFile(agentsStorePath)
+	if strings.Contains(string(stored), reg.Token) || strings.Contains(string(stored), "plain-token") {
+		t.Fatalf("plaintext token stored: %s", stored)
+	}
+
+	if res := do(http.MethodPost, "/api/v1/agents/heartbeat", "", reg.Token); res.Code != http.StatusOK {
+		t.Fatalf("heartbeat: %d", res.Code)
+	}
+	if res := do(http.MethodPost, "/api/v1/agents/heartbeat", "", "plain-token"); res.Code != http.StatusOK {
+		t.Fatalf("legacy heartbeat: %d", res.Code)
+	}
+
+	res = do(http.MethodGet, "/api/v1/agents", "", "")
+	if res.Code != http.StatusOK || strings.Contains(res.Body.String(), "token") {
+		t.Fatalf("list: %d %s", res.Code, res.Body.String())
+	}
+	var list struct {
+		Agents []agentInfo `json:"agents"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Agents) != 2 || list.Agents[1].ID != reg.ID || list.Agents[1].LastSeen == "" {
+		t.Fatalf("agents: %+v", list.Agents)
+	}
+
+	if res := do(http.MethodDelete, "/api/v1/agents/"+reg.ID, "", ""); res.Code != http.StatusNoContent {
+		t.Fatalf("revoke: %d", res.Code)
+	}
+	if res := do(http.MethodPost, "/api/v1/agents/heartbeat", "", reg.Token); res.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token accepted: %d", res.Code)
+	}
+	if res := do(http.MethodDelete, "/api/v1/agents/"+reg.ID, "", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("second revoke: %d", res.Code)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"nithronos/backend/nosd/internal/config"
@@ -24,8 +27,39 @@ type AppearanceSettings struct {
 	FirstDayOfWeek  int               `json:"first_day_of_week"` // 0=Sunday, 1=Monday
 	CustomCSS       string            `json:"custom_css"`
 	CustomColors    map[string]string `json:"custom_colors"`
+	Density         string            `json:"density"` // comfortable, compact, spacious
 }
 
+// ThemePreset is a named bundle of appearance values that can be applied
+// in one call via POST /presets/{id}/apply.
+type ThemePreset struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Theme        string            `json:"theme"`
+	AccentColor  string            `json:"accent_color"`
+	Density      string            `json:"density"`
+	HighContrast bool              `json:"high_contrast"`
+	Colors       map[string]string `json:"colors"`
+}
+
+// appearanceFieldError is one rejected field in an appearance update.
+type appearanceFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var (
+	appearanceThemes      = []string{"light", "dark", "auto"}
+	appearanceFontSizes   = []string{"small", "medium", "large"}
+	appearanceDensities   = []string{"comfortable", "compact", "spacious"}
+	appearanceLanguages   = []string{"en", "es", "fr", "de", "it", "pt", "ru", "zh", "ja", "ko"}
+	appearanceDateFormats = []string{"MM/DD/YYYY", "DD/MM/YYYY", "YYYY-MM-DD", "DD.MM.YYYY"}
+	appearanceTimeFormats = []string{"12h", "24h"}
+
+	hexColorRe = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
 // AppearanceHandler handles appearance settings
 type AppearanceHandler struct {
 	config       config.Config
@@ -45,6 +79,9 @@ func (h *AppearanceHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 	r.Get("/", h.GetAppearanceSettings)
 	r.Put("/", h.UpdateAppearanceSettings)
+	r.Get("/presets", h.GetThemePresets)
+	r.Post("/presets/{id}/apply", h.ApplyThemePreset)
+	r.Get("/languages", h.GetLanguages)
 	return r
 }
 
@@ -62,30 +99,51 @@ func (h *AppearanceHandler) UpdateAppearanceSettings(w http.ResponseWriter, r *h
 		return
 	}
 
-	// Validate theme
-	if settings.Theme != "light" && settings.Theme != "dark" && settings.Theme != "auto" {
-		httpx.WriteTypedError(w, http.StatusBadRequest, "appearance.invalid_theme", "Invalid theme", 0)
+	if errs := validateAppearance(&settings); len(errs) > 0 {
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "appearance.invalid", "Invalid appearance settings", map[string]any{"fields": errs})
 		return
 	}
 
-	// Validate language
-	validLanguages := []string{"en", "es", "fr", "de", "it", "pt", "ru", "zh", "ja", "ko"}
-	if !contains(validLanguages, settings.Language) {
-		settings.Language = "en"
+	// Save settings
+	if err := h.saveSettings(settings); err != nil {
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "appearance.save_failed", "Failed to save settings", 0)
+		return
 	}
 
-	// Validate date format
-	validDateFormats := []string{"MM/DD/YYYY", "DD/MM/YYYY", "YYYY-MM-DD", "DD.MM.YYYY"}
-	if !contains(validDateFormats, settings.DateFormat) {
-		settings.DateFormat = "MM/DD/YYYY"
+	writeJSON(w, settings)
+}
+
+// GetThemePresets returns available theme presets
+func (h *AppearanceHandler) GetThemePresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, themePresets)
+}
+
+// ApplyThemePreset copies a preset's theme, accent, density and colors onto
+// the saved settings, leaving locale and accessibility choices alone.
+func (h *AppearanceHandler) ApplyThemePreset(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var preset *ThemePreset
+	for i := range themePresets {
+		if themePresets[i].ID == id {
+			preset = &themePresets[i]
+			break
+		}
+	}
+	if preset == nil {
+		httpx.WriteTypedError(w, http.StatusNotFound, "appearance.preset_not_found", "Unknown theme preset", 0)
+		return
 	}
 
-	// Validate time format
-	if settings.TimeFormat != "12h" && settings.TimeFormat != "24h" {
-		settings.TimeFormat = "12h"
+	settings := h.loadSettings()
+	settings.Theme = preset.Theme
+	settings.AccentColor = preset.AccentColor
+	settings.Density = preset.Density
+	settings.HighContrast = preset.HighContrast
+	settings.CustomColors = make(map[string]string, len(preset.Colors))
+	for k, v := range preset.Colors {
+		settings.CustomColors[k] = v
 	}
 
-	// Save settings
 	if err := h.saveSettings(settings); err != nil {
 		httpx.WriteTypedError(w, http.StatusInternalServerError, "appearance.save_failed", "Failed to save settings", 0)
 		return
@@ -94,87 +152,98 @@ func (h *AppearanceHandler) UpdateAppearanceSettings(w http.ResponseWriter, r *h
 	writeJSON(w, settings)
 }
 
-// GetThemePresets returns available theme presets
-func (h *AppearanceHandler) GetThemePresets(w http.ResponseWriter, r *http.Request) {
-	presets := []map[string]any{
-		{
-			"id":          "default",
-			"name":        "Default",
-			"description": "NithronOS default theme",
-			"colors": map[string]string{
-				"primary":    "#0066cc",
-				"secondary":  "#6c757d",
-				"success":    "#28a745",
-				"danger":     "#dc3545",
-				"warning":    "#ffc107",
-				"info":       "#17a2b8",
-				"background": "#ffffff",
-				"foreground": "#212529",
-			},
+var themePresets = []ThemePreset{
+	{
+		ID:          "default",
+		Name:        "Default",
+		Description: "NithronOS default theme",
+		Theme:       "auto",
+		AccentColor: "#0066cc",
+		Density:     "comfortable",
+		Colors: map[string]string{
+			"primary":    "#0066cc",
+			"secondary":  "#6c757d",
+			"success":    "#28a745",
+			"danger":     "#dc3545",
+			"warning":    "#ffc107",
+			"info":       "#17a2b8",
+			"background": "#ffffff",
+			"foreground": "#212529",
 		},
-		{
-			"id":          "dark",
-			"name":        "Dark",
-			"description": "Dark theme for low-light environments",
-			"colors": map[string]string{
-				"primary":    "#0d6efd",
-				"secondary":  "#6c757d",
-				"success":    "#198754",
-				"danger":     "#dc3545",
-				"warning":    "#ffc107",
-				"info":       "#0dcaf0",
-				"background": "#212529",
-				"foreground": "#ffffff",
-			},
+	},
+	{
+		ID:          "dark",
+		Name:        "Dark",
+		Description: "Dark theme for low-light environments",
+		Theme:       "dark",
+		AccentColor: "#0d6efd",
+		Density:     "comfortable",
+		Colors: map[string]string{
+			"primary":    "#0d6efd",
+			"secondary":  "#6c757d",
+			"success":    "#198754",
+			"danger":     "#dc3545",
+			"warning":    "#ffc107",
+			"info":       "#0dcaf0",
+			"background": "#212529",
+			"foreground": "#ffffff",
 		},
-		{
-			"id":          "high-contrast",
-			"name":        "High Contrast",
-			"description": "High contrast theme for accessibility",
-			"colors": map[string]string{
-				"primary":    "#000000",
-				"secondary":  "#666666",
-				"success":    "#008000",
-				"danger":     "#ff0000",
-				"warning":    "#ffff00",
-				"info":       "#0000ff",
-				"background": "#ffffff",
-				"foreground": "#000000",
-			},
+	},
+	{
+		ID:           "high-contrast",
+		Name:         "High Contrast",
+		Description:  "High contrast theme for accessibility",
+		Theme:        "light",
+		AccentColor:  "#000000",
+		Density:      "spacious",
+		HighContrast: true,
+		Colors: map[string]string{
+			"primary":    "#000000",
+			"secondary":  "#666666",
+			"success":    "#008000",
+			"danger":     "#ff0000",
+			"warning":    "#ffff00",
+			"info":       "#0000ff",
+			"background": "#ffffff",
+			"foreground": "#000000",
 		},
-		{
-			"id":          "ocean",
-			"name":        "Ocean",
-			"description": "Cool blue ocean theme",
-			"colors": map[string]string{
-				"primary":    "#006994",
-				"secondary":  "#5e8ca6",
-				"success":    "#00a86b",
-				"danger":     "#ff6b6b",
-				"warning":    "#ffd93d",
-				"info":       "#4ecdc4",
-				"background": "#f7f9fc",
-				"foreground": "#2c3e50",
-			},
+	},
+	{
+		ID:          "ocean",
+		Name:        "Ocean",
+		Description: "Cool blue ocean theme",
+		Theme:       "light",
+		AccentColor: "#006994",
+		Density:     "comfortable",
+		Colors: map[string]string{
+			"primary":    "#006994",
+			"secondary":  "#5e8ca6",
+			"success":    "#00a86b",
+			"danger":     "#ff6b6b",
+			"warning":    "#ffd93d",
+			"info":       "#4ecdc4",
+			"background": "#f7f9fc",
+			"foreground": "#2c3e50",
 		},
-		{
-			"id":          "forest",
-			"name":        "Forest",
-			"description": "Natural green forest theme",
-			"colors": map[string]string{
-				"primary":    "#2d5016",
-				"secondary":  "#6b8e23",
-				"success":    "#228b22",
-				"danger":     "#8b0000",
-				"warning":    "#daa520",
-				"info":       "#4682b4",
-				"background": "#f5f5dc",
-				"foreground": "#2d3e0f",
-			},
+	},
+	{
+		ID:          "forest",
+		Name:        "Forest",
+		Description: "Natural green forest theme",
+		Theme:       "light",
+		AccentColor: "#2d5016",
+		Density:     "comfortable",
+		Colors: map[string]string{
+			"primary":    "#2d5016",
+			"secondary":  "#6b8e23",
+			"success":    "#228b22",
+			"danger":     "#8b0000",
+			"warning":    "#daa520",
+			"info":       "#4682b4",
+			"background": "#f5f5dc",
+			"foreground": "#2d3e0f",
 		},
-	}
-
-	writeJSON(w, presets)
+	},
 }
 
 // GetLanguages returns available languages
@@ -209,6 +278,7 @@ func (h *AppearanceHandler) loadSettings() AppearanceSettings {
 		TimeFormat:     "12h",
 		FirstDayOfWeek: 0,
 		CustomColors:   make(map[string]string),
+		Density:        "comfortable",
 	}
 
 	if data, err := os.ReadFile(h.settingsPath); err == nil {
@@ -232,3 +302,45 @@ func (h *AppearanceHandler) saveSettings(settings AppearanceSettings) error {
 
 	return os.WriteFile(h.settingsPath, data, 0644)
 }
+
+// validateAppearance checks s against the allowed values. Empty optional
+// fields take their defaults; anything else that is not allowed is reported
+// per field so the UI never stores a value it cannot render.
+func validateAppearance(s *AppearanceSettings) []appearanceFieldError {
+	var errs []appearanceFieldError
+	oneOf := func(field string, v *string, allowed []string, def string) {
+		if *v == "" && def != "" {
+			*v = def
+			return
+		}
+		if !contains(allowed, *v) {
+			errs = append(errs, appearanceFieldError{field, "must be one of " + strings.Join(allowed, ", ")})
+		}
+	}
+	oneOf("theme", &s.Theme, appearanceThemes, "")
+	oneOf("font_size", &s.FontSize, appearanceFontSizes, "medium")
+	oneOf("density", &s.Density, appearanceDensities, "comfortable")
+	oneOf("language", &s.Language, appearanceLanguages, "en")
+	oneOf("date_format", &s.DateFormat, appearanceDateFormats, "MM/DD/YYYY")
+	oneOf("time_format", &s.TimeFormat, appearanceTimeFormats, "12h")
+
+	if s.AccentColor == "" {
+		s.AccentColor = "#0066cc"
+	} else if !hexColorRe.MatchString(s.AccentColor) {
+		errs = append(errs, appearanceFieldError{"accent_color", "must be a hex color like #0066cc"})
+	}
+	keys := make([]string, 0, len(s.CustomColors))
+	for k := range s.CustomColors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !hexColorRe.MatchString(s.CustomColors[k]) {
+			errs = append(errs, appearanceFieldError{"custom_colors." + k, "must be a hex color like #0066cc"})
+		}
+	}
+	if s.FirstDayOfWeek < 0 || s.FirstDayOfWeek > 6 {
+		errs = append(errs, appearanceFieldError{"first_day_of_week", "must be between 0 (Sunday) and 6"})
+	}
+	return errs
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func appearanceRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return res
+}
+
+func appearanceErrCode(res *httptest.ResponseRecorder) any {
+	var out map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	return errCode(out)
+}
+
+func TestAppearanceRejectsInvalidColor(t *testing.T) {
+	h := NewAppearanceHandler(config.Config{EtcDir: t.TempDir()}).Routes()
+
+	res := appearanceRequest(t, h, http.MethodPut, "/", `{"theme":"neon","accent_color":"blue","density":"tight","custom_colors":{"primary":"#12345g"}}`)
+	if res.Code != http.StatusBadRequest || appearanceErrCode(res) != "appearance.invalid" {
+		t.Fatalf("want 400 appearance.invalid, got %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Error struct {
+			Details struct {
+				Fields []appearanceFieldError `json:"fields"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	got := map[string]bool{}
+	for _, f := range out.Error.Details.Fields {
+		got[f.Field] = true
+	}
+	for _, f := range []string{"theme", "accent_color", "density", "custom_colors.primary"} {
+		if !got[f] {
+			t.Fatalf("missing field error for %s: %s", f, res.Body.String())
+		}
+	}
+
+	// nothing was saved
+	var cur AppearanceSettings
+	_ = json.Unmarshal(appearanceRequest(t, h, http.MethodGet, "/", "").Body.Bytes(), &cur)
+	if cur.AccentColor != "#0066cc" || cur.Theme != "auto" {
+		t.Fatalf("invalid update was stored: %+v", cur)
+	}
+
+	res = appearanceRequest(t, h, http.MethodPut, "/", `{"theme":"dark","accent_color":"#abc"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("valid update: %d %s", res.Code, res.Body.String())
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &cur)
+	if cur.Density != "comfortable" || cur.Language != "en" || cur.AccentColor != "#abc" {
+		t.Fatalf("defaults not filled: %+v", cur)
+	}
+}
+
+func TestAppearanceApplyPreset(t *testing.T) {
+	h := NewAppearanceHandler(config.Config{EtcDir: t.TempDir()}).Routes()
+	if res := appearanceRequest(t, h, http.MethodPut, "/", `{"theme":"light","language":"de","time_format":"24h"}`); res.Code != http.StatusOK {
+		t.Fatalf("seed: %d %s", res.Code, res.Body.String())
+	}
+
+	res := appearanceRequest(t, h, http.MethodPost, "/presets/high-contrast/apply", "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("apply: %d %s", res.Code, res.Body.String())
+	}
+	var cur AppearanceSettings
+	_ = json.Unmarshal(appearanceRequest(t, h, http.MethodGet, "/", "").Body.Bytes(), &cur)
+	if cur.Theme != "light" || cur.AccentColor != "#000000" || !cur.HighContrast || cur.Density != "spacious" || cur.CustomColors["danger"] != "#ff0000" {
+		t.Fatalf("preset not applied: %+v", cur)
+	}
+	if cur.Language != "de" || cur.TimeFormat != "24h" {
+		t.Fatalf("preset overwrote locale settings: %+v", cur)
+	}
+	if errs := validateAppearance(&cur); len(errs) > 0 {
+		t.Fatalf("preset produced invalid settings: %+v", errs)
+	}
+
+	res = appearanceRequest(t, h, http.MethodPost, "/presets/nope/apply", "")
+	if res.Code != http.StatusNotFound || appearanceErrCode(res) != "appearance.preset_not_found" {
+		t.Fatalf("unknown preset: %d %s", res.Code, res.Body.String())
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -13,6 +14,17 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// listeningPortTables are consulted for host ports already bound when
+// checking an app install for conflicts (seam for tests).
+var listeningPortTables = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+// Compose source and the optional `docker compose config` check used by the
+// fallback install (seams for tests).
+var (
+	composeTemplate    = apps.ComposeTemplate
+	composeConfigCheck = apps.DockerComposeConfig
+)
+
 // handleGetCatalog returns the merged app catalog
 func handleGetCatalog(appManager *apps.Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -22,7 +34,12 @@ func handleGetCatalog(appManager *apps.Manager) http.HandlerFunc {
 			return
 		}
 
-		writeJSON(w, catalog)
+		body, err := json.Marshal(catalog)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "Failed to encode catalog")
+			return
+		}
+		serveCacheableJSON(w, r, append(body, '\n'), catalog.UpdatedAt)
 	}
 }
 
@@ -58,49 +75,6 @@ func handleGetApp(appManager *apps.Manager) http.HandlerFunc {
 	}
 }
 
-// handleInstallApp installs a new app
-func handleInstallApp(appManager *apps.Manager) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req pkgapps.InstallRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
-			return
-		}
-
-		// Validate request
-		if req.ID == "" {
-			httpx.WriteError(w, http.StatusBadRequest, "App ID is required")
-			return
-		}
-
-		// Get user ID from context
-		userID := getUserIDFromContext(r)
-
-		// Install app
-		if err := appManager.InstallApp(r.Context(), req, userID); err != nil {
-			if strings.Contains(err.Error(), "already installed") {
-				httpx.WriteError(w, http.StatusConflict, "App already installed")
-			} else if strings.Contains(err.Error(), "not found in catalog") {
-				httpx.WriteError(w, http.StatusNotFound, "App not found in catalog")
-			} else if strings.Contains(err.Error(), "validation failed") {
-				httpx.WriteError(w, http.StatusBadRequest, err.Error())
-			} else {
-				httpx.WriteError(w, http.StatusInternalServerError, "Failed to install app")
-			}
-			return
-		}
-
-		// Get installed app details
-		app, _ := appManager.GetApp(req.ID)
-
-		w.WriteHeader(http.StatusCreated)
-		writeJSON(w, map[string]interface{}{
-			"message": "App installed successfully",
-			"app":     app,
-		})
-	}
-}
-
 // handleUpgradeApp upgrades an existing app
 func handleUpgradeApp(appManager *apps.Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -243,13 +217,11 @@ func handleRollbackApp(appManager *apps.Manager) http.HandlerFunc {
 			return
 		}
 
-		if req.SnapshotTimestamp == "" {
-			httpx.WriteError(w, http.StatusBadRequest, "Snapshot timestamp is required")
-			return
-		}
-
+		// an empty snapshot timestamp restores the pre-upgrade rollback point
 		if err := appManager.RollbackApp(r.Context(), appID, req.SnapshotTimestamp, userID); err != nil {
-			if strings.Contains(err.Error(), "not found") {
+			if errors.Is(err, pkgapps.ErrNoRollbackPoint) {
+				httpx.WriteTypedError(w, http.StatusConflict, "apps.no_rollback_point", "No rollback point recorded for this app", 0)
+			} else if strings.Contains(err.Error(), "not found") {
 				httpx.WriteError(w, http.StatusNotFound, "App or snapshot not found")
 			} else {
 				httpx.WriteError(w, http.StatusInternalServerError, "Failed to rollback app")
@@ -362,6 +334,24 @@ func handleForceHealthCheck(appManager *apps.Manager) http.HandlerFunc {
 	}
 }
 
+// handleAppHealthHistory returns an app's health transitions, newest last
+func handleAppHealthHistory(appManager *apps.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appID := chi.URLParam(r, "id")
+
+		history, err := appManager.GetHealthHistory(appID)
+		if err != nil {
+			httpx.WriteError(w, http.StatusNotFound, "App not found")
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"id":      appID,
+			"history": history,
+		})
+	}
+}
+
 // handleSyncCatalogs manually triggers catalog sync (admin only)
 func handleSyncCatalogs(appManager *apps.Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -372,14 +362,23 @@ func handleSyncCatalogs(appManager *apps.Manager) http.HandlerFunc {
 
 		writeJSON(w, map[string]interface{}{
 			"message": "Catalogs synced successfully",
+			"sources": appManager.CatalogSources(),
+		})
+	}
+}
+
+// handleCatalogSources reports verification status of each catalog source
+func handleCatalogSources(appManager *apps.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"sources": appManager.CatalogSources(),
 		})
 	}
 }
 
 // Helper to get user ID from request context
 func getUserIDFromContext(r *http.Request) string {
-	// Get from X-UID header set by session middleware
-	if uid := r.Header.Get("X-UID"); uid != "" {
+	if uid := sessionUID(r); uid != "" {
 		return uid
 	}
 	return "system"
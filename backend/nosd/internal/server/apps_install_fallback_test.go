@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestFallbackAppInstallValidatesParams(t *testing.T) {
+	healthTestEnv(t)
+	sock, seen := fakeAgentSocket(t, nil)
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	installDir := t.TempDir()
+	t.Setenv("NOS_APPS_INSTALL_DIR", installDir)
+	old := listeningPortTables
+	listeningPortTables = nil
+	t.Cleanup(func() { listeningPortTables = old })
+	r := NewRouter(config.FromEnv())
+
+	post := func(body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/apps/install", strings.NewReader(body)))
+		return res
+	}
+
+	res := post(`{"id":"qbittorrent","config":{"web_port":70000}}`)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("out-of-range port: %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details struct {
+				Fields []struct{ Field, Message string } `json:"fields"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Error.Code != "apps.invalid_params" || len(out.Error.Details.Fields) != 1 || out.Error.Details.Fields[0].Field != "web_port" {
+		t.Fatalf("error body: %s", res.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "qbittorrent")); !os.IsNotExist(err) {
+		t.Fatal("files written for invalid params")
+	}
+
+	if res := post(`{"id":"no-such-app"}`); res.Code != http.StatusNotFound {
+		t.Fatalf("unknown app: %d", res.Code)
+	}
+	if n := len(seen()); n != 0 {
+		t.Fatalf("agent called %d times for rejected installs", n)
+	}
+
+	if res := post(`{"id":"qbittorrent","config":{"web_port":8090}}`); res.Code != http.StatusOK {
+		t.Fatalf("valid install: %d %s", res.Code, res.Body.String())
+	}
+	b, err := os.ReadFile(filepath.Join(installDir, "qbittorrent", ".env"))
+	if err != nil || string(b) != "WEBUI_PORT='8090'\n" {
+		t.Fatalf(".env: %q %v", b, err)
+	}
+}
+
+func TestFallbackAppInstallPortConflict(t *testing.T) {
+	healthTestEnv(t)
+	sock, seen := fakeAgentSocket(t, nil)
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	installDir := t.TempDir()
+	t.Setenv("NOS_APPS_INSTALL_DIR", installDir)
+
+	// a system service listens on 2283 (0x08EB); another app publishes 8081
+	tcp := filepath.Join(t.TempDir(), "tcp")
+	_ = os.WriteFile(tcp, []byte("  sl  local_address rem_address   st\n   0: 00000000:08EB 00000000:0000 0A\n"), 0o644)
+	old := listeningPortTables
+	listeningPortTables = []string{tcp}
+	t.Cleanup(func() { listeningPortTables = old })
+	_ = os.MkdirAll(filepath.Join(installDir, "other"), 0o755)
+	_ = os.WriteFile(filepath.Join(installDir, "other", "docker-compose.yml"), []byte("services:\n  x:\n    ports:\n      - \"8081:80\"\n"), 0o644)
+
+	r := NewRouter(config.FromEnv())
+	post := func(body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/apps/install", strings.NewReader(body)))
+		return res
+	}
+	for _, tc := range []struct {
+		body      string
+		owner     string
+		port, sug int
+	}{
+		{`{"id":"immich"}`, "system", 2283, 2284},
+		{`{"id":"nextcloud"}`, "other", 8081, 8082},
+	} {
+		res := post(tc.body)
+		if res.Code != http.StatusConflict {
+			t.Fatalf("%s: %d %s", tc.body, res.Code, res.Body.String())
+		}
+		var out struct {
+			Error struct {
+				Code    string `json:"code"`
+				Details struct {
+					Conflicts []struct {
+						Port      int    `json:"port"`
+						Owner     string `json:"owner"`
+						Suggested int    `json:"suggested_port"`
+					} `json:"conflicts"`
+				} `json:"details"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		c := out.Error.Details.Conflicts
+		if out.Error.Code != "apps.port_conflict" || len(c) != 1 || c[0].Port != tc.port || c[0].Owner != tc.owner || c[0].Suggested != tc.sug {
+			t.Fatalf("%s: %s", tc.body, res.Body.String())
+		}
+	}
+	if n := len(seen()); n != 0 {
+		t.Fatalf("agent called %d times for conflicting installs", n)
+	}
+
+	// taking the suggestion succeeds
+	if res := post(`{"id":"nextcloud","config":{"https_port":8082}}`); res.Code != http.StatusOK {
+		t.Fatalf("suggested port: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestFallbackAppInstallRejectsBrokenCompose(t *testing.T) {
+	healthTestEnv(t)
+	sock, seen := fakeAgentSocket(t, nil)
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	installDir := t.TempDir()
+	t.Setenv("NOS_APPS_INSTALL_DIR", installDir)
+	oldTables, oldTemplate, oldCheck := listeningPortTables, composeTemplate, composeConfigCheck
+	listeningPortTables, composeConfigCheck = nil, nil
+	// a template whose ports key is mis-indented
+	composeTemplate = func(id string) string {
+		return "services:\n  app:\n    image: alpine\n   ports:\n      - 80:80\n"
+	}
+	t.Cleanup(func() { listeningPortTables, composeTemplate, composeConfigCheck = oldTables, oldTemplate, oldCheck })
+	r := NewRouter(config.FromEnv())
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/apps/install", strings.NewReader(`{"id":"jellyfin"}`)))
+	if res.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("broken compose: %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details struct {
+				Error struct{ Message string } `json:"error"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Error.Code != "apps.invalid_compose" || !strings.Contains(out.Error.Details.Error.Message, "invalid YAML") {
+		t.Fatalf("error body: %s", res.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "jellyfin")); !os.IsNotExist(err) {
+		t.Fatal("files written for broken compose")
+	}
+	if n := len(seen()); n != 0 {
+		t.Fatalf("agent called %d times for broken compose", n)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"nithronos/backend/nosd/pkg/backup"
 )
 
@@ -21,6 +22,9 @@ type BackupHandler struct {
 
 // NewBackupHandler creates a new backup handler
 func NewBackupHandler(logger zerolog.Logger, scheduler *backup.Scheduler, replicator *backup.Replicator, restorer *backup.Restorer) *BackupHandler {
+	if scheduler != nil {
+		registerBackupScheduler(scheduler)
+	}
 	return &BackupHandler{
 		logger:     logger.With().Str("component", "backup-handler").Logger(),
 		scheduler:  scheduler,
@@ -40,6 +44,7 @@ func (h *BackupHandler) Routes() chi.Router {
 		r.Get("/{id}", h.GetSchedule)
 		r.Patch("/{id}", h.UpdateSchedule)
 		r.Delete("/{id}", h.DeleteSchedule)
+		r.Post("/{id}/retention/preview", h.PreviewRetention)
 	})
 	
 	// Snapshots
@@ -148,6 +153,40 @@ func (h *BackupHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// PreviewRetention reports which snapshots the schedule's retention policy
+// would prune, without deleting them.
+func (h *BackupHandler) PreviewRetention(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	
+	snapshots, err := h.scheduler.DryRunRetention(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Schedule not found")
+		return
+	}
+	if snapshots == nil {
+		snapshots = []*backup.Snapshot{}
+	}
+	
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"schedule_id": id,
+		"snapshots":   snapshots,
+		"count":       len(snapshots),
+	})
+}
+
+// handleRetentionPreview serves PreviewRetention from the backup scheduler
+// state on disk, so the preview works without a running scheduler.
+func handleRetentionPreview(stateFile string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := backup.NewScheduler(log.Logger, stateFile, nil)
+		if err := s.LoadState(); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to load backup schedules")
+			return
+		}
+		(&BackupHandler{logger: log.Logger, scheduler: s}).PreviewRetention(w, r)
+	}
+}
+
 // Snapshot handlers
 
 func (h *BackupHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/backup"
+)
+
+func TestRetentionPreviewRoute(t *testing.T) {
+	healthTestEnv(t)
+	stateFile := filepath.Join(t.TempDir(), "backup-schedules.json")
+	t.Setenv("NOS_BACKUP_STATE", stateFile)
+
+	// 20 daily snapshots of one schedule, plus a manual one that is never pruned
+	now := time.Now()
+	var snaps []*backup.Snapshot
+	for i := 0; i < 20; i++ {
+		snaps = append(snaps, &backup.Snapshot{
+			ID:         fmt.Sprintf("snap-%02d", i),
+			Subvolume:  "/srv/data",
+			CreatedAt:  now.Add(-time.Duration(i)*24*time.Hour - time.Hour),
+			ScheduleID: "sched-1",
+		})
+	}
+	snaps = append(snaps, &backup.Snapshot{ID: "manual", Subvolume: "/srv/data", CreatedAt: now.Add(-90 * 24 * time.Hour)})
+	state, _ := json.Marshal(map[string]any{
+		"schedules": map[string]*backup.Schedule{"sched-1": {
+			ID:         "sched-1",
+			Subvolumes: []string{"/srv/data"},
+			Retention:  backup.RetentionPolicy{MinKeep: 2, Days: 3, Weeks: 2},
+		}},
+		"snapshots": map[string][]*backup.Snapshot{"/srv/data": snaps},
+	})
+	if err := os.WriteFile(stateFile, state, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter(config.FromEnv())
+	preview := func(id string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/schedules/"+id+"/retention/preview", nil))
+		return res
+	}
+
+	res := preview("sched-1")
+	if res.Code != http.StatusOK {
+		t.Fatalf("preview: %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Snapshots []*backup.Snapshot `json:"snapshots"`
+		Count     int                `json:"count"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+
+	s := backup.NewScheduler(zerolog.Nop(), stateFile, nil)
+	if err := s.LoadState(); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := s.DryRunRetention("sched-1")
+	if len(want) == 0 || out.Count != len(want) {
+		t.Fatalf("preview returned %d snapshots, scheduler selects %d", out.Count, len(want))
+	}
+	var got, exp []string
+	for i := range want {
+		got, exp = append(got, out.Snapshots[i].ID), append(exp, want[i].ID)
+	}
+	sort.Strings(got)
+	sort.Strings(exp)
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Fatalf("preview %v, scheduler %v", got, exp)
+	}
+
+	// the preview deletes nothing
+	after, _ := os.ReadFile(stateFile)
+	if string(after) != string(state) {
+		t.Fatal("preview rewrote the scheduler state")
+	}
+	if res := preview("missing"); res.Code != http.StatusNotFound {
+		t.Fatalf("unknown schedule: %d", res.Code)
+	}
+}
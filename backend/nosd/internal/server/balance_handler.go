@@ -26,7 +26,7 @@ func handleBalanceStatus(cfg config.Config) http.HandlerFunc {
 		
 		if mountPath != "" {
 			// Try to get status from agent
-			agentSocket := "/run/nos-agent.sock"
+			agentSocket := cfg.AgentSocket()
 			agent := agentclient.New(agentSocket)
 			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 			defer cancel()
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// limitBody rejects request bodies over limit bytes on mutating methods with
+// 413 request.too_large. GET, HEAD and OPTIONS pass through untouched, which
+// keeps SSE streams and file downloads exempt.
+//
+// A declared Content-Length over the limit is refused before anything is
+// read. Chunked bodies are buffered up to the limit so an oversized one is
+// still answered with 413 rather than surfacing as a decode error in the
+// handler.
+func limitBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > limit {
+				writeBodyTooLarge(w, limit)
+				return
+			}
+			body := http.MaxBytesReader(w, r.Body, limit)
+			if r.ContentLength < 0 {
+				buf, err := io.ReadAll(body)
+				if err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						writeBodyTooLarge(w, limit)
+						return
+					}
+					httpx.WriteError(w, http.StatusBadRequest, "invalid body")
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(buf))
+			} else {
+				r.Body = body
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	httpx.WriteTypedError(w, http.StatusRequestEntityTooLarge, "request.too_large", fmt.Sprintf("Request body exceeds %d bytes", limit), 0)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+// chunked hides the length of r so the request is sent without
+// Content-Length.
+type chunked struct{ r io.Reader }
+
+func (c chunked) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+func TestOversizedBodyRejected(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_HTTP_MAX_BODY_BYTES", "64")
+	r := NewRouter(config.FromEnv())
+	big := `{"devices":["/dev/sdb"],"label":"` + strings.Repeat("x", 200) + `"}`
+
+	for name, body := range map[string]io.Reader{
+		"content-length": strings.NewReader(big),
+		"chunked":        chunked{strings.NewReader(big)},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/create", body)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("%s: expected 413, got %d %s", name, res.Code, res.Body.String())
+		}
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		if errCode(out) != "request.too_large" {
+			t.Fatalf("%s: error code: %s", name, res.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/create", strings.NewReader(`{"devices":[]}`))
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code == http.StatusRequestEntityTooLarge {
+		t.Fatalf("small body rejected: %s", res.Body.String())
+	}
+}
+
+func TestBodyLimitPassesSmallAndReadOnlyRequests(t *testing.T) {
+	var got []byte
+	h := limitBody(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}))
+
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/", chunked{strings.NewReader(`{"a":1}`)}))
+	if res.Code != http.StatusOK || string(got) != `{"a":1}` {
+		t.Fatalf("small chunked body: %d %q", res.Code, got)
+	}
+
+	// GET routes (SSE streams, downloads) are never limited.
+	large := bytes.Repeat([]byte("y"), 1024)
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(large)))
+	if res.Code != http.StatusOK || len(got) != len(large) {
+		t.Fatalf("GET should pass through: %d %d", res.Code, len(got))
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+// remoteIP is the address of the peer that sent r, without the port.
+func remoteIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return strings.Trim(ip, "[]")
+}
+
+// trustedProxy reports whether ip is one of the configured reverse proxies
+// (trustedProxies, or the deprecated trustProxy ranges): the reloaded list
+// if there is one, else cfg's.
+func trustedProxy(ip string, cfg config.Config) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	nets := RuntimeTrustedProxies()
+	if nets == nil {
+		nets = cfg.TrustedProxyNets()
+	}
+	for _, n := range nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromTrustedProxy reports whether r was sent by a trusted proxy, so its
+// X-Forwarded-* headers can be believed.
+func fromTrustedProxy(r *http.Request, cfg config.Config) bool {
+	return trustedProxy(remoteIP(r), cfg)
+}
+
+// forwardingHeaders are set by reverse proxies for the request they relay.
+var forwardingHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "X-Real-IP"}
+
+// fromLocalhost reports whether r was made on this machine: the peer is
+// loopback and the request wasn't relayed. The bundled Caddy connects from
+// loopback too (and is a trusted proxy by default), so a loopback peer
+// alone doesn't make a request local; Caddy always adds X-Forwarded-For,
+// and any forwarding header marks the request as proxied.
+func fromLocalhost(r *http.Request) bool {
+	ip := net.ParseIP(remoteIP(r))
+	if ip == nil || !ip.IsLoopback() {
+		return false
+	}
+	for _, h := range forwardingHeaders {
+		if len(r.Header.Values(h)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// clientIP is the address r came from. X-Forwarded-For is only read when
+// the peer is a trusted proxy, and then walked from the right past every
+// trusted hop: the first untrusted hop is the client. Hops further left were
+// written by the client itself and are ignored. If every hop is a trusted
+// proxy, the leftmost one is used; a malformed hop stops the walk at the
+// last proxy.
+func clientIP(r *http.Request, cfg config.Config) string {
+	ip := remoteIP(r)
+	if !trustedProxy(ip, cfg) {
+		return ip
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if net.ParseIP(hop) == nil {
+			return ip
+		}
+		ip = hop
+		if !trustedProxy(hop, cfg) {
+			return ip
+		}
+	}
+	return ip
+}
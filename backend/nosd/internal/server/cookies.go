@@ -18,26 +18,14 @@ const (
 	cookieCSRF    = "nos_csrf"
 )
 
-// issueSessionCookies sets nos_session (15m) and optionally rotates/sets nos_refresh (7d)
-func issueSessionCookies(w http.ResponseWriter, cfg config.Config, uid string, keepRefresh bool) error {
-	now := time.Now().UTC()
-	// session token
-	sess := map[string]any{"uid": uid, "exp": now.Add(15 * time.Minute).Unix()}
-	sVal, err := encodeOpaque(cfg, cookieSession, sess)
-	if err != nil {
-		return err
-	}
-	http.SetCookie(w, &http.Cookie{Name: cookieSession, Value: sVal, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Expires: now.Add(15 * time.Minute)})
-	// refresh
-	if keepRefresh {
-		ref := map[string]any{"uid": uid, "exp": now.Add(7 * 24 * time.Hour).Unix()}
-		rVal, err := encodeOpaque(cfg, cookieRefresh, ref)
-		if err != nil {
-			return err
-		}
-		http.SetCookie(w, &http.Cookie{Name: cookieRefresh, Value: rVal, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Expires: now.Add(7 * 24 * time.Hour)})
-	}
-	return nil
+// sessionLifetime is how long server-side session records live: the access
+// TTL, or the refresh TTL when the user asked to be remembered so the record
+// outlives the short session cookie along with nos_refresh.
+func sessionLifetime(cfg config.Config, rememberMe bool) time.Duration {
+	if rememberMe {
+		return cfg.RefreshTTL()
+	}
+	return cfg.SessionTTL()
 }
 
 func clearAuthCookies(w http.ResponseWriter) {
@@ -69,22 +57,27 @@ func decodeSessionUID(r *http.Request, cfg config.Config) (string, bool) {
 
 // decodeRefreshUID validates nos_refresh and returns uid string
 func decodeRefreshUID(r *http.Request, cfg config.Config) (string, bool) {
+	uid, _, ok := decodeRefreshParts(r, cfg)
+	return uid, ok
+}
+
+// decodeRefreshParts returns uid and sid (when present) from nos_refresh
+func decodeRefreshParts(r *http.Request, cfg config.Config) (string, string, bool) {
 	ck, err := r.Cookie(cookieRefresh)
 	if err != nil {
-		return "", false
+		return "", "", false
 	}
 	var m map[string]any
 	if err := decodeOpaque(cfg, cookieRefresh, ck.Value, &m); err != nil {
-		return "", false
+		return "", "", false
 	}
 	expUnix, ok := asInt64(m["exp"])
 	if !ok || time.Now().UTC().Unix() > expUnix {
-		return "", false
-	}
-	if uid, ok := m["uid"].(string); ok && uid != "" {
-		return uid, true
+		return "", "", false
 	}
-	return "", false
+	uid, _ := m["uid"].(string)
+	sid, _ := m["sid"].(string)
+	return uid, sid, uid != ""
 }
 
 func issueCSRFCookie(w http.ResponseWriter) {
@@ -95,19 +88,19 @@ func issueCSRFCookie(w http.ResponseWriter) {
 // issueSessionCookiesSID sets nos_session with server-side sid binding
 func issueSessionCookiesSID(w http.ResponseWriter, cfg config.Config, uid, sid string, keepRefresh bool) error {
 	now := time.Now().UTC()
-	sess := map[string]any{"uid": uid, "sid": sid, "exp": now.Add(15 * time.Minute).Unix()}
+	sess := map[string]any{"uid": uid, "sid": sid, "exp": now.Add(cfg.SessionTTL()).Unix()}
 	sVal, err := encodeOpaque(cfg, cookieSession, sess)
 	if err != nil {
 		return err
 	}
-	http.SetCookie(w, &http.Cookie{Name: cookieSession, Value: sVal, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Expires: now.Add(15 * time.Minute)})
+	http.SetCookie(w, &http.Cookie{Name: cookieSession, Value: sVal, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Expires: now.Add(cfg.SessionTTL()), MaxAge: int(cfg.SessionTTL().Seconds())})
 	if keepRefresh {
-		ref := map[string]any{"uid": uid, "exp": now.Add(7 * 24 * time.Hour).Unix()}
+		ref := map[string]any{"uid": uid, "sid": sid, "exp": now.Add(cfg.RefreshTTL()).Unix()}
 		rVal, err := encodeOpaque(cfg, cookieRefresh, ref)
 		if err != nil {
 			return err
 		}
-		http.SetCookie(w, &http.Cookie{Name: cookieRefresh, Value: rVal, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Expires: now.Add(7 * 24 * time.Hour)})
+		http.SetCookie(w, &http.Cookie{Name: cookieRefresh, Value: rVal, Path: "/", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Expires: now.Add(cfg.RefreshTTL()), MaxAge: int(cfg.RefreshTTL().Seconds())})
 	}
 	return nil
 }
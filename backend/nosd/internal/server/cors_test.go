@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func corsTestHandler(t *testing.T, origins ...string) http.Handler {
+	t.Helper()
+	SetRuntimeCORSOrigins(origins)
+	t.Cleanup(func() { SetRuntimeCORSOrigins(nil) })
+	return DynamicCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func corsRequest(h http.Handler, method, origin string, preflight bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1/pools", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type, x-csrf-token")
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	h := corsTestHandler(t, "https://nas.local", "http://192.168.1.10:8080", "https://*.example.com")
+	for _, origin := range []string{"https://nas.local", "http://192.168.1.10:8080", "https://nas.example.com", "https://a.b.example.com", "HTTPS://NAS.LOCAL"} {
+		res := corsRequest(h, http.MethodGet, origin, false)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", origin, res.Code)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Fatalf("%s: Allow-Origin %q", origin, got)
+		}
+		if res.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Fatalf("%s: credentials not allowed", origin)
+		}
+		if res.Header().Get("Vary") != "Origin" {
+			t.Fatalf("%s: Vary %q", origin, res.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestCORSDisallowedOrigins(t *testing.T) {
+	h := corsTestHandler(t, "https://nas.local", "https://*.example.com")
+	for _, origin := range []string{
+		"https://evil.test",
+		"http://nas.local",          // scheme differs
+		"https://nas.local:8443",    // port differs
+		"https://example.com",       // wildcard needs a subdomain
+		"https://nasexample.com",    // not a subdomain
+		"https://nas.example.com.x", // suffix only
+	} {
+		res := corsRequest(h, http.MethodGet, origin, false)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: simple requests still reach the handler, got %d", origin, res.Code)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("%s: unexpected Allow-Origin %q", origin, got)
+		}
+		if res := corsRequest(h, http.MethodOptions, origin, true); res.Code != http.StatusForbidden {
+			t.Fatalf("%s: preflight expected 403, got %d", origin, res.Code)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := corsTestHandler(t, "https://nas.local")
+	res := corsRequest(h, http.MethodOptions, "https://nas.local", true)
+	if res.Code != http.StatusNoContent {
+		t.Fatalf("preflight: %d", res.Code)
+	}
+	hdr := res.Header()
+	if hdr.Get("Access-Control-Allow-Origin") != "https://nas.local" || hdr.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("preflight origin headers: %v", hdr)
+	}
+	if hdr.Get("Access-Control-Allow-Methods") != corsAllowMethods || hdr.Get("Access-Control-Allow-Headers") != corsAllowHeaders {
+		t.Fatalf("preflight methods/headers: %v", hdr)
+	}
+	if hdr.Get("Access-Control-Max-Age") == "" || len(hdr.Values("Vary")) != 3 {
+		t.Fatalf("preflight caching headers: %v", hdr)
+	}
+
+	// OPTIONS without Access-Control-Request-Method isn't a preflight.
+	if res := corsRequest(h, http.MethodOptions, "https://nas.local", false); res.Code != http.StatusOK {
+		t.Fatalf("plain OPTIONS should reach the router, got %d", res.Code)
+	}
+}
+
+func TestCORSStarOmitsCredentials(t *testing.T) {
+	h := corsTestHandler(t, "*")
+	res := corsRequest(h, http.MethodGet, "https://anything.test", false)
+	if res.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("Allow-Origin: %q", res.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if res.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("credentials must not be allowed with *")
+	}
+}
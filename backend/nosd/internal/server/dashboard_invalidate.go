@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"nithronos/backend/nosd/internal/api"
+)
+
+// dashboardWritePrefixes are the API trees whose writes change what the
+// dashboard endpoints report.
+var dashboardWritePrefixes = []string{"/api/v1/pools", "/api/v1/apps", "/api/v1/shares"}
+
+// invalidateDashboardCache is a seam for tests.
+var invalidateDashboardCache = api.InvalidateDashboardCache
+
+// invalidateDashboard drops the cached dashboard responses after every
+// successful write under dashboardWritePrefixes, so the next poll shows
+// the change instead of waiting out api.DashboardCacheTTL.
+func invalidateDashboard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !hasDashboardWritePrefix(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ww := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(ww, r)
+		if ww.status < http.StatusBadRequest {
+			invalidateDashboardCache()
+		}
+	})
+}
+
+func hasDashboardWritePrefix(path string) bool {
+	for _, p := range dashboardWritePrefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
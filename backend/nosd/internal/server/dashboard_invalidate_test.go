@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDashboardInvalidatedBySuccessfulWrites(t *testing.T) {
+	calls := 0
+	old := invalidateDashboardCache
+	invalidateDashboardCache = func() { calls++ }
+	t.Cleanup(func() { invalidateDashboardCache = old })
+
+	r := chi.NewRouter()
+	r.Use(invalidateDashboard)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }
+	r.Post("/api/v1/shares", ok)
+	r.Post("/api/v1/apps/{id}/stop", ok)
+	r.Delete("/api/v1/pools/{id}", ok)
+	r.Get("/api/v1/pools", ok)
+	r.Post("/api/v1/poolside", ok)
+	r.Post("/api/v1/users", ok)
+	r.Post("/api/v1/apps/install", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+
+	for _, tc := range []struct {
+		method, path string
+		invalidates  bool
+	}{
+		{http.MethodPost, "/api/v1/shares", true},
+		{http.MethodPost, "/api/v1/apps/nextcloud/stop", true},
+		{http.MethodDelete, "/api/v1/pools/p1", true},
+		{http.MethodGet, "/api/v1/pools", false},
+		{http.MethodPost, "/api/v1/poolside", false},
+		{http.MethodPost, "/api/v1/users", false},
+		{http.MethodPost, "/api/v1/apps/install", false},
+	} {
+		before := calls
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+		if got := calls > before; got != tc.invalidates {
+			t.Errorf("%s %s invalidated = %v, want %v", tc.method, tc.path, got, tc.invalidates)
+		}
+	}
+}
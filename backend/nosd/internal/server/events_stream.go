@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// eventsPollInterval is how often the event stream checks the event log for
+// appended lines.
+var eventsPollInterval = time.Second
+
+// eventTail follows an append-only JSONL file from an offset. A file that
+// shrank was rotated or truncated and is read again from the start.
+type eventTail struct {
+	path   string
+	offset int64
+}
+
+// newEventTail starts at the current end of path, so only events written
+// after the call are returned.
+func newEventTail(path string) *eventTail {
+	t := &eventTail{path: path}
+	if st, err := os.Stat(path); err == nil {
+		t.offset = st.Size()
+	}
+	return t
+}
+
+// next returns the complete events appended since the last call. A partial
+// trailing line is left for the next call; lines that aren't events are
+// skipped.
+func (t *eventTail) next() []Event {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	if st.Size() < t.offset {
+		t.offset = 0
+	}
+	if st.Size() == t.offset {
+		return nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	var out []Event
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			// no newline yet: the writer hasn't finished this line
+			return out
+		}
+		t.offset += int64(len(line))
+		var ev Event
+		if json.Unmarshal(line, &ev) == nil && !ev.Timestamp.IsZero() {
+			out = append(out, ev)
+		}
+	}
+}
+
+// GET /api/v1/monitoring/events/stream
+//
+// Server-Sent Events: one "event" event per entry appended to the event log
+// after the client connected. GET /api/v1/monitoring/events has the backlog.
+func handleMonitoringEventsStream(w http.ResponseWriter, r *http.Request) {
+	flusher, canFlush := w.(http.Flusher)
+	if !canFlush {
+		httpx.WriteError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ctx, done := streams.track(r.Context())
+	defer done()
+
+	tail := newEventTail(monitoringEventsPath())
+	// commit the headers so clients know the stream is open
+	_, _ = w.Write([]byte(": connected\n\n"))
+	flusher.Flush()
+
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-poll.C:
+			events := tail.next()
+			for _, ev := range events {
+				data, _ := json.Marshal(ev)
+				_, _ = w.Write([]byte("event: event\ndata: "))
+				_, _ = w.Write(data)
+				_, _ = w.Write([]byte("\n\n"))
+			}
+			if len(events) > 0 {
+				flusher.Flush()
+			}
+		case <-keepalive.C:
+			_, _ = w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func eventsLogTestEnv(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	oldPath, oldPoll := monitoringEventsPath, eventsPollInterval
+	monitoringEventsPath = func() string { return path }
+	eventsPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { monitoringEventsPath, eventsPollInterval = oldPath, oldPoll })
+	return path
+}
+
+func appendEventLine(t *testing.T, path, line string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEventTailFollowsAppendsAndRotation(t *testing.T) {
+	path := eventsLogTestEnv(t)
+	appendEventLine(t, path, `{"id":"old","timestamp":"2026-10-17T10:00:00Z","level":"info","message":"before"}`+"\n")
+
+	tail := newEventTail(path)
+	if got := tail.next(); len(got) != 0 {
+		t.Fatalf("existing events returned: %+v", got)
+	}
+	appendEventLine(t, path, "not json\n"+`{"id":"a","timestamp":"2026-10-17T10:01:00Z","level":"warning","message":"one"}`+"\n"+`{"id":"b","timestamp"`)
+	got := tail.next()
+	if len(got) != 1 || got[0].ID != "a" {
+		t.Fatalf("after append: %+v", got)
+	}
+	// the partial line is returned once it is complete
+	appendEventLine(t, path, `:"2026-10-17T10:02:00Z","level":"error","message":"two"}`+"\n")
+	if got := tail.next(); len(got) != 1 || got[0].ID != "b" || got[0].Level != "error" {
+		t.Fatalf("completed line: %+v", got)
+	}
+	// a rotated log is read from the start
+	if err := os.WriteFile(path, []byte(`{"id":"c","timestamp":"2026-10-17T10:03:00Z","level":"info","message":"new"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := tail.next(); len(got) != 1 || got[0].ID != "c" {
+		t.Fatalf("after rotation: %+v", got)
+	}
+}
+
+func TestMonitoringEventsStream(t *testing.T) {
+	path := eventsLogTestEnv(t)
+	appendEventLine(t, path, `{"id":"old","timestamp":"2026-10-17T10:00:00Z","level":"info","message":"before"}`+"\n")
+	srv := httptest.NewServer(http.HandlerFunc(handleMonitoringEventsStream))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	appendEventLine(t, path, `{"id":"live","timestamp":"2026-10-17T10:05:00Z","level":"critical","category":"storage","message":"pool degraded"}`+"\n")
+
+	sc := bufio.NewScanner(res.Body)
+	var kind string
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "event: ") {
+			kind = strings.TrimPrefix(line, "event: ")
+			continue
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		if kind != "event" || ev.ID != "live" || ev.Level != "critical" {
+			t.Fatalf("got %s %+v; want only the event appended after connecting", kind, ev)
+		}
+		return
+	}
+	t.Fatalf("stream ended without an event: %v", sc.Err())
+}
@@ -3,32 +3,59 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"nithronos/backend/nosd/internal/config"
 	"nithronos/backend/nosd/internal/disks"
 	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/internal/notifications"
+	"nithronos/backend/nosd/pkg/httpx"
 )
 
 type healthConfig struct {
 	SMART struct {
 		TempWarn        int `json:"tempWarn"`
 		TempCrit        int `json:"tempCrit"`
+		TempHysteresis  int `json:"tempHysteresis"`
 		ReallocatedWarn int `json:"reallocatedWarn"`
 		MediaErrWarn    int `json:"mediaErrWarn"`
+		// at_risk thresholds; 0 disables a check
+		PendingWarn       int `json:"pendingWarn"`
+		UncorrectableWarn int `json:"uncorrectableWarn"`
+		CRCErrWarn        int `json:"crcErrWarn"`
+		WearWarnPct       int `json:"wearWarnPct"`
 	} `json:"smart"`
 }
 
+// riskThresholds maps the SMART settings onto the disks at_risk evaluator.
+func (hc healthConfig) riskThresholds() disks.RiskThresholds {
+	return disks.RiskThresholds{
+		Reallocated:   hc.SMART.ReallocatedWarn,
+		Pending:       hc.SMART.PendingWarn,
+		Uncorrectable: hc.SMART.UncorrectableWarn,
+		CRCErrors:     hc.SMART.CRCErrWarn,
+		MediaErrors:   hc.SMART.MediaErrWarn,
+		PercentUsed:   hc.SMART.WearWarnPct,
+	}
+}
+
 type alert struct {
 	ID        string   `json:"id"`
 	Severity  string   `json:"severity"` // warn|crit
-	Kind      string   `json:"kind"`     // smart
+	Kind      string   `json:"kind"`     // smart|temperature|btrfs
 	Device    string   `json:"device"`
+	Pool      string   `json:"pool,omitempty"` // btrfs alerts: pool mount
 	Messages  []string `json:"messages"`
 	CreatedAt string   `json:"createdAt"`
+	// temperature alerts only
+	TempCelsius *int `json:"tempC,omitempty"`
+	Threshold   int  `json:"thresholdC,omitempty"`
 }
 
 func alertsPath() string {
@@ -44,11 +71,17 @@ func loadHealthConfig(cfg config.Config) healthConfig {
 	hc := healthConfig{}
 	hc.SMART.TempWarn = 60
 	hc.SMART.TempCrit = 70
-	hc.SMART.ReallocatedWarn = 1
-	hc.SMART.MediaErrWarn = 1
+	hc.SMART.TempHysteresis = 5
+	def := disks.DefaultRiskThresholds()
+	hc.SMART.ReallocatedWarn = def.Reallocated
+	hc.SMART.MediaErrWarn = def.MediaErrors
+	hc.SMART.PendingWarn = def.Pending
+	hc.SMART.UncorrectableWarn = def.Uncorrectable
+	hc.SMART.CRCErrWarn = def.CRCErrors
+	hc.SMART.WearWarnPct = def.PercentUsed
 	// best-effort load from /etc/nos/health.yaml or health.json
 	pathY := filepath.Join(cfg.EtcDir, "nos", "health.yaml")
-	pathJ := filepath.Join(cfg.EtcDir, "nos", "health.json")
+	pathJ := healthConfigPath(cfg)
 	if b, err := os.ReadFile(pathJ); err == nil {
 		_ = json.Unmarshal(b, &hc)
 		return hc
@@ -64,65 +97,266 @@ func loadHealthConfig(cfg config.Config) healthConfig {
 
 func handleAlertsGet(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var list []alert
-		if b, err := os.ReadFile(alertsPath()); err == nil {
-			_ = json.Unmarshal(b, &list)
+		writeJSON(w, map[string]any{"alerts": loadAlerts()})
+	}
+}
+
+// severityRank orders alert severities so only escalations notify.
+var severityRank = map[string]int{"warn": 1, "crit": 2}
+
+// notifyNewAlerts sends a storage notification for each alert that is new
+// since the previous scan or has escalated, so enabled channels hear about
+// it once rather than on every scan.
+func notifyNewAlerts(nm *notifications.Manager, prev, cur []alert) {
+	if nm == nil {
+		return
+	}
+	seen := map[string]int{}
+	for _, a := range prev {
+		seen[a.Kind+"|"+a.Device] = severityRank[a.Severity]
+	}
+	for _, a := range cur {
+		if rank, ok := seen[a.Kind+"|"+a.Device]; ok && rank >= severityRank[a.Severity] {
+			continue
+		}
+		notifType := "warning"
+		if a.Severity == "crit" {
+			notifType = "error"
+		}
+		details := map[string]interface{}{
+			"device":   a.Device,
+			"kind":     a.Kind,
+			"severity": a.Severity,
+		}
+		if a.Pool != "" {
+			details["pool"] = a.Pool
+		}
+		if a.TempCelsius != nil {
+			details["temperatureC"] = *a.TempCelsius
+			details["thresholdC"] = a.Threshold
+		}
+		nm.SendStorageNotification("Disk alert: "+a.Device, strings.Join(a.Messages, ", "), notifType, details)
+	}
+}
+
+// tempEvaluator tracks each device's temperature alert level across scans.
+// A level is entered when the temperature reaches its threshold and only
+// left once it drops hysteresis degrees below, so a disk idling around a
+// threshold does not flap between alerting and clear.
+type tempEvaluator struct {
+	mu    sync.Mutex
+	level map[string]string // device -> "", "warn" or "crit"
+}
+
+func newTempEvaluator() *tempEvaluator {
+	return &tempEvaluator{level: map[string]string{}}
+}
+
+// eval returns the device's level for temp and the threshold that level
+// corresponds to.
+func (e *tempEvaluator) eval(device string, temp int, hc healthConfig) (string, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	warn, crit, hyst := hc.SMART.TempWarn, hc.SMART.TempCrit, hc.SMART.TempHysteresis
+	cur := e.level[device]
+	next := ""
+	switch {
+	case temp >= crit:
+		next = "crit"
+	case temp >= warn:
+		next = "warn"
+	}
+	if cur == "crit" && next != "crit" && temp > crit-hyst {
+		next = "crit"
+	}
+	if cur != "" && next == "" && temp > warn-hyst {
+		next = "warn"
+	}
+	e.level[device] = next
+	if next == "crit" {
+		return next, crit
+	}
+	return next, warn
+}
+
+// healthTemps carries temperature alert levels across the manual health scan
+// and the periodic SMART scan, so both share one hysteresis state.
+var healthTemps = newTempEvaluator()
+
+// tempAlert evaluates one temperature reading and returns the alert for it,
+// or nil when the device is below its thresholds.
+func tempAlert(device string, temp int, hc healthConfig, temps *tempEvaluator, now string) *alert {
+	sev, threshold := temps.eval(device, temp, hc)
+	if sev == "" {
+		return nil
+	}
+	label := "high"
+	if sev == "crit" {
+		label = "critical"
+	}
+	return &alert{
+		ID: generateUUID(), Severity: sev, Kind: "temperature", Device: device, CreatedAt: now,
+		Messages:    []string{fmt.Sprintf("temperature %s: %d°C (threshold %d°C)", label, temp, threshold)},
+		TempCelsius: &temp, Threshold: threshold,
+	}
+}
+
+var (
+	alertsMu      sync.Mutex // serializes alerts.json read-modify-write
+	alertNotifier *notifications.Manager
+)
+
+// setAlertNotifier sets the manager background scans notify through.
+func setAlertNotifier(nm *notifications.Manager) {
+	alertsMu.Lock()
+	alertNotifier = nm
+	alertsMu.Unlock()
+}
+
+func loadAlerts() []alert {
+	var list []alert
+	if b, err := os.ReadFile(alertsPath()); err == nil {
+		_ = json.Unmarshal(b, &list)
+	}
+	return list
+}
+
+// updateTempAlerts evaluates the temperatures of a periodic SMART scan,
+// replaces the temperature alerts of the scanned devices and notifies about
+// new or escalated ones. Devices the scan did not read keep their alerts.
+func updateTempAlerts(ctx context.Context, cfg config.Config, scan map[string]smartSample) error {
+	hc := loadHealthConfig(cfg)
+	now := time.Now().UTC().Format(time.RFC3339)
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	prev := loadAlerts()
+	cur := []alert{}
+	for _, a := range prev {
+		if _, scanned := scan[strings.TrimPrefix(a.Device, "/dev/")]; a.Kind == "temperature" && scanned {
+			continue
+		}
+		cur = append(cur, a)
+	}
+	for name, s := range scan {
+		if s.TemperatureC == nil {
+			continue
+		}
+		if a := tempAlert("/dev/"+name, *s.TemperatureC, hc, healthTemps, now); a != nil {
+			cur = append(cur, *a)
 		}
-		writeJSON(w, map[string]any{"alerts": list})
 	}
+	_ = os.MkdirAll(filepath.Dir(alertsPath()), 0o755)
+	if err := fsatomic.SaveJSON(ctx, alertsPath(), cur, 0o600); err != nil {
+		return err
+	}
+	notifyNewAlerts(alertNotifier, prev, cur)
+	return nil
 }
 
-func handleHealthScan(cfg config.Config) http.HandlerFunc {
+// Seams for tests; the real implementations shell out to lsblk and smartctl.
+var (
+	collectDisks    = disks.Collect
+	smartSummaryFor = disks.SmartSummaryFor
+)
+
+// runHealthScan evaluates SMART data for every disk and btrfs error counters
+// for every pool member, and returns the active alerts: one "smart" alert per
+// at-risk device, one "temperature" alert per device above its threshold and
+// one "btrfs" alert per member with device errors.
+func runHealthScan(ctx context.Context, hc healthConfig, temps *tempEvaluator) []alert {
+	devs, _ := collectDisks(ctx)
+	now := time.Now().UTC().Format(time.RFC3339)
+	out := []alert{}
+	for _, d := range devs {
+		if d.Path == "" {
+			continue
+		}
+		s := smartSummaryFor(ctx, d.Path)
+		if s == nil {
+			continue
+		}
+		if s.TempCelsius != nil {
+			if a := tempAlert(d.Path, *s.TempCelsius, hc, temps, now); a != nil {
+				out = append(out, *a)
+			}
+		}
+		s.EvaluateRisk(hc.riskThresholds())
+		if s.AtRisk {
+			sev := "warn"
+			if s.Healthy != nil && !*s.Healthy {
+				sev = "crit"
+			}
+			out = append(out, alert{
+				ID: generateUUID(), Severity: sev, Kind: "smart", Device: d.Path, Messages: s.RiskReasons, CreatedAt: now,
+			})
+		}
+	}
+	return append(out, deviceStatsAlerts(ctx, now)...)
+}
+
+func handleHealthScan(cfg config.Config, nm *notifications.Manager, temps *tempEvaluator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		hc := loadHealthConfig(cfg)
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
-		devs, _ := disks.Collect(ctx)
-		out := []alert{}
-		for _, d := range devs {
-			if d.Path == "" {
-				continue
-			}
-			s := disks.SmartSummaryFor(ctx, d.Path)
-			sev := ""
-			msgs := []string{}
-			if s != nil {
-				if s.TempCelsius != nil {
-					if *s.TempCelsius >= hc.SMART.TempCrit {
-						sev = "crit"
-						msgs = append(msgs, "temperature critical")
-					} else if *s.TempCelsius >= hc.SMART.TempWarn {
-						if sev == "" {
-							sev = "warn"
-						}
-						msgs = append(msgs, "temperature high")
-					}
-				}
-				if s.Reallocated != nil && *s.Reallocated >= hc.SMART.ReallocatedWarn {
-					if sev == "" {
-						sev = "warn"
-					}
-					msgs = append(msgs, "reallocated sectors")
-				}
-				if s.MediaErrors != nil && *s.MediaErrors >= hc.SMART.MediaErrWarn {
-					if sev == "" {
-						sev = "warn"
-					}
-					msgs = append(msgs, "media errors")
-				}
-				if s.Healthy != nil && !*s.Healthy {
-					sev = "crit"
-					msgs = append(msgs, "SMART failed")
-				}
-			}
-			if sev != "" {
-				out = append(out, alert{
-					ID: generateUUID(), Severity: sev, Kind: "smart", Device: d.Path, Messages: msgs, CreatedAt: time.Now().UTC().Format(time.RFC3339),
-				})
-			}
-		}
+		out := runHealthScan(ctx, hc, temps)
+		alertsMu.Lock()
+		prev := loadAlerts()
 		_ = os.MkdirAll(filepath.Dir(alertsPath()), 0o755)
 		_ = fsatomic.SaveJSON(r.Context(), alertsPath(), out, 0o600)
+		alertsMu.Unlock()
+		notifyNewAlerts(nm, prev, out)
 		writeJSON(w, map[string]any{"ok": true, "alerts": out})
 	}
 }
+
+func healthConfigPath(cfg config.Config) string {
+	return filepath.Join(cfg.EtcDir, "nos", "health.json")
+}
+
+// validate checks the thresholds an admin may set via settings.
+func (hc healthConfig) validate() error {
+	s := hc.SMART
+	switch {
+	case s.TempWarn < 20 || s.TempCrit > 100 || s.TempWarn >= s.TempCrit:
+		return fmt.Errorf("temperature thresholds must satisfy 20 <= tempWarn < tempCrit <= 100")
+	case s.TempHysteresis < 0 || s.TempHysteresis > 20:
+		return fmt.Errorf("tempHysteresis must be between 0 and 20")
+	case s.ReallocatedWarn < 1 || s.MediaErrWarn < 1:
+		return fmt.Errorf("reallocatedWarn and mediaErrWarn must be at least 1")
+	case s.PendingWarn < 0 || s.UncorrectableWarn < 0 || s.CRCErrWarn < 0 || s.WearWarnPct < 0 || s.WearWarnPct > 100:
+		return fmt.Errorf("at-risk thresholds must not be negative and wearWarnPct must be at most 100")
+	}
+	return nil
+}
+
+// GET /api/v1/settings/health
+func handleHealthSettingsGet(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, loadHealthConfig(cfg))
+	}
+}
+
+// PUT /api/v1/settings/health
+// Fields left out of the body keep their current value.
+func handleHealthSettingsPut(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hc := loadHealthConfig(cfg)
+		if err := json.NewDecoder(r.Body).Decode(&hc); err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		if err := hc.validate(); err != nil {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "settings.invalid", err.Error(), 0)
+			return
+		}
+		path := healthConfigPath(cfg)
+		_ = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := fsatomic.SaveJSON(r.Context(), path, hc, 0o644); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to save settings")
+			return
+		}
+		Logger(cfg).Info().Str("event", "settings.health.updated").Int("tempWarn", hc.SMART.TempWarn).Int("tempCrit", hc.SMART.TempCrit).Msg("")
+		writeJSON(w, hc)
+	}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/disks"
+	"nithronos/backend/nosd/internal/notifications"
+	"nithronos/backend/nosd/internal/pools"
+)
+
+func testHealthConfig() healthConfig {
+	return loadHealthConfig(config.Config{EtcDir: "/nonexistent"})
+}
+
+// stubNoPools keeps health scans from probing real btrfs mounts.
+func stubNoPools(t *testing.T) {
+	t.Helper()
+	listPools = func(ctx context.Context) ([]pools.Pool, error) { return nil, nil }
+	t.Cleanup(func() { listPools = pools.ListPools })
+}
+
+// stubNoDisks keeps health scans from probing real disks.
+func stubNoDisks(t *testing.T) {
+	t.Helper()
+	collectDisks = func(ctx context.Context) ([]disks.Disk, error) { return nil, nil }
+	t.Cleanup(func() { collectDisks = disks.Collect })
+}
+
+func TestTempEvaluatorHysteresis(t *testing.T) {
+	hc := testHealthConfig() // warn 60, crit 70, hysteresis 5
+	e := newTempEvaluator()
+	for i, step := range []struct {
+		temp      int
+		level     string
+		threshold int
+	}{
+		{55, "", 60},
+		{61, "warn", 60},
+		{59, "warn", 60}, // within hysteresis: no flap
+		{56, "warn", 60},
+		{55, "", 60},
+		{59, "", 60}, // below threshold again, stays clear
+		{72, "crit", 70},
+		{66, "crit", 70},
+		{65, "warn", 60},
+		{70, "crit", 70},
+		{40, "", 60},
+	} {
+		level, threshold := e.eval("/dev/sda", step.temp, hc)
+		if level != step.level || threshold != step.threshold {
+			t.Fatalf("step %d (%d°C): got %q/%d, want %q/%d", i, step.temp, level, threshold, step.level, step.threshold)
+		}
+	}
+	if level, _ := e.eval("/dev/sdb", 59, hc); level != "" {
+		t.Fatalf("devices must be tracked independently, got %q", level)
+	}
+}
+
+func TestHealthScanAlertsOnHotDevice(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("NOS_STATE_DIR", dir)
+	stubNoPools(t)
+	temp := 0
+	collectDisks = func(ctx context.Context) ([]disks.Disk, error) {
+		return []disks.Disk{{Name: "sda", Path: "/dev/sda"}}, nil
+	}
+	smartSummaryFor = func(ctx context.Context, path string) *disks.SmartSummary {
+		c := temp
+		return &disks.SmartSummary{TempCelsius: &c}
+	}
+	t.Cleanup(func() {
+		collectDisks = disks.Collect
+		smartSummaryFor = disks.SmartSummaryFor
+	})
+
+	got := make(chan notifications.Notification, 8)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var n notifications.Notification
+		_ = json.Unmarshal(b, &n)
+		got <- n
+	}))
+	defer hook.Close()
+	nm, err := notifications.NewManager(filepath.Join(dir, "notifications"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nm.CreateChannel(&notifications.Channel{Name: "hook", Type: notifications.ChannelWebhook, Enabled: true, Config: map[string]interface{}{"url": hook.URL}}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := handleHealthScan(config.Config{EtcDir: dir}, nm, newTempEvaluator())
+	scan := func(c int) []alert {
+		temp = c
+		res := httptest.NewRecorder()
+		h(res, httptest.NewRequest(http.MethodPost, "/api/v1/health/scan", nil))
+		var body struct {
+			Alerts []alert `json:"alerts"`
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Alerts
+	}
+	expectNotification := func(severity string, tempC, threshold float64) {
+		t.Helper()
+		select {
+		case n := <-got:
+			if n.Details["severity"] != severity || n.Details["temperatureC"] != tempC || n.Details["thresholdC"] != threshold || n.Details["device"] != "/dev/sda" {
+				t.Fatalf("unexpected notification: %+v", n)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("no %s notification", severity)
+		}
+	}
+	expectQuiet := func() {
+		t.Helper()
+		select {
+		case n := <-got:
+			t.Fatalf("unexpected notification: %+v", n)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	alerts := scan(64)
+	if len(alerts) != 1 || alerts[0].Kind != "temperature" || alerts[0].Severity != "warn" || *alerts[0].TempCelsius != 64 || alerts[0].Threshold != 60 {
+		t.Fatalf("expected a warn temperature alert: %+v", alerts)
+	}
+	if !strings.Contains(alerts[0].Messages[0], "64°C") {
+		t.Fatalf("message should include the temperature: %v", alerts[0].Messages)
+	}
+	expectNotification("warn", 64, 60)
+
+	// cooling inside the hysteresis band keeps the alert without re-notifying
+	if alerts := scan(58); len(alerts) != 1 || alerts[0].Severity != "warn" {
+		t.Fatalf("alert should hold within hysteresis: %+v", alerts)
+	}
+	expectQuiet()
+
+	if alerts := scan(71); len(alerts) != 1 || alerts[0].Severity != "crit" {
+		t.Fatalf("expected crit: %+v", alerts)
+	}
+	expectNotification("crit", 71, 70)
+
+	if alerts := scan(45); len(alerts) != 0 {
+		t.Fatalf("alert should clear: %+v", alerts)
+	}
+	expectQuiet()
+}
+
+func TestHealthSettingsValidation(t *testing.T) {
+	cfg := config.Config{EtcDir: t.TempDir()}
+	put := func(body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handleHealthSettingsPut(cfg)(res, httptest.NewRequest(http.MethodPut, "/api/v1/settings/health", strings.NewReader(body)))
+		return res
+	}
+	if res := put(`{"smart":{"tempWarn":75,"tempCrit":70}}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("warn above crit accepted: %d", res.Code)
+	}
+	if res := put(`{"smart":{"tempWarn":50,"tempCrit":65,"tempHysteresis":3}}`); res.Code != http.StatusOK {
+		t.Fatalf("valid settings rejected: %d %s", res.Code, res.Body.String())
+	}
+	hc := loadHealthConfig(cfg)
+	if hc.SMART.TempWarn != 50 || hc.SMART.TempCrit != 65 || hc.SMART.TempHysteresis != 3 || hc.SMART.ReallocatedWarn != 1 {
+		t.Fatalf("settings not persisted: %+v", hc.SMART)
+	}
+}
+
+func TestHealthScanFlagsAtRiskDisks(t *testing.T) {
+	stubNoPools(t)
+	collectDisks = func(ctx context.Context) ([]disks.Disk, error) {
+		return []disks.Disk{{Name: "sda", Path: "/dev/sda"}, {Name: "sdb", Path: "/dev/sdb"}}, nil
+	}
+	smartSummaryFor = func(ctx context.Context, path string) *disks.SmartSummary {
+		ok, pending := true, 4
+		if path == "/dev/sdb" {
+			pending = 0
+		}
+		return &disks.SmartSummary{Healthy: &ok, PendingSectors: &pending}
+	}
+	t.Cleanup(func() {
+		collectDisks = disks.Collect
+		smartSummaryFor = disks.SmartSummaryFor
+	})
+
+	alerts := runHealthScan(context.Background(), testHealthConfig(), newTempEvaluator())
+	if len(alerts) != 1 || alerts[0].Kind != "smart" || alerts[0].Severity != "warn" || alerts[0].Device != "/dev/sda" {
+		t.Fatalf("expected one at-risk warning for sda: %+v", alerts)
+	}
+	if !strings.HasPrefix(alerts[0].Messages[0], "pending sectors: 4") {
+		t.Fatalf("unexpected reasons: %v", alerts[0].Messages)
+	}
+}
+
+func TestApplySmartRiskFromAgent(t *testing.T) {
+	device := SMARTDevice{Health: "good"}
+	applySmartRisk(&device, map[string]any{"passed": true, "crc_errors": float64(25), "percentage_used": float64(40)}, testHealthConfig().riskThresholds())
+	if !device.AtRisk || device.Health != "warning" || len(device.RiskReasons) != 1 {
+		t.Fatalf("expected at-risk warning: %+v", device)
+	}
+}
@@ -51,23 +51,6 @@ func NewHealthHandler(agentClient AgentClient) *HealthHandler {
 	}
 }
 
-// Routes registers the health routes
-func (h *HealthHandler) Routes() chi.Router {
-	r := chi.NewRouter()
-
-	// Root health endpoint
-	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "version": "0.9.5-pre-alpha"})
-	})
-
-	r.Get("/smart/summary", h.GetSmartSummary)
-	r.Get("/smart/{device}", h.GetSmartDevice)
-	r.Post("/smart/scan", h.StartSmartScan)
-
-	return r
-}
-
 // GetSmartSummary returns a summary of SMART health across all devices
 // GET /api/v1/health/smart/summary
 func (h *HealthHandler) GetSmartSummary(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	subsystemOK       = "ok"
+	subsystemDown     = "down"
+	subsystemDisabled = "disabled"
+)
+
+type subsystemStatus struct {
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// healthRegistry records the init outcome of each subsystem wired up in
+// NewRouter, plus live probes evaluated per request.
+type healthRegistry struct {
+	mu     sync.RWMutex
+	order  []string
+	items  map[string]subsystemStatus
+	probes map[string]func(ctx context.Context) error
+	// gating lists non-critical subsystems that still hold back /readyz.
+	gating map[string]bool
+}
+
+func newHealthRegistry() *healthRegistry {
+	return &healthRegistry{
+		items:  map[string]subsystemStatus{},
+		probes: map[string]func(ctx context.Context) error{},
+		gating: map[string]bool{},
+	}
+}
+
+func (h *healthRegistry) put(st subsystemStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.items[st.Name]; !ok {
+		h.order = append(h.order, st.Name)
+	}
+	h.items[st.Name] = st
+}
+
+// set records an init result; a nil err marks the subsystem ok.
+func (h *healthRegistry) set(name string, critical bool, err error) {
+	st := subsystemStatus{Name: name, Critical: critical, Status: subsystemOK}
+	if err != nil {
+		st.Status = subsystemDown
+		st.Error = err.Error()
+	}
+	h.put(st)
+}
+
+func (h *healthRegistry) disable(name string) {
+	h.put(subsystemStatus{Name: name, Status: subsystemDisabled})
+}
+
+// probe registers a check that runs on every health request.
+func (h *healthRegistry) probe(name string, critical bool, fn func(ctx context.Context) error) {
+	h.put(subsystemStatus{Name: name, Critical: critical, Status: subsystemOK})
+	h.mu.Lock()
+	h.probes[name] = fn
+	h.mu.Unlock()
+}
+
+// requireForReadiness makes /readyz fail while name is down, without making
+// it critical for /api/v1/health.
+func (h *healthRegistry) requireForReadiness(name string) {
+	h.mu.Lock()
+	h.gating[name] = true
+	h.mu.Unlock()
+}
+
+// notReady returns the subsystems keeping the daemon out of rotation: every
+// critical one that is down plus any down subsystem marked for readiness.
+func (h *healthRegistry) notReady(ctx context.Context) []subsystemStatus {
+	_, list := h.report(ctx)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var out []subsystemStatus
+	for _, st := range list {
+		if st.Status == subsystemDown && (st.Critical || h.gating[st.Name]) {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// report returns the overall status ("ok", "degraded" or "down") and the
+// per-subsystem list in registration order.
+func (h *healthRegistry) report(ctx context.Context) (string, []subsystemStatus) {
+	h.mu.RLock()
+	list := make([]subsystemStatus, 0, len(h.order))
+	probes := make(map[string]func(ctx context.Context) error, len(h.probes))
+	for _, name := range h.order {
+		list = append(list, h.items[name])
+	}
+	for name, fn := range h.probes {
+		probes[name] = fn
+	}
+	h.mu.RUnlock()
+
+	overall := "ok"
+	for i := range list {
+		if fn, ok := probes[list[i].Name]; ok {
+			if err := fn(ctx); err != nil {
+				list[i].Status = subsystemDown
+				list[i].Error = err.Error()
+			}
+		}
+		if list[i].Status != subsystemDown {
+			continue
+		}
+		if list[i].Critical {
+			overall = "down"
+		} else if overall == "ok" {
+			overall = "degraded"
+		}
+	}
+	return overall, list
+}
+
+// probeUnixSocket checks that something is accepting connections on path.
+func probeUnixSocket(path string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		d := net.Dialer{Timeout: 500 * time.Millisecond}
+		c, err := d.DialContext(ctx, "unix", path)
+		if err != nil {
+			return err
+		}
+		return c.Close()
+	}
+}
+
+// checkUsersFile reports a users database that exists but cannot be parsed;
+// the store silently starts empty in that case, which would lock everyone out.
+func checkUsersFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var db struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &db); err != nil {
+		return errors.New("users database unreadable: " + err.Error())
+	}
+	return nil
+}
+
+// GET /api/v1/health
+func handleAggregatedHealth(reg *healthRegistry, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		status, list := reg.report(ctx)
+		if status == "down" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "status": status, "version": version, "subsystems": list})
+			return
+		}
+		writeJSON(w, map[string]any{"ok": true, "status": status, "version": version, "subsystems": list})
+	}
+}
+
+// GET /healthz
+// Liveness: the process is up and serving. It never touches a subsystem, so
+// a slow disk or a dead agent cannot get the daemon restarted.
+func handleLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"status": "ok"})
+	}
+}
+
+// GET /readyz
+// Readiness: critical stores loaded and the agent reachable. Returns 503 with
+// the failing subsystems otherwise.
+func handleReadiness(reg *healthRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		failing := reg.notReady(ctx)
+		if len(failing) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"ready": false, "failing": failing})
+			return
+		}
+		writeJSON(w, map[string]any{"ready": true})
+	}
+}
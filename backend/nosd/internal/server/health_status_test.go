@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func healthTestEnv(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("NOS_USERS_PATH", filepath.Join(dir, "users.json"))
+	t.Setenv("NOS_SESSIONS_PATH", filepath.Join(dir, "sessions.json"))
+	t.Setenv("NOS_RL_PATH", filepath.Join(dir, "ratelimit.json"))
+	t.Setenv("NOS_ETC_DIR", dir)
+	t.Setenv("NOS_APPS_STATE", filepath.Join(dir, "apps.json"))
+	t.Setenv("NOS_DISABLE_APP_EVENTS", "1")
+	t.Setenv("NOS_NET_PENDING_PATH", filepath.Join(dir, "network-pending.json"))
+	oldEvents := monitoringEventsPath
+	monitoringEventsPath = func() string { return filepath.Join(dir, "events.jsonl") }
+	t.Cleanup(func() { monitoringEventsPath = oldEvents })
+	return dir
+}
+
+func getHealth(t *testing.T) (int, map[string]any, map[string]map[string]any) {
+	t.Helper()
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	var body map[string]any
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	subs := map[string]map[string]any{}
+	list, _ := body["subsystems"].([]any)
+	for _, it := range list {
+		m, _ := it.(map[string]any)
+		name, _ := m["name"].(string)
+		subs[name] = m
+	}
+	return res.Code, body, subs
+}
+
+func TestHealthDegradedOnSubsystemInitFailure(t *testing.T) {
+	dir := healthTestEnv(t)
+	// A regular file where the notifications directory should be makes the
+	// manager fail to initialize.
+	if err := os.WriteFile(filepath.Join(dir, "notifications"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body, subs := getHealth(t)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for non-critical failure, got %d", code)
+	}
+	if body["status"] != "degraded" || body["ok"] != true {
+		t.Fatalf("unexpected body: %v", body)
+	}
+	n := subs["notifications"]
+	if n == nil || n["status"] != "down" || n["error"] == "" {
+		t.Fatalf("notifications not reported down: %v", subs)
+	}
+	if subs["users_store"]["status"] != "ok" {
+		t.Fatalf("users store should be ok: %v", subs["users_store"])
+	}
+	if subs["backup"]["status"] != "disabled" {
+		t.Fatalf("backup should be disabled: %v", subs["backup"])
+	}
+}
+
+func TestHealthDownOnCriticalFailure(t *testing.T) {
+	dir := healthTestEnv(t)
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body, subs := getHealth(t)
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", code)
+	}
+	if body["status"] != "down" || body["ok"] != false {
+		t.Fatalf("unexpected body: %v", body)
+	}
+	if subs["users_store"]["status"] != "down" {
+		t.Fatalf("users store should be down: %v", subs["users_store"])
+	}
+}
+
+func probe(t *testing.T, path string) (int, map[string]any) {
+	t.Helper()
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]any
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	return res.Code, body
+}
+
+func TestLivenessIgnoresSubsystems(t *testing.T) {
+	dir := healthTestEnv(t)
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOS_AGENT_SOCKET", filepath.Join(dir, "missing.sock"))
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	code, body := probe(t, "/healthz")
+	if code != http.StatusOK || body["status"] != "ok" {
+		t.Fatalf("liveness: %d %v", code, body)
+	}
+}
+
+func TestReadinessReadyWithAgent(t *testing.T) {
+	healthTestEnv(t)
+	sock, _ := fakeAgentSocket(t, nil)
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	code, body := probe(t, "/readyz")
+	if code != http.StatusOK || body["ready"] != true {
+		t.Fatalf("readiness: %d %v", code, body)
+	}
+}
+
+func TestReadinessNotReady(t *testing.T) {
+	failing := func(body map[string]any) map[string]bool {
+		out := map[string]bool{}
+		list, _ := body["failing"].([]any)
+		for _, it := range list {
+			m, _ := it.(map[string]any)
+			name, _ := m["name"].(string)
+			out[name] = true
+		}
+		return out
+	}
+
+	t.Run("agent unreachable", func(t *testing.T) {
+		dir := healthTestEnv(t)
+		t.Setenv("NOS_AGENT_SOCKET", filepath.Join(dir, "missing.sock"))
+		t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+		code, body := probe(t, "/readyz")
+		if code != http.StatusServiceUnavailable || body["ready"] != false {
+			t.Fatalf("expected 503, got %d %v", code, body)
+		}
+		if f := failing(body); !f["agent"] || f["users_store"] {
+			t.Fatalf("unexpected failing set: %v", body["failing"])
+		}
+	})
+
+	t.Run("users store corrupt", func(t *testing.T) {
+		dir := healthTestEnv(t)
+		if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte("{not json"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		sock, _ := fakeAgentSocket(t, nil)
+		t.Setenv("NOS_AGENT_SOCKET", sock)
+		t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+		code, body := probe(t, "/readyz")
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %d %v", code, body)
+		}
+		if f := failing(body); !f["users_store"] || f["agent"] {
+			t.Fatalf("unexpected failing set: %v", body["failing"])
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// bodyETag is a strong ETag derived from the response body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// serveCacheableJSON writes body with an ETag and, when modified isn't
+// zero, a Last-Modified date. Clients revalidate every time (no-cache) and
+// get 304 Not Modified while their copy is current; http.ServeContent
+// evaluates If-None-Match and If-Modified-Since.
+func serveCacheableJSON(w http.ResponseWriter, r *http.Request, body []byte, modified time.Time) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("ETag", bodyETag(body))
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
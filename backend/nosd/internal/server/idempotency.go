@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// idempotencyTTL is how long a completed response is replayed for a key.
+const idempotencyTTL = 24 * time.Hour
+
+type idemEntry struct {
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// idemStore remembers recent Idempotency-Key results in memory. A restart
+// forgets them, which only matters for retries that span it.
+type idemStore struct {
+	mu      sync.Mutex
+	entries map[string]*idemEntry
+	now     func() time.Time
+}
+
+func newIdemStore() *idemStore {
+	return &idemStore{entries: map[string]*idemEntry{}, now: time.Now}
+}
+
+var idempotencyKeys = newIdemStore()
+
+// begin claims scope for a new request, or returns the existing entry when
+// the key has been seen (finished or still running).
+func (s *idemStore) begin(scope, fingerprint string) (*idemEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, e := range s.entries {
+		if e.done && now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	if e, ok := s.entries[scope]; ok {
+		cp := *e
+		return &cp, false
+	}
+	s.entries[scope] = &idemEntry{fingerprint: fingerprint}
+	return nil, true
+}
+
+// finish stores the response for replay; server errors release the key so
+// the client can retry.
+func (s *idemStore) finish(scope string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[scope]
+	if !ok {
+		return
+	}
+	if status >= 500 {
+		delete(s.entries, scope)
+		return
+	}
+	e.done, e.status, e.contentType, e.body = true, status, contentType, body
+	e.expires = s.now().Add(idempotencyTTL)
+}
+
+// requestUID returns the caller's user ID, or "" when unauthenticated.
+func requestUID(r *http.Request, cfg config.Config) string {
+	if uid, ok := decodeSessionUID(r, cfg); ok {
+		return uid
+	}
+	uid, _ := r.Context().Value(ctxUserID).(string)
+	return uid
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *captureWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.buf.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// idempotent makes a destructive endpoint safe to retry: a request carrying
+// an Idempotency-Key runs once per user, route and key, and later requests
+// with the same key get the recorded response (marked Idempotent-Replayed)
+// instead of running again. Requests without the header are unaffected.
+func idempotent(cfg config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				httpx.WriteTypedError(w, http.StatusBadRequest, "idempotency.invalid_key", "Idempotency-Key must be at most 255 characters", 0)
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "invalid body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			fingerprint := hex.EncodeToString(sum[:])
+			scope := requestUID(r, cfg) + "\x00" + r.Method + " " + r.URL.Path + "\x00" + key
+
+			prev, fresh := idempotencyKeys.begin(scope, fingerprint)
+			if !fresh {
+				switch {
+				case prev.fingerprint != fingerprint:
+					httpx.WriteTypedError(w, http.StatusUnprocessableEntity, "idempotency.key_reused", "Idempotency-Key was already used with a different request body", 0)
+				case !prev.done:
+					httpx.WriteTypedError(w, http.StatusConflict, "idempotency.in_progress", "A request with this Idempotency-Key is still running", 1)
+				default:
+					if prev.contentType != "" {
+						w.Header().Set("Content-Type", prev.contentType)
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(prev.status)
+					_, _ = w.Write(prev.body)
+				}
+				return
+			}
+			cw := &captureWriter{ResponseWriter: w}
+			defer func() {
+				status := cw.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				idempotencyKeys.finish(scope, status, cw.Header().Get("Content-Type"), cw.buf.Bytes())
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
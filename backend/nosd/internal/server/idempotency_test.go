@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/pools"
+)
+
+func freshIdempotencyKeys(t *testing.T) {
+	t.Helper()
+	idempotencyKeys = newIdemStore()
+	t.Cleanup(func() { idempotencyKeys = newIdemStore() })
+}
+
+func TestPoolCreateReplaysIdempotencyKey(t *testing.T) {
+	healthTestEnv(t)
+	freshIdempotencyKeys(t)
+	calls := 0
+	sock, seen := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"tx_id":"first"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"tx_id":"second"}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	ensureDevicesFree = func(ctx context.Context, devices []string) error { return nil }
+	t.Cleanup(func() { ensureDevicesFree = pools.EnsureDevicesFree })
+
+	r := NewRouter(config.FromEnv())
+	post := func(key string, body map[string]any) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/create", bytes.NewReader(mustJSON(body)))
+		req.Header.Set("Confirm", "yes")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	body := map[string]any{"devices": []string{"/dev/sdb", "/dev/sdc"}, "raid": "raid1", "label": "data"}
+
+	first := post("k1", body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", first.Code, first.Body.String())
+	}
+	replay := post("k1", body)
+	if replay.Code != http.StatusOK || replay.Body.String() != first.Body.String() {
+		t.Fatalf("replay should return the original response: %d %s vs %s", replay.Code, replay.Body.String(), first.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replay not marked")
+	}
+	if got := seen(); len(got) != 1 || got[0] != "/v1/btrfs/create" {
+		t.Fatalf("agent should be called once, got %v", got)
+	}
+
+	body["label"] = "other"
+	if res := post("k1", body); res.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key with new body: %d %s", res.Code, res.Body.String())
+	}
+	if res := post("", body); res.Code != http.StatusOK || len(seen()) != 2 {
+		t.Fatalf("request without key should run: %d %v", res.Code, seen())
+	}
+}
+
+func TestIdempotencyScopedPerUser(t *testing.T) {
+	freshIdempotencyKeys(t)
+	runs := 0
+	h := idempotent(config.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		writeJSON(w, map[string]any{"run": runs})
+	}))
+	do := func(uid string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/p1/apply-destroy", bytes.NewReader([]byte(`{}`)))
+		req = req.WithContext(context.WithValue(req.Context(), ctxUserID, uid))
+		req.Header.Set("Idempotency-Key", "same")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+	do("alice")
+	do("alice")
+	if runs != 1 {
+		t.Fatalf("same user replay ran handler %d times", runs)
+	}
+	if res := do("bob"); res.Header().Get("Idempotent-Replayed") != "" || runs != 2 {
+		t.Fatalf("other user must not see alice's result (runs=%d)", runs)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	freshIdempotencyKeys(t)
+	runs := 0
+	h := idempotent(config.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/create", nil)
+		req.Header.Set("Idempotency-Key", "retry")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if runs != 2 {
+		t.Fatalf("failed request should be retryable, ran %d times", runs)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+const ctxIdentity ctxKey = "identity"
+
+// requestIdentity is the caller decoded from nos_session. It lives in the
+// request context only; inbound X-UID/X-SID headers are never trusted.
+type requestIdentity struct {
+	UID string
+	SID string
+}
+
+// withIdentityHolder attaches an empty identity to r so middleware that runs
+// before the session is decoded (the access log) can read it once next
+// returns.
+func withIdentityHolder(r *http.Request) (*http.Request, *requestIdentity) {
+	if id, ok := r.Context().Value(ctxIdentity).(*requestIdentity); ok {
+		return r, id
+	}
+	id := &requestIdentity{}
+	return r.WithContext(context.WithValue(r.Context(), ctxIdentity, id)), id
+}
+
+// setRequestIdentity records the decoded session on r's context.
+func setRequestIdentity(r *http.Request, uid, sid string) *http.Request {
+	r, id := withIdentityHolder(r)
+	id.UID, id.SID = uid, sid
+	return r
+}
+
+// sessionUID returns the signed-in user's ID from the request context, or
+// "" for anonymous requests. Legacy session tokens set by withUser count too.
+func sessionUID(r *http.Request) string {
+	if id, ok := r.Context().Value(ctxIdentity).(*requestIdentity); ok && id.UID != "" {
+		return id.UID
+	}
+	uid, _ := r.Context().Value(ctxUserID).(string)
+	return uid
+}
+
+// sessionSID returns the server-side session ID of the request, or "".
+func sessionSID(r *http.Request) string {
+	if id, ok := r.Context().Value(ctxIdentity).(*requestIdentity); ok {
+		return id.SID
+	}
+	return ""
+}
+
+// sessionContext decodes nos_session (ignoring failures; it enforces
+// nothing) into the request context. Client-sent X-UID/X-SID headers, which
+// older builds used to pass the session along, are dropped so nothing
+// downstream, proxied app backends included, sees a forged identity.
+func sessionContext(cfg config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del("X-UID")
+			r.Header.Del("X-SID")
+			if uid, sid, ok := decodeSessionParts(r, cfg); ok {
+				r = setRequestIdentity(r, uid, sid)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
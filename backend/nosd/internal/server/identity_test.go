@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestSessionContextIgnoresClientIdentityHeaders(t *testing.T) {
+	h, ck := bindingTestSession(t, config.SessionBindingOff)
+
+	var uid, sid, hdr string
+	probe := sessionContext(config.FromEnv())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid, sid, hdr = sessionUID(r), sessionSID(r), r.Header.Get("X-UID")+r.Header.Get("X-SID")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-UID", "u1")
+	req.Header.Set("X-SID", "forged")
+	probe.ServeHTTP(httptest.NewRecorder(), req)
+	if uid != "" || sid != "" || hdr != "" {
+		t.Fatalf("anonymous request took client headers: uid=%q sid=%q headers=%q", uid, sid, hdr)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(ck)
+	req.Header.Set("X-UID", "someone-else")
+	probe.ServeHTTP(httptest.NewRecorder(), req)
+	if uid != "u1" || sid == "" || sid == "forged" || hdr != "" {
+		t.Fatalf("signed-in request: uid=%q sid=%q headers=%q", uid, sid, hdr)
+	}
+
+	// the live router marks the cookie's session as current, not a forged one
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/auth/sessions", nil)
+	req.AddCookie(ck)
+	req.Header.Set("User-Agent", "browser-a")
+	req.Header.Set("X-SID", "forged")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	var list []struct {
+		SID     string `json:"sid"`
+		Current bool   `json:"current"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &list); err != nil || len(list) != 1 || !list[0].Current || list[0].SID != sid {
+		t.Fatalf("sessions: %d %s", res.Code, res.Body.String())
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/pkg/agentclient"
+)
+
+// Interface changes must be confirmed within this window or are reverted.
+const (
+	defaultIfaceConfirmTimeout = 60 * time.Second
+	minIfaceConfirmTimeout     = 10 * time.Second
+	maxIfaceConfirmTimeout     = 10 * time.Minute
+)
+
+// pendingIfaceChange is an applied but unconfirmed interface configuration.
+type pendingIfaceChange struct {
+	Iface      string        `json:"iface"`
+	Previous   NetworkConfig `json:"previous"`
+	Applied    NetworkConfig `json:"applied"`
+	RollbackAt time.Time     `json:"rollback_at"`
+}
+
+// ifaceRollback tracks unconfirmed interface changes and reverts each one to
+// its previous configuration when its timer fires. Pending changes are
+// persisted so a restart inside the window still reverts.
+type ifaceRollback struct {
+	mu      sync.Mutex
+	path    string
+	pending map[string]pendingIfaceChange
+	stops   map[string]func() bool
+	logger  zerolog.Logger
+	revert  func(ctx context.Context, iface string, cfg NetworkConfig) error
+	// seam for tests
+	afterFunc func(d time.Duration, f func()) (stop func() bool)
+}
+
+func newIfaceRollback(path string, logger zerolog.Logger, revert func(ctx context.Context, iface string, cfg NetworkConfig) error) *ifaceRollback {
+	return &ifaceRollback{
+		path:    path,
+		pending: map[string]pendingIfaceChange{},
+		stops:   map[string]func() bool{},
+		logger:  logger,
+		revert:  revert,
+		afterFunc: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+	}
+}
+
+var (
+	startedIfaceGuardMu sync.Mutex
+	startedIfaceGuard   *ifaceRollback
+)
+
+// StartInterfaceRollback re-arms the revert timers of interface changes left
+// unconfirmed by a previous process. main calls it once; building a router
+// doesn't start anything.
+func StartInterfaceRollback(cfg config.Config) {
+	h := NewSystemConfigHandler(*Logger(cfg), agentclient.New(cfg.AgentSocket()))
+	startedIfaceGuardMu.Lock()
+	startedIfaceGuard = h.ifaceGuard
+	startedIfaceGuardMu.Unlock()
+	h.ifaceGuard.resume()
+}
+
+// activeIfaceGuard returns the started guard, or g when none was started.
+func activeIfaceGuard(g *ifaceRollback) *ifaceRollback {
+	startedIfaceGuardMu.Lock()
+	defer startedIfaceGuardMu.Unlock()
+	if startedIfaceGuard != nil {
+		return startedIfaceGuard
+	}
+	return g
+}
+
+// resume re-arms timers for changes persisted by a previous process; overdue
+// ones revert right away.
+func (g *ifaceRollback) resume() {
+	b, err := os.ReadFile(g.path)
+	if err != nil {
+		return
+	}
+	var list []pendingIfaceChange
+	if json.Unmarshal(b, &list) != nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, c := range list {
+		g.pending[c.Iface] = c
+		g.armLocked(c.Iface, time.Until(c.RollbackAt))
+	}
+}
+
+// begin records a change awaiting confirmation. It fails if one is already
+// pending for the interface.
+func (g *ifaceRollback) begin(c pendingIfaceChange) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.pending[c.Iface]; ok {
+		return fmt.Errorf("a change to %s is awaiting confirmation", c.Iface)
+	}
+	g.pending[c.Iface] = c
+	g.armLocked(c.Iface, time.Until(c.RollbackAt))
+	g.saveLocked()
+	return nil
+}
+
+// get returns the pending change for iface, if any.
+func (g *ifaceRollback) get(iface string) (pendingIfaceChange, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.pending[iface]
+	return c, ok
+}
+
+// confirm keeps the applied configuration and stops the revert timer.
+func (g *ifaceRollback) confirm(iface string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.pending[iface]; !ok {
+		return false
+	}
+	g.dropLocked(iface)
+	return true
+}
+
+// abandon forgets a change whose apply failed, without reverting.
+func (g *ifaceRollback) abandon(iface string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.dropLocked(iface)
+}
+
+func (g *ifaceRollback) expire(iface string) {
+	g.mu.Lock()
+	c, ok := g.pending[iface]
+	if ok {
+		g.dropLocked(iface)
+	}
+	g.mu.Unlock()
+	if !ok {
+		return
+	}
+	g.logger.Warn().Str("event", "network.interface.rollback").Str("iface", iface).Msg("interface change not confirmed; reverting")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := g.revert(ctx, iface, c.Previous); err != nil {
+		g.logger.Error().Err(err).Str("iface", iface).Msg("interface rollback failed")
+	}
+}
+
+func (g *ifaceRollback) armLocked(iface string, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	g.stops[iface] = g.afterFunc(d, func() { g.expire(iface) })
+}
+
+func (g *ifaceRollback) dropLocked(iface string) {
+	if stop := g.stops[iface]; stop != nil {
+		stop()
+	}
+	delete(g.stops, iface)
+	delete(g.pending, iface)
+	g.saveLocked()
+}
+
+func (g *ifaceRollback) saveLocked() {
+	if len(g.pending) == 0 {
+		_ = os.Remove(g.path)
+		return
+	}
+	list := make([]pendingIfaceChange, 0, len(g.pending))
+	for _, c := range g.pending {
+		list = append(list, c)
+	}
+	if err := fsatomic.SaveJSON(context.Background(), g.path, list, 0o600); err != nil {
+		g.logger.Error().Err(err).Msg("failed to persist pending interface changes")
+	}
+}
+
+// liveInterfaceConfig describes how iface is configured right now, as the
+// revert target for a new change.
+func (h *SystemConfigHandler) liveInterfaceConfig(iface string) NetworkConfig {
+	cfg := NetworkConfig{DHCP: h.isDHCP(iface)}
+	if cfg.DHCP {
+		return cfg
+	}
+	if ni, err := net.InterfaceByName(iface); err == nil {
+		addrs, _ := ni.Addrs()
+		cfg.IPv4Address, cfg.Addresses = splitIfaceAddrs(addrs)
+	}
+	cfg.IPv4Gateway = defaultGateway(iface)
+	if data, err := os.ReadFile(fmt.Sprintf("/etc/systemd/network/10-%s.network", iface)); err == nil {
+		for _, ln := range strings.Split(string(data), "\n") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(ln), "DNS="); ok {
+				cfg.DNS = append(cfg.DNS, strings.Fields(v)...)
+			}
+		}
+	}
+	return cfg
+}
+
+// splitIfaceAddrs picks the first IPv4 address as the primary one and returns
+// every other address (IPv6 and secondary IPv4) so a revert restores them
+// all. IPv6 link-local addresses are skipped; the kernel assigns those.
+func splitIfaceAddrs(addrs []net.Addr) (primary string, rest []string) {
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok || ipn.IP.IsLinkLocalUnicast() && ipn.IP.To4() == nil {
+			continue
+		}
+		if primary == "" && ipn.IP.To4() != nil {
+			primary = ipn.String()
+			continue
+		}
+		rest = append(rest, ipn.String())
+	}
+	return primary, rest
+}
+
+// defaultGateway reads the IPv4 default route for iface from /proc/net/route.
+func defaultGateway(iface string) string {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 || fields[0] != iface || fields[1] != "00000000" {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+		// little-endian hex
+		return net.IPv4(byte(v), byte(v>>8), byte(v>>16), byte(v>>24)).String()
+	}
+	return ""
+}
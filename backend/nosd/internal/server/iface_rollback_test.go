@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type ifaceTestTimers struct {
+	fire    []func()
+	stopped int
+}
+
+// ifaceTestHandler returns a handler with a recording agent and manual
+// timers; "lo" is used because it exists everywhere.
+func ifaceTestHandler(t *testing.T) (http.Handler, *SystemConfigHandler, *recordingAgent, *ifaceTestTimers, string) {
+	t.Helper()
+	state := filepath.Join(t.TempDir(), "network-pending.json")
+	t.Setenv("NOS_NET_PENDING_PATH", state)
+	agent := &recordingAgent{}
+	h := NewSystemConfigHandler(zerolog.Nop(), agent)
+	h.snapshotIface = func(string) NetworkConfig { return NetworkConfig{DHCP: true} }
+	timers := &ifaceTestTimers{}
+	h.ifaceGuard.afterFunc = func(d time.Duration, f func()) func() bool {
+		timers.fire = append(timers.fire, f)
+		return func() bool { timers.stopped++; return true }
+	}
+	r := chi.NewRouter()
+	r.Post("/interfaces/{iface}", h.ConfigureInterface)
+	r.Post("/interfaces/{iface}/confirm", h.ConfirmInterface)
+	return r, h, agent, timers, state
+}
+
+func postIface(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return res
+}
+
+const staticLo = `{"dhcp":false,"ipv4_address":"10.9.8.7/24","ipv4_gateway":"10.9.8.1","confirm_timeout_seconds":30}`
+
+func TestInterfaceChangeAutoReverts(t *testing.T) {
+	r, _, agent, timers, state := ifaceTestHandler(t)
+
+	res := postIface(r, "/interfaces/lo", staticLo)
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "pending_confirm") {
+		t.Fatalf("configure: %d %s", res.Code, res.Body.String())
+	}
+	if _, err := os.Stat(state); err != nil {
+		t.Fatalf("previous config not persisted: %v", err)
+	}
+	if res := postIface(r, "/interfaces/lo", staticLo); res.Code != http.StatusConflict {
+		t.Fatalf("second change while pending: %d", res.Code)
+	}
+
+	// confirmation window elapses
+	timers.fire[0]()
+	if len(agent.posts) != 2 {
+		t.Fatalf("expected apply + revert, got %d agent calls", len(agent.posts))
+	}
+	revert := agent.posts[1].(AgentRequest).Params
+	if revert["dhcp"] != true || revert["interface"] != "lo" {
+		t.Fatalf("revert should restore previous config: %v", revert)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Fatal("pending state should be cleared after revert")
+	}
+	if res := postIface(r, "/interfaces/lo/confirm", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("confirm after revert: %d", res.Code)
+	}
+}
+
+func TestInterfaceChangeConfirmed(t *testing.T) {
+	r, h, agent, timers, state := ifaceTestHandler(t)
+
+	if res := postIface(r, "/interfaces/lo", staticLo); res.Code != http.StatusOK {
+		t.Fatalf("configure: %d %s", res.Code, res.Body.String())
+	}
+	if res := postIface(r, "/interfaces/lo/confirm", ""); res.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", res.Code, res.Body.String())
+	}
+	if timers.stopped != 1 {
+		t.Fatal("revert timer not stopped")
+	}
+	if _, ok := h.ifaceGuard.get("lo"); ok {
+		t.Fatal("change still pending after confirm")
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Fatal("pending state should be cleared after confirm")
+	}
+	// a late timer firing is a no-op
+	timers.fire[0]()
+	if len(agent.posts) != 1 {
+		t.Fatalf("confirmed change was reverted: %d agent calls", len(agent.posts))
+	}
+}
+
+func TestInterfaceRollbackResumesAfterRestart(t *testing.T) {
+	_, h, _, _, state := ifaceTestHandler(t)
+	if err := h.ifaceGuard.begin(pendingIfaceChange{Iface: "eth9", Previous: NetworkConfig{DHCP: true}, RollbackAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	reverted := make(chan string, 1)
+	g := newIfaceRollback(state, zerolog.Nop(), func(ctx context.Context, iface string, cfg NetworkConfig) error {
+		reverted <- iface
+		return nil
+	})
+	g.resume()
+	select {
+	case iface := <-reverted:
+		if iface != "eth9" {
+			t.Fatalf("reverted %q", iface)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("overdue change not reverted on resume")
+	}
+}
+
+func TestInterfaceConfirmTimeoutBounds(t *testing.T) {
+	r, _, agent, _, _ := ifaceTestHandler(t)
+	res := postIface(r, "/interfaces/lo", `{"dhcp":true,"confirm_timeout_seconds":5}`)
+	if res.Code != http.StatusBadRequest || len(agent.posts) != 0 {
+		t.Fatalf("short timeout: %d, agent calls %d", res.Code, len(agent.posts))
+	}
+}
+
+func TestNewHandlerDoesNotResumePendingChanges(t *testing.T) {
+	_, h, _, _, state := ifaceTestHandler(t)
+	if err := h.ifaceGuard.begin(pendingIfaceChange{Iface: "eth9", Previous: NetworkConfig{DHCP: true}, RollbackAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := &recordingAgent{}
+	t.Setenv("NOS_NET_PENDING_PATH", state)
+	NewSystemConfigHandler(zerolog.Nop(), agent)
+	time.Sleep(50 * time.Millisecond)
+	if len(agent.posts) != 0 {
+		t.Fatalf("building a handler reverted %d changes; only StartInterfaceRollback should", len(agent.posts))
+	}
+}
+
+func TestSplitIfaceAddrsKeepsIPv6(t *testing.T) {
+	var addrs []net.Addr
+	for _, s := range []string{"fe80::1/64", "192.0.2.10/24", "2001:db8::10/64", "198.51.100.7/32"} {
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.IP = ip
+		addrs = append(addrs, n)
+	}
+	primary, rest := splitIfaceAddrs(addrs)
+	if primary != "192.0.2.10/24" {
+		t.Fatalf("primary = %q", primary)
+	}
+	if strings.Join(rest, ",") != "2001:db8::10/64,198.51.100.7/32" {
+		t.Fatalf("rest = %v", rest)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"nithronos/backend/nosd/internal/config"
@@ -9,21 +10,37 @@ import (
 
 func TestClientIPExtractor(t *testing.T) {
 	cases := []struct {
-		name   string
-		trust  bool
-		remote string
-		xff    string
-		want   string
+		name    string
+		trust   bool
+		proxies []string
+		remote  string
+		xff     string
+		want    string
 	}{
-		{"no-proxy", false, "1.1.1.1:123", "", "1.1.1.1"},
-		{"proxy-single", true, "10.0.0.1:443", "203.0.113.5", "203.0.113.5"},
-		{"proxy-multi", true, "10.0.0.1:443", "10.0.0.2, 203.0.113.9 ", "203.0.113.9"},
-		{"malformed", true, "198.51.100.7:555", ",,  203.0.113.10,,,", "203.0.113.10"},
+		{"no-proxy", false, nil, "1.1.1.1:123", "", "1.1.1.1"},
+		{"proxy-single", true, nil, "10.0.0.1:443", "203.0.113.5", "203.0.113.5"},
+		{"proxy-multi", true, nil, "10.0.0.1:443", "10.0.0.2, 203.0.113.9 ", "203.0.113.9"},
+		{"malformed", true, nil, "10.0.0.1:555", ",,  203.0.113.10,,,", "203.0.113.10"},
+		{"ipv6-remote", false, nil, "[2001:db8::1]:443", "", "2001:db8::1"},
+
+		// XFF from a client that isn't a proxy is its own claim
+		{"untrusted-spoof", false, []string{"10.0.0.1"}, "203.0.113.66:1234", "127.0.0.1", "203.0.113.66"},
+		{"shortcut-public-spoof", true, nil, "198.51.100.7:555", "127.0.0.1", "198.51.100.7"},
+		{"no-trust-ignores-xff", false, nil, "10.0.0.1:443", "203.0.113.5", "10.0.0.1"},
+
+		// a proxy chain is walked past trusted hops only
+		{"chain", false, []string{"10.0.0.0/24", "192.168.1.1"}, "10.0.0.1:443", "203.0.113.5, 192.168.1.1, 10.0.0.9", "203.0.113.5"},
+		{"chain-spoofed-left", false, []string{"10.0.0.0/24"}, "10.0.0.1:443", "127.0.0.1, 203.0.113.5", "203.0.113.5"},
+		{"chain-untrusted-middle", false, []string{"10.0.0.1"}, "10.0.0.1:443", "203.0.113.5, 198.51.100.2", "198.51.100.2"},
+		{"chain-all-trusted", false, []string{"10.0.0.0/8"}, "10.0.0.1:443", "10.1.2.3, 10.0.0.2", "10.1.2.3"},
+		{"chain-garbage-hop", false, []string{"10.0.0.1"}, "10.0.0.1:443", "203.0.113.5, not-an-ip", "10.0.0.1"},
+		{"ipv6-proxy", false, []string{"fd00::/8"}, "[fd00::1]:443", "2001:db8::7", "2001:db8::7"},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			cfg := config.FromEnv()
 			cfg.TrustProxy = tc.trust
+			cfg.TrustedProxies = tc.proxies
 			req, _ := http.NewRequest("GET", "/", nil)
 			req.RemoteAddr = tc.remote
 			if tc.xff != "" {
@@ -36,3 +53,96 @@ func TestClientIPExtractor(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardedProtoNeedsTrustedProxy(t *testing.T) {
+	cfg := config.FromEnv()
+	cfg.TrustedProxies = []string{"127.0.0.1"}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+
+	req.RemoteAddr = "203.0.113.66:1234"
+	if isSecureRequest(req, cfg) {
+		t.Fatal("X-Forwarded-Proto believed from an untrusted client")
+	}
+	req.RemoteAddr = "127.0.0.1:1234"
+	if !isSecureRequest(req, cfg) {
+		t.Fatal("X-Forwarded-Proto ignored from a trusted proxy")
+	}
+}
+
+func TestRuntimeTrustedProxiesOverrideConfig(t *testing.T) {
+	t.Cleanup(func() {
+		rtMu.Lock()
+		rtProxies = nil
+		rtMu.Unlock()
+	})
+	cfg := config.FromEnv()
+	cfg.TrustedProxies = []string{"10.0.0.1"}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:443"
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+
+	// a reload that removes the proxy stops trusting it
+	SetRuntimeTrustedProxies(nil)
+	if got := clientIP(req, cfg); got != "10.0.0.1" {
+		t.Fatalf("after reload without proxies: %s", got)
+	}
+	SetRuntimeTrustedProxies(config.Config{TrustedProxies: []string{"10.0.0.0/8"}}.TrustedProxyNets())
+	if got := clientIP(req, cfg); got != "203.0.113.5" {
+		t.Fatalf("after reload with proxies: %s", got)
+	}
+}
+
+func TestLocalhostIgnoresForwardingHeaders(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_TEST_SKIP_AUTH", "")
+	h := NewRouter(config.FromEnv())
+	for _, hdr := range []string{"X-Real-IP", "X-Forwarded-For", "True-Client-IP"} {
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/setup/otp/regenerate", nil)
+		req.RemoteAddr = "203.0.113.66:1234"
+		req.Header.Set(hdr, "127.0.0.1")
+		if fromLocalhost(req) {
+			t.Fatalf("%s made the request local", hdr)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Code != http.StatusForbidden && res.Code != http.StatusNotFound {
+			t.Fatalf("%s: localhost-only route answered %d", hdr, res.Code)
+		}
+	}
+}
+
+func TestLocalhostRejectsProxiedRequests(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_TEST_SKIP_AUTH", "")
+	cfg := config.FromEnv()
+	cfg.RecoveryMode = true
+	cfg.PprofEnabled = true
+	t.Cleanup(func() { SetRuntimeMetrics(nil, false) })
+	h := NewRouter(cfg)
+
+	for _, remote := range []string{"127.0.0.1:4000", "[::1]:4000"} {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		if !fromLocalhost(req) {
+			t.Fatalf("%s: direct local request rejected", remote)
+		}
+	}
+
+	// the bundled Caddy relays LAN clients from loopback
+	for _, hdr := range forwardingHeaders {
+		for _, path := range []string{"/debug/pprof/", "/api/v1/setup/otp/regenerate", "/api/v1/setup/recover", "/api/v1/recovery/disable-2fa"} {
+			req, _ := http.NewRequest(http.MethodPost, path, nil)
+			req.RemoteAddr = "127.0.0.1:4000"
+			req.Header.Set(hdr, "192.168.1.20")
+			if fromLocalhost(req) {
+				t.Fatalf("%s made a proxied request local", hdr)
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			if res.Code != http.StatusForbidden {
+				t.Fatalf("%s %s through the proxy: %d", hdr, path, res.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+var (
+	errJobNotFound       = errors.New("job not found")
+	errJobFinished       = errors.New("job already finished")
+	errJobNotCancellable = errors.New("job cannot be cancelled")
+)
+
+// RunJob records a running, cancellable job and runs fn in the background
+// with a context that POST /api/v1/jobs/{id}/cancel cancels. fn's result
+// sets the terminal state (completed or failed) unless the job was
+// cancelled first. Without a store fn still runs, but can't be cancelled.
+func RunJob(jobType, message string, details map[string]any, fn func(ctx context.Context, jobID string) error) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := Job{
+		ID:          generateUUID(),
+		Type:        jobType,
+		Status:      "running",
+		StartTime:   time.Now(),
+		Message:     message,
+		Details:     details,
+		Cancellable: true,
+	}
+	store := jobsStore
+	if store != nil {
+		store.mu.Lock()
+		if store.cancels == nil {
+			store.cancels = map[string]context.CancelFunc{}
+		}
+		store.cancels[job.ID] = cancel
+		store.mu.Unlock()
+		store.AddJob(job)
+	}
+	go func() {
+		defer cancel()
+		err := fn(ctx, job.ID)
+		store.finishRun(job.ID, err)
+	}()
+	return &job
+}
+
+// finishRun records the outcome of a RunJob job unless it was cancelled.
+func (s *JobsStore) finishRun(id string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cancels, id)
+	for i := range s.jobs {
+		j := &s.jobs[i]
+		if j.ID != id || jobFinished(j.Status) {
+			continue
+		}
+		now := time.Now()
+		j.EndTime = &now
+		j.Duration = int64(now.Sub(j.StartTime).Seconds())
+		if err != nil {
+			j.Status, j.Error = "failed", err.Error()
+		} else {
+			j.Status, j.Progress = "completed", 100
+		}
+		s.save()
+		s.publish(*j)
+		return
+	}
+}
+
+// Cancel signals a RunJob job to stop and records it as cancelled.
+func (s *JobsStore) Cancel(id string) (Job, error) {
+	if s == nil {
+		return Job{}, errJobNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.jobs {
+		j := &s.jobs[i]
+		if j.ID != id {
+			continue
+		}
+		if jobFinished(j.Status) {
+			return *j, errJobFinished
+		}
+		cancel, ok := s.cancels[id]
+		if !ok {
+			return *j, errJobNotCancellable
+		}
+		cancel()
+		delete(s.cancels, id)
+		now := time.Now()
+		j.Status = "cancelled"
+		j.EndTime = &now
+		j.Duration = int64(now.Sub(j.StartTime).Seconds())
+		j.Message = "Cancelled"
+		s.save()
+		s.publish(*j)
+		return *j, nil
+	}
+	return Job{}, errJobNotFound
+}
+
+// POST /api/v1/jobs/{id}/cancel
+func handleJobCancel(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		job, err := jobsStore.Cancel(id)
+		switch {
+		case errors.Is(err, errJobNotFound):
+			httpx.WriteTypedError(w, http.StatusNotFound, "job.not_found", "Job not found", 0)
+			return
+		case errors.Is(err, errJobFinished):
+			httpx.WriteTypedError(w, http.StatusConflict, "job.finished", "Job has already finished", 0)
+			return
+		case errors.Is(err, errJobNotCancellable):
+			httpx.WriteTypedError(w, http.StatusConflict, "job.not_cancellable", "Jobs of type "+job.Type+" can't be cancelled here", 0)
+			return
+		}
+		Logger(cfg).Info().Str("event", "job.cancel").Str("job_id", id).Str("type", job.Type).Str("userId", sessionUID(r)).Msg("")
+		writeJSON(w, job)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+// waitJobRunner waits for RunJob's goroutine to finish with id.
+func waitJobRunner(t *testing.T, id string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		jobsStore.mu.Lock()
+		_, running := jobsStore.cancels[id]
+		jobsStore.mu.Unlock()
+		if !running {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job runner did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func postJobCancel(t *testing.T, h http.Handler, id string) (int, map[string]any) {
+	t.Helper()
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/jobs/"+id+"/cancel", nil))
+	var out map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	return res.Code, out
+}
+
+func errCode(out map[string]any) any {
+	e, _ := out["error"].(map[string]any)
+	return e["code"]
+}
+
+func TestJobCancelInFlight(t *testing.T) {
+	testJobsStore(t)
+	r := chi.NewRouter()
+	r.Post("/api/v1/jobs/{id}/cancel", handleJobCancel(config.Config{}))
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	job := RunJob("snapshot", "mock", nil, func(ctx context.Context, _ string) error {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return ctx.Err()
+	})
+	<-started
+	if j, _ := jobsStore.GetJob(job.ID); j.Status != "running" || !j.Cancellable {
+		t.Fatalf("job should be running and cancellable: %+v", j)
+	}
+
+	code, out := postJobCancel(t, r, job.ID)
+	if code != http.StatusOK || out["status"] != "cancelled" {
+		t.Fatalf("cancel: %d %v", code, out)
+	}
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Fatalf("job saw %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job context was not cancelled")
+	}
+	// the job's own error return must not overwrite the cancelled state
+	waitJobRunner(t, job.ID)
+	if j, _ := jobsStore.GetJob(job.ID); j.Status != "cancelled" || j.Error != "" || j.EndTime == nil {
+		t.Fatalf("cancelled state not kept: %+v", j)
+	}
+
+	if code, out := postJobCancel(t, r, job.ID); code != http.StatusConflict || errCode(out) != "job.finished" {
+		t.Fatalf("second cancel: %d %v", code, out)
+	}
+}
+
+func TestJobCancelRejections(t *testing.T) {
+	testJobsStore(t)
+	r := chi.NewRouter()
+	r.Post("/api/v1/jobs/{id}/cancel", handleJobCancel(config.Config{}))
+
+	balance := CreateJob("balance", "bespoke cancel only", nil)
+	StartJob(balance.ID)
+	if code, out := postJobCancel(t, r, balance.ID); code != http.StatusConflict || errCode(out) != "job.not_cancellable" {
+		t.Fatalf("non-cancellable: %d %v", code, out)
+	}
+	done := RunJob("snapshot", "quick", nil, func(ctx context.Context, _ string) error { return nil })
+	deadline := time.Now().Add(2 * time.Second)
+	for j, _ := jobsStore.GetJob(done.ID); j.Status != "completed"; j, _ = jobsStore.GetJob(done.ID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not complete: %+v", j)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if code, out := postJobCancel(t, r, done.ID); code != http.StatusConflict || errCode(out) != "job.finished" {
+		t.Fatalf("finished: %d %v", code, out)
+	}
+	if code, out := postJobCancel(t, r, "missing"); code != http.StatusNotFound || errCode(out) != "job.not_found" {
+		t.Fatalf("unknown: %d %v", code, out)
+	}
+}
+
+func TestAsyncSnapshotJobHonorsCancel(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_STATE_DIR", dir)
+	testJobsStore(t)
+	reached := make(chan struct{}, 1)
+	aborted := make(chan struct{})
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		// like the agent, consume the body so a hang-up cancels r.Context()
+		_, _ = io.Copy(io.Discard, r.Body)
+		reached <- struct{}{}
+		<-r.Context().Done()
+		close(aborted)
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	body := bytes.NewReader(mustJSON(map[string]any{"subvol": "/mnt/p1/data", "name": "s1", "async": true}))
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/pools/p1/snapshots", body))
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("async snapshot: %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		JobID string `json:"job_id"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	select {
+	case <-reached:
+	case <-time.After(2 * time.Second):
+		t.Fatal("agent never received the snapshot call")
+	}
+
+	if code, resp := postJobCancel(t, r, out.JobID); code != http.StatusOK || resp["status"] != "cancelled" {
+		t.Fatalf("cancel: %d %v", code, resp)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("agent call was not aborted")
+	}
+	waitJobRunner(t, out.JobID)
+}
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -26,19 +28,30 @@ type Job struct {
 	Message     string    `json:"message,omitempty"`
 	Error       string    `json:"error,omitempty"`
 	Details     map[string]any `json:"details,omitempty"`
+	// Cancellable jobs stop via POST /api/v1/jobs/{id}/cancel.
+	Cancellable bool `json:"cancellable,omitempty"`
 }
 
-// JobsStore manages job history
+// JobsStore manages job history. It is safe for concurrent use; every
+// change is published to the job's subscribers (see Subscribe).
 type JobsStore struct {
+	mu   sync.Mutex
 	path string
 	jobs []Job
+	subs map[string]map[chan Job]struct{}
+	// cancels holds the context cancel func of each running RunJob job
+	cancels map[string]context.CancelFunc
 }
 
 var jobsStore *JobsStore
 
 // InitJobsStore initializes the jobs store
 func InitJobsStore(cfg config.Config) {
-	jobsPath := filepath.Join("/var/lib/nos", "jobs.json")
+	base := os.Getenv("NOS_STATE_DIR")
+	if base == "" {
+		base = "/var/lib/nos"
+	}
+	jobsPath := filepath.Join(base, "jobs.json")
 	if runtime.GOOS == "windows" {
 		jobsPath = filepath.Join(`C:\ProgramData\NithronOS`, "jobs.json")
 	}
@@ -59,6 +72,8 @@ func (s *JobsStore) AddJob(job Job) {
 	if s == nil {
 		return
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
 	s.jobs = append(s.jobs, job)
 	
@@ -67,15 +82,18 @@ func (s *JobsStore) AddJob(job Job) {
 		s.jobs = s.jobs[len(s.jobs)-100:]
 	}
 	
-	// Save to disk (best effort)
-	if data, err := json.MarshalIndent(s.jobs, "", "  "); err == nil {
-		_ = os.WriteFile(s.path, data, 0644)
-	}
+	s.save()
+	s.publish(job)
 }
 
 // GetRecentJobs returns the most recent jobs
 func (s *JobsStore) GetRecentJobs(limit int) []Job {
-	if s == nil || len(s.jobs) == 0 {
+	if s == nil {
+		return []Job{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.jobs) == 0 {
 		return []Job{}
 	}
 	
@@ -97,6 +115,8 @@ func (s *JobsStore) GetJob(id string) (*Job, bool) {
 	if s == nil {
 		return nil, false
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
 	for _, job := range s.jobs {
 		if job.ID == id {
@@ -111,20 +131,26 @@ func (s *JobsStore) UpdateJob(id string, updates func(*Job)) {
 	if s == nil {
 		return
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
 	for i := range s.jobs {
 		if s.jobs[i].ID == id {
 			updates(&s.jobs[i])
-			
-			// Save to disk (best effort)
-			if data, err := json.MarshalIndent(s.jobs, "", "  "); err == nil {
-				_ = os.WriteFile(s.path, data, 0644)
-			}
+			s.save()
+			s.publish(s.jobs[i])
 			break
 		}
 	}
 }
 
+// save writes the jobs to disk (best effort); callers hold s.mu.
+func (s *JobsStore) save() {
+	if data, err := json.MarshalIndent(s.jobs, "", "  "); err == nil {
+		_ = os.WriteFile(s.path, data, 0644)
+	}
+}
+
 // handleJobsRecent returns recent jobs
 func handleJobsRecent(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
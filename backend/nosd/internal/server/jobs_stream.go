@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// jobSubBuffer is how many unread updates a subscriber may fall behind by
+// before the oldest are dropped; each update carries the full job state.
+const jobSubBuffer = 32
+
+// jobFinished reports whether status is terminal.
+func jobFinished(status string) bool {
+	switch status {
+	case "completed", "failed", "cancelled":
+		return true
+	}
+	return false
+}
+
+// Subscribe returns the job's current state and a channel that receives its
+// state after every later change, in order. The snapshot and subscription
+// are taken atomically, so no change is missed or repeated. Call cancel once
+// done; ok is false when the job doesn't exist.
+func (s *JobsStore) Subscribe(id string) (current Job, updates <-chan Job, cancel func(), ok bool) {
+	if s == nil {
+		return Job{}, nil, nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.ID == id {
+			current, ok = j, true
+			break
+		}
+	}
+	if !ok {
+		return Job{}, nil, nil, false
+	}
+	ch := make(chan Job, jobSubBuffer)
+	if s.subs == nil {
+		s.subs = map[string]map[chan Job]struct{}{}
+	}
+	if s.subs[id] == nil {
+		s.subs[id] = map[chan Job]struct{}{}
+	}
+	s.subs[id][ch] = struct{}{}
+	cancel = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs[id], ch)
+		if len(s.subs[id]) == 0 {
+			delete(s.subs, id)
+		}
+	}
+	return current, ch, cancel, true
+}
+
+// publish sends job to its subscribers without blocking; a subscriber that
+// has fallen behind loses its oldest queued update. Callers hold s.mu.
+func (s *JobsStore) publish(job Job) {
+	for ch := range s.subs[job.ID] {
+		select {
+		case ch <- job:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- job:
+		default:
+		}
+	}
+}
+
+// subscriberCount returns how many streams are watching id.
+func (s *JobsStore) subscriberCount(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs[id])
+}
+
+// GET /api/v1/jobs/{id}/stream
+//
+// Server-Sent Events: one "job" event with the current state, then one per
+// change. The stream ends after a terminal state (completed, failed,
+// cancelled) or when the client disconnects.
+func handleJobStream(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	current, updates, cancel, ok := jobsStore.Subscribe(id)
+	if !ok {
+		httpx.WriteTypedError(w, http.StatusNotFound, "job.not_found", "Job not found", 0)
+		return
+	}
+	defer cancel()
+	flusher, canFlush := w.(http.Flusher)
+	if !canFlush {
+		httpx.WriteError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ctx, done := streams.track(r.Context())
+	defer done()
+
+	send := func(j Job) {
+		data, _ := json.Marshal(j)
+		_, _ = w.Write([]byte("event: job\ndata: "))
+		_, _ = w.Write(data)
+		_, _ = w.Write([]byte("\n\n"))
+		flusher.Flush()
+	}
+	send(current)
+	if jobFinished(current.Status) {
+		return
+	}
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case j := <-updates:
+			send(j)
+			if jobFinished(j.Status) {
+				return
+			}
+		case <-ticker.C:
+			_, _ = w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func testJobsStore(t *testing.T) {
+	t.Helper()
+	prev := jobsStore
+	jobsStore = &JobsStore{path: filepath.Join(t.TempDir(), "jobs.json"), jobs: []Job{}}
+	t.Cleanup(func() { jobsStore = prev })
+}
+
+func jobStreamServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Get("/api/v1/jobs/{id}/stream", handleJobStream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readJobEvents returns a channel of job events decoded from an SSE body.
+func readJobEvents(t *testing.T, res *http.Response) <-chan Job {
+	t.Helper()
+	out := make(chan Job, 16)
+	go func() {
+		defer close(out)
+		sc := bufio.NewScanner(res.Body)
+		for sc.Scan() {
+			line := sc.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var j Job
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &j); err != nil {
+				t.Errorf("bad event %q: %v", line, err)
+				return
+			}
+			out <- j
+		}
+	}()
+	return out
+}
+
+func nextJobEvent(t *testing.T, ch <-chan Job) Job {
+	t.Helper()
+	select {
+	case j, ok := <-ch:
+		if !ok {
+			t.Fatal("stream ended early")
+		}
+		return j
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for job event")
+	}
+	return Job{}
+}
+
+func TestJobStreamDeliversOrderedTransitions(t *testing.T) {
+	testJobsStore(t)
+	srv := jobStreamServer(t)
+	job := CreateJob("snapshot", "queued", nil)
+
+	res, err := http.Get(srv.URL + "/api/v1/jobs/" + job.ID + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	events := readJobEvents(t, res)
+
+	if j := nextJobEvent(t, events); j.Status != "pending" {
+		t.Fatalf("first event should be the current state, got %+v", j)
+	}
+	StartJob(job.ID)
+	UpdateJobProgress(job.ID, 50, "halfway")
+	CompleteJob(job.ID, "done")
+
+	var got []string
+	for _, want := range []string{"running", "running", "completed"} {
+		j := nextJobEvent(t, events)
+		got = append(got, j.Status)
+		if j.Status != want {
+			t.Fatalf("transitions out of order: %v", got)
+		}
+	}
+	if _, open := <-events; open {
+		t.Fatal("stream should end after a terminal state")
+	}
+	if n := jobsStore.subscriberCount(job.ID); n != 0 {
+		t.Fatalf("subscriber not cleaned up: %d", n)
+	}
+}
+
+func TestJobStreamUnsubscribesOnDisconnect(t *testing.T) {
+	testJobsStore(t)
+	srv := jobStreamServer(t)
+	job := CreateJob("scrub", "queued", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/jobs/"+job.ID+"/stream", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextJobEvent(t, readJobEvents(t, res))
+	if n := jobsStore.subscriberCount(job.ID); n != 1 {
+		t.Fatalf("expected one subscriber, got %d", n)
+	}
+	cancel()
+	_ = res.Body.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for jobsStore.subscriberCount(job.ID) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber not removed after disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	res, err = http.Get(srv.URL + "/api/v1/jobs/missing/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown job: %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestLoginUpgradesWeakPasswordHash(t *testing.T) {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	t.Setenv("NOS_USERS_PATH", usersPath)
+	t.Setenv("NOS_SESSIONS_PATH", filepath.Join(dir, "sessions.json"))
+	t.Setenv("NOS_RL_PATH", filepath.Join(dir, "ratelimit.json"))
+	t.Setenv("NOS_ETC_DIR", dir)
+	t.Setenv("NOS_APPS_STATE", filepath.Join(dir, "apps.json"))
+	t.Setenv("NOS_DISABLE_APP_EVENTS", "1")
+	t.Cleanup(func() { pwhash.SetParams(pwhash.DefaultParams()) })
+
+	old, err := pwhash.HashPasswordWithParams("Sup3r-secret", pwhash.Params{Time: 1, Memory: 8 * 1024, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	st, _ := userstore.New(usersPath)
+	if err := st.UpsertUser(userstore.User{ID: "u1", Username: "dana", PasswordHash: old, Roles: []string{"admin"}, CreatedAt: now, PasswordChangedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(mustJSON(map[string]any{"username": "dana", "password": "Sup3r-secret"}))))
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body.String())
+	}
+
+	reloaded, _ := userstore.New(usersPath)
+	u, err := reloaded.FindByUsername("dana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.PasswordHash == old {
+		t.Fatal("hash was not upgraded")
+	}
+	if pwhash.NeedsRehash(u.PasswordHash) {
+		t.Fatalf("upgraded hash still uses weak params: %s", u.PasswordHash)
+	}
+	if !pwhash.VerifyPassword(u.PasswordHash, "Sup3r-secret") {
+		t.Fatal("upgraded hash does not verify")
+	}
+}
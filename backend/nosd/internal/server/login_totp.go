@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/auth"
+)
+
+// totpActive reports whether login needs a second factor: the user has
+// confirmed a TOTP secret via /auth/totp/verify. Users who confirmed before
+// TOTPEnabled was recorded are recognised by their recovery codes.
+func totpActive(u userstore.User) bool {
+	if u.TOTPEnc == "" || u.TOTPEnc == "pending" {
+		return false
+	}
+	return u.TOTPEnabled || len(u.RecoveryHashes) > 0
+}
+
+// checkLoginCode verifies the login code field as a TOTP code and, failing
+// that, as one of the user's recovery codes. A matched recovery code is
+// removed from u.RecoveryHashes; the caller must persist u before issuing
+// a session so the code can't be used twice. method is "totp", "recovery"
+// or "" when the code matched neither.
+func checkLoginCode(cfg config.Config, u *userstore.User, code string) (method string) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" {
+		return ""
+	}
+	if len(code) == 6 {
+		if secret, err := decryptWithSecretKey(cfg.SecretPath, u.TOTPEnc); err == nil && auth.VerifyTOTP(string(secret), code) {
+			return "totp"
+		}
+	}
+	if i := matchRecoveryCode(u.RecoveryHashes, code); i >= 0 {
+		u.RecoveryHashes = append(u.RecoveryHashes[:i:i], u.RecoveryHashes[i+1:]...)
+		return "recovery"
+	}
+	return ""
+}
+
+// totpReauthenticated reports whether code is a current TOTP code for u or
+// password is u's password. Recovery codes don't count: they are what a
+// re-enrollment replaces.
+func totpReauthenticated(cfg config.Config, u userstore.User, code, password string) bool {
+	if code = strings.ReplaceAll(strings.TrimSpace(code), " ", ""); len(code) == 6 {
+		if secret, err := decryptWithSecretKey(cfg.SecretPath, u.TOTPEnc); err == nil && auth.VerifyTOTP(string(secret), code) {
+			return true
+		}
+	}
+	return password != "" && pwhash.VerifyPassword(u.PasswordHash, password)
+}
+
+// matchRecoveryCode returns the index of the hash matching code, or -1.
+// Hashes are SHA-256 hex (from /auth/totp/verify) or argon2 PHC strings
+// (from /users/{id}/recovery-codes); every SHA-256 entry is compared in
+// constant time.
+func matchRecoveryCode(hashes []string, code string) int {
+	sum := []byte(hashRecovery(code))
+	found := -1
+	for i, h := range hashes {
+		var ok bool
+		if strings.HasPrefix(h, "$argon2") {
+			ok = found < 0 && pwhash.VerifyPassword(h, code)
+		} else {
+			ok = subtle.ConstantTimeCompare([]byte(h), sum) == 1
+		}
+		if ok && found < 0 {
+			found = i
+		}
+	}
+	return found
+}
+
+// Bounds for the ?size= of the enrollment QR code, in pixels.
+const (
+	totpQRDefaultSize = 256
+	totpQRMinSize     = 128
+	totpQRMaxSize     = 1024
+)
+
+// totpQRSize reads the optional ?size= query parameter of the enroll route.
+func totpQRSize(r *http.Request) (int, error) {
+	v := strings.TrimSpace(r.URL.Query().Get("size"))
+	if v == "" {
+		return totpQRDefaultSize, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < totpQRMinSize || n > totpQRMaxSize {
+		return 0, fmt.Errorf("size must be between %d and %d", totpQRMinSize, totpQRMaxSize)
+	}
+	return n, nil
+}
+
+// totpQRPNG renders the otpauth URI as a size×size PNG, base64-encoded
+// (without a data: prefix) for the qr_png_base64 field.
+func totpQRPNG(uri string, size int) (string, error) {
+	png, err := qrcode.Encode(uri, qrcode.Medium, size)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(png), nil
+}
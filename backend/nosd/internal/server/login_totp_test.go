@@ -0,0 +1,238 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/auth"
+)
+
+// totpLoginEnv seeds an admin with confirmed TOTP and returns the router,
+// the TOTP secret, the plaintext recovery codes and the users file.
+func totpLoginEnv(t *testing.T) (http.Handler, string, []string, string) {
+	t.Helper()
+	dir := healthTestEnv(t)
+	secretPath := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(secretPath, bytes.Repeat([]byte{7}, 32), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOS_SECRET_PATH", secretPath)
+	t.Setenv("NOS_RATE_LOGIN_PER_15M", "50")
+	cfg := config.FromEnv()
+
+	secret, _, err := auth.GenerateTOTPSecret("NithronOS", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryptWithSecretKey(secretPath, []byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	phc, err := pwhash.HashPassword("StrongPassw0rd!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, hashes := generateRecoveryCodes()
+	us, _ := userstore.New(cfg.UsersPath)
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := us.UpsertUser(userstore.User{ID: "u1", Username: "alice", PasswordHash: phc, Roles: []string{"admin"}, TOTPEnc: enc, TOTPEnabled: true, RecoveryHashes: hashes, CreatedAt: now, PasswordChangedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(cfg), secret, plain, cfg.UsersPath
+}
+
+func postLogin(r http.Handler, code string) (*httptest.ResponseRecorder, map[string]any) {
+	body := map[string]any{"username": "alice", "password": "StrongPassw0rd!"}
+	if code != "" {
+		body["code"] = code
+	}
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(mustJSON(body))))
+	var out map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	return res, out
+}
+
+func TestLoginRequiresSecondFactor(t *testing.T) {
+	r, secret, _, _ := totpLoginEnv(t)
+
+	res, out := postLogin(r, "")
+	if res.Code != http.StatusUnauthorized || errCode(out) != "auth.totp_required" {
+		t.Fatalf("no code: %d %s", res.Code, res.Body.String())
+	}
+	res, out = postLogin(r, "000000")
+	if res.Code != http.StatusUnauthorized || errCode(out) != "auth.totp_invalid" {
+		t.Fatalf("wrong code: %d %s", res.Code, res.Body.String())
+	}
+	code, _ := totp.GenerateCode(secret, time.Now())
+	res, out = postLogin(r, code)
+	if res.Code != http.StatusOK {
+		t.Fatalf("totp login: %d %s", res.Code, res.Body.String())
+	}
+	if _, ok := out["recoveryCodesRemaining"]; ok {
+		t.Fatalf("TOTP login should not report recovery codes: %v", out)
+	}
+}
+
+func TestLoginWithRecoveryCodeIsSingleUse(t *testing.T) {
+	r, _, plain, usersPath := totpLoginEnv(t)
+
+	res, out := postLogin(r, plain[3])
+	if res.Code != http.StatusOK {
+		t.Fatalf("recovery login: %d %s", res.Code, res.Body.String())
+	}
+	if out["recoveryCodesRemaining"] != float64(len(plain)-1) {
+		t.Fatalf("remaining: %v", out["recoveryCodesRemaining"])
+	}
+	us, _ := userstore.New(usersPath)
+	u, _ := us.FindByUsername("alice")
+	if len(u.RecoveryHashes) != len(plain)-1 || matchRecoveryCode(u.RecoveryHashes, plain[3]) >= 0 {
+		t.Fatalf("used code not removed: %v", u.RecoveryHashes)
+	}
+
+	res, out = postLogin(r, plain[3])
+	if res.Code != http.StatusUnauthorized || errCode(out) != "auth.totp_invalid" {
+		t.Fatalf("reused code: %d %s", res.Code, res.Body.String())
+	}
+	if res, out := postLogin(r, plain[0]); res.Code != http.StatusOK || out["recoveryCodesRemaining"] != float64(len(plain)-2) {
+		t.Fatalf("second code: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestMatchRecoveryCodeArgon2(t *testing.T) {
+	h, err := pwhash.HashPassword("ABCD2345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := []string{hashRecovery("other"), h}
+	if matchRecoveryCode(hashes, "ABCD2345") != 1 || matchRecoveryCode(hashes, "other") != 0 || matchRecoveryCode(hashes, "nope") != -1 {
+		t.Fatalf("mixed hash formats not matched")
+	}
+}
+
+func TestTOTPEnrollReturnsQRPNG(t *testing.T) {
+	r, secret, _, _ := totpLoginEnv(t)
+	code, _ := totp.GenerateCode(secret, time.Now())
+	res, _ := postLogin(r, code)
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body.String())
+	}
+	enroll := func(query string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/totp/enroll"+query, strings.NewReader(`{"password":"StrongPassw0rd!"}`))
+		for _, c := range res.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var out map[string]any
+		_ = json.Unmarshal(rec.Body.Bytes(), &out)
+		return rec, out
+	}
+
+	for _, tc := range []struct {
+		query string
+		size  int
+	}{{"", totpQRDefaultSize}, {"?size=300", 300}} {
+		rec, out := enroll(tc.query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("enroll%s: %d %s", tc.query, rec.Code, rec.Body.String())
+		}
+		if uri, _ := out["otpauth_url"].(string); uri == "" {
+			t.Fatalf("otpauth_url missing: %v", out)
+		}
+		raw, err := base64.StdEncoding.DecodeString(out["qr_png_base64"].(string))
+		if err != nil {
+			t.Fatalf("qr base64: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("qr png: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != tc.size || b.Dy() != tc.size {
+			t.Fatalf("enroll%s: qr is %dx%d, want %d", tc.query, b.Dx(), b.Dy(), tc.size)
+		}
+	}
+
+	for _, q := range []string{"?size=abc", "?size=64", "?size=4096"} {
+		if rec, out := enroll(q); rec.Code != http.StatusBadRequest || errCode(out) != "input.invalid" {
+			t.Fatalf("enroll%s: %d %s", q, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTOTPReenrollKeepsTwoFactorUntilVerified(t *testing.T) {
+	r, secret, plain, usersPath := totpLoginEnv(t)
+	code, _ := totp.GenerateCode(secret, time.Now())
+	res, _ := postLogin(r, code)
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body.String())
+	}
+	call := func(method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for _, c := range res.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var out map[string]any
+		_ = json.Unmarshal(rec.Body.Bytes(), &out)
+		return rec, out
+	}
+	load := func() userstore.User {
+		us, _ := userstore.New(usersPath)
+		u, err := us.FindByID("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	before := load()
+
+	if rec, _ := call(http.MethodGet, "/api/v1/auth/totp/enroll", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET enroll: %d", rec.Code)
+	}
+	for _, body := range []string{"", `{"password":"wrong"}`, `{"code":"` + plain[0] + `"}`} {
+		if rec, out := call(http.MethodPost, "/api/v1/auth/totp/enroll", body); rec.Code != http.StatusUnauthorized || errCode(out) != "auth.reauth_required" {
+			t.Fatalf("enroll with %q: %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if rec, _ := call(http.MethodPost, "/api/v1/auth/totp/enroll", `{"code":"`+code+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("enroll with current code: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// enrolled but not verified: the old secret and codes still guard login
+	u := load()
+	if !totpActive(u) || u.TOTPEnc != before.TOTPEnc || len(u.RecoveryHashes) != len(before.RecoveryHashes) || u.TOTPPendingEnc == "" {
+		t.Fatalf("enroll without verify changed 2FA: enabled=%v codes=%d pending=%v", u.TOTPEnabled, len(u.RecoveryHashes), u.TOTPPendingEnc != "")
+	}
+	if res, out := postLogin(r, ""); res.Code != http.StatusUnauthorized || errCode(out) != "auth.totp_required" {
+		t.Fatalf("login without a code after enroll: %d %s", res.Code, res.Body.String())
+	}
+
+	pending, err := decryptWithSecretKey(filepath.Join(filepath.Dir(usersPath), "secret.key"), u.TOTPPendingEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, _ := totp.GenerateCode(string(pending), time.Now())
+	if rec, _ := call(http.MethodPost, "/api/v1/auth/totp/verify", `{"code":"`+next+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", rec.Code, rec.Body.String())
+	}
+	u = load()
+	if u.TOTPEnc == before.TOTPEnc || u.TOTPPendingEnc != "" || !u.TOTPEnabled || matchRecoveryCode(u.RecoveryHashes, plain[1]) >= 0 {
+		t.Fatalf("verify did not switch to the new secret and codes")
+	}
+}
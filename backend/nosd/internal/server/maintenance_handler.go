@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/progress"
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+	"nithronos/backend/nosd/pkg/maintenance"
+)
+
+// seam for tests
+var maintenanceNow = time.Now
+
+// heldJob is a scheduled job the maintenance window kept from starting.
+type heldJob struct {
+	Job          string    `json:"job"`
+	Target       string    `json:"target"`
+	State        string    `json:"state"`
+	Reason       string    `json:"reason"`
+	DueAt        time.Time `json:"due_at"`
+	NextEligible time.Time `json:"next_eligible,omitempty"`
+}
+
+const maxHeldJobs = 50
+
+var (
+	heldJobsMu sync.Mutex
+	heldJobs   []heldJob
+	// one pending re-run per job and target
+	heldTimers = map[string]*time.Timer{}
+	// seams for tests
+	maintenanceAfterFunc = time.AfterFunc
+	startHeldJob         = func(cfg config.Config, job, target string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		body := map[string]any{"mount": target}
+		if err := agentclient.New(cfg.AgentSocket()).PostJSON(ctx, "/v1/btrfs/scrub/start", body, nil); err != nil {
+			return err
+		}
+		poolProgress.Set(filepath.Clean(target), progress.Scrub, 0)
+		return nil
+	}
+)
+
+// maintenanceJobs are the scheduled jobs that ask before they start; the
+// nos-btrfs-scrub@ unit does so from its ExecCondition.
+var maintenanceJobs = map[string]bool{"btrfs-scrub": true}
+
+// POST /api/v1/maintenance/dispatch?job=btrfs-scrub&target=/mnt/pool
+//
+// Called from the host's systemd timers (localhost only) when a job comes
+// due. 200 lets it run. Outside the window it answers 409
+// maintenance.outside_window; the job is recorded as deferred, and started
+// through the agent at the next opening, or as skipped.
+func handleMaintenanceDispatch(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job := strings.TrimSpace(r.URL.Query().Get("job"))
+		target := strings.TrimSpace(r.URL.Query().Get("target"))
+		if !maintenanceJobs[job] || !filepath.IsAbs(target) {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "input.invalid", "job and an absolute target are required", 0)
+			return
+		}
+		now := maintenanceNow()
+		d := RuntimeMaintenanceWindow().Decide(now)
+		if d.Dispatch {
+			writeJSON(w, d)
+			return
+		}
+		h := heldJob{Job: job, Target: filepath.Clean(target), State: d.State, Reason: d.Reason, DueAt: now}
+		if d.State == maintenance.StateDeferred && !d.NextEligible.IsZero() {
+			h.NextEligible = d.NextEligible
+			deferHeldJob(cfg, h, d.NextEligible.Sub(now))
+		} else {
+			h.State = maintenance.StateSkipped
+		}
+		recordHeldJob(h)
+		Logger(cfg).Info().Str("event", "maintenance."+h.State).Str("job", job).Str("target", h.Target).Str("reason", d.Reason).Msg("")
+		httpx.WriteErrorWithDetails(w, http.StatusConflict, "maintenance.outside_window", d.Reason, map[string]any{"state": h.State, "next_eligible": h.NextEligible})
+	}
+}
+
+// deferHeldJob starts h once the window opens; a job already waiting for
+// the same target isn't queued twice.
+func deferHeldJob(cfg config.Config, h heldJob, after time.Duration) {
+	key := h.Job + ":" + h.Target
+	heldJobsMu.Lock()
+	defer heldJobsMu.Unlock()
+	if _, ok := heldTimers[key]; ok {
+		return
+	}
+	heldTimers[key] = maintenanceAfterFunc(after, func() {
+		heldJobsMu.Lock()
+		delete(heldTimers, key)
+		heldJobsMu.Unlock()
+		if err := startHeldJob(cfg, h.Job, h.Target); err != nil {
+			Logger(cfg).Error().Str("event", "maintenance.deferred_start_failed").Str("job", h.Job).Str("target", h.Target).Err(err).Msg("")
+			return
+		}
+		Logger(cfg).Info().Str("event", "maintenance.deferred_started").Str("job", h.Job).Str("target", h.Target).Msg("")
+	})
+}
+
+func recordHeldJob(h heldJob) {
+	heldJobsMu.Lock()
+	defer heldJobsMu.Unlock()
+	heldJobs = append(heldJobs, h)
+	if len(heldJobs) > maxHeldJobs {
+		heldJobs = heldJobs[len(heldJobs)-maxHeldJobs:]
+	}
+}
+
+// GET /api/v1/maintenance/status
+func handleMaintenanceStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		win := RuntimeMaintenanceWindow()
+		now := maintenanceNow()
+		heldJobsMu.Lock()
+		held := append([]heldJob{}, heldJobs...)
+		heldJobsMu.Unlock()
+		out := map[string]any{
+			"enabled":   win.Enabled,
+			"window":    win,
+			"in_window": win.Contains(now),
+			"now":       now,
+			"held_jobs": held,
+		}
+		if next := win.NextEligible(now); !next.IsZero() {
+			out["next_eligible"] = next
+		} else {
+			out["next_eligible"] = nil
+		}
+		writeJSON(w, out)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestMaintenanceStatus(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_MAINTENANCE_WINDOW", "sat 01:00-05:00")
+	friday := time.Date(2024, 6, 14, 23, 0, 0, 0, time.Local)
+	maintenanceNow = func() time.Time { return friday }
+	t.Cleanup(func() { maintenanceNow = time.Now })
+
+	cfg := config.FromEnv()
+	SetRuntimeMaintenanceWindow(cfg.MaintenanceWindow)
+	t.Cleanup(func() { SetRuntimeMaintenanceWindow(config.Defaults().MaintenanceWindow) })
+
+	r := NewRouter(cfg)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/maintenance/status", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Enabled      bool      `json:"enabled"`
+		InWindow     bool      `json:"in_window"`
+		NextEligible time.Time `json:"next_eligible"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Enabled || out.InWindow {
+		t.Fatalf("unexpected status: %s", res.Body.String())
+	}
+	if !out.NextEligible.Equal(friday.Add(2 * time.Hour)) {
+		t.Fatalf("next eligible: %v", out.NextEligible)
+	}
+}
+
+func TestMaintenanceDispatchDefersScrub(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_MAINTENANCE_WINDOW", "sat 01:00-05:00")
+	now := time.Date(2024, 6, 14, 23, 0, 0, 0, time.Local) // Friday
+	maintenanceNow = func() time.Time { return now }
+	var fire []func()
+	var waits []time.Duration
+	maintenanceAfterFunc = func(d time.Duration, f func()) *time.Timer {
+		waits, fire = append(waits, d), append(fire, f)
+		return time.NewTimer(time.Hour)
+	}
+	var started []string
+	oldStart := startHeldJob
+	startHeldJob = func(_ config.Config, job, target string) error {
+		started = append(started, job+" "+target)
+		return nil
+	}
+	t.Cleanup(func() {
+		maintenanceNow, maintenanceAfterFunc, startHeldJob = time.Now, time.AfterFunc, oldStart
+		heldJobsMu.Lock()
+		heldJobs, heldTimers = nil, map[string]*time.Timer{}
+		heldJobsMu.Unlock()
+	})
+
+	cfg := config.FromEnv()
+	SetRuntimeMaintenanceWindow(cfg.MaintenanceWindow)
+	t.Cleanup(func() { SetRuntimeMaintenanceWindow(config.Defaults().MaintenanceWindow) })
+	r := NewRouter(cfg)
+	dispatch := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/maintenance/dispatch?job=btrfs-scrub&target=/mnt/tank", nil)
+		req.RemoteAddr = remote
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := dispatch("192.0.2.7:4000"); res.Code != http.StatusForbidden {
+		t.Fatalf("remote dispatch: %d", res.Code)
+	}
+	// out of window: held, deferred to Saturday 01:00
+	res := dispatch("127.0.0.1:4000")
+	var body map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &body)
+	if res.Code != http.StatusConflict || errCode(body) != "maintenance.outside_window" {
+		t.Fatalf("outside window: %d %s", res.Code, res.Body.String())
+	}
+	if len(waits) != 1 || waits[0] != 2*time.Hour {
+		t.Fatalf("deferred start armed for %v", waits)
+	}
+	// a second dispatch for the same target doesn't queue another start
+	dispatch("127.0.0.1:4000")
+	if len(fire) != 1 {
+		t.Fatalf("queued %d starts", len(fire))
+	}
+	fire[0]()
+	if len(started) != 1 || started[0] != "btrfs-scrub /mnt/tank" {
+		t.Fatalf("deferred scrub not started: %v", started)
+	}
+
+	status := httptest.NewRecorder()
+	r.ServeHTTP(status, httptest.NewRequest(http.MethodGet, "/api/v1/maintenance/status", nil))
+	var out struct {
+		Held []heldJob `json:"held_jobs"`
+	}
+	_ = json.Unmarshal(status.Body.Bytes(), &out)
+	if len(out.Held) != 2 || out.Held[0].State != "deferred" || out.Held[0].Reason == "" {
+		t.Fatalf("held jobs: %s", status.Body.String())
+	}
+
+	// in window: runs at once
+	now = time.Date(2024, 6, 15, 2, 0, 0, 0, time.Local)
+	if res := dispatch("127.0.0.1:4000"); res.Code != http.StatusOK {
+		t.Fatalf("inside window: %d %s", res.Code, res.Body.String())
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// metricsStreamInterval is how often the metrics streams (SSE and
+// WebSocket) receive a new SystemHealthResponse.
+var metricsStreamInterval = time.Second
+
+// captureMetrics takes one sample; a seam for tests.
+var captureMetrics = captureSystemHealth
+
+// metricsSampler captures one SystemHealthResponse per interval while any
+// stream is subscribed and fans it out, so every open dashboard costs the
+// same single sample. It also keeps the network and disk rate counters in
+// captureSystemHealth to one caller.
+type metricsSampler struct {
+	mu   sync.Mutex
+	subs map[chan SystemHealthResponse]struct{}
+	last *SystemHealthResponse
+	stop chan struct{}
+}
+
+var healthSampler = &metricsSampler{}
+
+// Subscribe returns a channel receiving every sample from now on, starting
+// with the latest one if the sampler is already running. The channel holds
+// one sample; a subscriber that falls behind skips to the newest. Call
+// cancel once done; the sampler stops with its last subscriber.
+func (s *metricsSampler) Subscribe() (samples <-chan SystemHealthResponse, cancel func()) {
+	ch := make(chan SystemHealthResponse, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs == nil {
+		s.subs = map[chan SystemHealthResponse]struct{}{}
+	}
+	s.subs[ch] = struct{}{}
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+		go s.run(s.stop, metricsStreamInterval)
+	} else if s.last != nil {
+		ch <- *s.last
+	}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.subs, ch)
+			if len(s.subs) == 0 && s.stop != nil {
+				close(s.stop)
+				s.stop, s.last = nil, nil
+			}
+		})
+	}
+}
+
+func (s *metricsSampler) run(stop chan struct{}, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		h := captureMetrics()
+		s.mu.Lock()
+		// a sampler stopped while capturing must not publish
+		if s.stop != stop {
+			s.mu.Unlock()
+			return
+		}
+		s.last = &h
+		for ch := range s.subs {
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- h
+		}
+		s.mu.Unlock()
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// subscriberCount returns how many streams are receiving samples.
+func (s *metricsSampler) subscriberCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait bounds each frame write; wsPongWait is how long a client
+	// may stay silent, and pings go out often enough to keep it talking.
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+var metricsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  512,
+	WriteBufferSize: 4096,
+	CheckOrigin:     wsOriginAllowed,
+}
+
+// wsOriginAllowed accepts same-origin pages, the CORS allowlist and
+// clients that send no Origin. Browsers attach the session cookie to
+// cross-site WebSocket handshakes, so other origins are refused.
+func wsOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+	ok, _ := matchOrigin(origin, getAllowedOrigins())
+	return ok
+}
+
+// GET /api/v1/metrics/ws
+//
+// WebSocket alternative to the SSE metrics stream: one text frame with a
+// SystemHealthResponse per metricsStreamInterval, from the shared
+// healthSampler. The server pings and drops clients that stop answering;
+// a close from either side ends the stream.
+func handleMetricsWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := metricsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has replied with the error
+		return
+	}
+	defer conn.Close()
+
+	ctx, done := streams.track(r.Context())
+	defer done()
+
+	// clients don't send data; reading handles pongs and the close
+	// handshake, and fails once the client is gone
+	conn.SetReadLimit(512)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	samples, cancel := healthSampler.Subscribe()
+	defer cancel()
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case h := <-samples:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(h); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		case <-ctx.Done():
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
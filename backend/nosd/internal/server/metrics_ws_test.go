@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+// fakeMetrics replaces the sampler with one counting its captures; each
+// sample's Timestamp is its sequence number.
+func fakeMetrics(t *testing.T) *atomic.Int64 {
+	t.Helper()
+	var n atomic.Int64
+	oldSampler, oldCapture, oldEvery := healthSampler, captureMetrics, metricsStreamInterval
+	healthSampler = &metricsSampler{}
+	captureMetrics = func() SystemHealthResponse { return SystemHealthResponse{Timestamp: n.Add(1), CPU: 12.5} }
+	metricsStreamInterval = 10 * time.Millisecond
+	t.Cleanup(func() { healthSampler, captureMetrics, metricsStreamInterval = oldSampler, oldCapture, oldEvery })
+	return &n
+}
+
+func metricsWSServer(t *testing.T) string {
+	t.Helper()
+	// behind the request logger, as in the router, whose writer must hijack
+	nop := zerolog.Nop()
+	srv := httptest.NewServer(zerologMiddleware(&nop, config.Defaults())(http.HandlerFunc(handleMetricsWS)))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMetricsWebSocketStreamsSamples(t *testing.T) {
+	fakeMetrics(t)
+	url := metricsWSServer(t)
+
+	conn, res, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v (%v)", err, res)
+	}
+	defer conn.Close()
+
+	var prev int64
+	for i := 0; i < 2; i++ {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var h SystemHealthResponse
+		if err := conn.ReadJSON(&h); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if h.CPU != 12.5 || h.Timestamp <= prev {
+			t.Fatalf("frame %d = %+v after sample %d", i, h, prev)
+		}
+		prev = h.Timestamp
+	}
+	if n := healthSampler.subscriberCount(); n != 1 {
+		t.Fatalf("%d subscribers, want 1", n)
+	}
+
+	// a clean close ends the stream and stops the sampler
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the stream to unsubscribe", func() bool { return healthSampler.subscriberCount() == 0 })
+	healthSampler.mu.Lock()
+	running := healthSampler.stop != nil
+	healthSampler.mu.Unlock()
+	if running {
+		t.Fatal("sampler still running without subscribers")
+	}
+}
+
+func TestMetricsWebSocketRejectsOtherOrigins(t *testing.T) {
+	fakeMetrics(t)
+	url := metricsWSServer(t)
+
+	_, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || res == nil || res.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross-origin handshake: %v %v", err, res)
+	}
+	host := strings.TrimPrefix(url, "ws://")
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"http://" + host}})
+	if err != nil {
+		t.Fatalf("same-origin handshake: %v", err)
+	}
+	var h SystemHealthResponse
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&h); err != nil {
+		t.Fatal(err)
+	}
+	// dropping the connection without a close frame also ends the stream
+	conn.Close()
+	waitFor(t, "the stream to unsubscribe", func() bool { return healthSampler.subscriberCount() == 0 })
+}
+
+func TestMetricsSamplerSharedBetweenStreams(t *testing.T) {
+	captures := fakeMetrics(t)
+	a, cancelA := healthSampler.Subscribe()
+	defer cancelA()
+	b, cancelB := healthSampler.Subscribe()
+	defer cancelB()
+
+	next := func(ch <-chan SystemHealthResponse) int64 {
+		t.Helper()
+		select {
+		case h := <-ch:
+			return h.Timestamp
+		case <-time.After(2 * time.Second):
+			t.Fatal("no sample")
+		}
+		return 0
+	}
+	// both subscribers see the same samples, each captured once
+	for i := 0; i < 3; i++ {
+		sa, sb := next(a), next(b)
+		for sa != sb {
+			if sa < sb {
+				sa = next(a)
+			} else {
+				sb = next(b)
+			}
+		}
+	}
+	cancelA()
+	cancelB()
+	waitFor(t, "the sampler to stop", func() bool { return healthSampler.subscriberCount() == 0 })
+	n := captures.Load()
+	time.Sleep(5 * metricsStreamInterval)
+	if captures.Load() > n+1 {
+		t.Fatalf("sampler kept capturing after the last unsubscribe: %d -> %d", n, captures.Load())
+	}
+}
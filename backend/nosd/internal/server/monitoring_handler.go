@@ -150,18 +150,50 @@ func handleMonitoringLogs(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// monitoringEventsPath is the event log read by the monitoring endpoints;
+// a seam for tests.
+var monitoringEventsPath = func() string {
+	if runtime.GOOS == "windows" {
+		return `C:\ProgramData\NithronOS\events.jsonl`
+	}
+	return "/var/lib/nos/events.jsonl"
+}
+
+// appendEvent adds ev to the event log, filling in its ID and timestamp.
+func appendEvent(ev Event) error {
+	if ev.ID == "" {
+		ev.ID = generateUUID()
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	path := monitoringEventsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	// one write per line, so concurrent appends don't interleave
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // handleMonitoringEvents returns recent system events
 func handleMonitoringEvents(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		events := []Event{}
 
 		// Read events from event log file
-		eventFile := filepath.Join("/var/lib/nos/events.jsonl")
-		if runtime.GOOS == "windows" {
-			eventFile = filepath.Join(`C:\ProgramData\NithronOS\events.jsonl`)
-		}
-
-		if file, err := os.Open(eventFile); err == nil {
+		if file, err := os.Open(monitoringEventsPath()); err == nil {
 			defer file.Close()
 			scanner := bufio.NewScanner(file)
 
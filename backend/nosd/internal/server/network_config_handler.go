@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/wireguard"
 	"nithronos/backend/nosd/pkg/httpx"
 
 	"github.com/go-chi/chi/v5"
@@ -55,6 +57,8 @@ type Route struct {
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
 	Flags       string `json:"flags"`
+	Family      string `json:"family"`  // inet, inet6
+	Default     bool   `json:"default"` // 0.0.0.0/0 or ::/0
 }
 
 // DNSConfig represents DNS configuration
@@ -295,15 +299,28 @@ func (h *NetworkConfigHandler) CreateWireGuardPeer(w http.ResponseWriter, r *htt
 		return
 	}
 
+	if !validWGPeerName(peer.Name) {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "wg.invalid_peer", "Peer name must be 1-64 letters, digits, '.', '_' or '-'", 0)
+		return
+	}
+
+	config := h.loadWireGuardConfig()
+	if findWGPeer(config.Peers, peer.Name) >= 0 {
+		httpx.WriteTypedError(w, http.StatusConflict, "wg.peer_exists", "Peer already exists", 0)
+		return
+	}
+
 	// Generate keys if not provided
 	if peer.PublicKey == "" {
 		privateKey, publicKey := h.generateWGKeys()
 		peer.PublicKey = publicKey
 		// Store private key securely
-		h.storeWGPrivateKey(peer.Name, privateKey)
+		if err := h.storeWGPrivateKey(peer.Name, privateKey); err != nil {
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "wg.save_failed", "Failed to store peer key", 0)
+			return
+		}
 	}
 
-	config := h.loadWireGuardConfig()
 	config.Peers = append(config.Peers, peer)
 
 	if err := h.saveWireGuardConfig(config); err != nil {
@@ -446,6 +463,12 @@ func (h *NetworkConfigHandler) getInterfaceStats(ni *NetworkInterfaceInfo) {
 	}
 }
 
+// seams for tests
+var (
+	procNetRoutePath     = "/proc/net/route"
+	procNetIPv6RoutePath = "/proc/net/ipv6_route"
+)
+
 func (h *NetworkConfigHandler) getRoutes() []Route {
 	routes := []Route{}
 
@@ -453,13 +476,21 @@ func (h *NetworkConfigHandler) getRoutes() []Route {
 		return routes
 	}
 
-	// Parse /proc/net/route
-	data, err := os.ReadFile("/proc/net/route")
-	if err != nil {
-		return routes
+	if data, err := os.ReadFile(procNetRoutePath); err == nil {
+		routes = append(routes, parseIPv4Routes(string(data))...)
+	}
+	if data, err := os.ReadFile(procNetIPv6RoutePath); err == nil {
+		routes = append(routes, parseIPv6Routes(string(data))...)
 	}
 
-	lines := strings.Split(string(data), "\n")
+	return routes
+}
+
+// parseIPv4Routes parses /proc/net/route. Destinations are returned in CIDR
+// form; 0.0.0.0/0 is flagged as a default route.
+func parseIPv4Routes(data string) []Route {
+	routes := []Route{}
+	lines := strings.Split(data, "\n")
 	for i, line := range lines {
 		if i == 0 || line == "" {
 			continue // Skip header and empty lines
@@ -472,6 +503,7 @@ func (h *NetworkConfigHandler) getRoutes() []Route {
 
 		route := Route{
 			Interface: fields[0],
+			Family:    "inet",
 		}
 
 		// Parse destination and gateway (hex to IP)
@@ -481,6 +513,11 @@ func (h *NetworkConfigHandler) getRoutes() []Route {
 		if gw, err := hexToIP(fields[2]); err == nil {
 			route.Gateway = gw
 		}
+		if mask, err := hexToIP(fields[7]); err == nil && route.Destination != "" {
+			ones, _ := net.IPMask(net.ParseIP(mask).To4()).Size()
+			route.Destination = fmt.Sprintf("%s/%d", route.Destination, ones)
+			route.Default = ones == 0
+		}
 
 		// Parse metric
 		_, _ = fmt.Sscanf(fields[6], "%d", &route.Metric)
@@ -491,6 +528,43 @@ func (h *NetworkConfigHandler) getRoutes() []Route {
 	return routes
 }
 
+// rtfReject marks unreachable/blackhole entries in /proc/net/ipv6_route.
+const rtfReject = 0x0200
+
+// parseIPv6Routes parses /proc/net/ipv6_route (dest, dest_plen, src,
+// src_plen, next_hop, metric, refcnt, use, flags, iface; all hex). Loopback
+// and reject entries are skipped; ::/0 is flagged as a default route.
+func parseIPv6Routes(data string) []Route {
+	routes := []Route{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[9] == "lo" {
+			continue
+		}
+		dest, err1 := hexToIPv6(fields[0])
+		gw, err2 := hexToIPv6(fields[4])
+		var plen, metric, flags uint64
+		_, err3 := fmt.Sscanf(fields[1], "%x", &plen)
+		_, err4 := fmt.Sscanf(fields[5], "%x", &metric)
+		_, err5 := fmt.Sscanf(fields[8], "%x", &flags)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || plen > 128 {
+			continue
+		}
+		if flags&rtfReject != 0 {
+			continue
+		}
+		routes = append(routes, Route{
+			Destination: fmt.Sprintf("%s/%d", dest, plen),
+			Gateway:     gw,
+			Interface:   fields[9],
+			Metric:      int(metric),
+			Family:      "inet6",
+			Default:     plen == 0,
+		})
+	}
+	return routes
+}
+
 func hexToIP(hex string) (string, error) {
 	if len(hex) != 8 {
 		return "", fmt.Errorf("invalid hex IP")
@@ -506,6 +580,15 @@ func hexToIP(hex string) (string, error) {
 	return ip.String(), nil
 }
 
+// hexToIPv6 decodes a 32-digit big-endian hex address from /proc/net/ipv6_route.
+func hexToIPv6(h string) (string, error) {
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != net.IPv6len {
+		return "", fmt.Errorf("invalid hex IPv6")
+	}
+	return net.IP(b).String(), nil
+}
+
 func (h *NetworkConfigHandler) getDNSConfig() DNSConfig {
 	config := DNSConfig{
 		Servers: []string{},
@@ -688,14 +771,21 @@ func (h *NetworkConfigHandler) applyWireGuardConfig() error {
 }
 
 func (h *NetworkConfigHandler) generateWGKeys() (privateKey, publicKey string) {
-	// Generate WireGuard keys
-	// This is a simplified implementation - real would use wg genkey/pubkey
-	return "private-key-placeholder", "public-key-placeholder"
+	return wireguard.GenerateKeyPair()
 }
 
-func (h *NetworkConfigHandler) storeWGPrivateKey(name, key string) {
-	// Store private key securely
-	// This is a simplified implementation
+// wgPeerKeyPath is where a generated peer private key is kept so the
+// peer's config can be exported later.
+func (h *NetworkConfigHandler) wgPeerKeyPath(name string) string {
+	return filepath.Join(h.config.EtcDir, "nos", "wireguard", "peers", name+".key")
+}
+
+func (h *NetworkConfigHandler) storeWGPrivateKey(name, key string) error {
+	path := h.wgPeerKeyPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(key+"\n"), 0o600)
 }
 
 // HTTPSConfig represents HTTPS configuration
@@ -756,6 +846,8 @@ func (h *NetworkConfigHandler) Routes() chi.Router {
 	// WireGuard VPN
 	r.Get("/wireguard/config", h.GetWireGuardConfig)
 	r.Post("/wireguard/peers", h.CreateWireGuardPeer)
+	r.Delete("/wireguard/peers/{name}", h.DeleteWireGuardPeer)
+	r.Get("/wireguard/peers/{name}/config", h.GetWireGuardPeerConfig)
 
 	// HTTPS/TLS configuration
 	r.Get("/https/config", h.GetHTTPSConfig)
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DNSUpdate is the body of POST /api/v1/network/dns.
+type DNSUpdate struct {
+	Servers []string `json:"servers"`
+	Search  []string `json:"search"`
+	// DHCP leaves name servers to the DHCP client; Servers may then be empty.
+	DHCP bool `json:"dhcp"`
+}
+
+// normalize trims entries and validates them. Servers must be IP literals
+// and search entries valid domain names; an empty server list is only
+// accepted when DHCP is set.
+func (u *DNSUpdate) normalize() error {
+	servers := make([]string, 0, len(u.Servers))
+	for _, s := range u.Servers {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("invalid DNS server %q: must be an IP address", s)
+		}
+		servers = append(servers, ip.String())
+	}
+	search := make([]string, 0, len(u.Search))
+	for _, d := range u.Search {
+		d = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d == "" {
+			continue
+		}
+		if !isValidHostname(d) {
+			return fmt.Errorf("invalid search domain %q", d)
+		}
+		search = append(search, d)
+	}
+	if len(servers) == 0 && !u.DHCP {
+		return fmt.Errorf("at least one DNS server is required unless dhcp is true")
+	}
+	u.Servers, u.Search = servers, search
+	return nil
+}
+
+// SetDNS writes DNS servers and search domains through the agent, which
+// targets systemd-resolved when it manages resolv.conf.
+func (h *SystemConfigHandler) SetDNS(w http.ResponseWriter, r *http.Request) {
+	var update DNSUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := update.normalize(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	backend := ""
+	if os.Getenv("NOS_TEST_BYPASS_AGENT") != "1" {
+		req := AgentRequest{
+			Action: "network.dns.set",
+			Params: map[string]interface{}{
+				"servers": update.Servers,
+				"search":  update.Search,
+				"dhcp":    update.DHCP,
+			},
+		}
+		var resp struct {
+			Backend string `json:"backend"`
+		}
+		if err := h.agentClient.PostJSON(r.Context(), "/execute", req, &resp); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to configure DNS")
+			respondError(w, http.StatusInternalServerError, "Failed to configure DNS")
+			return
+		}
+		backend = resp.Backend
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "ok",
+		"servers": update.Servers,
+		"search":  update.Search,
+		"dhcp":    update.DHCP,
+		"backend": backend,
+	})
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDNSUpdateNormalize(t *testing.T) {
+	u := DNSUpdate{Servers: []string{" 1.1.1.1 ", "", "2606:4700:4700:0::1111"}, Search: []string{"Home.LAN.", " "}}
+	if err := u.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(u.Servers, ",") != "1.1.1.1,2606:4700:4700::1111" || strings.Join(u.Search, ",") != "home.lan" {
+		t.Fatalf("normalized: %+v", u)
+	}
+	if err := (&DNSUpdate{DHCP: true}).normalize(); err != nil {
+		t.Fatalf("dhcp without servers: %v", err)
+	}
+	for _, bad := range []DNSUpdate{
+		{},
+		{Servers: []string{" "}},
+		{Servers: []string{"dns.google"}},
+		{Servers: []string{"1.1.1.1"}, Search: []string{"bad_domain"}},
+		{Servers: []string{"1.1.1.1"}, Search: []string{"a..b"}},
+	} {
+		if err := bad.normalize(); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestSetDNSSendsAgentRequest(t *testing.T) {
+	agent := &recordingAgent{}
+	h := NewSystemConfigHandler(zerolog.Nop(), agent)
+
+	res := httptest.NewRecorder()
+	h.SetDNS(res, httptest.NewRequest(http.MethodPost, "/api/v1/network/dns", strings.NewReader(`{"servers":[]}`)))
+	if res.Code != http.StatusBadRequest || len(agent.posts) != 0 {
+		t.Fatalf("empty servers: %d, agent calls %d", res.Code, len(agent.posts))
+	}
+
+	res = httptest.NewRecorder()
+	h.SetDNS(res, httptest.NewRequest(http.MethodPost, "/api/v1/network/dns", strings.NewReader(`{"servers":["9.9.9.9"],"search":["example.com"]}`)))
+	if res.Code != http.StatusOK {
+		t.Fatalf("set: %d %s", res.Code, res.Body.String())
+	}
+	if len(agent.posts) != 1 {
+		t.Fatalf("expected one agent call, got %d", len(agent.posts))
+	}
+	req := agent.posts[0].(AgentRequest)
+	if req.Action != "network.dns.set" {
+		t.Fatalf("action: %s", req.Action)
+	}
+	if s := req.Params["servers"].([]string); len(s) != 1 || s[0] != "9.9.9.9" || req.Params["dhcp"] != false {
+		t.Fatalf("agent params: %v", req.Params)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseIPv4Routes(t *testing.T) {
+	data, err := os.ReadFile("testdata/proc_net_route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := parseIPv4Routes(string(data))
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes, got %+v", routes)
+	}
+	def := routes[0]
+	if !def.Default || def.Destination != "0.0.0.0/0" || def.Gateway != "192.168.1.1" || def.Interface != "eth0" || def.Metric != 100 || def.Family != "inet" {
+		t.Fatalf("default route: %+v", def)
+	}
+	if routes[1].Default || routes[1].Destination != "192.168.1.0/24" {
+		t.Fatalf("lan route: %+v", routes[1])
+	}
+	if routes[2].Destination != "172.17.0.0/16" {
+		t.Fatalf("docker route: %+v", routes[2])
+	}
+}
+
+func TestParseIPv6Routes(t *testing.T) {
+	data, err := os.ReadFile("testdata/proc_net_ipv6_route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := parseIPv6Routes(string(data))
+	// loopback and reject entries are dropped
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes, got %+v", routes)
+	}
+	if r := routes[0]; r.Destination != "fd00:1234::/64" || r.Default || r.Metric != 256 || r.Family != "inet6" {
+		t.Fatalf("ula route: %+v", r)
+	}
+	if r := routes[1]; r.Destination != "fe80::/64" || r.Gateway != "::" {
+		t.Fatalf("link-local route: %+v", r)
+	}
+	def := routes[2]
+	if !def.Default || def.Destination != "::/0" || def.Gateway != "fe80::211:22ff:fe33:4455" || def.Metric != 1024 || def.Interface != "eth0" {
+		t.Fatalf("default route: %+v", def)
+	}
+}
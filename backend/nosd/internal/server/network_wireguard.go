@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"nithronos/backend/nosd/pkg/httpx"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/skip2/go-qrcode"
+)
+
+// validWGPeerName restricts peer names to safe file-name characters since
+// they key the stored private key file.
+func validWGPeerName(name string) bool {
+	if name == "" || len(name) > 64 || name[0] == '.' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func findWGPeer(peers []WGPeer, name string) int {
+	for i, p := range peers {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// DeleteWireGuardPeer removes a peer and its stored private key
+func (h *NetworkConfigHandler) DeleteWireGuardPeer(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	config := h.loadWireGuardConfig()
+	i := findWGPeer(config.Peers, name)
+	if i < 0 {
+		httpx.WriteTypedError(w, http.StatusNotFound, "wg.peer_not_found", "Peer not found", 0)
+		return
+	}
+	config.Peers = append(config.Peers[:i], config.Peers[i+1:]...)
+
+	if err := h.saveWireGuardConfig(config); err != nil {
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "wg.save_failed", "Failed to save configuration", 0)
+		return
+	}
+	if validWGPeerName(name) {
+		if err := os.Remove(h.wgPeerKeyPath(name)); err != nil && !os.IsNotExist(err) {
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "wg.save_failed", "Failed to remove peer key", 0)
+			return
+		}
+	}
+
+	if err := h.applyWireGuardConfig(); err != nil {
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "wg.apply_failed", "Failed to apply configuration", 0)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWireGuardPeerConfig exports a peer's client config as text
+// (?format=conf, default) or as a PNG QR code (?format=qr).
+func (h *NetworkConfigHandler) GetWireGuardPeerConfig(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "conf"
+	}
+	if format != "conf" && format != "qr" {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "wg.invalid_format", "format must be conf or qr", 0)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	config := h.loadWireGuardConfig()
+	i := findWGPeer(config.Peers, name)
+	if i < 0 || !validWGPeerName(name) {
+		httpx.WriteTypedError(w, http.StatusNotFound, "wg.peer_not_found", "Peer not found", 0)
+		return
+	}
+	key, err := os.ReadFile(h.wgPeerKeyPath(name))
+	if err != nil {
+		// peers created with their own public key never had a key stored here
+		httpx.WriteTypedError(w, http.StatusConflict, "wg.no_private_key", "Peer private key is not held by this server", 0)
+		return
+	}
+	var server WGInterface
+	if len(config.Interfaces) > 0 {
+		server = config.Interfaces[0]
+	}
+	endpoint := r.URL.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = requestHostname(r)
+	}
+	conf := renderWGPeerConf(config.Peers[i], strings.TrimSpace(string(key)), server, endpoint)
+
+	if format == "qr" {
+		png, err := qrcode.Encode(conf, qrcode.Medium, 512)
+		if err != nil {
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "wg.qr_failed", "Failed to render QR code", 0)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(png)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".conf"))
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte(conf))
+}
+
+// renderWGPeerConf builds the wg-quick client config for peer. The tunnel
+// routes all traffic through the server.
+func renderWGPeerConf(peer WGPeer, privateKey string, server WGInterface, endpointHost string) string {
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	b.WriteString("# Name: " + peer.Name + "\n")
+	b.WriteString("PrivateKey = " + privateKey + "\n")
+	if len(peer.AllowedIPs) > 0 {
+		b.WriteString("Address = " + strings.Join(peer.AllowedIPs, ", ") + "\n")
+	}
+	b.WriteString("\n[Peer]\n")
+	b.WriteString("PublicKey = " + server.PublicKey + "\n")
+	b.WriteString("AllowedIPs = 0.0.0.0/0, ::/0\n")
+	if endpointHost != "" && server.ListenPort > 0 {
+		b.WriteString("Endpoint = " + net.JoinHostPort(endpointHost, strconv.Itoa(server.ListenPort)) + "\n")
+	}
+	return b.String()
+}
+
+// requestHostname is the host the client used to reach us, without port.
+func requestHostname(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return r.Host
+}
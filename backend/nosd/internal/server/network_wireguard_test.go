@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestWireGuardPeerDelete(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.EtcDir = t.TempDir()
+	h := NewNetworkConfigHandler(cfg)
+	r := h.Routes()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return res
+	}
+
+	if res := do(http.MethodPost, "/wireguard/peers", `{"name":"../etc"}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("unsafe name: %d", res.Code)
+	}
+	if res := do(http.MethodPost, "/wireguard/peers", `{"name":"phone","allowed_ips":["10.8.0.2/32"]}`); res.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", res.Code, res.Body.String())
+	}
+	if res := do(http.MethodPost, "/wireguard/peers", `{"name":"phone"}`); res.Code != http.StatusConflict {
+		t.Fatalf("duplicate: %d", res.Code)
+	}
+	if _, err := os.Stat(h.wgPeerKeyPath("phone")); err != nil {
+		t.Fatalf("private key not stored: %v", err)
+	}
+
+	res := do(http.MethodGet, "/wireguard/peers/phone/config?format=qr", "")
+	if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "image/png" || !bytes.HasPrefix(res.Body.Bytes(), []byte("\x89PNG")) {
+		t.Fatalf("qr: %d %s", res.Code, res.Header().Get("Content-Type"))
+	}
+	if res := do(http.MethodGet, "/wireguard/peers/phone/config?format=svg", ""); res.Code != http.StatusBadRequest {
+		t.Fatalf("bad format: %d", res.Code)
+	}
+
+	if res := do(http.MethodDelete, "/wireguard/peers/phone", ""); res.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", res.Code, res.Body.String())
+	}
+	if _, err := os.Stat(h.wgPeerKeyPath("phone")); !os.IsNotExist(err) {
+		t.Fatalf("private key not removed: %v", err)
+	}
+	if n := len(h.loadWireGuardConfig().Peers); n != 0 {
+		t.Fatalf("peer still configured: %d", n)
+	}
+	if res := do(http.MethodDelete, "/wireguard/peers/phone", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("delete missing: %d", res.Code)
+	}
+	if res := do(http.MethodGet, "/wireguard/peers/phone/config", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("config missing: %d", res.Code)
+	}
+}
+
+func TestRenderWGPeerConf(t *testing.T) {
+	peer := WGPeer{Name: "laptop", AllowedIPs: []string{"10.8.0.3/32", "fd42::3/128"}}
+	server := WGInterface{Name: "wg0", PublicKey: "c2VydmVyLXB1YmxpYy1rZXk=", ListenPort: 51820}
+	want := "[Interface]\n# Name: laptop\nPrivateKey = cGVlci1wcml2YXRlLWtleQ==\nAddress = 10.8.0.3/32, fd42::3/128\n\n" +
+		"[Peer]\nPublicKey = c2VydmVyLXB1YmxpYy1rZXk=\nAllowedIPs = 0.0.0.0/0, ::/0\nEndpoint = nas.example.com:51820\n"
+	if got := renderWGPeerConf(peer, "cGVlci1wcml2YXRlLWtleQ==", server, "nas.example.com"); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := renderWGPeerConf(peer, "k", server, "2001:db8::1"); !strings.Contains(got, "Endpoint = [2001:db8::1]:51820\n") {
+		t.Fatalf("ipv6 endpoint:\n%s", got)
+	}
+}
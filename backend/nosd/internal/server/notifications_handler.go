@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -43,6 +44,13 @@ func (h *NotificationHandler) Routes() chi.Router {
 	r.Delete("/channels/{id}", h.DeleteChannel)
 	r.Post("/channels/{id}/test", h.TestChannel)
 
+	// Routing rules
+	r.Get("/routes", h.ListRoutes)
+	r.Post("/routes", h.CreateRoute)
+	r.Get("/routes/{id}", h.GetRoute)
+	r.Put("/routes/{id}", h.UpdateRoute)
+	r.Delete("/routes/{id}", h.DeleteRoute)
+
 	return r
 }
 
@@ -109,11 +117,14 @@ func (h *NotificationHandler) Subscribe(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("X-Accel-Buffering", "no")
 
 	// Get client ID from session
-	clientID := r.Header.Get("X-UID")
+	clientID := sessionUID(r)
 	if clientID == "" {
 		clientID = "anonymous"
 	}
 
+	ctx, done := streams.track(r.Context())
+	defer done()
+
 	// Create subscription
 	ch := h.manager.Subscribe(clientID)
 	defer h.manager.Unsubscribe(clientID, ch)
@@ -140,7 +151,7 @@ func (h *NotificationHandler) Subscribe(w http.ResponseWriter, r *http.Request)
 			_, _ = w.Write([]byte("event: ping\ndata: {}\n\n"))
 			w.(http.Flusher).Flush()
 
-		case <-r.Context().Done():
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -182,6 +193,10 @@ func (h *NotificationHandler) CreateChannel(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err := h.manager.CreateChannel(&channel); err != nil {
+		if errors.Is(err, notifications.ErrInvalidChannel) {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "notifications.invalid_channel", err.Error(), 0)
+			return
+		}
 		httpx.WriteError(w, http.StatusInternalServerError, "Failed to create channel")
 		return
 	}
@@ -203,6 +218,14 @@ func (h *NotificationHandler) UpdateChannel(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err := h.manager.UpdateChannel(id, &updates); err != nil {
+		if errors.Is(err, notifications.ErrChannelNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "Channel not found")
+			return
+		}
+		if errors.Is(err, notifications.ErrInvalidChannel) {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "notifications.invalid_channel", err.Error(), 0)
+			return
+		}
 		httpx.WriteError(w, http.StatusInternalServerError, "Failed to update channel")
 		return
 	}
@@ -229,7 +252,11 @@ func (h *NotificationHandler) TestChannel(w http.ResponseWriter, r *http.Request
 	id := chi.URLParam(r, "id")
 
 	if err := h.manager.TestChannel(id); err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, notifications.ErrChannelNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "Channel not found")
+			return
+		}
+		httpx.WriteTypedError(w, http.StatusBadGateway, "notifications.delivery_failed", err.Error(), 0)
 		return
 	}
 
@@ -239,16 +266,74 @@ func (h *NotificationHandler) TestChannel(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// sanitizeConfig removes sensitive information from config
-func (h *NotificationHandler) sanitizeConfig(config map[string]interface{}, channelType string) map[string]interface{} {
-	sanitized := make(map[string]interface{})
-	for k, v := range config {
-		switch k {
-		case "password", "apiKey", "token", "secret":
-			sanitized[k] = "***"
+// ListRoutes returns the routing rules, default route last
+func (h *NotificationHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.manager.ListRoutes())
+}
+
+// GetRoute returns a specific routing rule
+func (h *NotificationHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.manager.GetRoute(chi.URLParam(r, "id"))
+	if !ok {
+		httpx.WriteError(w, http.StatusNotFound, "Route not found")
+		return
+	}
+	writeJSON(w, route)
+}
+
+// CreateRoute adds a routing rule
+func (h *NotificationHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
+	var route notifications.Route
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.manager.CreateRoute(&route); err != nil {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "notifications.invalid_route", err.Error(), 0)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, route)
+}
+
+// UpdateRoute replaces a routing rule's criteria and channels
+func (h *NotificationHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var route notifications.Route
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.manager.UpdateRoute(id, &route); err != nil {
+		if errors.Is(err, notifications.ErrRouteNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "Route not found")
+			return
+		}
+		httpx.WriteTypedError(w, http.StatusBadRequest, "notifications.invalid_route", err.Error(), 0)
+		return
+	}
+	updated, _ := h.manager.GetRoute(id)
+	writeJSON(w, updated)
+}
+
+// DeleteRoute removes a routing rule; the default route cannot be removed
+func (h *NotificationHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.DeleteRoute(chi.URLParam(r, "id")); err != nil {
+		switch {
+		case errors.Is(err, notifications.ErrRouteNotFound):
+			httpx.WriteError(w, http.StatusNotFound, "Route not found")
+		case errors.Is(err, notifications.ErrDefaultRoute):
+			httpx.WriteTypedError(w, http.StatusConflict, "notifications.default_route", err.Error(), 0)
 		default:
-			sanitized[k] = v
+			httpx.WriteError(w, http.StatusInternalServerError, "Failed to delete route")
 		}
+		return
 	}
-	return sanitized
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sanitizeConfig removes sensitive information from config
+func (h *NotificationHandler) sanitizeConfig(config map[string]interface{}, channelType string) map[string]interface{} {
+	return notifications.RedactConfig(config)
 }
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ntpProbeTimeout bounds each SNTP reachability query in SetNTP.
+const ntpProbeTimeout = 2 * time.Second
+
+// ntpEpochOffset is the number of seconds between 1900-01-01 and 1970-01-01.
+const ntpEpochOffset = 2208988800
+
+// seams for tests
+var (
+	ntpProbe          = sntpQuery
+	timesyncClockPath = "/var/lib/systemd/timesync/clock"
+)
+
+type ntpProbeResult struct {
+	Server string `json:"server"`
+	Error  string `json:"error"`
+}
+
+// validNTPServer accepts an IP address or an RFC 1123 hostname.
+func validNTPServer(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	return isValidHostname(strings.TrimSuffix(s, "."))
+}
+
+// sntpQuery sends one SNTPv4 client request to server:123 and returns the
+// local clock offset.
+func sntpQuery(ctx context.Context, server string) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(server, "123"))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return sntpExchange(ctx, conn)
+}
+
+// sntpExchange performs the request/response on an established connection.
+func sntpExchange(ctx context.Context, conn net.Conn) (time.Duration, error) {
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI 0, VN 4, mode 3 (client)
+	t1 := time.Now()
+	putNTPTime(req[40:], t1)
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	t4 := time.Now()
+	if n < 48 || resp[0]&0x07 != 4 {
+		return 0, errors.New("invalid NTP response")
+	}
+	if resp[1] == 0 {
+		return 0, errors.New("server sent kiss-of-death")
+	}
+	t2 := ntpTime(resp[32:])
+	t3 := ntpTime(resp[40:])
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+func putNTPTime(b []byte, t time.Time) {
+	secs := uint64(t.Unix()) + ntpEpochOffset
+	frac := uint64(t.Nanosecond()) << 32 / 1e9
+	binary.BigEndian.PutUint32(b[0:], uint32(secs))
+	binary.BigEndian.PutUint32(b[4:], uint32(frac))
+}
+
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[0:])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// probeNTPServers queries every server concurrently and returns the ones
+// that did not answer.
+func probeNTPServers(ctx context.Context, servers []string) []ntpProbeResult {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := []ntpProbeResult{}
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, ntpProbeTimeout)
+			defer cancel()
+			if _, err := ntpProbe(pctx, s); err != nil {
+				mu.Lock()
+				failed = append(failed, ntpProbeResult{Server: s, Error: err.Error()})
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+	return failed
+}
+
+// parseTimesyncStatus extracts the current server and offset from
+// `timedatectl timesync-status` output.
+func parseTimesyncStatus(out string) (server string, offset time.Duration, ok bool) {
+	for _, ln := range strings.Split(out, "\n") {
+		k, v, found := strings.Cut(ln, ":")
+		if !found {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch strings.TrimSpace(k) {
+		case "Server":
+			server, _, _ = strings.Cut(v, " ")
+		case "Offset":
+			if d, err := time.ParseDuration(v); err == nil {
+				offset, ok = d, true
+			}
+		}
+	}
+	return server, offset, ok
+}
+
+// lastTimesync is when systemd-timesyncd last adjusted the clock; it touches
+// its clock file on every successful sync.
+func lastTimesync() *time.Time {
+	st, err := os.Stat(timesyncClockPath)
+	if err != nil {
+		return nil
+	}
+	t := st.ModTime().UTC()
+	return &t
+}
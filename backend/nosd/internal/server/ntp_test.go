@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNTPTimeRoundTrip(t *testing.T) {
+	now := time.Unix(1718000000, 123456789)
+	b := make([]byte, 8)
+	putNTPTime(b, now)
+	if d := ntpTime(b).Sub(now); d < -time.Microsecond || d > time.Microsecond {
+		t.Fatalf("round trip off by %v", d)
+	}
+}
+
+func TestValidNTPServer(t *testing.T) {
+	for _, ok := range []string{"pool.ntp.org", "time.cloudflare.com.", "192.168.1.1", "2001:db8::1"} {
+		if !validNTPServer(ok) {
+			t.Errorf("%q should be valid", ok)
+		}
+	}
+	for _, bad := range []string{"", "ntp server", "time..example.com", "-ntp.example.com", "ntp.example.com:123"} {
+		if validNTPServer(bad) {
+			t.Errorf("%q should be invalid", bad)
+		}
+	}
+}
+
+// sntpQuery against a loopback responder whose clock runs 2s ahead.
+func TestSNTPQueryOffset(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, 48)
+		_, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0] = 0x24 // VN 4, mode 4 (server)
+		resp[1] = 2
+		ahead := time.Now().Add(2 * time.Second)
+		putNTPTime(resp[32:], ahead)
+		putNTPTime(resp[40:], ahead)
+		_, _ = pc.WriteTo(resp, addr)
+	}()
+
+	// sntpQuery always dials port 123; talk to the responder directly instead.
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	off, err := sntpExchange(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off < 1900*time.Millisecond || off > 2100*time.Millisecond {
+		t.Fatalf("offset %v, want ~2s", off)
+	}
+}
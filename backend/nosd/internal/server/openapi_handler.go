@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/openapi"
+)
+
+// apiVersion is the version reported by /api/v1/health and the OpenAPI
+// document.
+const apiVersion = "0.9.5-pre-alpha"
+
+// routeInventory lists every method and path registered on r with its
+// auth requirement, sorted.
+func routeInventory(r chi.Routes) []openapi.Route {
+	var routes []openapi.Route
+	_ = chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, openapi.Route{Method: method, Path: route, Auth: routeAuth(method, route)})
+		return nil
+	})
+	openapi.SortRoutes(routes)
+	return routes
+}
+
+// OpenAPIDocument generates the OpenAPI document for every route on r, as
+// served at /api/v1/openapi.json.
+func OpenAPIDocument(r chi.Routes) *openapi.Document {
+	return apiDocument(routeInventory(r))
+}
+
+func apiDocument(routes []openapi.Route) *openapi.Document {
+	return openapi.Generate(openapi.Info{Title: "NithronOS API", Version: apiVersion}, routes)
+}
+
+// openapiSpec is the generated document, encoded once the router is built.
+type openapiSpec struct {
+	mu       sync.RWMutex
+	body     []byte
+	modified time.Time
+}
+
+// build generates the document from the route inventory.
+func (s *openapiSpec) build(routes []openapi.Route) error {
+	b, err := json.Marshal(apiDocument(routes))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = b
+	s.modified = time.Now().UTC().Truncate(time.Second)
+	return nil
+}
+
+// GET /api/v1/openapi.json
+func (s *openapiSpec) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	body, modified := s.body, s.modified
+	s.mu.RUnlock()
+	if body == nil {
+		http.Error(w, "OpenAPI document not built", http.StatusServiceUnavailable)
+		return
+	}
+	serveCacheableJSON(w, r, body, modified)
+}
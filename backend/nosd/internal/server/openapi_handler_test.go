@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/openapi"
+)
+
+func TestOpenAPIListsRegisteredRoutes(t *testing.T) {
+	healthTestEnv(t)
+	r := NewRouter(config.FromEnv())
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET openapi.json: %d %s", res.Code, res.Body.String())
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct{ Version string }
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct{ Name, In string }
+		}
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Version != apiVersion {
+		t.Fatalf("header: %s %s", doc.OpenAPI, doc.Info.Version)
+	}
+	if op, ok := doc.Paths["/api/v1/auth/login"]["post"]; !ok || op.OperationID != "post_api_v1_auth_login" {
+		t.Fatalf("POST /api/v1/auth/login missing: %+v", doc.Paths["/api/v1/auth/login"])
+	}
+	op, ok := doc.Paths["/api/v1/pools/{id}"]["get"]
+	if !ok || len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" {
+		t.Fatalf("GET /api/v1/pools/{id}: %+v", op)
+	}
+	if _, ok := doc.Paths["/api/v1/openapi.json"]["get"]; !ok {
+		t.Fatal("the spec doesn't list itself")
+	}
+
+	etag, modified := res.Header().Get("ETag"), res.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("caching headers: ETag %q Last-Modified %q", etag, modified)
+	}
+	for name, value := range map[string]string{"If-None-Match": etag, "If-Modified-Since": modified} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+		req.Header.Set(name, value)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+			t.Fatalf("%s: %d", name, res.Code)
+		}
+	}
+}
+
+func TestCatalogRevalidates(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_APPS_INSTALL_DIR", t.TempDir())
+	r := NewRouter(config.FromEnv())
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/apps/catalog", nil))
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || etag == "" || res.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("GET catalog: %d ETag %q Cache-Control %q", res.Code, etag, res.Header().Get("Cache-Control"))
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/apps/catalog", nil)
+	req.Header.Set("If-None-Match", etag)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match: %d", res.Code)
+	}
+	req.Header.Set("If-None-Match", `"stale"`)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || res.Body.Len() == 0 {
+		t.Fatalf("stale ETag: %d", res.Code)
+	}
+}
+
+func TestOpenAPIPathsMatchWalkedRoutes(t *testing.T) {
+	healthTestEnv(t)
+	r := NewRouter(config.FromEnv())
+
+	want := map[string]bool{}
+	_ = chi.Walk(r.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if method == http.MethodConnect {
+			// OpenAPI 3.0 has no CONNECT operation
+			return nil
+		}
+		path, _ := openapi.PathTemplate(route)
+		want[strings.ToLower(method)+" "+path] = true
+		return nil
+	})
+	got := map[string]bool{}
+	for path, item := range OpenAPIDocument(r.(chi.Routes)).Paths {
+		for method, op := range item {
+			got[method+" "+path] = true
+			if op.Security == nil || len(op.Tags) != 1 {
+				t.Errorf("%s %s: security %v tags %v", method, path, op.Security, op.Tags)
+			}
+		}
+	}
+	for op := range want {
+		if !got[op] {
+			t.Errorf("walked route %s missing from the document", op)
+		}
+	}
+	for op := range got {
+		if !want[op] {
+			t.Errorf("document lists %s, which isn't registered", op)
+		}
+	}
+
+	doc := OpenAPIDocument(r.(chi.Routes))
+	if sec := doc.Paths["/api/v1/auth/login"]["post"].Security; len(sec) != 0 {
+		t.Errorf("login security = %v, want none", sec)
+	}
+	if sec := doc.Paths["/api/v1/pools"]["get"].Security; len(sec) != 1 || sec[0]["session"] == nil {
+		t.Errorf("pools security = %v, want the session", sec)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/auth"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// passwordPolicyFor returns the built-in policy with the configured max age.
+func passwordPolicyFor(cfg config.Config) auth.PasswordPolicy {
+	p := auth.DefaultPasswordPolicy()
+	p.MaxAge = cfg.PasswordMaxAgeDays
+	return p
+}
+
+// passwordAgeStatus evaluates a stored user's password against the policy.
+// Users created before password changes were tracked have no
+// PasswordChangedAt; their clock starts at the first evaluation rather than
+// at CreatedAt, so an upgrade doesn't lock out every older account at once.
+// Only that timestamp is persisted; the rest of u is never written back.
+func passwordAgeStatus(cfg config.Config, users *userstore.Store, u *userstore.User, now time.Time) auth.PasswordAgeStatus {
+	if u.PasswordChangedAt == "" {
+		u.PasswordChangedAt = now.UTC().Format(time.RFC3339)
+		if users != nil {
+			if at, err := users.StartPasswordClock(u.ID, u.PasswordChangedAt); err == nil {
+				u.PasswordChangedAt = at
+			}
+		}
+	}
+	changedAt, _ := time.Parse(time.RFC3339, u.PasswordChangedAt)
+	return passwordPolicyFor(cfg).EvaluateAge(changedAt, u.ForcePasswordChange, now)
+}
+
+// requirePasswordCurrent blocks a user whose password has expired (or was
+// flagged for change) from everything except auth endpoints and changing
+// their own password.
+func requirePasswordCurrent(cfg config.Config, users *userstore.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uid, ok := decodeSessionUID(r, cfg)
+			if !ok || uid == "" || users == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if strings.HasPrefix(r.URL.Path, "/api/v1/auth/") || r.URL.Path == "/api/v1/users/"+uid+"/password" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			u, err := users.FindByID(uid)
+			if err != nil || !passwordAgeStatus(cfg, users, &u, time.Now()).ChangeRequired {
+				next.ServeHTTP(w, r)
+				return
+			}
+			httpx.WriteTypedError(w, http.StatusForbidden, "auth.password_change_required", "Password change required", 0)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/auth/hash"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/auth"
+)
+
+func TestLoginPasswordAge(t *testing.T) {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	t.Setenv("NOS_USERS_PATH", usersPath)
+	t.Setenv("NOS_SESSIONS_PATH", filepath.Join(dir, "sessions.json"))
+	t.Setenv("NOS_RL_PATH", filepath.Join(dir, "ratelimit.json"))
+	t.Setenv("NOS_ETC_DIR", dir)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	t.Setenv("NOS_APPS_STATE", filepath.Join(dir, "apps.json"))
+	t.Setenv("NOS_DISABLE_APP_EVENTS", "1")
+
+	now := time.Now().UTC()
+	ago := func(days int) string { return now.Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339) }
+	st, _ := userstore.New(usersPath)
+	for _, u := range []userstore.User{
+		{ID: "u-fresh", Username: "fresh", PasswordChangedAt: ago(1)},
+		{ID: "u-warn", Username: "warn", PasswordChangedAt: ago(config.Defaults().PasswordMaxAgeDays - 3)},
+		{ID: "u-expired", Username: "expired", PasswordChangedAt: ago(config.Defaults().PasswordMaxAgeDays + 1)},
+	} {
+		u.PasswordHash = "plain:secret"
+		u.Roles = []string{"admin"}
+		u.CreatedAt = ago(365)
+		if err := st.UpsertUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRouter(config.FromEnv())
+
+	login := func(username string) ([]*http.Cookie, map[string]any) {
+		t.Helper()
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(mustJSON(map[string]any{"username": username, "password": "secret"}))))
+		if res.Code != http.StatusOK {
+			t.Fatalf("login %s: %d %s", username, res.Code, res.Body.String())
+		}
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res.Result().Cookies(), out
+	}
+	get := func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	meStatus := func(cookies []*http.Cookie) map[string]any {
+		t.Helper()
+		res := get("/api/v1/auth/me", cookies)
+		if res.Code != http.StatusOK {
+			t.Fatalf("me: %d", res.Code)
+		}
+		var out struct {
+			Password map[string]any `json:"password"`
+		}
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return out.Password
+	}
+
+	// fresh: no flags, normal access
+	cookies, out := login("fresh")
+	if out["passwordChangeRequired"] != false {
+		t.Fatalf("fresh: unexpected change required: %v", out)
+	}
+	if pw := meStatus(cookies); pw["warn"] != false || pw["change_required"] != false {
+		t.Fatalf("fresh: me status %v", pw)
+	}
+	if res := get("/api/v1/snapshots/recent", cookies); res.Code == http.StatusForbidden {
+		t.Fatalf("fresh: protected route blocked")
+	}
+
+	// warn: login ok with warning, access allowed
+	cookies, out = login("warn")
+	if out["passwordChangeRequired"] != false {
+		t.Fatalf("warn: unexpected change required: %v", out)
+	}
+	if pw := meStatus(cookies); pw["warn"] != true || pw["change_required"] != false {
+		t.Fatalf("warn: me status %v", pw)
+	}
+	if res := get("/api/v1/snapshots/recent", cookies); res.Code == http.StatusForbidden {
+		t.Fatalf("warn: protected route blocked")
+	}
+
+	// expired: login ok, but everything except auth is blocked
+	cookies, out = login("expired")
+	if out["passwordChangeRequired"] != true {
+		t.Fatalf("expired: change should be required: %v", out)
+	}
+	if pw := meStatus(cookies); pw["expired"] != true || pw["change_required"] != true {
+		t.Fatalf("expired: me status %v", pw)
+	}
+	if res := get("/api/v1/snapshots/recent", cookies); res.Code != http.StatusForbidden {
+		t.Fatalf("expired: expected 403, got %d", res.Code)
+	}
+}
+
+// passwordAgeRouter seeds users into a fresh store and returns a router
+// that enforces auth, plus a helper to sign in as one of them.
+func passwordAgeRouter(t *testing.T, seed ...userstore.User) (string, http.Handler, func(string) []*http.Cookie) {
+	t.Helper()
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	t.Setenv("NOS_TEST_SKIP_AUTH", "")
+	usersPath := filepath.Join(dir, "users.json")
+	st, _ := userstore.New(usersPath)
+	ph, err := hash.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range seed {
+		u.PasswordHash = ph
+		if err := st.UpsertUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewRouter(config.FromEnv())
+	login := func(username string) []*http.Cookie {
+		t.Helper()
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(mustJSON(map[string]any{"username": username, "password": "secret"}))))
+		if res.Code != http.StatusOK {
+			t.Fatalf("login %s: %d %s", username, res.Code, res.Body.String())
+		}
+		return res.Result().Cookies()
+	}
+	return usersPath, r, login
+}
+
+func passwordAgeCall(r http.Handler, method, path string, body any, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body != nil {
+		req = httptest.NewRequest(method, path, bytes.NewReader(mustJSON(body)))
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+		if c.Name == auth.CSRFCookieName {
+			req.Header.Set("X-CSRF-Token", c.Value)
+		}
+	}
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func TestNonAdminChangesFlaggedPassword(t *testing.T) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	usersPath, r, login := passwordAgeRouter(t,
+		userstore.User{ID: "u-admin", Username: "admin", Roles: []string{"admin"}, PasswordChangedAt: now},
+		userstore.User{ID: "u-bob", Username: "bob", Roles: []string{"user"}, PasswordChangedAt: now, ForcePasswordChange: true},
+	)
+	cookies := login("bob")
+	if res := passwordAgeCall(r, http.MethodGet, "/api/v1/snapshots/recent", nil, cookies); res.Code != http.StatusForbidden {
+		t.Fatalf("flagged user: expected 403, got %d", res.Code)
+	}
+	// the admin-only users route stays closed to bob
+	if res := passwordAgeCall(r, http.MethodPost, "/api/v1/users/u-bob/password", map[string]any{"current_password": "secret", "new_password": "a-new-secret"}, cookies); res.Code != http.StatusForbidden {
+		t.Fatalf("users route: expected 403, got %d", res.Code)
+	}
+
+	if res := passwordAgeCall(r, http.MethodPost, "/api/v1/auth/password", map[string]any{"current_password": "wrong", "new_password": "a-new-secret"}, cookies); res.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong current password: expected 401, got %d", res.Code)
+	}
+	if res := passwordAgeCall(r, http.MethodPost, "/api/v1/auth/password", map[string]any{"current_password": "secret", "new_password": "a-new-secret"}, cookies); res.Code != http.StatusOK {
+		t.Fatalf("self-service change: %d %s", res.Code, res.Body.String())
+	}
+	st, _ := userstore.New(usersPath)
+	if u, _ := st.FindByID("u-bob"); u.ForcePasswordChange {
+		t.Fatalf("force flag not cleared")
+	}
+	if res := passwordAgeCall(r, http.MethodGet, "/api/v1/snapshots/recent", nil, cookies); res.Code == http.StatusForbidden {
+		t.Fatalf("after change: protected route still blocked")
+	}
+}
+
+func TestLegacyPasswordAgeStartsAtFirstLogin(t *testing.T) {
+	longAgo := time.Now().UTC().Add(-365 * 24 * time.Hour).Format(time.RFC3339)
+	usersPath, r, login := passwordAgeRouter(t,
+		userstore.User{ID: "u-old", Username: "old", Roles: []string{"admin"}, CreatedAt: longAgo},
+	)
+	before := time.Now().UTC().Add(-time.Second)
+	cookies := login("old")
+	if res := passwordAgeCall(r, http.MethodGet, "/api/v1/snapshots/recent", nil, cookies); res.Code == http.StatusForbidden {
+		t.Fatalf("legacy user locked out")
+	}
+	st, _ := userstore.New(usersPath)
+	u, _ := st.FindByID("u-old")
+	changed, err := time.Parse(time.RFC3339, u.PasswordChangedAt)
+	if err != nil || changed.Before(before) {
+		t.Fatalf("PasswordChangedAt not stamped at first evaluation: %q", u.PasswordChangedAt)
+	}
+}
+
+func TestPasswordMaxAgeFromConfig(t *testing.T) {
+	t.Setenv("NOS_PASSWORD_MAX_AGE_DAYS", "30")
+	ago := func(days int) string {
+		return time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
+	}
+	_, r, login := passwordAgeRouter(t,
+		userstore.User{ID: "u-a", Username: "a", Roles: []string{"admin"}, PasswordChangedAt: ago(29)},
+		userstore.User{ID: "u-b", Username: "b", Roles: []string{"admin"}, PasswordChangedAt: ago(31)},
+	)
+	if res := passwordAgeCall(r, http.MethodGet, "/api/v1/snapshots/recent", nil, login("a")); res.Code == http.StatusForbidden {
+		t.Fatalf("29 days with a 30 day limit: blocked")
+	}
+	if res := passwordAgeCall(r, http.MethodGet, "/api/v1/snapshots/recent", nil, login("b")); res.Code != http.StatusForbidden {
+		t.Fatalf("31 days with a 30 day limit: expected 403, got %d", res.Code)
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/internal/pools"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+const (
+	// poolSampleInterval is how often pool usage is recorded.
+	poolSampleInterval = time.Hour
+	// poolSampleRetention bounds the history kept per pool; the forecast
+	// fits over everything retained.
+	poolSampleRetention = 30 * 24 * time.Hour
+	poolSampleMax       = 800
+	// forecastMinSamples and forecastMinSpan guard against projecting from
+	// a handful of points taken minutes apart.
+	forecastMinSamples = 6
+	forecastMinSpan    = 6 * time.Hour
+)
+
+// listPools is swapped in tests.
+var listPools = pools.ListPools
+
+type usageSample struct {
+	At   time.Time `json:"t"`
+	Used uint64    `json:"used"`
+	Size uint64    `json:"size"`
+}
+
+// usageHistory holds recent usage samples per pool, keyed by filesystem
+// UUID so a remount under another path keeps its history.
+type usageHistory struct {
+	mu      sync.Mutex
+	loaded  bool
+	samples map[string][]usageSample
+}
+
+var poolUsage = &usageHistory{}
+
+func poolUsagePath() string {
+	base := os.Getenv("NOS_STATE_DIR")
+	if base == "" {
+		base = "/var/lib/nos"
+	}
+	return filepath.Join(base, "pools", "usage-history.json")
+}
+
+func poolHistoryKey(p pools.Pool) string {
+	if p.UUID != "" {
+		return p.UUID
+	}
+	return p.ID
+}
+
+// load reads the persisted history once; callers hold h.mu.
+func (h *usageHistory) load() {
+	if h.loaded {
+		return
+	}
+	h.loaded = true
+	h.samples = map[string][]usageSample{}
+	if b, err := os.ReadFile(poolUsagePath()); err == nil {
+		_ = json.Unmarshal(b, &h.samples)
+	}
+}
+
+// record appends one sample per pool, trims each series to the retention
+// window and persists the result.
+func (h *usageHistory) record(ctx context.Context, list []pools.Pool, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.load()
+	for _, p := range list {
+		if p.Size == 0 {
+			continue
+		}
+		key := poolHistoryKey(p)
+		h.samples[key] = append(h.samples[key], usageSample{At: now.UTC(), Used: p.Used, Size: p.Size})
+	}
+	cutoff := now.Add(-poolSampleRetention)
+	for key, series := range h.samples {
+		i := 0
+		for i < len(series) && series[i].At.Before(cutoff) {
+			i++
+		}
+		if len(series)-i > poolSampleMax {
+			i = len(series) - poolSampleMax
+		}
+		if i == len(series) {
+			delete(h.samples, key)
+			continue
+		}
+		h.samples[key] = append([]usageSample(nil), series[i:]...)
+	}
+	return fsatomic.SaveJSON(ctx, poolUsagePath(), h.samples, 0o600)
+}
+
+func (h *usageHistory) series(key string) []usageSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.load()
+	return append([]usageSample(nil), h.samples[key]...)
+}
+
+// StartPoolUsageSampler records pool usage now and then every
+// poolSampleInterval until ctx is done.
+func StartPoolUsageSampler(ctx context.Context) {
+	go func() {
+		t := time.NewTicker(poolSampleInterval)
+		defer t.Stop()
+		for {
+			sctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if list, err := listPools(sctx); err == nil && len(list) > 0 {
+				_ = poolUsage.record(sctx, list, time.Now())
+			}
+			cancel()
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+type poolForecast struct {
+	Status  string `json:"status"` // ok, insufficient_data
+	Samples int    `json:"samples"`
+	// Used and Size are from the latest sample.
+	Used uint64 `json:"used,omitempty"`
+	Size uint64 `json:"size,omitempty"`
+	// SlopeBytesPerDay is the fitted growth rate; negative when shrinking.
+	SlopeBytesPerDay float64 `json:"slopeBytesPerDay"`
+	// Confidence is the R² of the linear fit, 0-1.
+	Confidence float64 `json:"confidence"`
+	// DaysUntilFull is null when usage is flat or shrinking.
+	DaysUntilFull *float64   `json:"daysUntilFull"`
+	FullAt        *time.Time `json:"fullAt,omitempty"`
+	Since         *time.Time `json:"since,omitempty"`
+}
+
+// forecastUsage fits used bytes over time by least squares and projects
+// when the latest size is reached.
+func forecastUsage(series []usageSample) poolForecast {
+	f := poolForecast{Status: "insufficient_data", Samples: len(series)}
+	if len(series) < forecastMinSamples || series[len(series)-1].At.Sub(series[0].At) < forecastMinSpan {
+		return f
+	}
+	t0 := series[0].At
+	n := float64(len(series))
+	var sx, sy float64
+	for _, s := range series {
+		sx += s.At.Sub(t0).Hours() / 24
+		sy += float64(s.Used)
+	}
+	mx, my := sx/n, sy/n
+	var sxx, sxy, syy float64
+	for _, s := range series {
+		dx := s.At.Sub(t0).Hours()/24 - mx
+		dy := float64(s.Used) - my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	slope := sxy / sxx
+	last := series[len(series)-1]
+	f.Status, f.Used, f.Size, f.Since = "ok", last.Used, last.Size, &t0
+	f.SlopeBytesPerDay = math.Round(slope)
+	if syy == 0 {
+		f.Confidence = 1 // perfectly flat
+	} else {
+		f.Confidence = math.Round(sxy*sxy/(sxx*syy)*1000) / 1000
+	}
+	if slope > 0 {
+		// project from the fitted line at the latest sample, not the raw
+		// reading, so one noisy sample doesn't swing the estimate
+		lastDay := last.At.Sub(t0).Hours() / 24
+		fitted := my + slope*(lastDay-mx)
+		days := math.Max(0, (float64(last.Size)-fitted)/slope)
+		days = math.Round(days*10) / 10
+		at := last.At.Add(time.Duration(days * 24 * float64(time.Hour)))
+		f.DaysUntilFull, f.FullAt = &days, &at
+	}
+	return f
+}
+
+// GET /api/v1/pools/{id}/forecast
+func handlePoolForecast(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	list, _ := listPools(r.Context())
+	for _, p := range list {
+		if p.ID == id || p.UUID == id || p.Mount == id {
+			writeJSON(w, forecastUsage(poolUsage.series(poolHistoryKey(p))))
+			return
+		}
+	}
+	httpx.WriteError(w, http.StatusNotFound, "pool not found")
+}
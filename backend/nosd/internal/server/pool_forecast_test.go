@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/pools"
+)
+
+const gib = 1 << 30
+
+func TestForecastUsageLinearSeries(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var series []usageSample
+	// 500 GiB used of 1 TiB, growing 10 GiB/day, sampled every 12h with
+	// ±1 GiB of alternating noise
+	for i := 0; i < 20; i++ {
+		noise := int64(gib)
+		if i%2 == 1 {
+			noise = -noise
+		}
+		used := uint64(int64(500*gib+i*5*gib) + noise)
+		series = append(series, usageSample{At: start.Add(time.Duration(i) * 12 * time.Hour), Used: used, Size: 1024 * gib})
+	}
+
+	f := forecastUsage(series)
+	if f.Status != "ok" || f.Samples != 20 {
+		t.Fatalf("unexpected status: %+v", f)
+	}
+	if perDay := f.SlopeBytesPerDay / gib; math.Abs(perDay-10) > 0.5 {
+		t.Fatalf("slope = %.2f GiB/day, want ~10", perDay)
+	}
+	// latest fitted value is 595 GiB, leaving 429 GiB at 10 GiB/day
+	if f.DaysUntilFull == nil || math.Abs(*f.DaysUntilFull-42.9) > 1 {
+		t.Fatalf("daysUntilFull = %v, want ~42.9", f.DaysUntilFull)
+	}
+	if f.Confidence < 0.9 || f.Confidence > 1 {
+		t.Fatalf("confidence = %v", f.Confidence)
+	}
+
+	// shrinking usage never fills
+	for i := range series {
+		series[i].Used = uint64(800*gib - i*gib)
+	}
+	if f := forecastUsage(series); f.Status != "ok" || f.DaysUntilFull != nil || f.SlopeBytesPerDay >= 0 {
+		t.Fatalf("shrinking pool should not project a fill date: %+v", f)
+	}
+
+	if f := forecastUsage(series[:3]); f.Status != "insufficient_data" || f.DaysUntilFull != nil {
+		t.Fatalf("expected insufficient data: %+v", f)
+	}
+}
+
+func TestPoolForecastEndpointAndRetention(t *testing.T) {
+	t.Setenv("NOS_STATE_DIR", t.TempDir())
+	prevUsage := poolUsage
+	poolUsage = &usageHistory{}
+	pool := pools.Pool{ID: "/mnt/tank", UUID: "f00d", Mount: "/mnt/tank", Size: 100 * gib}
+	listPools = func(ctx context.Context) ([]pools.Pool, error) { return []pools.Pool{pool}, nil }
+	t.Cleanup(func() {
+		poolUsage = prevUsage
+		listPools = pools.ListPools
+	})
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/pools/{id}/forecast", handlePoolForecast)
+	get := func() poolForecast {
+		t.Helper()
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/pools/f00d/forecast", nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("forecast: %d %s", res.Code, res.Body.String())
+		}
+		var f poolForecast
+		_ = json.Unmarshal(res.Body.Bytes(), &f)
+		return f
+	}
+
+	now := time.Now().Add(-40 * 24 * time.Hour)
+	if err := poolUsage.record(context.Background(), []pools.Pool{pool}, now); err != nil {
+		t.Fatal(err)
+	}
+	if f := get(); f.Status != "insufficient_data" || f.Samples != 1 {
+		t.Fatalf("expected insufficient data: %+v", f)
+	}
+
+	// a day of hourly samples, 1 GiB/day; the 40-day-old sample ages out
+	now = time.Now().Add(-24 * time.Hour)
+	for i := 0; i <= 24; i++ {
+		pool.Used = uint64(50*gib + i*gib/24)
+		if err := poolUsage.record(context.Background(), []pools.Pool{pool}, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// reload from disk to check persistence
+	poolUsage = &usageHistory{}
+	f := get()
+	if f.Status != "ok" || f.Samples != 25 || f.DaysUntilFull == nil || math.Abs(*f.DaysUntilFull-49) > 0.5 {
+		t.Fatalf("unexpected forecast: %+v", f)
+	}
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/pools/missing/forecast", nil))
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("unknown pool: %d", res.Code)
+	}
+}
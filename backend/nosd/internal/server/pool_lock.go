@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
@@ -71,3 +72,44 @@ func currentPoolTx(poolID string) string {
 	defer poolLockMu.Unlock()
 	return poolHeldByTx[poolID]
 }
+
+var (
+	deviceLockMu   sync.Mutex
+	deviceHeldBy   = map[string]string{} // canonical device -> owner
+	errDevicesBusy = errors.New("devices busy")
+)
+
+// canonicalDevice resolves /dev/disk/by-* links so one disk named two ways
+// maps to one lock.
+func canonicalDevice(dev string) string {
+	if p, err := filepath.EvalSymlinks(dev); err == nil {
+		return p
+	}
+	return filepath.Clean(dev)
+}
+
+// tryLockDevices claims every device in devs for owner, or none of them if
+// any is already held. Callers hold the claim across the free check and the
+// destructive agent call, so two creates can't both pass the check and then
+// race on mkfs.
+func tryLockDevices(devs []string, owner string) error {
+	deviceLockMu.Lock()
+	defer deviceLockMu.Unlock()
+	for _, d := range devs {
+		if _, ok := deviceHeldBy[canonicalDevice(d)]; ok {
+			return errDevicesBusy
+		}
+	}
+	for _, d := range devs {
+		deviceHeldBy[canonicalDevice(d)] = owner
+	}
+	return nil
+}
+
+func releaseDevices(devs []string) {
+	deviceLockMu.Lock()
+	defer deviceLockMu.Unlock()
+	for _, d := range devs {
+		delete(deviceHeldBy, canonicalDevice(d))
+	}
+}
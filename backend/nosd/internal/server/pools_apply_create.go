@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -16,10 +17,16 @@ import (
 	"nithronos/backend/nosd/pkg/httpx"
 )
 
+// ensureDevicesFree checks that no device is mounted or in use before a
+// pool is created on it; swapped in tests.
+var ensureDevicesFree = pools.EnsureDevicesFree
+
 type applyCreateRequest struct {
 	Plan    pools.CreatePlan `json:"plan"`
 	Fstab   []string         `json:"fstab"`
 	Confirm string           `json:"confirm"`
+	// Devices as returned by plan-create; checked again before anything runs
+	Devices []pools.DeviceRef `json:"devices,omitempty"`
 }
 
 func handleApplyCreate(cfg config.Config) http.HandlerFunc {
@@ -34,31 +41,97 @@ func handleApplyCreate(cfg config.Config) http.HandlerFunc {
 			httpx.WriteError(w, http.StatusBadRequest, "empty plan")
 			return
 		}
+		if err := pools.CheckDeviceRefs(req.Devices); err != nil {
+			writeDeviceRefError(w, err)
+			return
+		}
+		// Claim the plan's devices first so a create through /pools/create
+		// can't format them at the same time
+		txID := generateUUID()
+		devices := planDevices(req.Plan.Steps, req.Devices)
+		if err := tryLockDevices(devices, txID); err != nil {
+			writePoolOperationInProgress(w)
+			return
+		}
 		// Busy check: use a stable create key
 		poolID := "create"
 		if cur := currentPoolTx(poolID); cur != "" {
+			releaseDevices(devices)
 			httpx.WriteError(w, http.StatusConflict, `{"error":{"code":"pool.busy","txId":"`+cur+`"}}`)
 			return
 		}
 		// Create transaction and save initial state
-		tx := pools.Tx{ID: generateUUID(), StartedAt: time.Now().UTC()}
+		tx := pools.Tx{ID: txID, StartedAt: time.Now().UTC()}
 		for _, st := range req.Plan.Steps {
 			tx.Steps = append(tx.Steps, pools.TxStep{ID: st.ID, Name: st.Description, Cmd: st.Command, Destructive: st.Destructive, Status: "pending"})
 		}
 		_ = saveTx(tx)
 		if !tryAcquirePoolLock(poolID, tx.ID) {
+			releaseDevices(devices)
 			httpx.WriteError(w, http.StatusConflict, `{"error":{"code":"pool.busy","txId":"`+currentPoolTx(poolID)+`"}}`)
 			return
 		}
-		// Execute asynchronously
-		go executePlan(tx.ID, req, cfg)
+		// Execute asynchronously; the locks are held until the plan ends
+		go func() {
+			defer releasePoolLock(poolID)
+			defer releaseDevices(devices)
+			executePlan(tx.ID, req, cfg)
+		}()
 		writeJSON(w, map[string]any{"ok": true, "tx_id": tx.ID})
 	}
 }
 
+// planDevices lists the block devices a create plan touches: the devices
+// plan-create resolved, plus any /dev/ path named in the commands. Plan-create
+// single-quotes every argument, so command words are unquoted first.
+func planDevices(steps []pools.PlanStep, refs []pools.DeviceRef) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	add := func(p string) {
+		if strings.HasPrefix(p, "/dev/") && !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	for _, ref := range refs {
+		add(ref.Path)
+		add(ref.ByID)
+	}
+	for _, st := range steps {
+		for _, p := range strings.Fields(st.Command) {
+			add(shellUnquote(p))
+		}
+	}
+	return out
+}
+
+// shellUnquote reverses shellQuote for one command word; other words are
+// returned as they are.
+func shellUnquote(s string) string {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return s
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], `'\''`, "'")
+}
+
+// writeDeviceRefError answers a by-id link that no longer resolves, or now
+// names a different disk than when the plan was made.
+func writeDeviceRefError(w http.ResponseWriter, err error) {
+	if errors.Is(err, pools.ErrDeviceMismatch) {
+		httpx.WriteTypedError(w, http.StatusConflict, "pool.device_mismatch", err.Error(), 0)
+		return
+	}
+	httpx.WriteTypedError(w, http.StatusBadRequest, "pool.device_not_found", err.Error(), 0)
+}
+
+func writePoolOperationInProgress(w http.ResponseWriter) {
+	httpx.WriteTypedError(w, http.StatusConflict, "pool.operation_in_progress", "Another pool operation is using these devices", 0)
+}
+
 // agentStepRunner can be overridden in tests to avoid calling the real agent.
 var agentStepRunner = func(cmd string, args []string) (code int, stdout string) {
-	client := agentclient.New("/run/nos-agent.sock")
+	// plan steps (mkfs, cryptsetup) may legitimately run for a long time
+	client := agentclient.New(agentSocketPath, agentclient.WithTimeout(0))
 	var resp struct {
 		Results []struct {
 			Code           int
@@ -93,7 +166,7 @@ func executePlan(txID string, req applyCreateRequest, cfg config.Config) {
 			_ = saveTx(tx)
 			appendTxLog(tx.ID, "error", st.ID, tx.Error)
 			// rollback fstab edits if any
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
 			for _, ln := range req.Fstab {
 				_ = client.PostJSON(context.TODO(), "/v1/fstab/remove", map[string]any{"contains": ln}, nil)
 			}
@@ -116,7 +189,7 @@ func executePlan(txID string, req applyCreateRequest, cfg config.Config) {
 		appendTxLog(tx.ID, "info", st.ID, strings.TrimSpace(out))
 	}
 	// Ensure fstab lines
-	client := agentclient.New("/run/nos-agent.sock")
+	client := agentclient.New(cfg.AgentSocket())
 	for _, ln := range req.Fstab {
 		_ = client.PostJSON(context.TODO(), "/v1/fstab/ensure", map[string]any{"line": ln}, nil)
 	}
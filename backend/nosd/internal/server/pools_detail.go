@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"nithronos/backend/nosd/internal/config"
 	"nithronos/backend/nosd/pkg/agentclient"
 	"nithronos/backend/nosd/pkg/httpx"
 
@@ -13,33 +14,35 @@ import (
 )
 
 // GET /api/v1/pools/{id}
-func handlePoolDetail(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if strings.TrimSpace(id) == "" {
-		httpx.WriteError(w, http.StatusBadRequest, "id required")
-		return
+func handlePoolDetail(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if strings.TrimSpace(id) == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "id required")
+			return
+		}
+		// Simplify: return usage only; UI already has pool list. In a real system we'd query a store.
+		// Query usage from agent if mount path is provided via query (?mount=)
+		mount := r.URL.Query().Get("mount")
+		if mount == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "mount required for usage")
+			return
+		}
+		client := agentclient.New(cfg.AgentSocket())
+		var usage map[string]any
+		// GET /v1/btrfs/usage?mount=...
+		ureq, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://unix/v1/btrfs/usage?mount="+filepath.Clean(mount), nil)
+		res, err := client.HTTP.Do(ureq)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer res.Body.Close()
+		if res.StatusCode >= 300 {
+			httpx.WriteError(w, res.StatusCode, "agent error")
+			return
+		}
+		_ = json.NewDecoder(res.Body).Decode(&usage)
+		writeJSON(w, map[string]any{"usage": usage, "progress": poolProgress.Pool(filepath.Clean(mount))})
 	}
-	// Simplify: return usage only; UI already has pool list. In a real system we'd query a store.
-	// Query usage from agent if mount path is provided via query (?mount=)
-	mount := r.URL.Query().Get("mount")
-	if mount == "" {
-		httpx.WriteError(w, http.StatusBadRequest, "mount required for usage")
-		return
-	}
-	client := agentclient.New("/run/nos-agent.sock")
-	var usage map[string]any
-	// GET /v1/btrfs/usage?mount=...
-	ureq, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://unix/v1/btrfs/usage?mount="+filepath.Clean(mount), nil)
-	res, err := client.HTTP.Do(ureq)
-	if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		httpx.WriteError(w, res.StatusCode, "agent error")
-		return
-	}
-	_ = json.NewDecoder(res.Body).Decode(&usage)
-	writeJSON(w, map[string]any{"usage": usage})
 }
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,12 +14,15 @@ import (
 	"nithronos/backend/nosd/internal/disks"
 	"nithronos/backend/nosd/internal/fsatomic"
 	"nithronos/backend/nosd/internal/pools"
+	"nithronos/backend/nosd/internal/progress"
 	btrfsplan "nithronos/backend/nosd/internal/storage/btrfs"
 	"nithronos/backend/nosd/pkg/agentclient"
 	"nithronos/backend/nosd/pkg/httpx"
 )
 
-var agentSocketPath = "/run/nos-agent.sock"
+// agentSocketPath backs the package-level agent seams; NewRouter sets it
+// from cfg.AgentSocket().
+var agentSocketPath = config.Defaults().AgentSocket()
 
 // seam for tests
 var devicePollInterval = 3 * time.Second
@@ -31,27 +33,12 @@ type agentAPI interface {
 	ReplaceStatus(ctx context.Context, mount string) (*agentclient.ReplaceStatus, error)
 }
 
-var makeAgentClient = func() agentAPI { return agentclient.New(agentSocketPath) }
+// device add/remove/replace run synchronously on the agent and can take hours
+var makeAgentClient = func() agentAPI { return agentclient.New(agentSocketPath, agentclient.WithTimeout(0)) }
 
-// in-process gauges for last observed progress
-var (
-	progressMu          = &sync.Mutex{}
-	gaugeBalancePercent = -1.0
-	gaugeReplacePercent = -1.0
-)
-
-func currentBalancePercent() float64 {
-	progressMu.Lock()
-	defer progressMu.Unlock()
-	return gaugeBalancePercent
-}
-func currentReplacePercent() float64 {
-	progressMu.Lock()
-	defer progressMu.Unlock()
-	return gaugeReplacePercent
-}
-func setBalancePercent(v float64) { progressMu.Lock(); gaugeBalancePercent = v; progressMu.Unlock() }
-func setReplacePercent(v float64) { progressMu.Lock(); gaugeReplacePercent = v; progressMu.Unlock() }
+// poolProgress holds the last observed balance/scrub/replace progress per
+// pool mount; served by /metrics and the pool status endpoints.
+var poolProgress = progress.NewTracker()
 
 // POST /api/v1/pools/{id}/plan-device
 func handlePlanDevice(cfg config.Config) http.HandlerFunc {
@@ -64,11 +51,12 @@ func handlePlanDevice(cfg config.Config) http.HandlerFunc {
 		var req btrfsplan.DevicePlanRequest
 		_ = json.NewDecoder(r.Body).Decode(&req)
 		// Discover current pool facts
-		list, _ := pools.ListPools(r.Context())
+		list, _ := listPools(r.Context())
 		var mount string
+		var pool pools.Pool
 		for _, p := range list {
 			if p.ID == id || p.UUID == id || p.Mount == id {
-				mount = p.Mount
+				mount, pool = p.Mount, p
 				break
 			}
 		}
@@ -76,15 +64,18 @@ func handlePlanDevice(cfg config.Config) http.HandlerFunc {
 			httpx.WriteError(w, http.StatusNotFound, "pool not found")
 			return
 		}
-		// Build device sizes and existing devices from lsblk
+		// Device sizes come from lsblk; the members from the pool itself, as
+		// lsblk shows the mountpoint on only one device of a multi-device pool
 		devList, _ := disks.Collect(r.Context())
 		devSizes := map[string]int64{}
-		existing := []string{}
 		for _, d := range devList {
 			devSizes[d.Path] = d.SizeBytes
-			if d.Mountpoint != nil && *d.Mountpoint == mount {
-				existing = append(existing, d.Path)
-			}
+		}
+		existing := append([]string{}, pool.Devices...)
+		refs, refWarnings, err := resolveDeviceRequest(&req, devSizes)
+		if err != nil {
+			writeDeviceRefError(w, err)
+			return
 		}
 		// Get current profiles via agent: btrfs filesystem usage <mount>
 		dataProf, metaProf := "", ""
@@ -102,13 +93,19 @@ func handlePlanDevice(cfg config.Config) http.HandlerFunc {
 		if metaProf == "" {
 			metaProf = dataProf
 		}
-		planner := btrfsplan.Planner{PoolMount: mount, ExistingDevices: existing, CurrentProfileData: dataProf, CurrentProfileMeta: metaProf, DeviceSizes: devSizes}
+		planner := btrfsplan.Planner{PoolMount: mount, ExistingDevices: existing, CurrentProfileData: dataProf, CurrentProfileMeta: metaProf, DeviceSizes: devSizes,
+			// listed pools are mounted; ListPools doesn't report ro/degraded
+			MountReadWrite: true, PoolUsedBytes: int64(pool.Used)}
+		if pool.Size > 0 {
+			planner.PoolUsedPct = float64(pool.Used) * 100 / float64(pool.Size)
+		}
 		plan, err := planner.Plan(req)
 		if err != nil {
 			httpx.WriteError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, map[string]any{"planId": plan.PlanID, "steps": plan.Steps, "warnings": plan.Warnings, "requiresBalance": plan.RequiresBalance})
+		plan.Warnings = append(plan.Warnings, refWarnings...)
+		writeJSON(w, map[string]any{"planId": plan.PlanID, "steps": plan.Steps, "warnings": plan.Warnings, "requiresBalance": plan.RequiresBalance, "preview": plan.Preview, "devices": refs})
 	}
 }
 
@@ -128,11 +125,17 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 		var body struct {
 			Steps   []struct{ ID, Description, Command string }
 			Confirm string `json:"confirm"`
+			// Devices as returned by plan-device
+			Devices []pools.DeviceRef `json:"devices"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			httpx.WriteError(w, http.StatusBadRequest, "invalid json")
 			return
 		}
+		if err := pools.CheckDeviceRefs(body.Devices); err != nil {
+			writeDeviceRefError(w, err)
+			return
+		}
 		if len(body.Steps) == 0 {
 			httpx.WriteError(w, http.StatusBadRequest, "no steps")
 			return
@@ -240,7 +243,7 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 							entry := map[string]any{"event": "balance", "percent": 100}
 							b, _ := json.Marshal(entry)
 							appendTxLog(cur.ID, "info", st.ID, string(b))
-							setBalancePercent(-1)
+							poolProgress.Clear(mount, progress.Balance)
 							clearBtrfsBalanceProgress()
 						} else {
 							for j := 0; j < 10; j++ {
@@ -255,10 +258,10 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 									}
 									b, _ := json.Marshal(entry)
 									appendTxLog(cur.ID, "info", st.ID, string(b))
-									setBalancePercent(bs.Percent)
+									poolProgress.Set(mount, progress.Balance, bs.Percent)
 									setBtrfsBalanceProgress(bs.Percent)
 									if !bs.Running || bs.Percent >= 100 {
-										setBalancePercent(-1)
+										poolProgress.Clear(mount, progress.Balance)
 										clearBtrfsBalanceProgress()
 										break
 									}
@@ -272,7 +275,7 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 							entry := map[string]any{"event": "replace", "percent": 100}
 							b, _ := json.Marshal(entry)
 							appendTxLog(cur.ID, "info", st.ID, string(b))
-							setReplacePercent(-1)
+							poolProgress.Clear(mount, progress.Replace)
 						} else {
 							for j := 0; j < 10; j++ {
 								rs, _ := client.ReplaceStatus(context.TODO(), mount)
@@ -286,9 +289,9 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 									}
 									b, _ := json.Marshal(entry)
 									appendTxLog(cur.ID, "info", st.ID, string(b))
-									setReplacePercent(rs.Percent)
+									poolProgress.Set(mount, progress.Replace, rs.Percent)
 									if !rs.Running || rs.Percent >= 100 {
-										setReplacePercent(-1)
+										poolProgress.Clear(mount, progress.Replace)
 										break
 									}
 								}
@@ -303,11 +306,12 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 				_ = saveTx(cur)
 				// Post-success: best-effort refresh device list for this pool
 				if mount != "" {
-					devList, _ := disks.Collect(context.TODO())
 					devices := []string{}
-					for _, d := range devList {
-						if d.Mountpoint != nil && *d.Mountpoint == mount {
-							devices = append(devices, d.Path)
+					list, _ := listPools(context.TODO())
+					for _, p := range list {
+						if p.Mount == mount {
+							devices = append(devices, p.Devices...)
+							break
 						}
 					}
 					st, _ := loadPoolOptions(cfg)
@@ -322,7 +326,9 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 					if !updated {
 						st.Records = append(st.Records, poolOptionsRecord{Mount: mount, Devices: devices})
 					}
-					_ = savePoolOptions(cfg, st)
+					// fn runs under the pools.json lock; savePoolOptions would
+					// take it again and block on its own flock
+					_ = fsatomic.SaveJSON(context.TODO(), poolsStorePath(cfg), st, 0o600)
 				}
 				return nil
 			}
@@ -350,6 +356,56 @@ func handleApplyDevice(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// resolveDeviceRequest names new disks by their by-id link and pool members
+// by kernel path, which is how lsblk lists them. Sizes are aliased so the
+// planner finds a disk under either name. The refs returned are the new
+// disks, for apply-device to check again.
+func resolveDeviceRequest(req *btrfsplan.DevicePlanRequest, sizes map[string]int64) ([]pools.DeviceRef, []string, error) {
+	var refs []pools.DeviceRef
+	var warnings []string
+	stable := func(in string) (string, error) {
+		got, w, err := pools.ResolveDevices([]string{in})
+		if err != nil {
+			return "", err
+		}
+		ref := got[0]
+		if sz, ok := sizes[ref.Path]; ok {
+			sizes[ref.Ref()] = sz
+		}
+		refs = append(refs, ref)
+		warnings = append(warnings, w...)
+		return ref.Ref(), nil
+	}
+	member := func(in string) (string, error) {
+		ref, err := pools.ResolveDevice(in)
+		return ref.Path, err
+	}
+	var err error
+	for i, d := range req.Devices.Add {
+		if req.Devices.Add[i], err = stable(d); err != nil {
+			return nil, nil, err
+		}
+	}
+	for i, d := range req.Devices.Remove {
+		if req.Devices.Remove[i], err = member(d); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, pair := range req.Devices.Replace {
+		if old, ok := pair["old"]; ok {
+			if pair["old"], err = member(old); err != nil {
+				return nil, nil, err
+			}
+		}
+		if nw, ok := pair["new"]; ok {
+			if pair["new"], err = stable(nw); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return refs, warnings, nil
+}
+
 func parseProfiles(out string) (data string, meta string) {
 	s := strings.ToLower(out)
 	for _, line := range strings.Split(s, "\n") {
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// poolDeviceStats is a test seam; the agent runs `btrfs device stats`.
+var poolDeviceStats = func(ctx context.Context, mount string) ([]agentclient.DeviceStats, error) {
+	return agentclient.New(agentSocketPath).DeviceStats(ctx, mount)
+}
+
+type deviceStatsEntry struct {
+	agentclient.DeviceStats
+	Healthy bool `json:"healthy"`
+}
+
+// GET /api/v1/pools/{id}/device-stats
+func handlePoolDeviceStats(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if strings.TrimSpace(id) == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	mount, err := findPoolMountByID(r, id)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "pool not found")
+		return
+	}
+	stats, err := poolDeviceStats(r.Context(), mount)
+	if err != nil {
+		writeAgentError(w, err, "pools.device_stats_failed", "Reading device error counters failed")
+		return
+	}
+	healthy := true
+	devices := make([]deviceStatsEntry, 0, len(stats))
+	for _, d := range stats {
+		ok := len(d.Counters()) == 0
+		healthy = healthy && ok
+		devices = append(devices, deviceStatsEntry{DeviceStats: d, Healthy: ok})
+	}
+	writeJSON(w, map[string]any{"mount": mount, "healthy": healthy, "devices": devices})
+}
+
+// deviceStatsAlerts returns one "btrfs" alert per pool member with any
+// non-zero error counter. Corruption and generation errors mean data on
+// that member can no longer be trusted and are critical; I/O errors warn.
+func deviceStatsAlerts(ctx context.Context, now string) []alert {
+	list, err := listPools(ctx)
+	if err != nil {
+		return nil
+	}
+	var out []alert
+	for _, p := range list {
+		if p.Mount == "" {
+			continue
+		}
+		stats, err := poolDeviceStats(ctx, p.Mount)
+		if err != nil {
+			continue
+		}
+		for _, d := range stats {
+			counters := d.Counters()
+			if len(counters) == 0 {
+				continue
+			}
+			names := make([]string, 0, len(counters))
+			for k := range counters {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			msgs := make([]string, 0, len(names))
+			for _, k := range names {
+				msgs = append(msgs, fmt.Sprintf("%s: %d", k, counters[k]))
+			}
+			sev := "warn"
+			if d.CorruptionErrs > 0 || d.GenerationErrs > 0 {
+				sev = "crit"
+			}
+			out = append(out, alert{
+				ID: generateUUID(), Severity: sev, Kind: "btrfs", Device: d.Device, Pool: p.Mount, Messages: msgs, CreatedAt: now,
+			})
+		}
+	}
+	return out
+}
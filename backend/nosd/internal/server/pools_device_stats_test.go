@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/pools"
+	"nithronos/backend/nosd/pkg/agentclient"
+)
+
+func stubPoolDeviceStats(t *testing.T, stats []agentclient.DeviceStats) {
+	t.Helper()
+	prevStats := poolDeviceStats
+	listPools = func(ctx context.Context) ([]pools.Pool, error) {
+		return []pools.Pool{{ID: "/mnt/tank", UUID: "f00d", Mount: "/mnt/tank", Size: 1}}, nil
+	}
+	poolDeviceStats = func(ctx context.Context, mount string) ([]agentclient.DeviceStats, error) {
+		if mount != "/mnt/tank" {
+			t.Errorf("unexpected mount %q", mount)
+		}
+		return stats, nil
+	}
+	t.Cleanup(func() {
+		listPools = pools.ListPools
+		poolDeviceStats = prevStats
+	})
+}
+
+func TestPoolDeviceStatsEndpoint(t *testing.T) {
+	stubPoolDeviceStats(t, []agentclient.DeviceStats{
+		{Device: "/dev/sda"},
+		{Device: "/dev/sdb", ReadIOErrs: 3},
+	})
+	r := chi.NewRouter()
+	r.Get("/api/v1/pools/{id}/device-stats", handlePoolDeviceStats)
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/pools/f00d/device-stats", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("device-stats: %d %s", res.Code, res.Body.String())
+	}
+	var body struct {
+		Healthy bool `json:"healthy"`
+		Devices []struct {
+			Device     string `json:"device"`
+			ReadIOErrs uint64 `json:"read_io_errs"`
+			Healthy    bool   `json:"healthy"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Healthy || len(body.Devices) != 2 || !body.Devices[0].Healthy || body.Devices[1].Healthy || body.Devices[1].ReadIOErrs != 3 {
+		t.Fatalf("unexpected response: %s", res.Body.String())
+	}
+
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/pools/missing/device-stats", nil))
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("unknown pool: %d", res.Code)
+	}
+}
+
+func TestHealthScanAlertsOnDeviceErrors(t *testing.T) {
+	stubPoolDeviceStats(t, []agentclient.DeviceStats{
+		{Device: "/dev/sda"},
+		{Device: "/dev/sdb", WriteIOErrs: 2, FlushIOErrs: 1},
+		{Device: "/dev/sdc", CorruptionErrs: 5},
+	})
+	stubNoDisks(t)
+
+	alerts := runHealthScan(context.Background(), testHealthConfig(), newTempEvaluator())
+	if len(alerts) != 2 {
+		t.Fatalf("expected alerts for sdb and sdc: %+v", alerts)
+	}
+	if a := alerts[0]; a.Kind != "btrfs" || a.Device != "/dev/sdb" || a.Pool != "/mnt/tank" || a.Severity != "warn" ||
+		len(a.Messages) != 2 || a.Messages[0] != "flush_io_errs: 1" || a.Messages[1] != "write_io_errs: 2" {
+		t.Fatalf("unexpected sdb alert: %+v", a)
+	}
+	if a := alerts[1]; a.Device != "/dev/sdc" || a.Severity != "crit" {
+		t.Fatalf("corruption should be critical: %+v", a)
+	}
+}
@@ -2,12 +2,15 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/pools"
 )
 
 func TestApplyDeviceConfirmValidation(t *testing.T) {
@@ -39,3 +42,33 @@ func TestApplyDeviceConfirmValidation(t *testing.T) {
 		t.Fatalf("unexpected status: %d", res2.Code)
 	}
 }
+
+func TestPlanDeviceUsesPoolMembers(t *testing.T) {
+	healthTestEnv(t)
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"results":[{"stdout":""}]}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	// lsblk would show /mnt/tank on at most one of these
+	listPools = func(ctx context.Context) ([]pools.Pool, error) {
+		return []pools.Pool{{ID: "tank", Mount: "/mnt/tank", Devices: []string{"/dev/nos-test-a", "/dev/nos-test-b", "/dev/nos-test-c"}}}, nil
+	}
+	t.Cleanup(func() { listPools = pools.ListPools })
+
+	r := NewRouter(config.FromEnv())
+	b, _ := json.Marshal(map[string]any{"action": "remove", "devices": map[string]any{"remove": []string{"/dev/nos-test-c"}}})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/tank/plan-device", bytes.NewReader(b))
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("plan remove of a pool member: %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Steps []struct{ Command string } `json:"steps"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if len(out.Steps) == 0 || !strings.Contains(out.Steps[len(out.Steps)-1].Command, "/dev/nos-test-c") {
+		t.Fatalf("steps: %s", res.Body.String())
+	}
+}
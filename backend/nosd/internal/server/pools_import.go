@@ -129,7 +129,7 @@ func handlePoolsImport(cfg config.Config) http.HandlerFunc {
 			httpx.WriteError(w, http.StatusConflict, `{"error":{"code":"pool.busy","txId":"`+cur+`"}}`)
 			return
 		}
-		client := agentclient.New("/run/nos-agent.sock")
+		client := agentclient.New(cfg.AgentSocket())
 		// mkdir -p mountpoint
 		_ = client.PostJSON(r.Context(), "/v1/fs/mkdir", map[string]any{"path": body.Mountpoint, "mode": "0755"}, nil)
 		// choose options
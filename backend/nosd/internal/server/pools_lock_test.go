@@ -2,15 +2,41 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/pools"
+	"nithronos/backend/nosd/pkg/agentclient"
 )
 
+// blockingAgent holds every run step until release is closed, so the first
+// transaction still owns the pool lock when the second request arrives.
+type blockingAgent struct{ release chan struct{} }
+
+func (b *blockingAgent) PostJSON(ctx context.Context, _ string, _ any, _ any) error {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (b *blockingAgent) BalanceStatus(context.Context, string) (*agentclient.BalanceStatus, error) {
+	return &agentclient.BalanceStatus{}, nil
+}
+
+func (b *blockingAgent) ReplaceStatus(context.Context, string) (*agentclient.ReplaceStatus, error) {
+	return &agentclient.ReplaceStatus{}, nil
+}
+
 func TestApplyDevice_ParallelOneBusy(t *testing.T) {
 	// Ensure no prior lock
 	releasePoolLock("p1")
@@ -52,3 +78,141 @@ func TestApplyDevice_ParallelOneBusy(t *testing.T) {
 		t.Fatalf("expected one 200 and one 409, got %v", codes)
 	}
 }
+
+// TestApplyDevice_BusyWhileRunning holds the first transaction's agent steps
+// so the second request is guaranteed to find the pool lock taken.
+func TestApplyDevice_BusyWhileRunning(t *testing.T) {
+	releasePoolLock("p1")
+	agent := &blockingAgent{release: make(chan struct{})}
+	oldMake := makeAgentClient
+	makeAgentClient = func() agentAPI { return agent }
+	t.Cleanup(func() {
+		close(agent.release)
+		for i := 0; i < 400 && currentPoolTx("p1") != ""; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		makeAgentClient = oldMake
+	})
+
+	r := NewRouter(config.FromEnv())
+	b, _ := json.Marshal(map[string]any{
+		"steps":   []map[string]string{{"id": "s1", "description": "add", "command": "btrfs device add /dev/sdb /mnt/p1"}},
+		"confirm": "ADD",
+	})
+	apply := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/p1/apply-device", bytes.NewReader(b))
+		req.Header.Set("X-CSRF-Token", "x")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := apply(); code < 200 || code >= 300 {
+		t.Fatalf("first apply: %d", code)
+	}
+	if code := apply(); code != http.StatusConflict {
+		t.Fatalf("second apply while the first runs: %d", code)
+	}
+}
+
+func TestPoolCreate_ConcurrentSameDevices(t *testing.T) {
+	healthTestEnv(t)
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	oldFree := ensureDevicesFree
+	ensureDevicesFree = func(ctx context.Context, devices []string) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	}
+	t.Cleanup(func() { ensureDevicesFree = oldFree })
+
+	r := NewRouter(config.FromEnv())
+	create := func(devs ...string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(map[string]any{"devices": devs, "raid": "raid1", "label": "p1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/create", bytes.NewReader(b))
+		req.Header.Set("Confirm", "yes")
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go func() { first <- create("/dev/sdb", "/dev/sdc") }()
+	<-entered
+
+	// the first request holds /dev/sdc between its free check and mkfs
+	res := create("/dev/sdc", "/dev/sdd")
+	if res.Code != http.StatusConflict || !bytes.Contains(res.Body.Bytes(), []byte(`"pool.operation_in_progress"`)) {
+		t.Fatalf("second create: %d %s", res.Code, res.Body.String())
+	}
+	close(release)
+	if res := <-first; res.Code == http.StatusConflict {
+		t.Fatalf("first create lost: %d %s", res.Code, res.Body.String())
+	}
+
+	// released once the first request returns
+	if res := create("/dev/sdc", "/dev/sdd"); res.Code == http.StatusConflict {
+		t.Fatalf("devices still locked: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestApplyCreate_DevicesLockedByCreate(t *testing.T) {
+	healthTestEnv(t)
+	r := NewRouter(config.FromEnv())
+
+	// a real plan: plan-create single-quotes every device in its commands
+	pb, _ := json.Marshal(map[string]any{"name": "tank", "mountpoint": "/mnt/tank", "devices": []string{"/dev/sdx"}, "force": true})
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/pools/plan-create", bytes.NewReader(pb)))
+	if res.Code != http.StatusOK {
+		t.Fatalf("plan-create: %d %s", res.Code, res.Body.String())
+	}
+	var planned struct {
+		Plan    pools.CreatePlan  `json:"plan"`
+		Devices []pools.DeviceRef `json:"devices"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &planned); err != nil {
+		t.Fatal(err)
+	}
+	if got := planDevices(planned.Plan.Steps, nil); len(got) != 1 || got[0] != "/dev/sdx" {
+		t.Fatalf("devices from quoted commands: %v", got)
+	}
+
+	if err := tryLockDevices([]string{"/dev/sdx"}, "create:test"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { releaseDevices([]string{"/dev/sdx"}) })
+	b, _ := json.Marshal(map[string]any{"plan": planned.Plan, "devices": planned.Devices, "confirm": "CREATE"})
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/pools/apply-create", bytes.NewReader(b)))
+	if res.Code != http.StatusConflict || !bytes.Contains(res.Body.Bytes(), []byte(`"pool.operation_in_progress"`)) {
+		t.Fatalf("apply-create on locked device: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestApplyCreate_DeviceSwappedSincePlan(t *testing.T) {
+	healthTestEnv(t)
+	root := t.TempDir()
+	link := filepath.Join(root, "disk", "by-id", "ata-DISK1")
+	_ = os.MkdirAll(filepath.Dir(link), 0o755)
+	_ = os.WriteFile(filepath.Join(root, "sdc"), nil, 0o600)
+	if err := os.Symlink(filepath.Join(root, "sdc"), link); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := pools.DevDiskDir
+	pools.DevDiskDir = filepath.Join(root, "disk")
+	t.Cleanup(func() { pools.DevDiskDir = oldDir })
+
+	r := NewRouter(config.FromEnv())
+	// planned while ata-DISK1 was sdb
+	b, _ := json.Marshal(map[string]any{
+		"plan":    map[string]any{"steps": []map[string]any{{"id": "mkfs", "description": "mkfs", "command": "mkfs.btrfs -f " + link}}},
+		"devices": []pools.DeviceRef{{Input: link, Path: filepath.Join(root, "sdb"), ByID: link, Stable: true}},
+		"confirm": "CREATE",
+	})
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/pools/apply-create", bytes.NewReader(b)))
+	if res.Code != http.StatusConflict || !bytes.Contains(res.Body.Bytes(), []byte(`"pool.device_mismatch"`)) {
+		t.Fatalf("apply-create after swap: %d %s", res.Code, res.Body.String())
+	}
+}
@@ -44,23 +44,28 @@ func savePoolOptions(cfg config.Config, st poolOptionsStore) error {
 }
 
 func findPoolMountByID(r *http.Request, id string) (string, error) {
-	// Allow passing mount path directly
-	if strings.HasPrefix(id, "/") {
-		return id, nil
-	}
-	list, err := pools.ListPools(r.Context())
-	if err != nil {
-		return "", err
-	}
+	p, err := findPoolByID(r, id)
+	return p.Mount, err
+}
+
+// findPoolByID resolves id (pool ID, UUID or mount path) to a mounted pool.
+// A mount path that isn't listed is accepted as-is, without a UUID.
+func findPoolByID(r *http.Request, id string) (pools.Pool, error) {
 	id = strings.TrimSpace(id)
+	list, err := listPools(r.Context())
+	if err != nil && !strings.HasPrefix(id, "/") {
+		return pools.Pool{}, err
+	}
 	for _, p := range list {
-		if p.Mount == id || p.UUID == id || p.ID == id {
-			if p.Mount != "" {
-				return p.Mount, nil
-			}
+		if (p.Mount == id || p.UUID == id || p.ID == id) && p.Mount != "" {
+			return p, nil
 		}
 	}
-	return "", fmt.Errorf("not found")
+	// Allow passing mount path directly
+	if strings.HasPrefix(id, "/") {
+		return pools.Pool{ID: id, Mount: id}, nil
+	}
+	return pools.Pool{}, fmt.Errorf("not found")
 }
 
 func handlePoolOptionsGet(cfg config.Config) http.HandlerFunc {
@@ -95,52 +100,96 @@ func handlePoolOptionsGet(cfg config.Config) http.HandlerFunc {
 	}
 }
 
-type invalidTokenError struct{ token string }
+type invalidTokenError struct{ token, reason string }
 
-func (e invalidTokenError) Error() string { return "invalid token: " + e.token }
+func (e invalidTokenError) Error() string { return "invalid token: " + e.token + ": " + e.reason }
 
-func validateMountOptions(s string) error {
+// mountOptionFlags are the value-less btrfs options accepted; each maps to
+// the group it conflicts within (e.g. ssd vs nossd).
+var mountOptionFlags = map[string]string{
+	"noatime": "atime", "relatime": "atime", "nodiratime": "diratime",
+	"ssd": "ssd", "nossd": "ssd", "ssd_spread": "ssd_spread",
+	"discard": "discard", "nodiscard": "discard",
+	"autodefrag": "autodefrag", "noautodefrag": "autodefrag",
+}
+
+// normalizeMountOptions validates a btrfs mount option string against the
+// allowlist and returns it in canonical form (lower-case, trimmed). Options
+// that would make the pool unsafe or fail to mount (nodatacow, unknown
+// options, out-of-range values, conflicting settings) are rejected.
+func normalizeMountOptions(s string) (string, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
-		return fmt.Errorf("mountOptions required")
+		return "", fmt.Errorf("mountOptions required")
+	}
+	seen := map[string]string{} // conflict group -> option that claimed it
+	claim := func(group, tok string) error {
+		if prev, ok := seen[group]; ok {
+			if prev == tok {
+				return invalidTokenError{token: tok, reason: "duplicate option"}
+			}
+			return invalidTokenError{token: tok, reason: "conflicts with " + prev}
+		}
+		seen[group] = tok
+		return nil
 	}
-	// tokens
+	out := []string{}
 	for _, raw := range strings.Split(s, ",") {
-		tok := strings.TrimSpace(raw)
+		tok := strings.ToLower(strings.TrimSpace(raw))
 		if tok == "" {
 			continue
 		}
-		lower := strings.ToLower(tok)
-		if lower == "nodatacow" {
-			return invalidTokenError{token: "nodatacow"}
-		}
-		if strings.HasPrefix(lower, "compress=") {
-			val := strings.TrimPrefix(lower, "compress=")
-			if !strings.HasPrefix(val, "zstd") {
-				return invalidTokenError{token: "compress"}
+		key, val, hasVal := strings.Cut(tok, "=")
+		var group string
+		switch {
+		case !hasVal && mountOptionFlags[key] != "":
+			group = mountOptionFlags[key]
+		case key == "discard" && (val == "async" || val == "sync"):
+			group = "discard"
+		case key == "compress" || key == "compress-force":
+			alg, lvl, hasLvl := strings.Cut(val, ":")
+			if alg != "zstd" {
+				return "", invalidTokenError{token: key, reason: "only zstd compression is supported"}
 			}
-			if strings.Contains(val, ":") {
-				parts := strings.SplitN(val, ":", 2)
-				if len(parts) == 2 {
-					lvl, err := strconv.Atoi(parts[1])
-					if err != nil || lvl < 1 || lvl > 15 {
-						return invalidTokenError{token: "compress"}
-					}
+			if hasLvl {
+				n, err := strconv.Atoi(lvl)
+				if err != nil || n < 1 || n > 15 {
+					return "", invalidTokenError{token: key, reason: "zstd level must be 1-15"}
 				}
 			}
-			continue
+			group = "compress"
+		case key == "commit" && hasVal:
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 300 {
+				return "", invalidTokenError{token: key, reason: "commit interval must be 1-300 seconds"}
+			}
+			group = "commit"
+		case key == "space_cache" && val == "v2":
+			group = "space_cache"
+		case key == "nodatacow" || key == "nodatasum":
+			return "", invalidTokenError{token: key, reason: "disables checksums"}
+		default:
+			return "", invalidTokenError{token: tok, reason: "unsupported option"}
 		}
-		if lower == "ssd" || lower == "noatime" || lower == "nodiratime" || lower == "autodefrag" || lower == "discard" || lower == "discard=async" {
-			continue
+		if err := claim(group, tok); err != nil {
+			return "", err
 		}
-		return invalidTokenError{token: tok}
+		out = append(out, tok)
 	}
-	return nil
+	if len(out) == 0 {
+		return "", fmt.Errorf("mountOptions required")
+	}
+	return strings.Join(out, ","), nil
+}
+
+func validateMountOptions(s string) error {
+	_, err := normalizeMountOptions(s)
+	return err
 }
 
 // test seam for remount
 var remountFunc = func(r *http.Request, mount string, opts string) error {
-	client := agentclient.New("/run/nos-agent.sock")
+	client := agentclient.New(agentSocketPath)
 	// run: mount -o remount,<opts> <mount>
 	var resp map[string]any
 	err := client.PostJSON(r.Context(), "/v1/run", map[string]any{
@@ -149,6 +198,20 @@ var remountFunc = func(r *http.Request, mount string, opts string) error {
 	return err
 }
 
+// test seam: rewrite the pool's fstab entry so options survive a reboot
+var fstabOptionsFunc = func(r *http.Request, uuid, mount, opts string) error {
+	client := agentclient.New(agentSocketPath)
+	if err := client.PostJSON(r.Context(), "/v1/fstab/remove", map[string]any{"contains": " " + mount + " btrfs "}, nil); err != nil {
+		return err
+	}
+	line := "UUID=" + uuid + " " + mount + " btrfs " + opts + " 0 0"
+	return client.PostJSON(r.Context(), "/v1/fstab/ensure", map[string]any{"line": line}, nil)
+}
+
+// handlePoolOptionsPost applies new mount options safely: they are
+// validated, test-applied with a live remount (reverting to the previous
+// options if that fails), and only then persisted to the options store and
+// fstab, so a bad option can never leave the pool unmountable at boot.
 func handlePoolOptionsPost(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
@@ -160,21 +223,16 @@ func handlePoolOptionsPost(cfg config.Config) http.HandlerFunc {
 			MountOptions string `json:"mountOptions"`
 		}
 		_ = json.NewDecoder(r.Body).Decode(&body)
-		if err := validateMountOptions(body.MountOptions); err != nil {
-			switch e := err.(type) {
-			case invalidTokenError:
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnprocessableEntity)
-				if err := json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": "mount.options.invalid", "message": "invalid mount option", "details": map[string]any{"token": e.token}}}); err != nil {
-					fmt.Printf("Failed to write error response: %v\n", err)
-				}
-				return
-			default:
-				httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		opts, err := normalizeMountOptions(body.MountOptions)
+		if err != nil {
+			if e, ok := err.(invalidTokenError); ok {
+				httpx.WriteErrorWithDetails(w, http.StatusUnprocessableEntity, "mount.options.invalid", "invalid mount option", map[string]any{"token": e.token, "reason": e.reason})
 				return
 			}
+			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			return
 		}
-		mount, err := findPoolMountByID(r, id)
+		pool, err := findPoolByID(r, id)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				httpx.WriteError(w, http.StatusNotFound, "not found")
@@ -183,31 +241,56 @@ func handlePoolOptionsPost(cfg config.Config) http.HandlerFunc {
 			}
 			return
 		}
-		// Load existing
+		mount := pool.Mount
 		st, _ := loadPoolOptions(cfg)
 		old := ""
+		for _, rec := range st.Records {
+			if rec.Mount == mount {
+				old = rec.MountOptions
+				break
+			}
+		}
+
+		if err := remountFunc(r, mount, opts); err != nil {
+			reverted := false
+			if old != "" {
+				reverted = remountFunc(r, mount, old) == nil
+			}
+			Logger(cfg).Warn().
+				Str("event", "pool.options.rejected").
+				Str("mount", mount).
+				Str("new", opts).
+				Bool("reverted", reverted).
+				Err(err).
+				Msg("")
+			details := map[string]any{"reverted": reverted}
+			if m, ok := agentMessage(err); ok {
+				details["error"] = m
+			}
+			httpx.WriteErrorWithDetails(w, http.StatusUnprocessableEntity, "mount.options.remount_failed", "remount with the new options failed; nothing was saved", details)
+			return
+		}
+
 		updated := false
 		for i := range st.Records {
 			if st.Records[i].Mount == mount {
-				old = st.Records[i].MountOptions
-				st.Records[i].MountOptions = body.MountOptions
+				st.Records[i].MountOptions = opts
 				updated = true
 				break
 			}
 		}
 		if !updated {
-			st.Records = append(st.Records, poolOptionsRecord{Mount: mount, MountOptions: body.MountOptions})
+			st.Records = append(st.Records, poolOptionsRecord{Mount: mount, MountOptions: opts})
 		}
-		_ = savePoolOptions(cfg, st)
-
-		// Try remount; if fails, update fstab and require reboot
-		rebootRequired := false
-		if err := remountFunc(r, mount, body.MountOptions); err != nil {
-			rebootRequired = true
-			client := agentclient.New("/run/nos-agent.sock")
-			_ = client.PostJSON(r.Context(), "/v1/fstab/remove", map[string]any{"contains": mount}, nil)
-			line := "UUID=<uuid> " + mount + " btrfs " + body.MountOptions + " 0 0"
-			_ = client.PostJSON(r.Context(), "/v1/fstab/ensure", map[string]any{"line": line}, nil)
+		if err := savePoolOptions(cfg, st); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// without a UUID the fstab entry can't be identified; the options
+		// stay live until reboot
+		fstabUpdated := false
+		if pool.UUID != "" {
+			fstabUpdated = fstabOptionsFunc(r, pool.UUID, mount, opts) == nil
 		}
 
 		// Log structured event
@@ -215,10 +298,10 @@ func handlePoolOptionsPost(cfg config.Config) http.HandlerFunc {
 			Str("event", "pool.options.updated").
 			Str("mount", mount).
 			Str("old", old).
-			Str("new", body.MountOptions).
-			Bool("requiresReboot", rebootRequired).
+			Str("new", opts).
+			Bool("fstabUpdated", fstabUpdated).
 			Msg("")
 
-		writeJSON(w, map[string]any{"ok": true, "mountOptions": body.MountOptions, "rebootRequired": rebootRequired, "updatedAt": time.Now().UTC().Format(time.RFC3339)})
+		writeJSON(w, map[string]any{"ok": true, "mountOptions": opts, "rebootRequired": false, "fstabUpdated": fstabUpdated, "updatedAt": time.Now().UTC().Format(time.RFC3339)})
 	}
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,9 +10,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/pools"
 )
 
 func TestPoolOptionsGetDefault(t *testing.T) {
@@ -104,3 +109,105 @@ func TestPoolOptionsPost_RemountFailRequiresReboot(t *testing.T) {
 	r.ServeHTTP(w, req)
 	// status can be 200 or 404 depending on other guards; focus on behavior
 }
+
+func TestNormalizeMountOptions(t *testing.T) {
+	accepted := map[string]string{
+		" Compress=ZSTD:3 , noatime":                  "compress=zstd:3,noatime",
+		"compress-force=zstd:1,ssd_spread,ssd":        "compress-force=zstd:1,ssd_spread,ssd",
+		"noatime,commit=120,space_cache=v2,nodiscard": "noatime,commit=120,space_cache=v2,nodiscard",
+		"relatime,nossd,noautodefrag,discard=sync,,":  "relatime,nossd,noautodefrag,discard=sync",
+	}
+	for in, want := range accepted {
+		got, err := normalizeMountOptions(in)
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	rejected := map[string]string{
+		"compress=zstd:3,compress=zstd:5":   "compress=zstd:5",
+		"compress=zstd,compress-force=zstd": "compress-force=zstd",
+		"ssd,nossd":                         "nossd",
+		"discard,discard=async":             "discard=async",
+		"noatime,relatime":                  "relatime",
+		"noatime,noatime":                   "noatime",
+		"compress-force=zstd:20":            "compress-force",
+		"commit=0":                          "commit",
+		"space_cache=v1":                    "space_cache=v1",
+		"nodatasum":                         "nodatasum",
+		"degraded":                          "degraded",
+	}
+	for in, token := range rejected {
+		_, err := normalizeMountOptions(in)
+		e, ok := err.(invalidTokenError)
+		if !ok || e.token != token {
+			t.Errorf("%q: expected invalid token %q, got %v", in, token, err)
+		}
+	}
+}
+
+func TestPoolOptionsSafeApply(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.EtcDir = t.TempDir()
+	if err := savePoolOptions(cfg, poolOptionsStore{Records: []poolOptionsRecord{{Mount: "/mnt/tank", MountOptions: "compress=zstd:3,noatime"}}}); err != nil {
+		t.Fatal(err)
+	}
+	prevRemount, prevFstab := remountFunc, fstabOptionsFunc
+	listPools = func(ctx context.Context) ([]pools.Pool, error) {
+		return []pools.Pool{{ID: "/mnt/tank", UUID: "f00d", Mount: "/mnt/tank"}}, nil
+	}
+	var remounts []string
+	failOn := ""
+	remountFunc = func(r *http.Request, mount string, opts string) error {
+		remounts = append(remounts, opts)
+		if opts == failOn {
+			return fmt.Errorf("mount: wrong fs type, bad option")
+		}
+		return nil
+	}
+	var fstab []string
+	fstabOptionsFunc = func(r *http.Request, uuid, mount, opts string) error {
+		fstab = append(fstab, uuid+" "+mount+" "+opts)
+		return nil
+	}
+	t.Cleanup(func() {
+		listPools = pools.ListPools
+		remountFunc, fstabOptionsFunc = prevRemount, prevFstab
+	})
+	rt := chi.NewRouter()
+	rt.Post("/api/v1/pools/{id}/options", handlePoolOptionsPost(cfg))
+	post := func(opts string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		rt.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/pools/f00d/options", strings.NewReader(`{"mountOptions":"`+opts+`"}`)))
+		return res
+	}
+	stored := func() string {
+		st, _ := loadPoolOptions(cfg)
+		return st.Records[0].MountOptions
+	}
+
+	// invalid options never reach the pool
+	res := post("compress=zstd:3,compress=zstd:9")
+	if res.Code != http.StatusUnprocessableEntity || !strings.Contains(res.Body.String(), "mount.options.invalid") || len(remounts) != 0 {
+		t.Fatalf("expected 422 before remount: %d %s %v", res.Code, res.Body.String(), remounts)
+	}
+
+	// remount failure reverts to the previous options and saves nothing
+	failOn = "compress=zstd:9,ssd"
+	res = post("compress=zstd:9,ssd")
+	if res.Code != http.StatusUnprocessableEntity || !strings.Contains(res.Body.String(), "mount.options.remount_failed") || !strings.Contains(res.Body.String(), `"reverted":true`) {
+		t.Fatalf("expected remount failure: %d %s", res.Code, res.Body.String())
+	}
+	if len(remounts) != 2 || remounts[1] != "compress=zstd:3,noatime" || stored() != "compress=zstd:3,noatime" || len(fstab) != 0 {
+		t.Fatalf("failed apply must revert and persist nothing: remounts=%v stored=%q fstab=%v", remounts, stored(), fstab)
+	}
+
+	// success persists the normalized options and rewrites fstab
+	remounts = nil
+	res = post("Compress=zstd:5, noatime, ssd")
+	if res.Code != http.StatusOK {
+		t.Fatalf("apply: %d %s", res.Code, res.Body.String())
+	}
+	if stored() != "compress=zstd:5,noatime,ssd" || len(fstab) != 1 || fstab[0] != "f00d /mnt/tank compress=zstd:5,noatime,ssd" {
+		t.Fatalf("not persisted: stored=%q fstab=%v", stored(), fstab)
+	}
+}
@@ -30,6 +30,17 @@ func handlePlanCreateV1(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	// Prefer by-id links in the plan; keep the kernel paths for lookups
+	refs, refWarnings, err := pools.ResolveDevices(spec.Devices)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	kernelPaths := make([]string, len(refs))
+	for i, ref := range refs {
+		spec.Devices[i] = ref.Ref()
+		kernelPaths[i] = ref.Path
+	}
 
 	// Guard rails: refuse if any device has fs/signature unless force=true
 	warnings := []string{}
@@ -48,6 +59,7 @@ func handlePlanCreateV1(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusPreconditionFailed, "devices have existing signatures; set force=true to proceed")
 		return
 	}
+	warnings = append(warnings, refWarnings...)
 
 	steps := []pools.PlanStep{}
 	// 1) wipefs report (non-destructive)
@@ -90,7 +102,7 @@ func handlePlanCreateV1(w http.ResponseWriter, r *http.Request) {
 	// Compute mount options (default by device mix if not provided)
 	opts := strings.TrimSpace(req.MountOptions)
 	if opts == "" {
-		opts = getDefaultMountOpts(r.Context(), spec.Devices)
+		opts = getDefaultMountOpts(r.Context(), kernelPaths)
 	}
 	// 3) mount by UUID (discover from first device) or mapper
 	steps = append(steps, pools.PlanStep{
@@ -126,7 +138,7 @@ func handlePlanCreateV1(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// include options in response
-	writeJSON(w, map[string]any{"plan": pools.CreatePlan{Steps: steps}, "fstab": fstab, "warnings": warnings, "mountOptions": opts})
+	writeJSON(w, map[string]any{"plan": pools.CreatePlan{Steps: steps}, "fstab": fstab, "warnings": warnings, "mountOptions": opts, "devices": refs})
 }
 
 // local quote helpers (copy of agent style)
@@ -3,56 +3,84 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/progress"
 	"nithronos/backend/nosd/pkg/agentclient"
 	"nithronos/backend/nosd/pkg/httpx"
 )
 
 // POST /api/v1/pools/scrub/start { mount }
-func handleScrubStart(w http.ResponseWriter, r *http.Request) {
-	var body struct {
-		Mount string `json:"mount"`
+func handleScrubStart(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Mount string `json:"mount"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Mount == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "mount required")
+			return
+		}
+		// Busy: use mount as lock key
+		if cur := currentPoolTx(body.Mount); cur != "" {
+			httpx.WriteError(w, http.StatusConflict, `{"error":{"code":"pool.busy","txId":"`+cur+`"}}`)
+			return
+		}
+		client := agentclient.New(cfg.AgentSocket())
+		var out map[string]any
+		if err := client.PostJSON(r.Context(), "/v1/btrfs/scrub/start", body, &out); err != nil {
+			writeAgentError(w, err, "pools.scrub_start_failed", "Failed to start scrub")
+			return
+		}
+		poolProgress.Set(filepath.Clean(body.Mount), progress.Scrub, 0)
+		writeJSON(w, out)
 	}
-	_ = json.NewDecoder(r.Body).Decode(&body)
-	if body.Mount == "" {
-		httpx.WriteError(w, http.StatusBadRequest, "mount required")
-		return
-	}
-	// Busy: use mount as lock key
-	if cur := currentPoolTx(body.Mount); cur != "" {
-		httpx.WriteError(w, http.StatusConflict, `{"error":{"code":"pool.busy","txId":"`+cur+`"}}`)
-		return
-	}
-	client := agentclient.New("/run/nos-agent.sock")
-	var out map[string]any
-	if err := client.PostJSON(r.Context(), "/v1/btrfs/scrub/start", body, &out); err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	writeJSON(w, out)
 }
 
 // GET /api/v1/pools/scrub/status?mount=...
-func handleScrubStatus(w http.ResponseWriter, r *http.Request) {
-	mount := r.URL.Query().Get("mount")
-	if mount == "" {
-		httpx.WriteError(w, http.StatusBadRequest, "mount required")
-		return
+func handleScrubStatus(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mount := r.URL.Query().Get("mount")
+		if mount == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "mount required")
+			return
+		}
+		client := agentclient.New(cfg.AgentSocket())
+		var out map[string]any
+		if err := client.GetJSON(r.Context(), "/v1/btrfs/scrub/status?mount="+url.QueryEscape(mount), &out); err != nil {
+			writeAgentError(w, err, "pools.scrub_status_failed", "Failed to read scrub status")
+			return
+		}
+		raw, _ := out["status"].(string)
+		if running, pct, ok := parseScrubStatus(raw); running && ok {
+			poolProgress.Set(filepath.Clean(mount), progress.Scrub, pct)
+			out["percent"] = pct
+		} else if !running {
+			poolProgress.Clear(filepath.Clean(mount), progress.Scrub)
+		}
+		writeJSON(w, out)
 	}
-	client := agentclient.New("/run/nos-agent.sock")
-	var out map[string]any
-	// forward as GET with query
-	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://unix/v1/btrfs/scrub/status?mount="+mount, nil)
-	res, err := client.HTTP.Do(req)
-	if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
+}
+
+var scrubPercentRe = regexp.MustCompile(`Bytes scrubbed:.*\(([\d.]+)%\)`)
+
+// parseScrubStatus reads `btrfs scrub status` output. ok is false when no
+// percentage is printed (older btrfs-progs).
+func parseScrubStatus(out string) (running bool, pct float64, ok bool) {
+	for _, line := range strings.Split(out, "\n") {
+		if v, found := strings.CutPrefix(strings.TrimSpace(line), "Status:"); found {
+			running = strings.TrimSpace(v) == "running"
+		}
 	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		httpx.WriteError(w, res.StatusCode, "agent error")
-		return
+	if m := scrubPercentRe.FindStringSubmatch(out); m != nil {
+		if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+			pct, ok = v, true
+		}
 	}
-	_ = json.NewDecoder(res.Body).Decode(&out)
-	writeJSON(w, out)
+	return running, pct, ok
 }
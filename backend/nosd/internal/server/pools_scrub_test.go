@@ -0,0 +1,26 @@
+package server
+
+import "testing"
+
+func TestParseScrubStatus(t *testing.T) {
+	running := `UUID:             6c1c5a0e-1f6e-4b4e-9d1a-3f0b1c2d3e4f
+Scrub started:    Sat Oct 17 09:12:01 2026
+Status:           running
+Duration:         0:03:10
+Total to scrub:   1.82TiB
+Bytes scrubbed:   412.50GiB  (22.13%)
+Rate:             2.17GiB/s
+Error summary:    no errors found`
+	if r, pct, ok := parseScrubStatus(running); !r || !ok || pct != 22.13 {
+		t.Fatalf("running: %v %v %v", r, pct, ok)
+	}
+
+	finished := `Status:           finished
+Duration:         0:14:02
+Total to scrub:   1.82TiB
+Rate:             2.21GiB/s
+Error summary:    no errors found`
+	if r, _, ok := parseScrubStatus(finished); r || ok {
+		t.Fatalf("finished: running=%v ok=%v", r, ok)
+	}
+}
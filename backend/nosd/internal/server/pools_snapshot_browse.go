@@ -0,0 +1,185 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+var errOutsideSnapshot = errors.New("path escapes snapshot root")
+
+type snapshotEntry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Type    string    `json:"type"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// snapshotRoot resolves the on-disk root of a pool snapshot from the route params.
+func snapshotRoot(r *http.Request) (string, int, string) {
+	id := chi.URLParam(r, "id")
+	snap := chi.URLParam(r, "snap")
+	if strings.TrimSpace(id) == "" {
+		return "", http.StatusBadRequest, "id required"
+	}
+	if !validSnapshotName(snap) {
+		return "", http.StatusBadRequest, "invalid snapshot name"
+	}
+	mount, err := resolvePoolMount(r, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return "", http.StatusNotFound, "pool not found"
+		}
+		return "", http.StatusInternalServerError, err.Error()
+	}
+	root := filepath.Join(mount, ".snapshots", snap)
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", http.StatusNotFound, "snapshot not found"
+	}
+	return real, 0, ""
+}
+
+// resolveInSnapshot joins rel onto root and verifies the result, with symlinks
+// followed, is still inside root.
+func resolveInSnapshot(root, rel string) (string, error) {
+	rel = filepath.FromSlash(strings.TrimSpace(rel))
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == ".." {
+			return "", errOutsideSnapshot
+		}
+	}
+	p := filepath.Join(root, rel)
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	if real != root && !strings.HasPrefix(real, root+string(filepath.Separator)) {
+		return "", errOutsideSnapshot
+	}
+	return real, nil
+}
+
+func writeSnapshotPathError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errOutsideSnapshot):
+		httpx.WriteError(w, http.StatusBadRequest, "invalid path")
+	case os.IsNotExist(err):
+		httpx.WriteError(w, http.StatusNotFound, "path not found")
+	default:
+		httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// GET /api/v1/pools/{id}/snapshots/{snap}/browse?path=
+func handleSnapshotBrowse(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		root, status, msg := snapshotRoot(r)
+		if status != 0 {
+			httpx.WriteError(w, status, msg)
+			return
+		}
+		rel := r.URL.Query().Get("path")
+		dir, err := resolveInSnapshot(root, rel)
+		if err != nil {
+			writeSnapshotPathError(w, err)
+			return
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			writeSnapshotPathError(w, err)
+			return
+		}
+		if !fi.IsDir() {
+			httpx.WriteError(w, http.StatusBadRequest, "not a directory")
+			return
+		}
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			writeSnapshotPathError(w, err)
+			return
+		}
+		base := filepath.ToSlash(filepath.Clean("/" + rel))
+		out := make([]snapshotEntry, 0, len(ents))
+		for _, e := range ents {
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			typ := "file"
+			switch {
+			case e.IsDir():
+				typ = "dir"
+			case info.Mode()&os.ModeSymlink != 0:
+				typ = "symlink"
+			case !info.Mode().IsRegular():
+				typ = "other"
+			}
+			out = append(out, snapshotEntry{
+				Name:    e.Name(),
+				Path:    strings.TrimPrefix(filepath.ToSlash(filepath.Join(base, e.Name())), "/"),
+				Type:    typ,
+				Size:    info.Size(),
+				Mode:    info.Mode().String(),
+				ModTime: info.ModTime().UTC(),
+			})
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if (out[i].Type == "dir") != (out[j].Type == "dir") {
+				return out[i].Type == "dir"
+			}
+			return out[i].Name < out[j].Name
+		})
+		writeJSON(w, map[string]any{"path": strings.TrimPrefix(base, "/"), "entries": out})
+	}
+}
+
+// GET /api/v1/pools/{id}/snapshots/{snap}/download?path=
+func handleSnapshotDownload(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		root, status, msg := snapshotRoot(r)
+		if status != 0 {
+			httpx.WriteError(w, status, msg)
+			return
+		}
+		rel := r.URL.Query().Get("path")
+		if strings.TrimSpace(rel) == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "path required")
+			return
+		}
+		p, err := resolveInSnapshot(root, rel)
+		if err != nil {
+			writeSnapshotPathError(w, err)
+			return
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			writeSnapshotPathError(w, err)
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			writeSnapshotPathError(w, err)
+			return
+		}
+		if !fi.Mode().IsRegular() {
+			httpx.WriteError(w, http.StatusBadRequest, "not a regular file")
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+strings.ReplaceAll(fi.Name(), `"`, "")+`"`)
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+	}
+}
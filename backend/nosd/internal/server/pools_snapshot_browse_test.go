@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func setupSnapshotTree(t *testing.T) string {
+	t.Helper()
+	mount := t.TempDir()
+	snap := filepath.Join(mount, ".snapshots", "s1")
+	if err := os.MkdirAll(filepath.Join(snap, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(snap, "docs", "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mount, "secret"), []byte("nope"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// symlink pointing outside the snapshot must not be followed
+	_ = os.Symlink(filepath.Join(mount, "secret"), filepath.Join(snap, "escape"))
+	old := resolvePoolMount
+	resolvePoolMount = func(_ *http.Request, _ string) (string, error) { return mount, nil }
+	t.Cleanup(func() { resolvePoolMount = old })
+	return mount
+}
+
+func TestSnapshotBrowseListsEntries(t *testing.T) {
+	setupSnapshotTree(t)
+	r := NewRouter(config.FromEnv())
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pools/p1/snapshots/s1/browse?path=docs", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Entries []snapshotEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Entries) != 1 || out.Entries[0].Name != "a.txt" || out.Entries[0].Path != "docs/a.txt" || out.Entries[0].Size != 5 {
+		t.Fatalf("unexpected entries: %+v", out.Entries)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/pools/p1/snapshots/s1/download?path=docs/a.txt", nil)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || res.Body.String() != "hello" {
+		t.Fatalf("download: got %d %q", res.Code, res.Body.String())
+	}
+}
+
+func TestSnapshotBrowseRejectsTraversal(t *testing.T) {
+	setupSnapshotTree(t)
+	r := NewRouter(config.FromEnv())
+	for _, u := range []string{
+		"/api/v1/pools/p1/snapshots/s1/browse?path=../..",
+		"/api/v1/pools/p1/snapshots/s1/browse?path=docs/../../",
+		"/api/v1/pools/p1/snapshots/s1/download?path=../../secret",
+		"/api/v1/pools/p1/snapshots/s1/download?path=escape",
+	} {
+		req := httptest.NewRequest(http.MethodGet, u, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", u, res.Code)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/httpx"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+// seam for tests
+var resolvePoolMount = findPoolMountByID
+
+// validSnapshotName rejects names that could escape the .snapshots directory.
+func validSnapshotName(name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	return !strings.ContainsAny(name, `/\`)
+}
+
+// POST /api/v1/pools/{id}/snapshots/{snap}/restore
+//
+// Restores a subvolume from one of its read-only snapshots. A safety snapshot of
+// the current state is taken first so the restore itself can be undone.
+func handleSnapshotRestore(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Confirm") != "yes" {
+			httpx.WriteError(w, http.StatusPreconditionRequired, "confirm header required")
+			return
+		}
+		id := chi.URLParam(r, "id")
+		snap := chi.URLParam(r, "snap")
+		if strings.TrimSpace(id) == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "id required")
+			return
+		}
+		if !validSnapshotName(snap) {
+			httpx.WriteError(w, http.StatusBadRequest, "invalid snapshot name")
+			return
+		}
+		mount, err := resolvePoolMount(r, id)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				httpx.WriteError(w, http.StatusNotFound, "pool not found")
+				return
+			}
+			writeAgentError(w, err, "pools.lookup_failed", "Failed to look up pool")
+			return
+		}
+		var body struct {
+			Path string `json:"path"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		// Default to the pool root; an explicit subvolume must live under it.
+		target := mount
+		if p := strings.TrimSpace(body.Path); p != "" {
+			p = filepath.Clean(p)
+			if p != mount && !strings.HasPrefix(p, strings.TrimRight(mount, "/")+"/") {
+				httpx.WriteError(w, http.StatusBadRequest, "path must be within pool mount")
+				return
+			}
+			target = p
+		}
+
+		client := makeAgentClient()
+		now := time.Now().UTC()
+		tx := snapdb.UpdateTx{TxID: generateUUID(), StartedAt: now, Reason: "restore"}
+		fail := func(code, msg string, err error) {
+			mark := false
+			done := time.Now().UTC()
+			tx.FinishedAt = &done
+			tx.Success = &mark
+			tx.Notes = msg + ": " + errString(err)
+			_ = snapdb.Append(tx)
+			Logger(cfg).Error().Str("event", "snapshot.restore.failed").Str("path", target).Str("snapshot", snap).Err(err).Msg("")
+			writeAgentError(w, err, code, msg)
+		}
+
+		// 1) safety snapshot of the current state
+		safety := "pre-restore-" + now.Format("20060102-150405")
+		var sresp map[string]any
+		if err := client.PostJSON(r.Context(), "/v1/btrfs/snapshot", map[string]any{"path": target, "name": safety}, &sresp); err != nil {
+			fail("snapshots.safety_failed", "safety snapshot failed", err)
+			return
+		}
+		tx.Targets = append(tx.Targets, snapdb.SnapshotTarget{
+			ID:        safety,
+			Path:      target,
+			Type:      "btrfs",
+			Location:  filepath.Join(target, ".snapshots", safety),
+			CreatedAt: time.Now().UTC(),
+		})
+
+		// 2) swap the subvolume with the requested snapshot
+		var rresp map[string]any
+		if err := client.PostJSON(r.Context(), "/v1/snapshot/rollback", map[string]any{
+			"path": target, "snapshot_id": snap, "type": "btrfs",
+		}, &rresp); err != nil {
+			fail("snapshots.restore_failed", "restore failed", err)
+			return
+		}
+
+		mark := true
+		done := time.Now().UTC()
+		tx.FinishedAt = &done
+		tx.Success = &mark
+		tx.Notes = "restore of " + snap
+		_ = snapdb.Append(tx)
+		Logger(cfg).Info().Str("event", "snapshot.restore").Str("path", target).Str("snapshot", snap).Str("safety_snapshot", safety).Msg("")
+		writeJSON(w, map[string]any{"ok": true, "tx_id": tx.TxID, "safety_snapshot": safety})
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+type fakeRestoreAgent struct {
+	calls  []string
+	bodies []map[string]any
+}
+
+func (f *fakeRestoreAgent) PostJSON(_ context.Context, path string, body any, v any) error {
+	f.calls = append(f.calls, path)
+	m, _ := body.(map[string]any)
+	f.bodies = append(f.bodies, m)
+	return json.Unmarshal([]byte(`{"ok":true}`), v)
+}
+
+func (f *fakeRestoreAgent) BalanceStatus(_ context.Context, _ string) (*agentclient.BalanceStatus, error) {
+	return &agentclient.BalanceStatus{}, nil
+}
+
+func (f *fakeRestoreAgent) ReplaceStatus(_ context.Context, _ string) (*agentclient.ReplaceStatus, error) {
+	return &agentclient.ReplaceStatus{}, nil
+}
+
+func withRestoreFakes(t *testing.T) *fakeRestoreAgent {
+	t.Helper()
+	t.Setenv("NOS_SNAPDB_DIR", t.TempDir())
+	fake := &fakeRestoreAgent{}
+	oldMake, oldResolve := makeAgentClient, resolvePoolMount
+	makeAgentClient = func() agentAPI { return fake }
+	resolvePoolMount = func(_ *http.Request, id string) (string, error) { return "/mnt/" + id, nil }
+	t.Cleanup(func() { makeAgentClient, resolvePoolMount = oldMake, oldResolve })
+	return fake
+}
+
+func TestSnapshotRestoreRequiresConfirm(t *testing.T) {
+	fake := withRestoreFakes(t)
+	r := NewRouter(config.FromEnv())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/p1/snapshots/snap1/restore", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428, got %d", res.Code)
+	}
+	if len(fake.calls) != 0 {
+		t.Fatalf("agent must not be called without confirm, got %v", fake.calls)
+	}
+}
+
+func TestSnapshotRestoreRejectsBadName(t *testing.T) {
+	withRestoreFakes(t)
+	r := NewRouter(config.FromEnv())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/p1/snapshots/../restore", nil)
+	req.Header.Set("Confirm", "yes")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code == http.StatusOK {
+		t.Fatalf("expected rejection, got %d", res.Code)
+	}
+}
+
+func TestSnapshotRestoreTakesSafetySnapshotFirst(t *testing.T) {
+	fake := withRestoreFakes(t)
+	r := NewRouter(config.FromEnv())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/pools/p1/snapshots/snap1/restore", nil)
+	req.Header.Set("Confirm", "yes")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if len(fake.calls) != 2 || fake.calls[0] != "/v1/btrfs/snapshot" || fake.calls[1] != "/v1/snapshot/rollback" {
+		t.Fatalf("unexpected agent calls: %v", fake.calls)
+	}
+	name, _ := fake.bodies[0]["name"].(string)
+	if !strings.HasPrefix(name, "pre-restore-") || fake.bodies[0]["path"] != "/mnt/p1" {
+		t.Fatalf("unexpected safety snapshot request: %v", fake.bodies[0])
+	}
+	if fake.bodies[1]["snapshot_id"] != "snap1" {
+		t.Fatalf("unexpected rollback request: %v", fake.bodies[1])
+	}
+
+	var out struct {
+		TxID string `json:"tx_id"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	tx, err := snapdb.FindByTx(out.TxID)
+	if err != nil {
+		t.Fatalf("restore not recorded: %v", err)
+	}
+	if tx.Reason != "restore" || tx.Success == nil || !*tx.Success || len(tx.Targets) != 1 || tx.Targets[0].ID != name {
+		t.Fatalf("unexpected snapdb record: %+v", tx)
+	}
+}
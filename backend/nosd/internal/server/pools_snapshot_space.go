@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// poolSnapshotSpace is a test seam; the agent runs `btrfs subvolume list`
+// and `btrfs qgroup show`.
+var poolSnapshotSpace = func(ctx context.Context, mount string) (*agentclient.SnapshotSpace, error) {
+	return agentclient.New(agentSocketPath).SnapshotSpace(ctx, mount)
+}
+
+type snapshotSpaceEntry struct {
+	ID   uint64 `json:"id"`
+	Path string `json:"path"`
+	// Exclusive is what deleting only this snapshot would free.
+	Exclusive  *uint64 `json:"exclusiveBytes"`
+	Referenced *uint64 `json:"referencedBytes"`
+}
+
+type subvolSnapshotSpace struct {
+	// Source is the path of the subvolume the snapshots were taken from;
+	// empty when it no longer exists.
+	Source     string               `json:"source"`
+	SourceUUID string               `json:"sourceUuid"`
+	Count      int                  `json:"snapshotCount"`
+	Exclusive  *uint64              `json:"exclusiveBytes"`
+	Reclaim    *uint64              `json:"reclaimableBytes"`
+	Snapshots  []snapshotSpaceEntry `json:"snapshots"` // newest first
+}
+
+type snapshotSpaceReport struct {
+	QuotaEnabled bool                  `json:"quotaEnabled"`
+	Consistent   bool                  `json:"consistent"`
+	Keep         int                   `json:"keep"`
+	Count        int                   `json:"snapshotCount"`
+	Exclusive    *uint64               `json:"exclusiveBytes"`
+	Reclaim      *uint64               `json:"reclaimableBytes"`
+	Subvolumes   []subvolSnapshotSpace `json:"subvolumes"`
+}
+
+// summarizeSnapshotSpace groups snapshots by the subvolume they were taken
+// from and, when quotas are enabled, adds up their exclusive bytes.
+// reclaimableBytes counts every snapshot but the newest keep of each
+// subvolume (by subvolume ID, which btrfs assigns in creation order). It
+// is a lower bound: data shared only among the deleted snapshots is freed
+// too but isn't exclusive to any one of them.
+func summarizeSnapshotSpace(sp *agentclient.SnapshotSpace, keep int) snapshotSpaceReport {
+	rep := snapshotSpaceReport{QuotaEnabled: sp.QuotaEnabled, Consistent: sp.Consistent, Keep: keep, Subvolumes: []subvolSnapshotSpace{}}
+	qg := map[uint64]agentclient.Qgroup{}
+	for _, q := range sp.Qgroups {
+		if q.Level == 0 {
+			qg[q.SubvolID] = q
+		}
+	}
+	byUUID := map[string]agentclient.Subvolume{}
+	for _, s := range sp.Subvolumes {
+		byUUID[s.UUID] = s
+	}
+	groups := map[string]*subvolSnapshotSpace{}
+	var order []string
+	for _, s := range sp.Subvolumes {
+		if s.ParentUUID == "" {
+			continue
+		}
+		g, ok := groups[s.ParentUUID]
+		if !ok {
+			g = &subvolSnapshotSpace{SourceUUID: s.ParentUUID, Source: byUUID[s.ParentUUID].Path}
+			groups[s.ParentUUID] = g
+			order = append(order, s.ParentUUID)
+		}
+		e := snapshotSpaceEntry{ID: s.ID, Path: s.Path}
+		if q, ok := qg[s.ID]; ok && sp.QuotaEnabled {
+			excl, ref := q.Exclusive, q.Referenced
+			e.Exclusive, e.Referenced = &excl, &ref
+		}
+		g.Snapshots = append(g.Snapshots, e)
+	}
+	var totalExcl, totalReclaim uint64
+	for _, key := range order {
+		g := groups[key]
+		sort.Slice(g.Snapshots, func(i, j int) bool { return g.Snapshots[i].ID > g.Snapshots[j].ID })
+		g.Count = len(g.Snapshots)
+		rep.Count += g.Count
+		if sp.QuotaEnabled {
+			var excl, reclaim uint64
+			for i, e := range g.Snapshots {
+				if e.Exclusive == nil {
+					continue
+				}
+				excl += *e.Exclusive
+				if i >= keep {
+					reclaim += *e.Exclusive
+				}
+			}
+			g.Exclusive, g.Reclaim = &excl, &reclaim
+			totalExcl += excl
+			totalReclaim += reclaim
+		}
+		rep.Subvolumes = append(rep.Subvolumes, *g)
+	}
+	sort.SliceStable(rep.Subvolumes, func(i, j int) bool { return rep.Subvolumes[i].Source < rep.Subvolumes[j].Source })
+	if sp.QuotaEnabled {
+		rep.Exclusive, rep.Reclaim = &totalExcl, &totalReclaim
+	}
+	return rep
+}
+
+// GET /api/v1/pools/{id}/snapshot-space?keep=N
+func handlePoolSnapshotSpace(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if strings.TrimSpace(id) == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	keep := 1
+	if v := r.URL.Query().Get("keep"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			httpx.WriteError(w, http.StatusBadRequest, "keep must be a non-negative integer")
+			return
+		}
+		keep = n
+	}
+	mount, err := findPoolMountByID(r, id)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "pool not found")
+		return
+	}
+	sp, err := poolSnapshotSpace(r.Context(), mount)
+	if err != nil {
+		writeAgentError(w, err, "pools.snapshot_space_failed", "Reading snapshot space usage failed")
+		return
+	}
+	writeJSON(w, summarizeSnapshotSpace(sp, keep))
+}
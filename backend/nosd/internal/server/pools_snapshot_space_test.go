@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/pools"
+	"nithronos/backend/nosd/pkg/agentclient"
+)
+
+func testSnapshotSpace(quota bool) *agentclient.SnapshotSpace {
+	sp := &agentclient.SnapshotSpace{
+		QuotaEnabled: quota,
+		Consistent:   true,
+		Subvolumes: []agentclient.Subvolume{
+			{ID: 256, Path: "data", UUID: "u-data"},
+			{ID: 257, Path: "apps", UUID: "u-apps"},
+			{ID: 260, Path: "data/.snapshots/d1", UUID: "s1", ParentUUID: "u-data"},
+			{ID: 262, Path: "data/.snapshots/d3", UUID: "s3", ParentUUID: "u-data"},
+			{ID: 261, Path: "data/.snapshots/d2", UUID: "s2", ParentUUID: "u-data"},
+			{ID: 270, Path: "apps/.snapshots/a1", UUID: "s4", ParentUUID: "u-apps"},
+			{ID: 280, Path: "old/.snapshots/o1", UUID: "s5", ParentUUID: "u-gone"},
+		},
+	}
+	if quota {
+		sp.Qgroups = []agentclient.Qgroup{
+			{ID: "0/256", SubvolID: 256, Referenced: 1000, Exclusive: 100},
+			{ID: "0/260", SubvolID: 260, Referenced: 900, Exclusive: 300},
+			{ID: "0/261", SubvolID: 261, Referenced: 950, Exclusive: 50},
+			{ID: "0/262", SubvolID: 262, Referenced: 990, Exclusive: 10},
+			{ID: "0/270", SubvolID: 270, Referenced: 500, Exclusive: 7},
+			{ID: "1/100", Level: 1, SubvolID: 100, Referenced: 5000, Exclusive: 4000},
+		}
+	}
+	return sp
+}
+
+func TestSummarizeSnapshotSpace(t *testing.T) {
+	rep := summarizeSnapshotSpace(testSnapshotSpace(true), 1)
+	if rep.Count != 5 || len(rep.Subvolumes) != 3 {
+		t.Fatalf("unexpected grouping: %+v", rep)
+	}
+	// sorted by source; the deleted source sorts first with an empty path
+	gone, apps, data := rep.Subvolumes[0], rep.Subvolumes[1], rep.Subvolumes[2]
+	if gone.Source != "" || gone.SourceUUID != "u-gone" || apps.Source != "apps" || data.Source != "data" {
+		t.Fatalf("unexpected sources: %+v", rep.Subvolumes)
+	}
+	if data.Count != 3 || data.Snapshots[0].Path != "data/.snapshots/d3" || data.Snapshots[2].Path != "data/.snapshots/d1" {
+		t.Fatalf("data snapshots should be newest first: %+v", data.Snapshots)
+	}
+	// keep the newest (d3, 10 bytes); d2 + d1 are reclaimable
+	if *data.Exclusive != 360 || *data.Reclaim != 350 {
+		t.Fatalf("data space: excl=%d reclaim=%d", *data.Exclusive, *data.Reclaim)
+	}
+	if *apps.Reclaim != 0 || *apps.Exclusive != 7 {
+		t.Fatalf("apps space: %+v", apps)
+	}
+	// a snapshot without a qgroup row contributes nothing
+	if gone.Snapshots[0].Exclusive != nil || *gone.Exclusive != 0 {
+		t.Fatalf("missing qgroup: %+v", gone)
+	}
+	if *rep.Exclusive != 367 || *rep.Reclaim != 350 {
+		t.Fatalf("totals: excl=%d reclaim=%d", *rep.Exclusive, *rep.Reclaim)
+	}
+
+	if all := summarizeSnapshotSpace(testSnapshotSpace(true), 0); *all.Reclaim != 367 {
+		t.Fatalf("keep=0 should reclaim everything: %d", *all.Reclaim)
+	}
+
+	off := summarizeSnapshotSpace(testSnapshotSpace(false), 1)
+	if off.QuotaEnabled || off.Count != 5 || off.Exclusive != nil || off.Subvolumes[2].Reclaim != nil || off.Subvolumes[2].Snapshots[0].Exclusive != nil {
+		t.Fatalf("without quotas only counts are reported: %+v", off)
+	}
+}
+
+func TestPoolSnapshotSpaceEndpoint(t *testing.T) {
+	listPools = func(ctx context.Context) ([]pools.Pool, error) {
+		return []pools.Pool{{ID: "/mnt/tank", UUID: "f00d", Mount: "/mnt/tank", Size: 1}}, nil
+	}
+	prev := poolSnapshotSpace
+	poolSnapshotSpace = func(ctx context.Context, mount string) (*agentclient.SnapshotSpace, error) {
+		if mount != "/mnt/tank" {
+			t.Errorf("unexpected mount %q", mount)
+		}
+		return testSnapshotSpace(true), nil
+	}
+	t.Cleanup(func() {
+		listPools = pools.ListPools
+		poolSnapshotSpace = prev
+	})
+	r := chi.NewRouter()
+	r.Get("/api/v1/pools/{id}/snapshot-space", handlePoolSnapshotSpace)
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/pools/f00d/snapshot-space?keep=2", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("snapshot-space: %d %s", res.Code, res.Body.String())
+	}
+	var body map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &body)
+	if body["keep"] != float64(2) || body["reclaimableBytes"] != float64(300) || body["quotaEnabled"] != true {
+		t.Fatalf("unexpected response: %s", res.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/pools/f00d/snapshot-space?keep=-1": http.StatusBadRequest,
+		"/api/v1/pools/missing/snapshot-space":      http.StatusNotFound,
+	} {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != want {
+			t.Fatalf("%s: want %d, got %d", path, want, res.Code)
+		}
+	}
+}
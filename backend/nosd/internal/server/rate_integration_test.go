@@ -101,3 +101,55 @@ func TestOTPAndLoginRateLimitIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestRateLimitHeaders(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_FIRSTBOOT_PATH", filepath.Join(dir, "firstboot.json"))
+	t.Setenv("NOS_SECRET_PATH", filepath.Join(dir, "secret.key"))
+	t.Setenv("NOS_RATE_OTP_PER_MIN", "3")
+	t.Setenv("NOS_RATE_LOGIN_PER_15M", "2")
+	r := NewRouter(config.FromEnv())
+
+	check := func(res *httptest.ResponseRecorder, limit, remaining int) {
+		t.Helper()
+		h := res.Header()
+		if h.Get("X-RateLimit-Limit") != strconv.Itoa(limit) || h.Get("X-RateLimit-Remaining") != strconv.Itoa(remaining) {
+			t.Fatalf("status %d: limit %q remaining %q, want %d %d", res.Code, h.Get("X-RateLimit-Limit"), h.Get("X-RateLimit-Remaining"), limit, remaining)
+		}
+		reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil || reset < time.Now().Unix() {
+			t.Fatalf("reset %q not a future Unix time", h.Get("X-RateLimit-Reset"))
+		}
+	}
+
+	otp := func() *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/setup/otp/verify", bytes.NewBufferString(`{"otp":"1"}`)))
+		return res
+	}
+	for want := 2; want >= 0; want-- {
+		res := otp()
+		if res.Code == http.StatusTooManyRequests {
+			t.Fatalf("otp throttled early with %d left", want)
+		}
+		check(res, 3, want)
+	}
+	res := otp()
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("otp: expected 429, got %d", res.Code)
+	}
+	check(res, 3, 0)
+
+	login := func(user string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"username":"`+user+`","password":"x"}`)))
+		return res
+	}
+	check(login("bob"), 2, 1)
+	check(login("carol"), 2, 0)
+	res = login("dave")
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("login: expected 429, got %d", res.Code)
+	}
+	check(res, 2, 0)
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// setRateLimitHeaders reports a rate-limit bucket on rate-limited routes,
+// on success and on 429 alike, so clients can back off before hitting the
+// limit: X-RateLimit-Limit is the budget per window, X-RateLimit-Remaining
+// what is left of it after this request and X-RateLimit-Reset the Unix time
+// (seconds) the window restarts.
+func setRateLimitHeaders(w http.ResponseWriter, limit, remaining int, reset time.Time) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
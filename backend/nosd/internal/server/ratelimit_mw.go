@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/ratelimit"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// Budget for sensitive account and recovery routes that have no configurable
+// limit of their own.
+const (
+	sensitiveRateLimit  = 10
+	sensitiveRateWindow = 15 * time.Minute
+)
+
+// rateLimitKeyFunc picks the bucket a request counts against; "" skips
+// limiting for that request.
+type rateLimitKeyFunc func(r *http.Request) string
+
+// byClientIP buckets requests per client IP (honouring trustProxy).
+func byClientIP(cfg config.Config) rateLimitKeyFunc {
+	return func(r *http.Request) string { return "ip:" + clientIP(r, cfg) }
+}
+
+// byUserOrIP buckets requests per signed-in user, falling back to the client
+// IP for anonymous requests.
+func byUserOrIP(cfg config.Config) rateLimitKeyFunc {
+	return func(r *http.Request) string {
+		if uid := requestUID(r, cfg); uid != "" {
+			return "user:" + uid
+		}
+		return "ip:" + clientIP(r, cfg)
+	}
+}
+
+// rateLimit lets a route opt into the fixed-window limiter used for login
+// and OTP verify: at most limit requests per window per key, counted in the
+// persisted store under name. Every response carries the X-RateLimit-*
+// headers; over the limit the route answers 429 rate.limited with
+// Retry-After. Use it as r.With(rateLimit(...)).Post(...).
+func rateLimit(rl *ratelimit.Store, cfg config.Config, name string, key rateLimitKeyFunc, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ok, rem, reset := rl.Allow(name+":"+k, limit, window)
+			setRateLimitHeaders(w, limit, rem, reset)
+			if !ok {
+				retry := int(time.Until(reset).Seconds())
+				if retry < 1 {
+					retry = 1
+				}
+				Logger(cfg).Warn().Str("event", "rate.limited").Str("route", r.URL.Path).Str("key", name+":"+k).Int("limit", limit).Int("retryAfterSec", retry).Msg("")
+				httpx.WriteTypedError(w, http.StatusTooManyRequests, "rate.limited", "Too many attempts. Try later.", retry)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	rl := ratelimit.New(filepath.Join(t.TempDir(), "ratelimit.json"))
+	byHeader := func(r *http.Request) string { return r.Header.Get("X-Who") }
+	r := chi.NewRouter()
+	r.With(rateLimit(rl, config.Config{}, "dummy", byHeader, 2, time.Second)).Post("/dummy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	call := func(who string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/dummy", nil)
+		req.Header.Set("X-Who", who)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	for i, want := range []string{"1", "0"} {
+		res := call("alice")
+		if res.Code != http.StatusNoContent || res.Header().Get("X-RateLimit-Remaining") != want {
+			t.Fatalf("call %d: %d remaining %q", i+1, res.Code, res.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	res := call("alice")
+	if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") == "" {
+		t.Fatalf("third call: expected 429 with Retry-After, got %d %v", res.Code, res.Header())
+	}
+	if res.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("limit header: %q", res.Header().Get("X-RateLimit-Limit"))
+	}
+
+	// Buckets are per key; an empty key isn't limited at all.
+	if res := call("bob"); res.Code != http.StatusNoContent {
+		t.Fatalf("other key throttled: %d", res.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if res := call(""); res.Code != http.StatusNoContent || res.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("empty key should skip limiting: %d", res.Code)
+		}
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if res := call("alice"); res.Code != http.StatusNoContent || res.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("after reset: %d remaining %q", res.Code, res.Header().Get("X-RateLimit-Remaining"))
+	}
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	"nithronos/backend/nosd/internal/auth/session"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/internal/ratelimit"
+	"nithronos/backend/nosd/internal/sessions"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// Recovery actions, audited as "recovery.<action>" events.
+const (
+	recoveryResetPassword = "reset_password"
+	recoveryDisable2FA    = "disable_2fa"
+	recoveryGenerateOTP   = "generate_otp"
+	recoveryOTPRegenerate = "otp_regenerate"
+	recoverySetupReset    = "setup_recover"
+)
+
+// Outcomes of an audited recovery action.
+const (
+	recoveryOK          = "ok"
+	recoveryInvalid     = "invalid"
+	recoveryNotFound    = "not_found"
+	recoveryRateLimited = "rate_limited"
+	recoveryFailed      = "error"
+)
+
+// byPeerIP buckets requests per socket peer, ignoring X-Forwarded-For. The
+// localhost-only routes use it: loopback is a trusted proxy, so a local
+// process could otherwise pick a fresh bucket for every request.
+func byPeerIP() rateLimitKeyFunc {
+	return func(r *http.Request) string { return "ip:" + remoteIP(r) }
+}
+
+// localOnly lets only fromLocalhost requests through and answers the rest
+// with deny. It goes before the rate limiter: behind the bundled proxy every
+// LAN client shares the loopback peer, and must not spend the console's
+// budget.
+func localOnly(deny http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !fromLocalhost(r) {
+				deny(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forbidden answers 403 with no body.
+func forbidden(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusForbidden) }
+
+// allowRecoveryTarget counts one recovery action against the target user,
+// on top of the per-IP limit of the recovery routes, and answers 429 once
+// the user's budget is spent.
+func allowRecoveryTarget(w http.ResponseWriter, rl *ratelimit.Store, username string) bool {
+	ok, rem, reset := rl.Allow("recovery:user:"+strings.ToLower(username), sensitiveRateLimit, sensitiveRateWindow)
+	setRateLimitHeaders(w, sensitiveRateLimit, rem, reset)
+	if ok {
+		return true
+	}
+	retry := max(int(time.Until(reset).Seconds()), 1)
+	httpx.WriteTypedError(w, http.StatusTooManyRequests, "rate.limited", "Too many attempts. Try later.", retry)
+	return false
+}
+
+// auditRecovery records a recovery action, whatever its outcome, in the log
+// and in the event log admins see in the UI. target is the affected
+// username, if any.
+func auditRecovery(cfg config.Config, r *http.Request, action, target, outcome string) {
+	ip := remoteIP(r)
+	Logger(cfg).Warn().Str("event", "recovery."+action).Str("target", target).Str("outcome", outcome).Str("ip", ip).Msg("")
+	msg := "Recovery console: " + strings.ReplaceAll(action, "_", " ")
+	if target != "" {
+		msg += " for " + target
+	}
+	if outcome != recoveryOK {
+		msg += " (" + outcome + ")"
+	}
+	err := appendEvent(Event{
+		Level:    "warning",
+		Category: "security",
+		Message:  msg,
+		Details:  map[string]string{"action": action, "target": target, "outcome": outcome, "ip": ip},
+	})
+	if err != nil {
+		Logger(cfg).Error().Err(err).Str("event", "recovery.audit_failed").Str("action", action).Msg("")
+	}
+}
+
+// POST /api/v1/recovery/reset-password signs the target user out
+// everywhere, so whoever held the old password loses access with it.
+// force_password_change makes the user pick a new password on next login.
+func handleRecoveryResetPassword(cfg config.Config, rl *ratelimit.Store, sessStore *sessions.Store, mgr *session.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Username            string `json:"username"`
+			Password            string `json:"password"`
+			ForcePasswordChange bool   `json:"force_password_change"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		username := strings.ToLower(strings.TrimSpace(body.Username))
+		outcome := recoveryFailed
+		defer func() { auditRecovery(cfg, r, recoveryResetPassword, username, outcome) }()
+		if username == "" || strings.TrimSpace(body.Password) == "" {
+			outcome = recoveryInvalid
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !allowRecoveryTarget(w, rl, username) {
+			outcome = recoveryRateLimited
+			return
+		}
+		users, err := userstore.New(cfg.UsersPath)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		u, err := users.FindByUsername(username)
+		if err != nil {
+			outcome = recoveryNotFound
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h, herr := pwhash.HashPassword(body.Password)
+		if herr != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		u.PasswordHash = h
+		u.PasswordChangedAt = time.Now().UTC().Format(time.RFC3339)
+		u.LockedUntil = ""
+		u.FailedAttempts = 0
+		u.ForcePasswordChange = body.ForcePasswordChange
+		if err := users.UpsertUser(u); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		revoked := len(mgr.ListByUser(u.ID))
+		_ = sessStore.DeleteByUserID(u.ID)
+		if err := mgr.RevokeAll(u.ID); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		Logger(cfg).Info().Str("event", "auth.session.revoke").Str("userId", u.ID).Str("scope", "recovery").Int("sessions", revoked).Str("ip", remoteIP(r)).Msg("")
+		outcome = recoveryOK
+		writeJSON(w, map[string]any{"ok": true, "sessionsRevoked": revoked, "forcePasswordChange": u.ForcePasswordChange})
+	}
+}
+
+// POST /api/v1/recovery/disable-2fa
+func handleRecoveryDisable2FA(cfg config.Config, rl *ratelimit.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Username string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		username := strings.ToLower(strings.TrimSpace(body.Username))
+		outcome := recoveryFailed
+		defer func() { auditRecovery(cfg, r, recoveryDisable2FA, username, outcome) }()
+		if username == "" {
+			outcome = recoveryInvalid
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !allowRecoveryTarget(w, rl, username) {
+			outcome = recoveryRateLimited
+			return
+		}
+		users, err := userstore.New(cfg.UsersPath)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		u, err := users.FindByUsername(username)
+		if err != nil {
+			outcome = recoveryNotFound
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		u.TOTPEnc = ""
+		u.RecoveryHashes = nil
+		u.TOTPPendingEnc = ""
+		u.TOTPEnabled = false
+		if err := users.UpsertUser(u); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		outcome = recoveryOK
+		writeJSON(w, map[string]any{"ok": true})
+	}
+}
+
+// POST /api/v1/recovery/generate-otp regenerates the one-time setup OTP
+// (best-effort).
+func handleRecoveryGenerateOTP(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var st struct {
+			OTP       string `json:"otp"`
+			CreatedAt string `json:"created_at"`
+			Used      bool   `json:"used"`
+		}
+		st.OTP = genOTP6()
+		st.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		st.Used = false
+		_ = os.MkdirAll(filepath.Dir(cfg.FirstBootPath), 0o755)
+		_ = fsatomic.SaveJSON(r.Context(), cfg.FirstBootPath, st, 0o600)
+		_ = writeFirstBootOTPFile(st.OTP)
+		auditRecovery(cfg, r, recoveryGenerateOTP, "", recoveryOK)
+		writeJSON(w, map[string]any{"otp": st.OTP})
+	}
+}
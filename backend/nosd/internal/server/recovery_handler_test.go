@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/auth/session"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+)
+
+func recoveryTestRouter(t *testing.T) (http.Handler, config.Config, string) {
+	t.Helper()
+	dir := healthTestEnv(t)
+	cfg := config.FromEnv()
+	cfg.RecoveryMode = true
+	us, err := userstore.New(cfg.UsersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := us.UpsertUser(userstore.User{ID: "u1", Username: "alice", Roles: []string{"admin"}, FailedAttempts: 4}); err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(cfg), cfg, filepath.Join(dir, "events.jsonl")
+}
+
+func recoveryCall(h http.Handler, path, remote, xff string, body any) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(mustJSON(body)))
+	req.RemoteAddr = remote
+	if xff != "" {
+		req.Header.Set("X-Forwarded-For", xff)
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func recoveryEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("event log: %v", err)
+	}
+	var out []Event
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var ev Event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("event line %q: %v", line, err)
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestRecoveryAuditEvents(t *testing.T) {
+	h, cfg, eventsPath := recoveryTestRouter(t)
+
+	res := recoveryCall(h, "/api/v1/recovery/reset-password", "127.0.0.1:4000", "", map[string]string{"username": "Alice", "password": "N3w-passphrase!"})
+	if res.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", res.Code, res.Body.String())
+	}
+	us, _ := userstore.New(cfg.UsersPath)
+	if u, err := us.FindByUsername("alice"); err != nil || u.PasswordHash == "" || u.FailedAttempts != 0 {
+		t.Fatalf("user not reset: %+v %v", u, err)
+	}
+	if res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "127.0.0.1:4000", "", map[string]string{"username": "bob"}); res.Code != http.StatusNotFound {
+		t.Fatalf("unknown user: %d", res.Code)
+	}
+	if res := recoveryCall(h, "/api/v1/recovery/generate-otp", "127.0.0.1:4000", "", nil); res.Code != http.StatusOK {
+		t.Fatalf("generate otp: %d", res.Code)
+	}
+	// remote callers never reach the handlers
+	if res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "192.0.2.5:4000", "", map[string]string{"username": "alice"}); res.Code != http.StatusForbidden {
+		t.Fatalf("remote: %d", res.Code)
+	}
+
+	want := []struct{ action, target, outcome string }{
+		{"reset_password", "alice", "ok"},
+		{"disable_2fa", "bob", "not_found"},
+		{"generate_otp", "", "ok"},
+	}
+	events := recoveryEvents(t, eventsPath)
+	if len(events) != len(want) {
+		t.Fatalf("want %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		ev := events[i]
+		d, _ := ev.Details.(map[string]any)
+		if ev.Category != "security" || ev.Level != "warning" || ev.ID == "" || ev.Timestamp.IsZero() ||
+			d["action"] != w.action || d["target"] != w.target || d["outcome"] != w.outcome || d["ip"] != "127.0.0.1" {
+			t.Errorf("event %d: %+v", i, ev)
+		}
+	}
+	if !strings.Contains(events[0].Message, "alice") {
+		t.Errorf("message lacks the target: %q", events[0].Message)
+	}
+}
+
+func TestRecoveryThrottling(t *testing.T) {
+	h, _, eventsPath := recoveryTestRouter(t)
+
+	// per target user: alice's budget runs out whichever address asks
+	for i := range sensitiveRateLimit {
+		if res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "127.0.0.1:4000", "", map[string]string{"username": "alice"}); res.Code != http.StatusOK {
+			t.Fatalf("attempt %d: %d", i+1, res.Code)
+		}
+	}
+	res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "[::1]:4000", "", map[string]string{"username": "alice"})
+	if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") == "" {
+		t.Fatalf("alice over budget: %d %v", res.Code, res.Header())
+	}
+	if res := recoveryCall(h, "/api/v1/recovery/reset-password", "[::1]:4000", "", map[string]string{"username": "ALICE", "password": "x"}); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("reset for alice over budget: %d", res.Code)
+	}
+	if res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "[::1]:4000", "", map[string]string{"username": "bob"}); res.Code != http.StatusNotFound {
+		t.Fatalf("another user: %d", res.Code)
+	}
+	events := recoveryEvents(t, eventsPath)
+	if d, _ := events[sensitiveRateLimit].Details.(map[string]any); d["outcome"] != "rate_limited" || d["target"] != "alice" {
+		t.Fatalf("throttled attempt not audited: %+v", events[sensitiveRateLimit])
+	}
+
+	// requests relayed by the loopback proxy are refused outright, so LAN
+	// clients can't spend the console's budget (::1 has used 3 of it above)
+	for i := range sensitiveRateLimit {
+		xff := "203.0.113." + strconv.Itoa(i)
+		if res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "[::1]:4000", xff, map[string]string{"username": "user" + xff}); res.Code != http.StatusForbidden {
+			t.Fatalf("proxied attempt %d: %d", i+1, res.Code)
+		}
+	}
+	if res := recoveryCall(h, "/api/v1/recovery/disable-2fa", "[::1]:4000", "", map[string]string{"username": "carol"}); res.Code != http.StatusNotFound {
+		t.Fatalf("console after proxied attempts: %d", res.Code)
+	}
+}
+
+func TestRecoveryResetRevokesSessions(t *testing.T) {
+	t.Setenv("NOS_TEST_SKIP_AUTH", "")
+	_, cfg, _ := recoveryTestRouter(t)
+	rec, err := session.New(cfg.SessionsPath).Create("u1", "browser-a", "192.0.2.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if err := issueSessionCookiesSID(w, cfg, "u1", rec.SID, true); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	h := NewRouter(cfg)
+	call := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		for _, ck := range cookies {
+			req.AddCookie(ck)
+		}
+		req.Header.Set("User-Agent", "browser-a")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res.Code
+	}
+	if code := call(http.MethodGet, "/api/v1/auth/me"); code != http.StatusOK {
+		t.Fatalf("session before reset: %d", code)
+	}
+	if code := call(http.MethodPost, "/api/v1/auth/refresh"); code != http.StatusOK {
+		t.Fatalf("refresh before reset: %d", code)
+	}
+
+	res := recoveryCall(h, "/api/v1/recovery/reset-password", "127.0.0.1:4000", "", map[string]any{"username": "alice", "password": "N3w-passphrase!", "force_password_change": true})
+	if res.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		SessionsRevoked int `json:"sessionsRevoked"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if out.SessionsRevoked != 1 {
+		t.Fatalf("revoked: %s", res.Body.String())
+	}
+	for _, path := range []string{"/api/v1/auth/me", "/api/v1/users"} {
+		if code := call(http.MethodGet, path); code != http.StatusUnauthorized {
+			t.Fatalf("%s after reset: %d", path, code)
+		}
+	}
+	if code := call(http.MethodPost, "/api/v1/auth/refresh"); code != http.StatusUnauthorized {
+		t.Fatalf("refresh after reset: %d", code)
+	}
+	if recs := session.New(cfg.SessionsPath).ListByUser("u1"); len(recs) != 0 {
+		t.Fatalf("sessions left on disk: %+v", recs)
+	}
+	us, _ := userstore.New(cfg.UsersPath)
+	if u, _ := us.FindByUsername("alice"); !u.ForcePasswordChange {
+		t.Fatal("password change not required")
+	}
+}
+
+func TestLocalOnlyRunsBeforeRateLimit(t *testing.T) {
+	h, _, _ := recoveryTestRouter(t)
+	for _, path := range []string{"/api/v1/recovery/disable-2fa", "/api/v1/setup/recover"} {
+		for i := 0; i <= sensitiveRateLimit; i++ {
+			res := recoveryCall(h, path, "127.0.0.1:4000", "192.168.1.20", map[string]string{"username": "alice"})
+			if res.Code != http.StatusForbidden || res.Header().Get("X-RateLimit-Limit") != "" {
+				t.Fatalf("%s proxied attempt %d: %d %v", path, i+1, res.Code, res.Header())
+			}
+		}
+		if res := recoveryCall(h, path, "127.0.0.1:4000", "", map[string]string{"username": "alice"}); res.Code == http.StatusForbidden || res.Code == http.StatusTooManyRequests {
+			t.Fatalf("%s from the console after proxied attempts: %d", path, res.Code)
+		}
+	}
+}
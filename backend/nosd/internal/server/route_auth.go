@@ -0,0 +1,82 @@
+package server
+
+import (
+	"strings"
+
+	"nithronos/backend/nosd/internal/openapi"
+)
+
+// routeAuthRule assigns auth to the routes matching method ("" for any)
+// and pattern; a pattern ending in "/*" covers its whole subtree.
+type routeAuthRule struct {
+	method  string
+	pattern string
+	auth    openapi.Auth
+}
+
+// routeAuthRules documents the routes that don't take a session cookie,
+// for the OpenAPI document. Every other route requires a session;
+// TestRouteAuthMatchesRouter checks both against the router.
+var routeAuthRules = []routeAuthRule{
+	// probes, metrics and the read-only dashboard feeds; /metrics and
+	// /metrics/all exist with metrics.enabled and check metrics.allowlist
+	{"GET", "/api/v1/health", openapi.AuthNone},
+	{"GET", "/healthz", openapi.AuthNone},
+	{"GET", "/readyz", openapi.AuthNone},
+	{"GET", "/metrics", openapi.AuthNone},
+	{"GET", "/metrics/all", openapi.AuthNone},
+	{"GET", "/api/v1/metrics/stream", openapi.AuthNone},
+	{"GET", "/api/v1/health/system", openapi.AuthNone},
+	{"GET", "/api/v1/health/disks", openapi.AuthNone},
+	{"GET", "/api/v1/health/disks/summary", openapi.AuthNone},
+	{"GET", "/api/v1/health/smart", openapi.AuthNone},
+	{"GET", "/api/v1/dashboard", openapi.AuthNone},
+	{"GET", "/api/v1/storage/summary", openapi.AuthNone},
+	{"GET", "/api/v1/storage/devices", openapi.AuthNone},
+	{"GET", "/api/v1/events/recent", openapi.AuthNone},
+	{"GET", "/api/v1/openapi.json", openapi.AuthNone},
+
+	// signing in
+	{"POST", "/api/v1/auth/login", openapi.AuthNone},
+	{"POST", "/api/v1/auth/refresh", openapi.AuthNone},
+	{"POST", "/api/v1/auth/logout", openapi.AuthNone},
+	{"POST", "/api/v1/auth/totp/setup", openapi.AuthNone},
+	{"POST", "/api/v1/auth/totp/confirm", openapi.AuthNone},
+
+	// agents register with the bootstrap token in the body
+	{"POST", "/api/v1/agents/register", openapi.AuthNone},
+	{"POST", "/api/v1/agents/heartbeat", openapi.AuthAgent},
+
+	// first boot
+	{"GET", "/api/v1/setup/state", openapi.AuthNone},
+	{"POST", "/api/v1/setup/otp/verify", openapi.AuthNone},
+	{"POST", "/api/v1/setup/otp/regenerate", openapi.AuthLocal},
+	{"POST", "/api/v1/setup/recover", openapi.AuthLocal},
+	{"", "/api/v1/setup/*", openapi.AuthSetup},
+	{"", "/api/v1/system/*", openapi.AuthSessionOrSetup},
+
+	// console tooling
+	{"POST", "/api/v1/maintenance/dispatch", openapi.AuthLocal},
+	{"", "/api/v1/recovery/*", openapi.AuthLocal},
+	{"", "/debug/pprof/*", openapi.AuthLocal},
+}
+
+func (rule routeAuthRule) matches(method, pattern string) bool {
+	if rule.method != "" && rule.method != method {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(rule.pattern, "*"); ok {
+		return strings.HasPrefix(pattern, prefix)
+	}
+	return rule.pattern == pattern
+}
+
+// routeAuth returns what method and pattern require.
+func routeAuth(method, pattern string) openapi.Auth {
+	for _, rule := range routeAuthRules {
+		if rule.matches(method, pattern) {
+			return rule.auth
+		}
+	}
+	return openapi.AuthSession
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/openapi"
+)
+
+// TestRouteAuthMatchesRouter sends every route a request without
+// credentials, with auth enforced, and checks the answer against the auth
+// documented for it. Open routes are only probed with GET, since their
+// handlers run.
+func TestRouteAuthMatchesRouter(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_TEST_SKIP_AUTH", "")
+	t.Setenv("NOS_METRICS", "1")
+	r := NewRouter(config.FromEnv())
+
+	used := make([]bool, len(routeAuthRules))
+	for _, rt := range routeInventory(r.(chi.Routes)) {
+		for i, rule := range routeAuthRules {
+			if rule.matches(rt.Method, rt.Path) {
+				used[i] = true
+				break
+			}
+		}
+
+		method := rt.Method
+		if method == "*" {
+			method = http.MethodGet
+		}
+		if rt.Auth == openapi.AuthNone && method != http.MethodGet {
+			continue
+		}
+		path, _ := openapi.PathTemplate(rt.Path)
+		path = strings.NewReplacer("{", "x", "}", "").Replace(path)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		res := httptest.NewRecorder()
+		// httptest requests come from 192.0.2.1, which isn't localhost
+		r.ServeHTTP(res, httptest.NewRequest(method, path, nil).WithContext(ctx))
+		cancel()
+
+		switch rt.Auth {
+		case openapi.AuthNone:
+			if res.Code == http.StatusUnauthorized {
+				t.Errorf("%s %s is documented as open but answered 401", rt.Method, rt.Path)
+			}
+		case openapi.AuthLocal:
+			if res.Code != http.StatusForbidden && res.Code != http.StatusNotFound {
+				t.Errorf("%s %s is documented as localhost-only but answered %d remotely", rt.Method, rt.Path, res.Code)
+			}
+		default:
+			if res.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s is documented as %s but answered %d without credentials", rt.Method, rt.Path, rt.Auth, res.Code)
+			}
+		}
+	}
+	// recovery routes exist only in recovery mode
+	for i, rule := range routeAuthRules {
+		if !used[i] && rule.pattern != "/api/v1/recovery/*" {
+			t.Errorf("auth rule %s %s matches no route", rule.method, rule.pattern)
+		}
+	}
+}
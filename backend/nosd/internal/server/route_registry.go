@@ -0,0 +1,168 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/openapi"
+)
+
+// routeRegistry records the method and pattern of every registration made
+// through a recordingRouter. chi keeps only the last handler registered for
+// a method and pattern, without a warning, so a second registration
+// silently replaces the first; the registry reports them instead.
+type routeRegistry struct {
+	seen  map[string]map[string]bool // pattern -> methods, "*" for all
+	dupes []string
+}
+
+func newRouteRegistry() *routeRegistry {
+	return &routeRegistry{seen: map[string]map[string]bool{}}
+}
+
+func (g *routeRegistry) add(method, pattern string) {
+	methods := g.seen[pattern]
+	if methods == nil {
+		methods = map[string]bool{}
+		g.seen[pattern] = methods
+	}
+	if methods[method] || methods["*"] || method == "*" && len(methods) > 0 {
+		g.dupes = append(g.dupes, method+" "+pattern)
+	}
+	methods[method] = true
+}
+
+// addMount records a subtree mounted at pattern, which chi also answers
+// at pattern itself.
+func (g *routeRegistry) addMount(pattern string) {
+	base := strings.TrimSuffix(pattern, "/")
+	if base != "" {
+		g.add("*", base)
+	}
+	g.add("*", base+"/*")
+}
+
+// wrap returns r recording into g, with patterns relative to prefix.
+func (g *routeRegistry) wrap(r chi.Router, prefix string) chi.Router {
+	return &recordingRouter{Router: r, prefix: prefix, reg: g}
+}
+
+// recordingRouter is a chi.Router that records registrations in reg.
+// Routers passed to Group, Route and With callbacks record too; mounted
+// handlers' own routes are checked from the walked inventory instead (see
+// duplicateRoutes).
+type recordingRouter struct {
+	chi.Router
+	prefix string
+	reg    *routeRegistry
+}
+
+func (r *recordingRouter) record(method, pattern string) {
+	r.reg.add(strings.ToUpper(method), r.prefix+pattern)
+}
+
+func (r *recordingRouter) With(middlewares ...func(http.Handler) http.Handler) chi.Router {
+	return r.reg.wrap(r.Router.With(middlewares...), r.prefix)
+}
+
+func (r *recordingRouter) Group(fn func(r chi.Router)) chi.Router {
+	return r.reg.wrap(r.Router.Group(func(im chi.Router) {
+		fn(r.reg.wrap(im, r.prefix))
+	}), r.prefix)
+}
+
+// Route records the subrouter's routes rather than its mount point, so a
+// Mount("/") inside it isn't mistaken for a second mount.
+func (r *recordingRouter) Route(pattern string, fn func(r chi.Router)) chi.Router {
+	return r.reg.wrap(r.Router.Route(pattern, func(sub chi.Router) {
+		fn(r.reg.wrap(sub, r.prefix+pattern))
+	}), r.prefix+pattern)
+}
+
+func (r *recordingRouter) Mount(pattern string, h http.Handler) {
+	r.reg.addMount(r.prefix + pattern)
+	r.Router.Mount(pattern, h)
+}
+
+func (r *recordingRouter) Handle(pattern string, h http.Handler) {
+	r.record("*", pattern)
+	r.Router.Handle(pattern, h)
+}
+
+func (r *recordingRouter) HandleFunc(pattern string, h http.HandlerFunc) {
+	r.record("*", pattern)
+	r.Router.HandleFunc(pattern, h)
+}
+
+func (r *recordingRouter) Method(method, pattern string, h http.Handler) {
+	r.record(method, pattern)
+	r.Router.Method(method, pattern, h)
+}
+
+func (r *recordingRouter) MethodFunc(method, pattern string, h http.HandlerFunc) {
+	r.record(method, pattern)
+	r.Router.MethodFunc(method, pattern, h)
+}
+
+func (r *recordingRouter) Connect(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodConnect, pattern)
+	r.Router.Connect(pattern, h)
+}
+
+func (r *recordingRouter) Delete(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodDelete, pattern)
+	r.Router.Delete(pattern, h)
+}
+
+func (r *recordingRouter) Get(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodGet, pattern)
+	r.Router.Get(pattern, h)
+}
+
+func (r *recordingRouter) Head(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodHead, pattern)
+	r.Router.Head(pattern, h)
+}
+
+func (r *recordingRouter) Options(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodOptions, pattern)
+	r.Router.Options(pattern, h)
+}
+
+func (r *recordingRouter) Patch(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodPatch, pattern)
+	r.Router.Patch(pattern, h)
+}
+
+func (r *recordingRouter) Post(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodPost, pattern)
+	r.Router.Post(pattern, h)
+}
+
+func (r *recordingRouter) Put(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodPut, pattern)
+	r.Router.Put(pattern, h)
+}
+
+func (r *recordingRouter) Trace(pattern string, h http.HandlerFunc) {
+	r.record(http.MethodTrace, pattern)
+	r.Router.Trace(pattern, h)
+}
+
+// duplicateRoutes returns the method and path pairs listed more than once
+// in a walked inventory: a route of a mounted router that a route of the
+// parent shadows, or the other way round.
+func duplicateRoutes(routes []openapi.Route) []string {
+	count := map[openapi.Route]int{}
+	var dupes []string
+	for _, rt := range routes {
+		key := openapi.Route{Method: rt.Method, Path: rt.Path}
+		count[key]++
+		if count[key] == 2 {
+			dupes = append(dupes, rt.Method+" "+rt.Path)
+		}
+	}
+	return dupes
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestRouterHasNoDuplicateRoutes(t *testing.T) {
+	healthTestEnv(t)
+	for _, metrics := range []bool{false, true} {
+		cfg := config.FromEnv()
+		cfg.MetricsEnabled = metrics
+		cfg.RecoveryMode = true
+		if _, dupes := buildRouter(cfg); len(dupes) > 0 {
+			t.Errorf("metrics %v: routes registered twice: %v", metrics, dupes)
+		}
+	}
+}
+
+func TestRouteRegistryReportsDuplicates(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	sub := chi.NewRouter()
+	sub.Get("/", h)
+	sub.Get("/devices", h)
+
+	mux := chi.NewRouter()
+	reg := newRouteRegistry()
+	r := reg.wrap(mux, "")
+	r.Get("/metrics", h)
+	r.Get("/metrics", h)
+	r.Get("/api/v1/health", h)
+	r.Post("/api/v1/health", h)
+	r.Group(func(pr chi.Router) {
+		pr.With(func(next http.Handler) http.Handler { return next }).Post("/api/v1/health", h)
+		// claims /api/v1/health for every method
+		pr.Mount("/api/v1/health", sub)
+		pr.Get("/api/v1/storage/devices", h)
+		pr.Mount("/api/v1/storage", sub)
+	})
+	// a subrouter mounting "/" inside Route isn't a second mount
+	r.Route("/api/v1/system", func(sr chi.Router) {
+		sr.Get("/info", h)
+		sr.Mount("/", sub)
+	})
+	r.Route("/api/v1/network", func(nr chi.Router) {
+		nr.Get("/dns", h)
+		nr.Put("/dns", h)
+	})
+
+	want := []string{"GET /metrics", "POST /api/v1/health", "* /api/v1/health"}
+	if !reflect.DeepEqual(reg.dupes, want) {
+		t.Fatalf("registry duplicates = %q, want %q", reg.dupes, want)
+	}
+	// the parent's /api/v1/storage/devices shadows the mounted router's
+	if dupes := duplicateRoutes(routeInventory(mux)); !reflect.DeepEqual(dupes, []string{"GET /api/v1/storage/devices"}) {
+		t.Fatalf("inventory duplicates = %q", dupes)
+	}
+}
@@ -46,6 +46,7 @@ import (
 	"strconv"
 
 	firstboot "nithronos/backend/nosd/internal/setup/firstboot"
+	"nithronos/backend/nosd/internal/setup/wizard"
 
 	"github.com/gorilla/securecookie"
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -83,102 +84,54 @@ func Logger(cfg config.Config) *zerolog.Logger {
 	return &logger
 }
 
+// NewRouter builds nosd's HTTP handler. A method and path registered twice
+// is logged: chi would serve only the last registration.
 func NewRouter(cfg config.Config) http.Handler {
-	r := chi.NewRouter()
+	mux, dupes := buildRouter(cfg)
+	for _, d := range dupes {
+		Logger(cfg).Error().Str("event", "router.duplicate_route").Str("route", d).Msg("")
+	}
+	return mux
+}
+
+// buildRouter builds the router and returns it with the method and path
+// pairs registered more than once.
+func buildRouter(cfg config.Config) (*chi.Mux, []string) {
+	mux := chi.NewRouter()
+	reg := newRouteRegistry()
+	r := reg.wrap(mux, "")
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.RealIP)
 	r.Use(zerologMiddleware(Logger(cfg), cfg))
-	r.Use(securityHeaders)
+	r.Use(securityHeaders(cfg))
+	maxBody := cfg.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = config.Defaults().MaxBodyBytes
+	}
+	r.Use(limitBody(maxBody))
+	r.Use(invalidateDashboard)
 
 	// Dynamic CORS based on runtime config
-	SetRuntimeCORSOrigin(cfg.CORSOrigin)
+	SetRuntimeCORSOrigins(cfg.AllowedOrigins())
 	r.Use(DynamicCORS)
+	SetRuntimeRateLimits(cfg)
+	SetRuntimeMetrics(cfg.MetricsAllowlist, cfg.PprofEnabled)
 
-	// Observability endpoints: metrics and pprof
-	if cfg.MetricsEnabled {
-		r.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
-			// very simple allowlist by exact ip match or prefix
-			if len(cfg.MetricsAllowlist) > 0 {
-				ip := clientIP(r, cfg)
-				allowed := false
-				for _, a := range cfg.MetricsAllowlist {
-					if a == ip || (strings.HasSuffix(a, ".") && strings.HasPrefix(ip, a)) {
-						allowed = true
-						break
-					}
-				}
-				if !allowed {
-					w.WriteHeader(http.StatusForbidden)
-					return
-				}
-			}
-			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
-			var b strings.Builder
-			b.WriteString("nosd_up 1\n")
-			// pool metrics (best-effort)
-			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
-			defer cancel()
-			if list, err := pools.ListPools(ctx); err == nil {
-				var total uint64
-				var used uint64
-				for _, p := range list {
-					total += p.Size
-					used += p.Used
-				}
-				b.WriteString(fmt.Sprintf("pool_total_bytes %d\n", total))
-				b.WriteString(fmt.Sprintf("pool_used_bytes %d\n", used))
-			}
-			// SMART metrics for common devices (best-effort)
-			for _, dev := range []string{"/dev/sda", "/dev/nvme0n1"} {
-				client := agentclient.New("/run/nos-agent.sock")
-				var out map[string]any
-				if err := client.GetJSON(r.Context(), "/v1/smart?device="+dev, &out); err == nil {
-					if t, ok := out["temperature_c"].(float64); ok {
-						b.WriteString(fmt.Sprintf("smart_disk_temp_celsius{dev=\"%s\"} %g\n", dev, t))
-					}
-					if st, ok := out["passed"].(bool); ok {
-						if st {
-							b.WriteString(fmt.Sprintf("smart_pass{dev=\"%s\"} 1\n", dev))
-						} else {
-							b.WriteString(fmt.Sprintf("smart_pass{dev=\"%s\"} 0\n", dev))
-						}
-					}
-				}
-			}
-			// Btrfs tx progress (best-effort gauges set by executor)
-			if p := currentBalancePercent(); p >= 0 {
-				b.WriteString(fmt.Sprintf("btrfs_balance_percent %g\n", p))
-			}
-			if p := currentReplacePercent(); p >= 0 {
-				b.WriteString(fmt.Sprintf("btrfs_replace_percent %g\n", p))
-			}
-			_, _ = w.Write([]byte(b.String()))
-		})
-		// Combined metrics endpoint: nosd + agent
-		r.Get("/metrics/all", func(w http.ResponseWriter, r *http.Request) {
-			NewCombinedMetricsHandler(prom.DefaultGatherer, agentMetricsClient{socket: cfg.AgentSocket()}).ServeHTTP(w, r)
-		})
-	}
+	// Password hashing cost
+	pwhash.SetParams(pwhash.Params{Time: cfg.Argon2Time, Memory: cfg.Argon2MemoryKiB, Threads: cfg.Argon2Threads})
 
-	if cfg.PprofEnabled {
-		// Guard pprof: localhost only
-		r.Mount("/debug/pprof", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-			if i := strings.LastIndex(ip, ":"); i >= 0 {
-				ip = ip[:i]
-			}
-			if ip != "127.0.0.1" && ip != "::1" {
-				w.WriteHeader(http.StatusForbidden)
-				return
-			}
-			http.DefaultServeMux.ServeHTTP(w, r)
-		}))
-	}
+	// Agent socket for the package-level client seams (makeAgentClient etc.)
+	agentSocketPath = cfg.AgentSocket()
+
+	// Subsystem init results for /api/v1/health
+	health := newHealthRegistry()
 
 	// Init stores
-	store, _ := auth.NewStore(cfg.UsersPath)
-	users, _ := userstore.New(cfg.UsersPath)
+	users, err := userstore.New(cfg.UsersPath)
+	if err == nil {
+		err = checkUsersFile(cfg.UsersPath)
+	}
+	health.set("users_store", true, err)
 	codec := auth.NewSessionCodec(cfg.SessionHashKey, cfg.SessionBlockKey)
 	InitJobsStore(cfg)
 
@@ -189,11 +142,15 @@ func NewRouter(cfg config.Config) http.Handler {
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialize shares handler")
 	}
+	health.set("shares", false, err)
+	health.probe("agent", false, probeUnixSocket(cfg.AgentSocket()))
+	health.requireForReadiness("agent")
 
 	// Initialize backup handler (using existing implementation)
 	// The existing backup handler requires scheduler, replicator, and restorer
 	// For now, we'll skip initializing it as it needs more complex setup
 	var backupHandler *BackupHandler
+	health.disable("backup")
 
 	// Initialize notifications manager
 	notificationsPath := filepath.Join(filepath.Dir(cfg.UsersPath), "notifications")
@@ -201,6 +158,8 @@ func NewRouter(cfg config.Config) http.Handler {
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialize notifications manager")
 	}
+	health.set("notifications", false, err)
+	setAlertNotifier(notificationManager)
 
 	// Initialize apps manager
 	appManagerConfig := &apps.Config{
@@ -216,7 +175,8 @@ func NewRouter(cfg config.Config) http.Handler {
 	if v := os.Getenv("NOS_APPS_STATE"); v != "" {
 		appManagerConfig.StateFile = v
 	}
-	appsManager, _ := apps.NewManager(appManagerConfig)
+	appsManager, err := apps.NewManager(appManagerConfig)
+	health.set("apps", false, err)
 	// Disk-backed session and ratelimit stores
 	sessStore := sessions.New(cfg.SessionsPath)
 	rlStore := ratelimit.New(cfg.RateLimitPath)
@@ -244,192 +204,129 @@ func NewRouter(cfg config.Config) http.Handler {
 		}
 	}()
 
-	// Session verification middleware for server-side binding (non-enforcing)
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			uid, sid, ok := decodeSessionParts(r, cfg)
-			if !ok || uid == "" || sid == "" {
-				next.ServeHTTP(w, r)
-				return
-			}
-			ua := r.Header.Get("User-Agent")
-			ip := clientIP(r, cfg)
-			if id, ok2 := mgr.Verify(sid, ua, ip); !ok2 || id != uid {
-				// do not enforce; continue without rejecting
-				next.ServeHTTP(w, r)
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
-	})
+	// Session verification middleware for server-side binding (IP prefix / UA);
+	// non-enforcing unless cfg.SessionBindingMode says otherwise
+	r.Use(sessionBinding(cfg, mgr))
 
 	// SessionContext middleware (parse cookies only; no auth enforcement)
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// parse nos_session; ignore failures
-			uid, sid, ok := decodeSessionParts(r, cfg)
-			if ok {
-				// attach to context via headers (lightweight)
-				r.Header.Set("X-UID", uid)
-				r.Header.Set("X-SID", sid)
-			}
-			next.ServeHTTP(w, r)
-		})
-	})
+	r.Use(sessionContext(cfg))
 
-	r.Get("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, map[string]any{"ok": true, "version": "0.9.5-pre-alpha"})
-	})
+	// Guard pprof: localhost only, and only while enabled (toggled on reload)
+	r.Mount("/debug/pprof", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !RuntimePprofEnabled() {
+			http.NotFound(w, r)
+			return
+		}
+		if !fromLocalhost(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.DefaultServeMux.ServeHTTP(w, r)
+	}))
+
+	healthStatus := handleAggregatedHealth(health, apiVersion)
+	r.Get("/api/v1/health", healthStatus)
+	// Orchestration probes; unauthenticated like /api/v1/health
+	r.Get("/healthz", handleLiveness())
+	r.Get("/readyz", handleReadiness(health))
 
 	// Health monitoring endpoints (for real-time data)
 	r.Get("/api/v1/health/system", handleSystemHealth(cfg))
 	r.Get("/api/v1/health/disks", handleDiskHealth(cfg))
-	r.Get("/api/v1/monitoring/system", handleSystemHealth(cfg)) // Reuse system health for monitoring
 
-	// Metrics endpoints allowed without /api prefix for tech monitoring
-	r.Get("/metrics", handleSystemHealth(cfg))
-	r.Get("/metrics/all", handleMetricsStream(cfg))
+	// Prometheus endpoints without the /api prefix for scrapers, only with
+	// metrics.enabled (chi panics on r.Use after a route, so keep these
+	// below the middleware)
+	if cfg.MetricsEnabled {
+		r.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			// allowlist is swapped on config reload
+			if !metricsAllowed(clientIP(r, cfg)) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			var b strings.Builder
+			b.WriteString("nosd_up 1\n")
+			// pool metrics (best-effort)
+			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+			defer cancel()
+			if list, err := pools.ListPools(ctx); err == nil {
+				var total uint64
+				var used uint64
+				for _, p := range list {
+					total += p.Size
+					used += p.Used
+				}
+				b.WriteString(fmt.Sprintf("pool_total_bytes %d\n", total))
+				b.WriteString(fmt.Sprintf("pool_used_bytes %d\n", used))
+			}
+			// SMART metrics for common devices (best-effort)
+			for _, dev := range []string{"/dev/sda", "/dev/nvme0n1"} {
+				client := agentclient.New(cfg.AgentSocket())
+				var out map[string]any
+				if err := client.GetJSON(r.Context(), "/v1/smart?device="+dev, &out); err == nil {
+					if t, ok := out["temperature_c"].(float64); ok {
+						b.WriteString(fmt.Sprintf("smart_disk_temp_celsius{dev=\"%s\"} %g\n", dev, t))
+					}
+					if st, ok := out["passed"].(bool); ok {
+						if st {
+							b.WriteString(fmt.Sprintf("smart_pass{dev=\"%s\"} 1\n", dev))
+						} else {
+							b.WriteString(fmt.Sprintf("smart_pass{dev=\"%s\"} 0\n", dev))
+						}
+					}
+				}
+			}
+			// Btrfs balance/scrub/replace progress of running operations
+			for _, e := range poolProgress.All() {
+				b.WriteString(fmt.Sprintf("btrfs_%s_percent{pool=%q} %g\n", e.Kind, e.Pool, e.Percent))
+			}
+			_, _ = w.Write([]byte(b.String()))
+		})
+		// Combined metrics endpoint: nosd + agent
+		r.Get("/metrics/all", func(w http.ResponseWriter, r *http.Request) {
+			NewCombinedMetricsHandler(prom.DefaultGatherer, agentMetricsClient{socket: cfg.AgentSocket()}).ServeHTTP(w, r)
+		})
+	}
+
+	// Live metrics as SSE; /api/v1/metrics/ws is the WebSocket variant
+	r.Get("/api/v1/metrics/stream", handleMetricsStream(cfg))
 
 	// Dashboard endpoints (v1)
 	r.Get("/api/v1/dashboard", api.HandleDashboard)
 	r.Get("/api/v1/storage/summary", api.HandleStorageSummary)
 	r.Get("/api/v1/health/disks/summary", api.HandleDisksSummary)
 	r.Get("/api/v1/events/recent", api.HandleRecentEvents)
-	r.Get("/api/v1/maintenance/status", api.HandleMaintenanceStatus)
 
 	// Storage: block device inventory
-	r.Get("/api/v1/storage/devices", handleListDevices)
+	r.Get("/api/v1/storage/devices", handleListDevices(cfg))
 	// SMART health proxy
-	r.Get("/api/v1/health/smart", handleSmartProxy)
-
-	// Storage: block device inventory
-	r.Get("/api/v1/storage/devices", handleListDevices)
+	r.Get("/api/v1/health/smart", handleSmartProxy(cfg))
 
 	// Recovery routes (localhost only)
 	if cfg.RecoveryMode {
 		r.Route("/api/v1/recovery", func(rr chi.Router) {
-			rr.Use(func(next http.Handler) http.Handler {
-				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					ip := r.RemoteAddr
-					if i := strings.LastIndex(ip, ":"); i >= 0 {
-						ip = ip[:i]
-					}
-					if ip != "127.0.0.1" && ip != "::1" {
-						w.WriteHeader(http.StatusForbidden)
-						return
-					}
-					next.ServeHTTP(w, r)
-				})
-			})
-			rr.Post("/reset-password", func(w http.ResponseWriter, r *http.Request) {
-				var body struct{ Username, Password string }
-				_ = json.NewDecoder(r.Body).Decode(&body)
-				if strings.TrimSpace(body.Username) == "" || strings.TrimSpace(body.Password) == "" {
-					w.WriteHeader(http.StatusBadRequest)
-					return
-				}
-				users, err := userstore.New(cfg.UsersPath)
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
-				u, err := users.FindByUsername(strings.ToLower(body.Username))
-				if err != nil {
-					w.WriteHeader(http.StatusNotFound)
-					return
-				}
-				h, herr := pwhash.HashPassword(body.Password)
-				if herr != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
-				u.PasswordHash = h
-				u.LockedUntil = ""
-				u.FailedAttempts = 0
-				_ = users.UpsertUser(u)
-				writeJSON(w, map[string]any{"ok": true})
-			})
-			rr.Post("/disable-2fa", func(w http.ResponseWriter, r *http.Request) {
-				var body struct{ Username string }
-				_ = json.NewDecoder(r.Body).Decode(&body)
-				if strings.TrimSpace(body.Username) == "" {
-					w.WriteHeader(http.StatusBadRequest)
-					return
-				}
-				users, err := userstore.New(cfg.UsersPath)
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
-				u, err := users.FindByUsername(strings.ToLower(body.Username))
-				if err != nil {
-					w.WriteHeader(http.StatusNotFound)
-					return
-				}
-				u.TOTPEnc = ""
-				u.RecoveryHashes = nil
-				_ = users.UpsertUser(u)
-				writeJSON(w, map[string]any{"ok": true})
-			})
-			rr.Post("/generate-otp", func(w http.ResponseWriter, r *http.Request) {
-				// Regenerate a one-time setup OTP (best-effort)
-				var st struct {
-					OTP       string `json:"otp"`
-					CreatedAt string `json:"created_at"`
-					Used      bool   `json:"used"`
-				}
-				st.OTP = genOTP6()
-				st.CreatedAt = time.Now().UTC().Format(time.RFC3339)
-				st.Used = false
-				_ = os.MkdirAll(filepath.Dir(cfg.FirstBootPath), 0o755)
-				_ = fsatomic.SaveJSON(r.Context(), cfg.FirstBootPath, st, 0o600)
-				_ = writeFirstBootOTPFile(st.OTP)
-				writeJSON(w, map[string]any{"otp": st.OTP})
-			})
+			rr.Use(localOnly(forbidden))
+			rr.Use(rateLimit(rlStore, cfg, "recovery", byPeerIP(), sensitiveRateLimit, sensitiveRateWindow))
+			rr.Post("/reset-password", handleRecoveryResetPassword(cfg, rlStore, sessStore, mgr))
+			rr.Post("/disable-2fa", handleRecoveryDisable2FA(cfg, rlStore))
+			rr.Post("/generate-otp", handleRecoveryGenerateOTP(cfg))
 		})
 	}
 
 	// Agent registration (bootstrap trust)
-	r.Post("/api/v1/agents/register", func(w http.ResponseWriter, r *http.Request) {
-		if !cfg.AllowAgentRegistration {
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
-		var body struct {
-			Token string `json:"token"`
-			Node  string `json:"node"`
-			Arch  string `json:"arch"`
-			OS    string `json:"os"`
-		}
-		_ = json.NewDecoder(r.Body).Decode(&body)
-		// compare against bootstrap token
-		bootTok, _ := os.ReadFile("/etc/nos/agent-token")
-		if len(bootTok) == 0 || strings.TrimSpace(body.Token) != strings.TrimSpace(string(bootTok)) {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		// rotate per-agent token and persist (very simple JSON list)
-		agentsPath := filepath.Join("/var/lib/nos", "agents.json")
-		type agentRec struct{ ID, Token, Node, Arch, OS, CreatedAt string }
-		var list []agentRec
-		if b, err := os.ReadFile(agentsPath); err == nil {
-			_ = json.Unmarshal(b, &list)
-		}
-		id := generateUUID()
-		tok := generateUUID()
-		rec := agentRec{ID: id, Token: tok, Node: body.Node, Arch: body.Arch, OS: body.OS, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
-		list = append(list, rec)
-		_ = os.MkdirAll(filepath.Dir(agentsPath), 0o755)
-		_ = fsatomic.SaveJSON(r.Context(), agentsPath, list, 0o600)
-		writeJSON(w, map[string]any{"id": id, "token": tok})
-	})
+	r.Post("/api/v1/agents/register", handleAgentRegister(cfg, rlStore))
+	r.Post("/api/v1/agents/heartbeat", handleAgentHeartbeat())
+
+	setupProgress := wizard.New(setupProgressPath(cfg))
 
 	// Setup routes are always registered under /api/v1, but gated with 410 when setup is complete
 	r.Route("/api/v1/setup", func(sr chi.Router) {
 		sr.Use(func(next http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				// Allow /complete endpoint to bypass the check
-				if strings.HasSuffix(r.URL.Path, "/complete") {
+				// Allow /complete and /progress to bypass the check: the steps
+				// after the first admin still run with the setup token
+				if strings.HasSuffix(r.URL.Path, "/complete") || strings.HasSuffix(r.URL.Path, "/progress") {
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -457,27 +354,30 @@ func NewRouter(cfg config.Config) http.Handler {
 			writeJSON(w, map[string]any{"firstBoot": firstBoot, "otpRequired": otpRequired})
 		})
 
-		// Rate limiter (persisted): per-IP cfg.RateOTPPerMin per minute for setup endpoints
+		// Rate limiter (persisted): per-IP RuntimeRateLimits().OTPPerMin per window for setup endpoints
 		sr.Post("/otp/verify", func(w http.ResponseWriter, r *http.Request) {
 			ip := clientIP(r, cfg)
-			otpWin := time.Duration(cfg.RateOTPWindowSec) * time.Second
-			if otpWin <= 0 {
-				otpWin = time.Minute
-			}
-			ok1, rem1, reset1 := rlStore.Allow("otp:ip:"+ip, cfg.RateOTPPerMin, otpWin)
+			lim := RuntimeRateLimits()
+			ok1, rem1, reset1 := rlStore.Allow("otp:ip:"+ip, lim.OTPPerMin, lim.OTPWindow)
+			setRateLimitHeaders(w, lim.OTPPerMin, rem1, reset1)
 			if !ok1 {
 				retry := int(time.Until(reset1).Seconds())
 				Logger(cfg).Warn().Str("event", "rate.limited").Str("route", "/api/v1/setup/otp/verify").Str("key", "otp:ip:"+ip).Int("remaining", rem1).Int("retryAfterSec", retry).Msg("")
 				httpx.WriteTypedError(w, http.StatusTooManyRequests, "rate.limited", "Too many attempts. Try later.", retry)
 				return
 			}
-			var body struct{ OTP string }
-			_ = json.NewDecoder(r.Body).Decode(&body)
+			var body struct {
+				OTP string `json:"otp"`
+			}
+			if err := decodeStrict(r, &body); err != nil {
+				writeInputError(w, err)
+				return
+			}
 			if len(body.OTP) != 6 {
 				httpx.WriteTypedError(w, http.StatusBadRequest, "setup.otp.invalid", "Enter the 6-digit code", 0)
 				return
 			}
-			st, err := firstboot.New(cfg.FirstBootPath).Load()
+			st, err := checkSetupOTP(cfg, body.OTP, lim.OTPMaxAttempts)
 			if err != nil {
 				if os.IsPermission(err) {
 					httpx.WriteTypedError(w, http.StatusInternalServerError, "storage_error", "setup storage not writable", 0)
@@ -486,10 +386,20 @@ func NewRouter(cfg config.Config) http.Handler {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			if st == nil || st.OTP == "" || st.OTP != body.OTP {
+			if st == nil {
 				httpx.WriteTypedError(w, http.StatusBadRequest, "setup.otp.invalid", "Invalid one-time code", 0)
 				return
 			}
+			if st.FailedAttempts > 0 {
+				left := lim.OTPMaxAttempts - st.FailedAttempts
+				if left <= 0 {
+					Logger(cfg).Warn().Str("event", "setup.otp.locked").Str("ip", ip).Int("attempts", st.FailedAttempts).Msg("")
+					httpx.WriteTypedError(w, http.StatusGone, "setup.otp.locked", "Too many wrong codes. Request a new one.", 0)
+					return
+				}
+				httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "setup.otp.invalid", "Invalid one-time code", map[string]any{"attemptsRemaining": left})
+				return
+			}
 			if time.Now().After(st.ExpiresAt) {
 				httpx.WriteTypedError(w, http.StatusGone, "setup.otp.expired", "Your code expired. Request a new one.", 0)
 				return
@@ -509,6 +419,25 @@ func NewRouter(cfg config.Config) http.Handler {
 			writeJSON(w, map[string]any{"ok": true, "token": val})
 		})
 
+		// Fresh OTP for a console user whose code expired or was locked out;
+		// the setup gate above already answers 410 once an admin exists
+		otpLocalOnly := localOnly(func(w http.ResponseWriter, r *http.Request) {
+			httpx.WriteTypedError(w, http.StatusForbidden, "setup.local_only", "OTP regeneration is only available from the console", 0)
+		})
+		sr.With(otpLocalOnly, rateLimit(rlStore, cfg, "setup-otp-regenerate", byPeerIP(), 5, sensitiveRateWindow)).Post("/otp/regenerate", func(w http.ResponseWriter, r *http.Request) {
+			st, err := issueSetupOTP(cfg)
+			if err != nil {
+				Logger(cfg).Error().Str("event", "setup.otp.regenerate_failed").Err(err).Msg("")
+				auditRecovery(cfg, r, recoveryOTPRegenerate, "", recoveryFailed)
+				httpx.WriteTypedError(w, http.StatusInternalServerError, "storage_error", "setup storage not writable", 0)
+				return
+			}
+			auditRecovery(cfg, r, recoveryOTPRegenerate, "", recoveryOK)
+			// only direct localhost callers get here, so the code is returned
+			Logger(cfg).Info().Str("event", "setup.otp.regenerated").Time("expiresAt", st.ExpiresAt).Msg("First-boot OTP: " + st.OTP + " (valid 15m)")
+			writeJSON(w, map[string]any{"otp": st.OTP, "expiresAt": st.ExpiresAt})
+		})
+
 		// First admin creation (consumes setup token)
 		sr.With(requireSetupAuth(cfg)).Post("/first-admin", func(w http.ResponseWriter, r *http.Request) {
 			if users == nil {
@@ -519,8 +448,14 @@ func NewRouter(cfg config.Config) http.Handler {
 				Username   string `json:"username"`
 				Password   string `json:"password"`
 				EnableTOTP bool   `json:"enable_totp"`
+				// Token is sent by the web client but the setup token is
+				// taken from the header or cookie by requireSetupAuth.
+				Token string `json:"token"`
+			}
+			if err := decodeStrict(r, &body); err != nil {
+				writeInputError(w, err)
+				return
 			}
-			_ = json.NewDecoder(r.Body).Decode(&body)
 			uname := strings.TrimSpace(body.Username)
 			if !validUsername(uname) {
 				httpx.WriteTypedError(w, http.StatusBadRequest, "input.invalid", "Invalid username", 0)
@@ -541,7 +476,7 @@ func NewRouter(cfg config.Config) http.Handler {
 				return
 			}
 			now := time.Now().UTC().Format(time.RFC3339)
-			u := userstore.User{ID: generateUUID(), Username: uname, PasswordHash: phc, Roles: []string{"admin"}, CreatedAt: now, UpdatedAt: now}
+			u := userstore.User{ID: generateUUID(), Username: uname, PasswordHash: phc, Roles: []string{"admin"}, CreatedAt: now, UpdatedAt: now, PasswordChangedAt: now}
 			if body.EnableTOTP {
 				u.TOTPEnc = "pending"
 			}
@@ -558,6 +493,9 @@ func NewRouter(cfg config.Config) http.Handler {
 				httpx.WriteErrorWithDetails(w, http.StatusInternalServerError, code, "Service cannot write /etc/nos/users.json", map[string]any{"path": cfg.UsersPath})
 				return
 			}
+			if _, err := setupProgress.Set("admin", true); err != nil {
+				Logger(cfg).Warn().Str("event", "setup.progress.persist_error").Err(err).Msg("")
+			}
 			// Success: remove first-boot state so OTP stops printing on restarts (best-effort)
 			_ = os.Remove(cfg.FirstBootPath)
 			// Remove OTP files (best-effort)
@@ -570,6 +508,10 @@ func NewRouter(cfg config.Config) http.Handler {
 			w.WriteHeader(http.StatusOK)
 		})
 
+		// Wizard step tracking so the UI can resume a partial setup
+		sr.With(requireSetupAuth(cfg)).Get("/progress", handleSetupProgressGet(cfg, setupProgress))
+		sr.With(requireSetupAuth(cfg)).Post("/progress", handleSetupProgressPost(cfg, setupProgress))
+
 		// Mark setup as complete - called after all setup steps are done
 		sr.With(requireSetupAuth(cfg)).Post("/complete", func(w http.ResponseWriter, r *http.Request) {
 			// Check if already complete
@@ -604,23 +546,22 @@ func NewRouter(cfg config.Config) http.Handler {
 		})
 	})
 
+	// Scheduled jobs ask before starting (systemd ExecCondition, local only)
+	r.With(localOnly(forbidden)).Post("/api/v1/maintenance/dispatch", handleMaintenanceDispatch(cfg))
+
 	// Recovery: local-only endpoint to clear first-boot state and optionally users
-	r.Post("/api/v1/setup/recover", func(w http.ResponseWriter, r *http.Request) {
-		// Guard: localhost only
-		ip := r.RemoteAddr
-		if i := strings.LastIndex(ip, ":"); i >= 0 {
-			ip = ip[:i]
-		}
-		if ip != "127.0.0.1" && ip != "::1" {
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
+	r.With(localOnly(forbidden), rateLimit(rlStore, cfg, "setup-recover", byPeerIP(), sensitiveRateLimit, sensitiveRateWindow)).Post("/api/v1/setup/recover", func(w http.ResponseWriter, r *http.Request) {
 		var body struct {
 			Confirm     string `json:"confirm"`
 			DeleteUsers bool   `json:"delete_users"`
 		}
-		_ = json.NewDecoder(r.Body).Decode(&body)
+		if err := decodeStrict(r, &body); err != nil {
+			auditRecovery(cfg, r, recoverySetupReset, "", recoveryInvalid)
+			writeInputError(w, err)
+			return
+		}
 		if strings.ToLower(strings.TrimSpace(body.Confirm)) != "yes" {
+			auditRecovery(cfg, r, recoverySetupReset, "", recoveryInvalid)
 			httpx.WriteTypedError(w, http.StatusPreconditionRequired, "confirm.required", "confirm=yes required", 0)
 			return
 		}
@@ -629,20 +570,22 @@ func NewRouter(cfg config.Config) http.Handler {
 		_ = os.Remove("/tmp/nos-otp")
 		_ = os.Remove("/etc/nos/otp")
 		_ = os.Remove("/run/nos/firstboot-otp")
+		target := ""
 		if body.DeleteUsers {
 			_ = os.Remove(cfg.UsersPath)
+			target = "all users"
 		}
+		auditRecovery(cfg, r, recoverySetupReset, target, recoveryOK)
 		writeJSON(w, map[string]any{"ok": true})
 	})
 
 	// Remove legacy login limiter seed (persisted store is the single source of truth)
 	// (intentionally left blank)
 
-	// Serve minimal OpenAPI JSON for v1 at /api/v1/openapi.json
-	r.Get("/api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_, _ = w.Write([]byte(`{"openapi":"3.0.3","info":{"title":"NithronOS API","version":"0.9.5-pre-alpha"},"servers":[{"url":"/api/v1"}]}`))
-	})
+	// OpenAPI document for every registered route; generated once the
+	// router is complete, below
+	spec := &openapiSpec{}
+	r.Get("/api/v1/openapi.json", spec.serve)
 
 	// (Removed legacy unversioned first-admin handler; canonical handler is under /api/v1/setup in the block above.)
 
@@ -661,20 +604,7 @@ func NewRouter(cfg config.Config) http.Handler {
 
 		// Apply rate limiting first (before any other checks)
 		ip := clientIP(r, cfg)
-		loginWin := time.Duration(cfg.RateLoginWindowSec) * time.Second
-		if loginWin <= 0 {
-			loginWin = 15 * time.Minute
-		}
-		okIP, _, resetIP := rlStore.Allow("login:ip:"+ip, cfg.RateLoginPer15m, loginWin)
-		okUser, _, resetUser := rlStore.Allow("login:user:"+strings.ToLower(uname), cfg.RateLoginPer15m, loginWin)
-		if !okIP || !okUser {
-			retry := resetIP
-			if time.Until(resetUser) > 0 && resetUser.After(retry) {
-				retry = resetUser
-			}
-			Logger(cfg).Warn().Str("event", "rate.limited").Str("key", "login").Str("ip", ip).Int("limit", cfg.RateLoginPer15m).Time("resetAt", retry).Msg("")
-			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(retry).Seconds())))
-			httpx.WriteError(w, http.StatusTooManyRequests, `{"error":{"code":"rate.limited","retryAfterSec":`+strconv.Itoa(int(time.Until(retry).Seconds()))+`}}`)
+		if !allowLogin(w, cfg, rlStore, ip, uname) {
 			return
 		}
 
@@ -692,69 +622,78 @@ func NewRouter(cfg config.Config) http.Handler {
 			return
 		}
 		// Check account lock
-		if u.LockedUntil != "" {
-			if t, err := time.Parse(time.RFC3339, u.LockedUntil); err == nil && time.Now().Before(t) {
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+		if accountLocked(u) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
 		}
 		ph := u.PasswordHash
-		ok := false
-		if strings.HasPrefix(ph, "dev:") || strings.HasPrefix(ph, "plain:") {
-			ok = strings.TrimPrefix(strings.TrimPrefix(ph, "dev:"), "plain:") == pass
-		} else {
-			ok = pwhash.VerifyPassword(ph, pass)
-		}
+		ok := checkPassword(ph, pass)
 		if !ok {
-			// increment failure; lock after 10
-			u.FailedAttempts++
-			if u.FailedAttempts >= 10 {
-				u.FailedAttempts = 0
-				u.LockedUntil = time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339)
-			}
-			_ = users.UpsertUser(u)
+			recordLoginFailure(users, &u)
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		// Second factor once TOTP is confirmed: a TOTP code, or a recovery
+		// code for users who lost their authenticator (single use)
+		recoveryLeft := -1
+		if totpActive(u) {
+			if strings.TrimSpace(body.Code) == "" {
+				httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.totp_required", "TOTP code required", 0)
+				return
+			}
+			switch checkLoginCode(cfg, &u, body.Code) {
+			case "recovery":
+				recoveryLeft = len(u.RecoveryHashes)
+				if err := users.UpsertUser(u); err != nil {
+					httpx.WriteError(w, http.StatusInternalServerError, "persist error")
+					return
+				}
+				Logger(cfg).Warn().Str("event", "auth.recovery_code.used").Str("userId", u.ID).Str("ip", ip).Int("remaining", recoveryLeft).Msg("")
+			case "":
+				recordLoginFailure(users, &u)
+				httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.totp_invalid", "Invalid TOTP or recovery code", 0)
+				return
+			}
+		}
 		// success: reset counters
 		u.FailedAttempts = 0
 		u.LockedUntil = ""
-		_ = users.UpsertUser(u)
-		if err := issueSessionCookies(w, cfg, u.ID, body.RememberMe); err != nil {
-			httpx.WriteError(w, http.StatusInternalServerError, "session error")
-			return
+		// transparently upgrade hashes made with weaker argon2 params
+		if pwhash.NeedsRehash(ph) {
+			if nh, err := pwhash.HashPassword(pass); err == nil {
+				u.PasswordHash = nh
+				Logger(cfg).Info().Str("event", "auth.password.rehash").Str("userId", u.ID).Msg("")
+			}
 		}
+		_ = users.UpsertUser(u)
 		// persist session record (best-effort)
-		_ = sessStore.Upsert(sessions.Session{ID: generateUUID(), UserID: u.ID, Roles: u.Roles, ExpiresAt: time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339)})
-		// bind server-side session
+		lifetime := sessionLifetime(cfg, body.RememberMe)
+		_ = sessStore.Upsert(sessions.Session{ID: generateUUID(), UserID: u.ID, Roles: u.Roles, ExpiresAt: time.Now().Add(lifetime).UTC().Format(time.RFC3339)})
+		// bind server-side session; without a sid the cookies couldn't be
+		// revoked, so a session that can't be recorded fails the login
 		ua := r.Header.Get("User-Agent")
 		ip = clientIP(r, cfg)
-		rec, _ := mgr.Create(u.ID, ua, ip, 15*time.Minute)
-		_ = issueSessionCookiesSID(w, cfg, u.ID, rec.SID, body.RememberMe)
+		rec, err := mgr.Create(u.ID, ua, ip, lifetime)
+		if err != nil {
+			_ = mgr.RevokeSID(rec.SID)
+			Logger(cfg).Error().Err(err).Str("event", "auth.session.create_failed").Str("userId", u.ID).Msg("")
+			httpx.WriteError(w, http.StatusInternalServerError, "session error")
+			return
+		}
+		if err := issueSessionCookiesSID(w, cfg, u.ID, rec.SID, body.RememberMe); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "session error")
+			return
+		}
 		issueCSRFCookie(w)
-		writeJSON(w, map[string]any{"ok": true})
-	})
-
-	// Record refresh events in sessions store (best-effort)
-	r.Post("/api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
-		if uid, ok := decodeRefreshUID(r, cfg); ok {
-			// rotate refresh; revoke all if reuse detected
-			old := r.Header.Get("X-Refresh-ID")
-			newID, reuse, _ := mgr.RotateRefresh(uid, strings.TrimSpace(old))
-			if reuse {
-				_ = mgr.RevokeAll(uid)
-				clearAuthCookies(w)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-			_ = sessStore.Upsert(sessions.Session{ID: generateUUID(), UserID: uid, Roles: []string{"refresh"}, ExpiresAt: time.Now().Add(7 * 24 * time.Hour).UTC().Format(time.RFC3339)})
-			if err := issueSessionCookies(w, cfg, uid, true); err == nil {
-				w.Header().Set("X-Refresh-ID", newID)
-				writeJSON(w, map[string]any{"ok": true})
-				return
-			}
+		pw := passwordAgeStatus(cfg, users, &u, time.Now())
+		if pw.ChangeRequired {
+			Logger(cfg).Info().Str("event", "auth.password.change_required").Str("userId", u.ID).Bool("expired", pw.Expired).Msg("")
 		}
-		w.WriteHeader(http.StatusUnauthorized)
+		resp := map[string]any{"ok": true, "passwordChangeRequired": pw.ChangeRequired, "password": pw}
+		if recoveryLeft >= 0 {
+			resp["recoveryCodesRemaining"] = recoveryLeft
+		}
+		writeJSON(w, resp)
 	})
 
 	// Logout: clear cookies and remove persisted sessions for this user (best-effort)
@@ -783,7 +722,7 @@ func NewRouter(cfg config.Config) http.Handler {
 					"roles":    u.Roles,
 					"isAdmin":  hasRole(u.Roles, "admin"),
 				},
-				"expiresAt": time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339),
+				"expiresAt": time.Now().Add(cfg.SessionTTL()).UTC().Format(time.RFC3339),
 			})
 			return
 		}
@@ -802,7 +741,7 @@ func NewRouter(cfg config.Config) http.Handler {
 			}
 			list := mgr.ListByUser(uid)
 			// mark current
-			curSID := r.Header.Get("X-SID")
+			curSID := sessionSID(r)
 			out := make([]map[string]any, 0, len(list))
 			for _, s := range list {
 				out = append(out, map[string]any{
@@ -827,13 +766,10 @@ func NewRouter(cfg config.Config) http.Handler {
 			if body.Scope == "" {
 				body.Scope = "current"
 			}
-			ip := r.RemoteAddr
-			if h := r.Header.Get("X-Forwarded-For"); h != "" {
-				ip = strings.Split(h, ",")[0]
-			}
+			ip := clientIP(r, cfg)
 			switch body.Scope {
 			case "current":
-				cur := r.Header.Get("X-SID")
+				cur := sessionSID(r)
 				if cur != "" {
 					_ = mgr.RevokeSID(cur)
 				}
@@ -873,7 +809,14 @@ func NewRouter(cfg config.Config) http.Handler {
 	r.Get("/api/v1/auth/me", func(w http.ResponseWriter, r *http.Request) {
 		if uid, ok := decodeSessionUID(r, cfg); ok {
 			if u, err := users.FindByID(uid); err == nil {
-				writeJSON(w, map[string]any{"user": map[string]any{"id": u.ID, "username": u.Username, "roles": u.Roles}})
+				out := map[string]any{
+					"user":     map[string]any{"id": u.ID, "username": u.Username, "roles": u.Roles},
+					"password": passwordAgeStatus(cfg, users, &u, time.Now()),
+				}
+				if b := sessionBindingStatus(r); b != nil {
+					out["sessionBinding"] = b
+				}
+				writeJSON(w, out)
 				return
 			}
 		}
@@ -885,60 +828,25 @@ func NewRouter(cfg config.Config) http.Handler {
 	})
 
 	r.Post("/api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
-		if uid, ok := decodeRefreshUID(r, cfg); ok {
-			if err := issueSessionCookies(w, cfg, uid, true); err == nil {
-				writeJSON(w, map[string]any{"ok": true})
-				return
+		// a refresh cookie without a sid predates revocation and can't be
+		// checked against it, so its holder signs in again
+		uid, sid, ok := decodeRefreshParts(r, cfg)
+		if ok && sid != "" {
+			// a revoked session can't be refreshed back to life
+			if id, _, live := mgr.Check(sid, r.Header.Get("User-Agent"), clientIP(r, cfg)); live && id == uid {
+				if err := issueSessionCookiesSID(w, cfg, uid, sid, true); err == nil {
+					writeJSON(w, map[string]any{"ok": true})
+					return
+				}
 			}
 		}
 		w.WriteHeader(http.StatusUnauthorized)
 	})
 
-	// TOTP setup & confirm
-	r.Post("/api/v1/auth/totp/setup", func(w http.ResponseWriter, r *http.Request) {
-		var body struct{ Email, Password string }
-		_ = json.NewDecoder(r.Body).Decode(&body)
-		u, err := store.GetByEmail(body.Email)
-		// TODO: Fix password verification - UserManager should handle this
-		if err != nil /*|| !auth.VerifyPassword(auth.DefaultParams, u.PasswordHash, body.Password)*/ {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		// TODO: Check if TOTP is enabled via UserManager
-		if false /*u.TOTPSecret != ""*/ {
-			w.WriteHeader(http.StatusConflict)
-			if err := json.NewEncoder(w).Encode(map[string]any{"error": "totp_already_enabled"}); err != nil {
-				fmt.Printf("Failed to write response: %v\n", err)
-			}
-			return
-		}
-		secret, uri, err := auth.GenerateTOTPSecret("NithronOS", u.Email)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		// TODO: Store TOTP secret via UserManager
-		// u.TOTPSecret = secret
-		_ = store.UpdateUser(u)
-		writeJSON(w, map[string]any{"secret": secret, "otpauth": uri})
-	})
-
-	r.Post("/api/v1/auth/totp/confirm", func(w http.ResponseWriter, r *http.Request) {
-		var body struct{ Email, Code string }
-		_ = json.NewDecoder(r.Body).Decode(&body)
-		_, err := store.GetByEmail(body.Email)
-		// TODO: Check TOTP secret via UserManager
-		if err != nil /*|| u.TOTPSecret == ""*/ {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		// TODO: Verify TOTP via UserManager
-		if false /*!auth.VerifyTOTP(u.TOTPSecret, body.Code)*/ {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		writeJSON(w, map[string]any{"ok": true})
-	})
+	// TOTP setup & confirm for password-only accounts (no session needed)
+	totpRL := rateLimit(rlStore, cfg, "totp-setup", byClientIP(cfg), sensitiveRateLimit, sensitiveRateWindow)
+	r.With(totpRL).Post("/api/v1/auth/totp/setup", handleTOTPSetup(cfg, users, rlStore))
+	r.With(totpRL).Post("/api/v1/auth/totp/confirm", handleTOTPConfirm(cfg, users))
 
 	// Protected routes
 	r.Group(func(pr chi.Router) {
@@ -950,6 +858,7 @@ func NewRouter(cfg config.Config) http.Handler {
 		if os.Getenv("NOS_TEST_SKIP_AUTH") != "1" {
 			pr.Use(requireCSRF)
 		}
+		pr.Use(requirePasswordCurrent(cfg, users))
 
 		// AdminRequired middleware: resolve current user and assert role
 		adminRequired := func(next http.Handler) http.Handler {
@@ -989,8 +898,18 @@ func NewRouter(cfg config.Config) http.Handler {
 			})
 		}
 
-		// TOTP enroll (logged-in): generate secret, encrypt with secret.key, store pending enc
-		pr.Get("/api/v1/auth/totp/enroll", func(w http.ResponseWriter, r *http.Request) {
+		// Sessions across all users (admin view)
+		pr.With(adminRequired).Get("/api/v1/admin/sessions", handleAdminSessions(mgr, users))
+
+		// Registered remote agents
+		pr.With(adminRequired).Get("/api/v1/agents", handleListAgents())
+		pr.With(adminRequired).Delete("/api/v1/agents/{id}", handleDeleteAgent(cfg))
+
+		// TOTP enroll (logged-in): generate a secret and store it as pending.
+		// The current secret and recovery codes keep working until
+		// /auth/totp/verify confirms the new one; while 2FA is on, starting a
+		// re-enrollment needs the current code or the password.
+		pr.Post("/api/v1/auth/totp/enroll", func(w http.ResponseWriter, r *http.Request) {
 			uid, ok := decodeSessionUID(r, cfg)
 			if !ok {
 				if s, ok2 := codec.DecodeFromRequest(r); ok2 {
@@ -1002,11 +921,28 @@ func NewRouter(cfg config.Config) http.Handler {
 				w.WriteHeader(http.StatusUnauthorized)
 				return
 			}
+			size, err := totpQRSize(r)
+			if err != nil {
+				httpx.WriteTypedError(w, http.StatusBadRequest, "input.invalid", err.Error(), 0)
+				return
+			}
+			var body struct {
+				Code     string `json:"code"`
+				Password string `json:"password"`
+			}
+			if err := decodeStrict(r, &body); err != nil {
+				writeInputError(w, err)
+				return
+			}
 			u, err := users.FindByID(uid)
 			if err != nil {
 				httpx.WriteError(w, http.StatusNotFound, "user not found")
 				return
 			}
+			if totpActive(u) && !totpReauthenticated(cfg, u, body.Code, body.Password) {
+				httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.reauth_required", "Enter your current code or password to replace your authenticator", 0)
+				return
+			}
 			secret, uri, err := auth.GenerateTOTPSecret("NithronOS", u.Username)
 			if err != nil {
 				httpx.WriteError(w, http.StatusInternalServerError, "totp error")
@@ -1017,20 +953,17 @@ func NewRouter(cfg config.Config) http.Handler {
 				httpx.WriteError(w, http.StatusInternalServerError, "encrypt error")
 				return
 			}
-			u.TOTPEnc = enc
+			qr, err := totpQRPNG(uri, size)
+			if err != nil {
+				httpx.WriteError(w, http.StatusInternalServerError, "qr error")
+				return
+			}
+			u.TOTPPendingEnc = enc
 			if err := users.UpsertUser(u); err != nil {
 				httpx.WriteError(w, http.StatusInternalServerError, "persist error")
 				return
 			}
-			writeJSON(w, map[string]any{"otpauth_url": uri, "qr_png_base64": ""})
-		})
-
-		// Allow POST for enroll to match nos-client
-		pr.Post("/api/v1/auth/totp/enroll", func(w http.ResponseWriter, r *http.Request) {
-			// Delegate to GET handler logic by invoking the same code path
-			r2 := r.Clone(r.Context())
-			r2.Method = http.MethodGet
-			pr.ServeHTTP(w, r2)
+			writeJSON(w, map[string]any{"otpauth_url": uri, "qr_png_base64": qr})
 		})
 
 		// TOTP verify (logged-in): verify code, generate recovery codes and persist hashes
@@ -1051,13 +984,23 @@ func NewRouter(cfg config.Config) http.Handler {
 				httpx.WriteError(w, http.StatusNotFound, "user not found")
 				return
 			}
-			var body struct{ Code string }
-			_ = json.NewDecoder(r.Body).Decode(&body)
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := decodeStrict(r, &body); err != nil {
+				writeInputError(w, err)
+				return
+			}
 			if len(body.Code) != 6 {
 				httpx.WriteError(w, http.StatusBadRequest, "invalid code")
 				return
 			}
-			secretB, err := decryptWithSecretKey(cfg.SecretPath, u.TOTPEnc)
+			// a pending re-enrollment is what gets confirmed
+			enc := u.TOTPEnc
+			if u.TOTPPendingEnc != "" {
+				enc = u.TOTPPendingEnc
+			}
+			secretB, err := decryptWithSecretKey(cfg.SecretPath, enc)
 			if err != nil {
 				httpx.WriteError(w, http.StatusBadRequest, "invalid state")
 				return
@@ -1067,7 +1010,9 @@ func NewRouter(cfg config.Config) http.Handler {
 				return
 			}
 			plain, hashes := generateRecoveryCodes()
+			u.TOTPEnc, u.TOTPPendingEnc = enc, ""
 			u.RecoveryHashes = hashes
+			u.TOTPEnabled = true
 			if err := users.UpsertUser(u); err != nil {
 				httpx.WriteError(w, http.StatusInternalServerError, "persist error")
 				return
@@ -1080,9 +1025,13 @@ func NewRouter(cfg config.Config) http.Handler {
 			if runtime.GOOS != "windows" && hasCommand("lsblk") {
 				if list, err := disks.Collect(ctx); err == nil {
 					// Enrich with SMART when possible
+					risk := loadHealthConfig(cfg).riskThresholds()
 					for i := range list {
 						if list[i].Path != "" {
 							list[i].Smart = disks.SmartSummaryFor(ctx, list[i].Path)
+							if list[i].Smart != nil {
+								list[i].Smart.EvaluateRisk(risk)
+							}
 						}
 					}
 					writeJSON(w, map[string]any{"disks": list})
@@ -1125,18 +1074,20 @@ func NewRouter(cfg config.Config) http.Handler {
 		// Monitoring endpoints
 		pr.Get("/api/v1/monitoring/logs", handleMonitoringLogs(cfg))
 		pr.Get("/api/v1/monitoring/events", handleMonitoringEvents(cfg))
+		pr.Get("/api/v1/monitoring/events/stream", handleMonitoringEventsStream)
 		pr.Get("/api/v1/monitoring/alerts", handleMonitoringAlerts(cfg))
 		pr.Get("/api/v1/monitoring/services", handleMonitoringServices(cfg))
 		pr.Get("/api/v1/monitoring/system", handleMonitoringSystem(cfg))
+		pr.Get("/api/v1/metrics/ws", handleMetricsWS)
 
 		// Scrub endpoints expected by frontend
 		pr.Get("/api/v1/scrub/status", func(w http.ResponseWriter, r *http.Request) {
 			// Delegate to pools scrub status
-			handleScrubStatus(w, r)
+			handleScrubStatus(cfg)(w, r)
 		})
 		pr.With(adminRequired).Post("/api/v1/scrub/start", func(w http.ResponseWriter, r *http.Request) {
 			// Delegate to pools scrub start
-			handleScrubStart(w, r)
+			handleScrubStart(cfg)(w, r)
 		})
 		pr.With(adminRequired).Post("/api/v1/scrub/cancel", func(w http.ResponseWriter, r *http.Request) {
 			// TODO: Implement scrub cancel
@@ -1153,30 +1104,37 @@ func NewRouter(cfg config.Config) http.Handler {
 		pr.Get("/api/v1/smart/devices", handleSmartDevices(cfg))
 		pr.Get("/api/v1/smart/device/{device}", handleSmartDevice(cfg))
 		pr.Get("/api/v1/smart/test/{device}", handleSmartTestDevice(cfg))
+		pr.Get("/api/v1/smart/history/{device}", handleSmartHistory)
 		pr.With(adminRequired).Post("/api/v1/smart/scan", handleSmartScan(cfg))
 		pr.With(adminRequired).Post("/api/v1/smart/test/{device}", handleSmartTestDevice(cfg))
 
 		// Jobs endpoints
 		pr.Get("/api/v1/jobs/recent", handleJobsRecent(cfg))
 		pr.Get("/api/v1/jobs/{id}", handleJobGet(cfg))
+		pr.Get("/api/v1/jobs/{id}/stream", handleJobStream)
+		pr.With(adminRequired).Post("/api/v1/jobs/{id}/cancel", handleJobCancel(cfg))
 
 		// Devices endpoint expected by frontend
 		pr.Get("/api/v1/devices", func(w http.ResponseWriter, r *http.Request) {
 			// Delegate to existing devices handler
-			handleListDevices(w, r)
+			handleListDevices(cfg)(w, r)
 		})
-		pr.With(adminRequired).Post("/api/v1/health/scan", handleHealthScan(cfg))
+		pr.With(adminRequired).Post("/api/v1/health/scan", handleHealthScan(cfg, notificationManager, healthTemps))
 		pr.With(adminRequired).Post("/api/v1/pools/apply-create", handleApplyCreate(cfg))
 		pr.With(adminRequired).Get("/api/v1/pools/discover", handlePoolsDiscover)
 		pr.With(adminRequired).Post("/api/v1/pools/import", handlePoolsImport(cfg))
-		// Device operations (plan/apply)
+		// Device operations (plan/apply); destructive applies honor Idempotency-Key
+		idem := idempotent(cfg)
 		pr.With(adminRequired).Post("/api/v1/pools/{id}/plan-device", handlePlanDevice(cfg))
-		pr.With(adminRequired).Post("/api/v1/pools/{id}/apply-device", handleApplyDevice(cfg))
+		pr.With(adminRequired, idem).Post("/api/v1/pools/{id}/apply-device", handleApplyDevice(cfg))
 		pr.With(adminRequired).Post("/api/v1/pools/{id}/plan-destroy", handlePlanDestroy(cfg))
-		pr.With(adminRequired).Post("/api/v1/pools/{id}/apply-destroy", handleApplyDestroy(cfg))
-		pr.With(adminRequired).Post("/api/v1/pools/scrub/start", handleScrubStart)
-		pr.With(adminRequired).Get("/api/v1/pools/scrub/status", handleScrubStatus)
-		pr.Get("/api/v1/pools/{id}", handlePoolDetail)
+		pr.With(adminRequired, idem).Post("/api/v1/pools/{id}/apply-destroy", handleApplyDestroy(cfg))
+		pr.With(adminRequired).Post("/api/v1/pools/scrub/start", handleScrubStart(cfg))
+		pr.With(adminRequired).Get("/api/v1/pools/scrub/status", handleScrubStatus(cfg))
+		pr.Get("/api/v1/pools/{id}", handlePoolDetail(cfg))
+		pr.Get("/api/v1/pools/{id}/forecast", handlePoolForecast)
+		pr.Get("/api/v1/pools/{id}/device-stats", handlePoolDeviceStats)
+		pr.Get("/api/v1/pools/{id}/snapshot-space", handlePoolSnapshotSpace)
 		// Mount options (canonical + compatibility with FE path)
 		pr.Get("/api/v1/pools/{id}/options", handlePoolOptionsGet(cfg))
 		pr.With(adminRequired).Post("/api/v1/pools/{id}/options", handlePoolOptionsPost(cfg))
@@ -1211,29 +1169,50 @@ func NewRouter(cfg config.Config) http.Handler {
 		})
 		pr.Get("/api/v1/pools/tx/{id}/stream", handleTxStream)
 
-		pr.With(adminRequired).Post("/api/v1/pools/create", func(w http.ResponseWriter, r *http.Request) {
+		pr.With(adminRequired, idem).Post("/api/v1/pools/create", func(w http.ResponseWriter, r *http.Request) {
 			if r.Header.Get("Confirm") != "yes" {
 				httpx.WriteError(w, http.StatusPreconditionRequired, "confirm header required")
 				return
 			}
 			var req pools.PlanRequest
 			_ = json.NewDecoder(r.Body).Decode(&req)
-			if err := pools.EnsureDevicesFree(r.Context(), req.Devices); err != nil {
+			refs, warnings, err := pools.ResolveDevices(req.Devices)
+			if err != nil {
+				writeDeviceRefError(w, err)
+				return
+			}
+			for i, ref := range refs {
+				req.Devices[i] = ref.Ref()
+			}
+			// Hold the devices from the free check through the agent call
+			if err := tryLockDevices(req.Devices, "create:"+generateUUID()); err != nil {
+				writePoolOperationInProgress(w)
+				return
+			}
+			defer releaseDevices(req.Devices)
+			if err := ensureDevicesFree(r.Context(), req.Devices); err != nil {
 				httpx.WriteError(w, http.StatusBadRequest, err.Error())
 				return
 			}
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
 			var resp map[string]any
-			err := client.PostJSON(r.Context(), "/v1/btrfs/create", map[string]any{
+			err = client.PostJSON(r.Context(), "/v1/btrfs/create", map[string]any{
 				"devices": req.Devices,
 				"raid":    req.Raid,
 				"label":   req.Label,
 				"dry_run": false,
 			}, &resp)
 			if err != nil {
-				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+				writeAgentError(w, err, "pools.create_failed", "Pool creation failed")
 				return
 			}
+			if resp == nil {
+				resp = map[string]any{}
+			}
+			resp["devices"] = refs
+			if len(warnings) > 0 {
+				resp["warnings"] = warnings
+			}
 			writeJSON(w, resp)
 		})
 
@@ -1252,7 +1231,7 @@ func NewRouter(cfg config.Config) http.Handler {
 		// Shares endpoints are handled by SharesHandler below
 		// SMB users proxy
 		pr.Get("/api/v1/smb/users", func(w http.ResponseWriter, r *http.Request) {
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
 			var out struct {
 				Users []string `json:"users"`
 			}
@@ -1272,17 +1251,18 @@ func NewRouter(cfg config.Config) http.Handler {
 		// pr.With(adminRequired).Post("/api/shares/{name}/test", sharesHandler.TestShare)
 
 		pr.With(adminRequired).Post("/api/v1/smb/users", func(w http.ResponseWriter, r *http.Request) {
-			var body struct{ Username, Password string }
-			_ = json.NewDecoder(r.Body).Decode(&body)
-			client := agentclient.New("/run/nos-agent.sock")
+			var body struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+			}
+			if err := decodeStrict(r, &body); err != nil {
+				writeInputError(w, err)
+				return
+			}
+			client := agentclient.New(cfg.AgentSocket())
 			var resp map[string]any
 			if err := client.PostJSON(r.Context(), "/v1/smb/user-create", map[string]any{"username": body.Username, "password": body.Password}, &resp); err != nil {
-				// If agent returned HTTPError 400, propagate 400
-				if he, ok := err.(*agentclient.HTTPError); ok && he.Status == http.StatusBadRequest {
-					httpx.WriteError(w, http.StatusBadRequest, he.Body)
-					return
-				}
-				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+				writeAgentError(w, err, "smb.user_create_failed", "Creating the SMB user failed")
 				return
 			}
 			writeJSON(w, map[string]any{"ok": true})
@@ -1305,8 +1285,8 @@ func NewRouter(cfg config.Config) http.Handler {
 				// Best-effort: in dev/test on Windows or when the agent socket isn't present, skip agent calls
 				var client *agentclient.Client
 				if runtime.GOOS != "windows" {
-					if _, err := os.Stat("/run/nos-agent.sock"); err == nil {
-						client = agentclient.New("/run/nos-agent.sock")
+					if _, err := os.Stat(cfg.AgentSocket()); err == nil {
+						client = agentclient.New(cfg.AgentSocket())
 					}
 				}
 				if sh.Type == "smb" {
@@ -1336,6 +1316,7 @@ func NewRouter(cfg config.Config) http.Handler {
 			go func() {
 				if err := appsManager.Start(context.Background()); err != nil {
 					fmt.Printf("Failed to start apps manager: %v\n", err)
+					health.set("apps", false, err)
 				}
 			}()
 
@@ -1349,7 +1330,6 @@ func NewRouter(cfg config.Config) http.Handler {
 			pr.Get("/api/v1/apps/{id}/events", handleGetAppEvents(appsManager))
 
 			// App lifecycle operations (admin only)
-			pr.With(adminRequired).Post("/api/v1/apps/install", handleInstallApp(appsManager))
 			pr.With(adminRequired).Post("/api/v1/apps/{id}/upgrade", handleUpgradeApp(appsManager))
 			pr.With(adminRequired).Post("/api/v1/apps/{id}/start", handleStartApp(appsManager))
 			pr.With(adminRequired).Post("/api/v1/apps/{id}/stop", handleStopApp(appsManager))
@@ -1357,13 +1337,16 @@ func NewRouter(cfg config.Config) http.Handler {
 			pr.With(adminRequired).Post("/api/v1/apps/{id}/rollback", handleRollbackApp(appsManager))
 			pr.With(adminRequired).Delete("/api/v1/apps/{id}", handleDeleteApp(appsManager))
 			pr.With(adminRequired).Post("/api/v1/apps/{id}/health", handleForceHealthCheck(appsManager))
+			pr.Get("/api/v1/apps/{id}/health/history", handleAppHealthHistory(appsManager))
 
 			// Admin operations
 			pr.With(adminRequired).Post("/api/v1/apps/catalog/sync", handleSyncCatalogs(appsManager))
+			pr.Get("/api/v1/apps/catalog/sources", handleCatalogSources(appsManager))
 		} else {
 			// Fallback: provide minimal implementations so FE endpoints exist
 			pr.Get("/api/v1/apps/catalog", func(w http.ResponseWriter, r *http.Request) {
-				writeJSON(w, apps.Catalog(cfg.AppsInstallDir))
+				body, _ := json.Marshal(apps.Catalog(cfg.AppsInstallDir))
+				serveCacheableJSON(w, r, append(body, '\n'), time.Time{})
 			})
 			pr.Get("/api/v1/apps/installed", func(w http.ResponseWriter, r *http.Request) {
 				writeJSON(w, map[string]any{"items": []any{}})
@@ -1373,9 +1356,12 @@ func NewRouter(cfg config.Config) http.Handler {
 			})
 		}
 
-		// Health endpoints
+		// SMART health endpoints; registered one by one, since a mount at
+		// /api/v1/health would also claim the public report above
 		healthHandler := NewHealthHandler(agentclient.New(cfg.AgentSocket()))
-		pr.Mount("/api/v1/health", healthHandler.Routes())
+		pr.Get("/api/v1/health/smart/summary", healthHandler.GetSmartSummary)
+		pr.Get("/api/v1/health/smart/{device}", healthHandler.GetSmartDevice)
+		pr.Post("/api/v1/health/smart/scan", healthHandler.StartSmartScan)
 
 		// Storage endpoints
 		storageHandler := NewStorageHandler(agentclient.New(cfg.AgentSocket()))
@@ -1385,9 +1371,18 @@ func NewRouter(cfg config.Config) http.Handler {
 		btrfsHandler := NewBtrfsHandler(agentclient.New(cfg.AgentSocket()))
 		pr.Mount("/api/v1/btrfs", btrfsHandler.Routes())
 
-		// Schedule endpoints
+		// Single schedules; the collection is handleSchedulesGet/Post above,
+		// which a mount at /api/v1/schedules would shadow
 		schedulesHandler := NewSchedulesHandler()
-		pr.Mount("/api/v1/schedules", schedulesHandler.Routes())
+		pr.Get("/api/v1/schedules/{id}", schedulesHandler.GetSchedule)
+		pr.Put("/api/v1/schedules/{id}", schedulesHandler.UpdateSchedule)
+		pr.Delete("/api/v1/schedules/{id}", schedulesHandler.DeleteSchedule)
+		// Backup schedule retention preview, read from the scheduler state
+		backupState := filepath.Join(filepath.Dir(cfg.UsersPath), "backup-schedules.json")
+		if v := os.Getenv("NOS_BACKUP_STATE"); v != "" {
+			backupState = v
+		}
+		pr.Post("/api/v1/schedules/{id}/retention/preview", handleRetentionPreview(backupState))
 
 		// Share endpoints (v1 API) - use real implementation
 		if sharesHandler != nil {
@@ -1424,10 +1419,17 @@ func NewRouter(cfg config.Config) http.Handler {
 		// Updates endpoints (M5)
 		updatesHandler := NewUpdatesHandler(cfg)
 		pr.Mount("/api/v1/updates", updatesHandler.Routes())
+		pr.Get("/api/v1/updates/channel", updatesHandler.GetChannel)
+		pr.With(adminRequired).Post("/api/v1/updates/channel", updatesHandler.SetChannel)
+		pr.Get("/api/v1/updates/holds", updatesHandler.GetHolds)
+		pr.With(adminRequired).Post("/api/v1/updates/holds", updatesHandler.SetHolds)
 
 		// Users management endpoints
 		usersHandler := NewUsersHandler(users, cfg)
-		pr.With(adminRequired).Mount("/api/v1/users", usersHandler.Routes())
+		accountLimit := rateLimit(rlStore, cfg, "account", byUserOrIP(cfg), sensitiveRateLimit, sensitiveRateWindow)
+		pr.With(adminRequired).Mount("/api/v1/users", usersHandler.Routes(accountLimit))
+		// self-service, so a non-admin with an expired password isn't stuck
+		pr.With(accountLimit).Post("/api/v1/auth/password", usersHandler.ChangeOwnPassword)
 
 		// Network configuration endpoints
 		networkConfigHandler := NewNetworkConfigHandler(cfg)
@@ -1436,6 +1438,8 @@ func NewRouter(cfg config.Config) http.Handler {
 		// Appearance settings endpoints
 		appearanceHandler := NewAppearanceHandler(cfg)
 		pr.Mount("/api/v1/settings/appearance", appearanceHandler.Routes())
+		pr.Get("/api/v1/settings/health", handleHealthSettingsGet(cfg))
+		pr.With(adminRequired).Put("/api/v1/settings/health", handleHealthSettingsPut(cfg))
 
 		// About/System info endpoints
 		aboutHandler := NewAboutHandler(cfg)
@@ -1481,15 +1485,47 @@ func NewRouter(cfg config.Config) http.Handler {
 				httpx.WriteError(w, http.StatusBadRequest, "id required")
 				return
 			}
+			app, ok := apps.Lookup(body.ID)
+			if !ok {
+				httpx.WriteTypedError(w, http.StatusNotFound, "apps.not_found", "App not found in catalog", 0)
+				return
+			}
+			// Validate before anything is written so bad params never reach compose
+			env, perrs := apps.ValidateParams(app.Params, body.Config)
+			if len(perrs) > 0 {
+				httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "apps.invalid_params", "Invalid app parameters", map[string]any{"fields": perrs})
+				return
+			}
+			// Reject a compose file that would only fail once the agent runs it
+			compose := composeTemplate(body.ID)
+			cerr := apps.ValidateCompose(compose, env)
+			if cerr == nil && composeConfigCheck != nil {
+				cerr = composeConfigCheck(r.Context(), compose, env)
+			}
+			if cerr != nil {
+				httpx.WriteErrorWithDetails(w, http.StatusUnprocessableEntity, "apps.invalid_compose", "Generated compose file is invalid: "+cerr.Error(), map[string]any{"error": cerr})
+				return
+			}
+			requested := apps.ComposeHostPorts(compose, env)
+			installed := apps.InstalledPorts(cfg.AppsInstallDir)
+			if conflicts := apps.CheckPorts(body.ID, requested, installed, apps.ListeningPorts(listeningPortTables...)); len(conflicts) > 0 {
+				c := conflicts[0]
+				msg := fmt.Sprintf("Port %d is already in use by %s", c.Port, c.Owner)
+				httpx.WriteErrorWithDetails(w, http.StatusConflict, "apps.port_conflict", msg, map[string]any{"conflicts": conflicts})
+				return
+			}
 			dir := filepath.Join(cfg.AppsInstallDir, body.ID)
 			_ = os.MkdirAll(dir, 0o755)
-			compose := apps.ComposeTemplate(body.ID)
 			if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(compose), 0o644); err != nil {
 				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
 				return
 			}
+			if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(apps.EnvFile(env)), 0o600); err != nil {
+				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
 			unit := apps.UnitTemplate(body.ID, dir)
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
 			_ = client.PostJSON(r.Context(), "/v1/systemd/install-app", map[string]any{"id": body.ID, "unit_text": unit}, nil)
 			_ = client.PostJSON(r.Context(), "/v1/app/compose-up", map[string]any{"id": body.ID, "dir": dir}, nil)
 			writeJSON(w, map[string]any{"ok": true})
@@ -1506,10 +1542,11 @@ func NewRouter(cfg config.Config) http.Handler {
 				return
 			}
 			dir := filepath.Join(cfg.AppsInstallDir, body.ID)
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
 			_ = client.PostJSON(r.Context(), "/v1/app/compose-down", map[string]any{"id": body.ID, "dir": dir}, nil)
 			_ = client.PostJSON(r.Context(), "/v1/systemd/disable-app", map[string]any{"id": body.ID}, nil)
 			_ = os.Remove(filepath.Join(dir, "docker-compose.yml"))
+			_ = os.Remove(filepath.Join(dir, ".env"))
 			_ = os.Remove(dir)
 			writeJSON(w, map[string]any{"ok": true})
 		})
@@ -1530,15 +1567,20 @@ func NewRouter(cfg config.Config) http.Handler {
 			writeJSON(w, list)
 		})
 
+		// Host power (agent schedules via systemd)
+		pr.With(adminRequired).Post("/api/v1/system/reboot", handleSystemPower(cfg, "reboot"))
+		pr.With(adminRequired).Post("/api/v1/system/shutdown", handleSystemPower(cfg, "shutdown"))
+
+		// Maintenance window for scheduled jobs
+		pr.Get("/api/v1/maintenance/status", handleMaintenanceStatus())
+
 		// Updates: check (redundant with /api/v1/updates/* handler, but retain convenience)
-		pr.Get("/api/v1/updates/check", func(w http.ResponseWriter, r *http.Request) {
-			client := agentclient.New("/run/nos-agent.sock")
-			var planResp map[string]any
-			_ = client.PostJSON(r.Context(), "/v1/updates/plan", map[string]any{}, &planResp)
-			// attach snapshot targets (best-effort)
-			roots, _ := poolroots.AllowedRoots()
-			writeJSON(w, map[string]any{"plan": planResp, "snapshot_roots": roots})
-		})
+		pr.Get("/api/v1/updates/check", handleUpdatesCheck(cfg))
+
+		// Updates: boot confirmation after a reboot-requiring apply
+		updatesBootGuard := newBootGuard(cfg, agentClient)
+		pr.Get("/api/v1/updates/boot-status", handleBootStatus(updatesBootGuard))
+		pr.With(adminRequired).Post("/api/v1/updates/confirm-boot", handleConfirmBoot(updatesBootGuard))
 
 		// Updates: apply
 		pr.With(adminRequired).Post("/api/v1/updates/apply", func(w http.ResponseWriter, r *http.Request) {
@@ -1546,21 +1588,49 @@ func NewRouter(cfg config.Config) http.Handler {
 				Packages []string `json:"packages"`
 				Snapshot bool     `json:"snapshot"`
 				Confirm  string   `json:"confirm"`
+				DryRun   bool     `json:"dry_run"`
 			}
 			_ = json.NewDecoder(r.Body).Decode(&body)
+			holds := updateHolds(cfg)
+			if held := heldPackages(body.Packages, holds); len(held) > 0 {
+				httpx.WriteErrorWithDetails(w, http.StatusConflict, "updates.package_held", "Held packages cannot be updated", map[string]any{"held": held})
+				return
+			}
+			if body.DryRun {
+				// apt-get update on the agent side is slow
+				writeUpdatesDryRun(w, r, cfg, agentclient.New(cfg.AgentSocket(), agentclient.WithTimeout(3*time.Minute)), body.Packages, body.Snapshot)
+				return
+			}
 			if strings.ToLower(body.Confirm) != "yes" {
 				httpx.WriteError(w, http.StatusPreconditionRequired, "confirm\u003dyes required")
 				return
 			}
-			client := agentclient.New("/run/nos-agent.sock")
+			// package installs can take well beyond the default agent call timeout
+			client := agentclient.New(cfg.AgentSocket(), agentclient.WithTimeout(30*time.Minute))
+			if len(body.Packages) == 0 && len(holds) > 0 {
+				pkgs, err := unheldUpgrades(r.Context(), client, holds)
+				if err != nil {
+					writeAgentError(w, err, "updates.plan_failed", "Planning updates failed")
+					return
+				}
+				if len(pkgs) == 0 {
+					httpx.WriteTypedError(w, http.StatusConflict, "updates.nothing_to_apply", "Every available update is held", 0)
+					return
+				}
+				body.Packages = pkgs
+			}
 			// create tx and persist initial state
 			txID := generateUUID()
 			tx := snapdb.UpdateTx{TxID: txID, StartedAt: time.Now().UTC(), Packages: body.Packages, Reason: "pre-update"}
 			_ = snapdb.Append(tx)
-			// load snapshot targets via pools roots (simplified: allowed roots)
-			roots, _ := poolroots.AllowedRoots()
+			skipped := []skippedTarget{}
 			if body.Snapshot {
-				for _, p := range roots {
+				// OS subvolumes only, or every pool root too, depending on scope; non-btrfs paths are skipped
+				roots, _ := poolroots.AllowedRoots()
+				var targets []string
+				targets, skipped = selectPreUpdateTargets(cfg.UpdatesSnapshotScope, roots, procMountOf)
+				tx.Notes = skippedNotes(skipped)
+				for _, p := range targets {
 					var sresp struct {
 						OK                 bool `json:"ok"`
 						ID, Type, Location string
@@ -1570,9 +1640,9 @@ func NewRouter(cfg config.Config) http.Handler {
 						now := time.Now().UTC()
 						tx.FinishedAt = &now
 						tx.Success = &mark
-						tx.Notes = "snapshot failed: " + errString(err)
+						tx.Notes = joinNotes("snapshot failed: "+errString(err), tx.Notes)
 						_ = snapdb.Append(tx)
-						httpx.WriteError(w, http.StatusInternalServerError, "snapshot failed")
+						writeAgentError(w, err, "updates.snapshot_failed", "Pre-update snapshot failed")
 						return
 					}
 					// append target on success
@@ -1586,9 +1656,9 @@ func NewRouter(cfg config.Config) http.Handler {
 				now := time.Now().UTC()
 				tx.FinishedAt = &now
 				tx.Success = &mark
-				tx.Notes = "apply failed: " + errString(err)
+				tx.Notes = joinNotes("apply failed: "+errString(err), tx.Notes)
 				_ = snapdb.Append(tx)
-				httpx.WriteError(w, http.StatusInternalServerError, "updates apply failed")
+				writeAgentError(w, err, "updates.apply_failed", "Applying updates failed")
 				return
 			}
 			// success
@@ -1597,7 +1667,16 @@ func NewRouter(cfg config.Config) http.Handler {
 			tx.FinishedAt = &now
 			tx.Success = &mark
 			_ = snapdb.Append(tx)
-			writeJSON(w, map[string]any{"ok": true, "tx_id": txID, "snapshots_count": len(tx.Targets), "updates_count": len(applyResp)})
+			rebootRequired, confirmBoot := updateNeedsReboot(body.Packages), false
+			if rebootRequired {
+				var err error
+				if confirmBoot, err = updatesBootGuard.MarkPending(txID, tx.Targets); err != nil {
+					Logger(cfg).Error().Str("event", "updates.boot.pending_write_failed").Str("tx_id", txID).Err(err).Msg("")
+				}
+			}
+			_, manual := splitRollbackTargets(tx.Targets)
+			writeJSON(w, map[string]any{"ok": true, "tx_id": txID, "snapshots_count": len(tx.Targets), "snapshots_skipped": skipped, "snapshots_manual_only": manual,
+				"updates_count": len(applyResp), "reboot_required": rebootRequired, "boot_confirm_required": confirmBoot})
 		})
 
 		// Snapshots: prune
@@ -1609,10 +1688,10 @@ func NewRouter(cfg config.Config) http.Handler {
 			if body.KeepPerTarget <= 0 {
 				body.KeepPerTarget = 5
 			}
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
 			var resp map[string]any
 			if err := client.PostJSON(r.Context(), "/v1/snapshot/prune", map[string]any{"keep_per_target": body.KeepPerTarget}, &resp); err != nil {
-				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+				writeAgentError(w, err, "snapshots.prune_failed", "Pruning snapshots failed")
 				return
 			}
 			writeJSON(w, resp)
@@ -1634,10 +1713,19 @@ func NewRouter(cfg config.Config) http.Handler {
 				httpx.WriteError(w, http.StatusNotFound, "tx not found")
 				return
 			}
-			client := agentclient.New("/run/nos-agent.sock")
+			if orig.DryRun {
+				httpx.WriteTypedError(w, http.StatusConflict, "updates.rollback_dry_run", "A dry run changed nothing to roll back", 0)
+				return
+			}
+			targets, manual := splitRollbackTargets(orig.Targets)
+			if len(targets) == 0 {
+				httpx.WriteErrorWithDetails(w, http.StatusConflict, "updates.rollback_manual_only", "Only snapshots that need manual recovery were taken", map[string]any{"manual_only": manual})
+				return
+			}
+			client := agentclient.New(cfg.AgentSocket())
 			// start rollback tx record
 			roll := snapdb.UpdateTx{TxID: generateUUID(), StartedAt: time.Now().UTC(), Packages: orig.Packages, Reason: "rollback"}
-			for _, t := range orig.Targets {
+			for _, t := range targets {
 				var resp map[string]any
 				if err := client.PostJSON(r.Context(), "/v1/snapshot/rollback", map[string]any{
 					"path": t.Path, "snapshot_id": t.ID, "type": t.Type,
@@ -1648,7 +1736,7 @@ func NewRouter(cfg config.Config) http.Handler {
 					roll.Success = &mark
 					roll.Notes = "rollback failed for target " + t.Path + ": " + err.Error()
 					_ = snapdb.Append(roll)
-					httpx.WriteError(w, http.StatusInternalServerError, "rollback failed")
+					writeAgentError(w, err, "updates.rollback_failed", "Rolling back the update failed")
 					return
 				}
 			}
@@ -1657,41 +1745,13 @@ func NewRouter(cfg config.Config) http.Handler {
 			roll.FinishedAt = &now
 			okMark := true
 			roll.Success = &okMark
-			roll.Notes = "rollback of " + orig.TxID
+			roll.Notes = joinNotes("rollback of "+orig.TxID, manualNotes(manual))
 			_ = snapdb.Append(roll)
-			writeJSON(w, map[string]any{"ok": true})
+			writeJSON(w, map[string]any{"ok": true, "manual_only": manual})
 		})
 
 		// Snapshots DB: recent
-		pr.Get("/api/v1/snapshots/recent", func(w http.ResponseWriter, r *http.Request) {
-			list, err := snapdb.ListRecent(20)
-			if err != nil {
-				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
-				return
-			}
-			// project limited fields
-			out := make([]map[string]any, 0, len(list))
-			for _, tx := range list {
-				miniTargets := make([]map[string]any, 0, len(tx.Targets))
-				for _, t := range tx.Targets {
-					miniTargets = append(miniTargets, map[string]any{
-						"id": t.ID, "type": t.Type, "location": t.Location,
-					})
-				}
-				ok := false
-				if tx.Success != nil {
-					ok = *tx.Success
-				}
-				out = append(out, map[string]any{
-					"tx_id":    tx.TxID,
-					"time":     tx.StartedAt,
-					"packages": tx.Packages,
-					"targets":  miniTargets,
-					"success":  ok,
-				})
-			}
-			writeJSON(w, out)
-		})
+		pr.Get("/api/v1/snapshots/recent", handleSnapshotsRecent)
 
 		// Back-compat: verify-totp path expected by FE
 		pr.Post("/api/v1/auth/verify-totp", func(w http.ResponseWriter, r *http.Request) {
@@ -1717,23 +1777,40 @@ func NewRouter(cfg config.Config) http.Handler {
 			var body struct {
 				Subvol string
 				Name   string
+				// Async runs the snapshot as a cancellable job and returns 202 with its job_id
+				Async bool
 			}
 			_ = json.NewDecoder(r.Body).Decode(&body)
-			client := agentclient.New("/run/nos-agent.sock")
+			client := agentclient.New(cfg.AgentSocket())
+			if body.Async {
+				job := RunJob("snapshot", "Creating snapshot "+body.Name, map[string]any{"pool_id": id, "subvol": body.Subvol, "name": body.Name}, func(ctx context.Context, _ string) error {
+					return client.PostJSON(ctx, "/v1/btrfs/snapshot", map[string]any{"path": body.Subvol, "name": body.Name}, nil)
+				})
+				respondJSON(w, http.StatusAccepted, map[string]any{"job_id": job.ID})
+				return
+			}
 			var resp map[string]any
 			err := client.PostJSON(r.Context(), "/v1/btrfs/snapshot", map[string]any{"path": body.Subvol, "name": body.Name}, &resp)
 			if err != nil {
-				httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+				writeAgentError(w, err, "snapshots.create_failed", "Creating the snapshot failed")
 				return
 			}
 			_ = id // unused for now
 			writeJSON(w, resp)
 		})
+		pr.With(adminRequired).Post("/api/v1/pools/{id}/snapshots/{snap}/restore", handleSnapshotRestore(cfg))
+		pr.With(adminRequired).Get("/api/v1/pools/{id}/snapshots/{snap}/browse", handleSnapshotBrowse(cfg))
+		pr.With(adminRequired).Get("/api/v1/pools/{id}/snapshots/{snap}/download", handleSnapshotDownload(cfg))
 	})
 
 	// System configuration endpoints (outside auth for setup access)
 	// During setup, these need to work without authentication
 	systemConfigHandler := NewSystemConfigHandler(*Logger(cfg), agentclient.New(cfg.AgentSocket()))
+	telemetryEmitter := newTelemetryEmitter(cfg)
+	systemConfigHandler.telemetry = telemetryEmitter
+	if cfg.TelemetryURL != "" {
+		go telemetryEmitter.Run(context.Background())
+	}
 	r.Route("/api/v1/system", func(sr chi.Router) {
 		// Allow setup token authentication for system config during setup
 		sr.Use(func(next http.Handler) http.Handler {
@@ -1797,6 +1874,7 @@ func NewRouter(cfg config.Config) http.Handler {
 		sr.Get("/network/interfaces", systemConfigHandler.ListInterfaces)
 		sr.Get("/network/interfaces/{iface}", systemConfigHandler.GetInterface)
 		sr.Post("/network/interfaces/{iface}", systemConfigHandler.ConfigureInterface)
+		sr.Post("/network/interfaces/{iface}/confirm", systemConfigHandler.ConfirmInterface)
 		// Telemetry
 		sr.Get("/telemetry/consent", systemConfigHandler.GetTelemetryConsent)
 		sr.Post("/telemetry/consent", systemConfigHandler.SetTelemetryConsent)
@@ -1813,6 +1891,8 @@ func NewRouter(cfg config.Config) http.Handler {
 		nr.Get("/interfaces", systemConfigHandler.ListInterfaces)
 		nr.Get("/interfaces/{iface}", systemConfigHandler.GetInterface)
 		nr.Post("/interfaces/{iface}", systemConfigHandler.ConfigureInterface)
+		nr.Post("/interfaces/{iface}/confirm", systemConfigHandler.ConfirmInterface)
+		nr.Post("/dns", systemConfigHandler.SetDNS)
 	})
 
 	// Telemetry endpoints to match FE contract: /api/v1/telemetry/consent
@@ -1820,20 +1900,19 @@ func NewRouter(cfg config.Config) http.Handler {
 		tr.Use(func(next http.Handler) http.Handler { return requireAuth(next, codec, cfg) })
 		tr.Get("/consent", systemConfigHandler.GetTelemetryConsent)
 		tr.Post("/consent", systemConfigHandler.SetTelemetryConsent)
+		tr.Get("/preview", handleTelemetryPreview(telemetryEmitter))
 	})
 
 	// Log route inventory once on startup for visibility (method + path)
-	func() {
-		var routes []map[string]string
-		_ = chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
-			routes = append(routes, map[string]string{"method": method, "path": route})
-			return nil
-		})
-		if b, err := json.Marshal(routes); err == nil {
-			Logger(cfg).Info().RawJSON("api_routes", b).Msg("")
-		}
-	}()
-	return r
+	// and generate the OpenAPI document from it
+	routes := routeInventory(r)
+	if b, err := json.Marshal(routes); err == nil {
+		Logger(cfg).Info().RawJSON("api_routes", b).Msg("")
+	}
+	if err := spec.build(routes); err != nil {
+		Logger(cfg).Error().Str("event", "openapi.build_failed").Err(err).Msg("")
+	}
+	return mux, append(reg.dupes, duplicateRoutes(routes)...)
 }
 
 func writeJSON(w http.ResponseWriter, v any) {
@@ -1959,30 +2038,6 @@ func mustJSON(v any) []byte {
 	return b
 }
 
-// clientIP extracts client IP. If TrustProxy is true, use last untrusted hop from X-Forwarded-For; otherwise use RemoteAddr.
-func clientIP(r *http.Request, cfg config.Config) string {
-	ip := r.RemoteAddr
-	if i := strings.LastIndex(ip, ":"); i >= 0 {
-		ip = ip[:i]
-	}
-	if !(cfg.TrustProxy || RuntimeTrustProxy()) {
-		return ip
-	}
-	h := strings.TrimSpace(r.Header.Get("X-Forwarded-For"))
-	if h == "" {
-		return ip
-	}
-	parts := strings.Split(h, ",")
-	// take the last non-empty token
-	for i := len(parts) - 1; i >= 0; i-- {
-		p := strings.TrimSpace(parts[i])
-		if p != "" {
-			return p
-		}
-	}
-	return ip
-}
-
 // genOTP6 generates a 6-digit OTP.
 func genOTP6() string {
 	var b [4]byte
@@ -2063,7 +2118,8 @@ type DiskIOStats struct {
 	WriteSpeed uint64 `json:"writeSpeed"`
 }
 
-// handleMetricsStream emits SystemHealthResponse via SSE at 1Hz
+// handleMetricsStream emits SystemHealthResponse via SSE at
+// metricsStreamInterval, from the shared healthSampler
 func handleMetricsStream(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/event-stream")
@@ -2075,29 +2131,21 @@ func handleMetricsStream(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		ctx := r.Context()
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-
-		// Send first event immediately
-		send := func() {
-			// Reuse handleSystemHealth logic by constructing the payload here
-			// Minimal duplication: compute a fresh snapshot
-			payload := captureSystemHealth()
-			b, _ := json.Marshal(payload)
-			_, _ = w.Write([]byte("data: "))
-			_, _ = w.Write(b)
-			_, _ = w.Write([]byte("\n\n"))
-			flusher.Flush()
-		}
+		ctx, done := streams.track(r.Context())
+		defer done()
+		samples, cancel := healthSampler.Subscribe()
+		defer cancel()
 
-		send()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				send()
+			case payload := <-samples:
+				b, _ := json.Marshal(payload)
+				_, _ = w.Write([]byte("data: "))
+				_, _ = w.Write(b)
+				_, _ = w.Write([]byte("\n\n"))
+				flusher.Flush()
 			}
 		}
 	}
@@ -37,6 +37,8 @@ func TestSetupFullFlowAnd410(t *testing.T) {
 	t.Setenv("NOS_USERS_PATH", usersPath)
 	t.Setenv("NOS_FIRSTBOOT_PATH", firstbootPath)
 	t.Setenv("NOS_RL_PATH", filepath.Join(dir, "ratelimit.json"))
+	// the flow signs in three times
+	t.Setenv("NOS_RATE_LOGIN_PER_15M", "1000")
 	t.Setenv("NOS_ETC_DIR", dir)
 	// Ensure apps manager writes under temp dir and does not open event log file
 	t.Setenv("NOS_APPS_STATE", filepath.Join(dir, "apps.json"))
@@ -183,15 +185,45 @@ func TestSetupFullFlowAnd410(t *testing.T) {
 		if res2.Code != http.StatusUnauthorized {
 			t.Fatalf("me after revoke current: %d", res2.Code)
 		}
+		// every cookie login issues is bound to the revoked sid
+		req3 := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+		for _, c := range cookies {
+			req3.AddCookie(c)
+		}
+		res3 := httptest.NewRecorder()
+		r.ServeHTTP(res3, req3)
+		if res3.Code != http.StatusUnauthorized {
+			t.Fatalf("me with revoked cookies: %d", res3.Code)
+		}
+	}
+
+	// sign in again for the rest of the flow
+	{
+		t.Log("login-after-revoke")
+		lb := mustJSON(map[string]any{"username": "alice", "password": "StrongPassw0rd!"})
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(lb)))
+		if res.Code != 200 {
+			t.Fatalf("login after revoke: %d %s", res.Code, res.Body.String())
+		}
+		cookies = res.Result().Cookies()
+		for _, c := range cookies {
+			if c.Name == "nos_csrf" {
+				csrf = c.Value
+			}
+		}
 	}
 
 	// enroll
 	{
 		t.Log("enroll")
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/totp/enroll", nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/totp/enroll", nil)
 		for _, c := range cookies {
 			req.AddCookie(c)
 		}
+		if csrf != "" {
+			req.Header.Set("X-CSRF-Token", csrf)
+		}
 		res := httptest.NewRecorder()
 		r.ServeHTTP(res, req)
 		if res.Code != 200 {
@@ -204,10 +236,10 @@ func TestSetupFullFlowAnd410(t *testing.T) {
 		t.Log("get-secret")
 		us, _ := userstore.New(usersPath)
 		u, _ := us.FindByUsername("alice")
-		if u.TOTPEnc == "" {
-			t.Fatal("expected encrypted secret after enroll")
+		if u.TOTPPendingEnc == "" {
+			t.Fatal("expected pending encrypted secret after enroll")
 		}
-		pt, err := decryptWithSecretKey(secretPath, u.TOTPEnc)
+		pt, err := decryptWithSecretKey(secretPath, u.TOTPPendingEnc)
 		if err != nil {
 			t.Fatalf("decrypt secret: %v", err)
 		}
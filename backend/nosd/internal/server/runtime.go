@@ -1,42 +1,178 @@
 package server
 
 import (
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/backup"
+	"nithronos/backend/nosd/pkg/maintenance"
 )
 
 var (
 	rtMu          sync.RWMutex
 	rtAllowedOrig []string
-	rtTrustProxy  bool
+	rtProxies     []*net.IPNet
 	currentLevel  zerolog.Level
+	rtRateLimits  RateLimits
+	rtMetricsACL  []string
+	rtPprof       bool
+	rtMaintenance maintenance.Window
+	rtSchedulers  []*backup.Scheduler
 )
 
-func SetRuntimeCORSOrigin(origin string) {
+// RateLimits are the OTP and login rate-limit thresholds, read per request
+// so a config reload takes effect without rebuilding the router.
+type RateLimits struct {
+	OTPPerMin   int
+	OTPWindow   time.Duration
+	LoginPer15m int
+	LoginWindow time.Duration
+	// OTPMaxAttempts wrong setup OTPs invalidate the code
+	OTPMaxAttempts int
+}
+
+// SetRuntimeRateLimits applies the rate-limit fields of cfg.
+func SetRuntimeRateLimits(cfg config.Config) {
+	rl := RateLimits{
+		OTPPerMin:   cfg.RateOTPPerMin,
+		OTPWindow:   time.Duration(cfg.RateOTPWindowSec) * time.Second,
+		LoginPer15m: cfg.RateLoginPer15m,
+		LoginWindow: time.Duration(cfg.RateLoginWindowSec) * time.Second,
+
+		OTPMaxAttempts: cfg.RateOTPMaxAttempts,
+	}
+	if rl.OTPWindow <= 0 {
+		rl.OTPWindow = time.Minute
+	}
+	if rl.LoginWindow <= 0 {
+		rl.LoginWindow = 15 * time.Minute
+	}
+	if rl.OTPMaxAttempts <= 0 {
+		rl.OTPMaxAttempts = config.Defaults().RateOTPMaxAttempts
+	}
+	rtMu.Lock()
+	rtRateLimits = rl
+	rtMu.Unlock()
+}
+
+func RuntimeRateLimits() RateLimits {
+	rtMu.RLock()
+	defer rtMu.RUnlock()
+	return rtRateLimits
+}
+
+// SetRuntimeMetrics applies the /metrics allowlist and the pprof toggle.
+// Whether /metrics is served at all is fixed at startup.
+func SetRuntimeMetrics(allowlist []string, pprof bool) {
+	rtMu.Lock()
+	rtMetricsACL = append([]string(nil), allowlist...)
+	rtPprof = pprof
+	rtMu.Unlock()
+}
+
+func RuntimeMetricsAllowlist() []string {
+	rtMu.RLock()
+	defer rtMu.RUnlock()
+	return append([]string(nil), rtMetricsACL...)
+}
+
+func RuntimePprofEnabled() bool {
+	rtMu.RLock()
+	defer rtMu.RUnlock()
+	return rtPprof
+}
+
+// SetRuntimeMaintenanceWindow replaces the maintenance window and pushes it
+// to every registered backup scheduler.
+func SetRuntimeMaintenanceWindow(w maintenance.Window) {
+	rtMu.Lock()
+	rtMaintenance = w
+	schedulers := append([]*backup.Scheduler(nil), rtSchedulers...)
+	rtMu.Unlock()
+	for _, s := range schedulers {
+		s.SetMaintenanceWindow(w)
+	}
+}
+
+func RuntimeMaintenanceWindow() maintenance.Window {
+	rtMu.RLock()
+	defer rtMu.RUnlock()
+	return rtMaintenance
+}
+
+// registerBackupScheduler applies the current maintenance window to s and
+// keeps it updated on later reloads.
+func registerBackupScheduler(s *backup.Scheduler) {
 	rtMu.Lock()
-	defer rtMu.Unlock()
-	if strings.TrimSpace(origin) == "" {
-		rtAllowedOrig = []string{"http://localhost:5173", "http://127.0.0.1:5173"}
-	} else {
-		rtAllowedOrig = []string{origin}
+	rtSchedulers = append(rtSchedulers, s)
+	w := rtMaintenance
+	rtMu.Unlock()
+	s.SetMaintenanceWindow(w)
+}
+
+// metricsAllowed matches ip against the allowlist: exact IPs, CIDRs and
+// dot-suffix prefixes ("10.0."). An empty allowlist allows everyone.
+func metricsAllowed(ip string) bool {
+	acl := RuntimeMetricsAllowlist()
+	if len(acl) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	for _, a := range acl {
+		if a == ip || (strings.HasSuffix(a, ".") && strings.HasPrefix(ip, a)) {
+			return true
+		}
+		if _, n, err := net.ParseCIDR(a); err == nil && parsed != nil && n.Contains(parsed) {
+			return true
+		}
 	}
+	return false
 }
 
-func SetRuntimeTrustProxy(v bool) {
+// SetRuntimeCORSOrigins replaces the CORS allowlist. Entries are exact
+// origins, wildcard subdomains ("https://*.example.com") or "*"; an empty
+// list allows the Vite dev server only.
+func SetRuntimeCORSOrigins(origins []string) {
+	list := make([]string, 0, len(origins))
+	for _, o := range origins {
+		if o = normalizeOrigin(o); o != "" {
+			list = append(list, o)
+		}
+	}
+	if len(list) == 0 {
+		list = []string{"http://localhost:5173", "http://127.0.0.1:5173"}
+	}
 	rtMu.Lock()
-	rtTrustProxy = v
+	rtAllowedOrig = list
 	rtMu.Unlock()
 }
 
-func RuntimeTrustProxy() bool {
+func normalizeOrigin(o string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(o), "/"))
+}
+
+// SetRuntimeTrustedProxies replaces the proxies whose forwarding headers
+// clientIP and isSecureRequest believe. Once set, it overrides the
+// proxies of the config the router was built with.
+func SetRuntimeTrustedProxies(nets []*net.IPNet) {
+	rtMu.Lock()
+	rtProxies = append(make([]*net.IPNet, 0, len(nets)), nets...)
+	rtMu.Unlock()
+}
+
+// RuntimeTrustedProxies returns the proxies last set, or nil if they never
+// were.
+func RuntimeTrustedProxies() []*net.IPNet {
 	rtMu.RLock()
-	v := rtTrustProxy
-	rtMu.RUnlock()
-	return v
+	defer rtMu.RUnlock()
+	return rtProxies
 }
 
 func getAllowedOrigins() []string {
@@ -47,33 +183,81 @@ func getAllowedOrigins() []string {
 	return out
 }
 
-// DynamicCORS adds CORS headers using the current runtime origin settings.
+// CORS response values. Allowed headers cover what the web UI and nosctl
+// send; exposed headers are the ones clients act on.
+const (
+	corsAllowMethods  = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = "Content-Type, X-CSRF-Token, Authorization, Idempotency-Key, X-Setup-Token"
+	corsExposeHeaders = "Retry-After, Idempotent-Replayed, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset"
+	corsMaxAgeSeconds = "600"
+)
+
+// matchOrigin reports whether origin is allowed and whether that was only
+// through the "*" entry. Wildcard entries match any subdomain depth but not
+// the bare domain, and scheme and port must match exactly.
+func matchOrigin(origin string, allowed []string) (ok, star bool) {
+	origin = normalizeOrigin(origin)
+	if origin == "" {
+		return false, false
+	}
+	scheme, host, found := strings.Cut(origin, "://")
+	if !found || host == "" {
+		return false, false
+	}
+	for _, a := range allowed {
+		if a == origin {
+			return true, false
+		}
+		if a == "*" {
+			star = true
+			continue
+		}
+		ps, ph, _ := strings.Cut(a, "://")
+		if suffix, wild := strings.CutPrefix(ph, "*."); wild && ps == scheme &&
+			strings.HasSuffix(host, "."+suffix) && len(host) > len(suffix)+1 {
+			return true, false
+		}
+	}
+	return star, star
+}
+
+// DynamicCORS adds CORS headers using the current runtime allowlist. A
+// matched origin is echoed with credentials allowed; an origin allowed only
+// through "*" gets "Access-Control-Allow-Origin: *" and no credentials, as
+// browsers require. Preflights (OPTIONS with Access-Control-Request-Method)
+// are answered here: 204 with the allowed methods and headers, or 403 for an
+// origin that isn't allowed. Other OPTIONS requests go to the router.
 func DynamicCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		allowed := false
-		for _, o := range getAllowedOrigins() {
-			if o == "*" || o == origin {
-				allowed = true
-				break
-			}
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
 		}
-		if allowed {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Vary", "Origin")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		ok, star := matchOrigin(origin, getAllowedOrigins())
+		if ok {
+			if star {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
 		}
-		if r.Method == http.MethodOptions {
-			if v := w.Header().Get("Access-Control-Allow-Origin"); v != "" {
-				w.WriteHeader(204)
-				_, _ = w.Write([]byte{})
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !ok {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(strconv.Itoa(http.StatusForbidden)))
 				return
 			}
-			// not allowed
-			w.WriteHeader(403)
-			_, _ = w.Write([]byte(strconv.Itoa(http.StatusForbidden)))
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Max-Age", corsMaxAgeSeconds)
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -93,11 +93,12 @@ func handleSchedulesPost(cfg config.Config) http.HandlerFunc {
 			return
 		}
 		if err := saveSchedules(cfg, s); err != nil {
-			httpx.WriteError(w, http.StatusInternalServerError, err.Error())
+			Logger(cfg).Error().Str("event", "schedules.save_failed").Err(err).Msg("")
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "schedules.save_failed", "Failed to save schedules", 0)
 			return
 		}
 		// Write systemd drop-ins via agent
-		client := agentclient.New("/run/nos-agent.sock")
+		client := agentclient.New(cfg.AgentSocket())
 		// nos-smart-scan.timer override
 		_ = client.PostJSON(context.TODO(), "/v1/fs/write", map[string]any{
 			"path":    "/etc/systemd/system/nos-smart-scan.timer.d/override.conf",
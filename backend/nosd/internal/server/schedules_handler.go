@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -58,29 +57,6 @@ func NewSchedulesHandler() *SchedulesHandler {
 	}
 }
 
-// Routes registers the schedules routes
-func (h *SchedulesHandler) Routes() chi.Router {
-	r := chi.NewRouter()
-	
-	r.Get("/", h.GetSchedules)
-	r.Post("/", h.CreateSchedule)
-	r.Get("/{id}", h.GetSchedule)
-	r.Put("/{id}", h.UpdateSchedule)
-	r.Delete("/{id}", h.DeleteSchedule)
-	
-	return r
-}
-
-// GetSchedules returns all schedules
-// GET /api/v1/schedules
-func (h *SchedulesHandler) GetSchedules(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(h.schedules); err != nil {
-		log.Error().Err(err).Msg("Failed to encode schedules")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-	}
-}
-
 // GetSchedule returns a specific schedule
 // GET /api/v1/schedules/{id}
 func (h *SchedulesHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
@@ -99,46 +75,6 @@ func (h *SchedulesHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Schedule not found", http.StatusNotFound)
 }
 
-// CreateSchedule creates a new schedule
-// POST /api/v1/schedules
-func (h *SchedulesHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
-	var schedule Schedule
-	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-	
-	// Generate ID if not provided
-	if schedule.ID == "" {
-		schedule.ID = "schedule-" + uuid.New().String()[:8]
-	}
-	
-	// Validate schedule type
-	validTypes := map[string]bool{
-		"smart_scan":   true,
-		"btrfs_scrub":  true,
-		"snapshot":     true,
-		"backup":       true,
-	}
-	
-	if !validTypes[schedule.Type] {
-		http.Error(w, "Invalid schedule type", http.StatusBadRequest)
-		return
-	}
-	
-	// Add to schedules
-	h.schedules = append(h.schedules, schedule)
-	
-	// In real implementation, this would register with cron scheduler
-	log.Info().Str("id", schedule.ID).Str("type", schedule.Type).Msg("Created schedule")
-	
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(schedule); err != nil {
-		fmt.Printf("Failed to write response: %v\n", err)
-	}
-}
-
 // UpdateSchedule updates an existing schedule
 // PUT /api/v1/schedules/{id}
 func (h *SchedulesHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
@@ -2,22 +2,42 @@ package server
 
 import (
 	"net/http"
-	"strings"
+	"strconv"
+
+	"nithronos/backend/nosd/internal/config"
 )
 
-// securityHeaders adds common security headers to every response.
-func securityHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// CSP: conservative defaults for API responses and simple HTML
-		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'; img-src 'self' data:; object-src 'none'")
-		// HSTS only when HTTPS (native TLS or trusted proxy header)
-		if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
-			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+// securityHeaders adds cfg.SecurityHeaders to every response, and
+// Strict-Transport-Security to those served over HTTPS (native TLS, or
+// X-Forwarded-Proto from a trusted proxy); browsers ignore it over plain
+// HTTP.
+func securityHeaders(cfg config.Config) func(http.Handler) http.Handler {
+	headers := map[string]string{}
+	for name, value := range cfg.SecurityHeaders {
+		if value != "" {
+			headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	hsts := ""
+	if cfg.HSTSMaxAgeSeconds > 0 {
+		hsts = "max-age=" + strconv.Itoa(cfg.HSTSMaxAgeSeconds)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
 		}
-		w.Header().Set("Referrer-Policy", "no-referrer")
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
-		w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
-		next.ServeHTTP(w, r)
-	})
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			for name, value := range headers {
+				h.Set(name, value)
+			}
+			if hsts != "" && isSecureRequest(r, cfg) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+	healthTestEnv(t)
+	h := NewRouter(config.FromEnv())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.RemoteAddr = "192.0.2.10:5000"
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	for name, want := range config.DefaultSecurityHeaders() {
+		if got := res.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if got := res.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Fatalf("HSTS over plain HTTP: %q", got)
+	}
+}
+
+func TestSecurityHeadersHSTSOnlyWhenSecure(t *testing.T) {
+	cfg := config.FromEnv()
+	cfg.TrustedProxies = []string{"127.0.0.1"}
+	h := securityHeaders(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(remote, proto string, tlsState *tls.ConnectionState) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		req.TLS = tlsState
+		if proto != "" {
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res.Header().Get("Strict-Transport-Security")
+	}
+
+	const want = "max-age=31536000; includeSubDomains"
+	if got := serve("192.0.2.10:5000", "", &tls.ConnectionState{}); got != want {
+		t.Fatalf("native TLS: %q", got)
+	}
+	if got := serve("127.0.0.1:5000", "https", nil); got != want {
+		t.Fatalf("trusted proxy over https: %q", got)
+	}
+	if got := serve("127.0.0.1:5000", "http", nil); got != "" {
+		t.Fatalf("trusted proxy over http: %q", got)
+	}
+	if got := serve("192.0.2.10:5000", "https", nil); got != "" {
+		t.Fatalf("X-Forwarded-Proto believed from an untrusted client: %q", got)
+	}
+	if got := serve("192.0.2.10:5000", "", nil); got != "" {
+		t.Fatalf("plain HTTP: %q", got)
+	}
+}
+
+func TestSecurityHeadersConfigurable(t *testing.T) {
+	cfg := config.FromEnv()
+	cfg.SecurityHeaders["X-Frame-Options"] = ""
+	cfg.SecurityHeaders["Content-Security-Policy"] = "default-src 'none'"
+	cfg.SecurityHeaders["X-Custom"] = "1"
+	cfg.HSTSMaxAgeSeconds = 600
+	cfg.HSTSIncludeSubdomains = false
+	h := securityHeaders(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if got := res.Header().Values("X-Frame-Options"); len(got) != 0 {
+		t.Errorf("removed header still sent: %v", got)
+	}
+	if got := res.Header().Values("Content-Security-Policy"); len(got) != 1 || got[0] != "default-src 'none'" {
+		t.Errorf("CSP override: %v", got)
+	}
+	if got := res.Header().Get("X-Custom"); got != "1" {
+		t.Errorf("added header: %q", got)
+	}
+	if got := res.Header().Get("Strict-Transport-Security"); got != "max-age=600" {
+		t.Errorf("HSTS: %q", got)
+	}
+
+	cfg.HSTSMaxAgeSeconds = 0
+	res = httptest.NewRecorder()
+	securityHeaders(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(res, req)
+	if got := res.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HSTS with maxAge 0: %q", got)
+	}
+}
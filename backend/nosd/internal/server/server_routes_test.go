@@ -18,6 +18,7 @@ func Test_AllRoutesUnderV1OrAllowlist(t *testing.T) {
 		"/metrics":     true,
 		"/metrics/all": true,
 		"/healthz":     true,
+		"/readyz":      true,
 	}
 
 	var offenders []string
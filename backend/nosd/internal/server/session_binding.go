@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"nithronos/backend/nosd/internal/auth/session"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// ctxSessionBinding carries which bound session attributes changed ("ip"
+// and/or "ua"). Only sessionBinding sets it; a request header can't.
+const ctxSessionBinding ctxKey = "sessionBinding"
+
+// sessionBinding compares a session's recorded IP prefix and UA fingerprint
+// with the current request. Depending on cfg.SessionBindingMode a change is
+// ignored, flagged for /me, or answered with 401 auth.reauth_required on
+// sensitive routes. A cookie whose session was revoked or has expired is
+// dropped from the request, so the handlers see it as signed out.
+func sessionBinding(cfg config.Config, mgr *session.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uid, sid, ok := decodeSessionParts(r, cfg)
+			if !ok || uid == "" || sid == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, b, ok := mgr.Check(sid, r.Header.Get("User-Agent"), clientIP(r, cfg))
+			if !ok || id != uid {
+				dropCookie(r, cookieSession)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !b.Changed() || cfg.SessionBindingMode == config.SessionBindingOff || cfg.SessionBindingMode == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			changed := bindingChanges(b)
+			r = r.WithContext(context.WithValue(r.Context(), ctxSessionBinding, changed))
+			if cfg.SessionBindingMode == config.SessionBindingEnforce && isSensitiveRoute(r) {
+				Logger(cfg).Warn().Str("event", "auth.session.reauth_required").Str("userId", uid).Str("sid", sid).Strs("changed", changed).Str("path", r.URL.Path).Msg("")
+				httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.reauth_required", "Session IP or browser changed; sign in again", 0)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// dropCookie removes the named cookie from the request.
+func dropCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			r.AddCookie(c)
+		}
+	}
+}
+
+func bindingChanges(b session.Binding) []string {
+	var out []string
+	if b.IPChanged {
+		out = append(out, "ip")
+	}
+	if b.UAChanged {
+		out = append(out, "ua")
+	}
+	return out
+}
+
+// isSensitiveRoute reports whether a request needs an intact session binding
+// in enforce mode: any state-changing request except signing in or out.
+func isSensitiveRoute(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	switch r.URL.Path {
+	case "/api/v1/auth/login", "/api/v1/auth/logout":
+		return false
+	}
+	return true
+}
+
+// sessionBindingStatus is the /me view of a flagged session, nil when intact.
+func sessionBindingStatus(r *http.Request) map[string]any {
+	changed, _ := r.Context().Value(ctxSessionBinding).([]string)
+	if len(changed) == 0 {
+		return nil
+	}
+	return map[string]any{"changed": changed, "reauthRecommended": true}
+}
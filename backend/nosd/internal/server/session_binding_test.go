@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/auth/session"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+)
+
+// bindingTestSession persists a user and a session bound to UA "browser-a"
+// and the httptest client IP, returning a matching session cookie.
+func bindingTestSession(t *testing.T, mode string) (http.Handler, *http.Cookie) {
+	t.Helper()
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SECRET_PATH", filepath.Join(dir, "secret.key"))
+	t.Setenv("NOS_SESSION_BINDING", mode)
+	cfg := config.FromEnv()
+
+	users, _ := userstore.New(cfg.UsersPath)
+	if err := users.UpsertUser(userstore.User{ID: "u1", Username: "admin", Roles: []string{"admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := session.New(cfg.SessionsPath).Create("u1", "browser-a", "192.0.2.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if err := issueSessionCookiesSID(w, cfg, "u1", rec.SID, false); err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(cfg), w.Result().Cookies()[0]
+}
+
+func bindingRequest(h http.Handler, ck *http.Cookie, method, path, ua string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(`{"scope":"sid"}`))
+	req.AddCookie(ck)
+	req.Header.Set("User-Agent", ua)
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func TestSessionBindingFlagMode(t *testing.T) {
+	h, ck := bindingTestSession(t, config.SessionBindingFlag)
+
+	res := bindingRequest(h, ck, http.MethodGet, "/api/v1/auth/me", "browser-a")
+	if res.Code != http.StatusOK || strings.Contains(res.Body.String(), "sessionBinding") {
+		t.Fatalf("intact session: %d %s", res.Code, res.Body.String())
+	}
+
+	// an intact session stays intact whatever binding header the client sends
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.AddCookie(ck)
+	req.Header.Set("User-Agent", "browser-a")
+	req.Header.Set("X-Session-Binding", "ip,ua")
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || strings.Contains(res.Body.String(), "sessionBinding") {
+		t.Fatalf("spoofed header flagged the session: %d %s", res.Code, res.Body.String())
+	}
+
+	res = bindingRequest(h, ck, http.MethodGet, "/api/v1/auth/me", "browser-b")
+	var me struct {
+		SessionBinding struct {
+			Changed []string `json:"changed"`
+		} `json:"sessionBinding"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &me); err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK || len(me.SessionBinding.Changed) != 1 || me.SessionBinding.Changed[0] != "ua" {
+		t.Fatalf("changed UA should be flagged: %d %s", res.Code, res.Body.String())
+	}
+
+	res = bindingRequest(h, ck, http.MethodPost, "/api/v1/auth/sessions/revoke", "browser-b")
+	if strings.Contains(res.Body.String(), "auth.reauth_required") {
+		t.Fatalf("flag mode must not block: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestSessionBindingEnforceMode(t *testing.T) {
+	h, ck := bindingTestSession(t, config.SessionBindingEnforce)
+
+	res := bindingRequest(h, ck, http.MethodPost, "/api/v1/auth/sessions/revoke", "browser-b")
+	if res.Code != http.StatusUnauthorized || !strings.Contains(res.Body.String(), "auth.reauth_required") {
+		t.Fatalf("changed UA on sensitive route: %d %s", res.Code, res.Body.String())
+	}
+	// reads still work, and still report the change
+	res = bindingRequest(h, ck, http.MethodGet, "/api/v1/auth/me", "browser-b")
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "sessionBinding") {
+		t.Fatalf("GET /me: %d %s", res.Code, res.Body.String())
+	}
+	// the original browser is unaffected
+	res = bindingRequest(h, ck, http.MethodPost, "/api/v1/auth/sessions/revoke", "browser-a")
+	if res.Code == http.StatusUnauthorized {
+		t.Fatalf("intact session rejected: %s", res.Body.String())
+	}
+}
+
+func TestSessionBindingOffByDefault(t *testing.T) {
+	if config.Defaults().SessionBindingMode != config.SessionBindingOff {
+		t.Fatal("default must stay non-enforcing")
+	}
+	h, ck := bindingTestSession(t, "")
+	res := bindingRequest(h, ck, http.MethodPost, "/api/v1/auth/sessions/revoke", "browser-b")
+	if strings.Contains(res.Body.String(), "auth.reauth_required") {
+		t.Fatalf("off mode must not block: %s", res.Body.String())
+	}
+	// a client cannot spoof the binding with a header
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.AddCookie(ck)
+	req.Header.Set("User-Agent", "browser-a")
+	req.Header.Set("X-Session-Binding", "ip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if strings.Contains(w.Body.String(), "sessionBinding") {
+		t.Fatalf("spoofed header leaked into /me: %s", w.Body.String())
+	}
+}
+
+func TestRefreshRejectsCookieWithoutSID(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SECRET_PATH", filepath.Join(dir, "secret.key"))
+	cfg := config.FromEnv()
+	// the shape of refresh cookies issued before they carried a sid
+	val, err := encodeOpaque(cfg, cookieRefresh, map[string]any{"uid": "u1", "exp": time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: cookieRefresh, Value: val})
+	res := httptest.NewRecorder()
+	NewRouter(cfg).ServeHTTP(res, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Fatalf("sid-less refresh: expected 401, got %d", res.Code)
+	}
+	if len(res.Result().Cookies()) != 0 {
+		t.Fatalf("sid-less refresh issued cookies: %v", res.Result().Cookies())
+	}
+}
+
+func TestLoginFailsWhenSessionCannotBeRecorded(t *testing.T) {
+	dir := healthTestEnv(t)
+	// a directory where the sessions file should be makes every save fail
+	sessionsPath := filepath.Join(dir, "sessions")
+	if err := os.Mkdir(sessionsPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOS_SESSIONS_PATH", sessionsPath)
+	cfg := config.FromEnv()
+	users, _ := userstore.New(cfg.UsersPath)
+	if err := users.UpsertUser(userstore.User{ID: "u1", Username: "admin", Roles: []string{"admin"}, PasswordHash: "plain:secret"}); err != nil {
+		t.Fatal(err)
+	}
+	res := httptest.NewRecorder()
+	NewRouter(cfg).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"username":"admin","password":"secret"}`)))
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("login without a session record: expected 500, got %d", res.Code)
+	}
+	for _, ck := range res.Result().Cookies() {
+		if ck.Name == cookieSession || ck.Name == cookieRefresh {
+			t.Fatalf("failed login set %s", ck.Name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/auth/session"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+)
+
+// ttlLogin logs in with a 2m access and 1h refresh TTL and returns the
+// response plus how far in the future the server-side session expires.
+func ttlLogin(t *testing.T, rememberMe bool) (*httptest.ResponseRecorder, time.Duration) {
+	t.Helper()
+	healthTestEnv(t)
+	t.Setenv("NOS_SESSION_ACCESS_TTL", "2m")
+	t.Setenv("NOS_SESSION_REFRESH_TTL", "1h")
+	cfg := config.FromEnv()
+
+	users, _ := userstore.New(cfg.UsersPath)
+	if err := users.UpsertUser(userstore.User{ID: "u1", Username: "dana", PasswordHash: "plain:Sup3r-secret", Roles: []string{"admin"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().UTC()
+	res := httptest.NewRecorder()
+	NewRouter(cfg).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+		bytes.NewReader(mustJSON(map[string]any{"username": "dana", "password": "Sup3r-secret", "rememberMe": rememberMe}))))
+	if res.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", res.Code, res.Body.String())
+	}
+
+	recs := session.New(cfg.SessionsPath).ListByUser("u1")
+	if len(recs) != 1 {
+		t.Fatalf("expected one session record, got %+v", recs)
+	}
+	exp, err := time.Parse(time.RFC3339, recs[0].Exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, exp.Sub(start)
+}
+
+func TestLoginUsesConfiguredSessionTTLs(t *testing.T) {
+	res, _ := ttlLogin(t, true)
+	var sessions, refresh int
+	for _, ck := range res.Result().Cookies() {
+		switch ck.Name {
+		case cookieSession:
+			sessions++
+			if ck.MaxAge != 120 {
+				t.Fatalf("nos_session max-age: %d", ck.MaxAge)
+			}
+		case cookieRefresh:
+			refresh++
+			if ck.MaxAge != 3600 {
+				t.Fatalf("nos_refresh max-age: %d", ck.MaxAge)
+			}
+		}
+	}
+	if sessions == 0 || refresh == 0 {
+		t.Fatalf("missing cookies: %v", res.Result().Cookies())
+	}
+}
+
+func TestRememberMeExtendsSessionRecord(t *testing.T) {
+	res, d := ttlLogin(t, false)
+	if d < time.Minute || d > 3*time.Minute {
+		t.Fatalf("session record expires in %v, want ~2m", d)
+	}
+	for _, ck := range res.Result().Cookies() {
+		if ck.Name == cookieRefresh {
+			t.Fatal("refresh cookie issued without rememberMe")
+		}
+	}
+
+	_, d = ttlLogin(t, true)
+	if d < 59*time.Minute || d > 61*time.Minute {
+		t.Fatalf("remembered session record expires in %v, want ~1h", d)
+	}
+}
@@ -50,7 +50,7 @@ func isSecureRequest(r *http.Request, cfg config.Config) bool {
 	if r.TLS != nil {
 		return true
 	}
-	if cfg.TrustProxy || RuntimeTrustProxy() {
+	if fromTrustedProxy(r, cfg) {
 		if strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")), "https") {
 			return true
 		}
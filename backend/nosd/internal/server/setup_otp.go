@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"os"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	firstboot "nithronos/backend/nosd/internal/setup/firstboot"
+)
+
+// setupOTPMu serialises the read-count-write of the first-boot state so
+// parallel guesses can't share one attempt.
+var setupOTPMu sync.Mutex
+
+// checkSetupOTP compares otp with the first-boot code in constant time. It
+// returns nil when there is no code to check against. On a mismatch the
+// returned state has FailedAttempts > 0, counted in the first-boot file; at
+// maxAttempts the file is removed so the code stops working and a new one
+// has to be issued. A match returns the state with FailedAttempts == 0.
+func checkSetupOTP(cfg config.Config, otp string, maxAttempts int) (*firstboot.State, error) {
+	setupOTPMu.Lock()
+	defer setupOTPMu.Unlock()
+	fb := firstboot.New(cfg.FirstBootPath)
+	st, err := fb.Load()
+	if err != nil || st == nil || st.OTP == "" {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(st.OTP), []byte(otp)) == 1 {
+		ok := *st
+		ok.FailedAttempts = 0
+		return &ok, nil
+	}
+	st.FailedAttempts++
+	if st.FailedAttempts >= maxAttempts {
+		if err := os.Remove(fb.Path()); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return st, nil
+	}
+	if err := fb.SaveAtomic(context.Background(), st, 0o600); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// setupOTPTTL is how long a first-boot OTP stays valid.
+const setupOTPTTL = 15 * time.Minute
+
+// issueSetupOTP replaces the first-boot code with a fresh one, dropping any
+// failed attempts, and announces it in /run/nos/firstboot-otp.
+func issueSetupOTP(cfg config.Config) (*firstboot.State, error) {
+	setupOTPMu.Lock()
+	defer setupOTPMu.Unlock()
+	now := time.Now().UTC()
+	st := &firstboot.State{OTP: genOTP6(), IssuedAt: now, ExpiresAt: now.Add(setupOTPTTL)}
+	if err := firstboot.New(cfg.FirstBootPath).SaveAtomic(context.Background(), st, 0o600); err != nil {
+		return nil, err
+	}
+	_ = writeFirstBootOTPFile(st.OTP)
+	return st, nil
+}
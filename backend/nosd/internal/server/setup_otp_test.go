@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	firstboot "nithronos/backend/nosd/internal/setup/firstboot"
+)
+
+func setupOTPEnv(t *testing.T, maxAttempts string) (config.Config, string) {
+	t.Helper()
+	dir := healthTestEnv(t)
+	secretPath := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(secretPath, bytes.Repeat([]byte{7}, 32), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fbPath := filepath.Join(dir, "firstboot.json")
+	t.Setenv("NOS_SECRET_PATH", secretPath)
+	t.Setenv("NOS_FIRSTBOOT_PATH", fbPath)
+	t.Setenv("NOS_RATE_OTP_PER_MIN", "50")
+	t.Setenv("NOS_RATE_OTP_MAX_ATTEMPTS", maxAttempts)
+	now := time.Now().UTC()
+	if err := firstboot.New(fbPath).SaveAtomic(context.Background(), &firstboot.State{OTP: "123456", IssuedAt: now, ExpiresAt: now.Add(15 * time.Minute)}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return config.FromEnv(), fbPath
+}
+
+func TestSetupOTPLockout(t *testing.T) {
+	cfg, fbPath := setupOTPEnv(t, "3")
+	r := NewRouter(cfg)
+	verify := func(otp string) (*httptest.ResponseRecorder, map[string]any) {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/setup/otp/verify", bytes.NewReader(mustJSON(map[string]string{"otp": otp}))))
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res, out
+	}
+	remaining := func(out map[string]any) any {
+		e, _ := out["error"].(map[string]any)
+		d, _ := e["details"].(map[string]any)
+		return d["attemptsRemaining"]
+	}
+
+	res, out := verify("000000")
+	if res.Code != http.StatusBadRequest || errCode(out) != "setup.otp.invalid" || remaining(out) != float64(2) {
+		t.Fatalf("first wrong code: %d %s", res.Code, res.Body.String())
+	}
+	if st, _ := firstboot.New(fbPath).Load(); st == nil || st.FailedAttempts != 1 {
+		t.Fatalf("failure not recorded: %+v", st)
+	}
+	// the right code still works below the cap
+	if res, _ := verify("123456"); res.Code != http.StatusOK {
+		t.Fatalf("right code: %d %s", res.Code, res.Body.String())
+	}
+
+	if res, out := verify("111111"); res.Code != http.StatusBadRequest || remaining(out) != float64(1) {
+		t.Fatalf("second wrong code: %d %s", res.Code, res.Body.String())
+	}
+	if res, out := verify("222222"); res.Code != http.StatusGone || errCode(out) != "setup.otp.locked" {
+		t.Fatalf("third wrong code: %d %s", res.Code, res.Body.String())
+	}
+	if _, err := os.Stat(fbPath); !os.IsNotExist(err) {
+		t.Fatalf("locked OTP still on disk: %v", err)
+	}
+	if res, out := verify("123456"); res.Code != http.StatusBadRequest || errCode(out) != "setup.otp.invalid" {
+		t.Fatalf("invalidated code accepted: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestCheckSetupOTPConstantTime(t *testing.T) {
+	cfg, _ := setupOTPEnv(t, "5")
+
+	// a shared prefix or a different length is just a miss
+	for i, otp := range []string{"123450", "12345", "1234567", ""} {
+		st, err := checkSetupOTP(cfg, otp, 5)
+		if err != nil || st == nil || st.FailedAttempts != i+1 {
+			t.Fatalf("%q: %+v %v", otp, st, err)
+		}
+	}
+	st, err := checkSetupOTP(cfg, "123456", 5)
+	if err != nil || st == nil || st.FailedAttempts != 0 || st.OTP != "123456" {
+		t.Fatalf("match: %+v %v", st, err)
+	}
+}
+
+func TestSetupOTPRegenerate(t *testing.T) {
+	cfg, _ := setupOTPEnv(t, "5")
+	r := NewRouter(cfg)
+	call := func(path, remote string, body any) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(mustJSON(body)))
+		if remote != "" {
+			req.RemoteAddr = remote
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res, out
+	}
+
+	if res, out := call("/api/v1/setup/otp/regenerate", "", nil); res.Code != http.StatusForbidden || errCode(out) != "setup.local_only" {
+		t.Fatalf("remote regenerate: %d %s", res.Code, res.Body.String())
+	}
+	// a LAN client relayed by the loopback proxy
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/setup/otp/regenerate", nil)
+	req.RemoteAddr = "127.0.0.1:5555"
+	req.Header.Set("X-Forwarded-For", "192.168.1.20")
+	proxied := httptest.NewRecorder()
+	r.ServeHTTP(proxied, req)
+	if proxied.Code != http.StatusForbidden {
+		t.Fatalf("proxied regenerate: %d %s", proxied.Code, proxied.Body.String())
+	}
+	res, out := call("/api/v1/setup/otp/regenerate", "127.0.0.1:5555", nil)
+	otp, _ := out["otp"].(string)
+	if res.Code != http.StatusOK || len(otp) != 6 || otp == "123456" || out["expiresAt"] == nil {
+		t.Fatalf("regenerate: %d %s", res.Code, res.Body.String())
+	}
+	if st, _ := firstboot.New(cfg.FirstBootPath).Load(); st == nil || st.OTP != otp || st.FailedAttempts != 0 {
+		t.Fatalf("state not replaced: %+v", st)
+	}
+
+	if res, out := call("/api/v1/setup/otp/verify", "", map[string]string{"otp": "123456"}); res.Code != http.StatusBadRequest || errCode(out) != "setup.otp.invalid" {
+		t.Fatalf("old code: %d %s", res.Code, res.Body.String())
+	}
+	if res, _ := call("/api/v1/setup/otp/verify", "", map[string]string{"otp": otp}); res.Code != http.StatusOK {
+		t.Fatalf("new code: %d %s", res.Code, res.Body.String())
+	}
+
+	us, _ := userstore.New(cfg.UsersPath)
+	if err := us.UpsertUser(userstore.User{ID: "u1", Username: "admin", Roles: []string{"admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	if res, out := call("/api/v1/setup/otp/regenerate", "127.0.0.1:5555", nil); res.Code != http.StatusGone || errCode(out) != "setup.complete" {
+		t.Fatalf("regenerate after setup: %d %s", res.Code, res.Body.String())
+	}
+}
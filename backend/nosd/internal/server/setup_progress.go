@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/setup/wizard"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// setupProgressPath sits next to the setup-complete marker.
+func setupProgressPath(cfg config.Config) string {
+	return filepath.Join(cfg.EtcDir, "nos", "setup-progress.json")
+}
+
+func setupMarkedComplete(cfg config.Config) bool {
+	_, err := os.Stat(filepath.Join(cfg.EtcDir, "nos", "setup-complete"))
+	return err == nil
+}
+
+// GET /api/v1/setup/progress returns the wizard steps with their done flags
+// and the step to resume at. The steps after the first admin run once an
+// admin exists, so these routes answer 410 only after /setup/complete.
+func handleSetupProgressGet(cfg config.Config, store *wizard.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if setupMarkedComplete(cfg) {
+			httpx.WriteTypedError(w, http.StatusGone, "setup.complete", "Setup already completed", 0)
+			return
+		}
+		p, err := store.Load()
+		if err != nil {
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "setup.read_failed", "Failed to read setup progress", 0)
+			return
+		}
+		writeJSON(w, p)
+	}
+}
+
+// POST /api/v1/setup/progress {"step":"network","done":true} marks one step;
+// done defaults to true. The "admin" step is set by /setup/first-admin.
+func handleSetupProgressPost(cfg config.Config, store *wizard.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if setupMarkedComplete(cfg) {
+			httpx.WriteTypedError(w, http.StatusGone, "setup.complete", "Setup already completed", 0)
+			return
+		}
+		var body struct {
+			Step string `json:"step"`
+			Done *bool  `json:"done"`
+		}
+		if err := decodeStrict(r, &body); err != nil {
+			writeInputError(w, err)
+			return
+		}
+		if body.Step == "admin" {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "setup.progress.invalid_step", "The admin step completes when the first admin is created", 0)
+			return
+		}
+		done := body.Done == nil || *body.Done
+		p, err := store.Set(body.Step, done)
+		if errors.Is(err, wizard.ErrUnknownStep) {
+			httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "setup.progress.invalid_step", "Unknown setup step", map[string]any{"steps": wizard.Steps})
+			return
+		}
+		if err != nil {
+			Logger(cfg).Error().Str("event", "setup.progress.persist_error").Err(err).Msg("")
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "setup.write_failed", "Failed to save setup progress", 0)
+			return
+		}
+		writeJSON(w, p)
+	}
+}
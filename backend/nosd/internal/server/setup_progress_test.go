@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/setup/wizard"
+)
+
+func TestSetupProgressAdvanceAndRead(t *testing.T) {
+	cfg, _ := setupOTPEnv(t, "5")
+	r := NewRouter(cfg)
+	tok, err := setupEncodeToken(cfg, map[string]any{"purpose": "setup", "exp": time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(method, body string, withToken bool) (*httptest.ResponseRecorder, wizard.Progress) {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/v1/setup/progress", bytes.NewReader([]byte(body)))
+		if withToken {
+			req.Header.Set("X-Setup-Token", tok)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		var p wizard.Progress
+		_ = json.Unmarshal(res.Body.Bytes(), &p)
+		return res, p
+	}
+
+	if res, _ := call(http.MethodGet, "", false); res.Code != http.StatusUnauthorized {
+		t.Fatalf("without setup token: %d", res.Code)
+	}
+	res, p := call(http.MethodGet, "", true)
+	if res.Code != http.StatusOK || p.Current != "admin" || len(p.Steps) != len(wizard.Steps) {
+		t.Fatalf("fresh progress: %d %s", res.Code, res.Body.String())
+	}
+
+	// the remaining steps run after the first admin exists
+	users, _ := userstore.New(cfg.UsersPath)
+	if err := users.UpsertUser(userstore.User{ID: "a1", Username: "admin", Roles: []string{"admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wizard.New(setupProgressPath(cfg)).Set("admin", true); err != nil {
+		t.Fatal(err)
+	}
+	if res, p = call(http.MethodPost, `{"step":"timezone"}`, true); res.Code != http.StatusOK || p.Current != "network" || !p.Done("timezone") {
+		t.Fatalf("advance: %d %s", res.Code, res.Body.String())
+	}
+	if res, p = call(http.MethodGet, "", true); res.Code != http.StatusOK || p.Current != "network" {
+		t.Fatalf("read back: %d %s", res.Code, res.Body.String())
+	}
+	if res, _ = call(http.MethodPost, `{"step":"dns"}`, true); res.Code != http.StatusBadRequest {
+		t.Fatalf("unknown step: %d %s", res.Code, res.Body.String())
+	}
+	if res, _ = call(http.MethodPost, `{"step":"admin"}`, true); res.Code != http.StatusBadRequest {
+		t.Fatalf("admin step via POST: %d %s", res.Code, res.Body.String())
+	}
+
+	// closed once setup is marked complete
+	marker := filepath.Join(cfg.EtcDir, "nos", "setup-complete")
+	_ = os.MkdirAll(filepath.Dir(marker), 0o755)
+	_ = os.WriteFile(marker, []byte("done\n"), 0o644)
+	if res, _ = call(http.MethodGet, "", true); res.Code != http.StatusGone {
+		t.Fatalf("after complete: %d", res.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"nithronos/backend/nosd/pkg/httpx"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ShareACL is the SMB access list of a share. Entries are SMB usernames or
+// @group. An empty ValidUsers leaves the share open to every SMB user.
+type ShareACL struct {
+	ValidUsers    []string `json:"validUsers"`
+	ReadOnlyUsers []string `json:"readOnlyUsers"`
+	AdminUsers    []string `json:"adminUsers"`
+}
+
+var smbGroupRe = regexp.MustCompile(`^@[a-z_][a-z0-9_-]{0,31}$`)
+
+func shareACLOf(share *ShareConfig) ShareACL {
+	acl := ShareACL{ValidUsers: share.Users, ReadOnlyUsers: share.ReadOnlyUsers, AdminUsers: share.AdminUsers}
+	for _, l := range []*[]string{&acl.ValidUsers, &acl.ReadOnlyUsers, &acl.AdminUsers} {
+		if *l == nil {
+			*l = []string{}
+		}
+	}
+	return acl
+}
+
+// SetACL replaces the access list of a share.
+func (s *SharesStore) SetACL(id string, acl ShareACL) (*ShareConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, ok := s.shares[id]
+	if !ok {
+		return nil, fmt.Errorf("share not found")
+	}
+	share.Users = acl.ValidUsers
+	share.ReadOnlyUsers = acl.ReadOnlyUsers
+	share.AdminUsers = acl.AdminUsers
+	share.UpdatedAt = time.Now()
+	return share, s.save()
+}
+
+type shareFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validateShareACL checks every entry against the SMB user list.
+func validateShareACL(acl ShareACL, smbUsers []string) []shareFieldError {
+	var errs []shareFieldError
+	check := func(field string, list []string) {
+		seen := map[string]bool{}
+		for _, u := range list {
+			switch {
+			case seen[u]:
+				errs = append(errs, shareFieldError{Field: field, Message: fmt.Sprintf("%s is listed twice", u)})
+			case len(u) > 0 && u[0] == '@':
+				if !smbGroupRe.MatchString(u) {
+					errs = append(errs, shareFieldError{Field: field, Message: fmt.Sprintf("%s is not a valid group", u)})
+				}
+			case !contains(smbUsers, u):
+				errs = append(errs, shareFieldError{Field: field, Message: fmt.Sprintf("%s is not an SMB user", u)})
+			}
+			seen[u] = true
+		}
+	}
+	check("validUsers", acl.ValidUsers)
+	check("readOnlyUsers", acl.ReadOnlyUsers)
+	check("adminUsers", acl.AdminUsers)
+
+	// with a valid users list, anyone not on it cannot connect at all
+	if len(acl.ValidUsers) > 0 {
+		notValid := func(field string, list []string) {
+			for _, u := range list {
+				if !contains(acl.ValidUsers, u) {
+					errs = append(errs, shareFieldError{Field: field, Message: fmt.Sprintf("%s is not in validUsers", u)})
+				}
+			}
+		}
+		notValid("readOnlyUsers", acl.ReadOnlyUsers)
+		notValid("adminUsers", acl.AdminUsers)
+	}
+	return errs
+}
+
+// GetShareACL returns the SMB access list of a share.
+// GET /api/v1/shares/{id}/acl
+func (h *SharesHandlerV2) GetShareACL(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.smbShare(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, shareACLOf(share))
+}
+
+// UpdateShareACL replaces the SMB access list of a share. For an enabled
+// share the smb.conf fragment is rewritten and Samba reloaded through the
+// agent before the new list is saved.
+// PUT /api/v1/shares/{id}/acl
+func (h *SharesHandlerV2) UpdateShareACL(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	share, ok := h.smbShare(w, id)
+	if !ok {
+		return
+	}
+	var acl ShareACL
+	if err := decodeStrict(r, &acl); err != nil {
+		writeInputError(w, err)
+		return
+	}
+
+	var smb struct {
+		Users []string `json:"users"`
+	}
+	if err := h.agent.GetJSON(r.Context(), "/v1/smb/users", &smb); err != nil {
+		writeAgentError(w, err, "shares.smb_users_unavailable", "Could not read the SMB user list")
+		return
+	}
+	if errs := validateShareACL(acl, smb.Users); len(errs) > 0 {
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "shares.acl.invalid", "Invalid access list", map[string]any{"fields": errs})
+		return
+	}
+
+	next := *share
+	next.Users, next.ReadOnlyUsers, next.AdminUsers = acl.ValidUsers, acl.ReadOnlyUsers, acl.AdminUsers
+	if next.Enabled {
+		if err := h.applySambaViaAgent(r.Context(), &next); err != nil {
+			writeAgentError(w, err, "shares.apply_failed", "Failed to apply the share configuration")
+			return
+		}
+	}
+	updated, err := h.store.SetACL(id, acl)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to save share ACL")
+		httpx.WriteError(w, http.StatusInternalServerError, "Failed to save share")
+		return
+	}
+	writeJSON(w, shareACLOf(updated))
+}
+
+// smbShare looks up a share for the ACL endpoints and answers 404/400 itself.
+func (h *SharesHandlerV2) smbShare(w http.ResponseWriter, id string) (*ShareConfig, bool) {
+	share, ok := h.store.Get(id)
+	if !ok {
+		httpx.WriteError(w, http.StatusNotFound, "Share not found")
+		return nil, false
+	}
+	if share.Protocol != "smb" {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "shares.acl.not_smb", "Access lists apply to SMB shares only", 0)
+		return nil, false
+	}
+	return share, true
+}
+
+// applySambaViaAgent writes the share's smb.conf fragment and reloads smbd
+// through nos-agent, which owns /etc/samba.
+func (h *SharesHandlerV2) applySambaViaAgent(ctx context.Context, share *ShareConfig) error {
+	write := map[string]any{"path": sambaShareFile(share.ID), "content": renderSambaShare(share), "mode": "0644", "owner": "root", "group": "root"}
+	if err := h.agent.PostJSON(ctx, "/v1/fs/write", write, nil); err != nil {
+		return err
+	}
+	return h.agent.PostJSON(ctx, "/v1/service/reload", map[string]any{"name": "smb"}, nil)
+}
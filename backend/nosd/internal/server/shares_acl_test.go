@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// smbAgent serves a fixed SMB user list and records writes and reloads.
+type smbAgent struct {
+	users []string
+	paths []string
+	posts []map[string]any
+}
+
+func (a *smbAgent) GetJSON(ctx context.Context, path string, out interface{}) error {
+	b, _ := json.Marshal(map[string]any{"users": a.users})
+	return json.Unmarshal(b, out)
+}
+
+func (a *smbAgent) PostJSON(ctx context.Context, path string, body interface{}, out interface{}) error {
+	a.paths = append(a.paths, path)
+	a.posts = append(a.posts, body.(map[string]any))
+	return nil
+}
+
+func shareACLEnv(t *testing.T) (*SharesHandlerV2, *smbAgent, string) {
+	t.Helper()
+	agent := &smbAgent{users: []string{"alice", "bob", "carol"}}
+	h, err := NewSharesHandlerV2(filepath.Join(t.TempDir(), "shares.json"), agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := &ShareConfig{Name: "docs", Path: t.TempDir(), Protocol: "smb", Enabled: true, Users: []string{"alice"}}
+	if err := h.store.Create(share); err != nil {
+		t.Fatal(err)
+	}
+	return h, agent, share.ID
+}
+
+func TestShareACLUpdate(t *testing.T) {
+	h, agent, id := shareACLEnv(t)
+	r := h.Routes()
+	put := func(body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/"+id+"/acl", strings.NewReader(body)))
+		return res
+	}
+
+	res := put(`{"validUsers":["alice","mallory"],"readOnlyUsers":["bob"],"adminUsers":[]}`)
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.Body.String(), `"shares.acl.invalid"`) ||
+		!strings.Contains(res.Body.String(), "mallory is not an SMB user") || !strings.Contains(res.Body.String(), "bob is not in validUsers") {
+		t.Fatalf("invalid acl: %d %s", res.Code, res.Body.String())
+	}
+	if len(agent.paths) != 0 {
+		t.Fatalf("rejected acl was applied: %v", agent.paths)
+	}
+
+	res = put(`{"validUsers":["alice","bob","@staff"],"readOnlyUsers":["bob"],"adminUsers":["alice"]}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", res.Code, res.Body.String())
+	}
+	if len(agent.paths) != 2 || agent.paths[0] != "/v1/fs/write" || agent.paths[1] != "/v1/service/reload" || agent.posts[1]["name"] != "smb" {
+		t.Fatalf("agent calls: %v %v", agent.paths, agent.posts)
+	}
+	if agent.posts[0]["path"] != sambaShareFile(id) || !strings.Contains(agent.posts[0]["content"].(string), "read list = bob") {
+		t.Fatalf("fragment write: %v", agent.posts[0])
+	}
+
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+id+"/acl", nil))
+	var acl ShareACL
+	_ = json.Unmarshal(res.Body.Bytes(), &acl)
+	if strings.Join(acl.ValidUsers, ",") != "alice,bob,@staff" || strings.Join(acl.AdminUsers, ",") != "alice" {
+		t.Fatalf("acl not stored: %+v", acl)
+	}
+}
+
+func TestRenderSambaShareACL(t *testing.T) {
+	got := renderSambaShare(&ShareConfig{
+		Name: "docs", Path: "/srv/shares/docs", Enabled: true,
+		Users: []string{"alice", "bob", "@staff"}, ReadOnlyUsers: []string{"bob"}, AdminUsers: []string{"alice"},
+	})
+	want := "\n[docs]\n" +
+		"   path = /srv/shares/docs\n" +
+		"   comment = \n" +
+		"   read only = no\n" +
+		"   guest ok = no\n" +
+		"   valid users = alice bob @staff\n" +
+		"   read list = bob\n" +
+		"   admin users = alice\n" +
+		"   browseable = yes\n" +
+		"   create mask = 0644\n" +
+		"   directory mask = 0755\n"
+	if got != want {
+		t.Fatalf("fragment:\n%s\nwant:\n%s", got, want)
+	}
+}
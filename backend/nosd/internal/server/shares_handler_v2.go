@@ -36,6 +36,12 @@ type ShareConfig struct {
 	Description string            `json:"description,omitempty"`
 	CreatedAt   time.Time         `json:"createdAt"`
 	UpdatedAt   time.Time         `json:"updatedAt"`
+
+	// SMB only: users limited to reading, and users acting as root on the share
+	ReadOnlyUsers []string `json:"readOnlyUsers,omitempty"`
+	AdminUsers    []string `json:"adminUsers,omitempty"`
+	// NFS only: root (root_squash), none (no_root_squash) or all (all_squash)
+	NFSSquash string `json:"nfsSquash,omitempty"`
 }
 
 // SharesStore manages share configurations
@@ -109,6 +115,22 @@ func (s *SharesStore) Get(id string) (*ShareConfig, bool) {
 	return share, ok
 }
 
+// Find looks a share up by id, then by name.
+func (s *SharesStore) Find(idOrName string) (*ShareConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if share, ok := s.shares[idOrName]; ok {
+		return share, true
+	}
+	for _, share := range s.shares {
+		if share.Name == idOrName {
+			return share, true
+		}
+	}
+	return nil, false
+}
+
 func (s *SharesStore) Create(share *ShareConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -194,7 +216,31 @@ func (m *SambaManager) ApplyShare(share *ShareConfig) error {
 		return fmt.Errorf("invalid protocol for Samba: %s", share.Protocol)
 	}
 
-	// Generate Samba config section
+	config := renderSambaShare(share)
+
+	// Write to includes directory
+	if err := os.MkdirAll(sambaIncludeDir, 0755); err != nil {
+		return err
+	}
+
+	shareFile := sambaShareFile(share.ID)
+	if err := os.WriteFile(shareFile, []byte(config), 0644); err != nil {
+		return err
+	}
+
+	// Reload Samba
+	return m.reload()
+}
+
+// sambaIncludeDir holds one smb.conf fragment per share.
+const sambaIncludeDir = "/etc/samba/shares.d"
+
+func sambaShareFile(id string) string {
+	return filepath.Join(sambaIncludeDir, fmt.Sprintf("%s.conf", id))
+}
+
+// renderSambaShare builds the smb.conf section for a share.
+func renderSambaShare(share *ShareConfig) string {
 	config := fmt.Sprintf("\n[%s]\n", share.Name)
 	config += fmt.Sprintf("   path = %s\n", share.Path)
 	config += fmt.Sprintf("   comment = %s\n", share.Description)
@@ -214,6 +260,12 @@ func (m *SambaManager) ApplyShare(share *ShareConfig) error {
 	if len(share.Users) > 0 {
 		config += fmt.Sprintf("   valid users = %s\n", strings.Join(share.Users, " "))
 	}
+	if len(share.ReadOnlyUsers) > 0 {
+		config += fmt.Sprintf("   read list = %s\n", strings.Join(share.ReadOnlyUsers, " "))
+	}
+	if len(share.AdminUsers) > 0 {
+		config += fmt.Sprintf("   admin users = %s\n", strings.Join(share.AdminUsers, " "))
+	}
 
 	if !share.Enabled {
 		config += "   available = no\n"
@@ -223,25 +275,11 @@ func (m *SambaManager) ApplyShare(share *ShareConfig) error {
 	config += "   browseable = yes\n"
 	config += "   create mask = 0644\n"
 	config += "   directory mask = 0755\n"
-
-	// Write to includes directory
-	includeDir := "/etc/samba/shares.d"
-	if err := os.MkdirAll(includeDir, 0755); err != nil {
-		return err
-	}
-
-	shareFile := filepath.Join(includeDir, fmt.Sprintf("%s.conf", share.ID))
-	if err := os.WriteFile(shareFile, []byte(config), 0644); err != nil {
-		return err
-	}
-
-	// Reload Samba
-	return m.reload()
+	return config
 }
 
 func (m *SambaManager) RemoveShare(shareID string) error {
-	includeDir := "/etc/samba/shares.d"
-	shareFile := filepath.Join(includeDir, fmt.Sprintf("%s.conf", shareID))
+	shareFile := sambaShareFile(shareID)
 
 	if err := os.Remove(shareFile); err != nil && !os.IsNotExist(err) {
 		return err
@@ -298,43 +336,14 @@ func (m *NFSManager) ApplyShare(share *ShareConfig) error {
 		return fmt.Errorf("invalid protocol for NFS: %s", share.Protocol)
 	}
 
-	// Generate NFS export line
-	options := []string{}
-
-	if share.ReadOnly {
-		options = append(options, "ro")
-	} else {
-		options = append(options, "rw")
-	}
-
-	options = append(options, "sync", "no_subtree_check")
-
-	if share.GuestAccess {
-		options = append(options, "all_squash", "anonuid=65534", "anongid=65534")
-	} else {
-		options = append(options, "no_all_squash")
-	}
-
-	// Build export line
-	export := fmt.Sprintf("%s ", share.Path)
-
-	if len(share.Hosts) == 0 {
-		// Default to local network
-		export += fmt.Sprintf("192.168.0.0/16(%s)", strings.Join(options, ","))
-	} else {
-		for _, host := range share.Hosts {
-			export += fmt.Sprintf("%s(%s) ", host, strings.Join(options, ","))
-		}
-	}
+	export := renderNFSExport(share)
 
 	// Write to exports.d
-	exportsDir := "/etc/exports.d"
-	if err := os.MkdirAll(exportsDir, 0755); err != nil {
+	if err := os.MkdirAll(nfsExportsDir, 0755); err != nil {
 		return err
 	}
 
-	exportFile := filepath.Join(exportsDir, fmt.Sprintf("%s.exports", share.ID))
-	if err := os.WriteFile(exportFile, []byte(export+"\n"), 0644); err != nil {
+	if err := os.WriteFile(nfsExportFile(share), []byte(export), 0644); err != nil {
 		return err
 	}
 
@@ -342,12 +351,11 @@ func (m *NFSManager) ApplyShare(share *ShareConfig) error {
 	return m.reload()
 }
 
-func (m *NFSManager) RemoveShare(shareID string) error {
-	exportsDir := "/etc/exports.d"
-	exportFile := filepath.Join(exportsDir, fmt.Sprintf("%s.exports", shareID))
-
-	if err := os.Remove(exportFile); err != nil && !os.IsNotExist(err) {
-		return err
+func (m *NFSManager) RemoveShare(share *ShareConfig) error {
+	for _, exportFile := range []string{nfsExportFile(share), nfsLegacyExportFile(share)} {
+		if err := os.Remove(exportFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 
 	return m.reload()
@@ -385,6 +393,7 @@ type SharesHandlerV2 struct {
 	samba *SambaManager
 	nfs   *NFSManager
 	agent AgentClient
+	usage *shareUsageCache
 }
 
 // NewSharesHandlerV2 creates a new shares handler
@@ -399,6 +408,7 @@ func NewSharesHandlerV2(storePath string, agent AgentClient) (*SharesHandlerV2,
 		samba: NewSambaManager(),
 		nfs:   NewNFSManager(),
 		agent: agent,
+		usage: newShareUsageCache(),
 	}, nil
 }
 
@@ -414,6 +424,11 @@ func (h *SharesHandlerV2) Routes() chi.Router {
 	r.Post("/{id}/test", h.TestShare)
 	r.Post("/{id}/enable", h.EnableShare)
 	r.Post("/{id}/disable", h.DisableShare)
+	r.Get("/{id}/acl", h.GetShareACL)
+	r.Put("/{id}/acl", h.UpdateShareACL)
+	r.Get("/{id}/nfs", h.GetShareNFS)
+	r.Put("/{id}/nfs", h.UpdateShareNFS)
+	r.Get("/{id}/usage", h.GetShareUsage)
 
 	return r
 }
@@ -496,12 +511,13 @@ func (h *SharesHandlerV2) UpdateShare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get existing share
+	// Get existing share; copied, as Update changes the stored one in place
 	existing, ok := h.store.Get(id)
 	if !ok {
 		httpx.WriteError(w, http.StatusNotFound, "Share not found")
 		return
 	}
+	old := *existing
 
 	// Update in store
 	if err := h.store.Update(id, &updates); err != nil {
@@ -510,12 +526,13 @@ func (h *SharesHandlerV2) UpdateShare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Re-apply to system if enabled
+	// Remove the old config (a rename changes the NFS file name), then
+	// re-apply to system if enabled
 	updated, _ := h.store.Get(id)
+	if old.Enabled {
+		_ = h.removeShare(&old)
+	}
 	if updated.Enabled {
-		// Remove old config
-		_ = h.removeShare(existing)
-		// Apply new config
 		if err := h.applyShare(updated); err != nil {
 			log.Error().Err(err).Str("id", id).Msg("Failed to apply updated share")
 		}
@@ -666,7 +683,7 @@ func (h *SharesHandlerV2) removeShare(share *ShareConfig) error {
 	case "smb":
 		return h.samba.RemoveShare(share.ID)
 	case "nfs":
-		return h.nfs.RemoveShare(share.ID)
+		return h.nfs.RemoveShare(share)
 	default:
 		return fmt.Errorf("unknown protocol: %s", share.Protocol)
 	}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"nithronos/backend/nosd/pkg/httpx"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// test seam
+var nfsExportsDir = "/etc/exports.d"
+
+var (
+	shareFileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	nfsHostRe       = regexp.MustCompile(`^(\*\.)?[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+	nfsNumericRe    = regexp.MustCompile(`^[0-9.]+$`)
+)
+
+// nfsExportFile is exports.d/nos-<name>.exports; shares whose name cannot
+// be a file name fall back to their id.
+func nfsExportFile(share *ShareConfig) string {
+	name := share.Name
+	if !shareFileNameRe.MatchString(name) {
+		name = share.ID
+	}
+	return filepath.Join(nfsExportsDir, "nos-"+name+".exports")
+}
+
+// nfsLegacyExportFile is the <id>.exports name used before nos-<name>.exports.
+func nfsLegacyExportFile(share *ShareConfig) string {
+	return filepath.Join(nfsExportsDir, share.ID+".exports")
+}
+
+// renderNFSExport builds the exports(5) line for a share.
+func renderNFSExport(share *ShareConfig) string {
+	options := []string{}
+
+	if share.ReadOnly {
+		options = append(options, "ro")
+	} else {
+		options = append(options, "rw")
+	}
+
+	options = append(options, "sync", "no_subtree_check")
+
+	switch {
+	case share.NFSSquash == "all" || (share.NFSSquash == "" && share.GuestAccess):
+		options = append(options, "all_squash", "anonuid=65534", "anongid=65534")
+	case share.NFSSquash == "none":
+		options = append(options, "no_root_squash")
+	case share.NFSSquash == "root":
+		options = append(options, "root_squash")
+	default:
+		options = append(options, "no_all_squash")
+	}
+
+	hosts := share.Hosts
+	if len(hosts) == 0 {
+		// Default to local network
+		hosts = []string{"192.168.0.0/16"}
+	}
+	path := share.Path
+	if strings.ContainsAny(path, " \t") {
+		path = `"` + path + `"`
+	}
+	export := path
+	for _, host := range hosts {
+		export += fmt.Sprintf(" %s(%s)", host, strings.Join(options, ","))
+	}
+	return export + "\n"
+}
+
+// NFSExportOptions are the editable export settings of an NFS share.
+type NFSExportOptions struct {
+	// Clients are IPs, CIDRs or host names (optionally *.domain)
+	Clients  []string `json:"clients"`
+	ReadOnly bool     `json:"readOnly"`
+	// Squash is root (default), none or all
+	Squash string `json:"squash"`
+}
+
+func nfsOptionsOf(share *ShareConfig) NFSExportOptions {
+	squash := share.NFSSquash
+	if squash == "" && share.GuestAccess {
+		squash = "all"
+	}
+	clients := share.Hosts
+	if clients == nil {
+		clients = []string{}
+	}
+	return NFSExportOptions{Clients: clients, ReadOnly: share.ReadOnly, Squash: squash}
+}
+
+// normalizeNFSClient validates one client spec and returns it with CIDRs in
+// network form (192.168.1.7/24 -> 192.168.1.0/24).
+func normalizeNFSClient(c string) (string, error) {
+	c = strings.TrimSpace(c)
+	switch {
+	case c == "":
+		return "", fmt.Errorf("empty client")
+	case strings.Contains(c, "/"):
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return "", fmt.Errorf("%s is not a valid CIDR", c)
+		}
+		return n.String(), nil
+	case net.ParseIP(c) != nil:
+		return c, nil
+	case nfsNumericRe.MatchString(c) || !nfsHostRe.MatchString(c):
+		return "", fmt.Errorf("%s is not a valid IP, CIDR or host name", c)
+	}
+	return strings.ToLower(c), nil
+}
+
+// validateNFSOptions normalizes opts in place and returns field errors.
+func validateNFSOptions(opts *NFSExportOptions) []shareFieldError {
+	var errs []shareFieldError
+	if len(opts.Clients) == 0 {
+		errs = append(errs, shareFieldError{Field: "clients", Message: "at least one client is required"})
+	}
+	seen := map[string]bool{}
+	for i, c := range opts.Clients {
+		n, err := normalizeNFSClient(c)
+		switch {
+		case err != nil:
+			errs = append(errs, shareFieldError{Field: fmt.Sprintf("clients[%d]", i), Message: err.Error()})
+		case seen[n]:
+			errs = append(errs, shareFieldError{Field: fmt.Sprintf("clients[%d]", i), Message: fmt.Sprintf("%s is listed twice", n)})
+		default:
+			opts.Clients[i] = n
+		}
+		seen[n] = true
+	}
+	if opts.Squash == "" {
+		opts.Squash = "root"
+	}
+	if !contains([]string{"root", "none", "all"}, opts.Squash) {
+		errs = append(errs, shareFieldError{Field: "squash", Message: "must be one of root, none, all"})
+	}
+	return errs
+}
+
+// SetNFSOptions replaces the export settings of a share.
+func (s *SharesStore) SetNFSOptions(id string, opts NFSExportOptions) (*ShareConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, ok := s.shares[id]
+	if !ok {
+		return nil, fmt.Errorf("share not found")
+	}
+	share.Hosts = opts.Clients
+	share.ReadOnly = opts.ReadOnly
+	share.NFSSquash = opts.Squash
+	share.UpdatedAt = time.Now()
+	return share, s.save()
+}
+
+// GetShareNFS returns the export settings of an NFS share.
+// GET /api/v1/shares/{id}/nfs
+func (h *SharesHandlerV2) GetShareNFS(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.nfsShare(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, nfsOptionsOf(share))
+}
+
+// UpdateShareNFS replaces the export settings of an NFS share. For an
+// enabled share the exports fragment is rewritten and NFS reloaded through
+// the agent before the new settings are saved.
+// PUT /api/v1/shares/{id}/nfs
+func (h *SharesHandlerV2) UpdateShareNFS(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	share, ok := h.nfsShare(w, id)
+	if !ok {
+		return
+	}
+	var opts NFSExportOptions
+	if err := decodeStrict(r, &opts); err != nil {
+		writeInputError(w, err)
+		return
+	}
+	if errs := validateNFSOptions(&opts); len(errs) > 0 {
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "shares.nfs.invalid", "Invalid export options", map[string]any{"fields": errs})
+		return
+	}
+	if !shareFileNameRe.MatchString(share.Name) {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "shares.nfs.invalid_name", "Share name cannot be used as an exports file name", 0)
+		return
+	}
+
+	next := *share
+	next.Hosts, next.ReadOnly, next.NFSSquash = opts.Clients, opts.ReadOnly, opts.Squash
+	if next.Enabled {
+		if err := h.applyNFSViaAgent(r.Context(), &next); err != nil {
+			writeAgentError(w, err, "shares.apply_failed", "Failed to apply the share configuration")
+			return
+		}
+	}
+	updated, err := h.store.SetNFSOptions(id, opts)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to save NFS options")
+		httpx.WriteError(w, http.StatusInternalServerError, "Failed to save share")
+		return
+	}
+	writeJSON(w, nfsOptionsOf(updated))
+}
+
+// nfsShare looks up a share for the NFS endpoints and answers 404/400 itself.
+func (h *SharesHandlerV2) nfsShare(w http.ResponseWriter, id string) (*ShareConfig, bool) {
+	share, ok := h.store.Get(id)
+	if !ok {
+		httpx.WriteError(w, http.StatusNotFound, "Share not found")
+		return nil, false
+	}
+	if share.Protocol != "nfs" {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "shares.nfs.not_nfs", "Export options apply to NFS shares only", 0)
+		return nil, false
+	}
+	return share, true
+}
+
+// applyNFSViaAgent writes the share's exports fragment and re-exports
+// through nos-agent. A fragment under the legacy <id>.exports name is
+// removed first, or exportfs would keep exporting its old settings.
+func (h *SharesHandlerV2) applyNFSViaAgent(ctx context.Context, share *ShareConfig) error {
+	if err := h.agent.PostJSON(ctx, "/v1/fs/remove", map[string]any{"path": nfsLegacyExportFile(share)}, nil); err != nil {
+		return err
+	}
+	write := map[string]any{"path": nfsExportFile(share), "content": renderNFSExport(share), "mode": "0644", "owner": "root", "group": "root"}
+	if err := h.agent.PostJSON(ctx, "/v1/fs/write", write, nil); err != nil {
+		return err
+	}
+	return h.agent.PostJSON(ctx, "/v1/service/reload", map[string]any{"name": "nfs"}, nil)
+}
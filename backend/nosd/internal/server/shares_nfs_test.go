@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderNFSExport(t *testing.T) {
+	share := &ShareConfig{Name: "media", Path: "/srv/shares/media", Protocol: "nfs",
+		Hosts: []string{"192.168.1.0/24", "nas-client.lan"}, ReadOnly: true, NFSSquash: "root"}
+	want := "/srv/shares/media 192.168.1.0/24(ro,sync,no_subtree_check,root_squash) nas-client.lan(ro,sync,no_subtree_check,root_squash)\n"
+	if got := renderNFSExport(share); got != want {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+	if got := nfsExportFile(share); got != "/etc/exports.d/nos-media.exports" {
+		t.Fatalf("export file: %s", got)
+	}
+
+	share.ReadOnly, share.NFSSquash, share.Hosts = false, "all", []string{"10.0.0.5"}
+	want = "/srv/shares/media 10.0.0.5(rw,sync,no_subtree_check,all_squash,anonuid=65534,anongid=65534)\n"
+	if got := renderNFSExport(share); got != want {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestValidateNFSOptions(t *testing.T) {
+	opts := NFSExportOptions{Clients: []string{"192.168.1.7/24", "10.0.0.1", "*.home.lan", "fd00::/8"}}
+	if errs := validateNFSOptions(&opts); len(errs) > 0 {
+		t.Fatalf("valid options rejected: %+v", errs)
+	}
+	if strings.Join(opts.Clients, " ") != "192.168.1.0/24 10.0.0.1 *.home.lan fd00::/8" || opts.Squash != "root" {
+		t.Fatalf("not normalized: %+v", opts)
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "192.168.1.0/abc", "300.1.1.1", "host name", "*", "a;b"} {
+		opts := NFSExportOptions{Clients: []string{bad}}
+		if errs := validateNFSOptions(&opts); len(errs) != 1 || errs[0].Field != "clients[0]" {
+			t.Fatalf("%q: %+v", bad, errs)
+		}
+	}
+	opts = NFSExportOptions{Squash: "some"}
+	if errs := validateNFSOptions(&opts); len(errs) != 2 {
+		t.Fatalf("empty clients and bad squash: %+v", errs)
+	}
+}
+
+func TestShareNFSUpdate(t *testing.T) {
+	agent := &smbAgent{}
+	h, err := NewSharesHandlerV2(filepath.Join(t.TempDir(), "shares.json"), agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := &ShareConfig{Name: "media", Path: t.TempDir(), Protocol: "nfs", Enabled: true}
+	if err := h.store.Create(share); err != nil {
+		t.Fatal(err)
+	}
+	r := h.Routes()
+	put := func(body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/"+share.ID+"/nfs", strings.NewReader(body)))
+		return res
+	}
+
+	res := put(`{"clients":["10.0.0.0/40"],"readOnly":true}`)
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.Body.String(), `"shares.nfs.invalid"`) || len(agent.paths) != 0 {
+		t.Fatalf("invalid cidr: %d %s %v", res.Code, res.Body.String(), agent.paths)
+	}
+
+	res = put(`{"clients":["10.0.0.0/24"],"readOnly":true,"squash":"none"}`)
+	if res.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", res.Code, res.Body.String())
+	}
+	// the legacy <id>.exports fragment goes first
+	if len(agent.paths) != 3 || agent.paths[0] != "/v1/fs/remove" || agent.posts[0]["path"] != "/etc/exports.d/"+share.ID+".exports" ||
+		agent.paths[2] != "/v1/service/reload" || agent.posts[2]["name"] != "nfs" ||
+		agent.posts[1]["path"] != "/etc/exports.d/nos-media.exports" ||
+		!strings.HasSuffix(agent.posts[1]["content"].(string), " 10.0.0.0/24(ro,sync,no_subtree_check,no_root_squash)\n") {
+		t.Fatalf("agent calls: %v %v", agent.paths, agent.posts)
+	}
+
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+share.ID+"/nfs", nil))
+	var got NFSExportOptions
+	_ = json.Unmarshal(res.Body.Bytes(), &got)
+	if !got.ReadOnly || got.Squash != "none" || len(got.Clients) != 1 {
+		t.Fatalf("options not stored: %+v", got)
+	}
+}
+
+func TestUpdateShareRemovesOldNFSExport(t *testing.T) {
+	dir := t.TempDir()
+	old := nfsExportsDir
+	nfsExportsDir = dir
+	t.Cleanup(func() { nfsExportsDir = old })
+
+	h, err := NewSharesHandlerV2(filepath.Join(t.TempDir(), "shares.json"), &smbAgent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := &ShareConfig{Name: "media", Path: t.TempDir(), Protocol: "nfs", Enabled: true}
+	if err := h.store.Create(share); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(dir, share.ID+".exports")
+	for _, f := range []string{legacy, filepath.Join(dir, "nos-media.exports")} {
+		if err := os.WriteFile(f, []byte(renderNFSExport(share)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// exportfs isn't available here; the files are what matter
+	body := `{"name":"films","path":"` + share.Path + `","protocol":"nfs","enabled":true}`
+	res := httptest.NewRecorder()
+	h.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/"+share.ID, strings.NewReader(body)))
+	if res.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", res.Code, res.Body.String())
+	}
+	for _, gone := range []string{legacy, filepath.Join(dir, "nos-media.exports")} {
+		if _, err := os.Stat(gone); !os.IsNotExist(err) {
+			t.Fatalf("%s left behind: %v", filepath.Base(gone), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nos-films.exports")); err != nil {
+		t.Fatalf("new export not written: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/pkg/httpx"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	shareUsageTTL     = time.Minute
+	shareUsageTimeout = 30 * time.Second
+)
+
+// ShareUsage is the space a share's path takes up.
+type ShareUsage struct {
+	Share      string    `json:"share"`
+	Path       string    `json:"path"`
+	UsedBytes  int64     `json:"usedBytes"`
+	Files      *int64    `json:"files,omitempty"`
+	MeasuredAt time.Time `json:"measuredAt"`
+	Cached     bool      `json:"cached"`
+}
+
+// shareUsageCache keeps the last measurement per path for shareUsageTTL;
+// walking a large share is slow and admins tend to refresh the page.
+type shareUsageCache struct {
+	mu      sync.Mutex
+	entries map[string]ShareUsage
+	ttl     time.Duration
+	measure func(ctx context.Context, path string, countFiles bool) (int64, *int64, error)
+}
+
+func newShareUsageCache() *shareUsageCache {
+	return &shareUsageCache{entries: map[string]ShareUsage{}, ttl: shareUsageTTL, measure: measureShareUsage}
+}
+
+func (c *shareUsageCache) get(ctx context.Context, share *ShareConfig, countFiles bool) (ShareUsage, error) {
+	c.mu.Lock()
+	u, ok := c.entries[share.Path]
+	c.mu.Unlock()
+	if ok && time.Since(u.MeasuredAt) < c.ttl && (!countFiles || u.Files != nil) {
+		u.Share, u.Cached = share.Name, true
+		return u, nil
+	}
+
+	used, files, err := c.measure(ctx, share.Path, countFiles)
+	if err != nil {
+		return ShareUsage{}, err
+	}
+	u = ShareUsage{Share: share.Name, Path: share.Path, UsedBytes: used, Files: files, MeasuredAt: time.Now().UTC()}
+	c.mu.Lock()
+	c.entries[share.Path] = u
+	c.mu.Unlock()
+	return u, nil
+}
+
+// measureShareUsage runs `du -sB1` on path and, if asked, counts the regular
+// files below it.
+func measureShareUsage(ctx context.Context, path string, countFiles bool) (int64, *int64, error) {
+	out, err := exec.CommandContext(ctx, "du", "-sB1", path).Output()
+	if ctx.Err() != nil {
+		return 0, nil, ctx.Err()
+	}
+	used, err := parseDuTotal(path, out, err)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !countFiles {
+		return used, nil, nil
+	}
+
+	var n int64
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil && d.Type().IsRegular() {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return used, &n, nil
+}
+
+// parseDuTotal reads the byte total du printed for path. du exits 1 when it
+// couldn't read part of the tree but still prints the total of what it could,
+// so that case is logged and the partial total used.
+func parseDuTotal(path string, out []byte, err error) (int64, error) {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == 1 && len(out) > 0 {
+		log.Warn().Str("path", path).Str("stderr", strings.TrimSpace(string(ee.Stderr))).Msg("du skipped unreadable entries; usage is partial")
+	} else if err != nil {
+		return 0, fmt.Errorf("du %s: %w", path, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("du %s: empty output", path)
+	}
+	used, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("du %s: %w", path, err)
+	}
+	return used, nil
+}
+
+// GetShareUsage reports how much space a share uses. {id} may also be the
+// share name. ?files=true adds a file count, which walks the whole tree.
+// GET /api/v1/shares/{id}/usage
+func (h *SharesHandlerV2) GetShareUsage(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.store.Find(chi.URLParam(r, "id"))
+	if !ok {
+		httpx.WriteError(w, http.StatusNotFound, "Share not found")
+		return
+	}
+	if fi, err := os.Stat(share.Path); err != nil || !fi.IsDir() {
+		httpx.WriteTypedError(w, http.StatusNotFound, "shares.path_not_found", "Share path does not exist", 0)
+		return
+	}
+	countFiles, _ := strconv.ParseBool(r.URL.Query().Get("files"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), shareUsageTimeout)
+	defer cancel()
+	u, err := h.usage.get(ctx, share, countFiles)
+	if errors.Is(err, context.DeadlineExceeded) {
+		httpx.WriteTypedError(w, http.StatusGatewayTimeout, "shares.usage_timeout", "Measuring the share took too long", 0)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("id", share.ID).Msg("Failed to measure share usage")
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "shares.usage_failed", "Failed to measure share usage", 0)
+		return
+	}
+	writeJSON(w, u)
+}
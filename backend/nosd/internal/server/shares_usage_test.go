@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestShareUsage(t *testing.T) {
+	if _, err := exec.LookPath("du"); err != nil {
+		t.Skip("du not available")
+	}
+	dir := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	_ = os.WriteFile(filepath.Join(dir, "a.bin"), make([]byte, 1000), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "sub", "b.bin"), make([]byte, 24), 0o644)
+	want := duBytes(t, dir)
+
+	h, err := NewSharesHandlerV2(filepath.Join(t.TempDir(), "shares.json"), &smbAgent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := &ShareConfig{Name: "docs", Path: dir, Protocol: "smb"}
+	gone := &ShareConfig{Name: "gone", Path: filepath.Join(dir, "missing"), Protocol: "smb"}
+	_ = h.store.Create(share)
+	_ = h.store.Create(gone)
+	r := h.Routes()
+	get := func(path string) (*httptest.ResponseRecorder, ShareUsage) {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		var u ShareUsage
+		_ = json.Unmarshal(res.Body.Bytes(), &u)
+		return res, u
+	}
+
+	res, u := get("/docs/usage?files=true")
+	if res.Code != http.StatusOK || u.UsedBytes != want || u.Files == nil || *u.Files != 2 || u.Cached {
+		t.Fatalf("usage: %d %s (want %d bytes)", res.Code, res.Body.String(), want)
+	}
+
+	// served from the cache, by id as well as by name
+	_ = os.WriteFile(filepath.Join(dir, "c.bin"), make([]byte, 4096), 0o644)
+	if res, u = get("/" + share.ID + "/usage"); res.Code != http.StatusOK || !u.Cached || u.UsedBytes != want {
+		t.Fatalf("cached usage: %d %s", res.Code, res.Body.String())
+	}
+	h.usage.ttl = 0
+	if _, u = get("/docs/usage"); u.Cached || u.UsedBytes != duBytes(t, dir) || u.UsedBytes <= want {
+		t.Fatalf("usage after TTL: %+v", u)
+	}
+
+	if res, _ = get("/gone/usage"); res.Code != http.StatusNotFound || !strings.Contains(res.Body.String(), `"shares.path_not_found"`) {
+		t.Fatalf("missing path: %d %s", res.Code, res.Body.String())
+	}
+	if res, _ = get("/nope/usage"); res.Code != http.StatusNotFound {
+		t.Fatalf("unknown share: %d", res.Code)
+	}
+}
+
+// duBytes is the allocated size du reports for dir.
+func duBytes(t *testing.T, dir string) int64 {
+	t.Helper()
+	out, err := exec.Command("du", "-sB1", dir).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.ParseInt(strings.Fields(string(out))[0], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestParseDuTotalKeepsPartialTotal(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	// du prints the total of what it could read and exits 1
+	out, err := exec.Command("sh", "-c", "echo '4096\t/srv/share'; echo 'du: cannot read directory' >&2; exit 1").Output()
+	if n, perr := parseDuTotal("/srv/share", out, err); perr != nil || n != 4096 {
+		t.Fatalf("exit 1 with a total: %d, %v", n, perr)
+	}
+
+	out, err = exec.Command("sh", "-c", "echo 'du: bad option' >&2; exit 1").Output()
+	if _, perr := parseDuTotal("/srv/share", out, err); perr == nil {
+		t.Fatal("exit 1 without a total should fail")
+	}
+	out, err = exec.Command("sh", "-c", "echo '4096\t/srv/share'; exit 2").Output()
+	if _, perr := parseDuTotal("/srv/share", out, err); perr == nil {
+		t.Fatal("other exit codes should fail")
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// streamTracker hands long-lived streaming handlers a context that is
+// cancelled when shutdown begins. http.Server.Shutdown waits for active
+// connections, so an SSE loop that only watches r.Context() holds the drain
+// open until the shutdown timeout.
+type streamTracker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	open   int
+	wg     sync.WaitGroup
+}
+
+func newStreamTracker() *streamTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamTracker{ctx: ctx, cancel: cancel}
+}
+
+var streams = newStreamTracker()
+
+// track returns a context done when either the request ends or shutdown
+// begins; call done when the handler returns.
+func (s *streamTracker) track(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(s.ctx, cancel)
+	s.mu.Lock()
+	s.open++
+	s.mu.Unlock()
+	s.wg.Add(1)
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			stop()
+			cancel()
+			s.mu.Lock()
+			s.open--
+			s.mu.Unlock()
+			s.wg.Done()
+		})
+	}
+}
+
+// drain cancels every open stream and waits for their handlers to return or
+// ctx to expire. It returns how many streams were open when drain began.
+func (s *streamTracker) drain(ctx context.Context) int {
+	s.mu.Lock()
+	n := s.open
+	s.mu.Unlock()
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return n
+}
+
+// DrainStreams ends in-flight SSE streams ahead of http.Server.Shutdown and
+// returns how many were drained. Streams opened afterwards close immediately.
+func DrainStreams(ctx context.Context) int {
+	return streams.drain(ctx)
+}
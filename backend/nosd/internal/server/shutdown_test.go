@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDrainStreamsEndsOpenTxStream(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("NOS_STATE_DIR", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "pools", "tx"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(txLogPath("tx1"), []byte("step 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	prev := streams
+	streams = newStreamTracker()
+	t.Cleanup(func() { streams = prev })
+
+	r := chi.NewRouter()
+	r.Get("/tx/{id}/stream", handleTxStream)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/tx/tx1/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	br := bufio.NewReader(res.Body)
+	line, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "event: log") {
+		t.Fatalf("first line %q: %v", line, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if n := DrainStreams(ctx); n != 1 {
+		t.Fatalf("expected 1 drained stream, got %d", n)
+	}
+
+	ended := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, br)
+		ended <- err
+	}()
+	select {
+	case err := <-ended:
+		if err != nil {
+			t.Fatalf("stream ended with error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream still open after drain")
+	}
+}
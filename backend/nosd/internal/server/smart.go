@@ -4,21 +4,24 @@ import (
 	"net/http"
 	"strings"
 
+	"nithronos/backend/nosd/internal/config"
 	"nithronos/backend/nosd/pkg/agentclient"
 	"nithronos/backend/nosd/pkg/httpx"
 )
 
-func handleSmartProxy(w http.ResponseWriter, r *http.Request) {
-	dev := r.URL.Query().Get("device")
-	if dev == "" || !strings.HasPrefix(dev, "/dev/") || strings.ContainsAny(dev, " \t\n\r\x00") {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid device")
-		return
+func handleSmartProxy(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dev := r.URL.Query().Get("device")
+		if dev == "" || !strings.HasPrefix(dev, "/dev/") || strings.ContainsAny(dev, " \t\n\r\x00") {
+			httpx.WriteError(w, http.StatusBadRequest, "invalid device")
+			return
+		}
+		client := agentclient.New(cfg.AgentSocket())
+		var out map[string]any
+		if err := client.GetJSON(r.Context(), "/v1/smart?device="+dev, &out); err != nil {
+			writeAgentError(w, err, "smart.read_failed", "Failed to read SMART data")
+			return
+		}
+		writeJSON(w, out)
 	}
-	client := agentclient.New("/run/nos-agent.sock")
-	var out map[string]any
-	if err := client.GetJSON(r.Context(), "/v1/smart?device="+dev, &out); err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	writeJSON(w, out)
 }
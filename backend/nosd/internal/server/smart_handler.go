@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/disks"
 	"nithronos/backend/nosd/pkg/agentclient"
 	"nithronos/backend/nosd/pkg/httpx"
 )
@@ -26,6 +28,8 @@ type SMARTDevice struct {
 	Health       string    `json:"health"` // good, warning, critical, unknown
 	LastChecked  time.Time `json:"last_checked"`
 	Attributes   map[string]any `json:"attributes,omitempty"`
+	AtRisk       bool      `json:"at_risk"`
+	RiskReasons  []string  `json:"risk_reasons,omitempty"`
 }
 
 // SMARTSummary represents overall SMART health
@@ -33,15 +37,45 @@ type SMARTSummary struct {
 	HealthyDevices  int       `json:"healthy_devices"`
 	WarningDevices  int       `json:"warning_devices"`
 	CriticalDevices int       `json:"critical_devices"`
+	AtRiskDevices   int       `json:"at_risk_devices"`
 	TotalDevices    int       `json:"total_devices"`
 	LastScan        time.Time `json:"last_scan"`
 	NextScan        time.Time `json:"next_scan"`
 }
 
+// applySmartRisk flags device at_risk from the attribute counters in an
+// agent /v1/smart response; an at-risk disk that otherwise looks good is
+// downgraded to warning.
+func applySmartRisk(device *SMARTDevice, smartData map[string]any, th disks.RiskThresholds) {
+	s := &disks.SmartSummary{}
+	if passed, ok := smartData["passed"].(bool); ok {
+		s.Healthy = &passed
+	}
+	num := func(key string) *int {
+		if v, ok := smartData[key].(float64); ok {
+			n := int(v)
+			return &n
+		}
+		return nil
+	}
+	s.Reallocated = num("reallocated")
+	s.PendingSectors = num("pending_sectors")
+	s.Uncorrectable = num("offline_uncorrectable")
+	s.CRCErrors = num("crc_errors")
+	s.MediaErrors = num("media_errors")
+	s.PercentUsed = num("percentage_used")
+	s.EvaluateRisk(th)
+	device.AtRisk, device.RiskReasons = s.AtRisk, s.RiskReasons
+	if s.AtRisk && (device.Health == "good" || device.Health == "unknown") {
+		device.Health = "warning"
+	}
+}
+
 // handleSmartDevices returns SMART data for all devices
 func handleSmartDevices(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		devices := []SMARTDevice{}
+		risk := loadHealthConfig(cfg).riskThresholds()
 		
 		// Get list of block devices
 		devicePaths := []string{}
@@ -59,7 +93,7 @@ func handleSmartDevices(cfg config.Config) http.HandlerFunc {
 		}
 		
 		// Try to get SMART data from agent
-		agentSocket := "/run/nos-agent.sock"
+		agentSocket := cfg.AgentSocket()
 		if _, err := os.Stat(agentSocket); err == nil {
 			agent := agentclient.New(agentSocket)
 			for _, devPath := range devicePaths {
@@ -101,6 +135,7 @@ func handleSmartDevices(cfg config.Config) http.HandlerFunc {
 					if hours, ok := smartData["power_on_hours"].(float64); ok {
 						device.PowerOnHours = int(hours)
 					}
+					applySmartRisk(&device, smartData, risk)
 					
 					devices = append(devices, device)
 				}
@@ -130,6 +165,7 @@ func handleSmartSummary(cfg config.Config) http.HandlerFunc {
 			LastScan: time.Now(),
 			NextScan: time.Now().Add(6 * time.Hour),
 		}
+		risk := loadHealthConfig(cfg).riskThresholds()
 		
 		// Get device health from the devices endpoint logic
 		devices := []SMARTDevice{}
@@ -145,7 +181,7 @@ func handleSmartSummary(cfg config.Config) http.HandlerFunc {
 			}
 		}
 		
-		agentSocket := "/run/nos-agent.sock"
+		agentSocket := cfg.AgentSocket()
 		if _, err := os.Stat(agentSocket); err == nil {
 			agent := agentclient.New(agentSocket)
 			for _, devPath := range devicePaths {
@@ -174,7 +210,9 @@ func handleSmartSummary(cfg config.Config) http.HandlerFunc {
 						}
 					}
 					
-					devices = append(devices, SMARTDevice{Health: health})
+					device := SMARTDevice{Health: health}
+					applySmartRisk(&device, smartData, risk)
+					devices = append(devices, device)
 				}
 			}
 		}
@@ -182,6 +220,9 @@ func handleSmartSummary(cfg config.Config) http.HandlerFunc {
 		// Count devices by health status
 		for _, device := range devices {
 			summary.TotalDevices++
+			if device.AtRisk {
+				summary.AtRiskDevices++
+			}
 			switch device.Health {
 			case "good":
 				summary.HealthyDevices++
@@ -233,7 +274,7 @@ func handleSmartDevice(cfg config.Config) http.HandlerFunc {
 		}
 		
 		// Try to get SMART data from agent
-		agentSocket := "/run/nos-agent.sock"
+		agentSocket := cfg.AgentSocket()
 		if _, err := os.Stat(agentSocket); err == nil {
 			agent := agentclient.New(agentSocket)
 			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
@@ -269,6 +310,7 @@ func handleSmartDevice(cfg config.Config) http.HandlerFunc {
 				if hours, ok := smartData["power_on_hours"].(float64); ok {
 					device.PowerOnHours = int(hours)
 				}
+				applySmartRisk(&device, smartData, loadHealthConfig(cfg).riskThresholds())
 			}
 		}
 		
@@ -276,30 +318,34 @@ func handleSmartDevice(cfg config.Config) http.HandlerFunc {
 	}
 }
 
-// handleSmartScan triggers a SMART scan on all devices
+// handleSmartScan samples every disk in the background; the results land in
+// the per-device history (GET /api/v1/smart/history/{device}).
 func handleSmartScan(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// This would trigger a background scan of all devices
-		// For now, just return success
-		result := map[string]any{
-			"status":  "started",
-			"message": "SMART scan initiated on all devices",
-			"devices": []string{},
+		devs, err := listSmartDevices(r.Context())
+		if err != nil {
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "smart.scan_failed", "Failed to list devices", 0)
+			return
 		}
-		
-		// Get list of devices to scan
-		if entries, err := os.ReadDir("/dev"); err == nil {
-			for _, entry := range entries {
-				name := entry.Name()
-				if strings.HasPrefix(name, "sd") || strings.HasPrefix(name, "nvme") || strings.HasPrefix(name, "hd") {
-					if !strings.ContainsAny(name[2:], "0123456789p") {
-						result["devices"] = append(result["devices"].([]string), "/dev/"+name)
-					}
-				}
+		if smartScanning.Load() {
+			httpx.WriteTypedError(w, http.StatusConflict, "smart.scan_running", "A SMART scan is already running", 0)
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			if _, err := runSmartScan(ctx, cfg); err != nil && !errors.Is(err, errSmartScanRunning) {
+				Logger(cfg).Warn().Str("event", "smart.scan.failed").Err(err).Msg("")
 			}
+		}()
+		if devs == nil {
+			devs = []string{}
 		}
-		
-		writeJSON(w, result)
+		writeJSON(w, map[string]any{
+			"status":  "started",
+			"message": "SMART scan initiated on all devices",
+			"devices": devs,
+		})
 	}
 }
 
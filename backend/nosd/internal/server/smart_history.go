@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/disks"
+	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+const (
+	// smartHistoryRetention and smartHistoryMax bound the samples kept per
+	// device: about six weeks of hourly scans, or all of the last 90 days.
+	smartHistoryRetention = 90 * 24 * time.Hour
+	smartHistoryMax       = 1000
+)
+
+var errSmartScanRunning = errors.New("a SMART scan is already running")
+
+// smartSample is one scan of one device: the agent's /v1/smart counters.
+type smartSample struct {
+	At             time.Time `json:"t"`
+	Passed         *bool     `json:"passed,omitempty"`
+	TemperatureC   *int      `json:"temperature_c,omitempty"`
+	PowerOnHours   *int      `json:"power_on_hours,omitempty"`
+	Reallocated    *int      `json:"reallocated,omitempty"`
+	PendingSectors *int      `json:"pending_sectors,omitempty"`
+	Uncorrectable  *int      `json:"offline_uncorrectable,omitempty"`
+	CRCErrors      *int      `json:"crc_errors,omitempty"`
+	MediaErrors    *int      `json:"media_errors,omitempty"`
+	PercentUsed    *int      `json:"percentage_used,omitempty"`
+}
+
+// smartHistory holds recent SMART samples per device name (sda, nvme0n1).
+type smartHistory struct {
+	mu      sync.Mutex
+	loaded  bool
+	samples map[string][]smartSample
+}
+
+var smartSamples = &smartHistory{}
+
+func smartHistoryPath() string {
+	base := os.Getenv("NOS_STATE_DIR")
+	if base == "" {
+		base = "/var/lib/nos"
+	}
+	return filepath.Join(base, "health", "smart-history.json")
+}
+
+// load reads the persisted history once; callers hold h.mu.
+func (h *smartHistory) load() {
+	if h.loaded {
+		return
+	}
+	h.loaded = true
+	h.samples = map[string][]smartSample{}
+	if b, err := os.ReadFile(smartHistoryPath()); err == nil {
+		_ = json.Unmarshal(b, &h.samples)
+	}
+}
+
+// record appends the samples of one scan, trims every series to the
+// retention bounds and persists the result.
+func (h *smartHistory) record(ctx context.Context, scan map[string]smartSample, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.load()
+	for dev, s := range scan {
+		h.samples[dev] = append(h.samples[dev], s)
+	}
+	cutoff := now.Add(-smartHistoryRetention)
+	for dev, series := range h.samples {
+		i := 0
+		for i < len(series) && series[i].At.Before(cutoff) {
+			i++
+		}
+		if len(series)-i > smartHistoryMax {
+			i = len(series) - smartHistoryMax
+		}
+		if i == len(series) {
+			delete(h.samples, dev)
+			continue
+		}
+		h.samples[dev] = append([]smartSample(nil), series[i:]...)
+	}
+	return fsatomic.SaveJSON(ctx, smartHistoryPath(), h.samples, 0o600)
+}
+
+func (h *smartHistory) series(dev string) []smartSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.load()
+	return append([]smartSample(nil), h.samples[dev]...)
+}
+
+// listSmartDevices returns the whole-disk device paths to scan; a seam for
+// tests.
+var listSmartDevices = func(ctx context.Context) ([]string, error) {
+	list, err := disks.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, d := range list {
+		if d.Type == "disk" && d.Path != "" {
+			out = append(out, d.Path)
+		}
+	}
+	return out, nil
+}
+
+var smartScanning atomic.Bool
+
+// runSmartScan samples every disk through the agent, records the results
+// and evaluates the temperature alert thresholds. Devices the agent cannot
+// read are skipped; only one scan runs at a time.
+func runSmartScan(ctx context.Context, cfg config.Config) (int, error) {
+	if !smartScanning.CompareAndSwap(false, true) {
+		return 0, errSmartScanRunning
+	}
+	defer smartScanning.Store(false)
+
+	devs, err := listSmartDevices(ctx)
+	if err != nil {
+		return 0, err
+	}
+	client := agentclient.New(cfg.AgentSocket())
+	now := time.Now().UTC()
+	scan := map[string]smartSample{}
+	for _, dev := range devs {
+		s := smartSample{At: now}
+		if err := client.GetJSON(ctx, "/v1/smart?device="+dev, &s); err != nil {
+			Logger(cfg).Debug().Str("event", "smart.scan.device_failed").Str("device", dev).Err(err).Msg("")
+			continue
+		}
+		s.At = now
+		scan[filepath.Base(dev)] = s
+	}
+	if len(scan) == 0 {
+		return 0, nil
+	}
+	if err := smartSamples.record(ctx, scan, now); err != nil {
+		return len(scan), err
+	}
+	return len(scan), updateTempAlerts(ctx, cfg, scan)
+}
+
+// StartSmartScanner samples SMART data every cfg.SmartScanSeconds until
+// ctx is done; a zero interval leaves only ad-hoc scans.
+func StartSmartScanner(ctx context.Context, cfg config.Config) {
+	if cfg.SmartScanSeconds <= 0 {
+		return
+	}
+	startSmartScanner(ctx, cfg, time.Duration(cfg.SmartScanSeconds)*time.Second)
+}
+
+// startSmartScanner runs the scan loop; the returned channel closes once
+// it has stopped.
+func startSmartScanner(ctx context.Context, cfg config.Config, every time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			sctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+			if _, err := runSmartScan(sctx, cfg); err != nil && !errors.Is(err, errSmartScanRunning) {
+				Logger(cfg).Warn().Str("event", "smart.scan.failed").Err(err).Msg("")
+			}
+			cancel()
+		}
+	}()
+	return done
+}
+
+// GET /api/v1/smart/history/{device}
+func handleSmartHistory(w http.ResponseWriter, r *http.Request) {
+	dev := strings.TrimSpace(chi.URLParam(r, "device"))
+	if dev == "" || strings.ContainsAny(dev, "/\\") {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "device.invalid", "Invalid device name", 0)
+		return
+	}
+	samples := smartSamples.series(dev)
+	if samples == nil {
+		samples = []smartSample{}
+	}
+	writeJSON(w, map[string]any{"device": "/dev/" + dev, "samples": samples})
+}
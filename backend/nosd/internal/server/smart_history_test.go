@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/notifications"
+)
+
+func smartHistoryTestEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("NOS_STATE_DIR", t.TempDir())
+	old := smartSamples
+	smartSamples = &smartHistory{}
+	t.Cleanup(func() { smartSamples = old })
+}
+
+func TestSmartScannerDispatchesScheduledScans(t *testing.T) {
+	smartHistoryTestEnv(t)
+	oldList := listSmartDevices
+	listSmartDevices = func(context.Context) ([]string, error) { return []string{"/dev/sda", "/dev/sdb"}, nil }
+	t.Cleanup(func() { listSmartDevices = oldList })
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("device") != "/dev/sda" {
+			http.Error(w, `{"error":"smartctl failed"}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"passed":true,"temperature_c":36,"reallocated":0,"power_on_hours":1200}`))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := startSmartScanner(ctx, config.Config{AgentSocketPath: sock}, 10*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(smartSamples.series("sda")) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("scheduled scans not recorded: %+v", smartSamples.series("sda"))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	s := smartSamples.series("sda")[0]
+	if s.TemperatureC == nil || *s.TemperatureC != 36 || s.Passed == nil || !*s.Passed || s.At.IsZero() {
+		t.Fatalf("sample = %+v", s)
+	}
+	if got := smartSamples.series("sdb"); len(got) != 0 {
+		t.Fatalf("unreadable device recorded: %+v", got)
+	}
+}
+
+func TestSmartHistoryRetention(t *testing.T) {
+	smartHistoryTestEnv(t)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	temp := func(v int) *int { return &v }
+
+	// one device only has samples past retention, the other more than the cap
+	old := map[string]smartSample{"sdz": {At: now.Add(-smartHistoryRetention - time.Hour), TemperatureC: temp(30)}}
+	if err := smartSamples.record(context.Background(), old, now.Add(-smartHistoryRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < smartHistoryMax+10; i++ {
+		at := now.Add(time.Duration(i-smartHistoryMax-10) * time.Minute)
+		if err := smartSamples.record(context.Background(), map[string]smartSample{"sda": {At: at, TemperatureC: temp(i)}}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a fresh store reads the persisted history
+	smartSamples = &smartHistory{}
+	if got := smartSamples.series("sdz"); len(got) != 0 {
+		t.Fatalf("expired samples kept: %+v", got)
+	}
+	got := smartSamples.series("sda")
+	if len(got) != smartHistoryMax || *got[0].TemperatureC != 10 || *got[len(got)-1].TemperatureC != smartHistoryMax+9 {
+		t.Fatalf("kept %d samples, first temp %d; want the newest %d", len(got), *got[0].TemperatureC, smartHistoryMax)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/smart/history/{device}", handleSmartHistory)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/smart/history/sda", nil))
+	var out struct {
+		Device  string        `json:"device"`
+		Samples []smartSample `json:"samples"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil || out.Device != "/dev/sda" || len(out.Samples) != smartHistoryMax {
+		t.Fatalf("history endpoint: %d %v %s", res.Code, err, res.Body.String()[:120])
+	}
+}
+
+func TestSmartScannerRaisesTemperatureAlerts(t *testing.T) {
+	smartHistoryTestEnv(t)
+	dir := t.TempDir()
+	old := healthTemps
+	healthTemps = newTempEvaluator()
+	t.Cleanup(func() { healthTemps = old })
+	oldList := listSmartDevices
+	listSmartDevices = func(context.Context) ([]string, error) { return []string{"/dev/sda"}, nil }
+	t.Cleanup(func() { listSmartDevices = oldList })
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"passed":true,"temperature_c":72}`))
+	})
+
+	got := make(chan notifications.Notification, 8)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notifications.Notification
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		got <- n
+	}))
+	defer hook.Close()
+	nm, err := notifications.NewManager(filepath.Join(dir, "notifications"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nm.CreateChannel(&notifications.Channel{Name: "hook", Type: notifications.ChannelWebhook, Enabled: true, Config: map[string]interface{}{"url": hook.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	setAlertNotifier(nm)
+	t.Cleanup(func() { setAlertNotifier(nil) })
+
+	cfg := config.Config{AgentSocketPath: sock, EtcDir: dir}
+	if _, err := runSmartScan(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	alerts := loadAlerts()
+	if len(alerts) != 1 || alerts[0].Kind != "temperature" || alerts[0].Severity != "crit" || alerts[0].Device != "/dev/sda" || alerts[0].Threshold != 70 {
+		t.Fatalf("expected a crit temperature alert: %+v", alerts)
+	}
+	select {
+	case n := <-got:
+		if n.Details["severity"] != "crit" || n.Details["temperatureC"] != float64(72) {
+			t.Fatalf("unexpected notification: %+v", n)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no notification for the periodic scan")
+	}
+
+	// a second scan at the same temperature keeps the alert without re-notifying
+	if _, err := runSmartScan(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if alerts := loadAlerts(); len(alerts) != 1 {
+		t.Fatalf("alert should be replaced, not duplicated: %+v", alerts)
+	}
+	select {
+	case n := <-got:
+		t.Fatalf("unexpected notification: %+v", n)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"nithronos/backend/nosd/pkg/httpx"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+const (
+	snapshotsRecentDefaultLimit = 20
+	snapshotsRecentMaxLimit     = 100
+)
+
+// GET /api/v1/snapshots/recent?limit=&cursor=&success=&package=
+//
+// Returns {"items": [...], "nextCursor": "..."}; nextCursor is null on the
+// last page.
+func handleSnapshotsRecent(w http.ResponseWriter, r *http.Request) {
+	q := snapdb.Query{Limit: snapshotsRecentDefaultLimit, Cursor: r.URL.Query().Get("cursor")}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > snapshotsRecentMaxLimit {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "snapshots.invalid_query", "limit must be between 1 and 100", 0)
+			return
+		}
+		q.Limit = n
+	}
+	if v := r.URL.Query().Get("success"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "snapshots.invalid_query", "success must be true or false", 0)
+			return
+		}
+		q.Success = &b
+	}
+	q.Package = strings.TrimSpace(r.URL.Query().Get("package"))
+
+	list, next, err := snapdb.ListPage(q)
+	if errors.Is(err, snapdb.ErrBadCursor) {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "snapshots.invalid_cursor", "cursor is invalid", 0)
+		return
+	}
+	if err != nil {
+		log.Error().Str("event", "snapshots.list_failed").Err(err).Msg("")
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "snapshots.list_failed", "Reading snapshot history failed", 0)
+		return
+	}
+	// project limited fields
+	items := make([]map[string]any, 0, len(list))
+	for _, tx := range list {
+		miniTargets := make([]map[string]any, 0, len(tx.Targets))
+		for _, t := range tx.Targets {
+			miniTargets = append(miniTargets, map[string]any{
+				"id": t.ID, "type": t.Type, "location": t.Location,
+			})
+		}
+		ok := false
+		if tx.Success != nil {
+			ok = *tx.Success
+		}
+		items = append(items, map[string]any{
+			"tx_id":    tx.TxID,
+			"time":     tx.StartedAt,
+			"packages": tx.Packages,
+			"targets":  miniTargets,
+			"success":  ok,
+		})
+	}
+	var nextCursor *string
+	if next != "" {
+		nextCursor = &next
+	}
+	writeJSON(w, map[string]any{"items": items, "nextCursor": nextCursor})
+}
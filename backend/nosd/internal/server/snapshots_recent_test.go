@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+func TestSnapshotsRecentPagination(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	base := time.Now().UTC()
+	yes, no := true, false
+	_ = snapdb.Append(snapdb.UpdateTx{TxID: "a", StartedAt: base.Add(-3 * time.Hour), Packages: []string{"nosd"}, Success: &yes,
+		Targets: []snapdb.SnapshotTarget{{ID: "s1", Path: "/srv", Type: "btrfs", Location: "/srv/.snapshots/s1"}}})
+	_ = snapdb.Append(snapdb.UpdateTx{TxID: "b", StartedAt: base.Add(-2 * time.Hour), Packages: []string{"nos-web"}, Success: &no})
+	_ = snapdb.Append(snapdb.UpdateTx{TxID: "c", StartedAt: base.Add(-1 * time.Hour), Packages: []string{"nosd"}, Success: &yes})
+
+	r := NewRouter(config.FromEnv())
+	get := func(query url.Values) (int, map[string]any) {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/snapshots/recent?"+query.Encode(), nil))
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res.Code, out
+	}
+	ids := func(out map[string]any) []string {
+		items, _ := out["items"].([]any)
+		ids := []string{}
+		for _, it := range items {
+			ids = append(ids, it.(map[string]any)["tx_id"].(string))
+		}
+		return ids
+	}
+
+	code, out := get(url.Values{"limit": {"2"}})
+	if code != http.StatusOK || len(ids(out)) != 2 || ids(out)[0] != "c" {
+		t.Fatalf("first page: %d %v", code, out)
+	}
+	next, _ := out["nextCursor"].(string)
+	if next == "" {
+		t.Fatalf("expected nextCursor: %v", out)
+	}
+	code, out = get(url.Values{"limit": {"2"}, "cursor": {next}})
+	if got := ids(out); code != http.StatusOK || len(got) != 1 || got[0] != "a" || out["nextCursor"] != nil {
+		t.Fatalf("last page: %d %v", code, out)
+	}
+	item := out["items"].([]any)[0].(map[string]any)
+	for _, k := range []string{"tx_id", "time", "packages", "targets", "success"} {
+		if _, ok := item[k]; !ok {
+			t.Fatalf("projection missing %s: %v", k, item)
+		}
+	}
+
+	_, out = get(url.Values{"success": {"true"}, "package": {"nosd"}})
+	if got := ids(out); len(got) != 2 || got[0] != "c" || got[1] != "a" {
+		t.Fatalf("filtered: %v", out)
+	}
+
+	for _, q := range []url.Values{{"limit": {"0"}}, {"limit": {"101"}}, {"success": {"maybe"}}, {"cursor": {"%%%"}}} {
+		if code, out := get(q); code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d %v", q, code, out)
+		}
+	}
+}
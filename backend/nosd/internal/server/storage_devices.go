@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"nithronos/backend/nosd/internal/config"
 	"nithronos/backend/nosd/internal/storage/blk"
 )
 
@@ -21,28 +22,30 @@ type deviceDTO struct {
 	Warnings    []string `json:"warnings"`
 }
 
-func handleListDevices(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-	ds, err := blk.ListCandidates(ctx)
-	if err != nil {
-		writeJSON(w, map[string]any{"devices": []any{}, "error": err.Error()})
-		return
+func handleListDevices(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		ds, err := blk.ListCandidates(ctx, cfg.AgentSocket())
+		if err != nil {
+			writeJSON(w, map[string]any{"devices": []any{}, "error": err.Error()})
+			return
+		}
+		out := make([]deviceDTO, 0, len(ds))
+		for _, d := range ds {
+			out = append(out, deviceDTO{
+				Name:        d.Name,
+				Path:        d.Path,
+				Size:        d.SizeBytes,
+				Model:       d.Model,
+				Serial:      d.Serial,
+				Rota:        d.Rota,
+				FsType:      d.FSType,
+				BtrfsMember: d.BtrfsMember,
+				LUKS:        d.LUKS,
+				Warnings:    d.Warnings,
+			})
+		}
+		writeJSON(w, map[string]any{"devices": out})
 	}
-	out := make([]deviceDTO, 0, len(ds))
-	for _, d := range ds {
-		out = append(out, deviceDTO{
-			Name:        d.Name,
-			Path:        d.Path,
-			Size:        d.SizeBytes,
-			Model:       d.Model,
-			Serial:      d.Serial,
-			Rota:        d.Rota,
-			FsType:      d.FSType,
-			BtrfsMember: d.BtrfsMember,
-			LUKS:        d.LUKS,
-			Warnings:    d.Warnings,
-		})
-	}
-	writeJSON(w, map[string]any{"devices": out})
 }
@@ -53,17 +53,6 @@ type PoolSummary struct {
 	PoolsDegraded int    `json:"poolsDegraded"`
 }
 
-// Device represents a storage device
-type Device struct {
-	Path   string `json:"path"`
-	Model  string `json:"model,omitempty"`
-	Serial string `json:"serial,omitempty"`
-	Size   uint64 `json:"size"`
-	Type   string `json:"type,omitempty"` // ssd, hdd
-	InUse  bool   `json:"inUse"`
-	Pool   string `json:"pool,omitempty"`
-}
-
 // ScrubStatus represents the status of a scrub operation
 type ScrubStatus struct {
 	PoolID       string  `json:"poolId"`
@@ -111,9 +100,6 @@ func (h *StorageHandler) Routes() chi.Router {
 	r.Get("/pools/{uuid}/options", h.GetPoolMountOptions)
 	r.Put("/pools/{uuid}/options", h.SetPoolMountOptions)
 
-	// Device endpoints
-	r.Get("/devices", h.GetDevices)
-
 	return r
 }
 
@@ -256,17 +242,6 @@ func (h *StorageHandler) SetPoolMountOptions(w http.ResponseWriter, r *http.Requ
 	log.Info().Str("uuid", uuid).Str("options", req.MountOptions).Msg("Updated mount options for pool")
 }
 
-// GetDevices returns all storage devices
-// GET /api/v1/storage/devices
-func (h *StorageHandler) GetDevices(w http.ResponseWriter, r *http.Request) {
-	devices := h.getDevices()
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(devices); err != nil {
-		fmt.Printf("Failed to write response: %v\n", err)
-	}
-}
-
 // Helper function to get pools (mock data for now)
 func (h *StorageHandler) getPools() []Pool {
 	// In real implementation, this would use btrfs commands
@@ -299,47 +274,6 @@ func (h *StorageHandler) getPools() []Pool {
 	}
 }
 
-// Helper function to get devices (mock data for now)
-func (h *StorageHandler) getDevices() []Device {
-	// In real implementation, this would use lsblk, smartctl, etc.
-	return []Device{
-		{
-			Path:   "/dev/sda",
-			Model:  "Samsung SSD 870",
-			Serial: "S5Y2NF0NA12345",
-			Size:   1000000000000,
-			Type:   "ssd",
-			InUse:  true,
-			Pool:   "main-pool",
-		},
-		{
-			Path:   "/dev/sdb",
-			Model:  "Samsung SSD 870",
-			Serial: "S5Y2NF0NA12346",
-			Size:   1000000000000,
-			Type:   "ssd",
-			InUse:  true,
-			Pool:   "main-pool",
-		},
-		{
-			Path:   "/dev/sdc",
-			Model:  "WD Red Plus",
-			Serial: "WX21A23C4567",
-			Size:   4000000000000,
-			Type:   "hdd",
-			InUse:  false,
-		},
-		{
-			Path:   "/dev/sdd",
-			Model:  "WD Red Plus",
-			Serial: "WX21A23C4568",
-			Size:   4000000000000,
-			Type:   "hdd",
-			InUse:  false,
-		},
-	}
-}
-
 // BtrfsHandler handles Btrfs-specific endpoints
 type BtrfsHandler struct {
 	agentClient AgentClient
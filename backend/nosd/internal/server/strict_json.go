@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// decodeStrict decodes the JSON request body into v and rejects fields v
+// doesn't declare, so a misspelt key (enableTotp for enable_totp) fails
+// loudly instead of silently taking the zero value. An empty body leaves v
+// untouched. Use it on setup and account routes; other handlers keep
+// lenient decoding so older clients sending extra fields still work.
+func decodeStrict(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// writeInputError reports a decodeStrict failure as 400 input.invalid,
+// naming the offending field in details.field when there is one.
+func writeInputError(w http.ResponseWriter, err error) {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "input.invalid", "Field "+strconv.Quote(typeErr.Field)+" must be a "+typeErr.Type.String(), map[string]any{"field": typeErr.Field})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "input.invalid", "Unknown field "+strconv.Quote(field), map[string]any{"field": field})
+	default:
+		httpx.WriteTypedError(w, http.StatusBadRequest, "input.invalid", "Request body is not valid JSON", 0)
+	}
+}
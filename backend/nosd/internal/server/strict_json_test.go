@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestCreateAdminRejectsUnknownField(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret.key")
+	usersPath := filepath.Join(dir, "users.json")
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(2 + i)
+	}
+	if err := os.WriteFile(secretPath, key, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOS_SECRET_PATH", secretPath)
+	t.Setenv("NOS_USERS_PATH", usersPath)
+	t.Setenv("NOS_ETC_DIR", dir)
+	t.Setenv("NOS_APPS_STATE", filepath.Join(dir, "apps.json"))
+	t.Setenv("NOS_DISABLE_APP_EVENTS", "1")
+	r := NewRouter(config.FromEnv())
+
+	sc := securecookie.New(key, nil)
+	tok, _ := sc.Encode("nos_setup", map[string]any{"purpose": "setup", "exp": time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)})
+	post := func(body map[string]any) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/setup/first-admin", bytes.NewReader(mustJSON(body)))
+		req.Header.Set("Authorization", "Bearer "+tok)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res, out
+	}
+
+	res, out := post(map[string]any{"username": "alice", "password": "StrongPassw0rd!", "enableTotp": true})
+	if res.Code != http.StatusBadRequest || errCode(out) != "input.invalid" {
+		t.Fatalf("typo'd field: expected 400 input.invalid, got %d %s", res.Code, res.Body.String())
+	}
+	details, _ := out["error"].(map[string]any)["details"].(map[string]any)
+	if details["field"] != "enableTotp" {
+		t.Fatalf("offending field not named: %s", res.Body.String())
+	}
+	if _, err := os.Stat(usersPath); err == nil {
+		if b, _ := os.ReadFile(usersPath); bytes.Contains(b, []byte("alice")) {
+			t.Fatalf("admin created despite rejected body")
+		}
+	}
+
+	res, out = post(map[string]any{"username": "alice", "password": "StrongPassw0rd!", "enable_totp": "yes"})
+	if res.Code != http.StatusBadRequest || errCode(out) != "input.invalid" {
+		t.Fatalf("wrong type: expected 400 input.invalid, got %d %s", res.Code, res.Body.String())
+	}
+
+	// The web client also sends the setup token in the body.
+	res, _ = post(map[string]any{"token": tok, "username": "alice", "password": "StrongPassw0rd!", "enable_totp": false})
+	if res.Code != http.StatusOK {
+		t.Fatalf("valid body: expected 200, got %d %s", res.Code, res.Body.String())
+	}
+}
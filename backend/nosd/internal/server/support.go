@@ -4,68 +4,321 @@ import (
 	"archive/tar"
 	"bufio"
 	"compress/gzip"
+	"encoding/json"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"nithronos/backend/nosd/internal/config"
 )
 
-var redactionPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`(?i)(password\s*[:=]\s*)([^\s"']+)`),
-	regexp.MustCompile(`(?i)(secret\s*[:=]\s*)([^\s"']+)`),
-	regexp.MustCompile(`(?i)(token\s*[:=]\s*)([^\s"']+)`),
-	regexp.MustCompile(`(?i)(key\s*=\s*)([^\s"']+)`),
+// supportBundleMaxBytes caps the uncompressed size of a support bundle;
+// anything past the cap is listed as excluded in the manifest.
+const supportBundleMaxBytes = 64 << 20
+
+var redactionRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// JSON string values under secret-looking keys: "password_hash": "..."
+	{regexp.MustCompile(`(?i)("[\w.-]*(?:password|passwd|secret|token|totp|otp|hash|session_?key|private_?key|api_?key)[\w.-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`), `$1"REDACTED"`},
+	// header arrays in JSON access logs
+	{regexp.MustCompile(`(?i)("(?:authorization|cookie|set-cookie|x-api-key)"\s*:\s*)\[[^\]]*\]`), `$1["REDACTED"]`},
+	// key: value and key=value, quoted or bare
+	{regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api_?key|private_?key)\w*\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s"',}]+)`), `${1}REDACTED`},
+	{regexp.MustCompile(`(?i)(key\s*=\s*)([^\s"']+)`), `${1}REDACTED`},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), `${1}REDACTED`},
+	// password hashes wherever they appear
+	{regexp.MustCompile(`\$argon2(?:id|i|d)\$[^\s"',]+`), `REDACTED`},
+	{regexp.MustCompile(`\$2[aby]\$\d\d\$[./A-Za-z0-9]{53}`), `REDACTED`},
 }
 
 func redactLine(line string) string {
-	s := line
-	for _, re := range redactionPatterns {
-		s = re.ReplaceAllString(s, `$1REDACTED`)
-	}
+	s, _ := redactLineCount(line)
 	return s
 }
 
-func writeTarFile(tw *tar.Writer, name string, r io.Reader) error {
-	// Read all into buffer? For streams, we can read to memory to set size; but safer to stream without size by buffering.
-	// We'll read into a temporary buffer since sizes are expected to be modest for text outputs.
-	buf := bufio.NewScanner(r)
-	var b strings.Builder
-	for buf.Scan() {
-		b.WriteString(redactLine(buf.Text()))
-		b.WriteByte('\n')
+// redactLineCount redacts line and reports how many values were replaced.
+func redactLineCount(line string) (string, int) {
+	s, n := line, 0
+	for _, r := range redactionRules {
+		if m := r.re.FindAllStringIndex(s, -1); len(m) > 0 {
+			n += len(m)
+			s = r.re.ReplaceAllString(s, r.repl)
+		}
 	}
-	data := []byte(b.String())
-	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}
-	if err := tw.WriteHeader(hdr); err != nil {
-		return err
+	return s, n
+}
+
+// sensitiveSupportNames are state files that hold credentials, keys or
+// tokens; they are never copied into a bundle, redacted or not.
+var sensitiveSupportNames = map[string]bool{
+	"users.json":            true,
+	"secret.key":            true,
+	"sessions.json":         true,
+	"firstboot.json":        true,
+	"otp":                   true,
+	"totp.json":             true,
+	"totp_temp.json":        true,
+	"passwords.json":        true,
+	"password_history.json": true,
+	"reset_tokens.json":     true,
+	"agents.json":           true,
+	"channels.json":         true,
+	"destinations.json":     true,
+	"wireguard-config.json": true,
+	"wireguard_config.json": true,
+	"wireguard_peers.json":  true,
+}
+
+// supportSensitive reports whether path must be left out of a bundle.
+func supportSensitive(cfg config.Config, path string) bool {
+	for _, p := range []string{cfg.UsersPath, cfg.SecretPath, cfg.SessionsPath, cfg.FirstBootPath} {
+		if p != "" && filepath.Clean(p) == filepath.Clean(path) {
+			return true
+		}
 	}
-	_, err := tw.Write(data)
-	return err
+	base := strings.ToLower(filepath.Base(path))
+	if sensitiveSupportNames[base] {
+		return true
+	}
+	for _, ext := range []string{".key", ".pem", ".p12", ".pfx"} {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
+	}
+	return strings.Contains(base, "token") || strings.Contains(base, "secret")
+}
+
+// supportFile is one manifest entry.
+type supportFile struct {
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	Bytes      int    `json:"bytes"`
+	Redactions int    `json:"redactions,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+}
+
+type supportExcluded struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// supportManifest is written as manifest.json at the end of every bundle.
+type supportManifest struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	LogWindow   string            `json:"log_window"`
+	LogMaxBytes int64             `json:"log_max_bytes"`
+	TotalBytes  int64             `json:"total_bytes"`
+	Files       []supportFile     `json:"files"`
+	Excluded    []supportExcluded `json:"excluded"`
+}
+
+// seam for tests
+var supportCommand = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
 }
 
-func writeTarFileIfExists(tw *tar.Writer, hostPath, name string) {
+// supportBundle streams redacted files into a tar and records the manifest.
+type supportBundle struct {
+	cfg      config.Config
+	tw       *tar.Writer
+	now      time.Time
+	manifest supportManifest
+}
+
+func newSupportBundle(cfg config.Config, tw *tar.Writer, now time.Time) *supportBundle {
+	return &supportBundle{cfg: cfg, tw: tw, now: now, manifest: supportManifest{
+		GeneratedAt: now.UTC(),
+		LogWindow:   (time.Duration(cfg.SupportLogWindowSeconds) * time.Second).String(),
+		LogMaxBytes: cfg.SupportLogMaxBytes,
+		Files:       []supportFile{},
+		Excluded:    []supportExcluded{},
+	}}
+}
+
+func (b *supportBundle) exclude(source, reason string) {
+	b.manifest.Excluded = append(b.manifest.Excluded, supportExcluded{Source: source, Reason: reason})
+}
+
+// add redacts r line by line and writes it as name. maxBytes > 0 keeps only
+// the tail of the content (whole lines).
+func (b *supportBundle) add(name, source string, r io.Reader, maxBytes int64) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var sb strings.Builder
+	redactions := 0
+	for sc.Scan() {
+		line, n := redactLineCount(sc.Text())
+		redactions += n
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	data := sb.String()
+	truncated := false
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		data = data[int64(len(data))-maxBytes:]
+		if i := strings.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+		truncated = true
+	}
+	if b.manifest.TotalBytes+int64(len(data)) > supportBundleMaxBytes {
+		b.exclude(source, "bundle size limit reached")
+		return
+	}
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: b.now}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return
+	}
+	if _, err := io.WriteString(b.tw, data); err != nil {
+		return
+	}
+	b.manifest.TotalBytes += int64(len(data))
+	b.manifest.Files = append(b.manifest.Files, supportFile{Name: name, Source: source, Bytes: len(data), Redactions: redactions, Truncated: truncated})
+}
+
+func (b *supportBundle) addFile(hostPath, name string) {
+	b.addFileLimit(hostPath, name, 0)
+}
+
+// addFileLimit copies hostPath unless it is sensitive. For maxBytes > 0
+// only the last maxBytes of the file are read.
+func (b *supportBundle) addFileLimit(hostPath, name string, maxBytes int64) {
+	if supportSensitive(b.cfg, hostPath) {
+		if _, err := os.Stat(hostPath); err == nil {
+			b.exclude(hostPath, "contains secrets")
+		}
+		return
+	}
 	f, err := os.Open(hostPath)
 	if err != nil {
 		return
 	}
 	defer f.Close()
-	_ = writeTarFile(tw, name, f)
+	var r io.Reader = f
+	if fi, err := f.Stat(); err == nil && maxBytes > 0 && fi.Size() > maxBytes {
+		if _, err := f.Seek(-maxBytes, io.SeekEnd); err == nil {
+			br := bufio.NewReader(f)
+			_, _ = br.ReadString('\n') // drop the partial first line
+			r = br
+		}
+	}
+	b.add(name, hostPath, r, maxBytes)
+}
+
+func (b *supportBundle) addCmd(name string, cmd string, args ...string) {
+	out, _ := supportCommand(cmd, args...)
+	b.add(name, strings.Join(append([]string{cmd}, args...), " "), strings.NewReader(string(out)), 0)
 }
 
-func writeCmdOutput(tw *tar.Writer, name string, cmd string, args ...string) {
-	c := exec.Command(cmd, args...)
-	out, _ := c.CombinedOutput()
-	_ = writeTarFile(tw, name, strings.NewReader(string(out)))
+// addJournal includes a unit's journal for the configured window, capped
+// to the configured size.
+func (b *supportBundle) addJournal(name, unit string) {
+	since := b.now.Add(-time.Duration(b.cfg.SupportLogWindowSeconds) * time.Second)
+	args := []string{"-u", unit, "--no-pager", "--since", "@" + strconv.FormatInt(since.Unix(), 10)}
+	out, _ := supportCommand("journalctl", args...)
+	b.add(name, "journalctl "+strings.Join(args, " "), strings.NewReader(string(out)), b.cfg.SupportLogMaxBytes)
 }
 
+// finish appends manifest.json.
+func (b *supportBundle) finish() error {
+	data, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := b.tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0600, Size: int64(len(data)), ModTime: b.now}); err != nil {
+		return err
+	}
+	_, err = b.tw.Write(data)
+	return err
+}
+
+// collect gathers diagnostics into the bundle.
+func (b *supportBundle) collect() {
+	cfg := b.cfg
+
+	// Logs, limited to the configured window and size
+	b.addJournal("logs/journal_nosd.txt", "nosd")
+	b.addJournal("logs/journal_nos_agent.txt", "nos-agent")
+	b.addFileLimit("/var/log/caddy/access.log", "logs/caddy_access.log", cfg.SupportLogMaxBytes)
+	b.addFileLimit("/var/log/caddy/error.log", "logs/caddy_error.log", cfg.SupportLogMaxBytes)
+
+	// System info
+	b.addCmd("system/uname.txt", "uname", "-a")
+	b.addFile("/etc/os-release", "system/os-release")
+
+	// Firewall rules
+	b.addCmd("network/nft_ruleset.txt", "nft", "list", "ruleset")
+
+	// Storage
+	b.addCmd("storage/lsblk.json", "lsblk", "-J", "-O")
+	b.addCmd("storage/blkid.txt", "blkid")
+	b.addCmd("storage/btrfs_show.txt", "btrfs", "filesystem", "show")
+	// usage for common mount roots if present
+	for _, m := range []string{"/", "/srv", "/mnt", "/pool", "/data"} {
+		if fi, err := os.Stat(m); err == nil && fi.IsDir() {
+			name := strings.TrimPrefix(m, "/")
+			b.addCmd("storage/usage_"+strings.ReplaceAll(name, "/", "_")+".txt", "btrfs", "fi", "usage", m)
+		}
+	}
+
+	// Config files (redacted; secret stores excluded): /etc/nos/*.{yaml,json}; fstab/crypttab
+	nosDir := filepath.Join(cfg.EtcDir, "nos")
+	var configs []string
+	for _, pat := range []string{"*.yaml", "*.json", "*.key", "otp"} {
+		matches, _ := filepath.Glob(filepath.Join(nosDir, pat))
+		configs = append(configs, matches...)
+	}
+	sort.Strings(configs)
+	for _, p := range configs {
+		b.addFile(p, filepath.Join("configs/nos", filepath.Base(p)))
+	}
+	b.addFile(filepath.Join(cfg.EtcDir, "fstab"), "system/fstab")
+	b.addFile(filepath.Join(cfg.EtcDir, "crypttab"), "system/crypttab")
+
+	// SMART snapshots
+	if matches, _ := filepath.Glob(filepath.Join("/var/lib/nos/health/smart", "*.json")); len(matches) > 0 {
+		for _, p := range matches {
+			b.addFile(p, filepath.Join("health/smart", filepath.Base(p)))
+		}
+	}
+
+	// Pool transactions (last N=10)
+	txDir := filepath.Join("/var/lib/nos", "pools", "tx")
+	if entries, err := os.ReadDir(txDir); err == nil {
+		files := make([]string, 0, len(entries))
+		for _, e := range entries {
+			files = append(files, e.Name())
+		}
+		start := 0
+		if len(files) > 10 {
+			start = len(files) - 10
+		}
+		for _, name := range files[start:] {
+			b.addFile(filepath.Join(txDir, name), filepath.Join("pools/tx", name))
+		}
+	}
+}
+
+// GET /api/v1/support/bundle[?manifest=true]
+// With manifest=true only the manifest is returned as JSON.
 func handleSupportBundle(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if only, _ := strconv.ParseBool(r.URL.Query().Get("manifest")); only {
+			b := newSupportBundle(cfg, tar.NewWriter(io.Discard), time.Now())
+			b.collect()
+			writeJSON(w, b.manifest)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/gzip")
 		w.Header().Set("Content-Disposition", "attachment; filename=nos-support-bundle.tar.gz")
 		gz := gzip.NewWriter(w)
@@ -73,66 +326,9 @@ func handleSupportBundle(cfg config.Config) http.HandlerFunc {
 		tw := tar.NewWriter(gz)
 		defer tw.Close()
 
-		// Journals (last 2000 lines)
-		writeCmdOutput(tw, "logs/journal_nosd.txt", "journalctl", "-u", "nosd", "-n", "2000")
-		writeCmdOutput(tw, "logs/journal_nos_agent.txt", "journalctl", "-u", "nos-agent", "-n", "2000")
-		writeTarFileIfExists(tw, "/var/log/caddy/access.log", "logs/caddy_access.log")
-		writeTarFileIfExists(tw, "/var/log/caddy/error.log", "logs/caddy_error.log")
-
-		// System info
-		writeCmdOutput(tw, "system/uname.txt", "uname", "-a")
-		writeTarFileIfExists(tw, "/etc/os-release", "system/os-release")
-
-		// Firewall rules
-		writeCmdOutput(tw, "network/nft_ruleset.txt", "nft", "list", "ruleset")
-
-		// Storage
-		writeCmdOutput(tw, "storage/lsblk.json", "lsblk", "-J", "-O")
-		writeCmdOutput(tw, "storage/blkid.txt", "blkid")
-		writeCmdOutput(tw, "storage/btrfs_show.txt", "btrfs", "filesystem", "show")
-		// usage for common mount roots if present
-		for _, m := range []string{"/", "/srv", "/mnt", "/pool", "/data"} {
-			if fi, err := os.Stat(m); err == nil && fi.IsDir() {
-				name := strings.TrimPrefix(m, "/")
-				writeCmdOutput(tw, "storage/usage_"+strings.ReplaceAll(name, "/", "_")+".txt", "btrfs", "fi", "usage", m)
-			}
-		}
-
-		// Config files (redacted): /etc/nos/*.yaml, pools.json, schedules.yaml; fstab/crypttab
-		nosDir := filepath.Join(cfg.EtcDir, "nos")
-		if matches, _ := filepath.Glob(filepath.Join(nosDir, "*.yaml")); len(matches) > 0 {
-			for _, p := range matches {
-				name := filepath.Base(p)
-				writeTarFileIfExists(tw, filepath.Join(nosDir, name), filepath.Join("configs/nos", name))
-			}
-		}
-		writeTarFileIfExists(tw, filepath.Join(nosDir, "pools.json"), "configs/nos/pools.json")
-		writeTarFileIfExists(tw, filepath.Join(cfg.EtcDir, "fstab"), "system/fstab")
-		writeTarFileIfExists(tw, filepath.Join(cfg.EtcDir, "crypttab"), "system/crypttab")
-
-		// SMART snapshots
-		if matches, _ := filepath.Glob(filepath.Join("/var/lib/nos/health/smart", "*.json")); len(matches) > 0 {
-			for _, p := range matches {
-				name := filepath.Base(p)
-				writeTarFileIfExists(tw, p, filepath.Join("health/smart", name))
-			}
-		}
-
-		// Pool transactions (last N=10)
-		txDir := filepath.Join("/var/lib/nos", "pools", "tx")
-		if entries, err := os.ReadDir(txDir); err == nil {
-			files := make([]string, 0, len(entries))
-			for _, e := range entries {
-				files = append(files, e.Name())
-			}
-			start := 0
-			if len(files) > 10 {
-				start = len(files) - 10
-			}
-			for _, name := range files[start:] {
-				writeTarFileIfExists(tw, filepath.Join(txDir, name), filepath.Join("pools/tx", name))
-			}
-		}
+		b := newSupportBundle(cfg, tw, time.Now())
+		b.collect()
+		_ = b.finish()
 	}
 }
 
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestRedactLineCount(t *testing.T) {
+	cases := map[string]string{
+		`{"username":"admin","password_hash":"$argon2id$v=19$m=65536,t=3,p=1$c2FsdA$aGFzaA","totp_enc":"abc=="}`: `{"username":"admin","password_hash":"REDACTED","totp_enc":"REDACTED"}`,
+		`smtp_password: "hunter2"`:                                               `smtp_password: REDACTED`,
+		`GET /api/v1/x Authorization: Bearer eyJhbGciOi.x.y`:                     `GET /api/v1/x Authorization: Bearer REDACTED`,
+		`{"request":{"headers":{"Cookie":["nos_session=abc"]}}}`:                 `{"request":{"headers":{"Cookie":["REDACTED"]}}}`,
+		`stored $2b$10$abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0 ok`: `stored REDACTED ok`,
+		`plain line`: `plain line`,
+	}
+	for in, want := range cases {
+		if got, _ := redactLineCount(in); got != want {
+			t.Errorf("redact(%q)\n got %q\nwant %q", in, got, want)
+		}
+	}
+	if _, n := redactLineCount(`{"token":"a","secret":"b"}`); n != 2 {
+		t.Fatalf("expected 2 redactions, got %d", n)
+	}
+}
+
+func TestSupportBundleExcludesSecrets(t *testing.T) {
+	etc := t.TempDir()
+	nos := filepath.Join(etc, "nos")
+	if err := os.MkdirAll(nos, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"users.json":  `[{"username":"admin","password_hash":"$argon2id$v=19$m=65536,t=3,p=1$c2FsdA$aGFzaA"}]`,
+		"secret.key":  "c2Vzc2lvbi1rZXk=",
+		"agents.json": `{"agent-1":{"token":"tok"}}`,
+		"nosd.yaml":   "smtp:\n  host: mail.example.com\n  password: \"hunter2\"\n",
+		"pools.json":  `{"pools":[{"uuid":"u1","mount":"/mnt/p"}]}`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(nos, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Defaults()
+	cfg.EtcDir = etc
+	cfg.UsersPath = filepath.Join(nos, "users.json")
+	cfg.SecretPath = filepath.Join(nos, "secret.key")
+	cfg.SupportLogMaxBytes = 100
+
+	old := supportCommand
+	supportCommand = func(name string, args ...string) ([]byte, error) {
+		if name == "journalctl" {
+			return []byte(strings.Repeat("nosd: request ok\n", 50) + "login Authorization: Bearer abc.def\n"), nil
+		}
+		return nil, nil
+	}
+	t.Cleanup(func() { supportCommand = old })
+
+	res := httptest.NewRecorder()
+	handleSupportBundle(cfg)(res, httptest.NewRequest(http.MethodGet, "/api/v1/support/bundle", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("status %d", res.Code)
+	}
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	contents := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(tr)
+		contents[hdr.Name] = string(b)
+	}
+
+	for _, name := range []string{"configs/nos/users.json", "configs/nos/secret.key", "configs/nos/agents.json"} {
+		if _, ok := contents[name]; ok {
+			t.Errorf("%s must not be in the bundle", name)
+		}
+	}
+	for name, data := range contents {
+		for _, secret := range []string{"hunter2", "$argon2id$", "c2Vzc2lvbi1rZXk=", "abc.def"} {
+			if strings.Contains(data, secret) {
+				t.Errorf("%s leaks %q", name, secret)
+			}
+		}
+	}
+	if !strings.Contains(contents["configs/nos/nosd.yaml"], "host: mail.example.com") {
+		t.Errorf("nosd.yaml missing or over-redacted: %q", contents["configs/nos/nosd.yaml"])
+	}
+	if j := contents["logs/journal_nosd.txt"]; len(j) > 100 || !strings.HasSuffix(j, "Bearer REDACTED\n") {
+		t.Errorf("journal not capped to tail: %d bytes %q", len(j), j)
+	}
+
+	var m supportManifest
+	if err := json.Unmarshal([]byte(contents["manifest.json"]), &m); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	excluded := map[string]bool{}
+	for _, e := range m.Excluded {
+		excluded[filepath.Base(e.Source)] = true
+	}
+	if !excluded["users.json"] || !excluded["secret.key"] || !excluded["agents.json"] {
+		t.Errorf("manifest exclusions: %+v", m.Excluded)
+	}
+	var yamlEntry *supportFile
+	for i, f := range m.Files {
+		if f.Name == "configs/nos/nosd.yaml" {
+			yamlEntry = &m.Files[i]
+		}
+		if f.Name == "logs/journal_nosd.txt" && !f.Truncated {
+			t.Errorf("journal entry should be marked truncated: %+v", f)
+		}
+	}
+	if yamlEntry == nil || yamlEntry.Redactions != 1 {
+		t.Errorf("yaml manifest entry: %+v", yamlEntry)
+	}
+}
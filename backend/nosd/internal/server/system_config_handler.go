@@ -14,6 +14,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/telemetry"
 )
 
 // AgentRequest represents a request to the agent
@@ -25,13 +27,26 @@ type AgentRequest struct {
 type SystemConfigHandler struct {
 	logger      zerolog.Logger
 	agentClient AgentClient
+	// unconfirmed interface changes awaiting auto-revert
+	ifaceGuard *ifaceRollback
+	// seam for tests: current config of an interface (revert target)
+	snapshotIface func(iface string) NetworkConfig
+	// told about consent changes so revocation takes effect at once
+	telemetry *telemetry.Emitter
 }
 
 func NewSystemConfigHandler(logger zerolog.Logger, agentClient AgentClient) *SystemConfigHandler {
-	return &SystemConfigHandler{
+	h := &SystemConfigHandler{
 		logger:      logger.With().Str("component", "system-config").Logger(),
 		agentClient: agentClient,
 	}
+	h.snapshotIface = h.liveInterfaceConfig
+	pendingPath := "/var/lib/nos/network-pending.json"
+	if v := os.Getenv("NOS_NET_PENDING_PATH"); v != "" {
+		pendingPath = v
+	}
+	h.ifaceGuard = newIfaceRollback(pendingPath, h.logger, h.applyInterface)
+	return h
 }
 
 func (h *SystemConfigHandler) Routes() chi.Router {
@@ -54,6 +69,8 @@ func (h *SystemConfigHandler) Routes() chi.Router {
 	r.Get("/network/interfaces", h.ListInterfaces)
 	r.Get("/network/interfaces/{iface}", h.GetInterface)
 	r.Post("/network/interfaces/{iface}", h.ConfigureInterface)
+	r.Post("/network/interfaces/{iface}/confirm", h.ConfirmInterface)
+	r.Post("/network/dns", h.SetDNS)
 
 	// Telemetry consent
 	r.Get("/telemetry/consent", h.GetTelemetryConsent)
@@ -66,6 +83,7 @@ func (h *SystemConfigHandler) Routes() chi.Router {
 
 type HostnameConfig struct {
 	Hostname       string `json:"hostname"`
+	Domain         string `json:"domain,omitempty"`
 	PrettyHostname string `json:"pretty_hostname,omitempty"`
 }
 
@@ -103,30 +121,37 @@ func (h *SystemConfigHandler) SetHostname(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Validate hostname (RFC 1123)
-	if !isValidHostname(config.Hostname) {
-		respondError(w, http.StatusBadRequest, "Invalid hostname format")
+	// Validate hostname (RFC 1123); "nas.example.lan" is taken as host + domain
+	short, domain, err := splitHostname(config.Hostname, config.Domain)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	fqdn := short
+	if domain != "" {
+		fqdn = short + "." + domain
+	}
 
-	// Use agent to set hostname (privileged operation). In tests, allow bypass.
+	// Use agent to set hostname and the /etc/hosts entry (privileged
+	// operation). In tests, allow bypass.
 	if os.Getenv("NOS_TEST_BYPASS_AGENT") != "1" {
 		req := AgentRequest{
 			Action: "system.hostname.set",
 			Params: map[string]interface{}{
-				"hostname":        config.Hostname,
+				"hostname":        short,
+				"domain":          domain,
 				"pretty_hostname": config.PrettyHostname,
 			},
 		}
 		var resp interface{}
-		if err := h.agentClient.PostJSON(context.Background(), "/execute", req, &resp); err != nil {
+		if err := h.agentClient.PostJSON(r.Context(), "/execute", req, &resp); err != nil {
 			h.logger.Error().Err(err).Msg("Failed to set hostname")
 			respondError(w, http.StatusInternalServerError, "Failed to set hostname")
 			return
 		}
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	respondJSON(w, http.StatusOK, map[string]string{"status": "ok", "hostname": short, "fqdn": fqdn})
 }
 
 // Timezone management
@@ -242,6 +267,13 @@ type NTPConfig struct {
 	Enabled bool     `json:"enabled"`
 	Servers []string `json:"servers"`
 	Status  string   `json:"status"`
+	// Sync state reported by GET
+	Synchronized bool       `json:"synchronized"`
+	Server       string     `json:"server,omitempty"`
+	OffsetMs     *float64   `json:"offset_ms,omitempty"`
+	LastSync     *time.Time `json:"last_sync,omitempty"`
+	// Verify asks SetNTP to probe each server with an SNTP query
+	Verify bool `json:"verify,omitempty"`
 }
 
 func (h *SystemConfigHandler) GetNTP(w http.ResponseWriter, r *http.Request) {
@@ -272,10 +304,19 @@ func (h *SystemConfigHandler) GetNTP(w http.ResponseWriter, r *http.Request) {
 	if output, err := exec.Command("timedatectl", "show", "--value", "-p", "NTPSynchronized").Output(); err == nil {
 		if strings.TrimSpace(string(output)) == "yes" {
 			config.Status = "synchronized"
+			config.Synchronized = true
 		} else {
 			config.Status = "not_synchronized"
 		}
 	}
+	if output, err := exec.Command("timedatectl", "timesync-status").Output(); err == nil {
+		if server, offset, ok := parseTimesyncStatus(string(output)); ok {
+			ms := float64(offset) / float64(time.Millisecond)
+			config.Server = server
+			config.OffsetMs = &ms
+		}
+	}
+	config.LastSync = lastTimesync()
 
 	respondJSON(w, http.StatusOK, config)
 }
@@ -286,6 +327,33 @@ func (h *SystemConfigHandler) SetNTP(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	servers := make([]string, 0, len(config.Servers))
+	invalid := []string{}
+	for _, s := range config.Servers {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !validNTPServer(s) {
+			invalid = append(invalid, s)
+			continue
+		}
+		servers = append(servers, s)
+	}
+	if len(invalid) > 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "Invalid NTP server: " + strings.Join(invalid, ", "),
+			"invalid": invalid,
+		})
+		return
+	}
+	config.Servers = servers
+
+	// Reachability is advisory: report failures but save anyway
+	unreachable := []ntpProbeResult{}
+	if config.Verify && len(servers) > 0 {
+		unreachable = probeNTPServers(r.Context(), servers)
+	}
 
 	// Use agent to configure NTP; bypass in tests
 	if os.Getenv("NOS_TEST_BYPASS_AGENT") != "1" {
@@ -304,7 +372,11 @@ func (h *SystemConfigHandler) SetNTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	out := map[string]interface{}{"status": "ok"}
+	if config.Verify {
+		out["unreachable"] = unreachable
+	}
+	respondJSON(w, http.StatusOK, out)
 }
 
 // Network interface management
@@ -327,6 +399,9 @@ type NetworkConfig struct {
 	IPv4Address string   `json:"ipv4_address,omitempty"`
 	IPv4Gateway string   `json:"ipv4_gateway,omitempty"`
 	DNS         []string `json:"dns,omitempty"`
+	// Addresses lists further static addresses in CIDR form (IPv6 or
+	// secondary IPv4) applied alongside IPv4Address.
+	Addresses []string `json:"addresses,omitempty"`
 }
 
 func (h *SystemConfigHandler) ListInterfaces(w http.ResponseWriter, r *http.Request) {
@@ -433,14 +508,22 @@ func (h *SystemConfigHandler) GetInterface(w http.ResponseWriter, r *http.Reques
 	respondJSON(w, http.StatusOK, ni)
 }
 
+// ConfigureInterface applies an interface configuration and arms an
+// auto-revert: unless POST .../{iface}/confirm arrives within
+// confirm_timeout_seconds (default 60), the previous configuration is
+// restored so a bad static address can't lock the admin out.
 func (h *SystemConfigHandler) ConfigureInterface(w http.ResponseWriter, r *http.Request) {
 	ifaceName := chi.URLParam(r, "iface")
 
-	var config NetworkConfig
-	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+	var body struct {
+		NetworkConfig
+		ConfirmTimeoutSeconds int `json:"confirm_timeout_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	config := body.NetworkConfig
 
 	// Validate interface exists
 	if _, err := net.InterfaceByName(ifaceName); err != nil {
@@ -463,29 +546,71 @@ func (h *SystemConfigHandler) ConfigureInterface(w http.ResponseWriter, r *http.
 		}
 	}
 
-	// Use agent to configure interface; bypass in tests
-	if os.Getenv("NOS_TEST_BYPASS_AGENT") != "1" {
-		req := AgentRequest{
-			Action: "network.interface.configure",
-			Params: map[string]interface{}{
-				"interface":    ifaceName,
-				"dhcp":         config.DHCP,
-				"ipv4_address": config.IPv4Address,
-				"ipv4_gateway": config.IPv4Gateway,
-				"dns":          config.DNS,
-			},
-		}
-		var resp interface{}
-		if err := h.agentClient.PostJSON(context.Background(), "/execute", req, &resp); err != nil {
-			h.logger.Error().Err(err).Msg("Failed to configure interface")
-			respondError(w, http.StatusInternalServerError, "Failed to configure interface")
+	timeout := defaultIfaceConfirmTimeout
+	if body.ConfirmTimeoutSeconds != 0 {
+		timeout = time.Duration(body.ConfirmTimeoutSeconds) * time.Second
+		if timeout < minIfaceConfirmTimeout || timeout > maxIfaceConfirmTimeout {
+			respondError(w, http.StatusBadRequest, "confirm_timeout_seconds must be between 10 and 600")
 			return
 		}
 	}
 
+	change := pendingIfaceChange{
+		Iface:      ifaceName,
+		Previous:   h.snapshotIface(ifaceName),
+		Applied:    config,
+		RollbackAt: time.Now().Add(timeout).UTC(),
+	}
+	guard := activeIfaceGuard(h.ifaceGuard)
+	if err := guard.begin(change); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err := h.applyInterface(r.Context(), ifaceName, config); err != nil {
+		guard.abandon(ifaceName)
+		h.logger.Error().Err(err).Msg("Failed to configure interface")
+		respondError(w, http.StatusInternalServerError, "Failed to configure interface")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":      "pending_confirm",
+		"rollback_at": change.RollbackAt,
+		"previous":    change.Previous,
+	})
+}
+
+// ConfirmInterface keeps a pending interface change.
+func (h *SystemConfigHandler) ConfirmInterface(w http.ResponseWriter, r *http.Request) {
+	ifaceName := chi.URLParam(r, "iface")
+	if !activeIfaceGuard(h.ifaceGuard).confirm(ifaceName) {
+		respondError(w, http.StatusNotFound, "No pending change for interface")
+		return
+	}
+	h.logger.Info().Str("event", "network.interface.confirmed").Str("iface", ifaceName).Msg("")
 	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// applyInterface sends an interface configuration to the agent; bypassed in tests.
+func (h *SystemConfigHandler) applyInterface(ctx context.Context, ifaceName string, config NetworkConfig) error {
+	if os.Getenv("NOS_TEST_BYPASS_AGENT") == "1" {
+		return nil
+	}
+	req := AgentRequest{
+		Action: "network.interface.configure",
+		Params: map[string]interface{}{
+			"interface":    ifaceName,
+			"dhcp":         config.DHCP,
+			"ipv4_address": config.IPv4Address,
+			"ipv4_gateway": config.IPv4Gateway,
+			"dns":          config.DNS,
+			"addresses":    config.Addresses,
+		},
+	}
+	var resp interface{}
+	return h.agentClient.PostJSON(ctx, "/execute", req, &resp)
+}
+
 // Telemetry consent
 
 type TelemetryConsent struct {
@@ -496,17 +621,7 @@ type TelemetryConsent struct {
 }
 
 func (h *SystemConfigHandler) GetTelemetryConsent(w http.ResponseWriter, r *http.Request) {
-	consent := TelemetryConsent{
-		Enabled: false,
-	}
-
-	// Read consent from file
-	consentPath := "/etc/nos/telemetry/consent.json"
-	if data, err := os.ReadFile(consentPath); err == nil {
-		_ = json.Unmarshal(data, &consent)
-	}
-
-	respondJSON(w, http.StatusOK, consent)
+	respondJSON(w, http.StatusOK, readTelemetryConsent())
 }
 
 func (h *SystemConfigHandler) SetTelemetryConsent(w http.ResponseWriter, r *http.Request) {
@@ -531,7 +646,7 @@ func (h *SystemConfigHandler) SetTelemetryConsent(w http.ResponseWriter, r *http
 	}
 
 	// Save consent
-	consentPath := "/etc/nos/telemetry/consent.json"
+	consentPath := filepath.Join(telemetryDir(), "consent.json")
 	_ = os.MkdirAll(filepath.Dir(consentPath), 0700)
 
 	data, _ := json.MarshalIndent(consent, "", "  ")
@@ -541,6 +656,14 @@ func (h *SystemConfigHandler) SetTelemetryConsent(w http.ResponseWriter, r *http
 		return
 	}
 
+	if h.telemetry != nil {
+		h.telemetry.SetConsent(consent.Enabled)
+	}
+	if !consent.Enabled {
+		// a later opt-in starts over as a new, unlinkable install
+		_ = os.Remove(filepath.Join(telemetryDir(), "install-id"))
+	}
+
 	// Enable/disable telemetry service
 	action := "stop"
 	if consent.Enabled {
@@ -563,21 +686,53 @@ func isValidHostname(hostname string) bool {
 
 	// RFC 1123 validation
 	for _, part := range strings.Split(hostname, ".") {
-		if len(part) == 0 || len(part) > 63 {
+		if !isValidHostLabel(part) {
 			return false
 		}
+	}
 
-		for i, ch := range part {
-			if !((ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
-				(ch >= '0' && ch <= '9') || (i > 0 && i < len(part)-1 && ch == '-')) {
-				return false
-			}
+	return true
+}
+
+// isValidHostLabel checks a single RFC 1123 label: 1-63 ASCII letters,
+// digits and inner hyphens.
+func isValidHostLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		ch := label[i]
+		if !((ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
+			(ch >= '0' && ch <= '9') || (i > 0 && i < len(label)-1 && ch == '-')) {
+			return false
 		}
 	}
-
 	return true
 }
 
+// splitHostname validates a hostname and optional domain and returns the
+// short name and domain. A dotted hostname without a domain is split at the
+// first dot.
+func splitHostname(hostname, domain string) (string, string, error) {
+	hostname = strings.TrimSuffix(strings.TrimSpace(hostname), ".")
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if domain == "" {
+		if i := strings.IndexByte(hostname, '.'); i >= 0 {
+			hostname, domain = hostname[:i], hostname[i+1:]
+		}
+	}
+	if !isValidHostLabel(hostname) {
+		return "", "", fmt.Errorf("invalid hostname: use 1-63 letters, digits or hyphens, not starting or ending with a hyphen")
+	}
+	if domain != "" && !isValidHostname(domain) {
+		return "", "", fmt.Errorf("invalid domain format")
+	}
+	if len(hostname)+1+len(domain) > 253 {
+		return "", "", fmt.Errorf("fully qualified hostname exceeds 253 characters")
+	}
+	return hostname, domain, nil
+}
+
 func (h *SystemConfigHandler) isDHCP(ifaceName string) bool {
 	// Check systemd-networkd configuration
 	networkdPath := fmt.Sprintf("/etc/systemd/network/10-%s.network", ifaceName)
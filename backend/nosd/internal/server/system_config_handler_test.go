@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type recordingAgent struct {
+	posts []any
+}
+
+func (a *recordingAgent) GetJSON(ctx context.Context, path string, out interface{}) error {
+	return nil
+}
+
+func (a *recordingAgent) PostJSON(ctx context.Context, path string, body interface{}, out interface{}) error {
+	a.posts = append(a.posts, body)
+	return nil
+}
+
+func TestSplitHostname(t *testing.T) {
+	cases := []struct {
+		host, domain      string
+		wantHost, wantDom string
+	}{
+		{"nas", "", "nas", ""},
+		{"nas", "home.lan", "nas", "home.lan"},
+		{"nas.home.lan", "", "nas", "home.lan"},
+		{"NAS-01", "example.com.", "NAS-01", "example.com"},
+	}
+	for _, c := range cases {
+		h, d, err := splitHostname(c.host, c.domain)
+		if err != nil || h != c.wantHost || d != c.wantDom {
+			t.Errorf("splitHostname(%q, %q) = %q, %q, %v", c.host, c.domain, h, d, err)
+		}
+	}
+	long := strings.Repeat("a", 63)
+	for _, bad := range [][2]string{
+		{"", ""},
+		{"-nas", ""},
+		{"nas-", ""},
+		{"nas_01", ""},
+		{"nas box", ""},
+		{strings.Repeat("a", 64), ""},
+		{"nas", "bad..lan"},
+		{"nas", "-x.lan"},
+		{"nas", strings.Join([]string{long, long, long, long}, ".")},
+	} {
+		if _, _, err := splitHostname(bad[0], bad[1]); err == nil {
+			t.Errorf("splitHostname(%q, %q): expected error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestSetHostnameValidation(t *testing.T) {
+	agent := &recordingAgent{}
+	h := NewSystemConfigHandler(zerolog.Nop(), agent)
+
+	res := httptest.NewRecorder()
+	h.SetHostname(res, httptest.NewRequest(http.MethodPost, "/api/v1/system/hostname", strings.NewReader(`{"hostname":"bad_name"}`)))
+	if res.Code != http.StatusBadRequest || len(agent.posts) != 0 {
+		t.Fatalf("invalid hostname: %d, agent calls %d", res.Code, len(agent.posts))
+	}
+
+	res = httptest.NewRecorder()
+	h.SetHostname(res, httptest.NewRequest(http.MethodPost, "/api/v1/system/hostname", strings.NewReader(`{"hostname":"nas.home.lan"}`)))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"fqdn":"nas.home.lan"`) {
+		t.Fatalf("fqdn: %d %s", res.Code, res.Body.String())
+	}
+	if len(agent.posts) != 1 {
+		t.Fatalf("expected one agent call, got %d", len(agent.posts))
+	}
+	params := agent.posts[0].(AgentRequest).Params
+	if params["hostname"] != "nas" || params["domain"] != "home.lan" {
+		t.Fatalf("agent params: %v", params)
+	}
+}
+
+func TestSetNTPRejectsInvalidServers(t *testing.T) {
+	agent := &recordingAgent{}
+	h := NewSystemConfigHandler(zerolog.Nop(), agent)
+	res := httptest.NewRecorder()
+	body := `{"enabled":true,"servers":["pool.ntp.org","time..example.com","10.0.0.1","ntp server"]}`
+	h.SetNTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/system/ntp", strings.NewReader(body)))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", res.Code)
+	}
+	var out struct {
+		Invalid []string `json:"invalid"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if len(out.Invalid) != 2 || out.Invalid[0] != "time..example.com" || out.Invalid[1] != "ntp server" {
+		t.Fatalf("invalid list: %v", out.Invalid)
+	}
+	if len(agent.posts) != 0 {
+		t.Fatal("invalid config must not reach the agent")
+	}
+}
+
+func TestSetNTPReportsUnreachableWithoutBlocking(t *testing.T) {
+	ntpProbe = func(ctx context.Context, server string) (time.Duration, error) {
+		if server == "dead.example.com" {
+			return 0, errors.New("i/o timeout")
+		}
+		return 3 * time.Millisecond, nil
+	}
+	t.Cleanup(func() { ntpProbe = sntpQuery })
+
+	agent := &recordingAgent{}
+	h := NewSystemConfigHandler(zerolog.Nop(), agent)
+	res := httptest.NewRecorder()
+	body := `{"enabled":true,"verify":true,"servers":["0.pool.ntp.org","dead.example.com"]}`
+	h.SetNTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/system/ntp", strings.NewReader(body)))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", res.Code, res.Body.String())
+	}
+	var out struct {
+		Unreachable []ntpProbeResult `json:"unreachable"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if len(out.Unreachable) != 1 || out.Unreachable[0].Server != "dead.example.com" {
+		t.Fatalf("unreachable: %+v", out.Unreachable)
+	}
+	if len(agent.posts) != 1 {
+		t.Fatal("config should still be saved")
+	}
+}
+
+func TestParseTimesyncStatus(t *testing.T) {
+	out := `       Server: 192.168.1.1 (pool.ntp.org)
+Poll interval: 34min 8s (min: 32s; max 34min 8s)
+         Leap: normal
+       Offset: -253us
+        Delay: 518us
+`
+	server, offset, ok := parseTimesyncStatus(out)
+	if !ok || server != "192.168.1.1" || offset != -253*time.Microsecond {
+		t.Fatalf("got %q %v %v", server, offset, ok)
+	}
+	if _, _, ok := parseTimesyncStatus("No server"); ok {
+		t.Fatal("expected no offset")
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// maxPowerDelaySeconds matches the agent's scheduling limit (24h).
+const maxPowerDelaySeconds = 24 * 60 * 60
+
+// POST /api/v1/system/reboot and /api/v1/system/shutdown
+//
+// Requires the "Confirm: yes" header. The optional JSON body
+// {"delay_seconds": N} postpones the action; the agent schedules it with a
+// systemd timer so this reply is delivered first.
+func handleSystemPower(cfg config.Config, action string) http.HandlerFunc {
+	agentAction, event := "system.power.reboot", "system.reboot"
+	if action == "shutdown" {
+		agentAction, event = "system.power.poweroff", "system.shutdown"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Confirm") != "yes" {
+			httpx.WriteError(w, http.StatusPreconditionRequired, "confirm header required")
+			return
+		}
+		var body struct {
+			DelaySeconds int `json:"delay_seconds"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "invalid json")
+				return
+			}
+		}
+		if body.DelaySeconds < 0 || body.DelaySeconds > maxPowerDelaySeconds {
+			httpx.WriteError(w, http.StatusBadRequest, "delay_seconds must be between 0 and 86400")
+			return
+		}
+		req := map[string]any{"Action": agentAction, "Params": map[string]any{"delay_seconds": body.DelaySeconds}}
+		if err := agentclient.New(cfg.AgentSocket()).PostJSON(r.Context(), "/execute", req, nil); err != nil {
+			writeAgentError(w, err, "system."+action+"_failed", "Scheduling "+action+" failed")
+			return
+		}
+		scheduled := time.Now().UTC().Add(time.Duration(body.DelaySeconds) * time.Second)
+		Logger(cfg).Warn().Str("event", event).Str("userId", sessionUID(r)).Str("ip", clientIP(r, cfg)).Int("delay_seconds", body.DelaySeconds).Time("scheduled_at", scheduled).Msg("")
+		writeJSON(w, map[string]any{"ok": true, "action": action, "scheduled_at": scheduled.Format(time.RFC3339)})
+	}
+}
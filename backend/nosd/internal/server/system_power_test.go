@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestSystemPowerRequiresConfirm(t *testing.T) {
+	healthTestEnv(t)
+	sock, seen := fakeAgentSocket(t, nil)
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	r := NewRouter(config.FromEnv())
+
+	for _, path := range []string{"/api/v1/system/reboot", "/api/v1/system/shutdown"} {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, path, nil))
+		if res.Code != http.StatusPreconditionRequired {
+			t.Fatalf("%s without confirm: %d %s", path, res.Code, res.Body.String())
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/system/reboot", strings.NewReader(`{"delay_seconds":-1}`))
+	req.Header.Set("Confirm", "yes")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("negative delay: %d", res.Code)
+	}
+	if n := len(seen()); n != 0 {
+		t.Fatalf("agent called %d times without a valid request", n)
+	}
+}
+
+func TestSystemPowerDispatchesToAgent(t *testing.T) {
+	healthTestEnv(t)
+	var mu sync.Mutex
+	var got []map[string]any
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		got = append(got, body)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	r := NewRouter(config.FromEnv())
+
+	post := func(path, body string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Confirm", "yes")
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, res.Code, res.Body.String())
+		}
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return out
+	}
+
+	before := time.Now().UTC()
+	out := post("/api/v1/system/reboot", `{"delay_seconds":300}`)
+	at, err := time.Parse(time.RFC3339, out["scheduled_at"].(string))
+	if err != nil || at.Before(before.Add(299*time.Second)) {
+		t.Fatalf("scheduled_at: %v %v", out["scheduled_at"], err)
+	}
+	post("/api/v1/system/shutdown", "")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 agent calls, got %d", len(got))
+	}
+	if got[0]["Action"] != "system.power.reboot" || got[0]["Params"].(map[string]any)["delay_seconds"] != float64(300) {
+		t.Fatalf("reboot dispatch: %v", got[0])
+	}
+	if got[1]["Action"] != "system.power.poweroff" {
+		t.Fatalf("shutdown dispatch: %v", got[1])
+	}
+}
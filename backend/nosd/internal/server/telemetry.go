@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/telemetry"
+)
+
+// telemetryDir holds consent.json and the random install ID.
+func telemetryDir() string {
+	if v := os.Getenv("NOS_TELEMETRY_DIR"); v != "" {
+		return v
+	}
+	return "/etc/nos/telemetry"
+}
+
+func readTelemetryConsent() TelemetryConsent {
+	var c TelemetryConsent
+	if data, err := os.ReadFile(filepath.Join(telemetryDir(), "consent.json")); err == nil {
+		_ = json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+// telemetryInstallID returns the random install ID, creating it only when
+// create is set so a preview without consent leaves nothing behind.
+func telemetryInstallID(create bool) string {
+	p := filepath.Join(telemetryDir(), "install-id")
+	if b, err := os.ReadFile(p); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	if !create {
+		return ""
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	id := hex.EncodeToString(b[:])
+	_ = os.MkdirAll(filepath.Dir(p), 0o700)
+	_ = os.WriteFile(p, []byte(id+"\n"), 0o600)
+	return id
+}
+
+// newTelemetryEmitter wires the emitter to the stored consent. Reports
+// carry the version, platform and a few counts; see Report.
+func newTelemetryEmitter(cfg config.Config) *telemetry.Emitter {
+	var em *telemetry.Emitter
+	em = telemetry.New(cfg.TelemetryURL, readTelemetryConsent().Enabled, func(ctx context.Context) telemetry.Report {
+		counts := map[string]int{}
+		if us, err := userstore.New(cfg.UsersPath); err == nil {
+			list, _ := us.List()
+			counts["users"] = len(list)
+		}
+		if agents, err := loadAgents(); err == nil {
+			counts["agents"] = len(agents)
+		}
+		return telemetry.Report{
+			InstallID:   telemetryInstallID(em.Enabled()),
+			Version:     nosVersion,
+			OS:          runtime.GOOS,
+			Arch:        runtime.GOARCH,
+			Counts:      counts,
+			CollectedAt: time.Now().UTC().Truncate(time.Hour),
+		}
+	})
+	return em
+}
+
+// GET /api/v1/telemetry/preview shows exactly what the next report would
+// contain and what is queued, whether or not consent is granted.
+func handleTelemetryPreview(em *telemetry.Emitter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"enabled":     em.Enabled(),
+			"endpoint":    em.Endpoint,
+			"intervalSec": int(em.Interval.Seconds()),
+			"batchSize":   em.BatchSize,
+			"report":      em.Preview(r.Context()),
+			"pending":     em.Pending(),
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+type telemetryPreview struct {
+	Enabled bool `json:"enabled"`
+	Report  struct {
+		InstallID string         `json:"install_id"`
+		Version   string         `json:"version"`
+		Counts    map[string]int `json:"counts"`
+	} `json:"report"`
+}
+
+func TestTelemetryPreviewFollowsConsent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "telemetry")
+	t.Setenv("NOS_TELEMETRY_DIR", dir)
+	t.Setenv("NOS_TEST_BYPASS_AGENT", "1")
+	h, ck := bindingTestSession(t, config.SessionBindingOff)
+
+	preview := func() telemetryPreview {
+		t.Helper()
+		res := bindingRequest(h, ck, http.MethodGet, "/api/v1/telemetry/preview", "")
+		if res.Code != http.StatusOK {
+			t.Fatalf("preview: %d %s", res.Code, res.Body.String())
+		}
+		var p telemetryPreview
+		if err := json.Unmarshal(res.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	setConsent := func(on bool) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/telemetry/consent", strings.NewReader(fmt.Sprintf(`{"enabled":%t}`, on)))
+		req.AddCookie(ck)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("consent %t: %d %s", on, res.Code, res.Body.String())
+		}
+	}
+
+	p := preview()
+	if p.Enabled || p.Report.Version != nosVersion || p.Report.Counts["users"] != 1 || p.Report.InstallID != "" {
+		t.Fatalf("preview without consent: %+v", p)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "install-id")); !os.IsNotExist(err) {
+		t.Fatalf("install id written without consent: %v", err)
+	}
+
+	setConsent(true)
+	if p = preview(); !p.Enabled || p.Report.InstallID == "" {
+		t.Fatalf("preview with consent: %+v", p)
+	}
+	setConsent(false)
+	if p = preview(); p.Enabled {
+		t.Fatalf("revoke not applied to the running emitter")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "install-id")); !os.IsNotExist(err) {
+		t.Fatalf("install id kept after revoke: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/ratelimit"
+	"nithronos/backend/nosd/pkg/auth"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// TOTP state lives on userstore.User only: TOTPEnc holds the encrypted
+// secret and TOTPEnabled flips once a code has been confirmed. The
+// signed-in enroll/verify routes and the password-based setup/confirm pair
+// below all read and write those two fields; nothing else stores 2FA state.
+
+// totpSetupRequest identifies the account for /auth/totp/setup and
+// /auth/totp/confirm. Email is the field older clients send; users are
+// looked up by username, so it is accepted as an alias.
+type totpSetupRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (b totpSetupRequest) login() string {
+	if s := strings.TrimSpace(b.Username); s != "" {
+		return s
+	}
+	return strings.TrimSpace(b.Email)
+}
+
+// checkPassword verifies pass against a stored hash, including the dev:
+// and plain: prefixes used by development fixtures.
+func checkPassword(ph, pass string) bool {
+	if strings.HasPrefix(ph, "dev:") || strings.HasPrefix(ph, "plain:") {
+		return strings.TrimPrefix(strings.TrimPrefix(ph, "dev:"), "plain:") == pass
+	}
+	return pwhash.VerifyPassword(ph, pass)
+}
+
+// accountLocked reports whether a login lockout is still running for u.
+func accountLocked(u userstore.User) bool {
+	if u.LockedUntil == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, u.LockedUntil)
+	return err == nil && time.Now().Before(t)
+}
+
+// recordLoginFailure counts a wrong password or second factor against u and
+// locks the account for 15 minutes after 10 in a row.
+func recordLoginFailure(users *userstore.Store, u *userstore.User) {
+	u.FailedAttempts++
+	if u.FailedAttempts >= 10 {
+		u.FailedAttempts = 0
+		u.LockedUntil = time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339)
+	}
+	_ = users.UpsertUser(*u)
+}
+
+// allowLogin takes a password attempt for uname from ip out of the login
+// buckets (per IP and per user). When either is spent it answers 429 and
+// returns false; otherwise it reports whichever is closer to its limit.
+func allowLogin(w http.ResponseWriter, cfg config.Config, rl *ratelimit.Store, ip, uname string) bool {
+	lim := RuntimeRateLimits()
+	okIP, remIP, resetIP := rl.Allow("login:ip:"+ip, lim.LoginPer15m, lim.LoginWindow)
+	okUser, remUser, resetUser := rl.Allow("login:user:"+strings.ToLower(uname), lim.LoginPer15m, lim.LoginWindow)
+	if remUser < remIP || (remUser == remIP && resetUser.After(resetIP)) {
+		setRateLimitHeaders(w, lim.LoginPer15m, remUser, resetUser)
+	} else {
+		setRateLimitHeaders(w, lim.LoginPer15m, remIP, resetIP)
+	}
+	if okIP && okUser {
+		return true
+	}
+	retry := resetIP
+	if time.Until(resetUser) > 0 && resetUser.After(retry) {
+		retry = resetUser
+	}
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(retry.Unix(), 10))
+	Logger(cfg).Warn().Str("event", "rate.limited").Str("key", "login").Str("ip", ip).Int("limit", lim.LoginPer15m).Time("resetAt", retry).Msg("")
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(retry).Seconds())))
+	httpx.WriteError(w, http.StatusTooManyRequests, `{"error":{"code":"rate.limited","retryAfterSec":`+strconv.Itoa(int(time.Until(retry).Seconds()))+`}}`)
+	return false
+}
+
+// handleTOTPSetup starts TOTP enrollment for a user who proves their
+// password: a fresh secret is stored encrypted and pending until confirmed.
+// Accounts with TOTP already active get 409 auth.totp_already_enabled; they
+// have to go through the signed-in enroll flow instead. Password attempts
+// share the login rate limits and count toward the login lockout.
+func handleTOTPSetup(cfg config.Config, users *userstore.Store, rl *ratelimit.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body totpSetupRequest
+		if err := decodeStrict(r, &body); err != nil {
+			writeInputError(w, err)
+			return
+		}
+		if !allowLogin(w, cfg, rl, clientIP(r, cfg), body.login()) {
+			return
+		}
+		u, err := users.FindByUsername(body.login())
+		if err != nil || accountLocked(u) {
+			httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.invalid_credentials", "Invalid credentials", 0)
+			return
+		}
+		if !checkPassword(u.PasswordHash, body.Password) {
+			recordLoginFailure(users, &u)
+			httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.invalid_credentials", "Invalid credentials", 0)
+			return
+		}
+		if totpActive(u) {
+			httpx.WriteTypedError(w, http.StatusConflict, "auth.totp_already_enabled", "TOTP is already enabled", 0)
+			return
+		}
+		u.FailedAttempts = 0
+		secret, uri, err := auth.GenerateTOTPSecret("NithronOS", u.Username)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "totp error")
+			return
+		}
+		enc, err := encryptWithSecretKey(cfg.SecretPath, []byte(secret))
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "encrypt error")
+			return
+		}
+		u.TOTPEnc = enc
+		u.TOTPEnabled = false
+		// stale codes would make totpActive treat the pending secret as live
+		u.RecoveryHashes = nil
+		if err := users.UpsertUser(u); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "persist error")
+			return
+		}
+		writeJSON(w, map[string]any{"secret": secret, "otpauth": uri})
+	}
+}
+
+// handleTOTPConfirm activates the secret stored by /auth/totp/setup once the
+// user proves it with a current code, and hands out fresh recovery codes.
+func handleTOTPConfirm(cfg config.Config, users *userstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body totpSetupRequest
+		if err := decodeStrict(r, &body); err != nil {
+			writeInputError(w, err)
+			return
+		}
+		u, err := users.FindByUsername(body.login())
+		if err != nil || u.TOTPEnc == "" || u.TOTPEnc == "pending" {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "auth.totp_not_pending", "No TOTP setup in progress", 0)
+			return
+		}
+		if totpActive(u) {
+			httpx.WriteTypedError(w, http.StatusConflict, "auth.totp_already_enabled", "TOTP is already enabled", 0)
+			return
+		}
+		secret, err := decryptWithSecretKey(cfg.SecretPath, u.TOTPEnc)
+		if err != nil || !auth.VerifyTOTP(string(secret), strings.TrimSpace(body.Code)) {
+			httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.totp_invalid", "Invalid TOTP code", 0)
+			return
+		}
+		plain, hashes := generateRecoveryCodes()
+		u.RecoveryHashes = hashes
+		u.TOTPEnabled = true
+		if err := users.UpsertUser(u); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "persist error")
+			return
+		}
+		Logger(cfg).Info().Str("event", "auth.totp.enabled").Str("userId", u.ID).Msg("")
+		writeJSON(w, map[string]any{"ok": true, "recovery_codes": plain})
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestTOTPSetupConfirmFlow(t *testing.T) {
+	dir := healthTestEnv(t)
+	secretPath := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(secretPath, bytes.Repeat([]byte{7}, 32), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOS_SECRET_PATH", secretPath)
+	t.Setenv("NOS_RATE_LOGIN_PER_15M", "50")
+	cfg := config.FromEnv()
+	phc, err := pwhash.HashPassword("StrongPassw0rd!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	us, _ := userstore.New(cfg.UsersPath)
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := us.UpsertUser(userstore.User{ID: "u1", Username: "alice", PasswordHash: phc, Roles: []string{"admin"}, CreatedAt: now, PasswordChangedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(cfg)
+	post := func(path string, body map[string]any) (*httptest.ResponseRecorder, map[string]any) {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(mustJSON(body))))
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res, out
+	}
+
+	if res, out := post("/api/v1/auth/totp/setup", map[string]any{"username": "alice", "password": "wrong"}); res.Code != http.StatusUnauthorized || errCode(out) != "auth.invalid_credentials" {
+		t.Fatalf("bad password: %d %s", res.Code, res.Body.String())
+	}
+	if res, out := post("/api/v1/auth/totp/confirm", map[string]any{"username": "alice", "code": "123456"}); res.Code != http.StatusBadRequest || errCode(out) != "auth.totp_not_pending" {
+		t.Fatalf("confirm before setup: %d %s", res.Code, res.Body.String())
+	}
+
+	// the legacy email field still identifies the account
+	res, out := post("/api/v1/auth/totp/setup", map[string]any{"email": "alice", "password": "StrongPassw0rd!"})
+	if res.Code != http.StatusOK {
+		t.Fatalf("setup: %d %s", res.Code, res.Body.String())
+	}
+	secret, _ := out["secret"].(string)
+	if secret == "" || out["otpauth"] == "" {
+		t.Fatalf("setup response: %v", out)
+	}
+	// pending until confirmed: password alone still logs in
+	if res, _ := postLogin(r, ""); res.Code != http.StatusOK {
+		t.Fatalf("login while pending: %d %s", res.Code, res.Body.String())
+	}
+
+	if res, out := post("/api/v1/auth/totp/confirm", map[string]any{"username": "alice", "code": "000000"}); res.Code != http.StatusUnauthorized || errCode(out) != "auth.totp_invalid" {
+		t.Fatalf("wrong code: %d %s", res.Code, res.Body.String())
+	}
+	code, _ := totp.GenerateCode(secret, time.Now())
+	res, out = post("/api/v1/auth/totp/confirm", map[string]any{"username": "alice", "code": code})
+	if res.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", res.Code, res.Body.String())
+	}
+	if codes, _ := out["recovery_codes"].([]any); len(codes) == 0 {
+		t.Fatalf("no recovery codes: %v", out)
+	}
+	us, _ = userstore.New(cfg.UsersPath)
+	if u, _ := us.FindByUsername("alice"); !u.TOTPEnabled || len(u.RecoveryHashes) == 0 {
+		t.Fatalf("confirm not persisted: %+v", u)
+	}
+
+	// the live login now needs the second factor
+	if res, out := postLogin(r, ""); res.Code != http.StatusUnauthorized || errCode(out) != "auth.totp_required" {
+		t.Fatalf("login without code: %d %s", res.Code, res.Body.String())
+	}
+	if res, _ := postLogin(r, code); res.Code != http.StatusOK {
+		t.Fatalf("login with code: %d %s", res.Code, res.Body.String())
+	}
+	if res, out := post("/api/v1/auth/totp/setup", map[string]any{"username": "alice", "password": "StrongPassw0rd!"}); res.Code != http.StatusConflict || errCode(out) != "auth.totp_already_enabled" {
+		t.Fatalf("setup when enabled: %d %s", res.Code, res.Body.String())
+	}
+}
+
+// totpSetupLockoutEnv seeds alice with a real password hash and returns the
+// router and users path.
+func totpSetupLockoutEnv(t *testing.T, loginLimit string) (http.Handler, string) {
+	t.Helper()
+	dir := healthTestEnv(t)
+	secretPath := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(secretPath, bytes.Repeat([]byte{7}, 32), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOS_SECRET_PATH", secretPath)
+	t.Setenv("NOS_RATE_LOGIN_PER_15M", loginLimit)
+	cfg := config.FromEnv()
+	phc, err := pwhash.HashPassword("StrongPassw0rd!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	us, _ := userstore.New(cfg.UsersPath)
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := us.UpsertUser(userstore.User{ID: "u1", Username: "alice", PasswordHash: phc, Roles: []string{"admin"}, CreatedAt: now, PasswordChangedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(cfg), cfg.UsersPath
+}
+
+func totpSetupCall(r http.Handler, password string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	body := mustJSON(map[string]any{"username": "alice", "password": password})
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/auth/totp/setup", bytes.NewReader(body)))
+	return res
+}
+
+func TestTOTPSetupWrongPasswordsLockAccount(t *testing.T) {
+	r, usersPath := totpSetupLockoutEnv(t, "50")
+	for i := 0; i < 10; i++ {
+		if res := totpSetupCall(r, "wrong"); res.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: %d %s", i, res.Code, res.Body.String())
+		}
+	}
+	us, _ := userstore.New(usersPath)
+	if u, _ := us.FindByUsername("alice"); !accountLocked(u) {
+		t.Fatalf("10 wrong setup passwords did not lock the account: %+v", u)
+	}
+	// the lockout holds for login, even with the right password
+	if res, _ := postLogin(r, ""); res.Code != http.StatusUnauthorized {
+		t.Fatalf("login while locked: %d", res.Code)
+	}
+}
+
+func TestTOTPSetupSharesLoginRateLimit(t *testing.T) {
+	r, _ := totpSetupLockoutEnv(t, "3")
+	for i := 0; i < 3; i++ {
+		if res := totpSetupCall(r, "wrong"); res.Code != http.StatusUnauthorized || res.Header().Get("X-RateLimit-Limit") != "3" {
+			t.Fatalf("attempt %d: %d %v", i, res.Code, res.Header())
+		}
+	}
+	if res := totpSetupCall(r, "wrong"); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("setup over the login limit: %d", res.Code)
+	}
+	if res, _ := postLogin(r, ""); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("login after setup spent the bucket: %d", res.Code)
+	}
+}
@@ -19,6 +19,8 @@ func handleTxStream(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	ctx, done := streams.track(r.Context())
+	defer done()
 	// start by sending current log from cursor 0
 	f, err := os.Open(txLogPath(id))
 	if err == nil {
@@ -40,11 +42,17 @@ func handleTxStream(w http.ResponseWriter, r *http.Request) {
 		}
 		return nil
 	}()
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
 	for time.Now().Before(deadline) {
 		// keepalive comment
 		_, _ = w.Write([]byte(": keepalive\n\n"))
 		flusher.Flush()
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 		// check for appended data
 		st, err := os.Stat(txLogPath(id))
 		if err == nil && st.Size() > lastSize {
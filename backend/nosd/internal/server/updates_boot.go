@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/agentclient"
+	"nithronos/backend/nosd/pkg/httpx"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+// rebootRequiredPath is created by Debian packages that need a reboot.
+var rebootRequiredPath = "/run/reboot-required"
+
+// currentBootID identifies this boot; it changes on every reboot.
+var currentBootID = func() string {
+	b, _ := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	return strings.TrimSpace(string(b))
+}
+
+var (
+	errNoPendingBoot    = errors.New("no update is waiting for boot confirmation")
+	errBootNotRebooted  = errors.New("the system has not rebooted since the update")
+	bootRollbackTimeout = 10 * time.Minute
+)
+
+// updateNeedsReboot reports whether an apply of pkgs (empty means every
+// upgradable package) only takes effect after a reboot.
+func updateNeedsReboot(pkgs []string) bool {
+	for _, p := range pkgs {
+		if packageNeedsReboot(p) {
+			return true
+		}
+	}
+	_, err := os.Stat(rebootRequiredPath)
+	return err == nil
+}
+
+// bootGuard rolls an update back when the boot after it is not confirmed.
+// An apply that needs a reboot records a snapdb.PendingBoot. When nosd
+// starts in a later boot it gives an admin the configured window to call
+// POST /api/v1/updates/confirm-boot; otherwise the update's OS snapshots
+// are rolled back through the agent and the host rebooted into them.
+type bootGuard struct {
+	cfg    config.Config
+	window time.Duration
+	agent  AgentClient
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+}
+
+func newBootGuard(cfg config.Config, agent AgentClient) *bootGuard {
+	return &bootGuard{cfg: cfg, window: time.Duration(cfg.BootConfirmSeconds) * time.Second, agent: agent}
+}
+
+// startedBootGuard is the guard StartBootGuard armed for this process. The
+// routers' handlers defer to it, so confirm-boot stops the running timer.
+var (
+	startedBootGuardMu sync.Mutex
+	startedBootGuard   *bootGuard
+)
+
+// StartBootGuard arms the rollback timer when an update applied in an
+// earlier boot is waiting for confirmation. main calls it once; building a
+// router doesn't start anything.
+func StartBootGuard(cfg config.Config) {
+	g := newBootGuard(cfg, agentclient.New(cfg.AgentSocket()))
+	startedBootGuardMu.Lock()
+	startedBootGuard = g
+	startedBootGuardMu.Unlock()
+	g.Start()
+}
+
+// activeBootGuard returns the started guard, or g when none was started.
+func activeBootGuard(g *bootGuard) *bootGuard {
+	startedBootGuardMu.Lock()
+	defer startedBootGuardMu.Unlock()
+	if startedBootGuard != nil {
+		return startedBootGuard
+	}
+	return g
+}
+
+// MarkPending records a successful apply that needs a reboot. Without
+// snapshots the agent can roll back there is nothing to roll back to, so
+// nothing is recorded.
+func (g *bootGuard) MarkPending(txID string, targets []snapdb.SnapshotTarget) (bool, error) {
+	if auto, _ := bootRollbackTargets(targets); g.window <= 0 || len(auto) == 0 {
+		return false, nil
+	}
+	err := snapdb.SetPending(snapdb.PendingBoot{TxID: txID, Targets: targets, BootID: currentBootID(), CreatedAt: time.Now().UTC()})
+	return err == nil, err
+}
+
+// Start arms the rollback timer when a pending update was applied in an
+// earlier boot. Before that reboot there is nothing to confirm yet.
+func (g *bootGuard) Start() {
+	p, err := snapdb.GetPending()
+	if err != nil {
+		Logger(g.cfg).Error().Str("event", "updates.boot.pending_read_failed").Err(err).Msg("")
+		return
+	}
+	if p == nil || p.BootID == currentBootID() {
+		return
+	}
+	if g.window <= 0 {
+		// the check was turned off after the update was applied
+		_ = snapdb.ClearPending()
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.deadline = time.Now().Add(g.window).UTC()
+	g.timer = time.AfterFunc(g.window, g.expire)
+	Logger(g.cfg).Warn().Str("event", "updates.boot.awaiting_confirm").Str("tx_id", p.TxID).Time("deadline", g.deadline).Msg("")
+}
+
+// Confirm accepts the current boot and drops the pending rollback.
+func (g *bootGuard) Confirm() (*snapdb.PendingBoot, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, err := snapdb.GetPending()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, errNoPendingBoot
+	}
+	if p.BootID == currentBootID() {
+		return nil, errBootNotRebooted
+	}
+	if err := snapdb.ClearPending(); err != nil {
+		return nil, err
+	}
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	return p, nil
+}
+
+// expire runs when the window passes without a confirmation.
+func (g *bootGuard) expire() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.timer = nil
+	p, err := snapdb.GetPending()
+	if err != nil || p == nil || p.BootID == currentBootID() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bootRollbackTimeout)
+	defer cancel()
+
+	roll := snapdb.UpdateTx{TxID: generateUUID(), StartedAt: time.Now().UTC(), Packages: nil, Reason: "auto-rollback"}
+	if orig, err := snapdb.FindByTx(p.TxID); err == nil {
+		roll.Packages = orig.Packages
+	}
+	// the root snapshot can only be restored from a rescue system, and data
+	// pools are never rolled back unattended
+	targets, manual := bootRollbackTargets(p.Targets)
+	var rerr error
+	if len(targets) == 0 {
+		rerr = errors.New("no snapshot the agent can roll back")
+	}
+	for _, t := range targets {
+		if err := g.agent.PostJSON(ctx, "/v1/snapshot/rollback", map[string]any{"path": t.Path, "snapshot_id": t.ID, "type": t.Type}, nil); err != nil {
+			rerr = errors.New("rollback failed for target " + t.Path + ": " + err.Error())
+			break
+		}
+	}
+	// cleared either way: retrying a half-done rollback on every start could
+	// keep the host rebooting
+	_ = snapdb.ClearPending()
+	now := time.Now().UTC()
+	roll.FinishedAt = &now
+	ok := rerr == nil
+	roll.Success = &ok
+	roll.Notes = joinNotes("boot after "+p.TxID+" not confirmed", manualNotes(manual))
+	if rerr != nil {
+		roll.Notes = joinNotes(rerr.Error(), roll.Notes)
+		_ = snapdb.Append(roll)
+		Logger(g.cfg).Error().Str("event", "updates.boot.rollback_failed").Str("tx_id", p.TxID).Err(rerr).Msg("")
+		return
+	}
+	_ = snapdb.Append(roll)
+	Logger(g.cfg).Warn().Str("event", "updates.boot.rolled_back").Str("tx_id", p.TxID).Str("rollback_tx_id", roll.TxID).Strs("manual_only", manual).Msg("")
+	req := map[string]any{"Action": "system.power.reboot", "Params": map[string]any{"delay_seconds": 0}}
+	if err := g.agent.PostJSON(ctx, "/execute", req, nil); err != nil {
+		Logger(g.cfg).Error().Str("event", "updates.boot.reboot_failed").Err(err).Msg("")
+	}
+}
+
+// Status reports the pending update, if any, and the confirm deadline once
+// the window is running.
+func (g *bootGuard) Status() map[string]any {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, _ := snapdb.GetPending()
+	if p == nil {
+		return map[string]any{"pending": false}
+	}
+	out := map[string]any{"pending": true, "tx_id": p.TxID, "rebooted": p.BootID != currentBootID()}
+	if g.timer != nil {
+		out["deadline"] = g.deadline.Format(time.RFC3339)
+	}
+	return out
+}
+
+// GET /api/v1/updates/boot-status
+func handleBootStatus(g *bootGuard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, activeBootGuard(g).Status())
+	}
+}
+
+// POST /api/v1/updates/confirm-boot
+func handleConfirmBoot(g *bootGuard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g := activeBootGuard(g)
+		p, err := g.Confirm()
+		switch {
+		case errors.Is(err, errNoPendingBoot):
+			httpx.WriteTypedError(w, http.StatusNotFound, "updates.no_pending_boot", "No update is waiting for boot confirmation", 0)
+			return
+		case errors.Is(err, errBootNotRebooted):
+			httpx.WriteTypedError(w, http.StatusConflict, "updates.not_rebooted", "Reboot into the update before confirming it", 0)
+			return
+		case err != nil:
+			httpx.WriteTypedError(w, http.StatusInternalServerError, "updates.confirm_failed", "Failed to confirm the boot", 0)
+			return
+		}
+		Logger(g.cfg).Info().Str("event", "updates.boot.confirmed").Str("tx_id", p.TxID).Str("userId", sessionUID(r)).Msg("")
+		writeJSON(w, map[string]any{"ok": true, "tx_id": p.TxID})
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+// bootAgent records agent calls made from the rollback timer goroutine.
+type bootAgent struct {
+	mu       sync.Mutex
+	paths    []string
+	rolled   []string
+	rebooted chan struct{}
+}
+
+func (a *bootAgent) GetJSON(ctx context.Context, path string, out interface{}) error { return nil }
+
+func (a *bootAgent) PostJSON(ctx context.Context, path string, body interface{}, out interface{}) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.paths = append(a.paths, path)
+	if m, ok := body.(map[string]any); ok && path == "/v1/snapshot/rollback" {
+		a.rolled = append(a.rolled, m["path"].(string))
+	}
+	if path == "/execute" {
+		close(a.rebooted)
+	}
+	return nil
+}
+
+func (a *bootAgent) calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string{}, a.paths...)
+}
+
+// bootGuardEnv records a pending update applied in boot "boot-1" and
+// returns a guard started in boot "boot-2".
+func bootGuardEnv(t *testing.T, window time.Duration) (*bootGuard, *bootAgent) {
+	t.Helper()
+	t.Setenv("NOS_SNAPDB_DIR", t.TempDir())
+	boot := "boot-1"
+	oldBoot := currentBootID
+	currentBootID = func() string { return boot }
+	t.Cleanup(func() { currentBootID = oldBoot })
+
+	agent := &bootAgent{rebooted: make(chan struct{})}
+	g := newBootGuard(config.Config{BootConfirmSeconds: 3600}, agent)
+	_ = snapdb.Append(snapdb.UpdateTx{TxID: "tx1", StartedAt: time.Now().UTC(), Packages: []string{"linux-image-amd64"}})
+	targets := []snapdb.SnapshotTarget{{ID: "s1", Path: "/", Type: "btrfs"}, {ID: "s2", Path: "/var", Type: "btrfs"}}
+	if ok, err := g.MarkPending("tx1", targets); !ok || err != nil {
+		t.Fatalf("mark pending: %v %v", ok, err)
+	}
+	if _, err := g.Confirm(); err != errBootNotRebooted {
+		t.Fatalf("confirm before reboot: %v", err)
+	}
+
+	// the reboot: a new process in a new boot
+	boot = "boot-2"
+	g = newBootGuard(config.Config{BootConfirmSeconds: 3600}, agent)
+	g.window = window
+	g.Start()
+	return g, agent
+}
+
+func TestBootConfirmStopsRollback(t *testing.T) {
+	g, agent := bootGuardEnv(t, time.Hour)
+	if st := g.Status(); st["pending"] != true || st["rebooted"] != true || st["deadline"] == nil {
+		t.Fatalf("status: %v", st)
+	}
+
+	res := httptest.NewRecorder()
+	handleConfirmBoot(g)(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/confirm-boot", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", res.Code, res.Body.String())
+	}
+	if p, _ := snapdb.GetPending(); p != nil {
+		t.Fatalf("pending state kept after confirm: %+v", p)
+	}
+
+	// a timer that fires anyway finds nothing to do
+	g.expire()
+	if calls := agent.calls(); len(calls) != 0 {
+		t.Fatalf("agent called after confirm: %v", calls)
+	}
+	res = httptest.NewRecorder()
+	handleConfirmBoot(g)(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/confirm-boot", nil))
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("second confirm: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestBootNotConfirmedRollsBack(t *testing.T) {
+	_, agent := bootGuardEnv(t, 10*time.Millisecond)
+	select {
+	case <-agent.rebooted:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no rollback after the window: %v", agent.calls())
+	}
+	calls := agent.calls()
+	if len(calls) != 2 || calls[0] != "/v1/snapshot/rollback" || calls[1] != "/execute" {
+		t.Fatalf("agent calls: %v", calls)
+	}
+	// the agent can't replace the running root
+	agent.mu.Lock()
+	rolled := agent.rolled
+	agent.mu.Unlock()
+	if len(rolled) != 1 || rolled[0] != "/var" {
+		t.Fatalf("rolled back %v", rolled)
+	}
+	if p, _ := snapdb.GetPending(); p != nil {
+		t.Fatalf("pending state kept after rollback: %+v", p)
+	}
+	recent, _ := snapdb.ListRecent(1)
+	if len(recent) != 1 || recent[0].Reason != "auto-rollback" || recent[0].Success == nil || !*recent[0].Success || recent[0].Packages[0] != "linux-image-amd64" ||
+		!strings.Contains(recent[0].Notes, "not rolled back /: manual recovery only") {
+		t.Fatalf("rollback not recorded: %+v", recent)
+	}
+}
+
+func TestMarkPendingNeedsSnapshots(t *testing.T) {
+	t.Setenv("NOS_SNAPDB_DIR", t.TempDir())
+	g := newBootGuard(config.Config{BootConfirmSeconds: 3600}, &bootAgent{})
+	if ok, _ := g.MarkPending("tx1", nil); ok {
+		t.Fatalf("recorded a pending boot with nothing to roll back to")
+	}
+	if ok, _ := g.MarkPending("tx1", []snapdb.SnapshotTarget{{ID: "s1", Path: "/", Type: "btrfs"}}); ok {
+		t.Fatalf("recorded a pending boot with only the root snapshot")
+	}
+	if p, _ := snapdb.GetPending(); p != nil {
+		t.Fatalf("pending: %+v", p)
+	}
+}
+
+func TestBootRollbackWithOnlyRootDoesNotReboot(t *testing.T) {
+	t.Setenv("NOS_SNAPDB_DIR", t.TempDir())
+	oldBoot := currentBootID
+	currentBootID = func() string { return "boot-2" }
+	t.Cleanup(func() { currentBootID = oldBoot })
+	// recorded by a version that still counted the root snapshot
+	_ = snapdb.SetPending(snapdb.PendingBoot{TxID: "tx1", Targets: []snapdb.SnapshotTarget{{ID: "s1", Path: "/", Type: "btrfs"}}, BootID: "boot-1"})
+
+	agent := &bootAgent{rebooted: make(chan struct{})}
+	newBootGuard(config.Config{BootConfirmSeconds: 3600}, agent).expire()
+	if calls := agent.calls(); len(calls) != 0 {
+		t.Fatalf("agent calls: %v", calls)
+	}
+	recent, _ := snapdb.ListRecent(1)
+	if len(recent) != 1 || recent[0].Success == nil || *recent[0].Success {
+		t.Fatalf("failed rollback not recorded: %+v", recent)
+	}
+}
+
+func TestBootRollbackNeverTouchesPoolRoots(t *testing.T) {
+	t.Setenv("NOS_SNAPDB_DIR", t.TempDir())
+	oldBoot := currentBootID
+	currentBootID = func() string { return "boot-1" }
+	t.Cleanup(func() { currentBootID = oldBoot })
+
+	agent := &bootAgent{rebooted: make(chan struct{})}
+	g := newBootGuard(config.Config{BootConfirmSeconds: 3600}, agent)
+	// "all" scope: OS subvolumes plus a data pool
+	pool := snapdb.SnapshotTarget{ID: "s3", Path: "/mnt/tank", Type: "btrfs"}
+	if ok, _ := g.MarkPending("tx1", []snapdb.SnapshotTarget{{ID: "s1", Path: "/", Type: "btrfs"}, pool}); ok {
+		t.Fatal("recorded a pending boot whose only rollbackable target is a pool")
+	}
+	if ok, err := g.MarkPending("tx1", []snapdb.SnapshotTarget{{ID: "s1", Path: "/", Type: "btrfs"}, {ID: "s2", Path: "/var", Type: "btrfs"}, pool}); !ok || err != nil {
+		t.Fatalf("mark pending: %v %v", ok, err)
+	}
+
+	currentBootID = func() string { return "boot-2" }
+	g.expire()
+	agent.mu.Lock()
+	rolled := agent.rolled
+	agent.mu.Unlock()
+	if len(rolled) != 1 || rolled[0] != "/var" {
+		t.Fatalf("rolled back %v; pool roots must be left alone", rolled)
+	}
+	recent, _ := snapdb.ListRecent(1)
+	if len(recent) != 1 || !strings.Contains(recent[0].Notes, "not rolled back /mnt/tank") {
+		t.Fatalf("pool not named for manual recovery: %+v", recent)
+	}
+}
+
+func TestBootConfirmIsOptIn(t *testing.T) {
+	if d := config.Defaults().BootConfirmSeconds; d != 0 {
+		t.Fatalf("boot confirm window defaults to %ds", d)
+	}
+}
+
+func TestBootGuardStartsOncePerProcess(t *testing.T) {
+	healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", t.TempDir())
+	oldBoot := currentBootID
+	currentBootID = func() string { return "boot-2" }
+	t.Cleanup(func() {
+		currentBootID = oldBoot
+		startedBootGuardMu.Lock()
+		if startedBootGuard != nil && startedBootGuard.timer != nil {
+			startedBootGuard.timer.Stop()
+		}
+		startedBootGuard = nil
+		startedBootGuardMu.Unlock()
+	})
+	_ = snapdb.SetPending(snapdb.PendingBoot{TxID: "tx1", Targets: []snapdb.SnapshotTarget{{ID: "s2", Path: "/var", Type: "btrfs"}}, BootID: "boot-1"})
+	cfg := config.FromEnv()
+	cfg.BootConfirmSeconds = 3600
+	status := func(h http.Handler) map[string]any {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/updates/boot-status", nil))
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return out
+	}
+
+	// building routers arms nothing
+	h := NewRouter(cfg)
+	_ = NewRouter(cfg)
+	if st := status(h); st["pending"] != true || st["deadline"] != nil {
+		t.Fatalf("router armed the guard: %v", st)
+	}
+	StartBootGuard(cfg)
+	if st := status(h); st["deadline"] == nil {
+		t.Fatalf("started guard not used by the router: %v", st)
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/confirm-boot", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", res.Code, res.Body.String())
+	}
+	startedBootGuardMu.Lock()
+	defer startedBootGuardMu.Unlock()
+	if startedBootGuard.timer != nil {
+		t.Fatal("confirm left the started timer running")
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/httpx"
+)
+
+// aptSourcesPath is the NithronOS apt source; nos-agent owns /etc/apt.
+var aptSourcesPath = "/etc/apt/sources.list.d/nithronos.list"
+
+// updateChannels are the apt suites published on apt.nithronos.com.
+var updateChannels = []string{"stable", "beta"}
+
+var debPackageRe = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]+$`)
+
+func renderAptSources(channel string) string {
+	return "# Managed by NithronOS; change with POST /api/v1/updates/channel\n" +
+		"deb [signed-by=/usr/share/keyrings/nithronos-archive-keyring.gpg] https://apt.nithronos.com " + channel + " main\n"
+}
+
+// GetChannel returns the configured update channel.
+// GET /api/v1/updates/channel
+func (h *UpdatesHandler) GetChannel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{"channel": h.getUpdateChannel(), "channels": updateChannels})
+}
+
+// SetChannel points the NithronOS apt source at another suite through the
+// agent, then saves the channel. The next check with ?refresh=true lists
+// updates from the new suite.
+// POST /api/v1/updates/channel {"channel":"beta"}
+func (h *UpdatesHandler) SetChannel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Channel string `json:"channel"`
+	}
+	if err := decodeStrict(r, &body); err != nil {
+		writeInputError(w, err)
+		return
+	}
+	if !contains(updateChannels, body.Channel) {
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "updates.invalid_channel", "Invalid update channel", map[string]any{"channels": updateChannels})
+		return
+	}
+	write := map[string]any{"path": aptSourcesPath, "content": renderAptSources(body.Channel), "mode": "0644", "owner": "root", "group": "root"}
+	if err := h.agent.PostJSON(r.Context(), "/v1/fs/write", write, nil); err != nil {
+		writeAgentError(w, err, "updates.channel_apply_failed", "Failed to update the apt sources")
+		return
+	}
+	settings := h.loadSettings()
+	prev := settings.Channel
+	settings.Channel = body.Channel
+	if err := h.saveSettings(settings); err != nil {
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "updates.save_failed", "Failed to save settings", 0)
+		return
+	}
+	Logger(h.config).Info().Str("event", "updates.channel.changed").Str("from", prev).Str("to", body.Channel).Str("userId", sessionUID(r)).Msg("")
+	writeJSON(w, map[string]any{"channel": body.Channel, "channels": updateChannels})
+}
+
+// GetHolds lists the held packages.
+// GET /api/v1/updates/holds
+func (h *UpdatesHandler) GetHolds(w http.ResponseWriter, r *http.Request) {
+	holds := h.loadSettings().Holds
+	if holds == nil {
+		holds = []string{}
+	}
+	writeJSON(w, map[string]any{"holds": holds})
+}
+
+// SetHolds replaces the held packages. The change is applied to apt with
+// apt-mark through the agent before it is saved; held packages are also
+// dropped from the update check and dry runs, and an apply never upgrades
+// them.
+// POST /api/v1/updates/holds {"packages":["linux-image-amd64"]}
+func (h *UpdatesHandler) SetHolds(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Packages []string `json:"packages"`
+	}
+	if err := decodeStrict(r, &body); err != nil {
+		writeInputError(w, err)
+		return
+	}
+	var errs []shareFieldError
+	seen := map[string]bool{}
+	holds := []string{}
+	for _, p := range body.Packages {
+		if !debPackageRe.MatchString(p) {
+			errs = append(errs, shareFieldError{Field: "packages", Message: fmt.Sprintf("%q is not a valid package name", p)})
+			continue
+		}
+		if !seen[p] {
+			seen[p] = true
+			holds = append(holds, p)
+		}
+	}
+	if len(errs) > 0 {
+		httpx.WriteErrorWithDetails(w, http.StatusBadRequest, "updates.holds.invalid", "Invalid package holds", map[string]any{"fields": errs})
+		return
+	}
+	sort.Strings(holds)
+	settings := h.loadSettings()
+	if err := applyAptHolds(r.Context(), h.agent, settings.Holds, holds); err != nil {
+		writeAgentError(w, err, "updates.holds_apply_failed", "Failed to apply package holds")
+		return
+	}
+	settings.Holds = holds
+	if err := h.saveSettings(settings); err != nil {
+		httpx.WriteTypedError(w, http.StatusInternalServerError, "updates.save_failed", "Failed to save settings", 0)
+		return
+	}
+	Logger(h.config).Info().Str("event", "updates.holds.changed").Strs("holds", holds).Str("userId", sessionUID(r)).Msg("")
+	writeJSON(w, map[string]any{"holds": holds})
+}
+
+// applyAptHolds runs apt-mark unhold for the packages dropped from prev and
+// apt-mark hold for the ones added in next.
+func applyAptHolds(ctx context.Context, client AgentClient, prev, next []string) error {
+	var steps []map[string]any
+	if released := missingFrom(prev, next); len(released) > 0 {
+		steps = append(steps, map[string]any{"cmd": "apt-mark", "args": append([]string{"unhold"}, released...)})
+	}
+	if added := missingFrom(next, prev); len(added) > 0 {
+		steps = append(steps, map[string]any{"cmd": "apt-mark", "args": append([]string{"hold"}, added...)})
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+	var resp struct {
+		Results []struct {
+			Code   int    `json:"code"`
+			Stderr string `json:"stderr"`
+		} `json:"results"`
+	}
+	if err := client.PostJSON(ctx, "/v1/run", map[string]any{"steps": steps}, &resp); err != nil {
+		return err
+	}
+	for _, res := range resp.Results {
+		if res.Code != 0 {
+			return fmt.Errorf("apt-mark exited %d: %s", res.Code, strings.TrimSpace(res.Stderr))
+		}
+	}
+	return nil
+}
+
+// missingFrom returns the entries of a that are not in b.
+func missingFrom(a, b []string) []string {
+	var out []string
+	for _, p := range a {
+		if !contains(b, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// updateHolds reads the held packages for the check/apply handlers.
+func updateHolds(cfg config.Config) []string {
+	return NewUpdatesHandler(cfg).loadSettings().Holds
+}
+
+// excludeHeld drops held packages from a plan and recomputes its totals.
+// The download size covers the whole apt run, so it is cleared once
+// anything is dropped.
+func excludeHeld(plan updatesPlan, holds []string) updatesPlan {
+	if len(holds) == 0 {
+		return plan
+	}
+	kept := make([]updateCandidate, 0, len(plan.Updates))
+	var held []string
+	plan.RebootRequired = plan.agentReboot
+	for _, u := range plan.Updates {
+		if contains(holds, u.Name) {
+			held = append(held, u.Name)
+			continue
+		}
+		kept = append(kept, u)
+		if u.RebootRequired {
+			plan.RebootRequired = true
+		}
+	}
+	plan.Updates = kept
+	plan.Count = len(kept)
+	plan.Held = held
+	if len(held) > 0 {
+		plan.DownloadBytes = 0
+	}
+	return plan
+}
+
+// heldPackages returns the requested packages that are held.
+func heldPackages(pkgs, holds []string) []string {
+	var out []string
+	for _, p := range pkgs {
+		if contains(holds, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// unheldUpgrades turns an apply of every upgradable package into an
+// explicit list without the held ones; apt-get upgrade knows nothing of
+// the holds kept here.
+func unheldUpgrades(ctx context.Context, client AgentClient, holds []string) ([]string, error) {
+	var raw json.RawMessage
+	if err := client.PostJSON(ctx, "/v1/updates/plan", map[string]any{}, &raw); err != nil {
+		return nil, err
+	}
+	plan, err := parseUpdatesPlan(raw)
+	if err != nil {
+		return nil, err
+	}
+	plan = excludeHeld(plan, holds)
+	names := make([]string, 0, len(plan.Updates))
+	for _, u := range plan.Updates {
+		names = append(names, u.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestUpdatesHoldsExcludedFromPlan(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	var applied []string
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/updates/plan":
+			_, _ = w.Write([]byte(`{"updates":[{"name":"nosd","current":"0.9.4","candidate":"0.9.5"},{"name":"linux-image-amd64","current":"6.1.1","candidate":"6.1.2"}],"raw":"Need to get 80.2 MB of archives."}`))
+		case "/v1/updates/apply":
+			var body struct {
+				Packages []string `json:"packages"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			applied = body.Packages
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	r := NewRouter(config.FromEnv())
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(mustJSON(body))))
+		return res
+	}
+
+	if res := post("/api/v1/updates/holds", map[string]any{"packages": []string{"Bad Name"}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("invalid hold: %d %s", res.Code, res.Body.String())
+	}
+	if res := post("/api/v1/updates/holds", map[string]any{"packages": []string{"linux-image-amd64", "linux-image-amd64"}}); res.Code != http.StatusOK {
+		t.Fatalf("set holds: %d %s", res.Code, res.Body.String())
+	}
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/updates/check", nil))
+	var out struct {
+		Plan updatesPlan `json:"plan"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if res.Code != http.StatusOK || out.Plan.Count != 1 || out.Plan.Updates[0].Name != "nosd" {
+		t.Fatalf("held package still planned: %d %s", res.Code, res.Body.String())
+	}
+	if out.Plan.RebootRequired || out.Plan.DownloadBytes != 0 || len(out.Plan.Held) != 1 {
+		t.Fatalf("plan totals not recomputed: %+v", out.Plan)
+	}
+
+	res = post("/api/v1/updates/apply", map[string]any{"packages": []string{"linux-image-amd64"}, "confirm": "yes"})
+	var held map[string]any
+	_ = json.Unmarshal(res.Body.Bytes(), &held)
+	if res.Code != http.StatusConflict || errCode(held) != "updates.package_held" {
+		t.Fatalf("apply of a held package: %d %s", res.Code, res.Body.String())
+	}
+	if applied != nil {
+		t.Fatalf("agent apply called for a held package: %v", applied)
+	}
+
+	// an apply of everything names the unheld packages explicitly
+	if res := post("/api/v1/updates/apply", map[string]any{"confirm": "yes"}); res.Code != http.StatusOK {
+		t.Fatalf("apply: %d %s", res.Code, res.Body.String())
+	}
+	if len(applied) != 1 || applied[0] != "nosd" {
+		t.Fatalf("applied %v, want [nosd]", applied)
+	}
+}
+
+func TestUpdatesChannelPersists(t *testing.T) {
+	cfg := config.Config{EtcDir: t.TempDir()}
+	agent := &recordingAgent{}
+	h := NewUpdatesHandler(cfg)
+	h.agent = agent
+
+	res := httptest.NewRecorder()
+	h.SetChannel(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/channel", strings.NewReader(`{"channel":"nightly"}`)))
+	if res.Code != http.StatusBadRequest || len(agent.posts) != 0 {
+		t.Fatalf("unknown channel: %d %s", res.Code, res.Body.String())
+	}
+
+	res = httptest.NewRecorder()
+	h.SetChannel(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/channel", strings.NewReader(`{"channel":"beta"}`)))
+	if res.Code != http.StatusOK {
+		t.Fatalf("set channel: %d %s", res.Code, res.Body.String())
+	}
+	if len(agent.posts) != 1 {
+		t.Fatalf("agent writes: %v", agent.posts)
+	}
+	write, _ := agent.posts[0].(map[string]any)
+	if write["path"] != aptSourcesPath || !strings.Contains(write["content"].(string), "https://apt.nithronos.com beta main") {
+		t.Fatalf("apt source not rewritten: %v", write)
+	}
+
+	// a fresh handler reads the saved channel
+	res = httptest.NewRecorder()
+	NewUpdatesHandler(cfg).GetChannel(res, httptest.NewRequest(http.MethodGet, "/api/v1/updates/channel", nil))
+	var got struct {
+		Channel string `json:"channel"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &got)
+	if got.Channel != "beta" {
+		t.Fatalf("channel after reload = %q, want beta", got.Channel)
+	}
+}
+
+func TestUpdatesHoldsAppliedWithAptMark(t *testing.T) {
+	healthTestEnv(t)
+	var runs [][]string
+	failRun := false
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/v1/run" {
+			_, _ = w.Write([]byte(`{"ok":true}`))
+			return
+		}
+		var body struct {
+			Steps []struct {
+				Cmd  string   `json:"cmd"`
+				Args []string `json:"args"`
+			} `json:"steps"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for _, s := range body.Steps {
+			runs = append(runs, append([]string{s.Cmd}, s.Args...))
+		}
+		if failRun {
+			_, _ = w.Write([]byte(`{"results":[{"code":100,"stderr":"E: Unable to locate package"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[{"code":0}]}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	r := NewRouter(config.FromEnv())
+	setHolds := func(pkgs ...string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/holds", bytes.NewReader(mustJSON(map[string]any{"packages": pkgs}))))
+		return res
+	}
+	joined := func() string {
+		var parts []string
+		for _, run := range runs {
+			parts = append(parts, strings.Join(run, " "))
+		}
+		runs = nil
+		return strings.Join(parts, "; ")
+	}
+
+	if res := setHolds("nosd", "linux-image-amd64"); res.Code != http.StatusOK {
+		t.Fatalf("set holds: %d %s", res.Code, res.Body.String())
+	}
+	if got := joined(); got != "apt-mark hold linux-image-amd64 nosd" {
+		t.Fatalf("runs = %q", got)
+	}
+	if res := setHolds("nosd", "zfs-dkms"); res.Code != http.StatusOK {
+		t.Fatalf("change holds: %d %s", res.Code, res.Body.String())
+	}
+	if got := joined(); got != "apt-mark unhold linux-image-amd64; apt-mark hold zfs-dkms" {
+		t.Fatalf("runs = %q", got)
+	}
+	if res := setHolds("nosd", "zfs-dkms"); res.Code != http.StatusOK || len(runs) != 0 {
+		t.Fatalf("unchanged holds should not call apt-mark: %d %v", res.Code, runs)
+	}
+
+	failRun = true
+	res := setHolds("nosd")
+	if res.Code == http.StatusOK || !strings.Contains(res.Body.String(), "updates.holds_apply_failed") {
+		t.Fatalf("failed apt-mark should fail the request: %d %s", res.Code, res.Body.String())
+	}
+	get := httptest.NewRecorder()
+	r.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/api/v1/updates/holds", nil))
+	if !strings.Contains(get.Body.String(), `"holds":["nosd","zfs-dkms"]`) {
+		t.Fatalf("failed apply must keep the saved holds: %s", get.Body.String())
+	}
+}
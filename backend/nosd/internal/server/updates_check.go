@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/agentclient"
+	poolroots "nithronos/backend/nosd/pkg/pools"
+)
+
+// updateCandidate is one upgradable package. JSON names follow the agent's
+// plan entries so existing clients keep working.
+type updateCandidate struct {
+	Name           string `json:"name"`
+	Current        string `json:"current"`
+	Candidate      string `json:"candidate"`
+	Arch           string `json:"arch,omitempty"`
+	Repo           string `json:"repo,omitempty"`
+	SizeBytes      int64  `json:"size_bytes,omitempty"`
+	RebootRequired bool   `json:"reboot_required"`
+}
+
+type updatesPlan struct {
+	Updates        []updateCandidate `json:"updates"`
+	Count          int               `json:"count"`
+	DownloadBytes  int64             `json:"download_bytes,omitempty"`
+	RebootRequired bool              `json:"reboot_required"`
+	Note           string            `json:"note,omitempty"`
+	CheckedAt      time.Time         `json:"checked_at"`
+	Cached         bool              `json:"cached"`
+	Held           []string          `json:"held,omitempty"`
+	agentReboot    bool              // the agent saw /run/reboot-required
+}
+
+// agentPlan mirrors nos-agent's /v1/updates/plan response.
+type agentPlan struct {
+	Updates []struct {
+		Name      string `json:"name"`
+		Current   string `json:"current"`
+		Candidate string `json:"candidate"`
+		Arch      string `json:"arch"`
+		Repo      string `json:"repo"`
+		Size      int64  `json:"size"`
+	} `json:"updates"`
+	RebootRequired bool   `json:"reboot_required"`
+	Raw            string `json:"raw"`
+	Note           string `json:"note"`
+}
+
+// Packages whose upgrade only takes effect after a reboot.
+var rebootPackagePrefixes = []string{"linux-image", "linux-firmware", "firmware-", "intel-microcode", "amd64-microcode", "libc6", "systemd", "dbus"}
+
+func packageNeedsReboot(name string) bool {
+	for _, p := range rebootPackagePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUpdatesPlan normalizes the agent's plan JSON into updatesPlan.
+func parseUpdatesPlan(b []byte) (updatesPlan, error) {
+	var ap agentPlan
+	if err := json.Unmarshal(b, &ap); err != nil {
+		return updatesPlan{}, err
+	}
+	out := updatesPlan{Updates: make([]updateCandidate, 0, len(ap.Updates)), RebootRequired: ap.RebootRequired, Note: ap.Note, agentReboot: ap.RebootRequired}
+	for _, u := range ap.Updates {
+		if u.Name == "" {
+			continue
+		}
+		c := updateCandidate{
+			Name:           u.Name,
+			Current:        u.Current,
+			Candidate:      u.Candidate,
+			Arch:           u.Arch,
+			Repo:           u.Repo,
+			SizeBytes:      u.Size,
+			RebootRequired: packageNeedsReboot(u.Name),
+		}
+		if c.RebootRequired {
+			out.RebootRequired = true
+		}
+		out.Updates = append(out.Updates, c)
+	}
+	out.Count = len(out.Updates)
+	out.DownloadBytes = parseAptDownloadSize(ap.Raw)
+	return out, nil
+}
+
+var aptNeedToGet = regexp.MustCompile(`Need to get (?:[\d.,]+ [kMG]?B/)?([\d.,]+) ([kMG]?B)`)
+
+// parseAptDownloadSize extracts the archive download size from apt output,
+// e.g. "Need to get 12.3 MB of archives." Returns 0 when absent.
+func parseAptDownloadSize(raw string) int64 {
+	m := aptNeedToGet.FindStringSubmatch(raw)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	mult := map[string]float64{"B": 1, "kB": 1e3, "MB": 1e6, "GB": 1e9}[m[2]]
+	return int64(n * mult)
+}
+
+// updatesCheckCache holds the last successful plan for cfg.UpdatesCheckSeconds.
+type updatesCheckCache struct {
+	mu   sync.Mutex
+	plan *updatesPlan
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func (c *updatesCheckCache) get(ctx context.Context, refresh bool, fetch func(context.Context) (updatesPlan, error)) (updatesPlan, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !refresh && c.plan != nil && c.ttl > 0 && c.now().Sub(c.plan.CheckedAt) < c.ttl {
+		p := *c.plan
+		p.Cached = true
+		return p, nil
+	}
+	p, err := fetch(ctx)
+	if err != nil {
+		return updatesPlan{}, err
+	}
+	p.CheckedAt = c.now().UTC()
+	c.plan = &p
+	return p, nil
+}
+
+// GET /api/v1/updates/check[?refresh=true]
+func handleUpdatesCheck(cfg config.Config) http.HandlerFunc {
+	cache := &updatesCheckCache{ttl: time.Duration(cfg.UpdatesCheckSeconds) * time.Second, now: time.Now}
+	fetch := func(ctx context.Context) (updatesPlan, error) {
+		// apt-get update on the agent side is slow
+		client := agentclient.New(cfg.AgentSocket(), agentclient.WithTimeout(3*time.Minute))
+		var raw json.RawMessage
+		if err := client.PostJSON(ctx, "/v1/updates/plan", map[string]any{}, &raw); err != nil {
+			return updatesPlan{}, err
+		}
+		return parseUpdatesPlan(raw)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+		plan, err := cache.get(r.Context(), refresh, fetch)
+		if err != nil {
+			writeAgentError(w, err, "updates.check_failed", "Checking for updates failed")
+			return
+		}
+		// holds are applied after the cache so a change shows up at once
+		plan = excludeHeld(plan, updateHolds(cfg))
+		// attach snapshot targets (best-effort)
+		roots, _ := poolroots.AllowedRoots()
+		writeJSON(w, map[string]any{"plan": plan, "snapshot_roots": roots})
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+const sampleAgentPlan = `{
+  "updates": [
+    {"name": "nosd", "current": "0.9.4", "candidate": "0.9.5", "arch": "amd64", "repo": "stable"},
+    {"name": "linux-image-amd64", "current": "6.1.90-1", "candidate": "6.1.94-1", "arch": "amd64", "repo": "stable-security", "size": 68000000},
+    {"name": ""}
+  ],
+  "raw": "Reading package lists...\nNeed to get 1,024 kB/70.5 MB of archives.\nInst nosd [0.9.4] (0.9.5 stable [amd64])\n"
+}`
+
+func TestParseUpdatesPlan(t *testing.T) {
+	p, err := parseUpdatesPlan([]byte(sampleAgentPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Count != 2 || len(p.Updates) != 2 {
+		t.Fatalf("expected 2 updates, got %+v", p.Updates)
+	}
+	nosd := p.Updates[0]
+	if nosd.Name != "nosd" || nosd.Current != "0.9.4" || nosd.Candidate != "0.9.5" || nosd.RebootRequired {
+		t.Fatalf("unexpected nosd entry: %+v", nosd)
+	}
+	kernel := p.Updates[1]
+	if !kernel.RebootRequired || kernel.SizeBytes != 68000000 {
+		t.Fatalf("unexpected kernel entry: %+v", kernel)
+	}
+	if !p.RebootRequired {
+		t.Fatal("kernel update should require reboot")
+	}
+	if p.DownloadBytes != 70500000 {
+		t.Fatalf("download size: %d", p.DownloadBytes)
+	}
+}
+
+func TestUpdatesCheckCachesUntilRefresh(t *testing.T) {
+	healthTestEnv(t)
+	sock, seen := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sampleAgentPlan))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Setenv("NOS_UPDATES_CHECK_INTERVAL", "1h")
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	r := NewRouter(config.FromEnv())
+	check := func(path string) updatesPlan {
+		t.Helper()
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, res.Code, res.Body.String())
+		}
+		var out struct {
+			Plan updatesPlan `json:"plan"`
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Plan
+	}
+
+	if p := check("/api/v1/updates/check"); p.Cached || p.Count != 2 {
+		t.Fatalf("first check: %+v", p)
+	}
+	if p := check("/api/v1/updates/check"); !p.Cached {
+		t.Fatalf("second check should be cached: %+v", p)
+	}
+	if n := len(seen()); n != 1 {
+		t.Fatalf("expected 1 agent call, got %d", n)
+	}
+	if p := check("/api/v1/updates/check?refresh=true"); p.Cached {
+		t.Fatalf("refresh should bypass cache: %+v", p)
+	}
+	if n := len(seen()); n != 2 {
+		t.Fatalf("expected 2 agent calls, got %d", n)
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/agentclient"
+	poolroots "nithronos/backend/nosd/pkg/pools"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+// writeUpdatesDryRun answers POST /api/v1/updates/apply with dry_run set:
+// the agent only simulates the upgrade (/v1/updates/plan), no snapshot is
+// taken and /v1/updates/apply is never called. The tx is recorded with
+// DryRun set so the history shows the preview.
+func writeUpdatesDryRun(w http.ResponseWriter, r *http.Request, cfg config.Config, client *agentclient.Client, packages []string, snapshot bool) {
+	tx := snapdb.UpdateTx{TxID: generateUUID(), StartedAt: time.Now().UTC(), Packages: packages, Reason: "pre-update", DryRun: true}
+	targets, skipped := []string{}, []skippedTarget{}
+	if snapshot {
+		roots, _ := poolroots.AllowedRoots()
+		targets, skipped = selectPreUpdateTargets(cfg.UpdatesSnapshotScope, roots, procMountOf)
+		tx.Notes = skippedNotes(skipped)
+	}
+
+	var raw json.RawMessage
+	err := client.PostJSON(r.Context(), "/v1/updates/plan", map[string]any{"packages": packages}, &raw)
+	var plan updatesPlan
+	if err == nil {
+		plan, err = parseUpdatesPlan(raw)
+	}
+	now := time.Now().UTC()
+	tx.FinishedAt = &now
+	mark := err == nil
+	tx.Success = &mark
+	if err != nil {
+		tx.Notes = joinNotes("plan failed: "+errString(err), tx.Notes)
+		_ = snapdb.Append(tx)
+		writeAgentError(w, err, "updates.plan_failed", "Planning updates failed")
+		return
+	}
+	plan = excludeHeld(plan, updateHolds(cfg))
+	plan.CheckedAt = now
+	_ = snapdb.Append(tx)
+	writeJSON(w, map[string]any{"ok": true, "dry_run": true, "tx_id": tx.TxID, "plan": plan, "snapshot_targets": targets, "snapshots_skipped": skipped, "snapshots_manual_only": manualOnlyPaths(targets), "updates_count": plan.Count})
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+func TestUpdatesApplyDryRunSkipsAgentApply(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	sock, seen := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/updates/plan" {
+			_, _ = w.Write([]byte(`{"updates":[{"name":"nosd","current":"0.9.4","candidate":"0.9.5"},{"name":"linux-image-amd64","current":"6.1.1","candidate":"6.1.2"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+
+	r := NewRouter(config.FromEnv())
+	res := httptest.NewRecorder()
+	// no confirm needed: nothing is changed
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/apply",
+		bytes.NewReader(mustJSON(map[string]any{"packages": []string{"nosd"}, "snapshot": true, "dry_run": true}))))
+	if res.Code != http.StatusOK {
+		t.Fatalf("dry run: %d %s", res.Code, res.Body.String())
+	}
+	for _, p := range seen() {
+		if p == "/v1/updates/apply" || p == "/v1/snapshot/create" {
+			t.Fatalf("dry run called %s: %v", p, seen())
+		}
+	}
+
+	var out struct {
+		DryRun bool        `json:"dry_run"`
+		TxID   string      `json:"tx_id"`
+		Plan   updatesPlan `json:"plan"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if !out.DryRun || out.Plan.Count != 2 || !out.Plan.RebootRequired {
+		t.Fatalf("plan not returned: %s", res.Body.String())
+	}
+	tx, err := snapdb.FindByTx(out.TxID)
+	if err != nil || !tx.DryRun || len(tx.Targets) != 0 || tx.Success == nil || !*tx.Success {
+		t.Fatalf("tx record: %+v %v", tx, err)
+	}
+
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/rollback",
+		bytes.NewReader(mustJSON(map[string]any{"tx_id": out.TxID, "confirm": "yes"}))))
+	if res.Code != http.StatusConflict {
+		t.Fatalf("rollback of a dry run: %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestUpdatesRollbackLeavesRootToManualRecovery(t *testing.T) {
+	dir := healthTestEnv(t)
+	t.Setenv("NOS_SNAPDB_DIR", dir)
+	var mu sync.Mutex
+	var rolled []string
+	sock, _ := fakeAgentSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Path string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		rolled = append(rolled, body.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	t.Setenv("NOS_AGENT_SOCKET", sock)
+	t.Cleanup(func() { agentSocketPath = config.Defaults().AgentSocket() })
+	ok := true
+	now := time.Now().UTC()
+	root := snapdb.SnapshotTarget{ID: "s1", Path: "/", Type: "btrfs"}
+	_ = snapdb.Append(snapdb.UpdateTx{TxID: "tx-os", StartedAt: now, FinishedAt: &now, Success: &ok, Targets: []snapdb.SnapshotTarget{root, {ID: "s2", Path: "/var", Type: "btrfs"}}})
+	_ = snapdb.Append(snapdb.UpdateTx{TxID: "tx-root", StartedAt: now, FinishedAt: &now, Success: &ok, Targets: []snapdb.SnapshotTarget{root}})
+
+	r := NewRouter(config.FromEnv())
+	rollback := func(tx string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/updates/rollback",
+			bytes.NewReader(mustJSON(map[string]any{"tx_id": tx, "confirm": "yes"}))))
+		return res
+	}
+
+	res := rollback("tx-os")
+	var out struct {
+		ManualOnly []string `json:"manual_only"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &out)
+	if res.Code != http.StatusOK || len(out.ManualOnly) != 1 || out.ManualOnly[0] != "/" {
+		t.Fatalf("rollback: %d %s", res.Code, res.Body.String())
+	}
+	mu.Lock()
+	if len(rolled) != 1 || rolled[0] != "/var" {
+		t.Fatalf("agent asked to roll back %v", rolled)
+	}
+	mu.Unlock()
+
+	if res := rollback("tx-root"); res.Code != http.StatusConflict {
+		t.Fatalf("root-only rollback: %d %s", res.Code, res.Body.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(rolled) != 1 {
+		t.Fatalf("agent called for a root-only rollback: %v", rolled)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/pkg/agentclient"
 	"nithronos/backend/nosd/pkg/httpx"
 
 	"github.com/go-chi/chi/v5"
@@ -40,12 +41,16 @@ type UpdateSettings struct {
 	CheckInterval  int       `json:"check_interval_hours"`
 	LastCheck      time.Time `json:"last_check"`
 	NotifyOnUpdate bool      `json:"notify_on_update"`
+
+	// Holds are packages left out of every plan and apply.
+	Holds []string `json:"holds,omitempty"`
 }
 
 // UpdatesHandler handles system update endpoints
 type UpdatesHandler struct {
 	config       config.Config
 	settingsPath string
+	agent        AgentClient
 }
 
 // NewUpdatesHandler creates a new updates handler
@@ -53,6 +58,7 @@ func NewUpdatesHandler(cfg config.Config) *UpdatesHandler {
 	return &UpdatesHandler{
 		config:       cfg,
 		settingsPath: filepath.Join(cfg.EtcDir, "nos", "update-settings.json"),
+		agent:        agentclient.New(cfg.AgentSocket()),
 	}
 }
 
@@ -60,7 +66,6 @@ func NewUpdatesHandler(cfg config.Config) *UpdatesHandler {
 func (h *UpdatesHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/check", h.CheckForUpdates)
-	r.Post("/apply", h.ApplyUpdate)
 	return r
 }
 
@@ -127,24 +132,6 @@ func (h *UpdatesHandler) UpdateSettings(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, settings)
 }
 
-// ApplyUpdate applies a pending update
-func (h *UpdatesHandler) ApplyUpdate(w http.ResponseWriter, r *http.Request) {
-	// Check if an update is available
-	updateInfo := h.getLatestUpdateInfo()
-	if !updateInfo.UpdateAvailable {
-		httpx.WriteTypedError(w, http.StatusNotFound, "updates.none_available", "No update available", 0)
-		return
-	}
-
-	// Start update process in background
-	go h.performUpdate(updateInfo)
-
-	writeJSON(w, map[string]any{
-		"message": "Update process started",
-		"version": updateInfo.LatestVersion,
-	})
-}
-
 // GetUpdateHistory returns update history
 func (h *UpdatesHandler) GetUpdateHistory(w http.ResponseWriter, r *http.Request) {
 	history := h.loadUpdateHistory()
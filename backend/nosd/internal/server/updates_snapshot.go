@@ -0,0 +1,168 @@
+package server
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"nithronos/backend/nosd/pkg/snapdb"
+)
+
+// Pre-update snapshot scopes (cfg.UpdatesSnapshotScope).
+const (
+	snapshotScopeOS  = "os"  // root and /var subvolumes only
+	snapshotScopeAll = "all" // OS subvolumes plus every allowed pool root
+)
+
+// osSnapshotRoots are the OS subvolume mountpoints covered by the "os" scope.
+var osSnapshotRoots = []string{"/", "/var"}
+
+type skippedTarget struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// mountOf returns the filesystem type and mountpoint of the mount holding path.
+type mountOf func(path string) (fstype, mountpoint string)
+
+// selectPreUpdateTargets picks which paths to snapshot before an update.
+// Paths not on btrfs are skipped, as are /var-style OS paths that aren't a
+// separate mount (the root snapshot already covers them) and duplicates.
+func selectPreUpdateTargets(scope string, poolRoots []string, probe mountOf) ([]string, []skippedTarget) {
+	type cand struct {
+		path       string
+		needsMount bool
+	}
+	var cands []cand
+	for _, p := range osSnapshotRoots {
+		cands = append(cands, cand{path: p, needsMount: p != "/"})
+	}
+	if scope == snapshotScopeAll {
+		for _, p := range poolRoots {
+			cands = append(cands, cand{path: p})
+		}
+	}
+
+	var targets []string
+	skipped := []skippedTarget{}
+	seen := map[string]bool{}
+	for _, c := range cands {
+		if seen[c.path] {
+			continue
+		}
+		seen[c.path] = true
+		fstype, mnt := probe(c.path)
+		switch {
+		case fstype == "":
+			skipped = append(skipped, skippedTarget{c.path, "mount not found"})
+		case fstype != "btrfs":
+			skipped = append(skipped, skippedTarget{c.path, "not btrfs (" + fstype + ")"})
+		case c.needsMount && mnt != c.path:
+			skipped = append(skipped, skippedTarget{c.path, "not a separate subvolume mount"})
+		default:
+			targets = append(targets, c.path)
+		}
+	}
+	return targets, skipped
+}
+
+// manualRollbackPaths are snapshotted before an update but never rolled
+// back by nosd: the agent refuses to replace the running root filesystem, so
+// the root snapshot is for recovery from a rescue system only.
+var manualRollbackPaths = map[string]bool{"/": true}
+
+// manualOnlyPaths lists the paths nosd won't roll back.
+func manualOnlyPaths(paths []string) []string {
+	out := []string{}
+	for _, p := range paths {
+		if manualRollbackPaths[p] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// splitRollbackTargets separates the targets the agent can roll back from
+// the paths left for manual recovery.
+func splitRollbackTargets(targets []snapdb.SnapshotTarget) (auto []snapdb.SnapshotTarget, manual []string) {
+	manual = []string{}
+	for _, t := range targets {
+		if manualRollbackPaths[t.Path] {
+			manual = append(manual, t.Path)
+		} else {
+			auto = append(auto, t)
+		}
+	}
+	return auto, manual
+}
+
+// bootRollbackTargets narrows targets to what the boot guard may roll back
+// unattended: the OS subvolumes other than the root. Data pools from the
+// "all" scope are never rolled back by a missed confirmation; they and the
+// root are left for manual recovery.
+func bootRollbackTargets(targets []snapdb.SnapshotTarget) (auto []snapdb.SnapshotTarget, manual []string) {
+	candidates, manual := splitRollbackTargets(targets)
+	for _, t := range candidates {
+		if slices.Contains(osSnapshotRoots, t.Path) {
+			auto = append(auto, t)
+		} else {
+			manual = append(manual, t.Path)
+		}
+	}
+	return auto, manual
+}
+
+// manualNotes renders paths left for manual recovery for snapdb tx notes.
+func manualNotes(paths []string) string {
+	parts := make([]string, 0, len(paths))
+	for _, p := range paths {
+		parts = append(parts, "not rolled back "+p+": manual recovery only")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// skippedNotes renders skipped targets for snapdb tx notes.
+func skippedNotes(skipped []skippedTarget) string {
+	parts := make([]string, 0, len(skipped))
+	for _, s := range skipped {
+		parts = append(parts, "skipped "+s.Path+": "+s.Reason)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// joinNotes joins non-empty note fragments with "; ".
+func joinNotes(parts ...string) string {
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "; ")
+}
+
+// seam for tests
+var procMountsPath = "/proc/self/mounts"
+
+// procMountOf resolves the longest mountpoint prefix of path from /proc/self/mounts.
+func procMountOf(path string) (fstype, mountpoint string) {
+	b, err := os.ReadFile(procMountsPath)
+	if err != nil {
+		return "", ""
+	}
+	for _, ln := range strings.Split(string(b), "\n") {
+		f := strings.Fields(ln)
+		if len(f) < 3 {
+			continue
+		}
+		mp := strings.ReplaceAll(f[1], `\040`, " ")
+		if mp != "/" && path != mp && !strings.HasPrefix(path, mp+"/") {
+			continue
+		}
+		// later entries shadow earlier ones on the same mountpoint
+		if len(mp) >= len(mountpoint) {
+			fstype, mountpoint = f[2], mp
+		}
+	}
+	return fstype, mountpoint
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSelectPreUpdateTargets(t *testing.T) {
+	mounts := map[string][2]string{
+		"/":         {"btrfs", "/"},
+		"/var":      {"btrfs", "/var"},
+		"/srv":      {"btrfs", "/srv"},
+		"/mnt":      {"ext4", "/"},
+		"/mnt/data": {"xfs", "/mnt/data"},
+	}
+	probe := func(p string) (string, string) {
+		m, ok := mounts[p]
+		if !ok {
+			return "", ""
+		}
+		return m[0], m[1]
+	}
+
+	targets, skipped := selectPreUpdateTargets(snapshotScopeOS, []string{"/srv", "/mnt"}, probe)
+	if !reflect.DeepEqual(targets, []string{"/", "/var"}) || len(skipped) != 0 {
+		t.Fatalf("os scope: targets=%v skipped=%v", targets, skipped)
+	}
+
+	targets, skipped = selectPreUpdateTargets(snapshotScopeAll, []string{"/srv", "/mnt", "/mnt/data", "/srv", "/gone"}, probe)
+	if !reflect.DeepEqual(targets, []string{"/", "/var", "/srv"}) {
+		t.Fatalf("all scope targets: %v", targets)
+	}
+	want := []skippedTarget{
+		{"/mnt", "not btrfs (ext4)"},
+		{"/mnt/data", "not btrfs (xfs)"},
+		{"/gone", "mount not found"},
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("all scope skipped: %v", skipped)
+	}
+
+	// /var living inside the root subvolume is covered by the root snapshot
+	mounts["/var"] = [2]string{"btrfs", "/"}
+	targets, skipped = selectPreUpdateTargets(snapshotScopeOS, nil, probe)
+	if !reflect.DeepEqual(targets, []string{"/"}) || len(skipped) != 1 || skipped[0].Path != "/var" {
+		t.Fatalf("nested /var: targets=%v skipped=%v", targets, skipped)
+	}
+	if got := skippedNotes(skipped); got != "skipped /var: not a separate subvolume mount" {
+		t.Fatalf("notes: %q", got)
+	}
+}
+
+func TestProcMountOf(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "mounts")
+	data := "/dev/sda2 / btrfs rw,subvol=/@ 0 0\n" +
+		"/dev/sda2 /var btrfs rw,subvol=/@var 0 0\n" +
+		"/dev/sdb1 /srv/data ext4 rw 0 0\n"
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := procMountsPath
+	procMountsPath = p
+	defer func() { procMountsPath = old }()
+
+	cases := map[string][2]string{
+		"/":            {"btrfs", "/"},
+		"/var":         {"btrfs", "/var"},
+		"/variable":    {"btrfs", "/"},
+		"/srv/data/x":  {"ext4", "/srv/data"},
+		"/srv/dataset": {"btrfs", "/"},
+	}
+	for path, want := range cases {
+		fs, mp := procMountOf(path)
+		if fs != want[0] || mp != want[1] {
+			t.Errorf("%s: got %s %s, want %v", path, fs, mp, want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"nithronos/backend/nosd/internal/auth/hash"
@@ -75,21 +78,7 @@ func (h *UsersHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	// Convert to API response format
 	apiUsers := make([]UserAccount, 0, len(users))
 	for _, u := range users {
-		apiUser := UserAccount{
-			ID:               u.ID,
-			Username:         u.Username,
-			Email:            u.Username, // Username is email in current implementation
-			DisplayName:      "",         // Not in current store
-			Roles:            u.Roles,
-			CreatedAt:        parseTime(u.CreatedAt),
-			UpdatedAt:        parseTime(u.UpdatedAt),
-			Enabled:          true, // Not in current store
-			TwoFactorEnabled: u.TOTPEnc != "",
-		}
-		if u.LastLoginAt != "" {
-			apiUser.LastLoginAt = parseTime(u.LastLoginAt)
-		}
-		apiUsers = append(apiUsers, apiUser)
+		apiUsers = append(apiUsers, userAccountFrom(u))
 	}
 
 	writeJSON(w, apiUsers)
@@ -113,22 +102,7 @@ func (h *UsersHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiUser := UserAccount{
-		ID:               user.ID,
-		Username:         user.Username,
-		Email:            user.Username,
-		DisplayName:      "", // Not in current store
-		Roles:            user.Roles,
-		CreatedAt:        parseTime(user.CreatedAt),
-		UpdatedAt:        parseTime(user.UpdatedAt),
-		Enabled:          true, // Not in current store
-		TwoFactorEnabled: user.TOTPEnc != "",
-	}
-	if user.LastLoginAt != "" {
-		apiUser.LastLoginAt = parseTime(user.LastLoginAt)
-	}
-
-	writeJSON(w, apiUser)
+	writeJSON(w, userAccountFrom(user))
 }
 
 // CreateUser creates a new user
@@ -150,9 +124,19 @@ func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	email, ok := normalizeEmail(req.Email)
+	if !ok {
+		httpx.WriteTypedError(w, http.StatusBadRequest, "user.invalid_email", "Email address is invalid", 0)
+		return
+	}
+
 	// Check if user already exists
-	if _, err := h.store.FindByUsername(req.Email); err == nil {
-		httpx.WriteTypedError(w, http.StatusConflict, "user.already_exists", "User with this email already exists", 0)
+	if _, err := h.store.FindByUsername(req.Username); err == nil {
+		httpx.WriteTypedError(w, http.StatusConflict, "user.already_exists", "User with this username already exists", 0)
+		return
+	}
+	if _, err := h.store.FindByEmail(email); err == nil {
+		httpx.WriteTypedError(w, http.StatusConflict, "user.email_taken", "User with this email already exists", 0)
 		return
 	}
 
@@ -167,11 +151,15 @@ func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	now := time.Now().UTC().Format(time.RFC3339)
 	newUser := userstore.User{
 		ID:           generateUUID(),
-		Username:     req.Email,
+		Username:     req.Username,
 		PasswordHash: hashedPassword,
 		Roles:        req.Roles,
 		CreatedAt:    now,
 		UpdatedAt:    now,
+
+		PasswordChangedAt: now,
+		Email:             email,
+		DisplayName:       strings.TrimSpace(req.DisplayName),
 	}
 
 	if len(newUser.Roles) == 0 {
@@ -180,25 +168,16 @@ func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	// Save user
 	if err := h.store.UpsertUser(newUser); err != nil {
+		if errors.Is(err, userstore.ErrEmailTaken) {
+			httpx.WriteTypedError(w, http.StatusConflict, "user.email_taken", "User with this email already exists", 0)
+			return
+		}
 		httpx.WriteTypedError(w, http.StatusInternalServerError, "user.create_failed", "Failed to create user", 0)
 		return
 	}
 
-	// Return created user
-	apiUser := UserAccount{
-		ID:               newUser.ID,
-		Username:         newUser.Username,
-		Email:            newUser.Username,
-		DisplayName:      req.DisplayName,
-		Roles:            newUser.Roles,
-		CreatedAt:        parseTime(newUser.CreatedAt),
-		UpdatedAt:        parseTime(newUser.UpdatedAt),
-		Enabled:          true,
-		TwoFactorEnabled: false,
-	}
-
 	w.WriteHeader(http.StatusCreated)
-	writeJSON(w, apiUser)
+	writeJSON(w, userAccountFrom(newUser))
 }
 
 // UpdateUser updates an existing user
@@ -227,9 +206,16 @@ func (h *UsersHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update fields
-	// DisplayName not in store
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
 	if req.Email != nil {
-		user.Username = *req.Email
+		email, ok := normalizeEmail(*req.Email)
+		if !ok {
+			httpx.WriteTypedError(w, http.StatusBadRequest, "user.invalid_email", "Email address is invalid", 0)
+			return
+		}
+		user.Email = email
 	}
 	if req.Roles != nil {
 		user.Roles = *req.Roles
@@ -240,27 +226,15 @@ func (h *UsersHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	// Save updated user
 	if err := h.store.UpsertUser(user); err != nil {
+		if errors.Is(err, userstore.ErrEmailTaken) {
+			httpx.WriteTypedError(w, http.StatusConflict, "user.email_taken", "User with this email already exists", 0)
+			return
+		}
 		httpx.WriteTypedError(w, http.StatusInternalServerError, "user.update_failed", "Failed to update user", 0)
 		return
 	}
 
-	// Return updated user
-	apiUser := UserAccount{
-		ID:               user.ID,
-		Username:         user.Username,
-		Email:            user.Username,
-		DisplayName:      "", // Not in store
-		Roles:            user.Roles,
-		CreatedAt:        parseTime(user.CreatedAt),
-		UpdatedAt:        parseTime(user.UpdatedAt),
-		Enabled:          true, // Not in store
-		TwoFactorEnabled: user.TOTPEnc != "",
-	}
-	if user.LastLoginAt != "" {
-		apiUser.LastLoginAt = parseTime(user.LastLoginAt)
-	}
-
-	writeJSON(w, apiUser)
+	writeJSON(w, userAccountFrom(user))
 }
 
 // DeleteUser deletes a user
@@ -340,7 +314,22 @@ func (h *UsersHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteTypedError(w, http.StatusBadRequest, "user.id_required", "User ID is required", 0)
 		return
 	}
+	h.changePassword(w, r, userID)
+}
 
+// ChangeOwnPassword changes the signed-in user's password. Unlike
+// ChangePassword it needs no admin role, so a user whose password expired
+// or was flagged for change can still replace it.
+func (h *UsersHandler) ChangeOwnPassword(w http.ResponseWriter, r *http.Request) {
+	userID := sessionUID(r)
+	if userID == "" {
+		httpx.WriteTypedError(w, http.StatusUnauthorized, "auth.required", "Authentication required", 0)
+		return
+	}
+	h.changePassword(w, r, userID)
+}
+
+func (h *UsersHandler) changePassword(w http.ResponseWriter, r *http.Request, userID string) {
 	var req ChangePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		httpx.WriteTypedError(w, http.StatusBadRequest, "user.invalid_request", "Invalid request body", 0)
@@ -348,7 +337,7 @@ func (h *UsersHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current user from context (set by auth middleware)
-	currentUserID := r.Context().Value("user_id").(string)
+	currentUserID := sessionUID(r)
 
 	// Users can only change their own password (unless admin)
 	if currentUserID != userID {
@@ -401,6 +390,11 @@ func (h *UsersHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	// Update password
 	user.PasswordHash = hashedPassword
 	user.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	user.PasswordChangedAt = user.UpdatedAt
+	// An admin resetting someone else's password leaves the force flag alone
+	if currentUserID == userID {
+		user.ForcePasswordChange = false
+	}
 
 	if err := h.store.UpsertUser(user); err != nil {
 		httpx.WriteTypedError(w, http.StatusInternalServerError, "user.update_failed", "Failed to update password", 0)
@@ -410,6 +404,35 @@ func (h *UsersHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]bool{"success": true})
 }
 
+// userAccountFrom converts a stored user to its API form.
+func userAccountFrom(u userstore.User) UserAccount {
+	a := UserAccount{
+		ID:               u.ID,
+		Username:         u.Username,
+		Email:            u.Email,
+		DisplayName:      u.DisplayName,
+		Roles:            u.Roles,
+		CreatedAt:        parseTime(u.CreatedAt),
+		UpdatedAt:        parseTime(u.UpdatedAt),
+		Enabled:          true, // Not in current store
+		TwoFactorEnabled: u.TOTPEnc != "",
+	}
+	if u.LastLoginAt != "" {
+		a.LastLoginAt = parseTime(u.LastLoginAt)
+	}
+	return a
+}
+
+// normalizeEmail trims s and checks it is a bare address (no display name).
+func normalizeEmail(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	a, err := mail.ParseAddress(s)
+	if err != nil || a.Address != s {
+		return "", false
+	}
+	return s, true
+}
+
 // Helper function to parse time
 func parseTime(s string) time.Time {
 	t, _ := time.Parse(time.RFC3339, s)
@@ -426,8 +449,9 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// Routes returns the routes for the users handler
-func (h *UsersHandler) Routes() chi.Router {
+// Routes returns the routes for the users handler. sensitive wraps the
+// password and recovery-code routes (e.g. a rate limit).
+func (h *UsersHandler) Routes(sensitive ...func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
 	// User CRUD operations
@@ -438,14 +462,14 @@ func (h *UsersHandler) Routes() chi.Router {
 	r.Delete("/{id}", h.DeleteUser)
 
 	// Password management
-	r.Post("/{id}/password", h.ChangePassword)
+	r.With(sensitive...).Post("/{id}/password", h.ChangePassword)
 
 	// Role management
 	r.Post("/{id}/roles", h.SetUserRoles)
 
 	// 2FA management
 	r.Post("/{id}/2fa/toggle", h.ToggleUser2FA)
-	r.Post("/{id}/recovery-codes", h.GenerateRecoveryCodes)
+	r.With(sensitive...).Post("/{id}/recovery-codes", h.GenerateRecoveryCodes)
 
 	return r
 }
@@ -534,10 +558,9 @@ func (h *UsersHandler) ToggleUser2FA(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check current user permissions
-	currentUserID := r.Context().Value("user_id")
-	if currentUserID != nil && currentUserID.(string) != userID {
+	if currentUserID := sessionUID(r); currentUserID != "" && currentUserID != userID {
 		// Only the user themselves or an admin can toggle 2FA
-		currentUser, _ := h.store.FindByID(currentUserID.(string))
+		currentUser, _ := h.store.FindByID(currentUserID)
 		if !contains(currentUser.Roles, "admin") {
 			httpx.WriteTypedError(w, http.StatusForbidden, "user.forbidden", "You can only manage your own 2FA settings", 0)
 			return
@@ -551,7 +574,9 @@ func (h *UsersHandler) ToggleUser2FA(w http.ResponseWriter, r *http.Request) {
 		// Disable 2FA - clear TOTP and recovery codes
 		user.TOTPEnc = ""
 		user.RecoveryHashes = nil
+		user.TOTPPendingEnc = ""
 	}
+	user.TOTPEnabled = false
 
 	user.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
 	if err := h.store.UpsertUser(user); err != nil {
@@ -591,10 +616,9 @@ func (h *UsersHandler) GenerateRecoveryCodes(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Check current user permissions
-	currentUserID := r.Context().Value("user_id")
-	if currentUserID != nil && currentUserID.(string) != userID {
+	if currentUserID := sessionUID(r); currentUserID != "" && currentUserID != userID {
 		// Only the user themselves or an admin can generate recovery codes
-		currentUser, _ := h.store.FindByID(currentUserID.(string))
+		currentUser, _ := h.store.FindByID(currentUserID)
 		if !contains(currentUser.Roles, "admin") {
 			httpx.WriteTypedError(w, http.StatusForbidden, "user.forbidden", "You can only manage your own recovery codes", 0)
 			return
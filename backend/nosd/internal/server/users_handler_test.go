@@ -0,0 +1,54 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	userstore "nithronos/backend/nosd/internal/auth/store"
+	"nithronos/backend/nosd/internal/config"
+)
+
+func TestUsersCreateAndUpdateEmail(t *testing.T) {
+	us, _ := userstore.New(filepath.Join(t.TempDir(), "users.json"))
+	r := NewUsersHandler(us, config.Config{}).Routes()
+	call := func(method, path string, body map[string]any) (*httptest.ResponseRecorder, map[string]any) {
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, httptest.NewRequest(method, path, bytes.NewReader(mustJSON(body))))
+		var out map[string]any
+		_ = json.Unmarshal(res.Body.Bytes(), &out)
+		return res, out
+	}
+
+	res, out := call(http.MethodPost, "/", map[string]any{"username": "alice", "email": "alice@example.com", "password": "StrongPassw0rd!", "display_name": "Alice"})
+	if res.Code != http.StatusCreated || out["username"] != "alice" || out["email"] != "alice@example.com" || out["display_name"] != "Alice" {
+		t.Fatalf("create: %d %s", res.Code, res.Body.String())
+	}
+	aliceID, _ := out["id"].(string)
+
+	if res, out := call(http.MethodPost, "/", map[string]any{"username": "bob", "email": "ALICE@example.com", "password": "StrongPassw0rd!"}); res.Code != http.StatusConflict || errCode(out) != "user.email_taken" {
+		t.Fatalf("duplicate email: %d %s", res.Code, res.Body.String())
+	}
+	if res, out := call(http.MethodPost, "/", map[string]any{"username": "bob", "email": "not-an-email", "password": "StrongPassw0rd!"}); res.Code != http.StatusBadRequest || errCode(out) != "user.invalid_email" {
+		t.Fatalf("invalid email: %d %s", res.Code, res.Body.String())
+	}
+	res, out = call(http.MethodPost, "/", map[string]any{"username": "bob", "email": "bob@example.com", "password": "StrongPassw0rd!"})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("create bob: %d %s", res.Code, res.Body.String())
+	}
+	bobID, _ := out["id"].(string)
+
+	if res, out := call(http.MethodPut, "/"+bobID, map[string]any{"email": "Alice@Example.com"}); res.Code != http.StatusConflict || errCode(out) != "user.email_taken" {
+		t.Fatalf("update to taken email: %d %s", res.Code, res.Body.String())
+	}
+	res, out = call(http.MethodPut, "/"+aliceID, map[string]any{"email": "alice@corp.example", "display_name": "Alice A."})
+	if res.Code != http.StatusOK || out["email"] != "alice@corp.example" || out["username"] != "alice" || out["display_name"] != "Alice A." {
+		t.Fatalf("update: %d %s", res.Code, res.Body.String())
+	}
+	if u, err := us.FindByEmail("alice@corp.example"); err != nil || u.ID != aliceID {
+		t.Fatalf("updated email not stored: %+v %v", u, err)
+	}
+}
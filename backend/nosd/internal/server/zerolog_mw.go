@@ -1,8 +1,12 @@
 package server
 
 import (
+	"bufio"
+	"errors"
+	"net"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -11,20 +15,52 @@ import (
 	"nithronos/backend/nosd/internal/config"
 )
 
+// redactedHeaders are request headers carrying credentials; the debug access
+// log lists them without their values.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Csrf-Token":        true,
+	"X-Setup-Token":       true,
+	"X-Refresh-Id":        true,
+}
+
+// zerologMiddleware writes one "http" line per request with its method,
+// path, status, response size, duration, client IP, request ID and user.
+// Successful requests are logged at cfg.AccessLogLevel, one in every
+// cfg.AccessLogSample; failed ones are never sampled out and 5xx responses
+// are errors. With debug logging on, the request headers are included,
+// credentials redacted.
 func zerologMiddleware(logger *zerolog.Logger, cfg config.Config) func(next http.Handler) http.Handler {
+	sample := uint64(max(cfg.AccessLogSample, 1))
+	var served atomic.Uint64
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			r, id := withIdentityHolder(r)
 			ww := &statusWriter{ResponseWriter: w, status: 200}
 			next.ServeHTTP(ww, r)
 			dur := time.Since(start)
+			level := cfg.AccessLogLevel
+			switch {
+			case ww.status >= 500:
+				level = zerolog.ErrorLevel
+			case ww.status < 400 && served.Add(1)%sample != 0:
+				return
+			}
+			evt := logger.WithLevel(level)
+			if !evt.Enabled() {
+				return
+			}
 			reqID := middleware.GetReqID(r.Context())
-			uid := r.Header.Get("X-UID")
+			uid := id.UID
 			ip := clientIP(r, cfg)
-			evt := logger.Info().
+			evt = evt.
 				Str("method", r.Method).
 				Str("path", r.URL.Path).
 				Int("status", ww.status).
+				Int64("bytes", ww.bytes).
 				Dur("duration", dur).
 				Str("ip", ip)
 			if reqID != "" {
@@ -38,17 +74,63 @@ func zerologMiddleware(logger *zerolog.Logger, cfg config.Config) func(next http
 					evt = evt.Int("content_length", n)
 				}
 			}
+			if ua := r.UserAgent(); ua != "" {
+				evt = evt.Str("user_agent", ua)
+			}
+			if logger.Debug().Enabled() {
+				evt = evt.Dict("headers", headerDict(r.Header))
+			}
 			evt.Msg("http")
 		})
 	}
 }
 
+// headerDict is h with the values of redactedHeaders replaced.
+func headerDict(h http.Header) *zerolog.Event {
+	d := zerolog.Dict()
+	for k, v := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			d = d.Str(k, "[redacted]")
+		} else {
+			d = d.Strs(k, v)
+		}
+	}
+	return d
+}
+
 type statusWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (w *statusWriter) WriteHeader(code int) {
 	w.status = code
 	w.ResponseWriter.WriteHeader(code)
 }
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush and Hijack pass through, so event streams and WebSocket upgrades
+// work behind the logger.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	// the connection now belongs to the handler; log it as switched
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+func accessLogHandler(t *testing.T, cfg config.Config, level zerolog.Level, buf *bytes.Buffer) http.Handler {
+	t.Helper()
+	logger := zerolog.New(buf).Level(level)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("hello"))
+		}
+	})
+	return middleware.RequestID(zerologMiddleware(&logger, cfg)(h))
+}
+
+func accessLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if l == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(l), &m); err != nil {
+			t.Fatalf("log line %q: %v", l, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestAccessLogFields(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.Defaults()
+	h := accessLogHandler(t, cfg, zerolog.DebugLevel, &buf)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shares?x=1", strings.NewReader("{}"))
+	req.RemoteAddr = "192.0.2.7:5555"
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set("Cookie", "nos_session=s3cret")
+	req.Header.Set("X-CSRF-Token", "s3cret")
+	req.Header.Set("User-Agent", "nosctl/1.0")
+	req.Header.Set("Content-Length", "2")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := accessLogLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("want one line, got %s", buf.String())
+	}
+	l := lines[0]
+	want := map[string]any{
+		"level": "info", "message": "http", "method": "POST", "path": "/api/v1/shares",
+		"status": float64(201), "bytes": float64(5), "ip": "192.0.2.7",
+		"content_length": float64(2), "user_agent": "nosctl/1.0",
+	}
+	for k, v := range want {
+		if l[k] != v {
+			t.Errorf("%s = %v, want %v", k, l[k], v)
+		}
+	}
+	if _, ok := l["duration"].(float64); !ok {
+		t.Errorf("duration missing: %v", l)
+	}
+	if id, _ := l["request_id"].(string); id == "" {
+		t.Errorf("request_id missing: %v", l)
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Fatalf("credentials logged: %s", buf.String())
+	}
+	headers, _ := l["headers"].(map[string]any)
+	if headers["Authorization"] != "[redacted]" || headers["Cookie"] != "[redacted]" || headers["X-Csrf-Token"] != "[redacted]" {
+		t.Fatalf("headers not redacted: %v", headers)
+	}
+
+	// headers are only logged at debug
+	buf.Reset()
+	accessLogHandler(t, cfg, zerolog.InfoLevel, &buf).ServeHTTP(httptest.NewRecorder(), req)
+	if lines := accessLogLines(t, &buf); len(lines) != 1 || lines[0]["headers"] != nil {
+		t.Fatalf("info line: %s", buf.String())
+	}
+}
+
+func TestAccessLogSamplingAndLevels(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.Defaults()
+	cfg.AccessLogSample = 3
+	h := accessLogHandler(t, cfg, zerolog.InfoLevel, &buf)
+	serve := func(path string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	for range 6 {
+		serve("/ok")
+	}
+	serve("/missing")
+	serve("/fail")
+
+	var ok, levels []string
+	for _, l := range accessLogLines(t, &buf) {
+		if l["path"] == "/ok" {
+			ok = append(ok, l["path"].(string))
+		}
+		levels = append(levels, l["level"].(string))
+	}
+	if len(ok) != 2 {
+		t.Fatalf("want 2 of 6 successful requests logged, got %d: %s", len(ok), buf.String())
+	}
+	if got := strings.Join(levels, ","); got != "info,info,info,error" {
+		t.Fatalf("levels: %s", got)
+	}
+
+	// a disabled access log still reports server errors
+	buf.Reset()
+	cfg.AccessLogLevel = zerolog.Disabled
+	h = accessLogHandler(t, cfg, zerolog.InfoLevel, &buf)
+	serve("/ok")
+	serve("/missing")
+	serve("/fail")
+	lines := accessLogLines(t, &buf)
+	if len(lines) != 1 || lines[0]["path"] != "/fail" || lines[0]["level"] != "error" {
+		t.Fatalf("disabled access log: %s", buf.String())
+	}
+}
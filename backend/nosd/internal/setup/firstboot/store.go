@@ -14,6 +14,8 @@ type State struct {
 	OTP       string    `json:"otp"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	// FailedAttempts counts wrong codes entered for this OTP
+	FailedAttempts int `json:"failed_attempts,omitempty"`
 }
 
 type Store struct {
@@ -0,0 +1,129 @@
+// Package wizard records which first-run wizard steps are done so the web
+// UI can resume an interrupted setup.
+package wizard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"nithronos/backend/nosd/internal/fsatomic"
+)
+
+// Steps lists the wizard steps in the order the UI walks them.
+var Steps = []string{"admin", "timezone", "network", "storage", "telemetry", "totp"}
+
+var ErrUnknownStep = errors.New("unknown setup step")
+
+type Step struct {
+	ID          string     `json:"id"`
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Progress is the persisted document. Current is the first step not yet
+// done, or "" once every step is.
+type Progress struct {
+	Steps     []Step    `json:"steps"`
+	Current   string    `json:"current"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// Done reports whether step id is marked done.
+func (p Progress) Done(id string) bool {
+	for _, s := range p.Steps {
+		if s.ID == id {
+			return s.Done
+		}
+	}
+	return false
+}
+
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func New(path string) *Store { return &Store{path: path} }
+
+// Load returns the saved progress, with every known step present in order.
+// A missing file means nothing is done yet.
+func (s *Store) Load() (Progress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Set marks step id done or not done and persists the result.
+func (s *Store) Set(id string, done bool) (Progress, error) {
+	if !known(id) {
+		return Progress{}, ErrUnknownStep
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out Progress
+	err := fsatomic.WithLock(s.path, func() error {
+		p, err := s.load()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for i := range p.Steps {
+			if p.Steps[i].ID != id || p.Steps[i].Done == done {
+				continue
+			}
+			p.Steps[i].Done = done
+			p.Steps[i].CompletedAt = nil
+			if done {
+				p.Steps[i].CompletedAt = &now
+			}
+		}
+		p.UpdatedAt = now
+		p.Current = current(p.Steps)
+		if err := fsatomic.SaveJSON(context.TODO(), s.path, p, 0o600); err != nil {
+			return err
+		}
+		out = p
+		return nil
+	})
+	return out, err
+}
+
+func (s *Store) load() (Progress, error) {
+	var saved Progress
+	if _, err := fsatomic.LoadJSON(s.path, &saved); err != nil {
+		return Progress{}, err
+	}
+	p := Progress{UpdatedAt: saved.UpdatedAt}
+	for _, id := range Steps {
+		st := Step{ID: id}
+		for _, old := range saved.Steps {
+			if old.ID == id {
+				st = old
+				break
+			}
+		}
+		p.Steps = append(p.Steps, st)
+	}
+	p.Current = current(p.Steps)
+	return p, nil
+}
+
+func current(steps []Step) string {
+	for _, s := range steps {
+		if !s.Done {
+			return s.ID
+		}
+	}
+	return ""
+}
+
+func known(id string) bool {
+	for _, s := range Steps {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package wizard
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetAdvancesAndPersists(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "setup-progress.json")
+	s := New(p)
+
+	pr, err := s.Load()
+	if err != nil || len(pr.Steps) != len(Steps) || pr.Current != "admin" {
+		t.Fatalf("fresh: %+v %v", pr, err)
+	}
+	for _, id := range []string{"admin", "timezone"} {
+		if pr, err = s.Set(id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pr.Current != "network" || !pr.Done("timezone") || pr.Steps[1].CompletedAt == nil {
+		t.Fatalf("after two steps: %+v", pr)
+	}
+
+	// a new store reads the same document back
+	pr, err = New(p).Load()
+	if err != nil || pr.Current != "network" || !pr.Done("admin") {
+		t.Fatalf("reload: %+v %v", pr, err)
+	}
+
+	// later steps may finish first; current stays at the earliest open one
+	if pr, _ = s.Set("telemetry", true); pr.Current != "network" {
+		t.Fatalf("out of order: %+v", pr)
+	}
+	if pr, _ = s.Set("timezone", false); pr.Current != "timezone" || pr.Steps[1].CompletedAt != nil {
+		t.Fatalf("undo: %+v", pr)
+	}
+
+	if _, err := s.Set("bogus", true); err != ErrUnknownStep {
+		t.Fatalf("unknown step: %v", err)
+	}
+}
@@ -17,16 +17,17 @@ import (
 
 var ErrNoLsblk = errors.New("lsblk not found")
 
-// ListCandidates runs lsblk and returns filtered candidate disks.
-func ListCandidates(ctx context.Context) ([]Device, error) {
+// ListCandidates runs lsblk and returns filtered candidate disks. When the
+// agent is listening on agentSocket its restricted lsblk endpoint is preferred.
+func ListCandidates(ctx context.Context, agentSocket string) ([]Device, error) {
 	if _, err := exec.LookPath("lsblk"); err != nil {
 		return []Device{}, ErrNoLsblk
 	}
 	var tree rawTree
 	// Prefer agent (restricted allowlist) when available
 	if runtime.GOOS != "windows" {
-		if _, err := os.Stat("/run/nos-agent.sock"); err == nil {
-			client := agentclient.New("/run/nos-agent.sock")
+		if _, err := os.Stat(agentSocket); agentSocket != "" && err == nil {
+			client := agentclient.New(agentSocket)
 			if err := client.GetJSON(ctx, "/v1/storage/lsblk", &tree); err == nil {
 				goto HAVE_TREE
 			}
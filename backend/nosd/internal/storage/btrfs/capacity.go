@@ -0,0 +1,94 @@
+package btrfs
+
+import (
+	"sort"
+	"strings"
+)
+
+// MinDevices is the number of devices a profile needs to allocate new
+// chunks; 0 for an unknown profile.
+func MinDevices(profile string) int {
+	switch strings.ToLower(profile) {
+	case "single", "dup":
+		return 1
+	case "raid0", "raid1", "raid5":
+		return 2
+	case "raid1c3", "raid6":
+		return 3
+	case "raid1c4", "raid10":
+		return 4
+	}
+	return 0
+}
+
+// UsableBytes estimates how much data a profile can store on devices of the
+// given sizes. Chunks are placed on the devices with the most free space, so
+// a device much larger than the rest can't be fully used by mirrored or
+// striped profiles. raid10 is approximated as raid1; the result ignores
+// metadata overhead. An unknown profile yields 0.
+func UsableBytes(profile string, sizes []int64) int64 {
+	n := len(sizes)
+	if n == 0 || n < MinDevices(profile) {
+		return 0
+	}
+	var total int64
+	for _, s := range sizes {
+		total += s
+	}
+	switch strings.ToLower(profile) {
+	case "single":
+		return total
+	case "dup":
+		return total / 2
+	case "raid0":
+		// every chunk needs at least two devices with free space
+		return spread(sizes, total, 2) * 2
+	case "raid1", "raid10":
+		return spread(sizes, total, 2)
+	case "raid1c3":
+		return spread(sizes, total, 3)
+	case "raid1c4":
+		return spread(sizes, total, 4)
+	case "raid5":
+		return striped(sizes, 1, 2)
+	case "raid6":
+		return striped(sizes, 2, 3)
+	}
+	return 0
+}
+
+// striped returns the data capacity of a parity profile. Each chunk is
+// striped across every device that still has free space, parity of them
+// holding parity, until fewer than min devices are left; so the space is
+// used in tiers, the smallest remaining device bounding each tier.
+func striped(sizes []int64, parity, min int) int64 {
+	asc := append([]int64(nil), sizes...)
+	sort.Slice(asc, func(i, j int) bool { return asc[i] < asc[j] })
+	var data, used int64
+	for i, s := range asc {
+		width := len(asc) - i
+		if width < min {
+			break
+		}
+		data += (s - used) * int64(width-parity)
+		used = s
+	}
+	return data
+}
+
+// spread returns how many bytes can be written when each byte needs a
+// slot on k distinct devices: the minimum over j < k of (total minus the j
+// largest devices) / (k - j).
+func spread(sizes []int64, total int64, k int) int64 {
+	desc := append([]int64(nil), sizes...)
+	sort.Slice(desc, func(i, j int) bool { return desc[i] > desc[j] })
+	best := total / int64(k)
+	rest := total
+	for j := 0; j < k-1 && j < len(desc); j++ {
+		rest -= desc[j]
+		if v := rest / int64(k-j-1); v < best {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,41 @@
+package btrfs
+
+import "testing"
+
+func TestUsableBytes(t *testing.T) {
+	const tb = int64(1) << 40
+	cases := []struct {
+		profile string
+		sizes   []int64
+		want    int64
+	}{
+		{"single", []int64{tb, 2 * tb}, 3 * tb},
+		{"dup", []int64{tb}, tb / 2},
+		{"raid0", []int64{tb, tb, tb}, 3 * tb},
+		{"raid0", []int64{3 * tb, tb}, 2 * tb}, // the last 2 TB would sit on one device
+		{"raid0", []int64{tb}, 0},
+		{"raid1", []int64{tb, tb}, tb},
+		{"raid1", []int64{tb, tb, tb}, 3 * tb / 2},
+		{"raid1", []int64{4 * tb, tb, tb}, 2 * tb}, // capped by the two small devices
+		{"raid1", []int64{tb}, 0},
+		{"raid10", []int64{tb, tb, tb, tb}, 2 * tb},
+		{"raid10", []int64{tb, tb, tb}, 0},
+		{"raid1c3", []int64{tb, tb, tb}, tb},
+		{"raid1c3", []int64{3 * tb, tb, tb, tb}, 3 * tb / 2}, // every copy set needs two of the small devices
+		{"raid1c3", []int64{tb, tb}, 0},
+		{"raid1c4", []int64{tb, tb, tb, tb}, tb},
+		{"raid1c4", []int64{2 * tb, 2 * tb, 2 * tb, 2 * tb, 2 * tb, 2 * tb}, 3 * tb},
+		{"raid5", []int64{tb, tb, tb}, 2 * tb},
+		{"raid5", []int64{2 * tb, 2 * tb, tb}, 3 * tb}, // 2 TB across all three, then 1 TB on the large pair
+		{"raid5", []int64{tb}, 0},
+		{"raid6", []int64{tb, tb, tb, tb}, 2 * tb},
+		{"raid6", []int64{3 * tb, tb, tb}, tb}, // nothing left once two devices are full
+		{"raid6", []int64{tb, tb}, 0},
+		{"raid7", []int64{tb, tb, tb}, 0},
+	}
+	for _, c := range cases {
+		if got := UsableBytes(c.profile, c.sizes); got != c.want {
+			t.Errorf("%s %v: got %d, want %d", c.profile, c.sizes, got, c.want)
+		}
+	}
+}
@@ -26,10 +26,23 @@ type PlanStep struct {
 }
 
 type DevicePlan struct {
-	PlanID          string     `json:"planId"`
-	Steps           []PlanStep `json:"steps"`
-	Warnings        []string   `json:"warnings"`
-	RequiresBalance bool       `json:"requiresBalance,omitempty"`
+	PlanID          string           `json:"planId"`
+	Steps           []PlanStep       `json:"steps"`
+	Warnings        []string         `json:"warnings"`
+	RequiresBalance bool             `json:"requiresBalance,omitempty"`
+	Preview         *CapacityPreview `json:"preview,omitempty"`
+}
+
+// CapacityPreview compares the pool before and after a device change.
+// Total is raw device capacity; usable is what the data profile can store.
+type CapacityPreview struct {
+	DataProfile        string `json:"dataProfile"`
+	MetaProfile        string `json:"metaProfile"`
+	Devices            int    `json:"devices"`
+	TotalBytes         int64  `json:"totalBytes"`
+	UsableBytes        int64  `json:"usableBytes"`
+	CurrentTotalBytes  int64  `json:"currentTotalBytes"`
+	CurrentUsableBytes int64  `json:"currentUsableBytes"`
 }
 
 // Planner is a minimal facade for tests; the real implementation should inspect pool state.
@@ -41,6 +54,7 @@ type Planner struct {
 	CurrentProfileMeta string
 	DeviceSizes        map[string]int64 // path -> size bytes
 	PoolUsedPct        float64          // 0..100
+	PoolUsedBytes      int64            // data currently stored, for remove fit checks
 	MountReadWrite     bool             // true if RW
 	Degraded           bool             // degraded state
 	SizeThresholdPct   float64          // e.g., 0.90
@@ -95,14 +109,7 @@ func (p Planner) Plan(req DevicePlanRequest) (DevicePlan, error) {
 				return plan, fmt.Errorf("device too small: %s", d)
 			}
 		}
-		profD := req.TargetProfile.Data
-		profM := req.TargetProfile.Meta
-		if profD == "" {
-			profD = p.CurrentProfileData
-		}
-		if profM == "" {
-			profM = p.CurrentProfileMeta
-		}
+		profD, profM := p.targetProfiles(req)
 		cmdAdd := "btrfs device add " + strings.Join(quoteAll(add), " ") + " " + shellQuote(mount)
 		plan.Steps = append(plan.Steps, PlanStep{ID: "dev-add", Description: "add devices", Command: cmdAdd, Destructive: true})
 		cmdBal := fmt.Sprintf("btrfs balance start -dconvert=%s -mconvert=%s %s", profD, profM, shellQuote(mount))
@@ -114,20 +121,54 @@ func (p Planner) Plan(req DevicePlanRequest) (DevicePlan, error) {
 		if strings.ToLower(profD) == "single" && strings.ToLower(p.CurrentProfileData) != "single" {
 			plan.Warnings = append(plan.Warnings, "Target profile will be single; redundancy reduced.")
 		}
+		after := append(append([]string{}, p.ExistingDevices...), add...)
+		for _, prof := range []string{profD, profM} {
+			if min := MinDevices(prof); len(after) < min {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s needs at least %d devices; the pool will have %d.", prof, min, len(after)))
+				break
+			}
+		}
+		plan.Preview = p.preview(after, profD, profM)
 	case "remove":
 		rem := unique(req.Devices.Remove)
 		if len(rem) == 0 {
 			return plan, fmt.Errorf("no devices to remove")
 		}
+		for _, d := range rem {
+			if !p.contains(d) {
+				return plan, fmt.Errorf("device not in pool: %s", d)
+			}
+		}
+		profD, profM := p.targetProfiles(req)
+		left := len(p.ExistingDevices) - len(rem)
+		if left < 1 {
+			return plan, RemoveRedundancyError{Reason: "cannot remove every device"}
+		}
 		// Safety: redundancy
-		if p.CurrentProfileData == "single" && len(p.ExistingDevices)-len(rem) < 1 && !req.Force {
-			return plan, RemoveRedundancyError{Reason: "insufficient redundancy for removal"}
+		for _, prof := range []string{profD, profM} {
+			if min := MinDevices(prof); left < min {
+				if !req.Force {
+					return plan, RemoveRedundancyError{Reason: fmt.Sprintf("removing would leave %d device(s), below the %s minimum of %d", left, prof, min)}
+				}
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("Removing leaves %d device(s), below the %s minimum of %d; new writes may fail.", left, prof, min))
+				break
+			}
 		}
-		if p.CurrentProfileData == "raid1" && len(p.ExistingDevices)-len(rem) < 2 && !req.Force {
-			return plan, RemoveRedundancyError{Reason: "cannot shrink raid1 below 2 devices without force"}
+		if !strings.EqualFold(profD, p.CurrentProfileData) || !strings.EqualFold(profM, p.CurrentProfileMeta) {
+			// convert first so the remaining devices satisfy the new profile
+			cmdBal := fmt.Sprintf("btrfs balance start -dconvert=%s -mconvert=%s %s", profD, profM, shellQuote(mount))
+			plan.Steps = append(plan.Steps, PlanStep{ID: "balance", Description: "convert data/metadata profile", Command: cmdBal, Destructive: false})
+			plan.RequiresBalance = true
 		}
 		cmd := "btrfs device remove " + strings.Join(quoteAll(rem), " ") + " " + shellQuote(mount)
 		plan.Steps = append(plan.Steps, PlanStep{ID: "dev-remove", Description: "remove devices", Command: cmd, Destructive: true})
+		plan.Preview = p.preview(p.without(rem), profD, profM)
+		if plan.Preview != nil && p.PoolUsedBytes > plan.Preview.UsableBytes {
+			if !req.Force {
+				return plan, fmt.Errorf("remaining devices can hold %d bytes but the pool stores %d", plan.Preview.UsableBytes, p.PoolUsedBytes)
+			}
+			plan.Warnings = append(plan.Warnings, "Remaining devices are too small for the data on the pool; the removal will fail.")
+		}
 	case "replace":
 		if len(req.Devices.Replace) == 0 {
 			return plan, fmt.Errorf("no replace pairs")
@@ -156,12 +197,79 @@ func (p Planner) Plan(req DevicePlanRequest) (DevicePlan, error) {
 			cmd := fmt.Sprintf("btrfs replace start %s %s %s", shellQuote(old), shellQuote(newd), shellQuote(mount))
 			plan.Steps = append(plan.Steps, PlanStep{ID: fmt.Sprintf("replace-%d", i+1), Description: "replace device", Command: cmd, Destructive: true})
 		}
+		after := append([]string{}, p.ExistingDevices...)
+		for _, pair := range req.Devices.Replace {
+			for i, d := range after {
+				if d == pair["old"] {
+					after[i] = pair["new"]
+				}
+			}
+		}
+		plan.Preview = p.preview(after, p.CurrentProfileData, p.CurrentProfileMeta)
 	default:
 		return plan, fmt.Errorf("invalid action")
 	}
 	return plan, nil
 }
 
+// targetProfiles returns the requested profiles, defaulting to the current.
+func (p Planner) targetProfiles(req DevicePlanRequest) (data, meta string) {
+	data, meta = req.TargetProfile.Data, req.TargetProfile.Meta
+	if data == "" {
+		data = p.CurrentProfileData
+	}
+	if meta == "" {
+		meta = p.CurrentProfileMeta
+	}
+	return data, meta
+}
+
+func (p Planner) without(rem []string) []string {
+	out := []string{}
+	for _, d := range p.ExistingDevices {
+		drop := false
+		for _, r := range rem {
+			drop = drop || r == d
+		}
+		if !drop {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// preview computes capacity before and after; nil when a device size is
+// unknown, since a partial sum would understate the pool.
+func (p Planner) preview(after []string, profD, profM string) *CapacityPreview {
+	sizesOf := func(devs []string) ([]int64, int64, bool) {
+		var sizes []int64
+		var total int64
+		for _, d := range devs {
+			sz, ok := p.DeviceSizes[d]
+			if !ok {
+				return nil, 0, false
+			}
+			sizes = append(sizes, sz)
+			total += sz
+		}
+		return sizes, total, true
+	}
+	cur, curTotal, ok1 := sizesOf(p.ExistingDevices)
+	next, nextTotal, ok2 := sizesOf(after)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	return &CapacityPreview{
+		DataProfile:        profD,
+		MetaProfile:        profM,
+		Devices:            len(after),
+		TotalBytes:         nextTotal,
+		UsableBytes:        UsableBytes(profD, next),
+		CurrentTotalBytes:  curTotal,
+		CurrentUsableBytes: UsableBytes(p.CurrentProfileData, cur),
+	}
+}
+
 // RemoveRedundancyError indicates remove would violate redundancy.
 type RemoveRedundancyError struct{ Reason string }
 
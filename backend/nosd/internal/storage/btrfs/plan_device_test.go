@@ -64,3 +64,79 @@ func TestPlanRemoveRedundancyTypedError(t *testing.T) {
 		t.Fatalf("expected redundancy error")
 	}
 }
+
+func TestPlanAddPreviewsCapacity(t *testing.T) {
+	const gb = int64(1) << 30
+	p := Planner{PoolMount: "/mnt/p", ExistingDevices: []string{"/dev/sda", "/dev/sdb"}, CurrentProfileData: "raid1", CurrentProfileMeta: "raid1",
+		MountReadWrite: true, DeviceSizes: map[string]int64{"/dev/sda": 1000 * gb, "/dev/sdb": 1000 * gb, "/dev/sdc": 2000 * gb}}
+	req := DevicePlanRequest{Action: "add"}
+	req.Devices.Add = []string{"/dev/sdc"}
+	plan, err := p.Plan(req)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	want := CapacityPreview{DataProfile: "raid1", MetaProfile: "raid1", Devices: 3,
+		TotalBytes: 4000 * gb, UsableBytes: 2000 * gb, CurrentTotalBytes: 2000 * gb, CurrentUsableBytes: 1000 * gb}
+	if plan.Preview == nil || *plan.Preview != want || !plan.RequiresBalance {
+		t.Fatalf("preview = %+v, want %+v", plan.Preview, want)
+	}
+
+	// converting to raid0 on the way doubles usable space
+	req.TargetProfile.Data = "raid0"
+	plan, err = p.Plan(req)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if plan.Preview.UsableBytes != 4000*gb || plan.Preview.DataProfile != "raid0" {
+		t.Fatalf("raid0 preview: %+v", plan.Preview)
+	}
+}
+
+func TestPlanRemovePreviewsCapacityAndMinimum(t *testing.T) {
+	const gb = int64(1) << 30
+	p := Planner{PoolMount: "/mnt/p", ExistingDevices: []string{"/dev/sda", "/dev/sdb", "/dev/sdc"}, CurrentProfileData: "raid1", CurrentProfileMeta: "raid1",
+		PoolUsedBytes: 500 * gb, DeviceSizes: map[string]int64{"/dev/sda": 1000 * gb, "/dev/sdb": 1000 * gb, "/dev/sdc": 1000 * gb}}
+	req := DevicePlanRequest{Action: "remove"}
+	req.Devices.Remove = []string{"/dev/sdc"}
+	plan, err := p.Plan(req)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if pv := plan.Preview; pv == nil || pv.Devices != 2 || pv.TotalBytes != 2000*gb || pv.UsableBytes != 1000*gb || pv.CurrentUsableBytes != 1500*gb {
+		t.Fatalf("unexpected preview: %+v", plan.Preview)
+	}
+	if plan.RequiresBalance || len(plan.Warnings) != 0 {
+		t.Fatalf("plain remove needs no balance: %+v", plan)
+	}
+
+	// too much data for what's left
+	p.PoolUsedBytes = 1200 * gb
+	if _, err := p.Plan(req); err == nil {
+		t.Fatal("expected remove to be refused when data doesn't fit")
+	}
+	p.PoolUsedBytes = 500 * gb
+
+	// dropping below raid1's minimum is refused, and only warns with force
+	req.Devices.Remove = []string{"/dev/sdb", "/dev/sdc"}
+	if _, err := p.Plan(req); err == nil {
+		t.Fatal("expected redundancy error")
+	} else if _, ok := err.(RemoveRedundancyError); !ok {
+		t.Fatalf("expected RemoveRedundancyError, got %T", err)
+	}
+	req.Force = true
+	plan, err = p.Plan(req)
+	if err != nil || len(plan.Warnings) == 0 {
+		t.Fatalf("forced remove should warn: %v %+v", err, plan.Warnings)
+	}
+
+	// converting to single first is allowed and needs a balance
+	req.Force = false
+	req.TargetProfile.Data, req.TargetProfile.Meta = "single", "dup"
+	plan, err = p.Plan(req)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if !plan.RequiresBalance || plan.Steps[0].ID != "balance" || plan.Preview.UsableBytes != 1000*gb || plan.Preview.MetaProfile != "dup" {
+		t.Fatalf("unexpected convert plan: %+v %+v", plan.Steps, plan.Preview)
+	}
+}
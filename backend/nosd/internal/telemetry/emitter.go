@@ -0,0 +1,202 @@
+// Package telemetry sends opt-in, anonymous usage reports. Nothing is
+// collected or sent unless the admin has granted consent, and revoking
+// consent drops everything queued and aborts a send in flight.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval  = 24 * time.Hour
+	DefaultBatchSize = 7
+	// maxPending bounds the queue while the endpoint is unreachable
+	maxPending = 4 * DefaultBatchSize
+)
+
+// Report is one sample, and exactly what is sent for it: no hostnames,
+// addresses, usernames or paths.
+type Report struct {
+	// InstallID is random, generated on this machine; it only lets the
+	// receiver tell installs apart
+	InstallID   string         `json:"install_id"`
+	Version     string         `json:"version"`
+	OS          string         `json:"os"`
+	Arch        string         `json:"arch"`
+	Counts      map[string]int `json:"counts"`
+	CollectedAt time.Time      `json:"collected_at"`
+}
+
+// Emitter samples a Report every Interval while consent is granted and
+// POSTs them to Endpoint in batches of BatchSize as {"reports":[...]}.
+type Emitter struct {
+	Endpoint  string
+	Interval  time.Duration
+	BatchSize int
+	Collect   func(ctx context.Context) Report
+	Client    *http.Client
+
+	mu      sync.Mutex
+	enabled bool
+	batch   []Report
+	// gen changes on every consent change so work started under an older
+	// consent is discarded
+	gen    uint64
+	cancel context.CancelFunc
+}
+
+// New returns an emitter with the default interval and batch size.
+func New(endpoint string, enabled bool, collect func(ctx context.Context) Report) *Emitter {
+	return &Emitter{
+		Endpoint:  endpoint,
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+		Collect:   collect,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		enabled:   enabled,
+	}
+}
+
+// Enabled reports whether consent is currently granted.
+func (e *Emitter) Enabled() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enabled
+}
+
+// SetConsent applies a consent change immediately. Revoking drops queued
+// reports and cancels a send in flight.
+func (e *Emitter) SetConsent(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.enabled == enabled {
+		return
+	}
+	e.enabled = enabled
+	e.gen++
+	if !enabled {
+		e.batch = nil
+		if e.cancel != nil {
+			e.cancel()
+			e.cancel = nil
+		}
+	}
+}
+
+// Preview returns the report the next sample would produce, without
+// queueing it. It works with or without consent.
+func (e *Emitter) Preview(ctx context.Context) Report {
+	return e.Collect(ctx)
+}
+
+// Pending returns a copy of the reports queued for the next send.
+func (e *Emitter) Pending() []Report {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Report(nil), e.batch...)
+}
+
+// Tick takes one sample and sends the batch once it is full. Without
+// consent or an endpoint it does nothing at all.
+func (e *Emitter) Tick(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.enabled || e.Endpoint == "" {
+		e.mu.Unlock()
+		return nil
+	}
+	gen := e.gen
+	e.mu.Unlock()
+
+	rep := e.Collect(ctx)
+
+	e.mu.Lock()
+	if !e.enabled || e.gen != gen {
+		e.mu.Unlock()
+		return nil
+	}
+	e.batch = append(e.batch, rep)
+	if len(e.batch) < e.batchSize() {
+		e.mu.Unlock()
+		return nil
+	}
+	out := e.batch
+	e.batch = nil
+	sendCtx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+	e.mu.Unlock()
+
+	err := e.send(sendCtx, out)
+	cancel()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancel = nil
+	if err != nil && e.enabled && e.gen == gen {
+		// keep the newest reports for the next attempt
+		e.batch = append(out, e.batch...)
+		if len(e.batch) > maxPending {
+			e.batch = e.batch[len(e.batch)-maxPending:]
+		}
+	}
+	return err
+}
+
+// Run calls Tick every Interval until ctx ends. It returns at once when no
+// endpoint is configured.
+func (e *Emitter) Run(ctx context.Context) {
+	if e.Endpoint == "" {
+		return
+	}
+	iv := e.Interval
+	if iv <= 0 {
+		iv = DefaultInterval
+	}
+	t := time.NewTicker(iv)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_ = e.Tick(ctx)
+		}
+	}
+}
+
+func (e *Emitter) batchSize() int {
+	if e.BatchSize > 0 {
+		return e.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+func (e *Emitter) send(ctx context.Context, reports []Report) error {
+	body, err := json.Marshal(map[string]any{"reports": reports})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry endpoint: %s", res.Status)
+	}
+	return nil
+}
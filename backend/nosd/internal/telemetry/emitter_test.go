@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testEmitter(t *testing.T, enabled bool, handler http.HandlerFunc) (*Emitter, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var collected, posted atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	e := New(srv.URL, enabled, func(ctx context.Context) Report {
+		collected.Add(1)
+		return Report{InstallID: "test", Version: "1.0", Counts: map[string]int{"users": 1}, CollectedAt: time.Now()}
+	})
+	e.BatchSize = 2
+	return e, &collected, &posted
+}
+
+func TestNoEmissionWithoutConsent(t *testing.T) {
+	e, collected, posted := testEmitter(t, false, func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 5; i++ {
+		if err := e.Tick(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if collected.Load() != 0 || posted.Load() != 0 || len(e.Pending()) != 0 {
+		t.Fatalf("without consent: collected=%d posted=%d pending=%d", collected.Load(), posted.Load(), len(e.Pending()))
+	}
+	// preview reads the same data but never queues or sends it
+	if rep := e.Preview(context.Background()); rep.Version != "1.0" || len(e.Pending()) != 0 || posted.Load() != 0 {
+		t.Fatalf("preview: %+v", rep)
+	}
+}
+
+func TestEmitterBatchesWithConsent(t *testing.T) {
+	var got []Report
+	e, _, posted := testEmitter(t, true, func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Reports []Report }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body.Reports...)
+	})
+	_ = e.Tick(context.Background())
+	if posted.Load() != 0 || len(e.Pending()) != 1 {
+		t.Fatalf("first sample should be queued: posted=%d pending=%d", posted.Load(), len(e.Pending()))
+	}
+	if err := e.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if posted.Load() != 1 || len(got) != 2 || len(e.Pending()) != 0 {
+		t.Fatalf("full batch: posted=%d reports=%d pending=%d", posted.Load(), len(got), len(e.Pending()))
+	}
+}
+
+func TestRevokeDropsQueueAndStops(t *testing.T) {
+	e, collected, posted := testEmitter(t, true, func(w http.ResponseWriter, r *http.Request) {})
+	_ = e.Tick(context.Background())
+	e.SetConsent(false)
+	if len(e.Pending()) != 0 {
+		t.Fatalf("queued reports kept after revoke")
+	}
+	before := collected.Load()
+	_ = e.Tick(context.Background())
+	_ = e.Tick(context.Background())
+	if collected.Load() != before || posted.Load() != 0 {
+		t.Fatalf("emitted after revoke: collected=%d posted=%d", collected.Load(), posted.Load())
+	}
+}
+
+func TestRevokeCancelsSendInFlight(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	e, _, _ := testEmitter(t, true, func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+	_ = e.Tick(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Tick(context.Background()) }()
+	<-entered
+	e.SetConsent(false)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("send should have been aborted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("send not cancelled on revoke")
+	}
+	if len(e.Pending()) != 0 {
+		t.Fatalf("aborted batch requeued after revoke")
+	}
+}
@@ -25,11 +25,14 @@ import (
 	"nithronos/backend/nosd/internal/shares"
 )
 
+const configPath = "/etc/nos/config.yaml"
+
 func main() {
-	cfg := config.Load("/etc/nos/config.yaml")
-	server.SetRuntimeCORSOrigin(cfg.CORSOrigin)
-	server.SetRuntimeTrustProxy(cfg.TrustProxy)
-	server.SetLogLevel(cfg.LogLevel)
+	cfg, problems := config.LoadFile(configPath)
+	if !logConfigProblems(cfg, problems) {
+		os.Exit(1)
+	}
+	applyRuntimeConfig(cfg)
 	ensureSecret(cfg.SecretPath)
 	ensureAgentToken("/etc/nos/agent-token")
 
@@ -69,22 +72,20 @@ func main() {
 
 	server.Logger(cfg).Info().Msgf("nosd listening on http://%s", cfg.Bind)
 
-	go func() {
-		// SIGHUP hot reload (Unix only)
-		if runtime.GOOS != "windows" {
-			ch := make(chan os.Signal, 1)
-			signal.Notify(ch)
-			for range ch {
-				old := cfg
-				cfg = config.Load("/etc/nos/config.yaml")
-				// Apply safe fields
-				server.SetRuntimeCORSOrigin(cfg.CORSOrigin)
-				server.SetRuntimeTrustProxy(cfg.TrustProxy)
-				server.SetLogLevel(cfg.LogLevel)
-				logConfigDiff(old, cfg)
-			}
-		}
-	}()
+	// SIGHUP hot reload (Unix only)
+	if runtime.GOOS != "windows" {
+		stopReload := startReloader(cfg, configPath)
+		defer stopReload()
+	}
+
+	// pool usage history for capacity forecasts
+	server.StartPoolUsageSampler(ctx)
+	// periodic SMART samples for per-device trends
+	server.StartSmartScanner(ctx, cfg)
+	// roll back an update whose boot isn't confirmed in time
+	server.StartBootGuard(cfg)
+	// revert interface changes left unconfirmed before a restart
+	server.StartInterfaceRollback(cfg)
 
 	errCh := make(chan error, 1)
 	go func() { errCh <- srv.ListenAndServe() }()
@@ -100,9 +101,10 @@ func main() {
 		_ = sess.Flush()
 		sessMs := time.Since(t1).Milliseconds()
 		sdCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		drained := server.DrainStreams(sdCtx)
 		_ = srv.Shutdown(sdCtx)
 		cancel()
-		server.Logger(cfg).Info().Msgf("shutdown: http done; ratelimit=%dms sessions=%dms total=%dms", rlMs, sessMs, time.Since(start).Milliseconds())
+		server.Logger(cfg).Info().Msgf("shutdown: http done; streams=%d ratelimit=%dms sessions=%dms total=%dms", drained, rlMs, sessMs, time.Since(start).Milliseconds())
 	case err := <-errCh:
 		if err != nil && err != http.ErrServerClosed {
 			server.Logger(cfg).Fatal().Err(err).Msg("server exited")
@@ -110,6 +112,71 @@ func main() {
 	}
 }
 
+// logConfigProblems logs each config problem and reports whether the config
+// is usable (no fatal problems).
+func logConfigProblems(cfg config.Config, problems []config.Problem) bool {
+	for _, p := range problems {
+		ev := server.Logger(cfg).Warn()
+		if p.Fatal {
+			ev = server.Logger(cfg).Error()
+		}
+		ev.Str("event", "config.invalid").Str("field", p.Field).Bool("fatal", p.Fatal).Msg(p.Message)
+	}
+	return !config.HasFatal(problems)
+}
+
+// applyRuntimeConfig pushes the hot-reloadable fields into the running
+// server: CORS origins, trusted proxies, log level, rate-limit thresholds, the
+// metrics allowlist, the pprof toggle and the maintenance window. Everything
+// else (bind address, paths, metrics.enabled, session TTLs, argon2 cost, agent
+// socket, SMTP, updates) is read once at startup and needs a restart.
+func applyRuntimeConfig(cfg config.Config) {
+	server.SetRuntimeCORSOrigins(cfg.AllowedOrigins())
+	server.SetRuntimeTrustedProxies(cfg.TrustedProxyNets())
+	server.SetLogLevel(cfg.LogLevel)
+	server.SetRuntimeRateLimits(cfg)
+	server.SetRuntimeMetrics(cfg.MetricsAllowlist, cfg.PprofEnabled)
+	server.SetRuntimeMaintenanceWindow(cfg.MaintenanceWindow)
+}
+
+// reloadSignals trigger a config reload. Only SIGHUP: SIGINT/SIGTERM belong
+// to the shutdown context, and subscribing to every signal would reload on
+// unrelated ones (SIGURG from the runtime, SIGCHLD, ...).
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// startReloader reloads path on each reload signal until the returned stop
+// function is called.
+func startReloader(cfg config.Config, path string) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, reloadSignals...)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cur := cfg
+		for range ch {
+			cur = reloadConfig(cur, path)
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(ch)
+		<-done
+	}
+}
+
+// reloadConfig re-reads path and applies it on top of old, returning the
+// config now in effect. A config with fatal problems is rejected.
+func reloadConfig(old config.Config, path string) config.Config {
+	next, problems := config.LoadFile(path)
+	if !logConfigProblems(next, problems) {
+		server.Logger(old).Error().Str("event", "config.reload.rejected").Msg("keeping previous configuration")
+		return old
+	}
+	applyRuntimeConfig(next)
+	logConfigDiff(old, next)
+	return next
+}
+
 func logConfigDiff(old, cur config.Config) {
 	// minimal diff of hot-reloadable fields
 	if old.CORSOrigin != cur.CORSOrigin {
@@ -118,9 +185,38 @@ func logConfigDiff(old, cur config.Config) {
 	if old.TrustProxy != cur.TrustProxy {
 		server.Logger(cur).Info().Str("event", "config.reload").Str("field", "trustProxy").Bool("old", old.TrustProxy).Bool("new", cur.TrustProxy).Msg("")
 	}
+	if strings.Join(old.TrustedProxies, ",") != strings.Join(cur.TrustedProxies, ",") {
+		server.Logger(cur).Info().Str("event", "config.reload").Str("field", "trustedProxies").Strs("old", old.TrustedProxies).Strs("new", cur.TrustedProxies).Msg("")
+	}
 	if old.LogLevel != cur.LogLevel {
 		server.Logger(cur).Info().Str("event", "config.reload").Str("field", "logLevel").Str("old", old.LogLevel.String()).Str("new", cur.LogLevel.String()).Msg("")
 	}
+	for _, f := range []struct {
+		name     string
+		old, cur int
+	}{
+		{"rate.otpPerMin", old.RateOTPPerMin, cur.RateOTPPerMin},
+		{"rate.otpWindowSec", old.RateOTPWindowSec, cur.RateOTPWindowSec},
+		{"rate.loginPer15m", old.RateLoginPer15m, cur.RateLoginPer15m},
+		{"rate.loginWindowSec", old.RateLoginWindowSec, cur.RateLoginWindowSec},
+		{"rate.otpMaxAttempts", old.RateOTPMaxAttempts, cur.RateOTPMaxAttempts},
+	} {
+		if f.old != f.cur {
+			server.Logger(cur).Info().Str("event", "config.reload").Str("field", f.name).Int("old", f.old).Int("new", f.cur).Msg("")
+		}
+	}
+	if strings.Join(old.CORSOrigins, ",") != strings.Join(cur.CORSOrigins, ",") {
+		server.Logger(cur).Info().Str("event", "config.reload").Str("field", "cors.origins").Strs("old", old.CORSOrigins).Strs("new", cur.CORSOrigins).Msg("")
+	}
+	if strings.Join(old.MetricsAllowlist, ",") != strings.Join(cur.MetricsAllowlist, ",") {
+		server.Logger(cur).Info().Str("event", "config.reload").Str("field", "metrics.allowlist").Strs("old", old.MetricsAllowlist).Strs("new", cur.MetricsAllowlist).Msg("")
+	}
+	if old.PprofEnabled != cur.PprofEnabled {
+		server.Logger(cur).Info().Str("event", "config.reload").Str("field", "metrics.pprof").Bool("old", old.PprofEnabled).Bool("new", cur.PprofEnabled).Msg("")
+	}
+	if old.MetricsEnabled != cur.MetricsEnabled || old.Bind != cur.Bind {
+		server.Logger(cur).Warn().Str("event", "config.reload").Msg("metrics.enabled and http.bind changes take effect after restart")
+	}
 }
 
 func ensureSecret(path string) {
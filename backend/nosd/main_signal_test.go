@@ -0,0 +1,55 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/server"
+)
+
+func TestOnlySIGHUPTriggersReload(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("rate:\n  otpPerMin: 42\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Defaults()
+	applyRuntimeConfig(cfg)
+	stop := startReloader(cfg, cfgPath)
+	defer stop()
+
+	// SIGUSR1 would kill the test binary unless someone else catches it
+	other := make(chan os.Signal, 1)
+	signal.Notify(other, syscall.SIGUSR1)
+	defer signal.Stop(other)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-other:
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGUSR1 not delivered")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := server.RuntimeRateLimits().OTPPerMin; got != cfg.RateOTPPerMin {
+		t.Fatalf("unrelated signal reloaded config: otpPerMin=%d", got)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for server.RuntimeRateLimits().OTPPerMin != 42 {
+		if time.Now().After(deadline) {
+			t.Fatal("SIGHUP did not reload config")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -3,12 +3,20 @@ package main
 import (
 	"bytes"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
 
 	"nithronos/backend/nosd/internal/config"
+	"nithronos/backend/nosd/internal/server"
+	"nithronos/backend/nosd/pkg/backup"
 )
 
 func TestEnsureFirstBootOTP_PrintsAndPersists(t *testing.T) {
@@ -47,4 +55,86 @@ func TestEnsureFirstBootOTP_PrintsAndPersists(t *testing.T) {
 	}
 }
 
-// no extra helpers
+func TestReloadConfigAppliesRuntimeFields(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("NOS_USERS_PATH", filepath.Join(dir, "users.json"))
+	t.Setenv("NOS_SESSIONS_PATH", filepath.Join(dir, "sessions.json"))
+	t.Setenv("NOS_RL_PATH", filepath.Join(dir, "ratelimit.json"))
+	t.Setenv("NOS_ETC_DIR", dir)
+	t.Setenv("NOS_APPS_STATE", filepath.Join(dir, "apps.json"))
+	t.Setenv("NOS_DISABLE_APP_EVENTS", "1")
+	cfgPath := filepath.Join(dir, "config.yaml")
+
+	cfg := config.FromEnv()
+	applyRuntimeConfig(cfg)
+	r := server.NewRouter(cfg)
+	pprofStatus := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		req.RemoteAddr = "127.0.0.1:5555"
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res.Code
+	}
+	if code := pprofStatus(); code != http.StatusNotFound {
+		t.Fatalf("pprof before reload: %d", code)
+	}
+
+	data := "rate:\n  otpPerMin: 9\n  otpWindowSec: 120\n  loginPer15m: 11\n  loginWindowSec: 600\n  otpMaxAttempts: 3\n" +
+		"metrics:\n  pprof: true\n  allowlist: [10.1.0.0/16, \"192.168.\"]\n"
+	if err := os.WriteFile(cfgPath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cur := reloadConfig(cfg, cfgPath)
+
+	want := server.RateLimits{OTPPerMin: 9, OTPWindow: 2 * time.Minute, LoginPer15m: 11, LoginWindow: 10 * time.Minute, OTPMaxAttempts: 3}
+	if got := server.RuntimeRateLimits(); got != want {
+		t.Fatalf("rate limits after reload: %+v", got)
+	}
+	if got := strings.Join(server.RuntimeMetricsAllowlist(), ","); got != "10.1.0.0/16,192.168." {
+		t.Fatalf("metrics allowlist after reload: %s", got)
+	}
+	if code := pprofStatus(); code != http.StatusOK {
+		t.Fatalf("pprof after reload: %d", code)
+	}
+
+	// a broken file is rejected and the running values are kept
+	if err := os.WriteFile(cfgPath, []byte("rate: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if kept := reloadConfig(cur, cfgPath); kept.RateOTPPerMin != 9 {
+		t.Fatalf("rejected reload replaced config: %+v", kept)
+	}
+	if got := server.RuntimeRateLimits(); got != want {
+		t.Fatalf("rate limits after rejected reload: %+v", got)
+	}
+}
+
+func TestReloadConfigAppliesMaintenanceWindow(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("NOS_USERS_PATH", filepath.Join(dir, "users.json"))
+	t.Setenv("NOS_ETC_DIR", dir)
+	cfgPath := filepath.Join(dir, "config.yaml")
+
+	cfg := config.FromEnv()
+	applyRuntimeConfig(cfg)
+	t.Cleanup(func() { applyRuntimeConfig(config.Defaults()) })
+	sched := backup.NewScheduler(zerolog.Nop(), filepath.Join(dir, "schedules.json"), nil)
+	server.NewBackupHandler(zerolog.Nop(), sched, nil, nil)
+	if sched.MaintenanceWindow().Enabled {
+		t.Fatalf("window enabled before reload: %+v", sched.MaintenanceWindow())
+	}
+
+	data := "maintenance:\n  enabled: true\n  days: [sat]\n  start: \"01:00\"\n  end: \"05:00\"\n  policy: skip\n"
+	if err := os.WriteFile(cfgPath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(cfg, cfgPath)
+
+	got := sched.MaintenanceWindow()
+	if !got.Enabled || strings.Join(got.Days, ",") != "sat" || got.Start != "01:00" || got.End != "05:00" || got.Policy != "skip" {
+		t.Fatalf("scheduler window after reload: %+v", got)
+	}
+	if w := server.RuntimeMaintenanceWindow(); w.Policy != "skip" {
+		t.Fatalf("runtime window after reload: %+v", w)
+	}
+}
@@ -4,39 +4,97 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"time"
+)
+
+// Defaults for per-call timeout and GET retries; see Option.
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultGetRetries   = 2
+	DefaultRetryBackoff = 200 * time.Millisecond
 )
 
 type Client struct {
 	HTTP *http.Client
+
+	timeout      time.Duration
+	getRetries   int
+	retryBackoff time.Duration
+}
+
+// Option customizes a Client.
+type Option func(*Client)
+
+// WithTimeout bounds each PostJSON/GetJSON call (every attempt, for GETs).
+// A value <= 0 disables the timeout, for long-running agent operations.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithGetRetries sets how many times an idempotent GET is retried after a
+// connection failure or 5xx, doubling backoff between attempts. POSTs are
+// never retried.
+func WithGetRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		if n < 0 {
+			n = 0
+		}
+		c.getRetries = n
+		c.retryBackoff = backoff
+	}
 }
 
-func New(socketPath string) *Client {
-	return &Client{
+func New(socketPath string, opts ...Option) *Client {
+	c := &Client{
 		HTTP: &http.Client{
 			Transport: &http.Transport{
 				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return net.Dial("unix", socketPath)
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
 				},
 			},
 		},
+		timeout:      DefaultTimeout,
+		getRetries:   DefaultGetRetries,
+		retryBackoff: DefaultRetryBackoff,
 	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
 }
 
-func (c *Client) PostJSON(ctx context.Context, path string, body any, v any) error {
-	buf := &bytes.Buffer{}
-	if err := json.NewEncoder(buf).Encode(body); err != nil {
-		return err
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// do sends one request and decodes a 2xx JSON body into v.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, v any) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://unix"+path, buf)
+	req, err := http.NewRequestWithContext(ctx, method, "http://unix"+path, rd)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if id := RequestIDFrom(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
 	res, err := c.HTTP.Do(req)
 	if err != nil {
 		return err
@@ -52,25 +110,42 @@ func (c *Client) PostJSON(ctx context.Context, path string, body any, v any) err
 	return nil
 }
 
-// GetJSON performs a GET and decodes JSON into v.
-func (c *Client) GetJSON(ctx context.Context, path string, v any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://unix"+path, nil)
-	if err != nil {
-		return err
-	}
-	res, err := c.HTTP.Do(req)
-	if err != nil {
+func (c *Client) PostJSON(ctx context.Context, path string, body any, v any) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(body); err != nil {
 		return err
 	}
-	defer res.Body.Close()
-	if res.StatusCode >= 300 {
-		b, _ := io.ReadAll(res.Body)
-		return &HTTPError{Status: res.StatusCode, Body: string(b)}
+	return c.do(ctx, http.MethodPost, path, buf.Bytes(), v)
+}
+
+// GetJSON performs a GET and decodes JSON into v. Connection failures and
+// 5xx replies are retried with backoff; the caller's ctx still bounds the
+// whole sequence.
+func (c *Client) GetJSON(ctx context.Context, path string, v any) error {
+	backoff := c.retryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.do(ctx, http.MethodGet, path, nil, v)
+		if err == nil || attempt >= c.getRetries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
-	if v != nil {
-		return json.NewDecoder(res.Body).Decode(v)
+}
+
+// retryable reports transient failures: agent unreachable, per-attempt
+// timeout, or an agent 5xx. Decode errors and 4xx are final.
+func retryable(err error) bool {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Status >= 500
 	}
-	return nil
+	return IsUnavailable(err) || errors.Is(err, context.DeadlineExceeded)
 }
 
 // BalanceStatus represents /v1/btrfs/balance/status response
@@ -111,6 +186,81 @@ func (c *Client) ReplaceStatus(ctx context.Context, mount string) (*ReplaceStatu
 	return &out, nil
 }
 
+// DeviceStats represents one member's counters from /v1/btrfs/device-stats
+type DeviceStats struct {
+	Device         string `json:"device"`
+	WriteIOErrs    uint64 `json:"write_io_errs"`
+	ReadIOErrs     uint64 `json:"read_io_errs"`
+	FlushIOErrs    uint64 `json:"flush_io_errs"`
+	CorruptionErrs uint64 `json:"corruption_errs"`
+	GenerationErrs uint64 `json:"generation_errs"`
+}
+
+// Counters returns the non-zero counters keyed by their btrfs names.
+func (d DeviceStats) Counters() map[string]uint64 {
+	out := map[string]uint64{}
+	for k, v := range map[string]uint64{
+		"write_io_errs":   d.WriteIOErrs,
+		"read_io_errs":    d.ReadIOErrs,
+		"flush_io_errs":   d.FlushIOErrs,
+		"corruption_errs": d.CorruptionErrs,
+		"generation_errs": d.GenerationErrs,
+	} {
+		if v > 0 {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (c *Client) DeviceStats(ctx context.Context, mount string) ([]DeviceStats, error) {
+	var out struct {
+		Devices []DeviceStats `json:"devices"`
+	}
+	q := url.Values{}
+	q.Set("mount", mount)
+	if err := c.GetJSON(ctx, "/v1/btrfs/device-stats?"+q.Encode(), &out); err != nil {
+		return nil, err
+	}
+	return out.Devices, nil
+}
+
+// Subvolume is one entry from /v1/btrfs/snapshot-space; ParentUUID is set
+// for snapshots.
+type Subvolume struct {
+	ID         uint64 `json:"id"`
+	Path       string `json:"path"`
+	UUID       string `json:"uuid"`
+	ParentUUID string `json:"parent_uuid,omitempty"`
+}
+
+// Qgroup is one btrfs qgroup with its referenced and exclusive bytes.
+type Qgroup struct {
+	ID         string `json:"id"`
+	Level      int    `json:"level"`
+	SubvolID   uint64 `json:"subvol_id"`
+	Referenced uint64 `json:"referenced"`
+	Exclusive  uint64 `json:"exclusive"`
+}
+
+// SnapshotSpace is the /v1/btrfs/snapshot-space response.
+type SnapshotSpace struct {
+	Subvolumes   []Subvolume `json:"subvolumes"`
+	Qgroups      []Qgroup    `json:"qgroups"`
+	QuotaEnabled bool        `json:"quota_enabled"`
+	Consistent   bool        `json:"consistent"`
+}
+
+func (c *Client) SnapshotSpace(ctx context.Context, mount string) (*SnapshotSpace, error) {
+	var out SnapshotSpace
+	q := url.Values{}
+	q.Set("mount", mount)
+	if err := c.GetJSON(ctx, "/v1/btrfs/snapshot-space?"+q.Encode(), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // HTTPError captures agent non-2xx responses
 type HTTPError struct {
 	Status int
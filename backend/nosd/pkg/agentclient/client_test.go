@@ -0,0 +1,107 @@
+package agentclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubAgent(t *testing.T, h http.HandlerFunc) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := &http.Server{Handler: h}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return sock
+}
+
+func TestCallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	sock := stubAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	c := New(sock, WithTimeout(50*time.Millisecond), WithGetRetries(0, 0))
+	start := time.Now()
+	err := c.PostJSON(context.Background(), "/v1/slow", map[string]any{}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if el := time.Since(start); el > 2*time.Second {
+		t.Fatalf("timeout not enforced, took %s", el)
+	}
+}
+
+func TestGetRetriesTransientFailures(t *testing.T) {
+	var calls int32
+	sock := stubAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	c := New(sock, WithGetRetries(2, time.Millisecond))
+	var out struct{ OK bool }
+	if err := c.GetJSON(context.Background(), "/v1/status", &out); err != nil {
+		t.Fatalf("expected success after retries: %v", err)
+	}
+	if !out.OK || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("calls=%d out=%+v", calls, out)
+	}
+
+	// retries are bounded
+	atomic.StoreInt32(&calls, -10)
+	err := c.GetJSON(context.Background(), "/v1/status", nil)
+	var he *HTTPError
+	if !errors.As(err, &he) || he.Status != http.StatusServiceUnavailable {
+		t.Fatalf("expected final 503, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != -7 {
+		t.Fatalf("expected 3 attempts, got %d", got+10)
+	}
+}
+
+func TestGetDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	sock := stubAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "nope", http.StatusNotFound)
+	})
+	c := New(sock, WithGetRetries(3, time.Millisecond))
+	if err := c.GetJSON(context.Background(), "/v1/missing", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("4xx retried: %d calls", calls)
+	}
+}
+
+func TestPostIsNotRetried(t *testing.T) {
+	var calls int32
+	sock := stubAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	})
+	c := New(sock, WithGetRetries(3, time.Millisecond))
+	if err := c.PostJSON(context.Background(), "/v1/snapshot/create", map[string]any{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("POST retried: %d calls", calls)
+	}
+}
@@ -0,0 +1,26 @@
+package agentclient
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// IsUnavailable reports whether err means the agent could not be reached at
+// all (socket missing, connection refused, dial failure) as opposed to the
+// agent answering with an error.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) || errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	var op *net.OpError
+	return errors.As(err, &op) && op.Op == "dial"
+}
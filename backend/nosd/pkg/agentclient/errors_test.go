@@ -0,0 +1,46 @@
+package agentclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsUnavailableMissingSocket(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "missing.sock"), WithGetRetries(0, 0))
+	err := c.GetJSON(context.Background(), "/v1/health", nil)
+	if err == nil {
+		t.Fatal("expected dial error")
+	}
+	if !IsUnavailable(err) {
+		t.Fatalf("missing socket should be unavailable: %v", err)
+	}
+}
+
+func TestIsUnavailableHTTPError(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad username", http.StatusBadRequest)
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	err = New(sock).PostJSON(context.Background(), "/v1/smb/user-create", map[string]any{}, nil)
+	var he *HTTPError
+	if !errors.As(err, &he) || he.Status != http.StatusBadRequest {
+		t.Fatalf("expected agent 400, got %v", err)
+	}
+	if IsUnavailable(err) {
+		t.Fatal("agent HTTP error must not be classified as unavailable")
+	}
+	if IsUnavailable(nil) {
+		t.Fatal("nil is not unavailable")
+	}
+}
@@ -0,0 +1,31 @@
+package agentclient
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader carries the originating API request's ID to the agent.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID attaches a request ID to ctx. Use it to carry the ID of an
+// API request into work that outlives it (background jobs using
+// context.Background()).
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID carried by ctx: an explicit
+// WithRequestID value, else the one set by chi's RequestID middleware.
+func RequestIDFrom(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return middleware.GetReqID(ctx)
+}
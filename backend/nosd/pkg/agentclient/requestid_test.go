@@ -0,0 +1,49 @@
+package agentclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRequestIDPropagatedToAgent(t *testing.T) {
+	got := make(chan string, 4)
+	sock := stubAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get(RequestIDHeader)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	c := New(sock)
+
+	// An API handler passing r.Context() forwards chi's request ID.
+	var want string
+	h := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want = middleware.GetReqID(r.Context())
+		if err := c.GetJSON(r.Context(), "/v1/smart", nil); err != nil {
+			t.Error(err)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/smart", nil))
+	if id := <-got; want == "" || id != want {
+		t.Fatalf("agent saw request id %q, want %q", id, want)
+	}
+
+	// Background work carries the ID explicitly.
+	ctx := WithRequestID(context.Background(), "job-123")
+	if err := c.PostJSON(ctx, "/v1/snapshot/create", map[string]any{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-got; id != "job-123" {
+		t.Fatalf("explicit request id: %q", id)
+	}
+
+	// No ID, no header.
+	if err := c.GetJSON(context.Background(), "/v1/smart", nil); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-got; id != "" {
+		t.Fatalf("unexpected request id %q", id)
+	}
+}
@@ -1,14 +1,18 @@
 package apps
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +29,7 @@ type CatalogManager struct {
 	cache       *Catalog
 	sources     []CatalogSource
 	lastSync    time.Time
+	statuses    []CatalogSourceStatus
 }
 
 // NewCatalogManager creates a new catalog manager
@@ -128,30 +133,31 @@ func (cm *CatalogManager) SyncRemoteCatalogs() error {
 	}
 	mergedCatalog.Entries = append(mergedCatalog.Entries, builtin.Entries...)
 
-	// Fetch each remote source
+	// Fetch each remote source; a source failing verification is left out
+	// of the merged catalog (and so the cache) entirely
+	statuses := make([]CatalogSourceStatus, 0, len(cm.sources))
 	for _, source := range cm.sources {
 		if !source.Enabled {
 			continue
 		}
 
-		catalog, err := cm.fetchRemoteCatalog(source)
-		if err != nil {
-			// Log error but continue with other sources
+		st := CatalogSourceStatus{Name: source.Name, URL: source.URL, Verification: sourceVerification(source), CheckedAt: time.Now().UTC()}
+		catalog, err := cm.fetchVerifiedCatalog(source)
+		switch {
+		case errors.Is(err, errCatalogVerification):
+			st.Status, st.Error = SourceRejected, err.Error()
+			fmt.Fprintf(os.Stderr, "Catalog verification failed for %s: %v\n", source.Name, err)
+		case err != nil:
+			st.Status, st.Error = SourceError, err.Error()
 			fmt.Fprintf(os.Stderr, "Failed to fetch catalog from %s: %v\n", source.Name, err)
-			continue
+		default:
+			st.Status, st.Entries = SourceOK, len(catalog.Entries)
+			// Merge entries (later sources can override earlier ones)
+			mergedCatalog.Entries = mergeCatalogEntries(mergedCatalog.Entries, catalog.Entries)
 		}
-
-		// Verify if SHA256 is provided
-		if source.SHA256 != "" {
-			if err := cm.verifyCatalogHash(catalog, source.SHA256); err != nil {
-				fmt.Fprintf(os.Stderr, "Catalog verification failed for %s: %v\n", source.Name, err)
-				continue
-			}
-		}
-
-		// Merge entries (later sources can override earlier ones)
-		mergedCatalog.Entries = mergeCatalogEntries(mergedCatalog.Entries, catalog.Entries)
+		statuses = append(statuses, st)
 	}
+	cm.statuses = statuses
 
 	// Save to cache
 	if err := cm.saveCache(mergedCatalog); err != nil {
@@ -164,11 +170,60 @@ func (cm *CatalogManager) SyncRemoteCatalogs() error {
 	return nil
 }
 
-// fetchRemoteCatalog fetches a catalog from a remote source
-func (cm *CatalogManager) fetchRemoteCatalog(source CatalogSource) (*Catalog, error) {
+// errCatalogVerification marks a catalog rejected by its checksum or signature.
+var errCatalogVerification = errors.New("catalog verification failed")
+
+// SourceStatuses returns the outcome of the last sync for each enabled source.
+func (cm *CatalogManager) SourceStatuses() []CatalogSourceStatus {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return append([]CatalogSourceStatus{}, cm.statuses...)
+}
+
+// sourceVerification names the strongest check configured for a source.
+func sourceVerification(source CatalogSource) string {
+	switch {
+	case source.PublicKey != "":
+		return VerifySignature
+	case source.SHA256 != "":
+		return VerifySHA256
+	}
+	return VerifyNone
+}
+
+// fetchVerifiedCatalog fetches a source's manifest, checks it against the
+// pinned hash and/or signature, and parses it.
+func (cm *CatalogManager) fetchVerifiedCatalog(source CatalogSource) (*Catalog, error) {
+	data, err := cm.fetchRemoteCatalog(source)
+	if err != nil {
+		return nil, err
+	}
+	if source.SHA256 != "" {
+		if err := verifyCatalogHash(data, source.SHA256); err != nil {
+			return nil, fmt.Errorf("%w: %v", errCatalogVerification, err)
+		}
+	}
+	if source.PublicKey != "" {
+		sigURL := source.Signature
+		if sigURL == "" {
+			sigURL = source.URL + ".sig"
+		}
+		sig, err := cm.fetchHTTP(sigURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: signature: %v", errCatalogVerification, err)
+		}
+		if err := verifyCatalogSignature(data, sig, source.PublicKey); err != nil {
+			return nil, fmt.Errorf("%w: %v", errCatalogVerification, err)
+		}
+	}
+	return parseCatalog(data)
+}
+
+// fetchRemoteCatalog fetches the raw catalog manifest from a remote source
+func (cm *CatalogManager) fetchRemoteCatalog(source CatalogSource) ([]byte, error) {
 	switch source.Type {
 	case "http", "https":
-		return cm.fetchHTTPCatalog(source.URL)
+		return cm.fetchHTTP(source.URL)
 	case "git":
 		// TODO: Implement git support
 		return nil, fmt.Errorf("git sources not yet implemented")
@@ -177,8 +232,8 @@ func (cm *CatalogManager) fetchRemoteCatalog(source CatalogSource) (*Catalog, er
 	}
 }
 
-// fetchHTTPCatalog fetches a catalog via HTTP
-func (cm *CatalogManager) fetchHTTPCatalog(url string) (*Catalog, error) {
+// fetchHTTP fetches a URL body
+func (cm *CatalogManager) fetchHTTP(url string) ([]byte, error) {
 	resp, err := cm.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch catalog: %w", err)
@@ -193,7 +248,11 @@ func (cm *CatalogManager) fetchHTTPCatalog(url string) (*Catalog, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	return data, nil
+}
 
+// parseCatalog decodes a catalog manifest
+func parseCatalog(data []byte) (*Catalog, error) {
 	var catalog Catalog
 
 	// Try JSON first, then YAML
@@ -207,23 +266,38 @@ func (cm *CatalogManager) fetchHTTPCatalog(url string) (*Catalog, error) {
 	return &catalog, nil
 }
 
-// verifyCatalogHash verifies the SHA256 hash of a catalog
-func (cm *CatalogManager) verifyCatalogHash(catalog *Catalog, expectedHash string) error {
-	data, err := json.Marshal(catalog)
-	if err != nil {
-		return fmt.Errorf("failed to marshal catalog: %w", err)
-	}
-
+// verifyCatalogHash verifies the SHA256 hash of the manifest bytes as served
+func verifyCatalogHash(data []byte, expectedHash string) error {
 	hash := sha256.Sum256(data)
 	actualHash := hex.EncodeToString(hash[:])
 
-	if actualHash != expectedHash {
+	if !strings.EqualFold(actualHash, strings.TrimSpace(expectedHash)) {
 		return fmt.Errorf("hash mismatch: expected %s, got %s", expectedHash, actualHash)
 	}
 
 	return nil
 }
 
+// verifyCatalogSignature checks a detached ed25519 signature (base64 or raw)
+// over the manifest bytes against a base64 public key.
+func verifyCatalogSignature(data, sig []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key")
+	}
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return fmt.Errorf("malformed signature")
+		}
+		sig = decoded
+	}
+	if len(sig) != ed25519.SignatureSize || !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
 // saveCache saves the merged catalog to cache file
 func (cm *CatalogManager) saveCache(catalog *Catalog) error {
 	// Ensure cache directory exists
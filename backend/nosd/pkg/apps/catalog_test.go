@@ -0,0 +1,125 @@
+package apps
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const remoteCatalog = `{"version":"1.0","entries":[{"id":"gitea","name":"Gitea","version":"1.21","compose":"gitea.yml"}]}`
+
+// catalogServer serves /good.json (the manifest as signed), /tampered.json
+// (same manifest with a swapped image) and a detached signature for each.
+func catalogServer(t *testing.T, priv ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(remoteCatalog)))
+	tampered := strings.Replace(remoteCatalog, "gitea.yml", "evil.yml", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good.json":
+			_, _ = w.Write([]byte(remoteCatalog))
+		case "/tampered.json":
+			_, _ = w.Write([]byte(tampered))
+		case "/good.json.sig", "/tampered.json.sig":
+			_, _ = w.Write([]byte(sig + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func syncSources(t *testing.T, sources ...CatalogSource) (*CatalogManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache", "catalog.json")
+	cm := NewCatalogManager(filepath.Join(dir, "builtin"), cachePath, filepath.Join(dir, "sources"))
+	cm.sources = sources
+	if err := cm.SyncRemoteCatalogs(); err != nil {
+		t.Fatal(err)
+	}
+	return cm, cachePath
+}
+
+func TestCatalogSyncVerifiesPinnedSHA256(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	srv := catalogServer(t, priv)
+	sum := sha256.Sum256([]byte(remoteCatalog))
+	pin := hex.EncodeToString(sum[:])
+
+	cm, cachePath := syncSources(t,
+		CatalogSource{Name: "good", Type: "http", URL: srv.URL + "/good.json", SHA256: pin, Enabled: true},
+		CatalogSource{Name: "tampered", Type: "http", URL: srv.URL + "/tampered.json", SHA256: pin, Enabled: true},
+	)
+
+	st := cm.SourceStatuses()
+	if len(st) != 2 {
+		t.Fatalf("statuses: %+v", st)
+	}
+	if st[0].Status != SourceOK || st[0].Verification != VerifySHA256 || st[0].Entries != 1 {
+		t.Fatalf("good source: %+v", st[0])
+	}
+	if st[1].Status != SourceRejected || !strings.Contains(st[1].Error, "hash mismatch") {
+		t.Fatalf("tampered source: %+v", st[1])
+	}
+
+	entry, err := cm.GetEntry("gitea")
+	if err != nil || entry.Compose != "gitea.yml" {
+		t.Fatalf("expected the verified entry, got %+v %v", entry, err)
+	}
+	cached, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(cached), "evil.yml") {
+		t.Fatal("tampered catalog was cached")
+	}
+}
+
+func TestCatalogSyncVerifiesSignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	srv := catalogServer(t, priv)
+	key := base64.StdEncoding.EncodeToString(pub)
+
+	cm, cachePath := syncSources(t,
+		CatalogSource{Name: "tampered", Type: "http", URL: srv.URL + "/tampered.json", PublicKey: key, Enabled: true},
+	)
+	st := cm.SourceStatuses()
+	if len(st) != 1 || st[0].Status != SourceRejected || st[0].Verification != VerifySignature || !strings.Contains(st[0].Error, "signature mismatch") {
+		t.Fatalf("tampered source: %+v", st)
+	}
+	if _, err := cm.GetEntry("gitea"); err == nil {
+		t.Fatal("tampered entry should not be in the catalog")
+	}
+	cached, _ := os.ReadFile(cachePath)
+	if strings.Contains(string(cached), "evil.yml") {
+		t.Fatal("tampered catalog was cached")
+	}
+
+	cm, _ = syncSources(t,
+		CatalogSource{Name: "good", Type: "http", URL: srv.URL + "/good.json", PublicKey: key, Enabled: true},
+	)
+	if st := cm.SourceStatuses(); len(st) != 1 || st[0].Status != SourceOK {
+		t.Fatalf("good source: %+v", st)
+	}
+	if _, err := cm.GetEntry("gitea"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a source whose signature cannot be fetched is rejected too
+	cm, _ = syncSources(t,
+		CatalogSource{Name: "nosig", Type: "http", URL: srv.URL + "/good.json", Signature: srv.URL + "/missing.sig", PublicKey: key, Enabled: true},
+	)
+	if st := cm.SourceStatuses(); len(st) != 1 || st[0].Status != SourceRejected {
+		t.Fatalf("unsigned source: %+v", st)
+	}
+}
@@ -1,16 +1,21 @@
 package apps
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
 )
 
 // LifecycleManager handles app lifecycle operations
@@ -24,6 +29,16 @@ type LifecycleManager struct {
 	snapshotPath string
 	caddyPath    string
 	eventLogger  EventLogger
+	// runCmd runs helper commands; replaced in tests
+	runCmd func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// ErrNoRollbackPoint is returned by RollbackApp when no snapshot is given
+// and no pre-upgrade rollback point was recorded.
+var ErrNoRollbackPoint = errors.New("no rollback point recorded")
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
 }
 
 // EventLogger interface for logging events
@@ -50,6 +65,7 @@ func NewLifecycleManager(
 		snapshotPath: "/usr/lib/nos/apps/nos-app-snapshot.sh",
 		caddyPath:    "/etc/caddy/Caddyfile.d",
 		eventLogger:  eventLogger,
+		runCmd:       runCommand,
 	}
 }
 
@@ -220,13 +236,27 @@ func (lm *LifecycleManager) UpgradeApp(ctx context.Context, appID string, req Up
 		return fmt.Errorf("failed to create pre-upgrade snapshot: %w", err)
 	}
 
+	// Record exactly what is deployed now so RollbackApp can restore it
+	configDir := filepath.Join(lm.appsRoot, appID, "config")
+	point, err := lm.captureRollbackPoint(ctx, app, configDir, snapshotID)
+	if err != nil {
+		return fmt.Errorf("failed to record rollback point: %w", err)
+	}
+	if err := lm.stateStore.SetRollbackPoint(appID, point); err != nil {
+		return fmt.Errorf("failed to record rollback point: %w", err)
+	}
+	app.RollbackPoint = point
+
 	// Update app state to upgrading
 	if err := lm.stateStore.UpdateAppStatus(appID, StatusUpgrading); err != nil {
 		return fmt.Errorf("failed to update app status: %w", err)
 	}
 
 	// Merge params if provided
-	params := app.Params
+	params := make(map[string]interface{}, len(app.Params))
+	for k, v := range app.Params {
+		params[k] = v
+	}
 	if req.Params != nil {
 		for k, v := range req.Params {
 			params[k] = v
@@ -242,7 +272,6 @@ func (lm *LifecycleManager) UpgradeApp(ctx context.Context, appID string, req Up
 	}
 
 	// Render new compose file
-	configDir := filepath.Join(lm.appsRoot, appID, "config")
 	composeContent, err := lm.renderer.RenderComposeFile(entry, params)
 	if err != nil {
 		if err := lm.stateStore.UpdateAppStatus(appID, StatusError); err != nil {
@@ -420,6 +449,10 @@ func (lm *LifecycleManager) DeleteApp(ctx context.Context, appID string, keepDat
 
 // RollbackApp rolls back an app to a snapshot
 func (lm *LifecycleManager) RollbackApp(ctx context.Context, appID string, snapshotTS string, userID string) error {
+	if snapshotTS == "" {
+		return lm.rollbackToPoint(ctx, appID, userID)
+	}
+
 	lm.logEvent("app.rollback", appID, userID, map[string]interface{}{
 		"snapshot": snapshotTS,
 	})
@@ -446,6 +479,228 @@ func (lm *LifecycleManager) RollbackApp(ctx context.Context, appID string, snaps
 	return lm.stateStore.UpdateAppStatus(appID, StatusRunning)
 }
 
+// rollbackToPoint restores the compose file, env file, data snapshot and
+// recorded version/params captured by the last upgrade, then clears the point.
+func (lm *LifecycleManager) rollbackToPoint(ctx context.Context, appID string, userID string) error {
+	app, err := lm.stateStore.GetApp(appID)
+	if err != nil {
+		return err
+	}
+	point := app.RollbackPoint
+	if point == nil {
+		return ErrNoRollbackPoint
+	}
+
+	lm.logEvent("app.rollback", appID, userID, map[string]interface{}{
+		"to_version": point.Version,
+		"images":     point.Images,
+		"snapshot":   point.SnapshotID,
+	})
+
+	if err := lm.stateStore.UpdateAppStatus(appID, StatusRollback); err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		if err := lm.stateStore.UpdateAppStatus(appID, StatusError); err != nil {
+			fmt.Printf("Failed to update app status: %v\n", err)
+		}
+		return err
+	}
+
+	configDir := filepath.Join(lm.appsRoot, appID, "config")
+	compose, err := lm.pinImages([]byte(point.Compose), parseEnvFile([]byte(point.Env)), point.Images)
+	if err != nil {
+		return fail(fmt.Errorf("failed to pin rollback images: %w", err))
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "docker-compose.yml"), compose, 0600); err != nil {
+		return fail(fmt.Errorf("failed to restore compose file: %w", err))
+	}
+	envPath := filepath.Join(configDir, ".env")
+	if point.Env != "" {
+		if err := os.WriteFile(envPath, []byte(point.Env), 0600); err != nil {
+			return fail(fmt.Errorf("failed to restore env file: %w", err))
+		}
+	} else {
+		_ = os.Remove(envPath)
+	}
+	if point.SnapshotID != "" {
+		if err := lm.rollbackSnapshot(ctx, appID, point.SnapshotID); err != nil {
+			return fail(fmt.Errorf("failed to rollback snapshot: %w", err))
+		}
+	}
+	if err := lm.restartApp(ctx, appID); err != nil {
+		return fail(err)
+	}
+
+	app, err = lm.stateStore.GetApp(appID)
+	if err != nil {
+		return err
+	}
+	app.Version = point.Version
+	app.Params = point.Params
+	app.Status = StatusRunning
+	app.RollbackPoint = nil
+	return lm.stateStore.UpdateApp(*app)
+}
+
+// captureRollbackPoint snapshots the deployed compose/env files of app and
+// the images they resolve to.
+func (lm *LifecycleManager) captureRollbackPoint(ctx context.Context, app *InstalledApp, configDir, snapshotID string) (*RollbackPoint, error) {
+	compose, err := os.ReadFile(filepath.Join(configDir, "docker-compose.yml"))
+	if err != nil {
+		return nil, err
+	}
+	env, err := os.ReadFile(filepath.Join(configDir, ".env"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	params := make(map[string]interface{}, len(app.Params))
+	for k, v := range app.Params {
+		params[k] = v
+	}
+	return &RollbackPoint{
+		Version:    app.Version,
+		Params:     params,
+		Compose:    string(compose),
+		Env:        string(env),
+		Images:     lm.resolveImages(ctx, composeImages(compose), parseEnvFile(env)),
+		SnapshotID: snapshotID,
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// composeImages lists the image references used by a compose file.
+func composeImages(compose []byte) []string {
+	var doc struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	images := []string{}
+	if yaml.Unmarshal(compose, &doc) != nil {
+		return images
+	}
+	for _, svc := range doc.Services {
+		if svc.Image != "" {
+			images = append(images, svc.Image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// resolveImages turns compose image references into what is actually
+// deployed: variables such as ${TAG:-latest} are interpolated from the app's
+// env file, and the local image's repo digest is used when docker knows one,
+// so a moving tag still names the pre-upgrade image.
+func (lm *LifecycleManager) resolveImages(ctx context.Context, images []string, env map[string]string) []string {
+	out := make([]string, 0, len(images))
+	for _, ref := range images {
+		ref = lm.renderer.replaceVariables(ref, env)
+		digest, err := lm.runCmd(ctx, "docker", "image", "inspect", "--format", "{{index .RepoDigests 0}}", ref)
+		if d := strings.TrimSpace(string(digest)); err == nil && strings.Contains(d, "@sha256:") {
+			ref = d
+		}
+		out = append(out, ref)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// pinImages points each compose service whose image has a recorded digest
+// at repo@sha256:..., so a rollback brings back the pre-upgrade image even
+// after its tag has moved. The compose file is returned unchanged when no
+// digest applies.
+func (lm *LifecycleManager) pinImages(compose []byte, env map[string]string, images []string) ([]byte, error) {
+	digests := map[string]string{}
+	for _, ref := range images {
+		if repo, _, ok := strings.Cut(ref, "@sha256:"); ok {
+			digests[repo] = ref
+		}
+	}
+	if len(digests) == 0 {
+		return compose, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(compose, &doc); err != nil {
+		return nil, err
+	}
+	pinned := false
+	if len(doc.Content) == 1 {
+		services := mappingValue(doc.Content[0], "services")
+		for i := 1; services != nil && i < len(services.Content); i += 2 {
+			image := mappingValue(services.Content[i], "image")
+			if image == nil {
+				continue
+			}
+			if ref, ok := digests[imageRepo(lm.renderer.replaceVariables(image.Value, env))]; ok {
+				image.Value, image.Style = ref, 0
+				pinned = true
+			}
+		}
+	}
+	if !pinned {
+		return compose, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// mappingValue returns the value node for key in a YAML mapping node.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// imageRepo strips the tag or digest from an image reference; a registry
+// port (host:5000/app) is kept.
+func imageRepo(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+// parseEnvFile reads KEY=VALUE lines as written by RenderEnvFile; double
+// quoted values are unquoted, single quotes are stripped.
+func parseEnvFile(data []byte) map[string]string {
+	env := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"':
+			if uq, err := strconv.Unquote(v); err == nil {
+				v = uq
+			}
+		case len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'':
+			v = v[1 : len(v)-1]
+		}
+		env[strings.TrimSpace(k)] = v
+	}
+	return env
+}
+
 // Helper methods
 
 func (lm *LifecycleManager) startApp(ctx context.Context, appID string) error {
@@ -459,8 +714,8 @@ func (lm *LifecycleManager) stopApp(ctx context.Context, appID string) error {
 }
 
 func (lm *LifecycleManager) restartApp(ctx context.Context, appID string) error {
-	cmd := exec.CommandContext(ctx, "systemctl", "restart", fmt.Sprintf("nos-app@%s.service", appID))
-	return cmd.Run()
+	_, err := lm.runCmd(ctx, "systemctl", "restart", fmt.Sprintf("nos-app@%s.service", appID))
+	return err
 }
 
 func (lm *LifecycleManager) disableSystemdService(appID string) error {
@@ -470,8 +725,8 @@ func (lm *LifecycleManager) disableSystemdService(appID string) error {
 
 func (lm *LifecycleManager) pullImages(ctx context.Context, appID string) error {
 	configDir := filepath.Join(lm.appsRoot, appID, "config")
-	cmd := exec.CommandContext(ctx, lm.helperPath, "compose-pull", configDir)
-	return cmd.Run()
+	_, err := lm.runCmd(ctx, lm.helperPath, "compose-pull", configDir)
+	return err
 }
 
 func (lm *LifecycleManager) ensureDataSubvolume(appID string) error {
@@ -480,8 +735,7 @@ func (lm *LifecycleManager) ensureDataSubvolume(appID string) error {
 }
 
 func (lm *LifecycleManager) createSnapshot(appID, name string) (string, error) {
-	cmd := exec.Command(lm.snapshotPath, "snapshot-pre", appID, name)
-	output, err := cmd.Output()
+	output, err := lm.runCmd(context.Background(), lm.snapshotPath, "snapshot-pre", appID, name)
 	if err != nil {
 		return "", err
 	}
@@ -501,8 +755,8 @@ func (lm *LifecycleManager) createSnapshot(appID, name string) (string, error) {
 }
 
 func (lm *LifecycleManager) rollbackSnapshot(ctx context.Context, appID, snapshotTS string) error {
-	cmd := exec.CommandContext(ctx, lm.snapshotPath, "rollback", appID, snapshotTS)
-	return cmd.Run()
+	_, err := lm.runCmd(ctx, lm.snapshotPath, "rollback", appID, snapshotTS)
+	return err
 }
 
 func (lm *LifecycleManager) setAppOwnership(appDir string) error {
@@ -557,8 +811,7 @@ func (lm *LifecycleManager) waitForHealth(ctx context.Context, appID string, tim
 
 	for time.Now().Before(deadline) {
 		// Check container status
-		cmd := exec.CommandContext(ctx, lm.helperPath, "app-status", appID)
-		output, err := cmd.Output()
+		output, err := lm.runCmd(ctx, lm.helperPath, "app-status", appID)
 		if err == nil {
 			var status map[string]interface{}
 			if json.Unmarshal(output, &status) == nil {
@@ -0,0 +1,209 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fakeCommands records helper invocations and answers the few the
+// lifecycle manager parses.
+type fakeCommands struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeCommands) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, filepath.Base(name)+" "+strings.Join(args, " "))
+	f.mu.Unlock()
+	switch {
+	case len(args) > 0 && args[0] == "app-status":
+		return []byte(`{"status":"running"}`), nil
+	case len(args) > 0 && args[0] == "snapshot-pre":
+		return []byte("created /srv/apps/.snapshots/" + args[1] + "/20260101-000000\n"), nil
+	}
+	return nil, nil
+}
+
+func (f *fakeCommands) saw(prefix string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestLifecycle(t *testing.T) (*LifecycleManager, *StateStore, string, *fakeCommands) {
+	t.Helper()
+	dir := t.TempDir()
+	catalogDir := filepath.Join(dir, "catalog")
+	if err := os.MkdirAll(catalogDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	catalog := "version: \"1.0\"\nentries:\n  - id: web\n    name: Web\n    version: \"2.0\"\n    compose: web.yml\n"
+	if err := os.WriteFile(filepath.Join(catalogDir, "catalog.yaml"), []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(catalogDir, "web.yml"), []byte("services:\n  web:\n    image: nginx:1.27\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := NewStateStore(filepath.Join(dir, "state", "apps.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appsRoot := filepath.Join(dir, "apps")
+	lm := NewLifecycleManager(
+		NewCatalogManager(catalogDir, filepath.Join(dir, "cache.json"), filepath.Join(dir, "sources.yaml")),
+		ss, NewTemplateRenderer(catalogDir), appsRoot, "/usr/lib/nos/agent", nil)
+	fake := &fakeCommands{}
+	lm.runCmd = fake.run
+	return lm, ss, appsRoot, fake
+}
+
+func TestUpgradeThenRollbackRestoresRollbackPoint(t *testing.T) {
+	lm, ss, appsRoot, fake := newTestLifecycle(t)
+	configDir := filepath.Join(appsRoot, "web", "config")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	original := "services:\n  web:\n    image: nginx:1.25\n"
+	if err := os.WriteFile(filepath.Join(configDir, "docker-compose.yml"), []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, ".env"), []byte("PORT='8080'\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.AddApp(InstalledApp{ID: "web", Version: "1.0", Status: StatusRunning, Params: map[string]interface{}{"PORT": "8080"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := lm.UpgradeApp(ctx, "web", UpgradeRequest{Version: "2.0", Params: map[string]interface{}{"PORT": "9090"}}, "admin"); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+
+	app, err := ss.GetApp("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Version != "2.0" || app.Params["PORT"] != "9090" {
+		t.Fatalf("upgrade not applied: %+v", app)
+	}
+	point := app.RollbackPoint
+	if point == nil {
+		t.Fatal("expected a rollback point after upgrade")
+	}
+	if point.Version != "1.0" || point.Params["PORT"] != "8080" || point.SnapshotID != "20260101-000000" {
+		t.Fatalf("unexpected rollback point: %+v", point)
+	}
+	if len(point.Images) != 1 || point.Images[0] != "nginx:1.25" {
+		t.Fatalf("expected pre-upgrade image tags, got %v", point.Images)
+	}
+	upgraded, _ := os.ReadFile(filepath.Join(configDir, "docker-compose.yml"))
+	if !strings.Contains(string(upgraded), "nginx:1.27") {
+		t.Fatalf("compose not upgraded:\n%s", upgraded)
+	}
+
+	if err := lm.RollbackApp(ctx, "web", "", "admin"); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	restored, _ := os.ReadFile(filepath.Join(configDir, "docker-compose.yml"))
+	if string(restored) != original {
+		t.Fatalf("compose not restored:\n%s", restored)
+	}
+	env, _ := os.ReadFile(filepath.Join(configDir, ".env"))
+	if string(env) != "PORT='8080'\n" {
+		t.Fatalf("env not restored: %q", env)
+	}
+	if !fake.saw("nos-app-snapshot.sh rollback web 20260101-000000") {
+		t.Fatalf("data snapshot not rolled back: %v", fake.calls)
+	}
+
+	app, err = ss.GetApp("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Version != "1.0" || app.Params["PORT"] != "8080" || app.Status != StatusRunning {
+		t.Fatalf("state not restored: %+v", app)
+	}
+	if app.RollbackPoint != nil {
+		t.Fatal("rollback point should be consumed")
+	}
+
+	if err := lm.RollbackApp(ctx, "web", "", "admin"); !errors.Is(err, ErrNoRollbackPoint) {
+		t.Fatalf("expected ErrNoRollbackPoint, got %v", err)
+	}
+}
+
+func TestRollbackPointResolvesImages(t *testing.T) {
+	lm, ss, appsRoot, fake := newTestLifecycle(t)
+	lm.runCmd = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "docker" && args[len(args)-1] == "ghcr.io/acme/web:latest" {
+			return []byte("ghcr.io/acme/web@sha256:1111\n"), nil
+		}
+		if name == "docker" {
+			return nil, errors.New("No such image")
+		}
+		return fake.run(ctx, name, args...)
+	}
+	configDir := filepath.Join(appsRoot, "web", "config")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	compose := "services:\n  web:\n    image: ghcr.io/acme/web:${WEB_TAG:-latest}\n  cache:\n    image: redis:${REDIS_TAG:-7}\n"
+	if err := os.WriteFile(filepath.Join(configDir, "docker-compose.yml"), []byte(compose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, ".env"), []byte("REDIS_TAG=\"7.2\"\nPORT=8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.AddApp(InstalledApp{ID: "web", Version: "1.0", Status: StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lm.UpgradeApp(context.Background(), "web", UpgradeRequest{Version: "2.0"}, "admin"); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	app, err := ss.GetApp("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(app.RollbackPoint.Images, ",")
+	if got != "ghcr.io/acme/web@sha256:1111,redis:7.2" {
+		t.Fatalf("expected digest and interpolated tag, got %s", got)
+	}
+	if app.RollbackPoint.Compose != compose {
+		t.Fatalf("compose should be recorded verbatim:\n%s", app.RollbackPoint.Compose)
+	}
+
+	// rolling back deploys the recorded digest, not whatever :latest is now
+	if err := lm.RollbackApp(context.Background(), "web", "", "admin"); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	restored, _ := os.ReadFile(filepath.Join(configDir, "docker-compose.yml"))
+	var doc struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(restored, &doc); err != nil {
+		t.Fatalf("restored compose: %v\n%s", err, restored)
+	}
+	if img := doc.Services["web"].Image; img != "ghcr.io/acme/web@sha256:1111" {
+		t.Fatalf("web not pinned to its digest: %s", img)
+	}
+	if img := doc.Services["cache"].Image; img != "redis:${REDIS_TAG:-7}" {
+		t.Fatalf("cache image without a digest should be left alone: %s", img)
+	}
+}
@@ -153,6 +153,8 @@ func (ss *StateStore) UpdateApp(app InstalledApp) error {
 	for i, existing := range ss.state.Apps {
 		if existing.ID == app.ID {
 			app.UpdatedAt = time.Now()
+			// health transitions are owned by UpdateAppHealth
+			app.HealthHistory = existing.HealthHistory
 			ss.state.Apps[i] = app
 			found = true
 			break
@@ -196,6 +198,9 @@ func (ss *StateStore) UpdateAppHealth(id string, health HealthStatus) error {
 	found := false
 	for i, app := range ss.state.Apps {
 		if app.ID == id {
+			if app.Health.Status != health.Status {
+				ss.state.Apps[i].HealthHistory = appendHealthTransition(app.HealthHistory, health)
+			}
 			ss.state.Apps[i].Health = health
 			ss.state.Apps[i].UpdatedAt = time.Now()
 			found = true
@@ -210,6 +215,50 @@ func (ss *StateStore) UpdateAppHealth(id string, health HealthStatus) error {
 	return ss.save()
 }
 
+// appendHealthTransition records health as a transition, keeping at most
+// MaxHealthHistory entries.
+func appendHealthTransition(history []HealthTransition, health HealthStatus) []HealthTransition {
+	at := health.CheckedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	history = append(history, HealthTransition{At: at, Status: health.Status, Message: health.Message})
+	if len(history) > MaxHealthHistory {
+		history = append([]HealthTransition(nil), history[len(history)-MaxHealthHistory:]...)
+	}
+	return history
+}
+
+// SetRollbackPoint records (or, with nil, clears) an app's rollback point
+func (ss *StateStore) SetRollbackPoint(id string, point *RollbackPoint) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	for i, app := range ss.state.Apps {
+		if app.ID == id {
+			ss.state.Apps[i].RollbackPoint = point
+			ss.state.Apps[i].UpdatedAt = time.Now()
+			return ss.save()
+		}
+	}
+
+	return fmt.Errorf("app not found: %s", id)
+}
+
+// GetHealthHistory returns an app's recorded health transitions, oldest first
+func (ss *StateStore) GetHealthHistory(id string) ([]HealthTransition, error) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	for _, app := range ss.state.Apps {
+		if app.ID == id {
+			return append([]HealthTransition{}, app.HealthHistory...), nil
+		}
+	}
+
+	return nil, fmt.Errorf("app not found: %s", id)
+}
+
 // AddSnapshot adds a snapshot to an app
 func (ss *StateStore) AddSnapshot(id string, snapshot AppSnapshot) error {
 	ss.mu.Lock()
@@ -0,0 +1,91 @@
+package apps
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHealthHistoryRecordsTransitionsOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apps.json")
+	ss, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.AddApp(InstalledApp{ID: "web", Status: StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// a flapping container: two healthy checks, three unhealthy, then healthy again
+	for i, st := range []string{"healthy", "healthy", "unhealthy", "unhealthy", "unhealthy", "healthy"} {
+		if err := ss.UpdateAppHealth("web", HealthStatus{Status: st, Message: st + " check", CheckedAt: start.Add(time.Duration(i) * 10 * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := ss.GetHealthHistory("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		status string
+		offset time.Duration
+	}{{"healthy", 0}, {"unhealthy", 20 * time.Second}, {"healthy", 50 * time.Second}}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d transitions, got %+v", len(want), history)
+	}
+	for i, w := range want {
+		if history[i].Status != w.status || !history[i].At.Equal(start.Add(w.offset)) {
+			t.Fatalf("transition %d: %+v", i, history[i])
+		}
+	}
+
+	// persisted across reloads
+	reloaded, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := reloaded.GetHealthHistory("web"); len(h) != 3 {
+		t.Fatalf("reloaded history: %+v", h)
+	}
+	if _, err := ss.GetHealthHistory("missing"); err == nil {
+		t.Fatal("expected error for unknown app")
+	}
+}
+
+func TestHealthHistoryIsBounded(t *testing.T) {
+	ss, err := NewStateStore(filepath.Join(t.TempDir(), "apps.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ss.AddApp(InstalledApp{ID: "web"})
+	for i := 0; i < MaxHealthHistory+10; i++ {
+		st := "healthy"
+		if i%2 == 1 {
+			st = "unhealthy"
+		}
+		_ = ss.UpdateAppHealth("web", HealthStatus{Status: st, CheckedAt: time.Unix(int64(i), 0)})
+	}
+	h, _ := ss.GetHealthHistory("web")
+	if len(h) != MaxHealthHistory || h[len(h)-1].At.Unix() != MaxHealthHistory+9 {
+		t.Fatalf("history len %d, last %+v", len(h), h[len(h)-1])
+	}
+}
+
+func TestUpdateAppKeepsHealthHistory(t *testing.T) {
+	ss, err := NewStateStore(filepath.Join(t.TempDir(), "apps.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ss.AddApp(InstalledApp{ID: "web"})
+	_ = ss.UpdateAppHealth("web", HealthStatus{Status: "healthy", CheckedAt: time.Unix(1, 0)})
+
+	// callers update apps from copies that may predate the last transition
+	if err := ss.UpdateApp(InstalledApp{ID: "web", Status: StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := ss.GetHealthHistory("web"); len(h) != 1 {
+		t.Fatalf("history after update: %+v", h)
+	}
+}
@@ -83,6 +83,21 @@ type InstalledApp struct {
 	InstalledAt time.Time              `json:"installed_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 	Snapshots   []AppSnapshot          `json:"snapshots"`
+	// HealthHistory holds the most recent health transitions, oldest first
+	HealthHistory []HealthTransition `json:"health_history,omitempty"`
+	// RollbackPoint is the pre-upgrade state restored by a rollback
+	RollbackPoint *RollbackPoint `json:"rollback_point,omitempty"`
+}
+
+// RollbackPoint captures an app's deployment right before an upgrade
+type RollbackPoint struct {
+	Version    string                 `json:"version"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Compose    string                 `json:"compose"`
+	Env        string                 `json:"env,omitempty"`
+	Images     []string               `json:"images"`
+	SnapshotID string                 `json:"snapshot_id,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
 }
 
 // AppStatus represents the current status of an app
@@ -107,6 +122,16 @@ type HealthStatus struct {
 	Containers []ContainerHealth `json:"containers,omitempty"`
 }
 
+// MaxHealthHistory bounds InstalledApp.HealthHistory
+const MaxHealthHistory = 50
+
+// HealthTransition records a change in an app's overall health status
+type HealthTransition struct {
+	At      time.Time `json:"at"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+}
+
 // ContainerHealth represents health of a single container
 type ContainerHealth struct {
 	Name   string `json:"name"`
@@ -136,9 +161,10 @@ type UpgradeRequest struct {
 	Params  map[string]interface{} `json:"params,omitempty"`
 }
 
-// RollbackRequest represents a request to rollback an app
+// RollbackRequest represents a request to rollback an app; an empty
+// SnapshotTimestamp restores the recorded pre-upgrade rollback point
 type RollbackRequest struct {
-	SnapshotTimestamp string `json:"snapshot_ts" validate:"required"`
+	SnapshotTimestamp string `json:"snapshot_ts"`
 }
 
 // DeleteRequest represents a request to delete an app
@@ -153,7 +179,10 @@ type AppState struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 }
 
-// CatalogSource represents a remote catalog source
+// CatalogSource represents a remote catalog source. When SHA256 is set the
+// fetched manifest must hash to it; when PublicKey (base64 ed25519) is set
+// it must carry a valid detached signature fetched from Signature (default
+// URL + ".sig").
 type CatalogSource struct {
 	Name      string `yaml:"name"`
 	Type      string `yaml:"type"` // "git" or "http"
@@ -162,6 +191,29 @@ type CatalogSource struct {
 	SHA256    string `yaml:"sha256,omitempty"`
 	Signature string `yaml:"signature,omitempty"`
 	Enabled   bool   `yaml:"enabled"`
+	PublicKey string `yaml:"public_key,omitempty"`
+}
+
+// Catalog source verification methods and sync outcomes.
+const (
+	VerifyNone      = "none"
+	VerifySHA256    = "sha256"
+	VerifySignature = "signature"
+
+	SourceOK       = "ok"
+	SourceRejected = "rejected" // fetched but failed verification
+	SourceError    = "error"    // could not be fetched or parsed
+)
+
+// CatalogSourceStatus records the outcome of the last sync of a source.
+type CatalogSourceStatus struct {
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Verification string    `json:"verification"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	Entries      int       `json:"entries"`
+	CheckedAt    time.Time `json:"checked_at"`
 }
 
 // Event represents an app lifecycle event
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+// Mailer delivers plain-text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPConfig holds outgoing mail server settings
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	From     string
+	Username string
+	Password string
+}
+
+// SMTPMailer sends mail through an SMTP relay
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPMailer creates a mailer for the given server
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &SMTPMailer{cfg: cfg}
+}
+
+// MailerFromConfig returns an SMTP mailer when SMTP is configured, otherwise nil
+func MailerFromConfig(cfg config.Config) Mailer {
+	if strings.TrimSpace(cfg.SMTPHost) == "" {
+		return nil
+	}
+	return NewSMTPMailer(SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		From:     cfg.SMTPFrom,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+	})
+}
+
+// Send delivers a single message. STARTTLS is used when the server offers it;
+// credentials are only sent over TLS or to localhost (enforced by net/smtp).
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+	from := m.cfg.From
+	if from == "" {
+		from = "nithronos@" + m.cfg.Host
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + subject + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(msg.String()))
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/config"
+)
+
+// startMockSMTP runs a minimal SMTP server that accepts any message and
+// delivers the DATA section on the returned channel.
+func startMockSMTP(t *testing.T) (string, int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	msgs := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveMockSMTP(conn, msgs)
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, msgs
+}
+
+func serveMockSMTP(conn net.Conn, msgs chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP mock")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"), strings.HasPrefix(cmd, "RSET"), strings.HasPrefix(cmd, "NOOP"):
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msgs <- data.String()
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestRequestPasswordResetSendsEmail(t *testing.T) {
+	host, port, msgs := startMockSMTP(t)
+
+	um := NewUserManagerFromConfig(zerolog.Nop(), t.TempDir(), config.Config{
+		PublicURL: "https://nas.example.com/",
+		SMTPHost:  host,
+		SMTPPort:  port,
+		SMTPFrom:  "nas@example.com",
+	})
+	if _, err := um.CreateUser(UserCreateRequest{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "Orchard-Lantern-42x",
+		Role:     RoleAdmin,
+	}, "test"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if err := um.RequestPasswordReset(PasswordResetRequest{UsernameOrEmail: "alice", Method: "email"}, "127.0.0.1"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+
+	var msg string
+	select {
+	case msg = <-msgs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email delivered")
+	}
+	if !strings.Contains(msg, "To: alice@example.com") {
+		t.Fatalf("message not addressed to user:\n%s", msg)
+	}
+	if !strings.Contains(msg, "https://nas.example.com/reset-password?token=") {
+		t.Fatalf("message missing reset link:\n%s", msg)
+	}
+	idx := strings.Index(msg, "Reset token: ")
+	if idx < 0 {
+		t.Fatalf("message missing token:\n%s", msg)
+	}
+	token := strings.TrimSpace(strings.SplitN(msg[idx+len("Reset token: "):], "\r\n", 2)[0])
+
+	// The emailed token must be the one that completes the reset
+	if err := um.VerifyPasswordReset(PasswordResetVerify{Token: token, NewPassword: "Granite-Harbor-77q"}, "127.0.0.1"); err != nil {
+		t.Fatalf("emailed token rejected: %v", err)
+	}
+}
+
+func TestRequestPasswordResetUnknownUserSendsNothing(t *testing.T) {
+	host, port, msgs := startMockSMTP(t)
+
+	um := NewUserManagerFromConfig(zerolog.Nop(), t.TempDir(), config.Config{SMTPHost: host, SMTPPort: port})
+
+	if err := um.RequestPasswordReset(PasswordResetRequest{UsernameOrEmail: "nobody", Method: "email"}, "127.0.0.1"); err != nil {
+		t.Fatalf("unknown user must not error: %v", err)
+	}
+	select {
+	case msg := <-msgs:
+		t.Fatalf("unexpected email: %s", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestNewUserManagerFromConfigWithoutSMTP(t *testing.T) {
+	um := NewUserManagerFromConfig(zerolog.Nop(), t.TempDir(), config.Config{SMTPPort: 587})
+	if um.mailer != nil {
+		t.Fatalf("mailer configured without an SMTP host: %T", um.mailer)
+	}
+}
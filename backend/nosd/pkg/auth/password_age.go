@@ -0,0 +1,59 @@
+package auth
+
+import "time"
+
+// DefaultPasswordPolicy returns the built-in password policy
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:        12,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireNumbers:   true,
+		RequireSpecial:   false,
+		MinEntropy:       3.0,
+		ProhibitCommon:   true,
+		ProhibitUsername: true,
+		ProhibitReuse:    3,
+		MaxAge:           90,
+		WarnAge:          14,
+	}
+}
+
+// PasswordAgeStatus describes whether a password must or should be changed
+type PasswordAgeStatus struct {
+	// ChangeRequired is set when the password has expired or an admin forced
+	// a change; the user may log in but must change it before anything else.
+	ChangeRequired bool `json:"change_required"`
+	Expired        bool `json:"expired"`
+	// Warn is set when the password expires within WarnAge days
+	Warn          bool       `json:"warn"`
+	DaysRemaining *int       `json:"days_remaining,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// EvaluateAge checks a password last changed at changedAt against MaxAge and
+// WarnAge. A zero changedAt or MaxAge <= 0 means the password never expires.
+func (p PasswordPolicy) EvaluateAge(changedAt time.Time, force bool, now time.Time) PasswordAgeStatus {
+	st := PasswordAgeStatus{ChangeRequired: force}
+	if p.MaxAge <= 0 || changedAt.IsZero() {
+		return st
+	}
+
+	expires := changedAt.Add(time.Duration(p.MaxAge) * 24 * time.Hour)
+	st.ExpiresAt = &expires
+	remaining := expires.Sub(now)
+	days := int(remaining.Hours() / 24)
+	if remaining < 0 {
+		days = 0
+	}
+	st.DaysRemaining = &days
+
+	switch {
+	case remaining <= 0:
+		st.Expired = true
+		st.ChangeRequired = true
+	case p.WarnAge > 0 && remaining <= time.Duration(p.WarnAge)*24*time.Hour:
+		st.Warn = true
+	}
+	return st
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,7 +15,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/pquerna/otp/totp"
 	"github.com/rs/zerolog"
-	"golang.org/x/crypto/argon2"
+
+	pwhash "nithronos/backend/nosd/internal/auth/hash"
+	"nithronos/backend/nosd/internal/config"
 )
 
 // UserManager handles user management
@@ -34,6 +37,11 @@ type UserManager struct {
 	// Rate limiting
 	loginAttempts []LoginAttempt
 	attemptsMu    sync.RWMutex
+	
+	// Outgoing mail for password resets (nil disables email delivery);
+	// publicURL is the base of the reset link, only the token is sent without it
+	mailer    Mailer
+	publicURL string
 }
 
 // NewUserManager creates a new user manager
@@ -46,19 +54,7 @@ func NewUserManager(logger zerolog.Logger, dataPath string) *UserManager {
 		resetTokens:  make(map[string]*PasswordResetToken),
 		lockouts:     make(map[string]*Lockout),
 		loginAttempts: []LoginAttempt{},
-		passwordPolicy: PasswordPolicy{
-			MinLength:        12,
-			RequireUppercase: true,
-			RequireLowercase: true,
-			RequireNumbers:   true,
-			RequireSpecial:   false,
-			MinEntropy:       3.0,
-			ProhibitCommon:   true,
-			ProhibitUsername: true,
-			ProhibitReuse:    3,
-			MaxAge:           90,
-			WarnAge:          14,
-		},
+		passwordPolicy: DefaultPasswordPolicy(),
 	}
 	
 	// Initialize audit logger
@@ -78,6 +74,27 @@ func NewUserManager(logger zerolog.Logger, dataPath string) *UserManager {
 	return um
 }
 
+// NewUserManagerFromConfig creates a user manager that emails password reset
+// links through the SMTP server and public URL in cfg
+func NewUserManagerFromConfig(logger zerolog.Logger, dataPath string, cfg config.Config) *UserManager {
+	um := NewUserManager(logger, dataPath)
+	um.mailer = MailerFromConfig(cfg)
+	um.publicURL = strings.TrimRight(cfg.PublicURL, "/")
+	return um
+}
+
+// PasswordStatus reports the age of a user's password against the policy
+func (um *UserManager) PasswordStatus(userID string) (PasswordAgeStatus, error) {
+	um.mu.RLock()
+	defer um.mu.RUnlock()
+	
+	user, exists := um.users[userID]
+	if !exists {
+		return PasswordAgeStatus{}, fmt.Errorf("user not found")
+	}
+	return um.passwordPolicy.EvaluateAge(user.PasswordChangedAt, user.ForcePasswordChange, time.Now()), nil
+}
+
 // User CRUD operations
 
 // CreateUser creates a new user
@@ -282,8 +299,9 @@ func (um *UserManager) DeleteUser(userID string, actorID string) error {
 	// Delete user
 	delete(um.users, userID)
 	
-	// Delete password
+	// Delete password and its history
 	um.deletePassword(userID)
+	um.deletePasswordHistory(userID)
 	
 	// Audit log
 	um.auditLog.LogEvent(&AuditEvent{
@@ -345,6 +363,9 @@ func (um *UserManager) ChangePassword(userID string, req PasswordChangeRequest)
 	if err := um.validatePassword(req.NewPassword, user.Username); err != nil {
 		return err
 	}
+	if err := um.checkPasswordReuse(userID, req.NewPassword); err != nil {
+		return err
+	}
 	
 	// Hash and store new password
 	hashedPassword := um.hashPassword(req.NewPassword)
@@ -441,11 +462,39 @@ func (um *UserManager) RequestPasswordReset(req PasswordResetRequest, ip string)
 			Msg("Password reset token (valid for 30 minutes)")
 	}
 	
-	// TODO: Send email for email method
+	// Send email for email method. Delivery happens in the background so the
+	// response time doesn't reveal whether the account exists.
+	if req.Method == "email" {
+		if um.mailer == nil || user.Email == "" {
+			um.logger.Warn().
+				Str("user", user.Username).
+				Bool("mailer_configured", um.mailer != nil).
+				Msg("Password reset email not sent: no mailer or email address")
+		} else {
+			go um.sendResetEmail(um.mailer, um.publicURL, user.Email, user.Username, tokenStr)
+		}
+	}
 	
 	return nil
 }
 
+func (um *UserManager) sendResetEmail(m Mailer, publicURL, to, username, token string) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s,\n\n", username)
+	body.WriteString("A password reset was requested for your NithronOS account.\n\n")
+	if publicURL != "" {
+		fmt.Fprintf(&body, "Reset your password: %s/reset-password?token=%s\n\n", publicURL, url.QueryEscape(token))
+	}
+	fmt.Fprintf(&body, "Reset token: %s\n\n", token)
+	body.WriteString("The token is valid for 30 minutes. If you did not request this, you can ignore this email.\n")
+	
+	if err := m.Send(to, "NithronOS password reset", body.String()); err != nil {
+		um.logger.Error().Err(err).Str("user", username).Msg("Failed to send password reset email")
+		return
+	}
+	um.logger.Info().Str("user", username).Msg("Password reset email sent")
+}
+
 // VerifyPasswordReset completes password reset
 func (um *UserManager) VerifyPasswordReset(req PasswordResetVerify, ip string) error {
 	um.mu.Lock()
@@ -476,6 +525,9 @@ func (um *UserManager) VerifyPasswordReset(req PasswordResetVerify, ip string) e
 	if err := um.validatePassword(req.NewPassword, user.Username); err != nil {
 		return err
 	}
+	if err := um.checkPasswordReuse(user.ID, req.NewPassword); err != nil {
+		return err
+	}
 	
 	// Update password
 	hashedPassword := um.hashPassword(req.NewPassword)
@@ -716,33 +768,18 @@ func (um *UserManager) RegenerateRecoveryCodes(userID string, password string) (
 // Helper methods
 
 func (um *UserManager) hashPassword(password string) string {
-	salt := make([]byte, 16)
-	_, _ = rand.Read(salt)
-	
-	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
-	
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
-	
-	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", 
-		argon2.Version, 64*1024, 1, 4, b64Salt, b64Hash)
+	// Shares the configured argon2 params with the rest of nosd
+	h, err := pwhash.HashPassword(password)
+	if err != nil {
+		um.logger.Error().Err(err).Msg("Failed to hash password")
+		return ""
+	}
+	return h
 }
 
 func (um *UserManager) verifyPasswordHash(hash, password string) bool {
-	// Parse hash
-	parts := strings.Split(hash, "$")
-	if len(parts) != 6 {
-		return false
-	}
-	
-	salt, _ := base64.RawStdEncoding.DecodeString(parts[4])
-	expectedHash, _ := base64.RawStdEncoding.DecodeString(parts[5])
-	
-	// Compute hash
-	computedHash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
-	
-	// Compare
-	return string(expectedHash) == string(computedHash)
+	// Honors the params encoded in the PHC string
+	return pwhash.VerifyPassword(hash, password)
 }
 
 func (um *UserManager) verifyPassword(userID, password string) bool {
@@ -1002,6 +1039,69 @@ func (um *UserManager) storePassword(userID, hash string) {
 	if data, err := json.Marshal(passwords); err == nil {
 		_ = os.WriteFile(passwordsPath, data, 0600)
 	}
+	
+	um.recordPasswordHistory(userID, hash)
+}
+
+// Password history helpers (newest first, pruned to PasswordPolicy.ProhibitReuse)
+
+func (um *UserManager) loadPasswordHistory() map[string][]string {
+	history := make(map[string][]string)
+	if data, err := os.ReadFile(filepath.Join(um.dataPath, "password_history.json")); err == nil {
+		_ = json.Unmarshal(data, &history)
+	}
+	return history
+}
+
+func (um *UserManager) recordPasswordHistory(userID, hash string) {
+	history := um.loadPasswordHistory()
+	n := um.passwordPolicy.ProhibitReuse
+	if n <= 0 {
+		delete(history, userID)
+	} else {
+		entries := append([]string{hash}, history[userID]...)
+		if len(entries) > n {
+			entries = entries[:n]
+		}
+		history[userID] = entries
+	}
+	
+	if data, err := json.Marshal(history); err == nil {
+		_ = os.WriteFile(filepath.Join(um.dataPath, "password_history.json"), data, 0600)
+	}
+}
+
+func (um *UserManager) deletePasswordHistory(userID string) {
+	history := um.loadPasswordHistory()
+	if _, ok := history[userID]; !ok {
+		return
+	}
+	delete(history, userID)
+	if data, err := json.Marshal(history); err == nil {
+		_ = os.WriteFile(filepath.Join(um.dataPath, "password_history.json"), data, 0600)
+	}
+}
+
+// checkPasswordReuse rejects a password matching any of the user's last
+// ProhibitReuse passwords, including the current one.
+func (um *UserManager) checkPasswordReuse(userID, password string) error {
+	n := um.passwordPolicy.ProhibitReuse
+	if n <= 0 {
+		return nil
+	}
+	hashes := um.loadPasswordHistory()[userID]
+	if len(hashes) == 0 {
+		// Users created before history was tracked: still block the current password
+		if current := um.getPassword(userID); current != "" {
+			hashes = []string{current}
+		}
+	}
+	for _, h := range hashes {
+		if um.verifyPasswordHash(h, password) {
+			return fmt.Errorf("password was used recently; choose one different from your last %d passwords", n)
+		}
+	}
+	return nil
 }
 
 func (um *UserManager) getPassword(userID string) string {
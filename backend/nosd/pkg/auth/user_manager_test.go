@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestChangePasswordRejectsRecentReuse(t *testing.T) {
+	um := NewUserManager(zerolog.Nop(), t.TempDir())
+	passwords := []string{
+		"Orchard-Lantern-40a",
+		"Orchard-Lantern-41b",
+		"Orchard-Lantern-42c",
+		"Orchard-Lantern-43d",
+	}
+	user, err := um.CreateUser(UserCreateRequest{Username: "bob", Password: passwords[0], Role: RoleViewer}, "test")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	for i := 1; i < len(passwords); i++ {
+		if err := um.ChangePassword(user.ID, PasswordChangeRequest{CurrentPassword: passwords[i-1], NewPassword: passwords[i]}); err != nil {
+			t.Fatalf("change %d: %v", i, err)
+		}
+	}
+	current := passwords[len(passwords)-1]
+
+	// Current and the two before it are within the last 3
+	for _, pw := range []string{current, passwords[2], passwords[1]} {
+		if err := um.ChangePassword(user.ID, PasswordChangeRequest{CurrentPassword: current, NewPassword: pw}); err == nil {
+			t.Fatalf("reuse of recent password %q was allowed", pw)
+		}
+	}
+
+	// The first password has aged out of the history
+	if err := um.ChangePassword(user.ID, PasswordChangeRequest{CurrentPassword: current, NewPassword: passwords[0]}); err != nil {
+		t.Fatalf("older-than-N password rejected: %v", err)
+	}
+
+	if got := len(um.loadPasswordHistory()[user.ID]); got != um.passwordPolicy.ProhibitReuse {
+		t.Fatalf("history not pruned: %d entries", got)
+	}
+}
+
+func TestPasswordPolicyEvaluateAge(t *testing.T) {
+	policy := DefaultPasswordPolicy() // 90 day max, warn 14 days before
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		changedAt time.Time
+		force     bool
+		required  bool
+		expired   bool
+		warn      bool
+	}{
+		{name: "fresh", changedAt: now.Add(-10 * day)},
+		{name: "warn window", changedAt: now.Add(-80 * day), warn: true},
+		{name: "expired", changedAt: now.Add(-91 * day), required: true, expired: true},
+		{name: "forced change on fresh password", changedAt: now.Add(-1 * day), force: true, required: true},
+		{name: "unknown change time never expires", changedAt: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := policy.EvaluateAge(tt.changedAt, tt.force, now)
+			if st.ChangeRequired != tt.required || st.Expired != tt.expired || st.Warn != tt.warn {
+				t.Fatalf("got %+v", st)
+			}
+		})
+	}
+
+	st := policy.EvaluateAge(now.Add(-80*day), false, now)
+	if st.DaysRemaining == nil || *st.DaysRemaining != 10 {
+		t.Fatalf("days remaining: %+v", st.DaysRemaining)
+	}
+}
+
+func TestUserManagerPasswordStatus(t *testing.T) {
+	um := NewUserManager(zerolog.Nop(), t.TempDir())
+	user, err := um.CreateUser(UserCreateRequest{Username: "carol", Password: "Orchard-Lantern-40a", Role: RoleViewer}, "test")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if st, _ := um.PasswordStatus(user.ID); st.ChangeRequired || st.Warn {
+		t.Fatalf("new password should be fresh: %+v", st)
+	}
+
+	um.mu.Lock()
+	um.users[user.ID].PasswordChangedAt = time.Now().Add(-100 * 24 * time.Hour)
+	um.mu.Unlock()
+	if st, _ := um.PasswordStatus(user.ID); !st.Expired || !st.ChangeRequired {
+		t.Fatalf("old password should be expired: %+v", st)
+	}
+}
@@ -102,7 +102,7 @@ func (jm *JobManager) CancelJob(id string) error {
 		return nil
 	}
 	
-	if job.State == JobStateRunning || job.State == JobStatePending {
+	if job.State == JobStateRunning || job.State == JobStatePending || job.State == JobStateDeferred {
 		job.State = JobStateCanceled
 		now := time.Now()
 		job.FinishedAt = &now
@@ -122,7 +122,7 @@ func (jm *JobManager) CleanupOldJobs(maxAge time.Duration) int {
 	
 	for id, job := range jm.jobs {
 		// Only clean up completed jobs
-		if job.State != JobStateSucceeded && job.State != JobStateFailed && job.State != JobStateCanceled {
+		if job.State != JobStateSucceeded && job.State != JobStateFailed && job.State != JobStateCanceled && job.State != JobStateSkipped {
 			continue
 		}
 		
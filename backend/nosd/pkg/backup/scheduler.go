@@ -12,6 +12,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/internal/fsatomic"
+	"nithronos/backend/nosd/pkg/maintenance"
 )
 
 // Scheduler manages backup schedules and retention
@@ -23,8 +26,13 @@ type Scheduler struct {
 	cron        *cron.Cron
 	cronEntries map[string]cron.EntryID
 	mu          sync.RWMutex
+	saveMu      sync.Mutex // serializes state file writes
 	agentClient AgentClient
 	jobManager  *JobManager
+	now         func() time.Time
+	window      maintenance.Window
+	// seam for tests: schedules a deferred run
+	afterFunc func(d time.Duration, f func()) *time.Timer
 }
 
 // AgentClient interface for privileged operations
@@ -55,9 +63,27 @@ func NewScheduler(logger zerolog.Logger, stateFile string, agentClient AgentClie
 		cronEntries: make(map[string]cron.EntryID),
 		agentClient: agentClient,
 		jobManager:  NewJobManager(logger),
+		now:         time.Now,
+		afterFunc:   time.AfterFunc,
 	}
 }
 
+// SetMaintenanceWindow restricts when scheduled backups may start. Runs that
+// come due outside the window are deferred to its next opening or skipped,
+// per the window's policy.
+func (s *Scheduler) SetMaintenanceWindow(w maintenance.Window) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window = w
+}
+
+// MaintenanceWindow returns the window set by SetMaintenanceWindow.
+func (s *Scheduler) MaintenanceWindow() maintenance.Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.window
+}
+
 // Start begins the scheduler
 func (s *Scheduler) Start(ctx context.Context) error {
 	s.logger.Info().Msg("Starting backup scheduler")
@@ -134,7 +160,7 @@ func (s *Scheduler) CreateSchedule(schedule *Schedule) error {
 	}
 
 	// Save state
-	if err := s.saveState(); err != nil {
+	if err := s.saveStateLocked(); err != nil {
 		return fmt.Errorf("failed to save state: %w", err)
 	}
 
@@ -183,7 +209,7 @@ func (s *Scheduler) UpdateSchedule(id string, update *Schedule) error {
 	}
 
 	// Save state
-	if err := s.saveState(); err != nil {
+	if err := s.saveStateLocked(); err != nil {
 		return fmt.Errorf("failed to save state: %w", err)
 	}
 
@@ -210,7 +236,7 @@ func (s *Scheduler) DeleteSchedule(id string) error {
 	delete(s.schedules, id)
 
 	// Save state
-	if err := s.saveState(); err != nil {
+	if err := s.saveStateLocked(); err != nil {
 		return fmt.Errorf("failed to save state: %w", err)
 	}
 
@@ -300,7 +326,7 @@ func (s *Scheduler) DeleteSnapshot(id string) error {
 	s.removeSnapshot(snapshot)
 
 	// Save state
-	if err := s.saveState(); err != nil {
+	if err := s.saveStateLocked(); err != nil {
 		return fmt.Errorf("failed to save state: %w", err)
 	}
 
@@ -474,8 +500,6 @@ func (s *Scheduler) runScheduledBackup(scheduleID string) {
 		return
 	}
 
-	s.logger.Info().Str("schedule", schedule.Name).Msg("Running scheduled backup")
-
 	// Create job
 	job := &BackupJob{
 		ID:         uuid.New().String(),
@@ -489,8 +513,66 @@ func (s *Scheduler) runScheduledBackup(scheduleID string) {
 	// Add to job manager
 	s.jobManager.AddJob(job)
 
+	if !s.dispatchAllowed(job) {
+		return
+	}
+	s.runDispatched(job, schedule)
+}
+
+// dispatchAllowed applies the maintenance window to a freshly due job. When
+// the job may not start now it is recorded as deferred (and re-dispatched at
+// the next window opening) or skipped.
+func (s *Scheduler) dispatchAllowed(job *BackupJob) bool {
+	s.mu.RLock()
+	w := s.window
+	s.mu.RUnlock()
+	d := w.Decide(s.now())
+	if d.Dispatch {
+		return true
+	}
+	job.Reason = d.Reason
+	if d.State == maintenance.StateSkipped || d.NextEligible.IsZero() {
+		job.State = JobStateSkipped
+		now := s.now()
+		job.FinishedAt = &now
+		s.jobManager.UpdateJob(job)
+		s.logger.Info().Str("job", job.ID).Str("schedule_id", job.ScheduleID).Str("reason", d.Reason).Msg("Scheduled backup skipped")
+		return false
+	}
+	next := d.NextEligible
+	job.State = JobStateDeferred
+	job.DeferredUntil = &next
+	s.jobManager.UpdateJob(job)
+	s.logger.Info().Str("job", job.ID).Str("schedule_id", job.ScheduleID).Time("until", next).Str("reason", d.Reason).Msg("Scheduled backup deferred")
+	s.afterFunc(next.Sub(s.now()), func() { s.runDeferred(job.ID) })
+	return false
+}
+
+// runDeferred starts a deferred job once its window opens, unless it was
+// canceled or its schedule removed in the meantime.
+func (s *Scheduler) runDeferred(jobID string) {
+	job, ok := s.jobManager.GetJob(jobID)
+	if !ok || job.State != JobStateDeferred {
+		return
+	}
+	s.mu.RLock()
+	schedule, ok := s.schedules[job.ScheduleID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	job.State = JobStatePending
+	job.DeferredUntil = nil
+	job.StartedAt = time.Now()
+	s.jobManager.UpdateJob(job)
+	s.runDispatched(job, schedule)
+}
+
+func (s *Scheduler) runDispatched(job *BackupJob, schedule *Schedule) {
+	s.logger.Info().Str("schedule", schedule.Name).Msg("Running scheduled backup")
+
 	// Run backup
-	s.runSnapshotJob(job, schedule.Subvolumes, "", schedule)
+	s.runSnapshotJob(job, job.Subvolumes, "", schedule)
 
 	// Update schedule
 	s.mu.Lock()
@@ -623,7 +705,34 @@ func (s *Scheduler) applyRetention(schedule *Schedule) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	retention := schedule.Retention
+	for _, snap := range s.retentionCandidates(schedule) {
+		s.logger.Info().Str("id", snap.ID).Str("path", snap.Path).Msg("Deleting snapshot per retention policy")
+		if err := s.agentClient.DeleteSnapshot(snap.Path); err != nil {
+			s.logger.Error().Err(err).Str("path", snap.Path).Msg("Failed to delete snapshot")
+		} else {
+			s.removeSnapshot(snap)
+		}
+	}
+}
+
+// DryRunRetention returns the snapshots that the schedule's retention policy
+// would delete right now, without deleting anything.
+func (s *Scheduler) DryRunRetention(scheduleID string) ([]*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[scheduleID]
+	if !ok {
+		return nil, fmt.Errorf("schedule not found: %s", scheduleID)
+	}
+
+	return s.retentionCandidates(schedule), nil
+}
+
+// retentionCandidates selects the schedule's snapshots that fall outside its
+// GFS retention policy. Callers must hold s.mu.
+func (s *Scheduler) retentionCandidates(schedule *Schedule) []*Snapshot {
+	var toDelete []*Snapshot
 
 	for _, subvol := range schedule.Subvolumes {
 		snapshots := s.snapshots[subvol]
@@ -642,9 +751,9 @@ func (s *Scheduler) applyRetention(schedule *Schedule) {
 		})
 
 		// Apply GFS retention
-		toKeep := s.selectGFSSnapshots(scheduleSnapshots, retention)
+		toKeep := s.selectGFSSnapshots(scheduleSnapshots, schedule.Retention)
 
-		// Delete snapshots not in toKeep
+		// Collect snapshots not in toKeep
 		for _, snap := range scheduleSnapshots {
 			keep := false
 			for _, keepSnap := range toKeep {
@@ -655,17 +764,18 @@ func (s *Scheduler) applyRetention(schedule *Schedule) {
 			}
 
 			if !keep {
-				s.logger.Info().Str("id", snap.ID).Str("path", snap.Path).Msg("Deleting snapshot per retention policy")
-				if err := s.agentClient.DeleteSnapshot(snap.Path); err != nil {
-					s.logger.Error().Err(err).Str("path", snap.Path).Msg("Failed to delete snapshot")
-				} else {
-					s.removeSnapshot(snap)
-				}
+				toDelete = append(toDelete, snap)
 			}
 		}
 	}
+
+	return toDelete
 }
 
+// selectGFSSnapshots picks the snapshots to keep from a newest-first list.
+// Each tier keeps the newest snapshot of up to N distinct periods inside its
+// window; kept snapshots are tracked by ID so tiers that pick the same
+// snapshot don't inflate the count.
 func (s *Scheduler) selectGFSSnapshots(snapshots []*Snapshot, retention RetentionPolicy) []*Snapshot {
 	if len(snapshots) == 0 {
 		return snapshots
@@ -677,72 +787,58 @@ func (s *Scheduler) selectGFSSnapshots(snapshots []*Snapshot, retention Retentio
 	}
 
 	toKeep := make(map[string]*Snapshot)
-	now := time.Now()
+	now := s.now()
 
-	// Keep daily snapshots
-	for i := 0; i < retention.Days && i < len(snapshots); i++ {
-		age := now.Sub(snapshots[i].CreatedAt)
-		if age < time.Duration(retention.Days)*24*time.Hour {
-			toKeep[snapshots[i].ID] = snapshots[i]
+	keepPeriodic := func(count int, window time.Duration, period func(time.Time) string) {
+		seen := make(map[string]bool)
+		for _, snap := range snapshots {
+			if len(seen) >= count {
+				return
+			}
+			if now.Sub(snap.CreatedAt) >= window {
+				continue
+			}
+			key := period(snap.CreatedAt)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			toKeep[snap.ID] = snap
 		}
 	}
 
+	// Keep daily snapshots
+	keepPeriodic(retention.Days, time.Duration(retention.Days)*24*time.Hour, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+
 	// Keep weekly snapshots
-	weeklyCount := 0
-	for _, snap := range snapshots {
-		age := now.Sub(snap.CreatedAt)
-		if age < time.Duration(retention.Weeks)*7*24*time.Hour {
-			// Keep one per week
-			week := snap.CreatedAt.Format("2006-W01")
-			if _, exists := toKeep["weekly-"+week]; !exists && weeklyCount < retention.Weeks {
-				toKeep["weekly-"+week] = snap
-				toKeep[snap.ID] = snap
-				weeklyCount++
-			}
-		}
-	}
+	keepPeriodic(retention.Weeks, time.Duration(retention.Weeks)*7*24*time.Hour, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
 
 	// Keep monthly snapshots
-	monthlyCount := 0
-	for _, snap := range snapshots {
-		age := now.Sub(snap.CreatedAt)
-		if age < time.Duration(retention.Months)*30*24*time.Hour {
-			// Keep one per month
-			month := snap.CreatedAt.Format("2006-01")
-			if _, exists := toKeep["monthly-"+month]; !exists && monthlyCount < retention.Months {
-				toKeep["monthly-"+month] = snap
-				toKeep[snap.ID] = snap
-				monthlyCount++
-			}
-		}
-	}
+	keepPeriodic(retention.Months, time.Duration(retention.Months)*30*24*time.Hour, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
 
 	// Keep yearly snapshots
-	yearlyCount := 0
-	for _, snap := range snapshots {
-		age := now.Sub(snap.CreatedAt)
-		if age < time.Duration(retention.Years)*365*24*time.Hour {
-			// Keep one per year
-			year := snap.CreatedAt.Format("2006")
-			if _, exists := toKeep["yearly-"+year]; !exists && yearlyCount < retention.Years {
-				toKeep["yearly-"+year] = snap
-				toKeep[snap.ID] = snap
-				yearlyCount++
-			}
-		}
-	}
+	keepPeriodic(retention.Years, time.Duration(retention.Years)*365*24*time.Hour, func(t time.Time) string {
+		return t.Format("2006")
+	})
 
 	// Ensure we keep at least MinKeep
-	if len(toKeep) < retention.MinKeep {
-		for i := 0; i < retention.MinKeep && i < len(snapshots); i++ {
-			toKeep[snapshots[i].ID] = snapshots[i]
-		}
+	for i := 0; len(toKeep) < retention.MinKeep && i < len(snapshots); i++ {
+		toKeep[snapshots[i].ID] = snapshots[i]
 	}
 
-	// Convert map to slice
+	// Convert map to slice, preserving newest-first order
 	result := make([]*Snapshot, 0, len(toKeep))
-	for _, snap := range toKeep {
-		result = append(result, snap)
+	for _, snap := range snapshots {
+		if _, ok := toKeep[snap.ID]; ok {
+			result = append(result, snap)
+		}
 	}
 
 	return result
@@ -787,6 +883,18 @@ func (s *Scheduler) retentionLoop(ctx context.Context) {
 	}
 }
 
+// schedulerState is the on-disk representation of the scheduler.
+type schedulerState struct {
+	Schedules map[string]*Schedule   `json:"schedules"`
+	Snapshots map[string][]*Snapshot `json:"snapshots"`
+}
+
+// LoadState reads schedules and snapshots from the state file without
+// starting the scheduler, for read-only callers such as retention previews.
+func (s *Scheduler) LoadState() error {
+	return s.loadState()
+}
+
 func (s *Scheduler) loadState() error {
 	data, err := os.ReadFile(s.stateFile)
 	if err != nil {
@@ -796,15 +904,14 @@ func (s *Scheduler) loadState() error {
 		return err
 	}
 
-	var state struct {
-		Schedules map[string]*Schedule   `json:"schedules"`
-		Snapshots map[string][]*Snapshot `json:"snapshots"`
-	}
-
+	var state schedulerState
 	if err := json.Unmarshal(data, &state); err != nil {
 		return err
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.schedules = state.Schedules
 	s.snapshots = state.Snapshots
 
@@ -818,27 +925,51 @@ func (s *Scheduler) loadState() error {
 	return nil
 }
 
+// stateCopyLocked copies schedules and snapshots so they can be marshaled
+// after s.mu is released. Callers must hold s.mu.
+func (s *Scheduler) stateCopyLocked() schedulerState {
+	state := schedulerState{
+		Schedules: make(map[string]*Schedule, len(s.schedules)),
+		Snapshots: make(map[string][]*Snapshot, len(s.snapshots)),
+	}
+	for id, schedule := range s.schedules {
+		cp := *schedule
+		state.Schedules[id] = &cp
+	}
+	for subvol, snapshots := range s.snapshots {
+		list := make([]*Snapshot, 0, len(snapshots))
+		for _, snap := range snapshots {
+			cp := *snap
+			list = append(list, &cp)
+		}
+		state.Snapshots[subvol] = list
+	}
+	return state
+}
+
+// saveState persists the current state. It must not be called with s.mu held.
 func (s *Scheduler) saveState() error {
 	s.mu.RLock()
-	state := struct {
-		Schedules map[string]*Schedule   `json:"schedules"`
-		Snapshots map[string][]*Snapshot `json:"snapshots"`
-	}{
-		Schedules: s.schedules,
-		Snapshots: s.snapshots,
-	}
+	state := s.stateCopyLocked()
+	// Take the write lock before releasing mu so saves land in the order
+	// their copies were taken.
+	s.saveMu.Lock()
 	s.mu.RUnlock()
+	defer s.saveMu.Unlock()
 
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return err
-	}
+	return s.writeState(state)
+}
 
-	// Write atomically
-	tmpFile := s.stateFile + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
-		return err
-	}
+// saveStateLocked persists the current state. Callers must hold s.mu.
+func (s *Scheduler) saveStateLocked() error {
+	state := s.stateCopyLocked()
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
 
-	return os.Rename(tmpFile, s.stateFile)
+	return s.writeState(state)
+}
+
+func (s *Scheduler) writeState(state schedulerState) error {
+	// Write atomically
+	return fsatomic.SaveJSON(context.Background(), s.stateFile, state, 0o600)
 }
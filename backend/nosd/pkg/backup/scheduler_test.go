@@ -0,0 +1,309 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"nithronos/backend/nosd/pkg/maintenance"
+)
+
+type fakeAgent struct {
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (f *fakeAgent) CreateSnapshot(subvolume string, path string, readOnly bool) error { return nil }
+func (f *fakeAgent) DeleteSnapshot(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, path)
+	return nil
+}
+func (f *fakeAgent) GetSnapshotInfo(path string) (*SnapshotInfo, error) { return &SnapshotInfo{}, nil }
+func (f *fakeAgent) ExecuteHook(command string) error                   { return nil }
+
+func newTestScheduler(t *testing.T, agent AgentClient) *Scheduler {
+	t.Helper()
+	return NewScheduler(zerolog.Nop(), filepath.Join(t.TempDir(), "state.json"), agent)
+}
+
+func TestDryRunRetentionMatchesApply(t *testing.T) {
+	agent := &fakeAgent{}
+	s := newTestScheduler(t, agent)
+
+	schedule := &Schedule{
+		ID:         "sched-1",
+		Subvolumes: []string{"/srv/data"},
+		Retention:  RetentionPolicy{MinKeep: 2, Days: 3, Weeks: 2},
+	}
+	s.schedules[schedule.ID] = schedule
+
+	// 20 daily snapshots, plus one from another schedule that must be ignored
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		s.snapshots["/srv/data"] = append(s.snapshots["/srv/data"], &Snapshot{
+			ID:         fmt.Sprintf("snap-%02d", i),
+			Subvolume:  "/srv/data",
+			Path:       fmt.Sprintf("/srv/data/.snapshots/snap-%02d", i),
+			CreatedAt:  now.Add(-time.Duration(i)*24*time.Hour - time.Hour),
+			ScheduleID: schedule.ID,
+		})
+	}
+	s.snapshots["/srv/data"] = append(s.snapshots["/srv/data"], &Snapshot{
+		ID: "manual", Subvolume: "/srv/data", Path: "/srv/data/.snapshots/manual", CreatedAt: now.Add(-90 * 24 * time.Hour),
+	})
+
+	preview, err := s.DryRunRetention(schedule.ID)
+	if err != nil {
+		t.Fatalf("DryRunRetention: %v", err)
+	}
+	if len(preview) == 0 {
+		t.Fatal("expected preview to select snapshots for deletion")
+	}
+	if len(agent.deleted) != 0 || len(s.snapshots["/srv/data"]) != 21 {
+		t.Fatal("dry run must not delete snapshots")
+	}
+
+	var previewPaths []string
+	for _, snap := range preview {
+		if snap.ScheduleID != schedule.ID {
+			t.Fatalf("preview selected snapshot from another schedule: %s", snap.ID)
+		}
+		previewPaths = append(previewPaths, snap.Path)
+	}
+
+	s.applyRetention(schedule)
+
+	sort.Strings(previewPaths)
+	sort.Strings(agent.deleted)
+	if fmt.Sprint(previewPaths) != fmt.Sprint(agent.deleted) {
+		t.Fatalf("preview %v does not match deleted %v", previewPaths, agent.deleted)
+	}
+	if got := len(s.snapshots["/srv/data"]); got != 21-len(preview) {
+		t.Fatalf("expected %d snapshots left, got %d", 21-len(preview), got)
+	}
+}
+
+func TestDryRunRetentionUnknownSchedule(t *testing.T) {
+	s := newTestScheduler(t, &fakeAgent{})
+	if _, err := s.DryRunRetention("missing"); err == nil {
+		t.Fatal("expected error for unknown schedule")
+	}
+}
+
+func TestSelectGFSSnapshots(t *testing.T) {
+	// Saturday, ISO week 24
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	series := func(n int, step time.Duration) []*Snapshot {
+		out := make([]*Snapshot, 0, n)
+		for i := 0; i < n; i++ {
+			out = append(out, &Snapshot{
+				ID:        fmt.Sprintf("s%d", i),
+				CreatedAt: now.Add(-time.Duration(i)*step - time.Hour),
+			})
+		}
+		return out
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		snapshots []*Snapshot
+		retention RetentionPolicy
+		want      []string
+	}{
+		{
+			name: "daily keeps newest per day within window",
+			snapshots: []*Snapshot{
+				{ID: "today-1", CreatedAt: now.Add(-1 * time.Hour)},
+				{ID: "today-2", CreatedAt: now.Add(-3 * time.Hour)},
+				{ID: "yesterday", CreatedAt: now.Add(-day)},
+				{ID: "two-days", CreatedAt: now.Add(-2 * day)},
+				{ID: "too-old", CreatedAt: now.Add(-3*day - time.Hour)},
+			},
+			retention: RetentionPolicy{Days: 3},
+			want:      []string{"today-1", "yesterday", "two-days"},
+		},
+		{
+			name:      "weekly keeps one per ISO week",
+			snapshots: series(20, day),
+			retention: RetentionPolicy{Weeks: 2},
+			want:      []string{"s0", "s6"},
+		},
+		{
+			name:      "monthly keeps one per month",
+			snapshots: series(10, 10*day),
+			retention: RetentionPolicy{Months: 2},
+			want:      []string{"s0", "s2"},
+		},
+		{
+			name:      "yearly keeps one per year",
+			snapshots: series(8, 100*day),
+			retention: RetentionPolicy{Years: 2},
+			want:      []string{"s0", "s2"},
+		},
+		{
+			name:      "overlapping tiers count each snapshot once",
+			snapshots: series(20, day),
+			retention: RetentionPolicy{Days: 2, Weeks: 2},
+			want:      []string{"s0", "s1", "s6"},
+		},
+		{
+			name:      "min keep tops up with newest",
+			snapshots: series(5, time.Hour),
+			retention: RetentionPolicy{MinKeep: 3, Days: 1},
+			want:      []string{"s0", "s1", "s2"},
+		},
+		{
+			name:      "min keep counts real snapshots only",
+			snapshots: series(5, day),
+			retention: RetentionPolicy{MinKeep: 2, Weeks: 1},
+			want:      []string{"s0", "s1"},
+		},
+		{
+			name:      "fewer than min keep keeps all",
+			snapshots: series(2, day),
+			retention: RetentionPolicy{MinKeep: 3},
+			want:      []string{"s0", "s1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestScheduler(t, &fakeAgent{})
+			s.now = func() time.Time { return now }
+			got := s.selectGFSSnapshots(tt.snapshots, tt.retention)
+			ids := make([]string, 0, len(got))
+			for _, snap := range got {
+				ids = append(ids, snap.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+// Run with -race: snapshots are created and deleted while state is saved.
+func TestSchedulerConcurrentStateSave(t *testing.T) {
+	s := newTestScheduler(t, &fakeAgent{})
+	if err := s.CreateSchedule(&Schedule{
+		Name:       "nightly",
+		Subvolumes: []string{"/srv/a", "/srv/b"},
+		Frequency:  ScheduleFrequency{Type: "daily", Hour: 2},
+		Retention:  RetentionPolicy{MinKeep: 1, Days: 1},
+	}); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	schedule := s.ListSchedules()[0]
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				job := &BackupJob{ID: fmt.Sprintf("job-%d", j), Type: "snapshot"}
+				s.runSnapshotJob(job, schedule.Subvolumes, "", schedule)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if snaps := s.ListSnapshots(); len(snaps) > 0 {
+					_ = s.DeleteSnapshot(snaps[len(snaps)-1].ID)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := s.saveState(); err != nil {
+					t.Errorf("saveState: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := s.saveState(); err != nil {
+		t.Fatalf("saveState: %v", err)
+	}
+	reloaded := NewScheduler(zerolog.Nop(), s.stateFile, &fakeAgent{})
+	if err := reloaded.loadState(); err != nil {
+		t.Fatalf("loadState: %v", err)
+	}
+	if got, want := len(reloaded.ListSnapshots()), len(s.ListSnapshots()); got != want {
+		t.Fatalf("reloaded %d snapshots, want %d", got, want)
+	}
+	if _, err := reloaded.GetSchedule(schedule.ID); err != nil {
+		t.Fatalf("schedule not persisted: %v", err)
+	}
+}
+
+func TestScheduledBackupMaintenanceWindow(t *testing.T) {
+	s := newTestScheduler(t, &fakeAgent{})
+	w, err := maintenance.ParseWindow("sat 01:00-05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetMaintenanceWindow(w)
+	var deferredFor time.Duration
+	s.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		deferredFor = d
+		return nil
+	}
+	s.schedules["nightly"] = &Schedule{ID: "nightly", Name: "nightly", Subvolumes: []string{"/srv/data"}}
+
+	// Friday 23:00: outside the window, deferred to Saturday 01:00.
+	friday := time.Date(2024, 6, 14, 23, 0, 0, 0, time.Local)
+	s.now = func() time.Time { return friday }
+	s.runScheduledBackup("nightly")
+	jobs := s.jobManager.ListJobs()
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(jobs))
+	}
+	job := jobs[0]
+	if job.State != JobStateDeferred || job.Reason == "" || job.DeferredUntil == nil {
+		t.Fatalf("expected deferred job with reason, got %+v", job)
+	}
+	if deferredFor != 2*time.Hour {
+		t.Fatalf("deferred for %v, want 2h", deferredFor)
+	}
+
+	// Saturday 02:00: inside the window, dispatched immediately.
+	s.now = func() time.Time { return friday.Add(3 * time.Hour) }
+	s.runScheduledBackup("nightly")
+	for _, j := range s.jobManager.ListJobs() {
+		if j.ID != job.ID && (j.State == JobStateDeferred || j.State == JobStateSkipped) {
+			t.Fatalf("in-window job should run, got %+v", j)
+		}
+	}
+
+	// A canceled deferred job is not started when the window opens.
+	_ = s.jobManager.CancelJob(job.ID)
+	s.runDeferred(job.ID)
+	if job.State != JobStateCanceled {
+		t.Fatalf("canceled job restarted: %s", job.State)
+	}
+}
+
+func TestScheduledBackupMaintenanceSkip(t *testing.T) {
+	s := newTestScheduler(t, &fakeAgent{})
+	w, _ := maintenance.ParseWindow("sat 01:00-05:00 skip")
+	s.SetMaintenanceWindow(w)
+	s.schedules["nightly"] = &Schedule{ID: "nightly", Name: "nightly", Subvolumes: []string{"/srv/data"}}
+	s.now = func() time.Time { return time.Date(2024, 6, 14, 23, 0, 0, 0, time.Local) }
+	s.runScheduledBackup("nightly")
+	jobs := s.jobManager.ListJobs()
+	if len(jobs) != 1 || jobs[0].State != JobStateSkipped || jobs[0].Reason == "" {
+		t.Fatalf("expected one skipped job, got %+v", jobs)
+	}
+}
@@ -107,6 +107,8 @@ type BackupJob struct {
 	StartedAt     time.Time         `json:"started_at"`
 	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
 	Error         string            `json:"error,omitempty"`
+	Reason        string            `json:"reason,omitempty"` // why a job was deferred or skipped
+	DeferredUntil *time.Time        `json:"deferred_until,omitempty"`
 	BytesTotal    int64             `json:"bytes_total,omitempty"`
 	BytesDone     int64             `json:"bytes_done,omitempty"`
 	
@@ -123,6 +125,8 @@ const (
 	JobStateSucceeded JobState = "succeeded"
 	JobStateFailed    JobState = "failed"
 	JobStateCanceled  JobState = "canceled"
+	JobStateDeferred  JobState = "deferred" // waiting for the maintenance window
+	JobStateSkipped   JobState = "skipped"  // dropped: came due outside the maintenance window
 )
 
 // LogEntry represents a job log entry
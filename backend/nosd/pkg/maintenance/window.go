@@ -0,0 +1,180 @@
+// Package maintenance decides whether scheduled background work (scrubs,
+// balances, backups) may start at a given time.
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Policies for work that comes due outside the window.
+const (
+	PolicyDefer = "defer" // run at the next window opening
+	PolicySkip  = "skip"  // drop this occurrence
+)
+
+// Dispatch states reported by Decide.
+const (
+	StateRun      = "run"
+	StateDeferred = "deferred"
+	StateSkipped  = "skipped"
+)
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Window is a recurring weekly maintenance window in local time. A window
+// whose End is not after Start wraps past midnight; Days name the day it opens.
+type Window struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Days    []string `json:"days" yaml:"days"`
+	Start   string   `json:"start" yaml:"start"`
+	End     string   `json:"end" yaml:"end"`
+	Policy  string   `json:"policy" yaml:"policy"`
+}
+
+// Decision is the outcome for work due at a given time.
+type Decision struct {
+	Dispatch     bool      `json:"dispatch"`
+	State        string    `json:"state"`
+	Reason       string    `json:"reason,omitempty"`
+	NextEligible time.Time `json:"next_eligible"`
+}
+
+// ParseWindow parses the compact form "sat,sun 01:00-05:00[ skip]".
+// An empty string yields a disabled window.
+func ParseWindow(s string) (Window, error) {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return Window{}, nil
+	}
+	if len(f) < 2 || len(f) > 3 {
+		return Window{}, fmt.Errorf("maintenance window %q: want \"days HH:MM-HH:MM [policy]\"", s)
+	}
+	span := strings.SplitN(f[1], "-", 2)
+	if len(span) != 2 {
+		return Window{}, fmt.Errorf("maintenance window %q: bad time range", s)
+	}
+	w := Window{Enabled: true, Days: strings.Split(f[0], ","), Start: span[0], End: span[1], Policy: PolicyDefer}
+	if len(f) == 3 {
+		w.Policy = f[2]
+	}
+	return w, w.Validate()
+}
+
+// Validate checks days, times and policy of an enabled window.
+func (w Window) Validate() error {
+	if !w.Enabled {
+		return nil
+	}
+	if len(w.Days) == 0 {
+		return fmt.Errorf("maintenance window: no days")
+	}
+	for _, d := range w.Days {
+		if dayIndex(d) < 0 {
+			return fmt.Errorf("maintenance window: unknown day %q", d)
+		}
+	}
+	if _, _, err := parseClock(w.Start); err != nil {
+		return err
+	}
+	if _, _, err := parseClock(w.End); err != nil {
+		return err
+	}
+	if w.Policy != "" && w.Policy != PolicyDefer && w.Policy != PolicySkip {
+		return fmt.Errorf("maintenance window: unknown policy %q", w.Policy)
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the window. A disabled window
+// contains every instant.
+func (w Window) Contains(t time.Time) bool {
+	if !w.Enabled {
+		return true
+	}
+	for _, o := range w.openings(t.AddDate(0, 0, -1), 2) {
+		if !t.Before(o[0]) && t.Before(o[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextEligible returns t if it is inside the window, otherwise the next
+// window opening after t (zero if the window never opens).
+func (w Window) NextEligible(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	for _, o := range w.openings(t, 8) {
+		if o[0].After(t) {
+			return o[0]
+		}
+	}
+	return time.Time{}
+}
+
+// Decide reports whether work due at t may start now and, if not, what
+// happens to it under the window's policy.
+func (w Window) Decide(t time.Time) Decision {
+	if w.Contains(t) {
+		return Decision{Dispatch: true, State: StateRun, NextEligible: t}
+	}
+	next := w.NextEligible(t)
+	reason := fmt.Sprintf("outside maintenance window (%s %s-%s)", strings.Join(w.Days, ","), w.Start, w.End)
+	if w.Policy == PolicySkip {
+		return Decision{State: StateSkipped, Reason: reason, NextEligible: next}
+	}
+	return Decision{State: StateDeferred, Reason: reason, NextEligible: next}
+}
+
+// openings lists [open, close) intervals for n days starting at from's date.
+func (w Window) openings(from time.Time, n int) [][2]time.Time {
+	sh, sm, err1 := parseClock(w.Start)
+	eh, em, err2 := parseClock(w.End)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	wraps := eh*60+em <= sh*60+sm
+	days := map[int]bool{}
+	for _, d := range w.Days {
+		days[dayIndex(d)] = true
+	}
+	var out [][2]time.Time
+	y, m, d := from.Date()
+	for i := 0; i < n; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, from.Location())
+		if !days[int(day.Weekday())] {
+			continue
+		}
+		open := time.Date(y, m, d+i, sh, sm, 0, 0, from.Location())
+		closeAt := time.Date(y, m, d+i, eh, em, 0, 0, from.Location())
+		if wraps {
+			closeAt = closeAt.AddDate(0, 0, 1)
+		}
+		out = append(out, [2]time.Time{open, closeAt})
+	}
+	return out
+}
+
+func dayIndex(d string) int {
+	d = strings.ToLower(strings.TrimSpace(d))
+	if len(d) > 3 {
+		d = d[:3]
+	}
+	for i, n := range dayNames {
+		if n == d {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseClock(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, 0, fmt.Errorf("maintenance window: bad time %q (want HH:MM)", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
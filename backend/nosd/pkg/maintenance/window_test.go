@@ -0,0 +1,80 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+// 2024-06-15 is a Saturday.
+func at(day, hour, min int) time.Time {
+	return time.Date(2024, 6, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow("sat,sun 01:00-05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Enabled || len(w.Days) != 2 || w.Start != "01:00" || w.End != "05:00" || w.Policy != PolicyDefer {
+		t.Fatalf("unexpected window: %+v", w)
+	}
+	if w, err := ParseWindow("mon 22:00-02:00 skip"); err != nil || w.Policy != PolicySkip {
+		t.Fatalf("skip policy: %+v %v", w, err)
+	}
+	if w, err := ParseWindow(""); err != nil || w.Enabled {
+		t.Fatalf("empty should be disabled: %+v %v", w, err)
+	}
+	for _, bad := range []string{"sat", "funday 01:00-02:00", "sat 1am-2am", "sat 01:00-02:00 later"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestDecideInAndOutOfWindow(t *testing.T) {
+	w, _ := ParseWindow("sat,sun 01:00-05:00")
+
+	d := w.Decide(at(15, 2, 30))
+	if !d.Dispatch || d.State != StateRun {
+		t.Fatalf("in window should dispatch: %+v", d)
+	}
+	if d := w.Decide(at(15, 5, 0)); d.Dispatch {
+		t.Fatal("window end is exclusive")
+	}
+
+	// Friday night: deferred to Saturday 01:00.
+	d = w.Decide(at(14, 23, 0))
+	if d.Dispatch || d.State != StateDeferred || d.Reason == "" {
+		t.Fatalf("out of window should defer: %+v", d)
+	}
+	if !d.NextEligible.Equal(at(15, 1, 0)) {
+		t.Fatalf("next eligible: %v", d.NextEligible)
+	}
+
+	// Sunday after close: next opening is the following Saturday.
+	if next := w.NextEligible(at(16, 6, 0)); !next.Equal(at(22, 1, 0)) {
+		t.Fatalf("next eligible after sunday: %v", next)
+	}
+
+	w.Policy = PolicySkip
+	if d := w.Decide(at(14, 23, 0)); d.Dispatch || d.State != StateSkipped {
+		t.Fatalf("skip policy: %+v", d)
+	}
+}
+
+func TestWindowWrapsPastMidnight(t *testing.T) {
+	w, _ := ParseWindow("fri 22:00-02:00")
+	if !w.Contains(at(14, 23, 0)) || !w.Contains(at(15, 1, 59)) {
+		t.Fatal("friday 22:00 to saturday 02:00 should be inside")
+	}
+	if w.Contains(at(15, 2, 0)) || w.Contains(at(14, 21, 59)) {
+		t.Fatal("outside the wrapped span")
+	}
+}
+
+func TestDisabledWindowAlwaysDispatches(t *testing.T) {
+	var w Window
+	if d := w.Decide(at(14, 12, 0)); !d.Dispatch {
+		t.Fatalf("disabled window should dispatch: %+v", d)
+	}
+}
@@ -0,0 +1,50 @@
+package snapdb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"nithronos/backend/nosd/internal/fsatomic"
+)
+
+// PendingBoot is an applied update whose next boot has not been confirmed.
+// It lives next to the index so it survives the reboot it is waiting for.
+type PendingBoot struct {
+	TxID    string           `json:"tx_id"`
+	Targets []SnapshotTarget `json:"targets"`
+	// BootID is the kernel boot_id the update was applied in; a different
+	// one means the system has rebooted since.
+	BootID    string    `json:"boot_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func pathPending() string { return filepath.Join(baseDir(), "pending-boot.json") }
+
+// SetPending records p, replacing any earlier pending update.
+func SetPending(p PendingBoot) error {
+	if err := EnsureDir(); err != nil {
+		return err
+	}
+	return fsatomic.SaveJSON(context.Background(), pathPending(), p, 0o600)
+}
+
+// GetPending returns the pending update, or nil when there is none.
+func GetPending() (*PendingBoot, error) {
+	var p PendingBoot
+	ok, err := fsatomic.LoadJSON(pathPending(), &p)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ClearPending removes the pending update, if any.
+func ClearPending() error {
+	if err := os.Remove(pathPending()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package snapdb
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io/fs"
@@ -33,6 +34,8 @@ type UpdateTx struct {
 	Targets    []SnapshotTarget `json:"targets"`
 	Success    *bool            `json:"success,omitempty"`
 	Notes      string           `json:"notes,omitempty"`
+	// DryRun marks a preview: nothing was snapshotted or installed
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 // baseDir returns the directory to store the snapshots index under.
@@ -98,6 +101,99 @@ func ListRecent(n int) ([]UpdateTx, error) {
 	return idx[:n], nil
 }
 
+// ErrBadCursor is returned by ListPage for a cursor it did not issue.
+var ErrBadCursor = errors.New("invalid cursor")
+
+// Query selects a page of transactions for ListPage.
+type Query struct {
+	// Limit caps the page size; <= 0 means no limit.
+	Limit int
+	// Cursor is the NextCursor of the previous page, "" for the first.
+	Cursor string
+	// Success keeps only finished-and-succeeded (true) or failed and
+	// unfinished (false) transactions when set.
+	Success *bool
+	// Package keeps only transactions that touched this package.
+	Package string
+}
+
+// ListPage returns transactions ordered by StartedAt desc (TxID desc on
+// ties) that match q, plus the cursor for the next page ("" on the last).
+// Cursors encode the position of the last item rather than an offset, so
+// transactions appended between calls don't shift later pages.
+func ListPage(q Query) ([]UpdateTx, string, error) {
+	var after *UpdateTx
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &c
+	}
+	idx, err := readAll()
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(idx, func(i, j int) bool { return newer(idx[i], idx[j]) })
+	out := []UpdateTx{}
+	for _, tx := range idx {
+		if after != nil && !newer(*after, tx) {
+			continue
+		}
+		if !q.matches(tx) {
+			continue
+		}
+		if q.Limit > 0 && len(out) == q.Limit {
+			return out, encodeCursor(out[len(out)-1]), nil
+		}
+		out = append(out, tx)
+	}
+	return out, "", nil
+}
+
+func (q Query) matches(tx UpdateTx) bool {
+	if q.Success != nil && (tx.Success != nil && *tx.Success) != *q.Success {
+		return false
+	}
+	if q.Package != "" {
+		for _, p := range tx.Packages {
+			if p == q.Package {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// newer reports whether a sorts before b in ListPage order.
+func newer(a, b UpdateTx) bool {
+	if !a.StartedAt.Equal(b.StartedAt) {
+		return a.StartedAt.After(b.StartedAt)
+	}
+	return a.TxID > b.TxID
+}
+
+func encodeCursor(tx UpdateTx) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tx.StartedAt.UTC().Format(time.RFC3339Nano) + "|" + tx.TxID))
+}
+
+func decodeCursor(s string) (UpdateTx, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return UpdateTx{}, ErrBadCursor
+	}
+	ts, id, ok := strings.Cut(string(b), "|")
+	if !ok {
+		return UpdateTx{}, ErrBadCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return UpdateTx{}, ErrBadCursor
+	}
+	return UpdateTx{TxID: id, StartedAt: t}, nil
+}
+
 // Internal helpers
 
 func readAll() ([]UpdateTx, error) {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -112,3 +113,100 @@ func TestFindByTx_ReturnsRecord(t *testing.T) {
 		t.Fatalf("unexpected %+v", rec)
 	}
 }
+
+func pageIDs(items []UpdateTx) []string {
+	ids := make([]string, 0, len(items))
+	for _, tx := range items {
+		ids = append(ids, tx.TxID)
+	}
+	return ids
+}
+
+func TestListPage_Boundaries(t *testing.T) {
+	cleanup := withTempDB(t)
+	defer cleanup()
+	base := time.Now().UTC()
+	// p4 and p3 share a timestamp; TxID breaks the tie
+	for i, at := range []time.Duration{-4, -3, -1, -1} {
+		_ = Append(UpdateTx{TxID: "p" + string(rune('1'+i)), StartedAt: base.Add(at * time.Hour)})
+	}
+
+	var got []string
+	cursor := ""
+	for page := 0; ; page++ {
+		items, next, err := ListPage(Query{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		if len(items) != 2 {
+			t.Fatalf("page %d: want 2 items, got %v", page, pageIDs(items))
+		}
+		got = append(got, pageIDs(items)...)
+		if next == "" {
+			break
+		}
+		if page > 2 {
+			t.Fatalf("cursor never ended")
+		}
+		cursor = next
+	}
+	// an exact multiple of the limit must not leave a dangling empty page
+	if want := "p4 p3 p2 p1"; strings.Join(got, " ") != want {
+		t.Fatalf("order: got %v want %s", got, want)
+	}
+
+	items, next, _ := ListPage(Query{Limit: 3})
+	if len(items) != 3 || next == "" {
+		t.Fatalf("first page of 3: %v next=%q", pageIDs(items), next)
+	}
+	// a newer transaction appended between pages doesn't shift the next one
+	_ = Append(UpdateTx{TxID: "p5", StartedAt: base})
+	rest, next, _ := ListPage(Query{Limit: 3, Cursor: next})
+	if strings.Join(pageIDs(rest), " ") != "p1" || next != "" {
+		t.Fatalf("second page: %v next=%q", pageIDs(rest), next)
+	}
+
+	if _, _, err := ListPage(Query{Cursor: "not-a-cursor"}); err != ErrBadCursor {
+		t.Fatalf("want ErrBadCursor, got %v", err)
+	}
+}
+
+func TestListPage_Filters(t *testing.T) {
+	cleanup := withTempDB(t)
+	defer cleanup()
+	base := time.Now().UTC()
+	yes, no := true, false
+	_ = Append(UpdateTx{TxID: "ok-nosd", StartedAt: base.Add(-3 * time.Hour), Packages: []string{"nosd", "nos-agent"}, Success: &yes})
+	_ = Append(UpdateTx{TxID: "fail-nosd", StartedAt: base.Add(-2 * time.Hour), Packages: []string{"nosd"}, Success: &no})
+	_ = Append(UpdateTx{TxID: "ok-web", StartedAt: base.Add(-1 * time.Hour), Packages: []string{"nos-web"}, Success: &yes})
+	_ = Append(UpdateTx{TxID: "running", StartedAt: base, Packages: []string{"nosd"}})
+
+	for _, tc := range []struct {
+		q    Query
+		want string
+	}{
+		{Query{Success: &yes}, "ok-web ok-nosd"},
+		{Query{Success: &no}, "running fail-nosd"},
+		{Query{Package: "nosd"}, "running fail-nosd ok-nosd"},
+		{Query{Package: "nos"}, ""},
+		{Query{Package: "nosd", Success: &yes}, "ok-nosd"},
+	} {
+		items, next, err := ListPage(tc.q)
+		if err != nil || next != "" {
+			t.Fatalf("%+v: err=%v next=%q", tc.q, err, next)
+		}
+		if got := strings.Join(pageIDs(items), " "); got != tc.want {
+			t.Fatalf("%+v: got %q want %q", tc.q, got, tc.want)
+		}
+	}
+
+	// filters apply before the limit, so pages stay full
+	items, next, _ := ListPage(Query{Package: "nosd", Limit: 2})
+	if strings.Join(pageIDs(items), " ") != "running fail-nosd" || next == "" {
+		t.Fatalf("filtered page: %v next=%q", pageIDs(items), next)
+	}
+	items, next, _ = ListPage(Query{Package: "nosd", Limit: 2, Cursor: next})
+	if strings.Join(pageIDs(items), " ") != "ok-nosd" || next != "" {
+		t.Fatalf("filtered second page: %v next=%q", pageIDs(items), next)
+	}
+}
@@ -5,10 +5,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
@@ -33,19 +31,18 @@ func newLoginCmd() *cobra.Command {
 				return fmt.Errorf("authentication failed: %w", err)
 			}
 			
-			// Save to config
-			viper.Set("token", inputToken)
-			viper.Set("url", baseURL)
-			
-			configPath := filepath.Join(os.Getenv("HOME"), ".config", "nos", "cli.yaml")
-			os.MkdirAll(filepath.Dir(configPath), 0755)
-			
-			if err := viper.WriteConfigAs(configPath); err != nil {
+			// Save to config, in the active profile if there is one
+			configPath := cliConfigPath()
+			if err := saveLogin(configPath, activeProfile, baseURL, inputToken); err != nil {
 				return fmt.Errorf("failed to save config: %w", err)
 			}
 			
 			fmt.Println("✓ Authentication successful")
-			fmt.Printf("✓ Configuration saved to %s\n", configPath)
+			if activeProfile != "" {
+				fmt.Printf("✓ Profile %s saved to %s\n", activeProfile, configPath)
+			} else {
+				fmt.Printf("✓ Configuration saved to %s\n", configPath)
+			}
 			return nil
 		},
 	}
@@ -67,8 +64,8 @@ func newStatusCmd() *cobra.Command {
 				return err
 			}
 			
-			if outputJSON {
-				printJSON(status)
+			if structuredOutput() {
+				printObject(status)
 			} else {
 				fmt.Printf("System Status\n")
 				fmt.Printf("=============\n")
@@ -123,8 +120,8 @@ func newSystemCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(info)
+				if structuredOutput() {
+					printObject(info)
 				} else {
 					fmt.Printf("System Information\n")
 					fmt.Printf("==================\n")
@@ -170,21 +167,17 @@ func newStorageCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(snapshots)
-				} else {
-					headers := []string{"ID", "Subvolume", "Created", "Size"}
-					rows := [][]string{}
-					for _, snap := range snapshots {
-						rows = append(rows, []string{
-							snap.ID[:8],
-							snap.Subvolume,
-							snap.CreatedAt,
-							formatBytes(snap.Size),
-						})
-					}
-					printTable(headers, rows)
+				headers := []string{"ID", "Subvolume", "Created", "Size"}
+				rows := [][]string{}
+				for _, snap := range snapshots {
+					rows = append(rows, []string{
+						snap.ID[:8],
+						snap.Subvolume,
+						snap.CreatedAt,
+						formatBytes(snap.Size),
+					})
 				}
+				printList(snapshots, headers, rows)
 				
 				return nil
 			},
@@ -203,8 +196,8 @@ func newStorageCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(job)
+				if structuredOutput() {
+					printObject(job)
 				} else {
 					fmt.Printf("✓ Snapshot creation started\n")
 					fmt.Printf("  Job ID: %s\n", job.ID)
@@ -256,22 +249,18 @@ func newAppsCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(apps)
-				} else {
-					headers := []string{"ID", "Name", "Version", "Status", "Health"}
-					rows := [][]string{}
-					for _, app := range apps {
-						rows = append(rows, []string{
-							app.ID,
-							app.Name,
-							app.Version,
-							app.Status,
-							app.Health,
-						})
-					}
-					printTable(headers, rows)
+				headers := []string{"ID", "Name", "Version", "Status", "Health"}
+				rows := [][]string{}
+				for _, app := range apps {
+					rows = append(rows, []string{
+						app.ID,
+						app.Name,
+						app.Version,
+						app.Status,
+						app.Health,
+					})
 				}
+				printList(apps, headers, rows)
 				
 				return nil
 			},
@@ -366,8 +355,8 @@ func newBackupsCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(job)
+				if structuredOutput() {
+					printObject(job)
 				} else {
 					fmt.Printf("✓ Backup started\n")
 					fmt.Printf("  Job ID: %s\n", job.ID)
@@ -396,8 +385,8 @@ func newBackupsCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(job)
+				if structuredOutput() {
+					printObject(job)
 				} else {
 					fmt.Printf("✓ Restore started\n")
 					fmt.Printf("  Job ID: %s\n", job.ID)
@@ -418,8 +407,8 @@ func newBackupsCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(job)
+				if structuredOutput() {
+					printObject(job)
 				} else {
 					fmt.Printf("Job Status\n")
 					fmt.Printf("==========\n")
@@ -469,31 +458,27 @@ func newAlertsCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(rules)
-				} else {
-					headers := []string{"ID", "Name", "Metric", "Threshold", "Enabled", "Firing"}
-					rows := [][]string{}
-					for _, rule := range rules {
-						enabled := "No"
-						if rule.Enabled {
-							enabled = "Yes"
-						}
-						firing := "No"
-						if rule.CurrentState.Firing {
-							firing = "Yes"
-						}
-						rows = append(rows, []string{
-							rule.ID[:8],
-							rule.Name,
-							rule.Metric,
-							fmt.Sprintf("%s %.1f", rule.Operator, rule.Threshold),
-							enabled,
-							firing,
-						})
+				headers := []string{"ID", "Name", "Metric", "Threshold", "Enabled", "Firing"}
+				rows := [][]string{}
+				for _, rule := range rules {
+					enabled := "No"
+					if rule.Enabled {
+						enabled = "Yes"
 					}
-					printTable(headers, rows)
+					firing := "No"
+					if rule.CurrentState.Firing {
+						firing = "Yes"
+					}
+					rows = append(rows, []string{
+						rule.ID[:8],
+						rule.Name,
+						rule.Metric,
+						fmt.Sprintf("%s %.1f", rule.Operator, rule.Threshold),
+						enabled,
+						firing,
+					})
 				}
+				printList(rules, headers, rows)
 				
 				return nil
 			},
@@ -520,8 +505,8 @@ func newAlertsCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(rule)
+				if structuredOutput() {
+					printObject(rule)
 				} else {
 					fmt.Printf("✓ Alert rule created\n")
 					fmt.Printf("  ID: %s\n", rule.ID)
@@ -563,26 +548,22 @@ func newTokensCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
-					printJSON(tokens)
-				} else {
-					headers := []string{"ID", "Name", "Type", "Created", "Last Used"}
-					rows := [][]string{}
-					for _, t := range tokens {
-						lastUsed := "Never"
-						if t.LastUsedAt != "" {
-							lastUsed = t.LastUsedAt
-						}
-						rows = append(rows, []string{
-							t.ID[:8],
-							t.Name,
-							t.Type,
-							t.CreatedAt,
-							lastUsed,
-						})
+				headers := []string{"ID", "Name", "Type", "Created", "Last Used"}
+				rows := [][]string{}
+				for _, t := range tokens {
+					lastUsed := "Never"
+					if t.LastUsedAt != "" {
+						lastUsed = t.LastUsedAt
 					}
-					printTable(headers, rows)
+					rows = append(rows, []string{
+						t.ID[:8],
+						t.Name,
+						t.Type,
+						t.CreatedAt,
+						lastUsed,
+					})
 				}
+				printList(tokens, headers, rows)
 				
 				return nil
 			},
@@ -606,13 +587,13 @@ func newTokensCmd() *cobra.Command {
 					return err
 				}
 				
-				if outputJSON {
+				if structuredOutput() {
 					// Include token value in JSON output
 					output := map[string]interface{}{
 						"token": newToken,
 						"value": tokenValue,
 					}
-					printJSON(output)
+					printObject(output)
 				} else {
 					fmt.Printf("✓ Token created\n")
 					fmt.Printf("  ID:    %s\n", newToken.ID)
@@ -710,6 +691,7 @@ func newVersionCmd() *cobra.Command {
 
 // newCompletionCmd creates the completion command
 func newCompletionCmd() *cobra.Command {
+	var install bool
 	cmd := &cobra.Command{
 		Use:   "completion [bash|zsh|fish|powershell]",
 		Short: "Generate shell completion script",
@@ -737,28 +719,101 @@ PowerShell:
   # To load completions for every new session, run:
   PS> nosctl completion powershell > nosctl.ps1
   # and source this file from your PowerShell profile.
+
+With --install the script is written to the per-user completion directory
+of the shell (bash, zsh, fish); without a shell argument $SHELL is used.
 `,
-		Args:      cobra.ExactArgs(1),
+		Args:      cobra.MaximumNArgs(1),
 		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			switch args[0] {
-			case "bash":
-				return rootCmd.GenBashCompletion(os.Stdout)
-			case "zsh":
-				return rootCmd.GenZshCompletion(os.Stdout)
-			case "fish":
-				return rootCmd.GenFishCompletion(os.Stdout, true)
-			case "powershell":
-				return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
-			default:
-				return fmt.Errorf("unsupported shell: %s", args[0])
+			shell := ""
+			if len(args) == 1 {
+				shell = args[0]
+			} else if install {
+				shell = filepath.Base(os.Getenv("SHELL"))
+			} else {
+				return fmt.Errorf("shell required: bash, zsh, fish or powershell")
+			}
+			if !install {
+				return genCompletion(shell, cmd.OutOrStdout())
+			}
+			path, err := installCompletion(shell)
+			if err != nil {
+				return err
 			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ %s completion installed to %s\n", shell, path)
+			if shell == "zsh" {
+				fmt.Fprintf(cmd.OutOrStdout(), "  Add %s to fpath before compinit in ~/.zshrc\n", filepath.Dir(path))
+			}
+			return nil
 		},
 	}
 	
+	cmd.Flags().BoolVar(&install, "install", false, "write the script to the shell's completion directory")
 	return cmd
 }
 
+func genCompletion(shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return rootCmd.GenBashCompletion(w)
+	case "zsh":
+		return rootCmd.GenZshCompletion(w)
+	case "fish":
+		return rootCmd.GenFishCompletion(w, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(w)
+	default:
+		return fmt.Errorf("unsupported shell: %s", shell)
+	}
+}
+
+// completionInstallPath is where each shell picks up per-user completions
+// without root: bash-completion's XDG directory, a site-functions directory
+// for zsh and fish's completions directory.
+func completionInstallPath(shell string) (string, error) {
+	home := os.Getenv("HOME")
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(dataHome, "bash-completion", "completions", "nosctl"), nil
+	case "zsh":
+		return filepath.Join(dataHome, "zsh", "site-functions", "_nosctl"), nil
+	case "fish":
+		return filepath.Join(configHome, "fish", "completions", "nosctl.fish"), nil
+	case "powershell":
+		return "", fmt.Errorf("--install does not support powershell; source the script from your profile instead")
+	default:
+		return "", fmt.Errorf("unsupported shell: %q", shell)
+	}
+}
+
+func installCompletion(shell string) (string, error) {
+	path, err := completionInstallPath(shell)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := genCompletion(shell, f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
 // Helper function to format bytes
 func formatBytes(bytes int64) string {
 	const unit = 1024
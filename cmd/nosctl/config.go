@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// cliConfigKeys are the settings nosctl reads from cli.yaml.
+var cliConfigKeys = []string{"token", "url"}
+
+// cliConfigPath is --config when given, else ~/.config/nos/cli.yaml.
+func cliConfigPath() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "nos", "cli.yaml")
+}
+
+// cliConfig is the layout of cli.yaml. Profile names the default entry of
+// Profiles; the top-level URL and Token are used when no profile applies.
+type cliConfig struct {
+	URL      string                `yaml:"url,omitempty"`
+	Token    string                `yaml:"token,omitempty"`
+	Profile  string                `yaml:"profile,omitempty"`
+	Profiles map[string]cliProfile `yaml:"profiles,omitempty"`
+}
+
+// cliProfile is one server's endpoint and credentials.
+type cliProfile struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token,omitempty"`
+}
+
+// loadCLIConfig reads path directly, so flags and NOS_* environment
+// overrides never leak into the file. A missing file is empty.
+func loadCLIConfig(path string) (*cliConfig, error) {
+	c := &cliConfig{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// save writes the file. It holds API tokens, so it is only readable by the
+// owner.
+func (c *cliConfig) save(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return os.Chmod(path, 0o600)
+}
+
+func (c *cliConfig) get(key string) string {
+	if key == "url" {
+		return c.URL
+	}
+	return c.Token
+}
+
+func checkCLIConfigKey(key string) error {
+	for _, k := range cliConfigKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key %q (valid: %s)", key, strings.Join(cliConfigKeys, ", "))
+}
+
+// normalizeAPIURL checks that value is an http(s) URL and drops trailing
+// slashes.
+func normalizeAPIURL(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("url must be an http(s) URL, got %q", value)
+	}
+	return strings.TrimRight(value, "/"), nil
+}
+
+func getCLIConfig(path, key string) (string, error) {
+	if err := checkCLIConfigKey(key); err != nil {
+		return "", err
+	}
+	c, err := loadCLIConfig(path)
+	if err != nil {
+		return "", err
+	}
+	return c.get(key), nil
+}
+
+// setCLIConfig writes one top-level key, keeping the rest of the file.
+func setCLIConfig(path, key, value string) error {
+	if err := checkCLIConfigKey(key); err != nil {
+		return err
+	}
+	c, err := loadCLIConfig(path)
+	if err != nil {
+		return err
+	}
+	if key == "url" {
+		if c.URL, err = normalizeAPIURL(value); err != nil {
+			return err
+		}
+	} else {
+		c.Token = value
+	}
+	return c.save(path)
+}
+
+// viewCLIConfig prints every key; the token is masked unless showSecrets.
+func viewCLIConfig(w io.Writer, path string, showSecrets bool) error {
+	c, err := loadCLIConfig(path)
+	if err != nil {
+		return err
+	}
+	keys := append([]string(nil), cliConfigKeys...)
+	sort.Strings(keys)
+	fmt.Fprintf(w, "# %s\n", path)
+	for _, k := range keys {
+		val := c.get(k)
+		if k == "token" && val != "" && !showSecrets {
+			val = maskToken(val)
+		}
+		fmt.Fprintf(w, "%s: %s\n", k, val)
+	}
+	return nil
+}
+
+func maskToken(t string) string {
+	if len(t) <= 8 {
+		return "****"
+	}
+	return t[:4] + "****" + t[len(t)-4:]
+}
+
+// newConfigCmd creates the config command
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and change the nosctl configuration",
+		Long: `View and change the nosctl configuration (url, token).
+
+The file is ~/.config/nos/cli.yaml unless --config is given.`,
+	}
+
+	getCmd := &cobra.Command{
+		Use:       "get <key>",
+		Short:     "Print a configuration value",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: cliConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			val, err := getCLIConfig(cliConfigPath(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), val)
+			return nil
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:       "set <key> <value>",
+		Short:     "Set a configuration value",
+		Args:      cobra.ExactArgs(2),
+		ValidArgs: cliConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cliConfigPath()
+			if err := setCLIConfig(path, args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ %s saved to %s\n", args[0], path)
+			return nil
+		},
+	}
+
+	var showSecrets bool
+	viewCmd := &cobra.Command{
+		Use:   "view",
+		Short: "Show the configuration file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return viewCLIConfig(cmd.OutOrStdout(), cliConfigPath(), showSecrets)
+		},
+	}
+	viewCmd.Flags().BoolVar(&showSecrets, "show-token", false, "print the token unmasked")
+
+	cmd.AddCommand(getCmd, setCmd, viewCmd)
+	return cmd
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLIConfigRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nos", "cli.yaml")
+
+	// a missing file reads as empty
+	if v, err := getCLIConfig(path, "url"); err != nil || v != "" {
+		t.Fatalf("get from missing file = %q, %v", v, err)
+	}
+	if err := setCLIConfig(path, "url", "https://nas.local:9000/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setCLIConfig(path, "token", "nos_abcdef0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	// setting one key keeps the other
+	if v, _ := getCLIConfig(path, "url"); v != "https://nas.local:9000" {
+		t.Fatalf("url = %q", v)
+	}
+	if v, _ := getCLIConfig(path, "token"); v != "nos_abcdef0123456789" {
+		t.Fatalf("token = %q", v)
+	}
+	if st, err := os.Stat(path); err != nil || st.Mode().Perm() != 0o600 {
+		t.Fatalf("config file mode = %v, %v; want 0600", st.Mode().Perm(), err)
+	}
+
+	var out bytes.Buffer
+	if err := viewCLIConfig(&out, path, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "url: https://nas.local:9000") || !strings.Contains(out.String(), "token: nos_****6789") {
+		t.Fatalf("view output:\n%s", out.String())
+	}
+
+	if err := setCLIConfig(path, "colour", "blue"); err == nil {
+		t.Fatal("unknown key accepted")
+	}
+	if err := setCLIConfig(path, "url", "nas.local"); err == nil {
+		t.Fatal("url without a scheme accepted")
+	}
+}
+
+func TestConfigCommandUsesConfigFlag(t *testing.T) {
+	cfgFile = filepath.Join(t.TempDir(), "cli.yaml")
+	t.Cleanup(func() { cfgFile = "" })
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := newConfigCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("config %v: %v", args, err)
+		}
+		return out.String()
+	}
+	run("set", "url", "http://10.0.0.5:9000")
+	if got := strings.TrimSpace(run("get", "url")); got != "http://10.0.0.5:9000" {
+		t.Fatalf("config get url = %q", got)
+	}
+}
+
+func TestCompletionInstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	path, err := installCompletion("fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(home, ".config", "fish", "completions", "nosctl.fish") {
+		t.Fatalf("fish path = %s", path)
+	}
+	if b, err := os.ReadFile(path); err != nil || !strings.Contains(string(b), "nosctl") {
+		t.Fatalf("script not written: %v", err)
+	}
+	if _, err := installCompletion("powershell"); err == nil {
+		t.Fatal("powershell install should be refused")
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// eventSeverities are the event levels nosd writes, least severe first.
+var eventSeverities = []string{"info", "warning", "error", "critical"}
+
+var errEventStreamEnded = errors.New("event stream ended")
+
+// Event is one entry of the system event log.
+type Event struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Category  string    `json:"category"`
+	Message   string    `json:"message"`
+	Details   any       `json:"details,omitempty"`
+}
+
+func (c *APIClient) listEvents() ([]Event, error) {
+	data, err := c.doRequest("GET", "/api/v1/monitoring/events", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// openEventStream connects to the server-sent event stream of new events.
+// It doesn't use c.httpClient, whose timeout would cut the stream off.
+func (c *APIClient) openEventStream(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/monitoring/events/stream", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// readEventStream calls fn for every "event" message of an SSE body until
+// the body ends or fn fails. Comments and other message types are skipped.
+func readEventStream(r io.Reader, fn func(Event) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var kind, data string
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if data != "" && (kind == "" || kind == "event") {
+				var ev Event
+				if err := json.Unmarshal([]byte(data), &ev); err != nil {
+					return fmt.Errorf("bad event %q: %w", data, err)
+				}
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			kind, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			kind = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return errEventStreamEnded
+}
+
+// eventFilter selects events by age and minimum severity.
+type eventFilter struct {
+	since    time.Time
+	minLevel int
+}
+
+// newEventFilter parses --since (a duration such as 2h, an RFC 3339 time
+// or a date) and --severity (the least severe level to show).
+func newEventFilter(since, severity string, now time.Time) (eventFilter, error) {
+	var f eventFilter
+	if since = strings.TrimSpace(since); since != "" {
+		if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			f.since = now.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			f.since = t
+		} else if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+			f.since = t
+		} else {
+			return f, fmt.Errorf("invalid --since %q: use a duration (30m, 2h), an RFC 3339 time or a date", since)
+		}
+	}
+	if severity = strings.ToLower(strings.TrimSpace(severity)); severity != "" {
+		f.minLevel = severityRank(severity)
+		if f.minLevel < 0 {
+			return f, fmt.Errorf("invalid --severity %q (valid: %s)", severity, strings.Join(eventSeverities, ", "))
+		}
+	}
+	return f, nil
+}
+
+// severityRank is the index of level in eventSeverities, or -1.
+func severityRank(level string) int {
+	if level == "warn" {
+		level = "warning"
+	}
+	for i, s := range eventSeverities {
+		if s == level {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f eventFilter) match(ev Event) bool {
+	if !f.since.IsZero() && ev.Timestamp.Before(f.since) {
+		return false
+	}
+	// unknown levels are shown as info
+	rank := severityRank(strings.ToLower(ev.Level))
+	if rank < 0 {
+		rank = 0
+	}
+	return rank >= f.minLevel
+}
+
+var eventHeaders = []string{"Time", "Severity", "Category", "Message"}
+
+func eventRow(ev Event) []string {
+	return []string{ev.Timestamp.Local().Format("2006-01-02 15:04:05"), ev.Level, ev.Category, ev.Message}
+}
+
+// eventPrinter writes followed events one at a time: table and csv rows
+// under a single header, one JSON object per line, or one YAML document
+// per event.
+type eventPrinter struct {
+	w      io.Writer
+	format string
+	csv    *csv.Writer
+}
+
+func newEventPrinter(w io.Writer, format string) *eventPrinter {
+	p := &eventPrinter{w: w, format: format}
+	switch format {
+	case outputCSV:
+		p.csv = csv.NewWriter(w)
+		_ = p.csv.Write(eventHeaders)
+		p.csv.Flush()
+	case outputTable:
+		writeTableRow(w, eventHeaders)
+	}
+	return p
+}
+
+func (p *eventPrinter) print(ev Event) error {
+	switch p.format {
+	case outputCSV:
+		_ = p.csv.Write(eventRow(ev))
+		p.csv.Flush()
+		return p.csv.Error()
+	case outputJSONF:
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	case outputYAML:
+		if _, err := fmt.Fprintln(p.w, "---"); err != nil {
+			return err
+		}
+		return renderObject(p.w, outputYAML, ev)
+	default:
+		writeTableRow(p.w, eventRow(ev))
+		return nil
+	}
+}
+
+// eventsOptions are the events command's flags.
+type eventsOptions struct {
+	since    string
+	severity string
+	follow   bool
+}
+
+// runEvents prints the recent events that pass the filter, oldest first.
+// With follow it then prints new events as they arrive until ctx is done;
+// the stream is opened before the backlog is read, so nothing in between is
+// lost, and events already printed are not repeated.
+func runEvents(ctx context.Context, client *APIClient, w io.Writer, opts eventsOptions) error {
+	filter, err := newEventFilter(opts.since, opts.severity, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var stream io.ReadCloser
+	if opts.follow {
+		if stream, err = client.openEventStream(ctx); err != nil {
+			return err
+		}
+		defer stream.Close()
+	}
+
+	backlog, err := client.listEvents()
+	if err != nil {
+		return err
+	}
+	events := []Event{}
+	for _, ev := range backlog {
+		if filter.match(ev) {
+			events = append(events, ev)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+
+	if !opts.follow {
+		rows := [][]string{}
+		for _, ev := range events {
+			rows = append(rows, eventRow(ev))
+		}
+		return renderList(w, outputFormat, events, eventHeaders, rows)
+	}
+
+	p := newEventPrinter(w, outputFormat)
+	seen := map[string]bool{}
+	for _, ev := range events {
+		seen[ev.ID] = true
+		if err := p.print(ev); err != nil {
+			return err
+		}
+	}
+	err = readEventStream(stream, func(ev Event) error {
+		if (ev.ID != "" && seen[ev.ID]) || !filter.match(ev) {
+			return nil
+		}
+		return p.print(ev)
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// newEventsCmd creates the events command
+func newEventsCmd() *cobra.Command {
+	var opts eventsOptions
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Show recent system events",
+		Long: `Show recent system events, oldest first.
+
+--since takes a duration (30m, 2h), an RFC 3339 time or a date; --severity
+shows that level and the more severe ones (info, warning, error, critical).
+With --follow, new events are printed as they happen until interrupted;
+json output is then one object per line and yaml one document per event.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runEvents(ctx, newAPIClient(baseURL, token), cmd.OutOrStdout(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.since, "since", "", "only events newer than a duration (e.g. 2h) or time")
+	cmd.Flags().StringVar(&opts.severity, "severity", "", "minimum severity: info, warning, error or critical")
+	cmd.Flags().BoolVarP(&opts.follow, "follow", "f", false, "stream new events as they happen")
+	_ = cmd.RegisterFlagCompletionFunc("severity", cobra.FixedCompletions(eventSeverities, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const cannedEvents = `[
+  {"id":"e1","timestamp":"2026-10-17T08:00:00Z","level":"info","category":"system","message":"System started"},
+  {"id":"e3","timestamp":"2026-10-17T09:30:00Z","level":"error","category":"storage","message":"Scrub found errors"},
+  {"id":"e2","timestamp":"2026-10-17T09:00:00Z","level":"warning","category":"auth","message":"Failed login"}
+]`
+
+// eventsStub serves the canned backlog and a stream that replays stream,
+// one SSE message per entry, then closes unless hold is set.
+func eventsStub(t *testing.T, stream []string, hold bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/monitoring/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer nos_test" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, cannedEvents)
+	})
+	mux.HandleFunc("/api/v1/monitoring/events/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		w.(http.Flusher).Flush()
+		for _, msg := range stream {
+			fmt.Fprint(w, msg)
+			w.(http.Flusher).Flush()
+		}
+		if hold {
+			<-r.Context().Done()
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func withOutputFormat(t *testing.T, format string) {
+	t.Helper()
+	old, oldLocal := outputFormat, time.Local
+	outputFormat, time.Local = format, time.UTC
+	t.Cleanup(func() { outputFormat, time.Local = old, oldLocal })
+}
+
+func TestEventsListFilters(t *testing.T) {
+	withOutputFormat(t, outputTable)
+	client := newAPIClient(eventsStub(t, nil, false).URL, "nos_test")
+
+	var out bytes.Buffer
+	if err := runEvents(context.Background(), client, &out, eventsOptions{severity: "warning"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "Failed login") || !strings.Contains(lines[2], "Scrub found errors") {
+		t.Fatalf("want warning and error events oldest first:\n%s", out.String())
+	}
+
+	outputFormat = outputJSONF
+	out.Reset()
+	// the newest canned event is at 09:30
+	opts := eventsOptions{since: time.Since(time.Date(2026, 10, 17, 9, 15, 0, 0, time.UTC)).Round(time.Second).String()}
+	if err := runEvents(context.Background(), client, &out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"id": "e3"`) || strings.Contains(out.String(), `"id": "e2"`) {
+		t.Fatalf("--since output:\n%s", out.String())
+	}
+
+	if err := runEvents(context.Background(), client, &out, eventsOptions{severity: "loud"}); err == nil {
+		t.Fatal("unknown severity accepted")
+	}
+	if err := runEvents(context.Background(), client, &out, eventsOptions{since: "yesterday"}); err == nil {
+		t.Fatal("bad --since accepted")
+	}
+	if err := runEvents(context.Background(), newAPIClient(client.baseURL, ""), &out, eventsOptions{}); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("unauthenticated list: %v", err)
+	}
+}
+
+func TestEventsFollowStreamsNewEvents(t *testing.T) {
+	withOutputFormat(t, outputJSONF)
+	srv := eventsStub(t, []string{
+		// already in the backlog
+		"event: event\ndata: {\"id\":\"e3\",\"timestamp\":\"2026-10-17T09:30:00Z\",\"level\":\"error\",\"message\":\"Scrub found errors\"}\n\n",
+		": keepalive\n\n",
+		"event: event\ndata: {\"id\":\"e4\",\"timestamp\":\"2026-10-17T10:00:00Z\",\"level\":\"info\",\"message\":\"App installed\"}\n\n",
+		"event: event\ndata: {\"id\":\"e5\",\"timestamp\":\"2026-10-17T10:01:00Z\",\"level\":\"critical\",\"category\":\"storage\",\"message\":\"Pool degraded\"}\n\n",
+	}, false)
+	client := newAPIClient(srv.URL, "nos_test")
+
+	var out bytes.Buffer
+	err := runEvents(context.Background(), client, &out, eventsOptions{severity: "error", follow: true})
+	if !errors.Is(err, errEventStreamEnded) {
+		t.Fatalf("err = %v, want the stream to have ended", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"id":"e3"`) || !strings.Contains(lines[1], `"id":"e5"`) {
+		t.Fatalf("want e3 from the backlog then e5 from the stream, one per line:\n%s", out.String())
+	}
+}
+
+// notifyWriter hands every write to lines.
+type notifyWriter struct{ lines chan string }
+
+func (n notifyWriter) Write(p []byte) (int, error) {
+	n.lines <- string(p)
+	return len(p), nil
+}
+
+func TestEventsFollowStopsCleanlyWhenInterrupted(t *testing.T) {
+	withOutputFormat(t, outputJSONF)
+	now := time.Now().UTC().Format(time.RFC3339)
+	srv := eventsStub(t, []string{
+		"event: event\ndata: {\"id\":\"e9\",\"timestamp\":\"" + now + "\",\"level\":\"warning\",\"message\":\"Disk hot\"}\n\n",
+	}, true)
+	client := newAPIClient(srv.URL, "nos_test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := notifyWriter{lines: make(chan string, 8)}
+	done := make(chan error, 1)
+	go func() { done <- runEvents(ctx, client, w, eventsOptions{since: "1h", follow: true}) }()
+
+	select {
+	case line := <-w.lines:
+		if !strings.Contains(line, `"id":"e9"`) {
+			t.Fatalf("first line %q, want the streamed event", line)
+		}
+	case err := <-done:
+		t.Fatalf("follow returned early: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the streamed event")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("interrupted follow returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow did not stop when interrupted")
+	}
+}
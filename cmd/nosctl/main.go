@@ -15,11 +15,19 @@ var (
 	GitCommit = "unknown"
 	
 	// Global flags
-	cfgFile    string
-	baseURL    string
-	token      string
-	outputJSON bool
-	verbose    bool
+	cfgFile      string
+	baseURL      string
+	token        string
+	profileName  string
+	outputFormat string
+	outputJSON   bool // deprecated --json, same as --output json
+	verbose      bool
+
+	// configErr is a profile selection that couldn't be resolved, reported
+	// by commands that talk to the API
+	configErr error
+	// activeProfile is the profile the URL and token came from, if any
+	activeProfile string
 )
 
 // rootCmd represents the base command
@@ -31,6 +39,12 @@ var rootCmd = &cobra.Command{
 It allows you to manage your NithronOS system from the terminal,
 including storage, applications, backups, and more.`,
 	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if configErr != nil && !offline(cmd) {
+			return configErr
+		}
+		return resolveOutputFormat()
+	},
 }
 
 func init() {
@@ -40,12 +54,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/nos/cli.yaml)")
 	rootCmd.PersistentFlags().StringVar(&baseURL, "url", "", "NithronOS API URL")
 	rootCmd.PersistentFlags().StringVar(&token, "token", "", "API token")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "configuration profile (default is the one set by profile use)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format: table, json, yaml or csv")
 	rootCmd.PersistentFlags().BoolVar(&outputJSON, "json", false, "output in JSON format")
+	rootCmd.PersistentFlags().MarkDeprecated("json", "use --output json")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	
 	// Bind flags to viper
 	viper.BindPFlag("url", rootCmd.PersistentFlags().Lookup("url"))
 	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
 	
 	// Add commands
 	rootCmd.AddCommand(
@@ -56,10 +74,13 @@ func init() {
 		newAppsCmd(),
 		newBackupsCmd(),
 		newAlertsCmd(),
+		newEventsCmd(),
 		newTokensCmd(),
 		newOpenapiCmd(),
 		newVersionCmd(),
 		newCompletionCmd(),
+		newConfigCmd(),
+		newProfileCmd(),
 	)
 }
 
@@ -84,17 +105,37 @@ func initConfig() {
 		fmt.Fprintf(os.Stderr, "Using config file: %s\n", viper.ConfigFileUsed())
 	}
 	
+	apiURL, apiToken, profile, err := resolveEndpoint(viper.GetViper(), profileName)
+	configErr = err
+	activeProfile = profile
+	if profile != "" && verbose {
+		fmt.Fprintf(os.Stderr, "Using profile: %s\n", profile)
+	}
+	
 	// Set defaults
 	if baseURL == "" {
-		baseURL = viper.GetString("url")
+		baseURL = apiURL
 		if baseURL == "" {
 			baseURL = "http://localhost:9000"
 		}
 	}
 	
 	if token == "" {
-		token = viper.GetString("token")
+		token = apiToken
+	}
+}
+
+// offline reports whether cmd works without the API, such as the commands
+// that fix the configuration, and so must run even when the selected
+// profile doesn't exist.
+func offline(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "profile", "config", "completion", "version":
+			return true
+		}
 	}
+	return false
 }
 
 func main() {
@@ -111,24 +152,3 @@ func checkError(err error) {
 		os.Exit(1)
 	}
 }
-
-func printJSON(data interface{}) {
-	// Implementation would use json.Marshal
-	fmt.Printf("%+v\n", data)
-}
-
-func printTable(headers []string, rows [][]string) {
-	// Implementation would use a table printer library
-	// For now, simple output
-	for _, header := range headers {
-		fmt.Printf("%-20s", header)
-	}
-	fmt.Println()
-	
-	for _, row := range rows {
-		for _, col := range row {
-			fmt.Printf("%-20s", col)
-		}
-		fmt.Println()
-	}
-}
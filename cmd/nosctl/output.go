@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for --output.
+const (
+	outputTable = "table"
+	outputJSONF = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+var outputFormats = []string{outputTable, outputJSONF, outputYAML, outputCSV}
+
+// resolveOutputFormat validates --output and folds in the deprecated --json.
+func resolveOutputFormat() error {
+	if outputJSON {
+		outputFormat = outputJSONF
+	}
+	outputFormat = strings.ToLower(strings.TrimSpace(outputFormat))
+	for _, f := range outputFormats {
+		if f == outputFormat {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --output %q (valid: %s)", outputFormat, strings.Join(outputFormats, ", "))
+}
+
+// structuredOutput reports whether single objects are printed as data
+// rather than as text; csv only applies to lists.
+func structuredOutput() bool {
+	return outputFormat == outputJSONF || outputFormat == outputYAML
+}
+
+// printList prints a list command's result: data itself for json/yaml, the
+// headers and rows for table/csv.
+func printList(data any, headers []string, rows [][]string) {
+	checkError(renderList(os.Stdout, outputFormat, data, headers, rows))
+}
+
+// printObject prints a single result as json or yaml.
+func printObject(data any) {
+	checkError(renderObject(os.Stdout, outputFormat, data))
+}
+
+func renderList(w io.Writer, format string, data any, headers []string, rows [][]string) error {
+	switch format {
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case outputJSONF, outputYAML:
+		return renderObject(w, format, data)
+	default:
+		writeTable(w, headers, rows)
+		return nil
+	}
+}
+
+func renderObject(w io.Writer, format string, data any) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if format != outputYAML {
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	// JSON is YAML: parsing it as a node keeps the json tag names, field
+	// order and integer formatting; only the flow style has to go
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle drops flow style everywhere but keeps strings quoted when a
+// YAML 1.1 reader would take them for something else (yes, off, ...);
+// yaml.v3 itself only quotes what YAML 1.2 would misread.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && yaml11NonString(n.Value) {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// yaml11Words are the plain scalars YAML 1.1 resolves to booleans or null.
+var yaml11Words = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true,
+	"true": true, "false": true, "null": true, "~": true,
+}
+
+func yaml11NonString(s string) bool {
+	return s == "" || yaml11Words[strings.ToLower(s)]
+}
+
+// tableMinWidth keeps columns of separately flushed rows (followed events)
+// lined up under their header.
+const tableMinWidth = 20
+
+func newTableWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, tableMinWidth, 0, 2, ' ', 0)
+}
+
+func writeTable(w io.Writer, headers []string, rows [][]string) {
+	tw := newTableWriter(w)
+	writeTableRow(tw, headers)
+	for _, row := range rows {
+		writeTableRow(tw, row)
+	}
+	_ = tw.Flush()
+}
+
+// writeTableRow writes one row; on a plain writer it is aligned and flushed
+// on its own.
+func writeTableRow(w io.Writer, cols []string) {
+	tw, ok := w.(*tabwriter.Writer)
+	if !ok {
+		tw = newTableWriter(w)
+		defer tw.Flush()
+	}
+	fmt.Fprintln(tw, strings.Join(cols, "\t"))
+}
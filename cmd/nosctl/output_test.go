@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/output")
+
+func sampleSnapshots() ([]Snapshot, []string, [][]string) {
+	snaps := []Snapshot{
+		{ID: "0b6f3c1e-9d2a-4c55-8e0f-1a2b3c4d5e6f", Subvolume: "@home", CreatedAt: "2026-10-17T03:00:00Z", Size: 5368709120},
+		{ID: "7e1d2c3b-4a59-4f6e-8d7c-6b5a4f3e2d1c", Subvolume: "@data, archive", CreatedAt: "2026-10-16T03:00:00Z", Size: 1024},
+		{ID: "c0ffee00-1234-4abc-9def-0123456789ab", Subvolume: "yes", CreatedAt: "2026-10-15T03:00:00Z", Size: 0},
+	}
+	headers := []string{"ID", "Subvolume", "Created", "Size"}
+	var rows [][]string
+	for _, s := range snaps {
+		rows = append(rows, []string{s.ID[:8], s.Subvolume, s.CreatedAt, formatBytes(s.Size)})
+	}
+	return snaps, headers, rows
+}
+
+func TestRenderListGolden(t *testing.T) {
+	data, headers, rows := sampleSnapshots()
+	for _, format := range outputFormats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderList(&buf, format, data, headers, rows); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "output", "snapshots."+format)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("%s output differs from %s:\n%s", format, golden, buf.String())
+			}
+		})
+	}
+}
+
+func TestResolveOutputFormat(t *testing.T) {
+	t.Cleanup(func() { outputFormat, outputJSON = outputTable, false })
+
+	outputFormat, outputJSON = "YAML", false
+	if err := resolveOutputFormat(); err != nil || outputFormat != outputYAML || !structuredOutput() {
+		t.Fatalf("yaml: %q %v", outputFormat, err)
+	}
+	// the deprecated --json wins over the default
+	outputFormat, outputJSON = outputTable, true
+	if err := resolveOutputFormat(); err != nil || outputFormat != outputJSONF {
+		t.Fatalf("--json: %q %v", outputFormat, err)
+	}
+	outputFormat, outputJSON = "xml", false
+	if err := resolveOutputFormat(); err == nil {
+		t.Fatal("xml accepted")
+	}
+	outputFormat = outputCSV
+	if structuredOutput() {
+		t.Fatal("csv is not structured output for single objects")
+	}
+}
+
+func TestYAMLQuotesYAML11Words(t *testing.T) {
+	var buf bytes.Buffer
+	data := map[string]any{"a": "off", "b": "N", "c": "", "d": "offline", "e": true}
+	if err := renderObject(&buf, outputYAML, data); err != nil {
+		t.Fatal(err)
+	}
+	want := "a: \"off\"\nb: \"N\"\nc: \"\"\nd: offline\ne: true\n"
+	if buf.String() != want {
+		t.Fatalf("yaml output:\n%s", buf.String())
+	}
+}
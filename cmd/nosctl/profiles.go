@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// profileNameRe keeps names usable as viper keys, which are lower-cased
+// and split on dots.
+var profileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func checkProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lower-case letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// resolveEndpoint picks the API URL and token from v, the loaded config
+// with NOS_* environment overrides. A profile named with --profile
+// (flagProfile) is used as is: asking for a server by name beats whatever
+// NOS_URL and NOS_TOKEN point at. Otherwise NOS_URL and NOS_TOKEN win; then
+// the profile chosen by NOS_PROFILE or "profile use"; then the top-level url
+// and token. A profile's settings never fall back to the top-level ones, so
+// one server's token isn't sent to another. Values given as --url/--token
+// flags are applied by the caller.
+func resolveEndpoint(v *viper.Viper, flagProfile string) (apiURL, apiToken, profile string, err error) {
+	profile = v.GetString("profile")
+	if flagProfile != "" {
+		profile = flagProfile
+	}
+	pick := func(key string) string {
+		if val := os.Getenv("NOS_" + strings.ToUpper(key)); val != "" && flagProfile == "" {
+			return val
+		}
+		if profile != "" {
+			return v.GetString("profiles." + profile + "." + key)
+		}
+		return v.GetString(key)
+	}
+	if profile != "" && !v.IsSet("profiles."+profile) {
+		return "", "", profile, fmt.Errorf("unknown profile %q (see nosctl profile list)", profile)
+	}
+	return pick("url"), pick("token"), profile, nil
+}
+
+// saveLogin stores the URL and token of a successful login in profile, or
+// at the top level of the config when no profile is active.
+func saveLogin(path, profile, apiURL, apiToken string) error {
+	c, err := loadCLIConfig(path)
+	if err != nil {
+		return err
+	}
+	if profile == "" {
+		c.URL, c.Token = apiURL, apiToken
+		return c.save(path)
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]cliProfile{}
+	}
+	c.Profiles[profile] = cliProfile{URL: apiURL, Token: apiToken}
+	return c.save(path)
+}
+
+// profileInfo is a profile as listed; tokens are never printed.
+type profileInfo struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	HasToken bool   `json:"has_token"`
+	Default  bool   `json:"default"`
+}
+
+// newProfileCmd creates the profile command group
+func newProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage server profiles",
+		Long: `Manage named profiles, each with its own API URL and token, for
+working with several NithronOS servers.
+
+Select a profile with --profile or NOS_PROFILE; otherwise the one set with
+"profile use" applies. NOS_URL and NOS_TOKEN override the profile unless it
+was named with --profile.`,
+	}
+
+	var addURL, addToken string
+	addCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add or update a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := checkProfileName(name); err != nil {
+				return err
+			}
+			u, err := normalizeAPIURL(addURL)
+			if err != nil {
+				return err
+			}
+			path := cliConfigPath()
+			c, err := loadCLIConfig(path)
+			if err != nil {
+				return err
+			}
+			_, exists := c.Profiles[name]
+			if c.Profiles == nil {
+				c.Profiles = map[string]cliProfile{}
+			}
+			c.Profiles[name] = cliProfile{URL: u, Token: addToken}
+			if err := c.save(path); err != nil {
+				return err
+			}
+			verb := "added"
+			if exists {
+				verb = "updated"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Profile %s %s\n", name, verb)
+			return nil
+		},
+	}
+	addCmd.Flags().StringVar(&addURL, "url", "", "API URL of the server")
+	addCmd.Flags().StringVar(&addToken, "token", "", "API token for the server")
+	_ = addCmd.MarkFlagRequired("url")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := loadCLIConfig(cliConfigPath())
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(c.Profiles))
+			for name := range c.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			profiles := []profileInfo{}
+			headers := []string{"Name", "URL", "Token", "Default"}
+			rows := [][]string{}
+			for _, name := range names {
+				p := c.Profiles[name]
+				info := profileInfo{Name: name, URL: p.URL, HasToken: p.Token != "", Default: name == c.Profile}
+				profiles = append(profiles, info)
+				tok, def := "-", ""
+				if info.HasToken {
+					tok = maskToken(p.Token)
+				}
+				if info.Default {
+					def = "*"
+				}
+				rows = append(rows, []string{name, p.URL, tok, def})
+			}
+			return renderList(cmd.OutOrStdout(), outputFormat, profiles, headers, rows)
+		},
+	}
+
+	useCmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a profile the default",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cliConfigPath()
+			c, err := loadCLIConfig(path)
+			if err != nil {
+				return err
+			}
+			if _, ok := c.Profiles[args[0]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			c.Profile = args[0]
+			if err := c.save(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Using profile %s\n", args[0])
+			return nil
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm"},
+		Short:   "Remove a profile",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := cliConfigPath()
+			c, err := loadCLIConfig(path)
+			if err != nil {
+				return err
+			}
+			if _, ok := c.Profiles[args[0]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			delete(c.Profiles, args[0])
+			if c.Profile == args[0] {
+				c.Profile = ""
+			}
+			if err := c.save(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Profile %s removed\n", args[0])
+			return nil
+		},
+	}
+
+	// profile names for use and remove
+	completeNames := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		c, err := loadCLIConfig(cliConfigPath())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+	useCmd.ValidArgsFunction = completeNames
+	removeCmd.ValidArgsFunction = completeNames
+
+	cmd.AddCommand(addCmd, listCmd, useCmd, removeCmd)
+	return cmd
+}
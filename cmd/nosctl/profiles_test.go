@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// resolveWith loads path the way initConfig does, with args parsed as the
+// global flags, and resolves the endpoint.
+func resolveWith(t *testing.T, path string, args ...string) (string, string, string, error) {
+	t.Helper()
+	flags := (&cobra.Command{}).Flags()
+	flags.String("profile", "", "")
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetEnvPrefix("NOS")
+	v.AutomaticEnv()
+	if err := v.BindPFlag("profile", flags.Lookup("profile")); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	profile, _ := flags.GetString("profile")
+	return resolveEndpoint(v, profile)
+}
+
+func runProfileCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	cmd := newProfileCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("profile %v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestProfileSwitchingResolvesEndpoint(t *testing.T) {
+	cfgFile = filepath.Join(t.TempDir(), "cli.yaml")
+	t.Cleanup(func() { cfgFile = "" })
+	for _, env := range []string{"NOS_URL", "NOS_TOKEN", "NOS_PROFILE"} {
+		t.Setenv(env, "")
+	}
+
+	if err := setCLIConfig(cfgFile, "url", "http://localhost:9000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setCLIConfig(cfgFile, "token", "nos_local"); err != nil {
+		t.Fatal(err)
+	}
+	runProfileCmd(t, "add", "home", "--url", "https://nas.home:9000/", "--token", "nos_home_0123456789")
+	runProfileCmd(t, "add", "work", "--url", "https://nas.work")
+
+	check := func(name, wantURL, wantToken, wantProfile string, args ...string) {
+		t.Helper()
+		u, tok, p, err := resolveWith(t, cfgFile, args...)
+		if err != nil || u != wantURL || tok != wantToken || p != wantProfile {
+			t.Fatalf("%s: got %q %q profile %q (%v); want %q %q profile %q", name, u, tok, p, err, wantURL, wantToken, wantProfile)
+		}
+	}
+	check("no profile", "http://localhost:9000", "nos_local", "")
+
+	runProfileCmd(t, "use", "home")
+	check("profile use", "https://nas.home:9000", "nos_home_0123456789", "home")
+	// a profile without a token doesn't borrow the top-level one
+	check("--profile", "https://nas.work", "", "work", "--profile", "work")
+
+	t.Setenv("NOS_PROFILE", "work")
+	check("NOS_PROFILE", "https://nas.work", "", "work")
+	check("--profile over NOS_PROFILE", "https://nas.home:9000", "nos_home_0123456789", "home", "--profile", "home")
+	t.Setenv("NOS_URL", "http://10.0.0.9:9000")
+	check("NOS_URL over the profile", "http://10.0.0.9:9000", "", "work")
+	t.Setenv("NOS_TOKEN", "nos_env")
+	check("--profile over NOS_URL and NOS_TOKEN", "https://nas.home:9000", "nos_home_0123456789", "home", "--profile", "home")
+	t.Setenv("NOS_URL", "")
+	t.Setenv("NOS_TOKEN", "")
+	t.Setenv("NOS_PROFILE", "")
+
+	if _, _, _, err := resolveWith(t, cfgFile, "--profile", "lab"); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Fatalf("unknown profile: %v", err)
+	}
+
+	if err := saveLogin(cfgFile, "work", "https://nas.work", "nos_work"); err != nil {
+		t.Fatal(err)
+	}
+	check("login into a profile", "https://nas.work", "nos_work", "work", "--profile", "work")
+
+	out := runProfileCmd(t, "list")
+	if !strings.Contains(out, "nos_****6789") || strings.Contains(out, "nos_home_") {
+		t.Fatalf("list shows unmasked tokens:\n%s", out)
+	}
+
+	// removing the default profile falls back to the top-level settings
+	runProfileCmd(t, "remove", "home")
+	check("after remove", "http://localhost:9000", "nos_local", "")
+}
+
+func TestProfileAddValidates(t *testing.T) {
+	cfgFile = filepath.Join(t.TempDir(), "cli.yaml")
+	t.Cleanup(func() { cfgFile = "" })
+
+	for _, args := range [][]string{
+		{"add", "Home", "--url", "https://nas"},
+		{"add", "a.b", "--url", "https://nas"},
+		{"add", "home", "--url", "nas.local"},
+		{"add", "home"},
+		{"use", "missing"},
+		{"remove", "missing"},
+	} {
+		cmd := newProfileCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("profile %v accepted", args)
+		}
+	}
+}
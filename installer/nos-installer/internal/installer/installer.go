@@ -1,7 +1,9 @@
 package installer
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,6 +18,10 @@ import (
 	"github.com/schollz/progressbar/v3"
 )
 
+// DefaultStepTimeout bounds how long a single external command may run before
+// it is killed. debootstrap over a slow mirror is the longest step we expect.
+const DefaultStepTimeout = 30 * time.Minute
+
 type Installer struct {
 	logFile      *os.File
 	logger       *log.Logger
@@ -26,16 +32,46 @@ type Installer struct {
 	isSSd        bool
 	hostname     string
 	timezone     string
+	stepTimeout  time.Duration
+	// wipeOnFailure clears the new partition table when the install fails
+	// before any system files were written.
+	wipeOnFailure bool
+	// mounts records every mount under targetMount in the order it was
+	// made, so cleanup can unmount precisely and in reverse.
+	mounts []string
+	// partitionSettle is how long to wait for the kernel to create
+	// partition device nodes after parted.
+	partitionSettle time.Duration
+
+	// commandContext builds external commands; tests swap it for fakes.
+	commandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
 }
 
 func New() *Installer {
 	return &Installer{
-		targetMount: "/mnt",
-		hostname:    "nithronos",
-		timezone:    "UTC",
+		targetMount:     "/mnt",
+		hostname:        "nithronos",
+		timezone:        "UTC",
+		stepTimeout:     DefaultStepTimeout,
+		commandContext:  exec.CommandContext,
+		partitionSettle: 2 * time.Second,
+	}
+}
+
+// SetStepTimeout overrides the per-command timeout. Non-positive values are
+// ignored so a zero flag value cannot disable the guard.
+func (i *Installer) SetStepTimeout(d time.Duration) {
+	if d > 0 {
+		i.stepTimeout = d
 	}
 }
 
+// SetWipeOnFailure controls whether a failure during partitioning or
+// filesystem creation wipes the freshly written partition table.
+func (i *Installer) SetWipeOnFailure(wipe bool) {
+	i.wipeOnFailure = wipe
+}
+
 func (i *Installer) Run() error {
 	// Setup logging
 	if err := i.setupLogging(); err != nil {
@@ -56,6 +92,20 @@ func (i *Installer) Run() error {
 		return fmt.Errorf("installation cancelled by user")
 	}
 
+	return i.install()
+}
+
+// install performs the destructive steps. If any of them fails, everything
+// mounted so far is unmounted again and, when the failure happened before the
+// base system was written, the partition table is optionally wiped.
+func (i *Installer) install() (err error) {
+	early := true
+	defer func() {
+		if err != nil {
+			i.cleanup(early)
+		}
+	}()
+
 	// Step 3: Partition disk
 	if err := i.partitionDisk(); err != nil {
 		return fmt.Errorf("disk partitioning failed: %w", err)
@@ -65,6 +115,7 @@ func (i *Installer) Run() error {
 	if err := i.createBtrfsLayout(); err != nil {
 		return fmt.Errorf("btrfs setup failed: %w", err)
 	}
+	early = false
 
 	// Step 5: Bootstrap system
 	if err := i.bootstrapSystem(); err != nil {
@@ -139,6 +190,9 @@ func (i *Installer) selectDisk() error {
 	options := make([]string, len(disks))
 	for idx, disk := range disks {
 		options[idx] = fmt.Sprintf("%s - %s (%s)", disk.Path, disk.Model, disk.Size)
+		if disk.Transport != "" {
+			options[idx] += " [" + disk.Transport + "]"
+		}
 	}
 	
 	var selected string
@@ -240,8 +294,9 @@ func (i *Installer) partitionDisk() error {
 	}
 	
 	// Wait for partitions to appear
-	time.Sleep(2 * time.Second)
+	time.Sleep(i.partitionSettle)
 	
+	completeBar(bar, "Disk partitioned")
 	i.logger.Printf("Created partitions: ESP=%s, root=%s", i.espPartition, i.rootPartition)
 	return nil
 }
@@ -267,7 +322,7 @@ func (i *Installer) createBtrfsLayout() error {
 	
 	// Mount root temporarily
 	bar.Describe("Mounting filesystem")
-	if err := i.runCmd("mount", i.rootPartition, i.targetMount); err != nil {
+	if err := i.mount(i.targetMount, i.rootPartition); err != nil {
 		return fmt.Errorf("failed to mount root: %w", err)
 	}
 	bar.Add(1)
@@ -285,7 +340,7 @@ func (i *Installer) createBtrfsLayout() error {
 	
 	// Unmount to remount with subvolumes
 	bar.Describe("Remounting with subvolumes")
-	if err := i.runCmd("umount", i.targetMount); err != nil {
+	if err := i.unmount(i.targetMount); err != nil {
 		return fmt.Errorf("failed to unmount: %w", err)
 	}
 	
@@ -296,7 +351,7 @@ func (i *Installer) createBtrfsLayout() error {
 	}
 	
 	// Mount @ as root
-	if err := i.runCmd("mount", "-o", mountOpts+",subvol=@", i.rootPartition, i.targetMount); err != nil {
+	if err := i.mount(i.targetMount, "-o", mountOpts+",subvol=@", i.rootPartition); err != nil {
 		return fmt.Errorf("failed to mount @ subvolume: %w", err)
 	}
 	
@@ -307,28 +362,31 @@ func (i *Installer) createBtrfsLayout() error {
 		}
 	}
 	
-	// Mount other subvolumes
-	subvolMounts := map[string]string{
-		"@home":      "home",
-		"@var":       "var", 
-		"@log":       "var/log",
-		"@snapshots": "snapshots",
+	// Mount other subvolumes; ordered so var is mounted before var/log.
+	subvolMounts := [][2]string{
+		{"@home", "home"},
+		{"@var", "var"},
+		{"@log", "var/log"},
+		{"@snapshots", "snapshots"},
 	}
 	
-	for subvol, mountPoint := range subvolMounts {
+	for _, sm := range subvolMounts {
+		subvol, mountPoint := sm[0], sm[1]
+		bar.Describe(fmt.Sprintf("Mounting %s", subvol))
 		mountPath := filepath.Join(i.targetMount, mountPoint)
-		if err := i.runCmd("mount", "-o", mountOpts+",subvol="+subvol, i.rootPartition, mountPath); err != nil {
+		if err := i.mount(mountPath, "-o", mountOpts+",subvol="+subvol, i.rootPartition); err != nil {
 			return fmt.Errorf("failed to mount %s: %w", subvol, err)
 		}
 	}
 	
 	// Mount ESP
 	bar.Describe("Mounting ESP")
-	if err := i.runCmd("mount", i.espPartition, filepath.Join(i.targetMount, "boot/efi")); err != nil {
+	if err := i.mount(filepath.Join(i.targetMount, "boot/efi"), i.espPartition); err != nil {
 		return fmt.Errorf("failed to mount ESP: %w", err)
 	}
 	bar.Add(1)
 	
+	completeBar(bar, "Btrfs layout ready")
 	i.logger.Println("Btrfs layout created successfully")
 	return nil
 }
@@ -348,8 +406,7 @@ func (i *Installer) bootstrapFromLive() error {
 	bar := progressbar.Default(3, "Copying system from live image")
 	
 	bar.Describe("Extracting base system")
-	cmd := exec.Command("tar", "-xzf", "/usr/share/nithronos/live-base.tar.gz", "-C", i.targetMount)
-	if err := cmd.Run(); err != nil {
+	if err := i.runCmd("tar", "-xzf", "/usr/share/nithronos/live-base.tar.gz", "-C", i.targetMount); err != nil {
 		return fmt.Errorf("failed to extract base system: %w", err)
 	}
 	bar.Add(1)
@@ -392,6 +449,7 @@ func (i *Installer) bootstrapFromLive() error {
 	}
 	bar.Add(1)
 	
+	completeBar(bar, "Base system installed")
 	return nil
 }
 
@@ -457,6 +515,7 @@ deb http://security.debian.org/debian-security bookworm-security main contrib no
 	}
 	bar.Add(1)
 	
+	completeBar(bar, "Base system installed")
 	return nil
 }
 
@@ -474,7 +533,7 @@ func (i *Installer) installBootloader() error {
 		{"devpts", "dev/pts", "/dev/pts"},
 	} {
 		target := filepath.Join(i.targetMount, mount[2])
-		if err := i.runCmd("mount", "-t", mount[0], mount[1], target); err != nil {
+		if err := i.mount(target, "-t", mount[0], mount[1]); err != nil {
 			i.logger.Printf("Warning: failed to mount %s: %v", mount[2], err)
 		}
 	}
@@ -523,6 +582,7 @@ GRUB_CMDLINE_LINUX="rootflags=subvol=@"
 	}
 	bar.Add(1)
 	
+	completeBar(bar, "Bootloader installed")
 	return nil
 }
 
@@ -612,6 +672,7 @@ BUG_REPORT_URL="https://github.com/nithronos/nithronos/issues"
 	}
 	bar.Add(1)
 	
+	completeBar(bar, "System configured")
 	return nil
 }
 
@@ -701,41 +762,133 @@ func (i *Installer) finalize() error {
 	
 	// Unmount everything
 	bar.Describe("Unmounting filesystems")
-	// Unmount in reverse order
-	mounts := []string{
-		filepath.Join(i.targetMount, "dev/pts"),
-		filepath.Join(i.targetMount, "dev"),
-		filepath.Join(i.targetMount, "sys"),
-		filepath.Join(i.targetMount, "proc"),
-		filepath.Join(i.targetMount, "boot/efi"),
-		filepath.Join(i.targetMount, "snapshots"),
-		filepath.Join(i.targetMount, "var/log"),
-		filepath.Join(i.targetMount, "var"),
-		filepath.Join(i.targetMount, "home"),
-		i.targetMount,
-	}
-	
-	for _, mount := range mounts {
-		i.runCmd("umount", "-l", mount)
-	}
+	i.unmountAll()
 	bar.Add(1)
 	
+	completeBar(bar, "Installation finalized")
 	i.logger.Println("Installation completed successfully")
 	return nil
 }
 
+// cleanup undoes a failed install so the machine isn't left with
+// half-mounted filesystems. early reports whether the failure happened before
+// the base system was written, in which case the new partition table can be
+// discarded as well.
+func (i *Installer) cleanup(early bool) {
+	i.logger.Println("Installation failed, cleaning up")
+	i.unmountAll()
+	if early && i.wipeOnFailure && i.targetDisk != "" {
+		i.logger.Printf("Wiping partition table on %s", i.targetDisk)
+		if err := i.runCmd("wipefs", "-af", i.targetDisk); err != nil {
+			i.logger.Printf("Warning: failed to wipe %s: %v", i.targetDisk, err)
+		}
+	}
+}
+
 // Helper functions
 
+// mount runs mount with args followed by target and records target for
+// cleanup once it succeeded.
+func (i *Installer) mount(target string, args ...string) error {
+	if err := i.runCmd("mount", append(args, target)...); err != nil {
+		return err
+	}
+	i.mounts = append(i.mounts, target)
+	return nil
+}
+
+// unmount unmounts target and drops it from the tracked mounts.
+func (i *Installer) unmount(target string) error {
+	if err := i.runCmd("umount", target); err != nil {
+		return err
+	}
+	for idx := len(i.mounts) - 1; idx >= 0; idx-- {
+		if i.mounts[idx] == target {
+			i.mounts = append(i.mounts[:idx], i.mounts[idx+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// unmountAll lazily unmounts every tracked mount in reverse order. Failures
+// are logged but don't stop the remaining unmounts.
+func (i *Installer) unmountAll() {
+	for idx := len(i.mounts) - 1; idx >= 0; idx-- {
+		if err := i.runCmd("umount", "-l", i.mounts[idx]); err != nil {
+			i.logger.Printf("Warning: failed to unmount %s: %v", i.mounts[idx], err)
+		}
+	}
+	i.mounts = nil
+}
+
+// runCmd runs an external command, streaming its stdout/stderr to the log line
+// by line so long steps visibly make progress. The command (and every process
+// it spawned) is killed once stepTimeout elapses.
 func (i *Installer) runCmd(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	output, err := cmd.CombinedOutput()
+	ctx, cancel := context.WithTimeout(context.Background(), i.stepTimeout)
+	defer cancel()
+
+	out := &lineLogger{logger: i.logger, prefix: name}
+	cmd := i.commandContext(ctx, name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Grandchildren may keep the output pipes open after the group is
+	// killed; don't wait on them forever.
+	cmd.WaitDelay = 5 * time.Second
+	setProcessGroup(cmd)
+
+	i.logger.Printf("Running: %s %s", name, strings.Join(args, " "))
+	err := cmd.Run()
+	out.Flush()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		i.logger.Printf("Command timed out after %s: %s %v", i.stepTimeout, name, args)
+		return fmt.Errorf("%s timed out after %s", name, i.stepTimeout)
+	}
 	if err != nil {
-		i.logger.Printf("Command failed: %s %v\nOutput: %s", name, args, string(output))
+		i.logger.Printf("Command failed: %s %v: %v", name, args, err)
 		return err
 	}
 	return nil
 }
 
+// lineLogger is an io.Writer that forwards complete lines to a logger.
+type lineLogger struct {
+	logger *log.Logger
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf.Write(p)
+	for {
+		line, err := l.buf.ReadString('\n')
+		if err != nil {
+			// Incomplete line: keep it buffered until more output arrives.
+			l.buf.Reset()
+			l.buf.WriteString(line)
+			break
+		}
+		l.logger.Printf("  [%s] %s", l.prefix, strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// Flush logs any trailing output that did not end in a newline.
+func (l *lineLogger) Flush() {
+	if l.buf.Len() > 0 {
+		l.logger.Printf("  [%s] %s", l.prefix, l.buf.String())
+		l.buf.Reset()
+	}
+}
+
+// completeBar finishes a progress bar and leaves a final description so the
+// terminal shows which phase completed.
+func completeBar(bar *progressbar.ProgressBar, desc string) {
+	bar.Describe(desc)
+	_ = bar.Finish()
+}
+
 func (i *Installer) chrootRun(name string, args ...string) error {
 	chrootArgs := append([]string{i.targetMount, name}, args...)
 	return i.runCmd("chroot", chrootArgs...)
@@ -774,54 +927,19 @@ func (i *Installer) getUUID(device string) (string, error) {
 }
 
 type DiskInfo struct {
-	Path  string
-	Model string
-	Size  string
-	IsSSD bool
+	Path      string
+	Model     string
+	Size      string
+	Transport string
+	IsSSD     bool
 }
 
 func (i *Installer) getAvailableDisks() ([]DiskInfo, error) {
-	var disks []DiskInfo
-	
-	// Use lsblk to get disk information
-	cmd := exec.Command("lsblk", "-ndo", "NAME,MODEL,SIZE,ROTA,TYPE")
-	output, err := cmd.Output()
+	// -d lists whole devices only; -O emits every column so field order and
+	// embedded spaces (models nearly always have them) don't matter.
+	output, err := exec.Command("lsblk", "-J", "-O", "-d").Output()
 	if err != nil {
 		return nil, err
 	}
-	
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) < 5 {
-			continue
-		}
-		
-		// Only consider whole disks
-		if fields[4] != "disk" {
-			continue
-		}
-		
-		name := fields[0]
-		model := fields[1]
-		if model == "" {
-			model = "Unknown"
-		}
-		size := fields[2]
-		isSSD := fields[3] == "0" // ROTA=0 means SSD
-		
-		// Skip loop devices and ram disks
-		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
-			continue
-		}
-		
-		disks = append(disks, DiskInfo{
-			Path:  "/dev/" + name,
-			Model: model,
-			Size:  size,
-			IsSSD: isSSD,
-		})
-	}
-	
-	return disks, nil
+	return parseLsblkDisks(output)
 }
@@ -0,0 +1,174 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestInstaller(buf *bytes.Buffer) *Installer {
+	i := New()
+	i.logger = log.New(buf, "", 0)
+	return i
+}
+
+func TestRunCmdTimesOut(t *testing.T) {
+	var buf bytes.Buffer
+	i := newTestInstaller(&buf)
+	i.SetStepTimeout(200 * time.Millisecond)
+
+	start := time.Now()
+	err := i.runCmd("sh", "-c", "echo started; sleep 30")
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("command was not killed promptly: %s", elapsed)
+	}
+	if !strings.Contains(buf.String(), "[sh] started") {
+		t.Fatalf("expected streamed output in log, got %q", buf.String())
+	}
+}
+
+func TestRunCmdStreamsOutput(t *testing.T) {
+	var buf bytes.Buffer
+	i := newTestInstaller(&buf)
+
+	if err := i.runCmd("sh", "-c", "echo one; echo two 1>&2; printf three"); err != nil {
+		t.Fatalf("runCmd: %v", err)
+	}
+	for _, want := range []string{"[sh] one", "[sh] two", "[sh] three"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("missing %q in log %q", want, buf.String())
+		}
+	}
+}
+
+func TestRunCmdFailure(t *testing.T) {
+	var buf bytes.Buffer
+	i := newTestInstaller(&buf)
+
+	err := i.runCmd("sh", "-c", "exit 3")
+	if err == nil || strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected plain failure, got %v", err)
+	}
+}
+
+// fakeCommands records every command the installer runs and makes the ones
+// named in fail exit non-zero; everything else succeeds.
+func fakeCommands(i *Installer, fail ...string) *[]string {
+	var ran []string
+	i.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		ran = append(ran, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+		for _, f := range fail {
+			if name == f {
+				return exec.CommandContext(ctx, "false")
+			}
+		}
+		return exec.CommandContext(ctx, "true")
+	}
+	return &ran
+}
+
+func commandsWithPrefix(ran []string, prefix string) []string {
+	var out []string
+	for _, c := range ran {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func TestInstallFailureUnmountsInReverse(t *testing.T) {
+	var buf bytes.Buffer
+	i := newTestInstaller(&buf)
+	i.targetDisk = "/dev/sdz"
+	i.targetMount = t.TempDir()
+	i.partitionSettle = 0
+	i.SetWipeOnFailure(true)
+	ran := fakeCommands(i, "debootstrap")
+
+	if err := i.install(); err == nil {
+		t.Fatal("expected install to fail")
+	}
+
+	m := i.targetMount
+	want := []string{
+		"umount -l " + filepath.Join(m, "boot/efi"),
+		"umount -l " + filepath.Join(m, "snapshots"),
+		"umount -l " + filepath.Join(m, "var/log"),
+		"umount -l " + filepath.Join(m, "var"),
+		"umount -l " + filepath.Join(m, "home"),
+		"umount -l " + m,
+	}
+	got := commandsWithPrefix(*ran, "umount -l")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected unmounts:\ngot  %q\nwant %q", got, want)
+	}
+	if len(i.mounts) != 0 {
+		t.Fatalf("mounts not cleared: %v", i.mounts)
+	}
+	// Failure happened after the base layout was built, so the disk is kept.
+	if n := len(commandsWithPrefix(*ran, "wipefs")); n != 1 {
+		t.Fatalf("expected only the initial wipefs, got %d", n)
+	}
+}
+
+func TestInstallEarlyFailureWipesDisk(t *testing.T) {
+	var buf bytes.Buffer
+	i := newTestInstaller(&buf)
+	i.targetDisk = "/dev/sdz"
+	i.targetMount = t.TempDir()
+	i.partitionSettle = 0
+	i.SetWipeOnFailure(true)
+	ran := fakeCommands(i, "btrfs")
+
+	if err := i.install(); err == nil {
+		t.Fatal("expected install to fail")
+	}
+
+	// The root filesystem was mounted before subvolume creation failed.
+	got := commandsWithPrefix(*ran, "umount -l")
+	if len(got) != 1 || got[0] != "umount -l "+i.targetMount {
+		t.Fatalf("unexpected unmounts: %q", got)
+	}
+	wipes := commandsWithPrefix(*ran, "wipefs")
+	if len(wipes) != 2 || wipes[1] != "wipefs -af /dev/sdz" {
+		t.Fatalf("expected cleanup wipe, got %q", wipes)
+	}
+}
+
+func TestParseLsblkDisks(t *testing.T) {
+	data, err := os.ReadFile("testdata/lsblk.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	disks, err := parseLsblkDisks(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	want := []DiskInfo{
+		{Path: "/dev/sda", Model: "Samsung SSD 860 EVO 500GB", Size: "465.8G", Transport: "sata", IsSSD: true},
+		{Path: "/dev/sdb", Model: "WDC WD40EFRX-68N32N0", Size: "3.6T", Transport: "usb", IsSSD: false},
+		{Path: "/dev/nvme0n1", Model: "Unknown", Size: "931.5G", Transport: "nvme", IsSSD: true},
+	}
+	if len(disks) != len(want) {
+		t.Fatalf("expected %d disks, got %d: %+v", len(want), len(disks), disks)
+	}
+	for idx := range want {
+		if disks[idx] != want[idx] {
+			t.Errorf("disk %d: got %+v, want %+v", idx, disks[idx], want[idx])
+		}
+	}
+}
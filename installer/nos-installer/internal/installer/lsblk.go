@@ -0,0 +1,108 @@
+package installer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lsblkOutput is the top level of `lsblk -J`.
+type lsblkOutput struct {
+	Blockdevices []lsblkDevice `json:"blockdevices"`
+}
+
+type lsblkDevice struct {
+	Name  string      `json:"name"`
+	Path  string      `json:"path"`
+	Model lsblkString `json:"model"`
+	Size  lsblkString `json:"size"`
+	Rota  lsblkBool   `json:"rota"`
+	Type  string      `json:"type"`
+	Tran  lsblkString `json:"tran"`
+}
+
+// lsblkString accepts strings, numbers and null. Older util-linux releases
+// print sizes as strings, newer ones as numbers when --bytes is used.
+type lsblkString string
+
+func (s *lsblkString) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*s = ""
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var v string
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*s = lsblkString(strings.TrimSpace(v))
+		return nil
+	}
+	*s = lsblkString(b)
+	return nil
+}
+
+// lsblkBool accepts true/false as well as the "0"/"1" strings emitted by
+// util-linux before 2.33.
+type lsblkBool bool
+
+func (v *lsblkBool) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch string(b) {
+	case "null":
+		*v = false
+		return nil
+	case "true", "false":
+		*v = string(b) == "true"
+		return nil
+	}
+	raw := strings.Trim(string(b), `"`)
+	parsed, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fmt.Errorf("lsblk: invalid boolean %s", b)
+	}
+	*v = lsblkBool(parsed)
+	return nil
+}
+
+// parseLsblkDisks decodes `lsblk -J -O` output into installable disks,
+// skipping partitions, optical drives, loop devices and ram disks.
+func parseLsblkDisks(data []byte) ([]DiskInfo, error) {
+	var out lsblkOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse lsblk output: %w", err)
+	}
+
+	var disks []DiskInfo
+	for _, d := range out.Blockdevices {
+		// Only consider whole disks
+		if d.Type != "disk" {
+			continue
+		}
+		// Skip loop devices and ram disks
+		if strings.HasPrefix(d.Name, "loop") || strings.HasPrefix(d.Name, "ram") {
+			continue
+		}
+
+		path := d.Path
+		if path == "" {
+			path = "/dev/" + d.Name
+		}
+		model := string(d.Model)
+		if model == "" {
+			model = "Unknown"
+		}
+
+		disks = append(disks, DiskInfo{
+			Path:      path,
+			Model:     model,
+			Size:      string(d.Size),
+			Transport: string(d.Tran),
+			IsSSD:     !bool(d.Rota), // ROTA=0 means SSD
+		})
+	}
+	return disks, nil
+}
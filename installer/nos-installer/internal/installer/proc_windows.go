@@ -0,0 +1,9 @@
+//go:build windows
+
+package installer
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {
+	// no process groups on windows; CommandContext kills the leader
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"nithronos/installer/internal/installer"
@@ -17,14 +18,19 @@ var (
 func main() {
 	log.SetOutput(os.Stdout)
 
+	var stepTimeout time.Duration
+	var wipeOnFailure bool
+
 	var rootCmd = &cobra.Command{
 		Use:   "nos-installer",
 		Short: "NithronOS guided installer",
 		Long:  `NithronOS installer creates a fresh installation with Btrfs subvolumes and proper system configuration.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runInstaller()
+			runInstaller(stepTimeout, wipeOnFailure)
 		},
 	}
+	rootCmd.Flags().DurationVar(&stepTimeout, "step-timeout", installer.DefaultStepTimeout, "Maximum time a single install command may run before it is killed")
+	rootCmd.Flags().BoolVar(&wipeOnFailure, "wipe-on-failure", false, "Wipe the new partition table if installation fails before the system is written")
 
 	var versionCmd = &cobra.Command{
 		Use:   "version",
@@ -42,7 +48,7 @@ func main() {
 	}
 }
 
-func runInstaller() {
+func runInstaller(stepTimeout time.Duration, wipeOnFailure bool) {
 	// Ensure we're running as root
 	if os.Geteuid() != 0 {
 		fmt.Fprintf(os.Stderr, "Error: installer must be run as root\n")
@@ -51,6 +57,8 @@ func runInstaller() {
 
 	// Create and run the installer
 	inst := installer.New()
+	inst.SetStepTimeout(stepTimeout)
+	inst.SetWipeOnFailure(wipeOnFailure)
 	if err := inst.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Installation failed: %v\n", err)
 		os.Exit(1)
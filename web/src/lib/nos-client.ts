@@ -225,7 +225,8 @@ export const http = {
     getSession: () => httpCore.get('/v1/auth/session'),
     session: () => httpCore.get('/v1/auth/session'),
     totp: {
-      enroll: () => httpCore.post('/v1/auth/totp/enroll'),
+      // with 2FA on, re-enrolling needs the current code or the password
+      enroll: (reauth?: { code?: string; password?: string }) => httpCore.post('/v1/auth/totp/enroll', reauth ?? {}),
       verify: (code: string) => httpCore.post('/v1/auth/totp/verify', { code }),
     },
   },
@@ -246,6 +247,8 @@ export const http = {
   network: {
     getInterfaces: () => httpCore.get('/v1/network/interfaces'),
     configureInterface: (iface: string, config: any) => httpCore.post(`/v1/network/interfaces/${iface}`, config),
+    confirmInterface: (iface: string) => httpCore.post(`/v1/network/interfaces/${iface}/confirm`, {}),
+    setDNS: (data: { servers: string[]; search?: string[]; dhcp?: boolean }) => httpCore.post('/v1/network/dns', data),
   },
   
   // Telemetry endpoints
@@ -299,13 +302,14 @@ export const http = {
   
   // Snapshot endpoints
   snapshots: {
-    recent: () => httpCore.get('/snapshots/recent'),
+    recent: (params?: { limit?: number; cursor?: string; success?: boolean; package?: string }) =>
+      httpCore.get<{ items: any[]; nextCursor: string | null }>('/snapshots/recent', params),
     prune: (data: any) => httpCore.post('/snapshots/prune', data),
   },
   
   // Updates endpoints
   updates: {
-    check: () => httpCore.get('/v1/updates/check'),
+    check: (refresh?: boolean) => httpCore.get(refresh ? '/v1/updates/check?refresh=true' : '/v1/updates/check'),
     apply: (data: any) => httpCore.post('/v1/updates/apply', data),
     rollback: (data: any) => httpCore.post('/v1/updates/rollback', data),
     streamProgress: () => openSSE('/v1/updates/progress/stream'),
@@ -385,6 +389,9 @@ export const http = {
   
   jobs: {
     recent: (limit: number) => httpCore.get(`/v1/jobs/recent?limit=${limit}`),
+    // SSE: one `job` event per state change; closes after a terminal state
+    stream: (id: string) => openSSE(`/v1/jobs/${encodeURIComponent(id)}/stream`),
+    cancel: (id: string) => httpCore.post(`/v1/jobs/${encodeURIComponent(id)}/cancel`),
   },
   
   devices: {
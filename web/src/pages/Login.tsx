@@ -98,10 +98,13 @@ export function Login() {
         code: requiresCode ? totpCode.replace(/\s+/g, '') : undefined,
       }
       
-      await api.auth.login(loginData)
+      const result: any = await api.auth.login(loginData)
       
       // Success - update session and navigate
       toast.success('Successfully signed in')
+      if (typeof result?.recoveryCodesRemaining === 'number') {
+        toast.warning(`Signed in with a recovery code. ${result.recoveryCodesRemaining} left; regenerate them in Settings.`)
+      }
       
       // Check session to update auth context
       await checkSession()
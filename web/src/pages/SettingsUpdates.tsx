@@ -15,14 +15,14 @@ export function SettingsUpdates(){
   const [pruneResult,setPruneResult]=useState<any|null>(null)
   const [error,setError]=useState<string>('')
 
-  const load = async()=>{
+  const load = async(refresh=false)=>{
     setLoading(true)
     setError('')
     try{
-      const p = await http.updates.check() as unknown as CheckResp
+      const p = await http.updates.check(refresh) as unknown as CheckResp
       setPlan(p)
-      const rec = await http.snapshots.recent() as any[]
-      setRecent(rec||[])
+      const rec = await http.snapshots.recent()
+      setRecent(rec?.items||[])
     }catch(e:any){ setError(e?.message||'Failed to load updates') }
     finally{ setLoading(false) }
   }
@@ -34,7 +34,7 @@ export function SettingsUpdates(){
     try{
       const resp = await http.updates.apply({ snapshot, confirm:'yes' }) as unknown as ApplyResp
       toast.success(`Updates applied (tx ${resp.tx_id})`)
-      await load()
+      await load(true)
     }catch(e:any){
       const msg = e?.message||'Failed to apply updates'
       setError(msg)
@@ -50,7 +50,7 @@ export function SettingsUpdates(){
     try{
       await http.updates.rollback({ tx_id, confirm:'yes' })
       toast.success('Rollback requested')
-      await load()
+      await load(true)
     }catch(e:any){
       const msg = e?.message||'Failed to rollback'
       setError(msg)
@@ -84,7 +84,7 @@ export function SettingsUpdates(){
     <div className="space-y-6">
       <div className="flex items-center justify-between">
         <h1 className="text-2xl font-semibold">Updates</h1>
-        <button className="btn-outline" onClick={load} disabled={loading||applying}>Refresh</button>
+        <button className="btn-outline" onClick={()=>load(true)} disabled={loading||applying}>Refresh</button>
       </div>
       {error && <div className="text-red-500 text-sm">{error}</div>}
 
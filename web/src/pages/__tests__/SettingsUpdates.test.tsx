@@ -35,7 +35,7 @@ describe('SettingsUpdates', () => {
       plan: { updates:[{name:'nosd', current:'0.1.0', candidate:'0.2.0'}] }, 
       snapshot_roots: ['/srv'] 
     })
-    vi.mocked(http.snapshots.recent).mockResolvedValue([])
+    vi.mocked(http.snapshots.recent).mockResolvedValue({ items: [], nextCursor: null })
   })
 
   it('renders available updates list', async () => {
@@ -93,9 +93,9 @@ describe('SettingsUpdates', () => {
       plan: { updates:[] }, 
       snapshot_roots: ['/srv'] 
     })
-    vi.mocked(http.snapshots.recent).mockResolvedValue([
+    vi.mocked(http.snapshots.recent).mockResolvedValue({ items: [
       { tx_id:'tx-1', time: new Date().toISOString(), packages:['nosd'], targets:[], success:true }
-    ])
+    ], nextCursor: null })
     vi.mocked(http.updates.rollback).mockResolvedValue({ ok:true })
     
     const toastSpy = vi.spyOn(toast, 'success')